@@ -0,0 +1,159 @@
+package azkustocompat
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	v2 "github.com/Azure/azure-kusto-go/azkustodata/query/v2"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	legacyerrors "github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	legacyvalue "github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	id := uuid.New()
+
+	tests := []struct {
+		desc   string
+		value  value.Kusto
+		legacy legacyvalue.Kusto
+	}{
+		{"bool", value.NewBool(true), legacyvalue.Bool{Value: true, Valid: true}},
+		{"null bool", value.NewNullBool(), legacyvalue.Bool{}},
+		{"int", value.NewInt(-3), legacyvalue.Int{Value: -3, Valid: true}},
+		{"null int", value.NewNullInt(), legacyvalue.Int{}},
+		{"long", value.NewLong(1 << 60), legacyvalue.Long{Value: 1 << 60, Valid: true}},
+		{"null long", value.NewNullLong(), legacyvalue.Long{}},
+		{"real", value.NewReal(1.5), legacyvalue.Real{Value: 1.5, Valid: true}},
+		{"null real", value.NewNullReal(), legacyvalue.Real{}},
+		{"decimal", value.NewDecimal(decimal.RequireFromString("12345678901234567890.123456789")), legacyvalue.Decimal{Value: "12345678901234567890.123456789", Valid: true}},
+		{"null decimal", value.NewNullDecimal(), legacyvalue.Decimal{}},
+		{"string", value.NewString("hello"), legacyvalue.String{Value: "hello", Valid: true}},
+		{"dynamic", value.NewDynamic([]byte(`{"a":1}`)), legacyvalue.Dynamic{Value: []byte(`{"a":1}`), Valid: true}},
+		{"null dynamic", value.NewNullDynamic(), legacyvalue.Dynamic{}},
+		{"datetime", value.NewDateTime(now), legacyvalue.DateTime{Value: now, Valid: true}},
+		{"null datetime", value.NewNullDateTime(), legacyvalue.DateTime{}},
+		{"timespan", value.NewTimespan(time.Hour + time.Nanosecond*100), legacyvalue.Timespan{Value: time.Hour + time.Nanosecond*100, Valid: true}},
+		{"null timespan", value.NewNullTimespan(), legacyvalue.Timespan{}},
+		{"guid", value.NewGUID(id), legacyvalue.GUID{Value: id, Valid: true}},
+		{"null guid", value.NewNullGUID(), legacyvalue.GUID{}},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			legacy, err := ValueToLegacy(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.legacy, legacy)
+
+			back, err := ValueFromLegacy(legacy)
+			require.NoError(t, err)
+			assert.Equal(t, test.value, back)
+		})
+	}
+}
+
+func TestValueFromLegacyBadDecimal(t *testing.T) {
+	t.Parallel()
+
+	_, err := ValueFromLegacy(legacyvalue.Decimal{Value: "not a number", Valid: true})
+	assert.Error(t, err)
+}
+
+func TestRowRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	legacy := &table.Row{
+		ColumnTypes: table.Columns{{Name: "A", Type: "int"}, {Name: "B", Type: "string"}},
+		Values:      legacyvalue.Values{legacyvalue.Int{Value: 1, Valid: true}, legacyvalue.String{Value: "x", Valid: true}},
+		Op:          legacyerrors.OpQuery,
+	}
+
+	row, err := RowFromLegacy(legacy, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, row.Index())
+	assert.Equal(t, types.String, row.Columns()[1].Type())
+
+	s, err := row.StringByName("B")
+	require.NoError(t, err)
+	assert.Equal(t, "x", s)
+
+	back, err := RowToLegacy(row)
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ColumnTypes, back.ColumnTypes)
+	assert.Equal(t, legacy.Values, back.Values)
+}
+
+const frames = `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"A","ColumnType":"int"},{"ColumnName":"B","ColumnType":"string"}]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[1,"a"],[2,"b"]]}
+,{"FrameType":"TableCompletion","TableId":1,"RowCount":2,"OneApiErrors":[{"error":{"code":"LimitsExceeded","message":"Request is invalid and cannot be executed."}}]}
+,{"FrameType":"DataTable","TableId":2,"TableKind":"QueryCompletionInformation","TableName":"QueryCompletionInformation","Columns":[{"ColumnName":"Timestamp","ColumnType":"datetime"}],"Rows":[["2024-01-01T00:00:00Z"]]}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+
+func newDataset(t *testing.T) query.IterativeDataset {
+	dataset, err := v2.NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(frames)), v2.DefaultIoCapacity, v2.DefaultRowCapacity, v2.DefaultTableCapacity)
+	require.NoError(t, err)
+	return dataset
+}
+
+func TestRowIteratorDoOnRowOrError(t *testing.T) {
+	t.Parallel()
+
+	iter := NewRowIterator(newDataset(t))
+	defer iter.Stop()
+
+	var got []string
+	var inline []*legacyerrors.Error
+	err := iter.DoOnRowOrError(func(r *table.Row, e *legacyerrors.Error) error {
+		if e != nil {
+			inline = append(inline, e)
+			return nil
+		}
+		assert.Equal(t, []string{"A", "B"}, r.ColumnNames())
+		got = append(got, r.String())
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1,a\n", "2,b\n"}, got)
+	require.Len(t, inline, 1)
+	assert.Contains(t, inline[0].Error(), "LimitsExceeded")
+}
+
+func TestRowIteratorDo(t *testing.T) {
+	t.Parallel()
+
+	iter := NewRowIterator(newDataset(t))
+	defer iter.Stop()
+
+	rows := 0
+	err := iter.Do(func(r *table.Row) error {
+		rows++
+		return nil
+	})
+
+	// Do fails on inline errors, like the legacy RowIterator.
+	require.Error(t, err)
+	assert.Equal(t, 2, rows)
+
+	_, err2 := iter.Next()
+	assert.Equal(t, err, err2)
+}
@@ -0,0 +1,27 @@
+/*
+Package azkustocompat provides conversions between the types of the legacy SDK (github.com/Azure/azure-kusto-go/kusto)
+and the types of azkustodata, for code bases that use both module generations while migrating.
+
+Values, columns and rows can be converted in both directions without losing information:
+
+	legacyRow, err := azkustocompat.RowToLegacy(row)
+	row, err := azkustocompat.RowFromLegacy(legacyRow, 0)
+
+Code that was written against the legacy kusto.RowIterator can be fed from an azkustodata query with RowIterator:
+
+	dataset, err := client.IterativeQuery(ctx, "Samples", kql.New("PopulationData"))
+	if err != nil {
+		panic(err)
+	}
+
+	iter := azkustocompat.NewRowIterator(dataset)
+	defer iter.Stop()
+
+	err = iter.DoOnRowOrError(func(row *table.Row, e *errors.Error) error {
+		...
+	})
+
+This package is intended for the duration of a migration only. It is kept in its own module so that azkustodata
+users don't depend on the legacy SDK.
+*/
+package azkustocompat
@@ -0,0 +1,17 @@
+module github.com/Azure/azure-kusto-go/azkustocompat
+
+go 1.22
+
+require (
+	github.com/Azure/azure-kusto-go v0.16.1
+	github.com/Azure/azure-kusto-go/azkustodata v1.0.0-preview-5
+	github.com/google/uuid v1.6.0
+	github.com/shopspring/decimal v1.4.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,145 @@
+package azkustocompat
+
+import (
+	"io"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	legacyerrors "github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+)
+
+// RowIterator provides the methods of the legacy kusto.RowIterator on top of an azkustodata query.IterativeDataset.
+// It iterates over the rows of the first primary result table, like the legacy client did.
+// Errors are converted to the legacy errors package, so existing error handling keeps working.
+type RowIterator struct {
+	dataset query.IterativeDataset
+	op      legacyerrors.Op
+
+	mu sync.Mutex
+	// rows is the rows channel of the table currently being read, nil between tables.
+	rows <-chan query.RowResult
+	// primary indicates the current table is the one whose rows are returned. Other tables are drained.
+	primary     bool
+	seenPrimary bool
+	columns     table.Columns
+	// err is the final error. Once set, all calls return it.
+	err error
+}
+
+// NewRowIterator creates a RowIterator reading from dataset. The RowIterator takes ownership of dataset, call Stop()
+// instead of closing it.
+func NewRowIterator(dataset query.IterativeDataset) *RowIterator {
+	return &RowIterator{dataset: dataset, op: opToLegacy(dataset.Op())}
+}
+
+// Do calls f for every row returned by the query. If f returns a non-nil error, iteration stops.
+// This method will fail on errors inline within the rows, like the legacy Do().
+func (r *RowIterator) Do(f func(r *table.Row) error) error {
+	for {
+		row, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+}
+
+// DoOnRowOrError calls f for every row returned by the query. If errors occur inline within the rows, they are passed to f.
+// Other errors will stop the iteration and be returned.
+// If f returns a non-nil error, iteration stops.
+func (r *RowIterator) DoOnRowOrError(f func(r *table.Row, e *legacyerrors.Error) error) error {
+	for {
+		row, inlineErr, err := r.NextRowOrError()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := f(row, inlineErr); err != nil {
+			return err
+		}
+	}
+}
+
+// Stop is called to stop any further iteration. Always defer a Stop() call after receiving a RowIterator.
+func (r *RowIterator) Stop() {
+	_ = r.dataset.Close()
+}
+
+// Next gets the next Row from the query. io.EOF is returned if there are no more entries in the output.
+// This method will fail on errors inline within the rows. Once Next() returns an error, all subsequent calls will
+// return the same error.
+func (r *RowIterator) Next() (*table.Row, error) {
+	row, inlineErr, err := r.NextRowOrError()
+	if err != nil {
+		return nil, err
+	}
+	if inlineErr != nil {
+		r.mu.Lock()
+		r.err = inlineErr
+		r.mu.Unlock()
+		return nil, inlineErr
+	}
+	return row, nil
+}
+
+// NextRowOrError gets the next Row or service-side error from the query.
+// On partial success, inlineError will be set.
+// Once finalError returns non-nil, all subsequent calls will return the same error.
+// finalError is io.EOF when the query completed with success or partial success.
+func (r *RowIterator) NextRowOrError() (row *table.Row, inlineError *legacyerrors.Error, finalError error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The dataset always closes its channels once it is done or closed, so there's no need to watch its context.
+	for r.err == nil {
+		if r.rows == nil {
+			tr, ok := <-r.dataset.Tables()
+			if !ok {
+				r.err = io.EOF
+				break
+			}
+			if tr.Err() != nil {
+				r.err = ErrorToLegacy(tr.Err())
+				break
+			}
+
+			tb := tr.Table()
+			r.rows = tb.Rows()
+			r.primary = tb.IsPrimaryResult() && !r.seenPrimary
+			if r.primary {
+				r.seenPrimary = true
+				r.columns = ColumnsToLegacy(tb.Columns())
+			}
+			continue
+		}
+
+		rr, ok := <-r.rows
+		if !ok {
+			r.rows = nil
+			continue
+		}
+		if !r.primary {
+			continue
+		}
+		if rr.Err() != nil {
+			return nil, ErrorToLegacy(rr.Err()), nil
+		}
+
+		row, err := rowToLegacy(rr.Row(), r.columns, r.op)
+		if err != nil {
+			r.err = ErrorToLegacy(err)
+			break
+		}
+		return row, nil, nil
+	}
+
+	return nil, nil, r.err
+}
@@ -0,0 +1,101 @@
+package azkustocompat
+
+import (
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	legacyerrors "github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	legacytypes "github.com/Azure/azure-kusto-go/kusto/data/types"
+)
+
+// ColumnToLegacy converts an azkustodata column into a legacy column.
+func ColumnToLegacy(c query.Column) table.Column {
+	return table.Column{Name: c.Name(), Type: legacytypes.Column(c.Type())}
+}
+
+// ColumnsToLegacy converts azkustodata columns into legacy columns.
+func ColumnsToLegacy(columns []query.Column) table.Columns {
+	converted := make(table.Columns, len(columns))
+	for i, c := range columns {
+		converted[i] = ColumnToLegacy(c)
+	}
+	return converted
+}
+
+// ColumnsFromLegacy converts legacy columns into azkustodata columns.
+func ColumnsFromLegacy(columns table.Columns) query.Columns {
+	converted := make(query.Columns, len(columns))
+	for i, c := range columns {
+		converted[i] = query.NewColumn(i, c.Name, types.Column(c.Type))
+	}
+	return converted
+}
+
+// RowToLegacy converts an azkustodata row into a legacy row.
+func RowToLegacy(r query.Row) (*table.Row, error) {
+	return rowToLegacy(r, ColumnsToLegacy(r.Columns()), legacyerrors.OpQuery)
+}
+
+func rowToLegacy(r query.Row, columns table.Columns, op legacyerrors.Op) (*table.Row, error) {
+	values, err := ValuesToLegacy(r.Values())
+	if err != nil {
+		return nil, err
+	}
+
+	return &table.Row{ColumnTypes: columns, Values: values, Op: op}, nil
+}
+
+// RowFromLegacy converts a legacy row into an azkustodata row. index is the index of the row in its table, which
+// legacy rows don't keep.
+func RowFromLegacy(r *table.Row, index int) (query.Row, error) {
+	if len(r.ColumnTypes) != len(r.Values) {
+		return nil, errors.ES(errors.OpUnknown, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values), len(r.ColumnTypes))
+	}
+
+	values, err := ValuesFromLegacy(r.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := ColumnsFromLegacy(r.ColumnTypes)
+	byName := make(map[string]query.Column, len(columns))
+	for _, c := range columns {
+		byName[c.Name()] = c
+	}
+
+	return query.NewRowFromParts(columns, func(name string) query.Column { return byName[name] }, index, values), nil
+}
+
+// ErrorToLegacy converts an error into a legacy *errors.Error. The Op and Kind of an azkustodata *errors.Error are
+// kept if the legacy package defines them, otherwise they are reported as unknown.
+func ErrorToLegacy(err error) *legacyerrors.Error {
+	if err == nil {
+		return nil
+	}
+
+	op, kind := legacyerrors.OpUnknown, legacyerrors.KOther
+	if e, ok := errors.GetKustoError(err); ok {
+		op, kind = opToLegacy(e.Op), kindToLegacy(e.Kind)
+	}
+
+	e := legacyerrors.E(op, kind, err)
+	if !errors.Retry(err) {
+		e.SetNoRetry()
+	}
+	return e
+}
+
+func opToLegacy(op errors.Op) legacyerrors.Op {
+	if op > errors.OpTokenProvider {
+		return legacyerrors.OpUnknown
+	}
+	return legacyerrors.Op(op)
+}
+
+func kindToLegacy(kind errors.Kind) legacyerrors.Kind {
+	if kind > errors.KLocalFileSystem {
+		return legacyerrors.KOther
+	}
+	return legacyerrors.Kind(kind)
+}
@@ -0,0 +1,130 @@
+package azkustocompat
+
+import (
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	legacyvalue "github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/shopspring/decimal"
+)
+
+// ValueToLegacy converts an azkustodata value into its legacy counterpart. Null values are converted to legacy
+// values with Valid set to false.
+func ValueToLegacy(v value.Kusto) (legacyvalue.Kusto, error) {
+	switch k := v.(type) {
+	case *value.Bool:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.Bool{Value: val, Valid: ok}, nil
+	case *value.Int:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.Int{Value: val, Valid: ok}, nil
+	case *value.Long:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.Long{Value: val, Valid: ok}, nil
+	case *value.Real:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.Real{Value: val, Valid: ok}, nil
+	case *value.Decimal:
+		// The legacy Decimal holds the textual representation, which is exact.
+		val, ok := deref(k.Ptr())
+		if !ok {
+			return legacyvalue.Decimal{}, nil
+		}
+		return legacyvalue.Decimal{Value: val.String(), Valid: true}, nil
+	case *value.String:
+		return legacyvalue.String{Value: k.Value, Valid: true}, nil
+	case *value.Dynamic:
+		return legacyvalue.Dynamic{Value: k.Value, Valid: k.Value != nil}, nil
+	case *value.DateTime:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.DateTime{Value: val, Valid: ok}, nil
+	case *value.Timespan:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.Timespan{Value: val, Valid: ok}, nil
+	case *value.GUID:
+		val, ok := deref(k.Ptr())
+		return legacyvalue.GUID{Value: val, Valid: ok}, nil
+	}
+
+	return nil, errors.ES(errors.OpUnknown, errors.KWrongColumnType, "cannot convert value of type %T to a legacy value", v)
+}
+
+// ValueFromLegacy converts a legacy value into its azkustodata counterpart. Legacy values with Valid set to false
+// are converted to null values.
+// Legacy strings don't have a null representation in azkustodata, so they are converted to empty strings.
+func ValueFromLegacy(v legacyvalue.Kusto) (value.Kusto, error) {
+	switch k := v.(type) {
+	case legacyvalue.Bool:
+		return fromLegacy(k.Value, k.Valid, value.NewBool, value.NewNullBool), nil
+	case legacyvalue.Int:
+		return fromLegacy(k.Value, k.Valid, value.NewInt, value.NewNullInt), nil
+	case legacyvalue.Long:
+		return fromLegacy(k.Value, k.Valid, value.NewLong, value.NewNullLong), nil
+	case legacyvalue.Real:
+		return fromLegacy(k.Value, k.Valid, value.NewReal, value.NewNullReal), nil
+	case legacyvalue.Decimal:
+		if !k.Valid {
+			return value.NewNullDecimal(), nil
+		}
+		dec, err := decimal.NewFromString(k.Value)
+		if err != nil {
+			return nil, errors.ES(errors.OpUnknown, errors.KFailedToParse, "legacy decimal value %q could not be parsed: %s", k.Value, err)
+		}
+		return value.NewDecimal(dec), nil
+	case legacyvalue.String:
+		return value.NewString(k.Value), nil
+	case legacyvalue.Dynamic:
+		if !k.Valid {
+			return value.NewNullDynamic(), nil
+		}
+		return value.NewDynamic(k.Value), nil
+	case legacyvalue.DateTime:
+		return fromLegacy(k.Value, k.Valid, value.NewDateTime, value.NewNullDateTime), nil
+	case legacyvalue.Timespan:
+		return fromLegacy(k.Value, k.Valid, value.NewTimespan, value.NewNullTimespan), nil
+	case legacyvalue.GUID:
+		return fromLegacy(k.Value, k.Valid, value.NewGUID, value.NewNullGUID), nil
+	}
+
+	return nil, errors.ES(errors.OpUnknown, errors.KWrongColumnType, "cannot convert legacy value of type %T", v)
+}
+
+// ValuesToLegacy converts a list of azkustodata values with ValueToLegacy.
+func ValuesToLegacy(values value.Values) (legacyvalue.Values, error) {
+	converted := make(legacyvalue.Values, len(values))
+	for i, v := range values {
+		c, err := ValueToLegacy(v)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+// ValuesFromLegacy converts a list of legacy values with ValueFromLegacy.
+func ValuesFromLegacy(values legacyvalue.Values) (value.Values, error) {
+	converted := make(value.Values, len(values))
+	for i, v := range values {
+		c, err := ValueFromLegacy(v)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+func deref[T any](p *T) (T, bool) {
+	if p == nil {
+		var zero T
+		return zero, false
+	}
+	return *p, true
+}
+
+func fromLegacy[T any, K value.Kusto](v T, valid bool, newValue func(T) K, newNull func() K) value.Kusto {
+	if !valid {
+		return newNull()
+	}
+	return newValue(v)
+}
@@ -0,0 +1,199 @@
+package azkustodata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// AccessAction is an action that CheckAccess checks the permissions of the principal of the client for.
+type AccessAction string
+
+const (
+	// AccessQuery queries the database, which requires the viewer role.
+	AccessQuery AccessAction = "query"
+	// AccessIngest ingests into the database or its table, which requires the ingestor role.
+	AccessIngest AccessAction = "ingest"
+	// AccessAdmin runs the management commands that alter the database or its table, which requires the admin role.
+	AccessAdmin AccessAction = "admin"
+)
+
+// PrincipalRole is a row of `.show principal roles`, a role of the principal of the client.
+type PrincipalRole struct {
+	// Scope is what the role applies to, such as "Cluster", "Database Logs" or "Table Logs.Events".
+	Scope       string
+	DisplayName string
+	AADObjectID string
+	// Role is the name of the role, such as "Viewer" or "AllDatabasesAdmin".
+	Role string
+}
+
+// AccessCheck is the result of CheckAccess.
+type AccessCheck struct {
+	Action   AccessAction
+	Database string
+	// Table is the table of CheckTableAccess, empty for CheckAccess.
+	Table string
+	// Allowed reports whether the principal has a role that allows the action.
+	Allowed bool
+	// GrantedBy is the role that allows the action, nil if it is not allowed.
+	GrantedBy *PrincipalRole
+	// MissingRole is the least privileged role that would allow the action, such as "Database Logs Ingestor", empty if
+	// it is allowed.
+	MissingRole string
+	// Principal is the display name of the principal, from its roles, empty if it has none.
+	Principal string
+	// Roles are all the roles of the principal.
+	Roles []PrincipalRole
+}
+
+// Err returns an error that names the missing role, and the command that grants it, if the action is not allowed, or
+// nil if it is.
+func (a AccessCheck) Err() error {
+	if a.Allowed {
+		return nil
+	}
+	principal := a.Principal
+	if principal == "" {
+		principal = "the principal of the client"
+	}
+	verb := map[AccessAction]string{AccessQuery: "query", AccessIngest: "ingest into", AccessAdmin: "administer"}[a.Action]
+	target := "database " + a.Database
+	if a.Table != "" {
+		target = "table " + a.Database + "." + a.Table
+	}
+	return errors.ES(errors.OpMgmt, errors.KOther, "%s is not allowed to %s %s, it requires the role %q, granted with `%s`",
+		principal, verb, target, a.MissingRole, a.grantCommand()).SetNoRetry()
+}
+
+// grantCommand returns the command that grants the missing role, with a placeholder for the principal.
+func (a AccessCheck) grantCommand() string {
+	role := requiredRole(a.Action)
+	if a.Table != "" {
+		return fmt.Sprintf(".add table %s %ss ('<principal>')", kql.NormalizeName(a.Table), strings.ToLower(role))
+	}
+	return fmt.Sprintf(".add database %s %ss ('<principal>')", kql.NormalizeName(a.Database), strings.ToLower(role))
+}
+
+// CheckAccess checks whether the principal of the client is allowed to run action on the database db, from its roles
+// as returned by `.show principal roles`, so that long-running pipelines can fail fast, with the missing role, instead
+// of failing with Forbidden errors midway. It only returns an error if the roles could not be fetched: a denied action
+// is returned as an AccessCheck, whose Err describes the missing role.
+// The roles are checked on the client, for the built-in roles of the cluster, the database and its tables. Roles
+// granted by the security groups of the principal are only returned if the service resolves them.
+func (c *Client) CheckAccess(ctx context.Context, db string, action AccessAction, options ...QueryOption) (AccessCheck, error) {
+	return c.checkAccess(ctx, "CheckAccess", db, "", action, options)
+}
+
+// CheckTableAccess checks whether the principal of the client is allowed to run action on the table of the database db,
+// like CheckAccess, so that the table-level roles are taken into account, such as the ingestor role of a table.
+func (c *Client) CheckTableAccess(ctx context.Context, db, table string, action AccessAction, options ...QueryOption) (AccessCheck, error) {
+	if table == "" {
+		return AccessCheck{}, errors.ES(errors.OpMgmt, errors.KClientArgs, "CheckTableAccess requires a table").SetNoRetry()
+	}
+	return c.checkAccess(ctx, "CheckTableAccess", db, table, action, options)
+}
+
+func (c *Client) checkAccess(ctx context.Context, op string, db, table string, action AccessAction, options []QueryOption) (AccessCheck, error) {
+	if db == "" {
+		return AccessCheck{}, errors.ES(errors.OpMgmt, errors.KClientArgs, "%s requires a database", op).SetNoRetry()
+	}
+	switch action {
+	case AccessQuery, AccessIngest, AccessAdmin:
+	default:
+		return AccessCheck{}, errors.ES(errors.OpMgmt, errors.KClientArgs, "%s action %q is not valid", op, action).SetNoRetry()
+	}
+
+	dataset, err := c.Mgmt(ctx, db, kql.New(".show principal roles"), options...)
+	if err != nil {
+		return AccessCheck{}, err
+	}
+	roles, err := query.ToStructs[PrincipalRole](dataset)
+	if err != nil {
+		return AccessCheck{}, err
+	}
+	return evaluateAccess(db, table, action, roles), nil
+}
+
+// evaluateAccess checks the roles of a principal for action.
+func evaluateAccess(db, table string, action AccessAction, roles []PrincipalRole) AccessCheck {
+	check := AccessCheck{Action: action, Database: db, Table: table, Roles: roles}
+	for i, r := range roles {
+		if check.Principal == "" {
+			check.Principal = r.DisplayName
+		}
+		if check.GrantedBy == nil && roleAllows(r, db, table, action) {
+			check.GrantedBy = &roles[i]
+		}
+	}
+	check.Allowed = check.GrantedBy != nil
+	if !check.Allowed {
+		if table != "" && action != AccessQuery {
+			check.MissingRole = fmt.Sprintf("Table %s.%s %s", db, table, requiredRole(action))
+		} else {
+			check.MissingRole = fmt.Sprintf("Database %s %s", db, requiredRole(action))
+		}
+	}
+	return check
+}
+
+// requiredRole returns the least privileged role of a database or table that allows action.
+func requiredRole(action AccessAction) string {
+	switch action {
+	case AccessIngest:
+		return "Ingestor"
+	case AccessAdmin:
+		return "Admin"
+	}
+	return "Viewer"
+}
+
+// roleAllows reports whether r allows action on the database db, or its table if table isn't empty.
+// The role is matched on its last word, as the service reports it either alone, such as "Admin", or with its scope,
+// such as "Database Logs Admin".
+func roleAllows(r PrincipalRole, db, table string, action AccessAction) bool {
+	fields := strings.Fields(r.Role)
+	if len(fields) == 0 {
+		return false
+	}
+	role := strings.ToLower(fields[len(fields)-1])
+
+	// Cluster-wide roles.
+	switch role {
+	case "alldatabasesadmin":
+		return true
+	case "alldatabasesviewer":
+		return action == AccessQuery
+	}
+
+	scope, name, _ := strings.Cut(strings.TrimSpace(r.Scope), " ")
+	switch strings.ToLower(scope) {
+	case "database":
+		if !strings.EqualFold(name, db) {
+			return false
+		}
+		switch action {
+		case AccessQuery:
+			return role == "viewer" || role == "unrestrictedviewer" || role == "user" || role == "admin"
+		case AccessIngest:
+			return role == "ingestor" || role == "admin"
+		case AccessAdmin:
+			return role == "admin"
+		}
+	case "table":
+		if table == "" || !strings.EqualFold(name, db+"."+table) {
+			return false
+		}
+		switch action {
+		case AccessIngest:
+			return role == "ingestor" || role == "admin"
+		case AccessAdmin:
+			return role == "admin"
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package azkustodata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const principalRolesResponse = `{"Tables":[{"TableName":"Table_0","Columns":[` +
+	`{"ColumnName":"Scope","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"DisplayName","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"AADObjectID","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"Role","DataType":"String","ColumnType":"string"}],` +
+	`"Rows":[` +
+	`["Database Logs","pipeline (app id: 1234)","1234","Viewer"],` +
+	`["Table Metrics.Events","pipeline (app id: 1234)","1234","Table Metrics.Events Ingestor"]]}]}`
+
+func TestCheckAccess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		db          string
+		table       string
+		action      AccessAction
+		wantAllowed bool
+		wantMissing string
+	}{
+		{desc: "database viewer can query", db: "Logs", action: AccessQuery, wantAllowed: true},
+		{desc: "database viewer can't ingest", db: "Logs", action: AccessIngest, wantMissing: "Database Logs Ingestor"},
+		{desc: "no role on database", db: "Other", action: AccessQuery, wantMissing: "Database Other Viewer"},
+		{desc: "table ingestor can ingest into its table", db: "Metrics", table: "Events", action: AccessIngest, wantAllowed: true},
+		{desc: "table ingestor can't ingest into other tables", db: "Metrics", table: "Traces", action: AccessIngest, wantMissing: "Table Metrics.Traces Ingestor"},
+		{desc: "table ingestor can't ingest into the database", db: "Metrics", action: AccessIngest, wantMissing: "Database Metrics Ingestor"},
+		{desc: "table ingestor isn't admin", db: "Metrics", table: "Events", action: AccessAdmin, wantMissing: "Table Metrics.Events Admin"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &fakeMgmtConn{responses: []string{principalRolesResponse}}
+			client := &Client{conn: conn}
+
+			var check AccessCheck
+			var err error
+			if test.table == "" {
+				check, err = client.CheckAccess(context.Background(), test.db, test.action)
+			} else {
+				check, err = client.CheckTableAccess(context.Background(), test.db, test.table, test.action)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{".show principal roles"}, conn.commands)
+			assert.Len(t, check.Roles, 2)
+			assert.Equal(t, "pipeline (app id: 1234)", check.Principal)
+			assert.Equal(t, test.wantAllowed, check.Allowed)
+			assert.Equal(t, test.wantMissing, check.MissingRole)
+			if test.wantAllowed {
+				assert.NotNil(t, check.GrantedBy)
+				assert.NoError(t, check.Err())
+				return
+			}
+			assert.Nil(t, check.GrantedBy)
+			assert.ErrorContains(t, check.Err(), test.wantMissing)
+		})
+	}
+}
+
+func TestCheckAccessClusterRoles(t *testing.T) {
+	t.Parallel()
+
+	viewer := []PrincipalRole{{Scope: "Cluster", Role: "AllDatabasesViewer"}}
+	assert.True(t, evaluateAccess("Logs", "", AccessQuery, viewer).Allowed)
+	assert.False(t, evaluateAccess("Logs", "", AccessIngest, viewer).Allowed)
+
+	admin := []PrincipalRole{{Scope: "Cluster", Role: "AllDatabasesAdmin"}}
+	assert.True(t, evaluateAccess("Logs", "Events", AccessAdmin, admin).Allowed)
+
+	denied := evaluateAccess("Logs", "", AccessIngest, nil)
+	assert.ErrorContains(t, denied.Err(), "the principal of the client is not allowed to ingest into database Logs")
+	assert.ErrorContains(t, denied.Err(), ".add database Logs ingestors ('<principal>')")
+}
+
+func TestCheckAccessArgs(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &fakeMgmtConn{}}
+	_, err := client.CheckAccess(context.Background(), "", AccessQuery)
+	assert.Error(t, err)
+	_, err = client.CheckAccess(context.Background(), "Logs", AccessAction("drop"))
+	assert.Error(t, err)
+	_, err = client.CheckTableAccess(context.Background(), "Logs", "", AccessIngest)
+	assert.Error(t, err)
+}
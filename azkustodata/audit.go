@@ -0,0 +1,98 @@
+package azkustodata
+
+import (
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// RedactedValue replaces the values of the redacted parameters in an AuditRecord.
+const RedactedValue = "<redacted>"
+
+// AuditRecord describes a query or management command just before the client sends it, for security audit trails of
+// what was executed against the cluster.
+type AuditRecord struct {
+	// Op is errors.OpQuery for queries, and errors.OpMgmt for management commands.
+	Op errors.Op
+	// Endpoint is the URL the request is sent to.
+	Endpoint string
+	// Database is the database the request runs in, or empty if the service picks it.
+	Database string
+	// Text is the text of the request as it is sent, including the declaration of its parameters.
+	// Values added to the statement with its Add methods are part of the text, and are never redacted - pass sensitive
+	// values as parameters with the QueryParameters option instead.
+	Text string
+	// Parameters are the values of the parameters of the request, as KQL literals by name, with the values of the
+	// redacted parameters replaced. It is nil if the request has no parameters.
+	Parameters map[string]string
+	// ClientRequestID is the client request id of the request, to correlate the record with the logs of the service.
+	ClientRequestID string
+}
+
+// AuditOption is an option of WithAuditHook.
+type AuditOption func(a *auditor)
+
+// RedactParameters replaces the values of the parameters with the given names by RedactedValue.
+func RedactParameters(names ...string) AuditOption {
+	return func(a *auditor) {
+		for _, n := range names {
+			a.redacted[n] = true
+		}
+	}
+}
+
+// RedactAllParameters replaces the values of all the parameters by RedactedValue, so that only their names are
+// audited.
+func RedactAllParameters() AuditOption {
+	return func(a *auditor) {
+		a.redactAll = true
+	}
+}
+
+// RedactParametersFunc sets a function that returns the audited value of every parameter, from its name and its value
+// as a KQL literal - for instance to mask a part of it. It is called after the other redaction options, for the
+// parameters they didn't redact, and must be safe for concurrent use.
+func RedactParametersFunc(redact func(name, value string) string) AuditOption {
+	return func(a *auditor) {
+		a.redact = redact
+	}
+}
+
+// WithAuditHook sets a function that is called with an AuditRecord for every query and management command, just
+// before it is sent. The call blocks the request, so the hook should be fast, and it must be safe for concurrent use.
+// The values of the parameters are audited as is unless redacted with the AuditOptions.
+func WithAuditHook(hook func(AuditRecord), options ...AuditOption) Option {
+	return func(c *Client) {
+		a := &auditor{hook: hook, redacted: map[string]bool{}}
+		for _, o := range options {
+			o(a)
+		}
+		c.auditor = a
+	}
+}
+
+// auditor sends AuditRecords to the hook of WithAuditHook.
+type auditor struct {
+	hook      func(AuditRecord)
+	redacted  map[string]bool
+	redactAll bool
+	redact    func(name, value string) string
+}
+
+// audit calls the hook with record, after redacting its parameters.
+func (a *auditor) audit(record AuditRecord) {
+	if len(record.Parameters) > 0 {
+		params := make(map[string]string, len(record.Parameters))
+		for name, value := range record.Parameters {
+			switch {
+			case a.redactAll || a.redacted[name]:
+				value = RedactedValue
+			case a.redact != nil:
+				value = a.redact(name, value)
+			}
+			params[name] = value
+		}
+		record.Parameters = params
+	} else {
+		record.Parameters = nil
+	}
+	a.hook(record)
+}
@@ -0,0 +1,81 @@
+package azkustodata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHook(t *testing.T) {
+	t.Parallel()
+
+	mask := func(name, value string) string {
+		return value[:2] + "***"
+	}
+
+	tests := []struct {
+		name    string
+		options []AuditOption
+		want    map[string]string
+	}{
+		{name: "not redacted", want: map[string]string{"secret": `"password"`, "id": "long(1)"}},
+		{name: "redacted", options: []AuditOption{RedactParameters("secret")}, want: map[string]string{"secret": RedactedValue, "id": "long(1)"}},
+		{name: "all redacted", options: []AuditOption{RedactAllParameters()}, want: map[string]string{"secret": RedactedValue, "id": RedactedValue}},
+		{
+			name:    "redaction func",
+			options: []AuditOption{RedactParameters("id"), RedactParametersFunc(mask)},
+			want:    map[string]string{"secret": `"p***`, "id": RedactedValue},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1]]}]}`))
+			}))
+			defer server.Close()
+
+			var records []AuditRecord
+			client := &Client{}
+			WithAuditHook(func(r AuditRecord) { records = append(records, r) }, test.options...)(client)
+
+			conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+			require.NoError(t, err)
+			conn.endpointValidated.Store(true)
+			conn.auditor = client.auditor
+			client.conn = conn
+
+			params := kql.NewParameters().AddString("secret", "password").AddLong("id", 1)
+			_, err = client.QueryV1(context.Background(), "db", kql.New("T | where Id == id and Secret == secret"), QueryParameters(params),
+				ClientRequestID("audited"))
+			require.NoError(t, err)
+			_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+			require.NoError(t, err)
+
+			require.Len(t, records, 2)
+			query := records[0]
+			assert.Equal(t, errors.OpQuery, query.Op)
+			assert.Equal(t, server.URL+"/v1/rest/query", query.Endpoint)
+			assert.Equal(t, "db", query.Database)
+			assert.True(t, strings.HasPrefix(query.Text, "declare query_parameters("))
+			assert.True(t, strings.HasSuffix(query.Text, "\nT | where Id == id and Secret == secret"))
+			assert.Equal(t, test.want, query.Parameters)
+			assert.Equal(t, "audited", query.ClientRequestID)
+
+			mgmt := records[1]
+			assert.Equal(t, errors.OpMgmt, mgmt.Op)
+			assert.Equal(t, ".show tables", mgmt.Text)
+			assert.Nil(t, mgmt.Parameters)
+		})
+	}
+}
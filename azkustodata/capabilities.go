@@ -0,0 +1,164 @@
+package azkustodata
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	queryv2 "github.com/Azure/azure-kusto-go/azkustodata/query/v2"
+	"github.com/Azure/azure-kusto-go/azkustodata/utils"
+)
+
+// The values of Capabilities.ServiceType.
+const (
+	// ServiceTypeEngine is the service type of the engine endpoint of a cluster, which runs queries and commands.
+	ServiceTypeEngine = "Engine"
+	// ServiceTypeDataManagement is the service type of the ingestion endpoint of a cluster, the "ingest-" endpoint.
+	ServiceTypeDataManagement = "DataManagement"
+)
+
+// Capabilities are the version and the features of the service a Client is connected to.
+type Capabilities struct {
+	// BuildVersion, ServiceType, ProductVersion and ServiceOffering are the columns of `.show version`.
+	BuildVersion    string
+	ServiceType     string
+	ProductVersion  string
+	ServiceOffering string
+	// StreamingIngest reports whether the service accepts streaming ingestion. Data management endpoints don't.
+	// The streaming ingestion policy of the database or table must also be enabled for it to succeed.
+	StreamingIngest bool
+	// InStreamErrors reports whether the service reports the errors of queries in the stream, in v2.1 datasets, when
+	// requested with ResultsErrorsInStream. It is turned off once the service answers such a query with an older
+	// version of the protocol.
+	InStreamErrors bool
+}
+
+// showVersionRow is a row of `.show version`.
+type showVersionRow struct {
+	BuildVersion    string
+	ServiceType     string
+	ProductVersion  string
+	ServiceOffering string
+}
+
+// WithCapabilities sets the capabilities of the service, in place of the ones read with `.show version`, for instance
+// to test how the client downgrades when a feature isn't supported.
+func WithCapabilities(capabilities Capabilities) Option {
+	return func(c *Client) {
+		c.capabilities.known = &capabilities
+	}
+}
+
+// negotiator keeps the capabilities of the service, read once per client.
+type negotiator struct {
+	// mu guards known and fetch, it isn't held while the capabilities are read.
+	mu    sync.Mutex
+	known *Capabilities
+	// fetch reads the capabilities once, for all the concurrent calls of Capabilities.
+	fetch utils.Once[Capabilities]
+	// noInStreamErrors is set once the service answered a query that requested in-stream errors without them.
+	noInStreamErrors atomic.Bool
+}
+
+// Capabilities returns the capabilities of the service, read with `.show version` on the first call, and kept for the
+// next ones. A failed read is retried on the next call.
+// The client downgrades the features the service doesn't support instead of failing: ResultsErrorsInStream is ignored
+// once the service is known not to report errors in the stream, and the managed ingestion client of azkustoingest
+// queues the data when streaming ingestion isn't supported.
+func (c *Client) Capabilities(ctx context.Context) (Capabilities, error) {
+	n := &c.capabilities
+	n.mu.Lock()
+	known := n.known
+	if known == nil && n.fetch == nil {
+		n.fetch = utils.NewOnce[Capabilities]()
+	}
+	fetch := n.fetch
+	n.mu.Unlock()
+
+	if known == nil {
+		fetched, err := fetch.Do(func() (Capabilities, error) {
+			return c.showVersion(ctx)
+		})
+		if err != nil {
+			return Capabilities{}, err
+		}
+
+		n.mu.Lock()
+		if n.known == nil {
+			n.known = &fetched
+		}
+		known = n.known
+		n.mu.Unlock()
+	}
+
+	caps := *known
+	if n.noInStreamErrors.Load() {
+		caps.InStreamErrors = false
+	}
+	return caps, nil
+}
+
+// showVersion reads the capabilities of the service with `.show version`.
+func (c *Client) showVersion(ctx context.Context) (Capabilities, error) {
+	dataset, err := c.Mgmt(ctx, "", kql.New(".show version"))
+	if err != nil {
+		return Capabilities{}, err
+	}
+	rows, err := query.ToStructs[showVersionRow](dataset)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	if len(rows) != 1 {
+		return Capabilities{}, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single row from .show version, got %d", len(rows))
+	}
+	row := rows[0]
+	return Capabilities{
+		BuildVersion:    row.BuildVersion,
+		ServiceType:     row.ServiceType,
+		ProductVersion:  row.ProductVersion,
+		ServiceOffering: row.ServiceOffering,
+		StreamingIngest: !strings.EqualFold(row.ServiceType, ServiceTypeDataManagement),
+		InStreamErrors:  !strings.EqualFold(row.ServiceType, ServiceTypeDataManagement),
+	}, nil
+}
+
+// inStreamErrors reports whether queries may request in-stream errors. Until the capabilities are known, they may.
+func (n *negotiator) inStreamErrors() bool {
+	if n.noInStreamErrors.Load() {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.known == nil || n.known.InStreamErrors
+}
+
+// negotiateInStreamErrors downgrades ResultsErrorsInStream to the default placement of the errors if the service doesn't
+// report errors in the stream. It must run after the options of the call.
+func (c *Client) negotiateInStreamErrors() QueryOption {
+	return func(q *queryOptions) error {
+		if q.requestProperties.Options[ResultsErrorReportingPlacementValue] != ResultsErrorReportingPlacementInData || c.capabilities.inStreamErrors() {
+			return nil
+		}
+		delete(q.requestProperties.Options, ResultsErrorReportingPlacementValue)
+		return nil
+	}
+}
+
+// learnInStreamErrors turns off in-stream errors if the service answers a query that requested them with an older
+// version of the protocol.
+func (c *Client) learnInStreamErrors(opts *queryOptions) {
+	if opts.requestProperties.Options[ResultsErrorReportingPlacementValue] != ResultsErrorReportingPlacementInData {
+		return
+	}
+	opts.datasetOptions = append(opts.datasetOptions, queryv2.WithDataSetHeaderHandler(func(header queryv2.DataSetHeader) {
+		if header.Version != queryv2.HeaderVersionInStreamErrors && !c.capabilities.noInStreamErrors.Swap(true) {
+			log.Writef(log.EventQuery, "%s answered with dataset version %s, errors will no longer be requested in the stream",
+				c.endpoint, header.Version)
+		}
+	}))
+}
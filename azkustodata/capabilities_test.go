@@ -0,0 +1,159 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capabilitiesConn answers `.show version` with serviceType, and queries with a v2.0 dataset, and records the
+// commands and the error placements of the queries.
+type capabilitiesConn struct {
+	mu          sync.Mutex
+	serviceType string
+	commands    int
+	placements  []interface{}
+	// started receives a value when a command starts, if set, and the commands wait until release is closed.
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *capabilitiesConn) rawQuery(_ context.Context, callType callType, _ string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	if callType == mgmtCall && c.release != nil {
+		c.started <- struct{}{}
+		<-c.release
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if callType == mgmtCall {
+		c.commands++
+		return io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+			`{"ColumnName":"BuildVersion","DataType":"String","ColumnType":"string"},` +
+			`{"ColumnName":"BuildTime","DataType":"DateTime","ColumnType":"datetime"},` +
+			`{"ColumnName":"ServiceType","DataType":"String","ColumnType":"string"},` +
+			`{"ColumnName":"ProductVersion","DataType":"String","ColumnType":"string"},` +
+			`{"ColumnName":"ServiceOffering","DataType":"String","ColumnType":"string"}],` +
+			`"Rows":[["1.0.9000.1","2024-01-02T03:04:05Z","` + c.serviceType + `","KustoRelease_2024.01.01","{\"Type\":\"Azure Data Explorer\"}"]]}]}`)), nil
+	}
+	c.placements = append(c.placements, options.requestProperties.Options[ResultsErrorReportingPlacementValue])
+	return io.NopCloser(strings.NewReader(concurrentQueryResponse)), nil
+}
+
+func (c *capabilitiesConn) Close() error {
+	return nil
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		serviceType string
+		want        Capabilities
+	}{
+		{
+			desc:        "Engine",
+			serviceType: ServiceTypeEngine,
+			want: Capabilities{BuildVersion: "1.0.9000.1", ServiceType: ServiceTypeEngine, ProductVersion: "KustoRelease_2024.01.01",
+				ServiceOffering: `{"Type":"Azure Data Explorer"}`, StreamingIngest: true, InStreamErrors: true},
+		},
+		{
+			desc:        "Data management",
+			serviceType: ServiceTypeDataManagement,
+			want: Capabilities{BuildVersion: "1.0.9000.1", ServiceType: ServiceTypeDataManagement, ProductVersion: "KustoRelease_2024.01.01",
+				ServiceOffering: `{"Type":"Azure Data Explorer"}`},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &capabilitiesConn{serviceType: test.serviceType}
+			client := &Client{conn: conn}
+
+			for i := 0; i < 2; i++ {
+				caps, err := client.Capabilities(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, test.want, caps)
+			}
+			assert.Equal(t, 1, conn.commands, "the capabilities are read once")
+		})
+	}
+}
+
+func TestCapabilitiesConcurrent(t *testing.T) {
+	t.Parallel()
+
+	conn := &capabilitiesConn{serviceType: ServiceTypeEngine, started: make(chan struct{}, 1), release: make(chan struct{})}
+	client := &Client{conn: conn}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			caps, err := client.Capabilities(context.Background())
+			assert.NoError(t, err)
+			assert.True(t, caps.InStreamErrors)
+		}()
+	}
+
+	// Queries don't wait for the capabilities while they are read.
+	<-conn.started
+	assert.True(t, client.capabilities.inStreamErrors())
+
+	close(conn.release)
+	wg.Wait()
+	assert.Equal(t, 1, conn.commands, "the capabilities are read once")
+}
+
+func TestInStreamErrorsNegotiation(t *testing.T) {
+	t.Parallel()
+
+	conn := &capabilitiesConn{serviceType: ServiceTypeEngine}
+	client := &Client{conn: conn}
+
+	for i := 0; i < 2; i++ {
+		dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T"), ResultsErrorsInStream())
+		require.NoError(t, err)
+		_, err = dataset.ToDataset()
+		require.NoError(t, err)
+	}
+
+	// The service answered with v2.0, so in-stream errors are no longer requested.
+	assert.Equal(t, []interface{}{ResultsErrorReportingPlacementInData, ResultsErrorReportingPlacementEndOfTable}, conn.placements)
+
+	caps, err := client.Capabilities(context.Background())
+	require.NoError(t, err)
+	assert.False(t, caps.InStreamErrors)
+	assert.True(t, caps.StreamingIngest)
+}
+
+func TestWithCapabilities(t *testing.T) {
+	t.Parallel()
+
+	conn := &capabilitiesConn{serviceType: ServiceTypeEngine}
+	client := &Client{conn: conn}
+	WithCapabilities(Capabilities{ServiceType: ServiceTypeEngine})(client)
+
+	caps, err := client.Capabilities(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Capabilities{ServiceType: ServiceTypeEngine}, caps)
+	assert.Equal(t, 0, conn.commands)
+
+	dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T"), ResultsErrorsInStream())
+	require.NoError(t, err)
+	_, err = dataset.ToDataset()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{ResultsErrorReportingPlacementEndOfTable}, conn.placements)
+}
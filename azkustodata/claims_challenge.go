@@ -0,0 +1,158 @@
+package azkustodata
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
+)
+
+// ClaimsChallenge is a claims challenge returned by the service, for instance when Continuous Access Evaluation (CAE)
+// revoked the token of the client because the user was disabled or their location changed.
+// The client acquires a new token with the claims and sends the request again once.
+type ClaimsChallenge struct {
+	// Endpoint is the URL of the request that was challenged.
+	Endpoint string
+	// Claims are the decoded claims the new token must satisfy.
+	Claims string
+	// Retried reports whether the request was sent again with the new token. Requests whose payload can't be read
+	// again, such as streaming ingestion, are not retried, but the following requests use the new token.
+	Retried bool
+	// Err is the error that prevented the retry, if any, such as a failure to acquire the new token.
+	Err error
+}
+
+// WithClaimsChallengeHook sets a function that is called for every claims challenge returned by the service, after the
+// client handled it, to observe CAE events. It must be safe for concurrent use.
+func WithClaimsChallengeHook(hook func(ClaimsChallenge)) Option {
+	return func(c *Client) {
+		c.onClaimsChallenge = hook
+	}
+}
+
+// replayableBody is a request body that can be sent again after a claims challenge.
+type replayableBody struct {
+	*bytes.Reader
+}
+
+func (replayableBody) Close() error {
+	return nil
+}
+
+// parseClaimsChallenge returns the decoded claims of the claims challenge in the WWW-Authenticate headers of a
+// response, if there is one. A claims challenge looks like:
+//
+//	Bearer realm="", authorization_uri="https://login.microsoftonline.com/common/oauth2/authorize",
+//	error="insufficient_claims", claims="eyJhY2Nlc3NfdG9rZW4iOnsibmJmIjp7ImVzc2VudGlhbCI6dHJ1ZX19fQ=="
+func parseClaimsChallenge(header http.Header) (string, bool) {
+	for _, challenge := range header.Values("WWW-Authenticate") {
+		params := challengeParams(challenge)
+		if params["error"] != "insufficient_claims" || params["claims"] == "" {
+			continue
+		}
+
+		encoded := params["claims"]
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if claims, err := encoding.DecodeString(encoded); err == nil {
+				return string(claims), true
+			}
+		}
+	}
+	return "", false
+}
+
+// challengeParams returns the parameters of a WWW-Authenticate challenge, by lowercase name.
+func challengeParams(challenge string) map[string]string {
+	params := map[string]string{}
+	// Skip the scheme.
+	if i := strings.IndexByte(challenge, ' '); i >= 0 {
+		challenge = challenge[i+1:]
+	}
+
+	for {
+		challenge = strings.TrimLeft(challenge, " ,")
+		eq := strings.IndexByte(challenge, '=')
+		if eq < 0 {
+			return params
+		}
+		name := strings.ToLower(strings.TrimSpace(challenge[:eq]))
+		challenge = strings.TrimLeft(challenge[eq+1:], " ")
+
+		var value string
+		if strings.HasPrefix(challenge, `"`) {
+			end := strings.IndexByte(challenge[1:], '"')
+			if end < 0 {
+				params[name] = challenge[1:]
+				return params
+			}
+			value = challenge[1 : end+1]
+			challenge = challenge[end+2:]
+		} else {
+			end := strings.IndexByte(challenge, ',')
+			if end < 0 {
+				end = len(challenge)
+			}
+			value = strings.TrimSpace(challenge[:end])
+			challenge = challenge[end:]
+		}
+		params[name] = value
+	}
+}
+
+// handleClaimsChallenge acquires a new token if resp is a claims challenge, and sends the request again with it when
+// its body can be read again. It returns the response to use, which is resp if the request was not sent again.
+func (c *Conn) handleClaimsChallenge(ctx context.Context, resp *http.Response, endpoint *url.URL, body io.ReadCloser, headers http.Header) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized || c.auth.TokenProvider == nil || !c.auth.TokenProvider.AuthorizationRequired() {
+		return resp, nil
+	}
+	claims, ok := parseClaimsChallenge(resp.Header)
+	if !ok {
+		return resp, nil
+	}
+
+	challenge := ClaimsChallenge{Endpoint: endpoint.String(), Claims: claims}
+	log.Writef(log.EventAuth, "%s returned a claims challenge, acquiring a new token", endpoint.Host)
+	if c.onClaimsChallenge != nil {
+		defer func() { c.onClaimsChallenge(challenge) }()
+	}
+
+	token, tokenType, err := c.auth.TokenProvider.AcquireTokenWithClaims(ctx, claims)
+	if err != nil {
+		challenge.Err = fmt.Errorf("could not acquire a token for the claims challenge: %w", err)
+		log.Write(log.EventAuth, challenge.Err.Error())
+		return resp, nil
+	}
+
+	replayable, ok := body.(replayableBody)
+	if !ok {
+		return resp, nil
+	}
+	if _, err := replayable.Seek(0, io.SeekStart); err != nil {
+		challenge.Err = err
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	headers.Set("Authorization", fmt.Sprintf("%s %s", tokenType, token))
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    endpoint,
+		Header: headers,
+		Body:   replayable,
+	}
+	retried, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		challenge.Err = err
+		return nil, err
+	}
+	challenge.Retried = true
+	return retried, nil
+}
@@ -0,0 +1,157 @@
+package azkustodata
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClaims = `{"access_token":{"nbf":{"essential":true,"value":"1700000000"}}}`
+
+func claimsChallengeHeader(claims string) string {
+	return `Bearer realm="", authorization_uri="https://login.microsoftonline.com/common/oauth2/authorize", error="insufficient_claims", claims="` +
+		base64.StdEncoding.EncodeToString([]byte(claims)) + `"`
+}
+
+// claimsCredential returns a token that depends on the claims it was requested with.
+type claimsCredential struct {
+	mu     sync.Mutex
+	claims []string
+}
+
+func (c *claimsCredential) GetToken(_ context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.claims = append(c.claims, options.Claims)
+	if options.Claims != "" {
+		return azcore.AccessToken{Token: "fresh"}, nil
+	}
+	return azcore.AccessToken{Token: "revoked"}, nil
+}
+
+func TestParseClaimsChallenge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc       string
+		challenges []string
+		want       string
+		wantOk     bool
+	}{
+		{desc: "no challenge"},
+		{desc: "other error", challenges: []string{`Bearer realm="", error="invalid_token"`}},
+		{desc: "claims challenge", challenges: []string{claimsChallengeHeader(testClaims)}, want: testClaims, wantOk: true},
+		{
+			desc:       "unpadded claims",
+			challenges: []string{`Bearer error=insufficient_claims, claims="` + base64.RawURLEncoding.EncodeToString([]byte(testClaims)) + `"`},
+			want:       testClaims,
+			wantOk:     true,
+		},
+		{
+			desc:       "second challenge",
+			challenges: []string{`PoP nonce="abc"`, claimsChallengeHeader(testClaims)},
+			want:       testClaims,
+			wantOk:     true,
+		},
+		{desc: "invalid claims", challenges: []string{`Bearer error="insufficient_claims", claims="!!!"`}},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			header := http.Header{}
+			for _, c := range test.challenges {
+				header.Add("WWW-Authenticate", c)
+			}
+			got, ok := parseClaimsChallenge(header)
+			assert.Equal(t, test.wantOk, ok)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestClaimsChallenge(t *testing.T) {
+	t.Parallel()
+
+	var tokens, bodies []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.Header().Set("WWW-Authenticate", claimsChallengeHeader(testClaims))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1]]}]}`))
+	}))
+	defer server.Close()
+
+	cred := &claimsCredential{}
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{tokenCred: cred, tokenScheme: "Bearer"}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+
+	var challenges []ClaimsChallenge
+	conn.onClaimsChallenge = func(c ClaimsChallenge) {
+		challenges = append(challenges, c)
+	}
+	client := &Client{conn: conn}
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", testClaims}, cred.claims)
+	assert.Equal(t, []string{"Bearer revoked", "Bearer fresh"}, tokens)
+	require.Len(t, bodies, 2)
+	assert.NotEmpty(t, bodies[0])
+	assert.Equal(t, bodies[0], bodies[1])
+
+	require.Len(t, challenges, 1)
+	assert.Equal(t, testClaims, challenges[0].Claims)
+	assert.True(t, challenges[0].Retried)
+	assert.NoError(t, challenges[0].Err)
+}
+
+func TestClaimsChallengeCustomToken(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("WWW-Authenticate", claimsChallengeHeader(testClaims))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{customToken: "token", tokenScheme: "Bearer"}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+
+	var challenges []ClaimsChallenge
+	conn.onClaimsChallenge = func(c ClaimsChallenge) {
+		challenges = append(challenges, c)
+	}
+	client := &Client{conn: conn}
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+
+	require.Len(t, challenges, 1)
+	assert.False(t, challenges[0].Retried)
+	assert.Error(t, challenges[0].Err)
+}
@@ -0,0 +1,5 @@
+// Package example holds the code generated by kustogen from schema.json, to show what it looks like and make sure it
+// compiles.
+package example
+
+//go:generate go run github.com/Azure/azure-kusto-go/azkustodata/cmd/kustogen -schema schema.json -out tables_gen.go
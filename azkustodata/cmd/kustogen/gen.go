@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+)
+
+// schema is the schema of a table, as the Schema column of `.show table T schema as json`, or of a function, as the
+// Schema column of `.show function F schema as json`.
+type schema struct {
+	Name      string
+	DocString string
+	// OrderedColumns are the columns of a table.
+	OrderedColumns []schemaColumn
+	// InputParameters and OutputColumns are the parameters and the columns of the results of a function.
+	InputParameters []schemaColumn
+	OutputColumns   []schemaColumn
+}
+
+func (s schema) isFunction() bool {
+	return len(s.OrderedColumns) == 0 && (len(s.OutputColumns) > 0 || len(s.InputParameters) > 0)
+}
+
+// schemaColumn is a column of a table or function, or a parameter of a function.
+type schemaColumn struct {
+	Name      string
+	CslType   string
+	DocString string
+}
+
+// parseSchemas parses a schema file, which holds a single schema or an array of schemas.
+func parseSchemas(b []byte) ([]schema, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var schemas []schema
+		if err := json.Unmarshal(b, &schemas); err != nil {
+			return nil, err
+		}
+		return schemas, nil
+	}
+	var s schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return []schema{s}, nil
+}
+
+// genOptions are the options of generate.
+type genOptions struct {
+	pkg string
+	// pointers generates pointer fields for the types that can be null, so that nulls can be told from zero values.
+	pointers bool
+}
+
+// goType is the Go type a Kusto type is decoded into, and the package it needs.
+type goType struct {
+	name string
+	pkg  string
+	// nullable is true for the types whose zero value can't be told from null.
+	nullable bool
+	// add is the kql.Parameters method that adds a parameter of the type.
+	add string
+}
+
+var goTypes = map[types.Column]goType{
+	types.Bool:     {name: "bool", nullable: true, add: "AddBool"},
+	types.DateTime: {name: "time.Time", pkg: "time", nullable: true, add: "AddDateTime"},
+	types.Decimal:  {name: "decimal.Decimal", pkg: "github.com/shopspring/decimal", nullable: true, add: "AddDecimal"},
+	types.Dynamic:  {name: "json.RawMessage", pkg: "encoding/json", add: "AddSerializedDynamic"},
+	types.GUID:     {name: "uuid.UUID", pkg: "github.com/google/uuid", nullable: true, add: "AddGUID"},
+	types.Int:      {name: "int32", nullable: true, add: "AddInt"},
+	types.Long:     {name: "int64", nullable: true, add: "AddLong"},
+	types.Real:     {name: "float64", nullable: true, add: "AddReal"},
+	types.String:   {name: "string", add: "AddString"},
+	types.Timespan: {name: "time.Duration", pkg: "time", nullable: true, add: "AddTimespan"},
+}
+
+// genType is a generated struct.
+type genType struct {
+	// Name is the name of the table or function.
+	Name string
+	// GoName is the name of the struct.
+	GoName string
+	Doc    []string
+	Fields []genField
+	// Function is set for functions.
+	Function *genFunction
+}
+
+type genField struct {
+	Name   string
+	GoName string
+	Type   string
+	Tag    string
+	Doc    []string
+}
+
+type genFunction struct {
+	// GoName is the name of the Go function that calls the function.
+	GoName string
+	// Call is the KQL that calls the function with its parameters.
+	Call   string
+	Params []genParam
+}
+
+type genParam struct {
+	Name   string
+	GoName string
+	Type   string
+	Add    string
+}
+
+// generate returns the Go source of the types of the schemas.
+func generate(schemas []schema, opts genOptions) ([]byte, error) {
+	imports := map[string]bool{
+		"context": true,
+		"github.com/Azure/azure-kusto-go/azkustodata":       true,
+		"github.com/Azure/azure-kusto-go/azkustodata/query": true,
+	}
+	names := map[string]string{}
+	var gen []genType
+
+	for _, s := range schemas {
+		columns := s.OrderedColumns
+		if s.isFunction() {
+			columns = s.OutputColumns
+		}
+		if s.Name == "" {
+			return nil, fmt.Errorf("a schema has no name")
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("%s has no columns", s.Name)
+		}
+
+		t := genType{Name: s.Name, GoName: goName(s.Name), Doc: docLines(s.DocString)}
+		if s.isFunction() {
+			t.GoName += "Result"
+		}
+		if other, ok := names[t.GoName]; ok {
+			return nil, fmt.Errorf("%s and %s both generate the type %s", other, s.Name, t.GoName)
+		}
+		names[t.GoName] = s.Name
+
+		fieldNames := map[string]bool{}
+		if !s.isFunction() {
+			// The constants of the columns would clash with the constant of the name of the table.
+			fieldNames["Table"] = true
+		}
+		for _, c := range columns {
+			gt, err := columnType(s.Name, c)
+			if err != nil {
+				return nil, err
+			}
+			typeName := gt.name
+			if opts.pointers && gt.nullable {
+				typeName = "*" + typeName
+			}
+			if gt.pkg != "" {
+				imports[gt.pkg] = true
+			}
+
+			f := genField{Name: c.Name, GoName: unique(goName(c.Name), fieldNames), Type: typeName, Tag: structTag(c.Name), Doc: docLines(c.DocString)}
+			t.Fields = append(t.Fields, f)
+		}
+
+		if s.isFunction() {
+			fn, err := function(s, imports)
+			if err != nil {
+				return nil, err
+			}
+			t.Function = fn
+		}
+		gen = append(gen, t)
+	}
+
+	// The imports of the standard library come first, as goimports groups them.
+	var std, others []string
+	for p := range imports {
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			others = append(others, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+
+	buf := bytes.Buffer{}
+	err := sourceTemplate.Execute(&buf, struct {
+		Package string
+		Std     []string
+		Imports []string
+		Types   []genType
+	}{Package: opts.pkg, Std: std, Imports: others, Types: gen})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("the generated code is not valid: %w", err)
+	}
+	return src, nil
+}
+
+// function returns the call of a function.
+func function(s schema, imports map[string]bool) (*genFunction, error) {
+	imports["github.com/Azure/azure-kusto-go/azkustodata/kql"] = true
+	fn := &genFunction{GoName: "Call" + goName(s.Name)}
+	// The names of the parameters of the generated functions, and of their variables.
+	goNames := map[string]bool{"ctx": true, "client": true, "db": true, "options": true, "params": true, "dataset": true, "err": true}
+	for p := range imports {
+		goNames[p[strings.LastIndex(p, "/")+1:]] = true
+	}
+	for p := range goTypes {
+		if pkg := goTypes[p].pkg; pkg != "" {
+			goNames[pkg[strings.LastIndex(pkg, "/")+1:]] = true
+		}
+	}
+	args := make([]string, 0, len(s.InputParameters))
+	for _, p := range s.InputParameters {
+		if kql.RequiresQuoting(p.Name) {
+			return nil, fmt.Errorf("parameter %q of function %s is not supported, as it can't be passed as a query parameter", p.Name, s.Name)
+		}
+		gt, err := columnType(s.Name, p)
+		if err != nil {
+			return nil, err
+		}
+		typeName := gt.name
+		if gt.name == "json.RawMessage" {
+			typeName = "[]byte"
+		} else if gt.pkg != "" {
+			imports[gt.pkg] = true
+		}
+		fn.Params = append(fn.Params, genParam{Name: p.Name, GoName: unique(goParamName(p.Name), goNames), Type: typeName, Add: gt.add})
+		args = append(args, p.Name)
+	}
+
+	name := s.Name
+	if kql.RequiresQuoting(name) {
+		name = "['" + strings.ReplaceAll(name, "'", "\\'") + "']"
+	}
+	fn.Call = strconv.Quote(name + "(" + strings.Join(args, ", ") + ")")
+	return fn, nil
+}
+
+func columnType(schemaName string, c schemaColumn) (goType, error) {
+	gt, ok := goTypes[types.NormalizeColumn(c.CslType)]
+	if !ok {
+		return goType{}, fmt.Errorf("%s of %s is of type %q, which is not supported", c.Name, schemaName, c.CslType)
+	}
+	return gt, nil
+}
+
+// goName returns an exported Go identifier for a Kusto name, removing the characters that can't be part of identifiers
+// and capitalizing the words they separate, e.g. "event-type" becomes "EventType".
+func goName(name string) string {
+	b := strings.Builder{}
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// goParamName returns an unexported Go identifier for a Kusto name.
+func goParamName(name string) string {
+	s := []rune(goName(name))
+	s[0] = unicode.ToLower(s[0])
+	p := string(s)
+	if token.IsKeyword(p) {
+		p += "_"
+	}
+	return p
+}
+
+// unique returns name, with a numeric suffix if it is in used already, and adds it to used.
+func unique(name string, used map[string]bool) string {
+	u := name
+	for i := 2; used[u]; i++ {
+		u = name + strconv.Itoa(i)
+	}
+	used[u] = true
+	return u
+}
+
+// structTag returns the struct tag that decodes the column name into a field.
+func structTag(name string) string {
+	tag := "kusto:" + strconv.Quote(name)
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// docLines returns the lines of a doc string.
+func docLines(doc string) []string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return nil
+	}
+	return strings.Split(doc, "\n")
+}
+
+var sourceTemplate = template.Must(template.New("source").Parse(`// Code generated by kustogen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Std}}
+	"{{.}}"
+{{- end}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range $t := .Types}}
+{{- if $t.Function}}
+// {{$t.GoName}} is a row of the results of the {{$t.Name}} function.
+{{- else}}
+// {{$t.GoName}}Table is the name of the {{$t.Name}} table.
+const {{$t.GoName}}Table = {{printf "%q" $t.Name}}
+
+// {{$t.GoName}} is a row of the {{$t.Name}} table.
+{{- end}}
+{{- if $t.Doc}}
+//
+{{- range $t.Doc}}
+// {{.}}
+{{- end}}
+{{- end}}
+type {{$t.GoName}} struct {
+{{- range $t.Fields}}
+{{- range .Doc}}
+	// {{.}}
+{{- end}}
+	{{.GoName}} {{.Type}} {{.Tag}}
+{{- end}}
+}
+
+// The names of the columns of {{$t.GoName}}.
+const (
+{{- range $t.Fields}}
+	{{$t.GoName}}{{.GoName}} = {{printf "%q" .Name}}
+{{- end}}
+)
+{{if $t.Function}}
+// {{$t.Function.GoName}} calls the {{$t.Name}} function, passing its arguments as query parameters, and returns its rows.
+func {{$t.Function.GoName}}(ctx context.Context, client *azkustodata.Client, db string{{range $t.Function.Params}}, {{.GoName}} {{.Type}}{{end}}, options ...azkustodata.QueryOption) ([]{{$t.GoName}}, error) {
+{{- if $t.Function.Params}}
+	params := kql.NewParameters(){{range $t.Function.Params}}.{{.Add}}({{printf "%q" .Name}}, {{.GoName}}){{end}}
+	options = append(options, azkustodata.QueryParameters(params))
+{{- end}}
+	dataset, err := client.Query(ctx, db, kql.New({{$t.Function.Call}}), options...)
+	if err != nil {
+		return nil, err
+	}
+	return query.ToStructs[{{$t.GoName}}](dataset)
+}
+{{else}}
+// Query{{$t.GoName}} runs a query that returns rows of the {{$t.Name}} table, and decodes them.
+func Query{{$t.GoName}}(ctx context.Context, client *azkustodata.Client, db string, stmt azkustodata.Statement, options ...azkustodata.QueryOption) ([]{{$t.GoName}}, error) {
+	dataset, err := client.Query(ctx, db, stmt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return query.ToStructs[{{$t.GoName}}](dataset)
+}
+{{end}}
+{{- end}}`))
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateExample checks that the example package is up to date. Run go generate in it after changing the
+// generator.
+func TestGenerateExample(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("example", "schema.json"))
+	require.NoError(t, err)
+	schemas, err := parseSchemas(b)
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+	assert.False(t, schemas[0].isFunction())
+	assert.True(t, schemas[1].isFunction())
+
+	src, err := generate(schemas, genOptions{pkg: "example"})
+	require.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join("example", "tables_gen.go"))
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(src))
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	schemas, err := parseSchemas([]byte(`{"Name": "T", "OrderedColumns": [
+		{"Name": "A", "CslType": "long"}, {"Name": "B", "CslType": "string"}, {"Name": "C", "CslType": "decimal"},
+		{"Name": "a", "CslType": "bool"}, {"Name": "Quote\"Back` + "`" + `", "CslType": "real"}]}`))
+	require.NoError(t, err)
+
+	src, err := generate(schemas, genOptions{pkg: "p", pointers: true})
+	require.NoError(t, err)
+	// Ignore the alignment of the fields.
+	code := strings.Join(strings.Fields(string(src)), " ")
+	// Nullable types are pointers, but strings are never null.
+	assert.Contains(t, code, "A *int64 `kusto:\"A\"`")
+	assert.Contains(t, code, "B string `kusto:\"B\"`")
+	assert.Contains(t, code, "C *decimal.Decimal `kusto:\"C\"`")
+	assert.Contains(t, code, `"github.com/shopspring/decimal"`)
+	// Names that clash get a suffix.
+	assert.Contains(t, code, "A2 *bool `kusto:\"a\"`")
+	assert.Contains(t, code, "QuoteBack *float64 \"kusto:\\\"Quote\\\\\\\"Back`\\\"\"")
+
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "no name", schema: `{"OrderedColumns": [{"Name": "A", "CslType": "long"}]}`, want: "no name"},
+		{name: "no columns", schema: `{"Name": "T"}`, want: "no columns"},
+		{name: "bad type", schema: `{"Name": "T", "OrderedColumns": [{"Name": "A", "CslType": "blob"}]}`, want: "not supported"},
+		{name: "bad parameter", schema: `{"Name": "F", "InputParameters": [{"Name": "a b", "CslType": "long"}], "OutputColumns": [{"Name": "A", "CslType": "long"}]}`, want: "not supported"},
+		{name: "same type", schema: `[{"Name": "T", "OrderedColumns": [{"Name": "A", "CslType": "long"}]}, {"Name": "t", "OrderedColumns": [{"Name": "A", "CslType": "long"}]}]`, want: "both generate"},
+	}
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			schemas, err := parseSchemas([]byte(test.schema))
+			require.NoError(t, err)
+			_, err = generate(schemas, genOptions{pkg: "p"})
+			require.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), test.want), err.Error())
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "EventType", goName("event-type"))
+	assert.Equal(t, "EventType", goName("event type"))
+	assert.Equal(t, "Name", goName("name"))
+	assert.Equal(t, "X1st", goName("1st"))
+	assert.Equal(t, "X", goName("$"))
+	assert.Equal(t, "type_", goParamName("type"))
+	assert.Equal(t, "startTime", goParamName("StartTime"))
+}
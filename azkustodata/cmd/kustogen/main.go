@@ -0,0 +1,159 @@
+/*
+Command kustogen generates typed Go code from the schemas of Kusto tables and functions: for every table, a struct with a
+field for every column, constants with the names of the table and its columns, and a function that runs a query and
+decodes its rows into the struct; for every function, a struct for the rows of its results and a function that calls it
+with typed arguments.
+
+The schemas are read from a JSON file, holding the Schema column of `.show table T schema as json` or
+`.show function F schema as json`, or an array of them:
+
+	kustogen -schema schema.json -out tables_gen.go
+
+or from a live cluster, authenticating with the default Azure credential - environment variables, managed identity or
+the Azure CLI:
+
+	kustogen -cluster https://mycluster.kusto.windows.net -database db -tables StormEvents,Logs -functions MyFunc
+
+It is meant to be run with go generate, which sets the package of the generated file:
+
+	//go:generate go run github.com/Azure/azure-kusto-go/azkustodata/cmd/kustogen -schema schema.json -out tables_gen.go
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "kustogen:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		schemaFile = flag.String("schema", "", "a JSON file with the schemas of tables and functions")
+		cluster    = flag.String("cluster", "", "the URL of a cluster to read the schemas from, instead of a file")
+		database   = flag.String("database", "", "the database of the tables and functions on the cluster")
+		tables     = flag.String("tables", "", "a comma separated list of the tables to read from the cluster")
+		functions  = flag.String("functions", "", "a comma separated list of the functions to read from the cluster")
+		pkg        = flag.String("package", os.Getenv("GOPACKAGE"), "the package of the generated code, by default the one of go generate")
+		out        = flag.String("out", "", "the file to write the generated code to, by default the standard output")
+		pointers   = flag.Bool("pointers", false, "generate pointer fields for the columns that can be null")
+		timeout    = flag.Duration("timeout", time.Minute, "the timeout of the commands that read the schemas from the cluster")
+	)
+	flag.Parse()
+
+	if *pkg == "" {
+		return fmt.Errorf("-package is required outside of go generate")
+	}
+
+	var schemas []schema
+	switch {
+	case *schemaFile != "" && *cluster != "":
+		return fmt.Errorf("-schema and -cluster can't be used together")
+	case *schemaFile != "":
+		b, err := os.ReadFile(*schemaFile)
+		if err != nil {
+			return err
+		}
+		schemas, err = parseSchemas(b)
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %w", *schemaFile, err)
+		}
+	case *cluster != "":
+		if *tables == "" && *functions == "" {
+			return fmt.Errorf("-cluster requires -tables or -functions")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		var err error
+		schemas, err = fetchSchemas(ctx, *cluster, *database, split(*tables), split(*functions))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("-schema or -cluster is required")
+	}
+
+	src, err := generate(schemas, genOptions{pkg: *pkg, pointers: *pointers})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// schemaRow is a row of `.show table T schema as json` and `.show function F schema as json`.
+type schemaRow struct {
+	Schema    string
+	DocString string
+}
+
+// fetchSchemas reads the schemas of tables and functions from a cluster.
+func fetchSchemas(ctx context.Context, cluster, db string, tables, functions []string) ([]schema, error) {
+	client, err := azkustodata.New(azkustodata.NewConnectionStringBuilder(cluster).WithDefaultAzureCredential())
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var schemas []schema
+	fetch := func(name string, cmd *kql.Builder) error {
+		dataset, err := client.Mgmt(ctx, db, cmd)
+		if err != nil {
+			return fmt.Errorf("could not read the schema of %s: %w", name, err)
+		}
+		rows, err := query.ToStructs[schemaRow](dataset)
+		if err != nil {
+			return fmt.Errorf("could not read the schema of %s: %w", name, err)
+		}
+		if len(rows) != 1 {
+			return fmt.Errorf("expected a single row for the schema of %s, got %d", name, len(rows))
+		}
+		s, err := parseSchemas([]byte(rows[0].Schema))
+		if err != nil || len(s) != 1 {
+			return fmt.Errorf("could not parse the schema of %s: %v", name, err)
+		}
+		if s[0].DocString == "" {
+			s[0].DocString = rows[0].DocString
+		}
+		schemas = append(schemas, s[0])
+		return nil
+	}
+
+	for _, t := range tables {
+		if err := fetch(t, kql.New(".show table ").AddTable(t).AddLiteral(" schema as json")); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range functions {
+		if err := fetch(f, kql.New(".show function ").AddTable(f).AddLiteral(" schema as json")); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
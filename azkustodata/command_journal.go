@@ -0,0 +1,451 @@
+package azkustodata
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+)
+
+const (
+	// defaultJournalCapacity is the default number of commands a CommandJournal holds before it drops the oldest.
+	defaultJournalCapacity = 1000
+	// defaultJournalAttempts is the default number of times a CommandJournal runs a command that fails transiently.
+	defaultJournalAttempts = 3
+	// defaultJournalRetryDelay is the default delay before the first retry of a command, doubled for every retry.
+	defaultJournalRetryDelay = time.Second
+)
+
+// JournaledCommand is a management command enqueued in a CommandJournal.
+type JournaledCommand struct {
+	// ID is the sequence number of the command in the journal.
+	ID uint64
+	// Database is the database the command runs against.
+	Database string
+	// Command is the text of the command.
+	Command string
+	// Enqueued is the time the command was enqueued.
+	Enqueued time.Time
+
+	statement Statement
+}
+
+// JournalOption is an option for a CommandJournal.
+type JournalOption func(o *journalOptions)
+
+type journalOptions struct {
+	capacity     int
+	path         string
+	attempts     int
+	retryDelay   time.Duration
+	onDrop       func(JournaledCommand, error)
+	queryOptions []QueryOption
+}
+
+// JournalCapacity sets the number of commands the journal holds. When it is full, the oldest command is dropped to
+// make room for the new one. The default is 1000.
+func JournalCapacity(n int) JournalOption {
+	return func(o *journalOptions) {
+		o.capacity = n
+	}
+}
+
+// JournalFile makes the journal durable, by writing the pending commands to a file. The commands that are still in the
+// file when the journal is created, because the process exited before they ran, are run again.
+// Only the database and the text of the commands are kept, so commands with lists sent as query parameters, see
+// kql.Builder.AddList, can't be enqueued.
+func JournalFile(path string) JournalOption {
+	return func(o *journalOptions) {
+		o.path = path
+	}
+}
+
+// JournalRetries sets the number of times a command that fails transiently is run before it is dropped, and the delay
+// before its first retry, which doubles for every retry. The default is 3 attempts, starting with a delay of 1 second.
+func JournalRetries(attempts int, delay time.Duration) JournalOption {
+	return func(o *journalOptions) {
+		o.attempts = attempts
+		o.retryDelay = delay
+	}
+}
+
+// OnDrop sets a callback called with every command that is dropped, and the reason: the error of its last attempt,
+// or an error of Kind KLimitsExceeded if the journal was full.
+// It is called from the goroutine of the journal, or of Enqueue, and must not block.
+func OnDrop(f func(cmd JournaledCommand, err error)) JournalOption {
+	return func(o *journalOptions) {
+		o.onDrop = f
+	}
+}
+
+// JournalQueryOptions sets the QueryOptions passed to the commands of the journal.
+func JournalQueryOptions(options ...QueryOption) JournalOption {
+	return func(o *journalOptions) {
+		o.queryOptions = append(o.queryOptions, options...)
+	}
+}
+
+// CommandJournal runs management commands in the background, so that the callers don't wait for the cluster, for
+// instance to append small markers to a table from a hot path:
+//
+//	journal.Enqueue("db", kql.New(".append Markers <| print Name=").AddString(name))
+//
+// The commands are run one at a time, in the order they were enqueued, and are retried when they fail transiently.
+// The journal is bounded: the commands that can't be run or don't fit are dropped, and reported to the callback set with
+// OnDrop. It is kept in memory, unless JournalFile is set.
+// A CommandJournal is safe for concurrent use. It must be closed with Close.
+type CommandJournal struct {
+	client  *Client
+	options journalOptions
+
+	mu      sync.Mutex
+	queue   []JournaledCommand
+	running bool
+	nextID  uint64
+	closed  bool
+	file    *os.File
+	// changed is closed and replaced whenever a command is done.
+	changed chan struct{}
+	// wake is signaled whenever a command is enqueued.
+	wake chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCommandJournal creates a CommandJournal, and starts running the commands it holds.
+func (c *Client) NewCommandJournal(options ...JournalOption) (*CommandJournal, error) {
+	opts := journalOptions{capacity: defaultJournalCapacity, attempts: defaultJournalAttempts, retryDelay: defaultJournalRetryDelay}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	if opts.capacity < 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "journal capacity must be positive, got %d", opts.capacity).SetNoRetry()
+	}
+	if opts.attempts < 1 || opts.retryDelay < 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "invalid journal retries: %d attempts, delay of %s", opts.attempts, opts.retryDelay).SetNoRetry()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &CommandJournal{
+		client:  c,
+		options: opts,
+		nextID:  1,
+		changed: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	if opts.path != "" {
+		dropped, err := j.openFile()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		j.reportFull(dropped)
+	}
+
+	go j.run()
+	return j, nil
+}
+
+// Enqueue adds a command to the journal, to be run against the database db, and returns without waiting for it.
+// It only fails if the command is invalid, the journal is closed, or the command can't be written to the journal file.
+func (j *CommandJournal) Enqueue(db string, command Statement) error {
+	if command == nil || command.String() == "" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "the command of a journal can't be empty").SetNoRetry()
+	}
+	if j.options.path != "" && command.ListParameters() != nil {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "commands with lists sent as query parameters can't be written to a journal file").SetNoRetry()
+	}
+
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "the journal is closed").SetNoRetry()
+	}
+
+	cmd := JournaledCommand{ID: j.nextID, Database: db, Command: command.String(), Enqueued: time.Now(), statement: kql.FromBuilder(command)}
+	if err := j.writeLocked(journalRecord{ID: cmd.ID, Database: cmd.Database, Command: cmd.Command, Enqueued: cmd.Enqueued}); err != nil {
+		j.mu.Unlock()
+		return err
+	}
+	j.nextID++
+	j.queue = append(j.queue, cmd)
+	dropped := j.trimLocked()
+	j.mu.Unlock()
+
+	j.reportFull(dropped)
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Len returns the number of commands in the journal, including the command being run.
+func (j *CommandJournal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n := len(j.queue)
+	if j.running {
+		n++
+	}
+	return n
+}
+
+// Close stops accepting commands, and waits for the commands of the journal to run until ctx is done.
+// The commands that didn't run are dropped, or kept in the journal file if JournalFile is set, and ctx.Err() is
+// returned.
+func (j *CommandJournal) Close(ctx context.Context) error {
+	j.mu.Lock()
+	j.closed = true
+	j.mu.Unlock()
+
+	var err error
+	for {
+		j.mu.Lock()
+		if len(j.queue) == 0 && !j.running {
+			j.mu.Unlock()
+			break
+		}
+		changed := j.changed
+		j.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-changed:
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	j.cancel()
+	<-j.done
+
+	j.mu.Lock()
+	remaining := j.queue
+	j.queue = nil
+	file := j.file
+	j.file = nil
+	j.mu.Unlock()
+
+	if file != nil {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = errors.ES(errors.OpMgmt, errors.KLocalFileSystem, "unable to close the journal file: %s", closeErr).SetNoRetry()
+		}
+	} else if j.options.onDrop != nil {
+		for _, cmd := range remaining {
+			j.options.onDrop(cmd, ctx.Err())
+		}
+	}
+	return err
+}
+
+// run runs the commands of the journal one at a time, until the journal is closed.
+func (j *CommandJournal) run() {
+	defer close(j.done)
+
+	for {
+		j.mu.Lock()
+		if len(j.queue) == 0 {
+			j.mu.Unlock()
+			select {
+			case <-j.ctx.Done():
+				return
+			case <-j.wake:
+			}
+			continue
+		}
+		cmd := j.queue[0]
+		j.queue = j.queue[1:]
+		j.running = true
+		j.mu.Unlock()
+
+		err := j.execute(cmd)
+		if err != nil && j.ctx.Err() != nil {
+			// The journal was closed while the command ran, which is kept like the other remaining commands.
+			j.mu.Lock()
+			j.queue = append([]JournaledCommand{cmd}, j.queue...)
+			j.running = false
+			j.mu.Unlock()
+			return
+		}
+		j.complete(cmd, err)
+	}
+}
+
+// execute runs a command, and retries it while it fails transiently.
+func (j *CommandJournal) execute(cmd JournaledCommand) error {
+	delay := j.options.retryDelay
+	for attempt := 1; ; attempt++ {
+		_, err := j.client.Mgmt(j.ctx, cmd.Database, cmd.statement, j.options.queryOptions...)
+		if err == nil || !errors.Retry(err) || attempt >= j.options.attempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-j.ctx.Done():
+			timer.Stop()
+			return j.ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// complete removes a command that ran from the journal file, and reports it if it failed.
+func (j *CommandJournal) complete(cmd JournaledCommand, err error) {
+	j.mu.Lock()
+	fileErr := j.writeLocked(journalRecord{ID: cmd.ID, Done: true})
+	if fileErr == nil && len(j.queue) == 0 {
+		fileErr = j.compactLocked(nil)
+	}
+	j.running = false
+	close(j.changed)
+	j.changed = make(chan struct{})
+	j.mu.Unlock()
+
+	if err == nil {
+		err = fileErr
+	}
+	if err != nil && j.options.onDrop != nil {
+		j.options.onDrop(cmd, err)
+	}
+}
+
+// trimLocked removes the oldest commands above the capacity of the journal, and returns them.
+// It must be called with the lock held.
+func (j *CommandJournal) trimLocked() []JournaledCommand {
+	over := len(j.queue) - j.options.capacity
+	if over <= 0 {
+		return nil
+	}
+
+	dropped := append([]JournaledCommand(nil), j.queue[:over]...)
+	j.queue = j.queue[over:]
+	for _, cmd := range dropped {
+		// A failure leaves the command in the file, to be run again if the journal is reopened.
+		_ = j.writeLocked(journalRecord{ID: cmd.ID, Done: true})
+	}
+	return dropped
+}
+
+func (j *CommandJournal) reportFull(dropped []JournaledCommand) {
+	if j.options.onDrop == nil {
+		return
+	}
+	for _, cmd := range dropped {
+		j.options.onDrop(cmd, errors.ES(errors.OpMgmt, errors.KLimitsExceeded, "the journal is full, the command was dropped").SetNoRetry())
+	}
+}
+
+// journalRecord is a line of a journal file. A command is added by a record with its text, and removed by a record with
+// its ID and Done set.
+type journalRecord struct {
+	ID       uint64    `json:"id"`
+	Database string    `json:"db,omitempty"`
+	Command  string    `json:"command,omitempty"`
+	Enqueued time.Time `json:"enqueued"`
+	Done     bool      `json:"done,omitempty"`
+}
+
+// openFile opens the journal file, and loads the commands it holds. It returns the commands that didn't fit.
+func (j *CommandJournal) openFile() ([]JournaledCommand, error) {
+	file, err := os.OpenFile(j.options.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KLocalFileSystem, "unable to open the journal file: %s", err).SetNoRetry()
+	}
+
+	var pending []JournaledCommand
+	index := map[uint64]int{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var record journalRecord
+		// A line that can't be decoded was only partially written, when the process exited.
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.ID >= j.nextID {
+			j.nextID = record.ID + 1
+		}
+		if record.Done {
+			if i, ok := index[record.ID]; ok {
+				pending[i].ID = 0
+			}
+			continue
+		}
+		index[record.ID] = len(pending)
+		pending = append(pending, JournaledCommand{
+			ID:        record.ID,
+			Database:  record.Database,
+			Command:   record.Command,
+			Enqueued:  record.Enqueued,
+			statement: kql.New("").AddUnsafe(record.Command),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, errors.ES(errors.OpMgmt, errors.KLocalFileSystem, "unable to read the journal file: %s", err).SetNoRetry()
+	}
+
+	j.file = file
+	for _, cmd := range pending {
+		if cmd.ID != 0 {
+			j.queue = append(j.queue, cmd)
+		}
+	}
+	dropped := j.trimLocked()
+	if err := j.compactLocked(j.queue); err != nil {
+		j.file = nil
+		_ = file.Close()
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// compactLocked rewrites the journal file with only the given commands.
+// It must be called with the lock held.
+func (j *CommandJournal) compactLocked(commands []JournaledCommand) error {
+	if j.file == nil {
+		return nil
+	}
+	if err := j.file.Truncate(0); err != nil {
+		return errors.ES(errors.OpMgmt, errors.KLocalFileSystem, "unable to compact the journal file: %s", err).SetNoRetry()
+	}
+	for _, cmd := range commands {
+		if err := j.writeLocked(journalRecord{ID: cmd.ID, Database: cmd.Database, Command: cmd.Command, Enqueued: cmd.Enqueued}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLocked appends a record to the journal file, if any.
+// It must be called with the lock held.
+func (j *CommandJournal) writeLocked(record journalRecord) error {
+	if j.file == nil {
+		return nil
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return errors.ES(errors.OpMgmt, errors.KInternal, "unable to encode the journal record: %s", err).SetNoRetry()
+	}
+	if _, err := j.file.Write(append(b, '\n')); err != nil {
+		return errors.ES(errors.OpMgmt, errors.KLocalFileSystem, "unable to write the journal file: %s", err).SetNoRetry()
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const emptyMgmtResponse = `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"String","ColumnType":"string"}],"Rows":[]}]}`
+
+// journalConn records the commands it runs, failing them with the errors of fail, and blocks while block is open.
+type journalConn struct {
+	mu       sync.Mutex
+	commands []string
+	fail     func(command string, attempt int) error
+	attempts map[string]int
+	started  chan string
+	block    chan struct{}
+}
+
+func (f *journalConn) rawQuery(ctx context.Context, _ callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	if f.started != nil {
+		f.started <- query.String()
+	}
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.attempts == nil {
+		f.attempts = map[string]int{}
+	}
+	f.attempts[query.String()]++
+	if f.fail != nil {
+		if err := f.fail(query.String(), f.attempts[query.String()]); err != nil {
+			return nil, err
+		}
+	}
+	f.commands = append(f.commands, query.String())
+	return io.NopCloser(strings.NewReader(emptyMgmtResponse)), nil
+}
+
+func (f *journalConn) Close() error {
+	return nil
+}
+
+func (f *journalConn) ran() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+// dropRecorder records the commands dropped by a journal.
+type dropRecorder struct {
+	mu      sync.Mutex
+	dropped []string
+	errs    []error
+}
+
+func (d *dropRecorder) onDrop(cmd JournaledCommand, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dropped = append(d.dropped, cmd.Command)
+	d.errs = append(d.errs, err)
+}
+
+func TestCommandJournal(t *testing.T) {
+	t.Parallel()
+
+	conn := &journalConn{fail: func(command string, attempt int) error {
+		switch {
+		case command == ".transient" && attempt < 3:
+			return errors.ES(errors.OpMgmt, errors.KHTTPError, "transient")
+		case command == ".permanent":
+			return errors.ES(errors.OpMgmt, errors.KHTTPError, "permanent").SetNoRetry()
+		}
+		return nil
+	}}
+	drops := &dropRecorder{}
+	journal, err := (&Client{conn: conn}).NewCommandJournal(JournalRetries(3, time.Millisecond), OnDrop(drops.onDrop))
+	require.NoError(t, err)
+
+	for _, cmd := range []string{".first", ".transient", ".permanent", ".last"} {
+		require.NoError(t, journal.Enqueue("db", kql.New("").AddUnsafe(cmd)))
+	}
+	require.NoError(t, journal.Close(context.Background()))
+
+	assert.Equal(t, []string{".first", ".transient", ".last"}, conn.ran())
+	assert.Equal(t, 3, conn.attempts[".transient"])
+	assert.Equal(t, 1, conn.attempts[".permanent"])
+	assert.Equal(t, []string{".permanent"}, drops.dropped)
+	assert.Zero(t, journal.Len())
+
+	err = journal.Enqueue("db", kql.New(".closed"))
+	require.Error(t, err)
+	assert.False(t, errors.Retry(err))
+}
+
+func TestCommandJournalFull(t *testing.T) {
+	t.Parallel()
+
+	conn := &journalConn{started: make(chan string, 10), block: make(chan struct{})}
+	drops := &dropRecorder{}
+	journal, err := (&Client{conn: conn}).NewCommandJournal(JournalCapacity(2), OnDrop(drops.onDrop))
+	require.NoError(t, err)
+
+	require.NoError(t, journal.Enqueue("db", kql.New(".a")))
+	assert.Equal(t, ".a", <-conn.started)
+
+	// .a is running, so the journal holds two more commands, and drops the oldest.
+	for _, cmd := range []string{".b", ".c", ".d"} {
+		require.NoError(t, journal.Enqueue("db", kql.New("").AddUnsafe(cmd)))
+	}
+	assert.Equal(t, 3, journal.Len())
+	require.Equal(t, []string{".b"}, drops.dropped)
+	assert.Equal(t, errors.KLimitsExceeded, drops.errs[0].(*errors.Error).Kind)
+
+	close(conn.block)
+	require.NoError(t, journal.Close(context.Background()))
+	assert.Equal(t, []string{".a", ".c", ".d"}, conn.ran())
+}
+
+func TestCommandJournalFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal")
+
+	// The first journal can't reach the cluster, and is closed with its commands pending.
+	blocked := &journalConn{started: make(chan string, 10), block: make(chan struct{})}
+	drops := &dropRecorder{}
+	journal, err := (&Client{conn: blocked}).NewCommandJournal(JournalFile(path), OnDrop(drops.onDrop))
+	require.NoError(t, err)
+	require.NoError(t, journal.Enqueue("db1", kql.New(".a")))
+	require.NoError(t, journal.Enqueue("db2", kql.New(".b")))
+	assert.Equal(t, ".a", <-blocked.started)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, journal.Close(ctx), context.Canceled)
+	assert.Empty(t, drops.dropped)
+
+	// The commands are run by the next journal.
+	conn := &journalConn{}
+	journal, err = (&Client{conn: conn}).NewCommandJournal(JournalFile(path), OnDrop(drops.onDrop))
+	require.NoError(t, err)
+	require.NoError(t, journal.Close(context.Background()))
+	assert.Equal(t, []string{".a", ".b"}, conn.ran())
+	assert.Empty(t, drops.dropped)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestCommandJournalErrors(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &journalConn{}}
+	for _, options := range [][]JournalOption{
+		{JournalCapacity(0)},
+		{JournalRetries(0, time.Second)},
+		{JournalRetries(1, -time.Second)},
+	} {
+		_, err := client.NewCommandJournal(options...)
+		require.Error(t, err)
+		assert.False(t, errors.Retry(err))
+	}
+
+	journal, err := client.NewCommandJournal(JournalFile(filepath.Join(t.TempDir(), "journal")))
+	require.NoError(t, err)
+	defer journal.Close(context.Background())
+
+	assert.Error(t, journal.Enqueue("db", kql.New("")))
+	list := kql.New(".append T <| print x=1 | where x in ").SetListParameterThreshold(1).AddLongList(1, 2, 3)
+	assert.Error(t, journal.Enqueue("db", list))
+}
@@ -0,0 +1,28 @@
+/*
+Package compat provides the Stmt, Definitions and Parameters types of the legacy SDK (github.com/Azure/azure-kusto-go/kusto)
+on top of the kql package, so that code bases that build their queries with kusto.NewStmt can move to azkustodata
+module by module, without rewriting every query at once.
+
+Replace the kusto import with this package, and build the Stmt into a kql.Builder and kql.Parameters to query:
+
+	stmt := compat.NewStmt("systemNodes | where NodeId == id").MustDefinitions(
+		compat.NewDefinitions().Must(compat.ParamTypes{
+			"id": compat.ParamType{Type: types.Int},
+		}),
+	).MustParameters(compat.NewParameters().Must(compat.QueryValues{"id": int32(1)}))
+
+	query, params, err := stmt.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	dataset, err := client.Query(ctx, "database", query, azkustodata.QueryParameters(params))
+
+Statements keep the semantics of the legacy SDK: they are immutable, only string constants can be added unless the
+Stmt was created with UnsafeStmt, and values are validated against the types of their definitions.
+The only difference is that the values of the parameters are sent by Build for every definition, so a definition that
+has no default must have a value.
+
+This package is intended for the duration of a migration only. New code should use kql.New and kql.NewParameters.
+*/
+package compat
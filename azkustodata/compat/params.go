@@ -0,0 +1,310 @@
+package compat
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ParamTypes is a list of parameter types and corresponding type data.
+type ParamTypes map[string]ParamType
+
+func (p ParamTypes) clone() ParamTypes {
+	c := make(ParamTypes, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
+// ParamType provides type and default value information about the query parameter
+type ParamType struct {
+	// Type is the type of Column type this QueryParam will represent.
+	Type types.Column
+	// Default is a default value to use if the query doesn't provide this value.
+	// The value that can be set is defined by the Type:
+	// CTBool must be a bool
+	// CTDateTime must be a time.Time
+	// CTDynamic cannot have a default value
+	// CTGuid must be an uuid.UUID
+	// CTInt must be an int32
+	// CTLong must be an int64
+	// CTReal must be an float64
+	// CTString must be a string
+	// CTTimespan must be a time.Duration
+	// CTDecimal must be a string, *big.Float or *big.Int representing a decimal value
+	Default interface{}
+}
+
+func (p ParamType) validate() error {
+	if p.Default == nil {
+		if types.NormalizeColumn(string(p.Type)) != p.Type || p.Type == "" {
+			return fmt.Errorf("the .Type was not a valid value, must be one of the values in the types package, was %s", p.Type)
+		}
+		return nil
+	}
+	if p.Type == types.Dynamic {
+		return fmt.Errorf("the .Type was %s, but Dynamic types cannot have default values", p.Type)
+	}
+	_, err := toValue(p.Type, p.Default)
+	return err
+}
+
+// declaration returns the declaration of the parameter in a query_parameters statement.
+func (p ParamType) declaration(name string) string {
+	if p.Default == nil {
+		return fmt.Sprintf("%s:%s", name, p.Type)
+	}
+	// The default was validated when the definitions were created.
+	v, _ := toValue(p.Type, p.Default)
+	return fmt.Sprintf("%s:%s = %s", name, p.Type, kql.QuoteValue(v))
+}
+
+// toValue converts v to a kusto value of type t, and fails if v is not of the Go type that corresponds to t.
+func toValue(t types.Column, v interface{}) (value.Kusto, error) {
+	wrongType := func(expected string) error {
+		return fmt.Errorf("the .Type was %s, but the value was a %T, which is not %s", t, v, expected)
+	}
+
+	switch t {
+	case types.Bool:
+		if b, ok := v.(bool); ok {
+			return value.NewBool(b), nil
+		}
+		return nil, wrongType("a bool")
+	case types.DateTime:
+		if d, ok := v.(time.Time); ok {
+			return value.NewDateTime(d), nil
+		}
+		return nil, wrongType("a time.Time")
+	case types.Dynamic:
+		d := value.DynamicFromInterface(v)
+		if d.Value == nil {
+			return nil, fmt.Errorf("the .Type was %s, but the %T value could not be marshalled into JSON", t, v)
+		}
+		return d, nil
+	case types.GUID:
+		if u, ok := v.(uuid.UUID); ok {
+			return value.NewGUID(u), nil
+		}
+		return nil, wrongType("a uuid.UUID")
+	case types.Int:
+		if i, ok := v.(int32); ok {
+			return value.NewInt(i), nil
+		}
+		return nil, wrongType("an int32")
+	case types.Long:
+		if i, ok := v.(int64); ok {
+			return value.NewLong(i), nil
+		}
+		return nil, wrongType("an int64")
+	case types.Real:
+		if f, ok := v.(float64); ok {
+			return value.NewReal(f), nil
+		}
+		return nil, wrongType("a float64")
+	case types.String:
+		if s, ok := v.(string); ok {
+			return value.NewString(s), nil
+		}
+		return nil, wrongType("a string")
+	case types.Timespan:
+		if d, ok := v.(time.Duration); ok {
+			return value.NewTimespan(d), nil
+		}
+		return nil, wrongType("a time.Duration")
+	case types.Decimal:
+		var s string
+		switch d := v.(type) {
+		case string:
+			s = d
+		case *big.Float:
+			if d == nil {
+				return nil, fmt.Errorf("*big.Float type cannot be set to the nil value")
+			}
+			s = d.Text('f', -1)
+		case *big.Int:
+			if d == nil {
+				return nil, fmt.Errorf("*big.Int type cannot be set to the nil value")
+			}
+			s = d.String()
+		default:
+			return nil, wrongType("a string, *big.Float or *big.Int")
+		}
+		dec, err := decimal.NewFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("string representing decimal does not appear to be a decimal number, was %v", s)
+		}
+		return value.NewDecimal(dec), nil
+	}
+	return nil, fmt.Errorf("received a field type %q we don't recognize", t)
+}
+
+// Definitions represents definitions of parameters that are substituted for variables in
+// a Kusto Query. This provides both variable substitution in a Stmt and provides protection against
+// SQL-like injection attacks.
+// See https://docs.microsoft.com/en-us/azure/kusto/query/queryparametersstatement?pivots=azuredataexplorer
+// for internals. This object is not thread-safe and passing it as an argument to a function will create a
+// copy that will share the internal state with the original.
+type Definitions struct {
+	m ParamTypes
+}
+
+// NewDefinitions is the constructor for Definitions.
+func NewDefinitions() Definitions {
+	return Definitions{}
+}
+
+// IsZero indicates if the Definitions object is the zero type.
+func (p Definitions) IsZero() bool {
+	return len(p.m) == 0
+}
+
+// With returns a copy of the Definitions object with the parameters names and types defined in "types".
+func (p Definitions) With(types ParamTypes) (Definitions, error) {
+	for name, param := range types {
+		if kql.RequiresQuoting(name) {
+			return p, errors.ES(errors.OpQuery, errors.KClientArgs, "name %q is not a valid parameter name", name).SetNoRetry()
+		}
+		if err := param.validate(); err != nil {
+			return p, errors.ES(errors.OpQuery, errors.KClientArgs, "parameter %q could not be added: %s", name, err).SetNoRetry()
+		}
+	}
+	p.m = types
+	return p, nil
+}
+
+// Must is the same as With(), but it must succeed or it panics.
+func (p Definitions) Must(types ParamTypes) Definitions {
+	var err error
+	p, err = p.With(types)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// names returns the names of the parameters, sorted.
+func (p Definitions) names() []string {
+	names := make([]string, 0, len(p.m))
+	for name := range p.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String implements fmt.Stringer.
+func (p Definitions) String() string {
+	if len(p.m) == 0 {
+		return ""
+	}
+
+	declarations := make([]string, 0, len(p.m))
+	for _, name := range p.names() {
+		declarations = append(declarations, p.m[name].declaration(name))
+	}
+	return "declare query_parameters(" + strings.Join(declarations, ", ") + ");"
+}
+
+// clone returns a clone of Definitions.
+func (p Definitions) clone() Definitions {
+	p.m = p.m.clone()
+	return p
+}
+
+// QueryValues represents a set of values that are substituted in Parameters. Every QueryValue key
+// must have a corresponding Parameter name. All values must be compatible with the Kusto Column type
+// it will go into (int64 for a long, int32 for int, time.Time for datetime, ...)
+type QueryValues map[string]interface{}
+
+func (v QueryValues) clone() QueryValues {
+	c := make(QueryValues, len(v))
+	for k, v := range v {
+		c[k] = v
+	}
+	return c
+}
+
+// Parameters represents values that will be substituted for a Stmt's Parameter. Keys are the names
+// of corresponding Parameters, values are the value to be used. Keys must exist in the Parameter
+// and value must be a Go type that corresponds to the ParamType.
+type Parameters struct {
+	m QueryValues
+}
+
+// NewParameters is the constructor for Parameters.
+func NewParameters() Parameters {
+	return Parameters{m: QueryValues{}}
+}
+
+// IsZero returns if Parameters is the zero value.
+func (q Parameters) IsZero() bool {
+	return len(q.m) == 0
+}
+
+// With returns a Parameters set to "values". values' keys represents Definitions names
+// that will substituted for and the values to be substituted.
+func (q Parameters) With(values QueryValues) (Parameters, error) {
+	q.m = values
+	return q, nil
+}
+
+// Must is the same as With() except any error is a panic.
+func (q Parameters) Must(values QueryValues) Parameters {
+	var err error
+	q, err = q.With(values)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q Parameters) clone() Parameters {
+	return Parameters{m: q.m.clone()}
+}
+
+// validate validates that every value of Parameters has a definition in defs, and is of the type of its definition.
+func (q Parameters) validate(defs Definitions) error {
+	for k, v := range q.m {
+		paramType, ok := defs.m[k]
+		if !ok {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "Parameters contains key %q that is not defined in the Stmt's Definitions", k).SetNoRetry()
+		}
+		if _, err := toValue(paramType.Type, v); err != nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "Parameters[%s]: %s", k, err).SetNoRetry()
+		}
+	}
+	return nil
+}
+
+// toKql converts Parameters into kql.Parameters, with the defaults of defs for the definitions that have no value.
+func (q Parameters) toKql(defs Definitions) (*kql.Parameters, error) {
+	params := kql.NewParameters()
+	for _, name := range defs.names() {
+		paramType := defs.m[name]
+		v, ok := q.m[name]
+		if !ok {
+			if paramType.Default == nil {
+				return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "parameter %q has no value and no default value", name).SetNoRetry()
+			}
+			v = paramType.Default
+		}
+
+		kv, err := toValue(paramType.Type, v)
+		if err != nil {
+			return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "Parameters[%s]: %s", name, err).SetNoRetry()
+		}
+		params.AddValue(name, kv)
+	}
+	return params, nil
+}
@@ -0,0 +1,161 @@
+package compat
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/unsafe"
+)
+
+// stringConstant is an internal type that cannot be created outside the package.  The only two ways to build
+// a stringConstant is to pass a string constant or use a local function to build the stringConstant.
+// This allows us to enforce the use of constants or strings built with injection protection.
+type stringConstant string
+
+// String implements fmt.Stringer.
+func (s stringConstant) String() string {
+	return string(s)
+}
+
+// Stmt is a Kusto Query statement. A Stmt is thread-safe, but methods on the Stmt are not.
+// All methods on a Stmt do not alter the statement, they return a new Stmt object with the changes.
+// This includes a copy of the Definitions and Parameters objects, if provided.  This allows a
+// root Stmt object that can be built upon. You should not pass *Stmt objects.
+type Stmt struct {
+	queryStr string
+	defs     Definitions
+	params   Parameters
+	unsafe   unsafe.Stmt
+}
+
+// StmtOption is an optional argument to NewStmt().
+type StmtOption func(s *Stmt)
+
+// UnsafeStmt enables unsafe actions on a Stmt and all Stmts derived from that Stmt.
+// This turns off safety features that could allow a service client to compromise your data store.
+// USE AT YOUR OWN RISK!
+func UnsafeStmt(options unsafe.Stmt) StmtOption {
+	return func(s *Stmt) {
+		s.unsafe.Add = true
+		s.unsafe.SuppressWarning = options.SuppressWarning
+	}
+}
+
+// NewStmt creates a Stmt from a string constant.
+//
+// Deprecated: Use kql.New and kql.NewParameters instead.
+func NewStmt(query stringConstant, options ...StmtOption) Stmt {
+	s := Stmt{queryStr: query.String()}
+	for _, option := range options {
+		option(&s)
+	}
+	return s
+}
+
+// Add will add more text to the Stmt. This is similar to the + operator on two strings, except
+// it only can be done with string constants. This allows dynamically building of a query from a root
+// Stmt.
+func (s Stmt) Add(query stringConstant) Stmt {
+	s.queryStr = s.queryStr + query.String()
+	return s
+}
+
+// UnsafeAdd provides a method to add strings that are not injection protected to the Stmt.
+// To utilize this method, you must create the Stmt with the UnsafeStmt() option and pass
+// the unsafe.Stmt with .Add set to true. If not set, THIS WILL PANIC!
+func (s Stmt) UnsafeAdd(query string) Stmt {
+	if !s.unsafe.Add {
+		panic("Stmt.UnsafeAdd() called, but the unsafe.Stmt.Add ability has not been enabled")
+	}
+
+	s.queryStr = s.queryStr + query
+	return s
+}
+
+// WithDefinitions will return a Stmt that can be used in a Query() with Kusto
+// Parameters to protect against SQL-like injection attacks. These Parameters must align with
+// the placeholders in the statement. The new Stmt object will have a copy of the Parameters passed,
+// not the original.
+func (s Stmt) WithDefinitions(defs Definitions) (Stmt, error) {
+	if defs.IsZero() {
+		return s, errors.ES(errors.OpQuery, errors.KClientArgs, "cannot pass Definitions that are empty").SetNoRetry()
+	}
+	s.defs = defs.clone()
+
+	return s, nil
+}
+
+// MustDefinitions is the same as WithDefinitions with the exceptions that an error causes a panic.
+func (s Stmt) MustDefinitions(defs Definitions) Stmt {
+	s, err := s.WithDefinitions(defs)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// WithParameters returns a Stmt that has the Parameters that will be substituted for
+// Definitions in the query.  Must have supplied the appropriate Definitions using WithDefinitions().
+func (s Stmt) WithParameters(params Parameters) (Stmt, error) {
+	if s.defs.IsZero() {
+		return s, errors.ES(errors.OpQuery, errors.KClientArgs, "cannot call WithParameters() if WithDefinitions hasn't been called").SetNoRetry()
+	}
+	if err := params.validate(s.defs); err != nil {
+		return s, err
+	}
+
+	s.params = params.clone()
+	return s, nil
+}
+
+// MustParameters is the same as WithParameters with the exceptions that an error causes a panic.
+func (s Stmt) MustParameters(params Parameters) Stmt {
+	stmt, err := s.WithParameters(params)
+	if err != nil {
+		panic(err)
+	}
+	return stmt
+}
+
+// String implements fmt.Stringer. This can be used to see what the query statement to the server will be
+// for debugging purposes.
+func (s Stmt) String() string {
+	if s.defs.IsZero() {
+		return s.queryStr
+	}
+	return s.defs.String() + "\n" + s.queryStr
+}
+
+// ValuesJSON returns a string in JSON format representing the Kusto QueryOptions.Parameters value
+// that will be passed to the server. These values are substituted for Definitions in the Stmt and
+// are represented by the Parameters that was passed, or the defaults of the Definitions.
+func (s Stmt) ValuesJSON() (string, error) {
+	params, err := s.params.toKql(s.defs)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(params.ToParameterCollection())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Build returns the query of the Stmt and its parameters, to pass to azkustodata with the QueryParameters option:
+//
+//	query, params, err := stmt.Build()
+//	if err != nil {
+//		return err
+//	}
+//	dataset, err := client.Query(ctx, db, query, azkustodata.QueryParameters(params))
+//
+// The query parameters are declared by azkustodata, so the query doesn't contain the declarations of String.
+func (s Stmt) Build() (*kql.Builder, *kql.Parameters, error) {
+	params, err := s.params.toKql(s.defs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kql.New("").AddUnsafe(s.queryStr), params, nil
+}
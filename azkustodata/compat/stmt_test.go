@@ -0,0 +1,125 @@
+package compat
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/unsafe"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmt(t *testing.T) {
+	t.Parallel()
+
+	dt := time.Date(2020, 3, 4, 14, 5, 1, 310996500, time.UTC)
+	guid := uuid.MustParse("74be27de-1e4e-49d9-b579-fe0b331d3642")
+
+	defs := NewDefinitions().Must(ParamTypes{
+		"b":   ParamType{Type: types.Bool, Default: true},
+		"dt":  ParamType{Type: types.DateTime, Default: dt},
+		"dyn": ParamType{Type: types.Dynamic},
+		"g":   ParamType{Type: types.GUID, Default: guid},
+		"i":   ParamType{Type: types.Int},
+		"l":   ParamType{Type: types.Long, Default: int64(2)},
+		"r":   ParamType{Type: types.Real, Default: 1.5},
+		"s":   ParamType{Type: types.String, Default: "it's"},
+		"ts":  ParamType{Type: types.Timespan, Default: time.Hour},
+		"dec": ParamType{Type: types.Decimal, Default: "1.25"},
+	})
+
+	stmt := NewStmt("Table | where I == i").Add(" and S == s").MustDefinitions(defs).
+		MustParameters(NewParameters().Must(QueryValues{"i": int32(1), "dyn": []int{1, 2}, "s": "x"}))
+
+	assert.Equal(t, `declare query_parameters(b:bool = bool(true), dec:decimal = decimal(1.25), dt:datetime = datetime(2020-03-04T14:05:01.3109965Z), `+
+		`dyn:dynamic, g:guid = guid(74be27de-1e4e-49d9-b579-fe0b331d3642), i:int, l:long = long(2), r:real = real(1.5), `+
+		`s:string = "it\'s", ts:timespan = timespan(01:00:00.0000000));`+"\nTable | where I == i and S == s", stmt.String())
+
+	query, params, err := stmt.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "Table | where I == i and S == s", query.String())
+	assert.Equal(t, map[string]string{
+		"b":   "bool(true)",
+		"dec": "decimal(1.25)",
+		"dt":  "datetime(2020-03-04T14:05:01.3109965Z)",
+		"dyn": "dynamic([1,2])",
+		"g":   "guid(74be27de-1e4e-49d9-b579-fe0b331d3642)",
+		"i":   "int(1)",
+		"l":   "long(2)",
+		"r":   "real(1.5)",
+		"s":   `"x"`,
+		"ts":  "timespan(01:00:00.0000000)",
+	}, params.ToParameterCollection())
+
+	values, err := stmt.ValuesJSON()
+	require.NoError(t, err)
+	assert.Contains(t, values, `"i":"int(1)"`)
+
+	// The original statement is not modified.
+	root := NewStmt("Table")
+	_ = root.Add(" | take 1")
+	assert.Equal(t, "Table", root.String())
+
+	query, params, err = root.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "Table", query.String())
+	assert.Equal(t, 0, params.Count())
+}
+
+func TestStmtErrors(t *testing.T) {
+	t.Parallel()
+
+	defs := NewDefinitions().Must(ParamTypes{
+		"i":   ParamType{Type: types.Int},
+		"dec": ParamType{Type: types.Decimal, Default: big.NewInt(3)},
+	})
+	stmt := NewStmt("Table | where I == i").MustDefinitions(defs)
+
+	_, err := NewDefinitions().With(ParamTypes{"i": ParamType{Type: types.Int, Default: int64(1)}})
+	assert.Error(t, err)
+	_, err = NewDefinitions().With(ParamTypes{"d": ParamType{Type: types.Dynamic, Default: 1}})
+	assert.Error(t, err)
+	_, err = NewDefinitions().With(ParamTypes{"d": ParamType{Type: "unknown"}})
+	assert.Error(t, err)
+	_, err = NewDefinitions().With(ParamTypes{"a b": ParamType{Type: types.Int}})
+	assert.Error(t, err)
+	_, err = NewDefinitions().With(ParamTypes{"d": ParamType{Type: types.Decimal, Default: "abc"}})
+	assert.Error(t, err)
+
+	_, err = NewStmt("Table").WithDefinitions(NewDefinitions())
+	assert.Error(t, err)
+	_, err = NewStmt("Table").WithParameters(NewParameters().Must(QueryValues{"i": int32(1)}))
+	assert.Error(t, err)
+	_, err = stmt.WithParameters(NewParameters().Must(QueryValues{"j": int32(1)}))
+	assert.Error(t, err)
+	_, err = stmt.WithParameters(NewParameters().Must(QueryValues{"i": 1}))
+	assert.Error(t, err)
+
+	// i has no value and no default.
+	_, _, err = stmt.Build()
+	assert.Error(t, err)
+
+	_, params, err := stmt.MustParameters(NewParameters().Must(QueryValues{"i": int32(1)})).Build()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"i": "int(1)", "dec": "decimal(3)"}, params.ToParameterCollection())
+}
+
+func TestStmtUnsafe(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		NewStmt("Table").UnsafeAdd(" | take 1")
+	})
+
+	stmt := NewStmt("Table", UnsafeStmt(unsafe.Stmt{Add: true})).UnsafeAdd(" | take 1")
+	assert.Equal(t, "Table | take 1", stmt.String())
+
+	// Statements derived from an unsafe statement are unsafe too.
+	stmt = stmt.Add(" | project A").UnsafeAdd(", B")
+	query, _, err := stmt.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "Table | take 1 | project A, B", query.String())
+}
@@ -0,0 +1,331 @@
+package azkustodata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+const (
+	// defaultAdvisorInterval is the interval between two capacity samples of a ConcurrencyAdvisor.
+	defaultAdvisorInterval = 30 * time.Second
+	// defaultCapacityShare is the share of the remaining query capacity of the cluster a ConcurrencyAdvisor allows.
+	defaultCapacityShare = 0.5
+	// queriesResource is the name of the query concurrency resource in the results of `.show capacity`.
+	queriesResource = "Queries"
+)
+
+// CapacitySample is a sample of the query capacity of a cluster, taken by a ConcurrencyAdvisor.
+type CapacitySample struct {
+	// Time is the time the sample was taken.
+	Time time.Time
+	// Total is the maximum number of concurrent queries of the cluster, from `.show capacity`.
+	Total int64
+	// Consumed is the number of queries running on the cluster, from `.show capacity`.
+	Consumed int64
+	// Remaining is the number of queries that can still run concurrently on the cluster, from `.show capacity`.
+	Remaining int64
+	// InProgress is the number of commands and queries in progress on the database, from
+	// `.show commands-and-queries`.
+	InProgress int64
+	// Limit is the concurrency limit recommended from this sample.
+	Limit int
+}
+
+// AdvisorOption is an option for a ConcurrencyAdvisor.
+type AdvisorOption func(a *advisorOptions)
+
+type advisorOptions struct {
+	interval      time.Duration
+	share         float64
+	minLimit      int
+	maxLimit      int
+	onSample      func(CapacitySample)
+	onLimitChange func(old, new int)
+	onError       func(error)
+	queryOptions  []QueryOption
+}
+
+// AdvisorInterval sets the interval between two samples taken by ConcurrencyAdvisor.Start. The default is 30 seconds.
+func AdvisorInterval(d time.Duration) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.interval = d
+	}
+}
+
+// CapacityShare sets the share of the remaining query capacity of the cluster the advisor allows, between 0 and 1.
+// The default is 0.5, which leaves half of the remaining capacity to the other clients of the cluster.
+func CapacityShare(share float64) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.share = share
+	}
+}
+
+// ConcurrencyBounds sets the minimum and maximum concurrency limits the advisor recommends.
+// The minimum defaults to 1, and the maximum to the total query capacity of the cluster.
+func ConcurrencyBounds(min, max int) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.minLimit = min
+		a.maxLimit = max
+	}
+}
+
+// OnCapacitySample sets a callback called with every sample.
+func OnCapacitySample(f func(CapacitySample)) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.onSample = f
+	}
+}
+
+// OnLimitChange sets a callback called when the recommended concurrency limit changes.
+func OnLimitChange(f func(old, new int)) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.onLimitChange = f
+	}
+}
+
+// OnSampleError sets a callback called when a sample taken by ConcurrencyAdvisor.Start fails. The limit is left
+// unchanged until the next successful sample.
+func OnSampleError(f func(error)) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.onError = f
+	}
+}
+
+// AdvisorQueryOptions sets the QueryOptions passed to the commands that sample the capacity.
+func AdvisorQueryOptions(options ...QueryOption) AdvisorOption {
+	return func(a *advisorOptions) {
+		a.queryOptions = append(a.queryOptions, options...)
+	}
+}
+
+// ConcurrencyAdvisor recommends and enforces a client-side limit on the number of concurrent queries, from the query
+// capacity of the cluster, so that batch jobs don't exceed the query concurrency limits of the cluster and fail with
+// throttling errors.
+// The capacity is sampled with `.show capacity` and `.show commands-and-queries`, which require database monitor
+// permissions. The limit is a share of the remaining capacity of the cluster, on top of the queries the advisor
+// already allowed.
+//
+// Use Acquire and Release around every query to enforce the limit, or Limit to only read the recommendation.
+// A ConcurrencyAdvisor is safe for concurrent use.
+type ConcurrencyAdvisor struct {
+	client  *Client
+	db      string
+	options advisorOptions
+
+	mu    sync.Mutex
+	limit int
+	inUse int
+	// changed is closed and replaced whenever a slot may have become available.
+	changed chan struct{}
+
+	// cancel stops the background sampling started with Start.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewConcurrencyAdvisor creates a ConcurrencyAdvisor that samples the capacity of the cluster through the database db.
+// The limit starts at the minimum limit, until the first sample is taken with Sample or Start.
+func (c *Client) NewConcurrencyAdvisor(db string, options ...AdvisorOption) (*ConcurrencyAdvisor, error) {
+	opts := advisorOptions{interval: defaultAdvisorInterval, share: defaultCapacityShare, minLimit: 1}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	if opts.interval <= 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "advisor interval must be positive, got %s", opts.interval).SetNoRetry()
+	}
+	if opts.share <= 0 || opts.share > 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "capacity share must be in (0, 1], got %v", opts.share).SetNoRetry()
+	}
+	if opts.minLimit < 1 || (opts.maxLimit != 0 && opts.maxLimit < opts.minLimit) {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "invalid concurrency bounds [%d, %d]", opts.minLimit, opts.maxLimit).SetNoRetry()
+	}
+
+	return &ConcurrencyAdvisor{
+		client:  c,
+		db:      db,
+		options: opts,
+		limit:   opts.minLimit,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// Limit returns the current recommended concurrency limit.
+func (a *ConcurrencyAdvisor) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit
+}
+
+// InUse returns the number of slots currently acquired with Acquire.
+func (a *ConcurrencyAdvisor) InUse() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inUse
+}
+
+// Acquire waits until the number of acquired slots is below the limit, and acquires one. Every successful call must be
+// followed by a call to Release once the query is done.
+func (a *ConcurrencyAdvisor) Acquire(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		if a.inUse < a.limit {
+			a.inUse++
+			a.mu.Unlock()
+			return nil
+		}
+		changed := a.changed
+		a.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Release releases a slot acquired with Acquire.
+func (a *ConcurrencyAdvisor) Release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inUse > 0 {
+		a.inUse--
+	}
+	a.notifyLocked()
+}
+
+func (a *ConcurrencyAdvisor) notifyLocked() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// Sample samples the capacity of the cluster, and updates the limit.
+func (a *ConcurrencyAdvisor) Sample(ctx context.Context) (CapacitySample, error) {
+	sample := CapacitySample{Time: time.Now()}
+
+	capacity, err := a.mgmtRows(ctx, kql.New(".show capacity"))
+	if err != nil {
+		return sample, err
+	}
+	found := false
+	for _, row := range capacity {
+		if row.Resource == queriesResource {
+			sample.Total, sample.Consumed, sample.Remaining = row.Total, row.Consumed, row.Remaining
+			found = true
+			break
+		}
+	}
+	if !found {
+		return sample, errors.ES(errors.OpMgmt, errors.KInternal, ".show capacity did not return the %s resource", queriesResource)
+	}
+
+	progress, err := a.mgmtRows(ctx, kql.New(".show commands-and-queries | where State == 'InProgress' | summarize InProgress = count()"))
+	if err != nil {
+		return sample, err
+	}
+	if len(progress) > 0 {
+		sample.InProgress = progress[0].InProgress
+	}
+
+	a.mu.Lock()
+	sample.Limit = a.recommend(sample)
+	old := a.limit
+	a.limit = sample.Limit
+	if sample.Limit > old {
+		a.notifyLocked()
+	}
+	a.mu.Unlock()
+
+	if a.options.onSample != nil {
+		a.options.onSample(sample)
+	}
+	if sample.Limit != old && a.options.onLimitChange != nil {
+		a.options.onLimitChange(old, sample.Limit)
+	}
+	return sample, nil
+}
+
+// capacityRow is a row of the results of the commands that sample the capacity.
+type capacityRow struct {
+	Resource   string
+	Total      int64
+	Consumed   int64
+	Remaining  int64
+	InProgress int64
+}
+
+func (a *ConcurrencyAdvisor) mgmtRows(ctx context.Context, cmd *kql.Builder) ([]capacityRow, error) {
+	dataset, err := a.client.Mgmt(ctx, a.db, cmd, a.options.queryOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return query.ToStructs[capacityRow](dataset.Tables()[0])
+}
+
+// recommend returns the limit for a sample: the slots already acquired, and a share of the remaining capacity.
+// It must be called with the lock held.
+func (a *ConcurrencyAdvisor) recommend(sample CapacitySample) int {
+	limit := a.inUse + int(float64(sample.Remaining)*a.options.share)
+
+	max := a.options.maxLimit
+	if max == 0 {
+		max = int(sample.Total)
+	}
+	if limit > max {
+		limit = max
+	}
+	if limit < a.options.minLimit {
+		limit = a.options.minLimit
+	}
+	return limit
+}
+
+// Start samples the capacity in the background, at the interval set with AdvisorInterval, until Close is called or
+// ctx is done. Failed samples are reported to the callback set with OnSampleError.
+// Start must be called at most once.
+func (a *ConcurrencyAdvisor) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.mu.Lock()
+	a.cancel = cancel
+	a.mu.Unlock()
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.options.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := a.Sample(ctx); err != nil && ctx.Err() == nil && a.options.onError != nil {
+				a.options.onError(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the background sampling started with Start, and waits for it to finish.
+// It does not release the acquired slots.
+func (a *ConcurrencyAdvisor) Close() {
+	a.mu.Lock()
+	cancel := a.cancel
+	a.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-a.done
+}
@@ -0,0 +1,210 @@
+package azkustodata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capacityConn is a fake conn that answers the commands of a ConcurrencyAdvisor.
+type capacityConn struct {
+	mu         sync.Mutex
+	remaining  int64
+	inProgress int64
+	fail       bool
+	commands   []string
+}
+
+func (c *capacityConn) setRemaining(remaining int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remaining = remaining
+}
+
+func (c *capacityConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, query.String())
+
+	if c.fail {
+		return nil, fmt.Errorf("forbidden")
+	}
+
+	if strings.HasPrefix(query.String(), ".show capacity") {
+		return io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+			`{"ColumnName":"Resource","DataType":"String","ColumnType":"string"},` +
+			`{"ColumnName":"Total","DataType":"Int64","ColumnType":"long"},` +
+			`{"ColumnName":"Consumed","DataType":"Int64","ColumnType":"long"},` +
+			`{"ColumnName":"Remaining","DataType":"Int64","ColumnType":"long"},` +
+			`{"ColumnName":"Origin","DataType":"String","ColumnType":"string"}],` +
+			fmt.Sprintf(`"Rows":[["ingestions",16,0,16,""],["Queries",20,%d,%d,"CPU"]]}]}`, 20-c.remaining, c.remaining))), nil
+	}
+
+	return io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"InProgress","DataType":"Int64","ColumnType":"long"}],` +
+		fmt.Sprintf(`"Rows":[[%d]]}]}`, c.inProgress))), nil
+}
+
+func (c *capacityConn) Close() error {
+	return nil
+}
+
+func TestConcurrencyAdvisorSample(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc      string
+		remaining int64
+		options   []AdvisorOption
+		wantLimit int
+	}{
+		{desc: "default share", remaining: 12, wantLimit: 6},
+		{desc: "custom share", remaining: 12, options: []AdvisorOption{CapacityShare(0.25)}, wantLimit: 3},
+		{desc: "maximum", remaining: 12, options: []AdvisorOption{ConcurrencyBounds(1, 4)}, wantLimit: 4},
+		{desc: "total capacity", remaining: 20, options: []AdvisorOption{CapacityShare(1)}, wantLimit: 20},
+		{desc: "minimum", remaining: 0, options: []AdvisorOption{ConcurrencyBounds(2, 0)}, wantLimit: 2},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &capacityConn{remaining: test.remaining, inProgress: 7}
+			client := &Client{conn: conn}
+
+			var samples []CapacitySample
+			var changes [][2]int
+			options := append([]AdvisorOption{
+				OnCapacitySample(func(s CapacitySample) { samples = append(samples, s) }),
+				OnLimitChange(func(old, new int) { changes = append(changes, [2]int{old, new}) }),
+			}, test.options...)
+
+			advisor, err := client.NewConcurrencyAdvisor("db", options...)
+			require.NoError(t, err)
+			initial := advisor.Limit()
+
+			sample, err := advisor.Sample(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, int64(20), sample.Total)
+			assert.Equal(t, test.remaining, sample.Remaining)
+			assert.Equal(t, 20-test.remaining, sample.Consumed)
+			assert.Equal(t, int64(7), sample.InProgress)
+			assert.Equal(t, test.wantLimit, sample.Limit)
+			assert.Equal(t, test.wantLimit, advisor.Limit())
+
+			assert.Equal(t, []CapacitySample{sample}, samples)
+			if initial != test.wantLimit {
+				assert.Equal(t, [][2]int{{initial, test.wantLimit}}, changes)
+			} else {
+				assert.Empty(t, changes)
+			}
+
+			assert.Equal(t, []string{
+				".show capacity",
+				".show commands-and-queries | where State == 'InProgress' | summarize InProgress = count()",
+			}, conn.commands)
+		})
+	}
+}
+
+func TestConcurrencyAdvisorAcquire(t *testing.T) {
+	t.Parallel()
+
+	conn := &capacityConn{remaining: 2}
+	client := &Client{conn: conn}
+
+	advisor, err := client.NewConcurrencyAdvisor("db")
+	require.NoError(t, err)
+	_, err = advisor.Sample(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, advisor.Limit())
+
+	require.NoError(t, advisor.Acquire(context.Background()))
+	assert.Equal(t, 1, advisor.InUse())
+
+	// The limit is reached.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, advisor.Acquire(ctx), context.DeadlineExceeded)
+
+	// A higher limit unblocks waiting calls, on top of the acquired slot.
+	acquired := make(chan error)
+	go func() {
+		acquired <- advisor.Acquire(context.Background())
+	}()
+	conn.setRemaining(4)
+	_, err = advisor.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, advisor.Limit())
+	require.NoError(t, <-acquired)
+	assert.Equal(t, 2, advisor.InUse())
+
+	// Releasing a slot unblocks waiting calls.
+	conn.setRemaining(0)
+	_, err = advisor.Sample(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, advisor.Limit())
+
+	go func() {
+		acquired <- advisor.Acquire(context.Background())
+	}()
+	advisor.Release()
+	require.NoError(t, <-acquired)
+	assert.Equal(t, 2, advisor.InUse())
+}
+
+func TestConcurrencyAdvisorStart(t *testing.T) {
+	t.Parallel()
+
+	conn := &capacityConn{remaining: 10, fail: true}
+	client := &Client{conn: conn}
+
+	errs := make(chan error, 100)
+	advisor, err := client.NewConcurrencyAdvisor("db", AdvisorInterval(time.Millisecond), OnSampleError(func(err error) {
+		errs <- err
+	}))
+	require.NoError(t, err)
+
+	advisor.Start(context.Background())
+	assert.Error(t, <-errs)
+	assert.Equal(t, 1, advisor.Limit())
+
+	conn.mu.Lock()
+	conn.fail = false
+	conn.mu.Unlock()
+
+	require.Eventually(t, func() bool { return advisor.Limit() == 5 }, 5*time.Second, time.Millisecond)
+
+	advisor.Close()
+	advisor.Close()
+}
+
+func TestConcurrencyAdvisorOptions(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &capacityConn{}}
+
+	for _, options := range [][]AdvisorOption{
+		{AdvisorInterval(0)},
+		{CapacityShare(0)},
+		{CapacityShare(1.5)},
+		{ConcurrencyBounds(0, 10)},
+		{ConcurrencyBounds(5, 4)},
+	} {
+		_, err := client.NewConcurrencyAdvisor("db", options...)
+		assert.Error(t, err)
+	}
+
+	// Close without Start doesn't block.
+	advisor, err := client.NewConcurrencyAdvisor("db")
+	require.NoError(t, err)
+	advisor.Close()
+}
@@ -0,0 +1,90 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+)
+
+const concurrentQueryResponse = `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"A","ColumnType":"int"}]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[1],[2]]}
+,{"FrameType":"TableCompletion","TableId":1,"RowCount":2}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+
+// concurrentConn answers queries and management commands with fixed responses, and is safe for concurrent use.
+type concurrentConn struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *concurrentConn) rawQuery(_ context.Context, callType callType, _ string, _ Statement, _ *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+
+	if callType == mgmtCall {
+		return io.NopCloser(strings.NewReader(operationIdResponse)), nil
+	}
+	return io.NopCloser(strings.NewReader(concurrentQueryResponse)), nil
+}
+
+func (c *concurrentConn) Close() error {
+	return nil
+}
+
+func TestClientConcurrent(t *testing.T) {
+	t.Parallel()
+
+	conn := &concurrentConn{}
+	client := &Client{conn: conn, defaultOptions: []QueryOption{Application("app")}}
+
+	type row struct {
+		A int32
+	}
+
+	const goroutines = 20
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			dataset, err := client.Query(context.Background(), "db", kql.New("T"), User("user"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			rows, err := query.ToStructs[row](dataset.Tables()[0])
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, []row{{A: 1}, {A: 2}}, rows)
+
+			iterative, err := client.IterativeQuery(context.Background(), "db", kql.New("T"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			full, err := iterative.ToDataset()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Len(t, full.Tables()[0].Rows(), 2)
+
+			_, err = client.Mgmt(context.Background(), "db", kql.New(".show operations"))
+			if !assert.NoError(t, err) {
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, goroutines*3, conn.calls)
+}
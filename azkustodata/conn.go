@@ -9,15 +9,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unicode"
 
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/internal/response"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	truestedEndpoints "github.com/Azure/azure-kusto-go/azkustodata/trusted_endpoints"
 	"github.com/google/uuid"
 )
@@ -32,12 +37,16 @@ var bufferPool = sync.Pool{
 
 // Conn provides connectivity to a Kusto instance.
 type Conn struct {
-	endpoint                           string
-	auth                               Authorization
-	endMgmt, endQuery, endStreamIngest *url.URL
-	client                             *http.Client
-	endpointValidated                  atomic.Bool
-	clientDetails                      *ClientDetails
+	endpoint                                       string
+	auth                                           Authorization
+	endMgmt, endQuery, endQueryV1, endStreamIngest *url.URL
+	client                                         *http.Client
+	endpointValidated                              atomic.Bool
+	clientDetails                                  *ClientDetails
+	// onClaimsChallenge is called for every claims challenge returned by the service, see WithClaimsChallengeHook.
+	onClaimsChallenge func(ClaimsChallenge)
+	// auditor is called before every query and management command is sent, see WithAuditHook.
+	auditor *auditor
 }
 
 // NewConn returns a new Conn object with an injected http.Client
@@ -63,6 +72,7 @@ func NewConn(endpoint string, auth Authorization, client *http.Client, clientDet
 		auth:            auth,
 		endMgmt:         u.JoinPath("/v1/rest/mgmt"),
 		endQuery:        u.JoinPath("/v2/rest/query"),
+		endQueryV1:      u.JoinPath("/v1/rest/query"),
 		endStreamIngest: u.JoinPath("/v1/rest/ingest"),
 		client:          client,
 		clientDetails:   clientDetails,
@@ -92,20 +102,15 @@ func (c *Conn) rawQuery(ctx context.Context, callType callType, db string, query
 }
 
 const (
-	execQuery = 1
-	execMgmt  = 2
+	execQuery   = 1
+	execMgmt    = 2
+	execQueryV1 = 3
 )
 
 func (c *Conn) doRequest(ctx context.Context, execType int, db string, query Statement, properties requestProperties) (errors.Op, http.Header, http.Header,
 	io.ReadCloser, error) {
 	var op errors.Op
-	err := c.validateEndpoint()
-	if err != nil {
-		op = errors.OpQuery
-		return 0, nil, nil, nil, errors.E(op, errors.KInternal, fmt.Errorf("could not validate endpoint: %w", err))
-	}
-
-	if execType == execQuery {
+	if execType == execQuery || execType == execQueryV1 {
 		op = errors.OpQuery
 	} else if execType == execMgmt {
 		op = errors.OpMgmt
@@ -117,10 +122,10 @@ func (c *Conn) doRequest(ctx context.Context, execType int, db string, query Sta
 	buff.Reset()
 	defer bufferPool.Put(buff)
 
+	var csl string
 	switch execType {
-	case execQuery, execMgmt:
+	case execQuery, execMgmt, execQueryV1:
 		var err error
-		var csl string
 		if query.SupportsInlineParameters() || properties.QueryParameters.Count() == 0 {
 			csl = query.String()
 		} else {
@@ -136,9 +141,12 @@ func (c *Conn) doRequest(ctx context.Context, execType int, db string, query Sta
 		if err != nil {
 			return 0, nil, nil, nil, errors.E(op, errors.KInternal, fmt.Errorf("could not JSON marshal the Query message: %w", err))
 		}
-		if execType == execQuery {
+		switch execType {
+		case execQuery:
 			endpoint = c.endQuery
-		} else {
+		case execQueryV1:
+			endpoint = c.endQueryV1
+		default:
 			endpoint = c.endMgmt
 		}
 	default:
@@ -146,7 +154,25 @@ func (c *Conn) doRequest(ctx context.Context, execType int, db string, query Sta
 	}
 
 	headers := c.getHeaders(properties)
-	responseHeaders, closer, err := c.doRequestImpl(ctx, op, endpoint, io.NopCloser(buff), headers, fmt.Sprintf("With query: %s", query.String()))
+	if c.auditor != nil {
+		c.auditor.audit(AuditRecord{
+			Op:              op,
+			Endpoint:        endpoint.String(),
+			Database:        db,
+			Text:            csl,
+			Parameters:      properties.Parameters,
+			ClientRequestID: headers.Get(ClientRequestIdHeader),
+		})
+	}
+	start := time.Now()
+	responseHeaders, closer, err := c.doRequestImpl(ctx, op, endpoint, replayableBody{bytes.NewReader(buff.Bytes())}, headers, fmt.Sprintf("With query: %s", query.String()))
+	if err != nil {
+		log.Writef(log.EventQuery, "%s to %s on database %q with client request id %q failed after %s: %s", op, endpoint, db,
+			headers.Get(ClientRequestIdHeader), time.Since(start), err)
+	} else {
+		log.Writef(log.EventQuery, "%s to %s on database %q with client request id %q returned after %s", op, endpoint, db,
+			headers.Get(ClientRequestIdHeader), time.Since(start))
+	}
 	return op, headers, responseHeaders, closer, err
 }
 
@@ -160,8 +186,8 @@ func (c *Conn) doRequestImpl(
 
 	// Replace non-ascii chars in headers with '?'
 	for _, values := range headers {
-		var builder strings.Builder
 		for i := range values {
+			var builder strings.Builder
 			for _, char := range values[i] {
 				if char > unicode.MaxASCII {
 					builder.WriteRune('?')
@@ -173,10 +199,18 @@ func (c *Conn) doRequestImpl(
 		}
 	}
 
+	if err := c.validateEndpoint(); err != nil {
+		if e, ok := errors.GetKustoError(err); ok && e.Kind == errors.KClientArgs {
+			return nil, nil, e
+		}
+		return nil, nil, errors.E(op, errors.KInternal, fmt.Errorf("could not validate endpoint: %w", err))
+	}
+
 	if c.auth.TokenProvider != nil && c.auth.TokenProvider.AuthorizationRequired() {
 		c.auth.TokenProvider.SetHttp(c.client)
 		token, tokenType, tkerr := c.auth.TokenProvider.AcquireToken(ctx)
 		if tkerr != nil {
+			log.Writef(log.EventAuth, "could not acquire a token for %s: %s", endpoint.Host, tkerr)
 			return nil, nil, errors.ES(op, errors.KInternal, "Error while getting token : %s", tkerr)
 		}
 		headers.Add("Authorization", fmt.Sprintf("%s %s", tokenType, token))
@@ -190,6 +224,9 @@ func (c *Conn) doRequestImpl(
 	}
 
 	resp, err := c.client.Do(req.WithContext(ctx))
+	if err == nil {
+		resp, err = c.handleClaimsChallenge(ctx, resp, endpoint, buff, headers)
+	}
 	if err != nil {
 		// TODO(jdoak): We need a http error unwrap function that pulls out an *errors.Error.
 		return nil, nil, errors.E(op, errors.KHTTPError, fmt.Errorf("%v, %w", errorContext, err))
@@ -200,29 +237,104 @@ func (c *Conn) doRequestImpl(
 		return nil, nil, err
 	}
 
+	if isGatewayPage(resp.Header.Get("Content-Type"), body) {
+		return nil, nil, gatewayError(op, resp, body, errorContext)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil, errors.HTTP(op, resp.Status, resp.StatusCode, body, fmt.Sprintf("error from Kusto endpoint, %v", errorContext))
+		return nil, nil, httpError(op, resp, body, errorContext)
 	}
 	return resp.Header, body, nil
 }
 
-func (c *Conn) validateEndpoint() error {
-	if !c.endpointValidated.Load() {
-		var err error
-		if cloud, err := GetMetadata(c.endpoint, c.client); err == nil {
-			err = truestedEndpoints.Instance.ValidateTrustedEndpoint(c.endpoint, cloud.LoginEndpoint)
-			if err == nil {
-				c.endpointValidated.Store(true)
-			}
+// httpError returns the error of a response whose status isn't 200 OK, and closes its body.
+func httpError(op errors.Op, resp *http.Response, body io.ReadCloser, errorContext string) *errors.HttpError {
+	httpErr := errors.HTTP(op, resp.Status, resp.StatusCode, body, fmt.Sprintf("error from Kusto endpoint, %v", errorContext))
+	httpErr.RetryAfter = retryAfter(resp.Header)
+	httpErr.ClientRequestID = resp.Header.Get(ClientRequestIdHeader)
+	httpErr.ActivityID = resp.Header.Get(ActivityIdHeader)
+	return httpErr
+}
+
+// gatewayPeekSize is the number of bytes of the body that isGatewayPage looks at.
+const gatewayPeekSize = 512
+
+// isGatewayPage reports whether a response is a page of a proxy or gateway rather than a response of the service, which
+// are JSON: it has an HTML or XML content type, or a body that starts with '<' without a JSON content type.
+func isGatewayPage(contentType string, body io.Reader) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return false
+	case mediaType == "text/html" || strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	peeker, ok := body.(interface{ Peek(n int) ([]byte, error) })
+	if !ok {
+		return false
+	}
+	start, _ := peeker.Peek(gatewayPeekSize)
+	start = bytes.TrimLeft(start, " \t\r\n\ufeff")
+	return len(start) > 0 && start[0] == '<'
+}
+
+// gatewayError returns the *errors.GatewayError of a page of a proxy or gateway, and closes its body.
+func gatewayError(op errors.Op, resp *http.Response, body io.ReadCloser, errorContext string) *errors.GatewayError {
+	defer body.Close()
+	start, _ := io.ReadAll(io.LimitReader(body, 4*errors.GatewaySnippetSize))
+
+	gatewayErr := errors.Gateway(op, resp.Status, resp.StatusCode, resp.Header.Get("Content-Type"), start)
+	if resp.StatusCode != http.StatusOK {
+		gatewayErr.HTTP = httpError(op, resp, io.NopCloser(bytes.NewReader(start)), errorContext)
+	}
+	return gatewayErr
+}
+
+// retryAfter returns the delay of the Retry-After header, given in seconds or as an HTTP date, or 0 if there is none.
+func retryAfter(header http.Header) time.Duration {
+	v := strings.TrimSpace(header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds <= 0 {
+			return 0
 		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(v); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
 
+// validateEndpoint makes sure that the endpoint is trusted before a token is sent to it, see AddTrustedHosts.
+// Once validated, the endpoint is not validated again. As before, the request goes on without validation if the cloud
+// metadata of the endpoint can't be retrieved, and the validation is tried again on the next request.
+func (c *Conn) validateEndpoint() error {
+	if c.endpointValidated.Load() {
+		return nil
+	}
+
+	cloud, err := GetMetadata(c.endpoint, c.client)
+	if err != nil {
+		log.Writef(log.EventQuery, "could not get the cloud metadata of %s, the endpoint was not validated: %s", c.endpoint, err)
+		return nil
+	}
+
+	if err := truestedEndpoints.Instance.ValidateTrustedEndpoint(c.endpoint, cloud.LoginEndpoint); err != nil {
 		return err
 	}
 
+	c.endpointValidated.Store(true)
 	return nil
 }
 
 const ClientRequestIdHeader = "x-ms-client-request-id"
+const ActivityIdHeader = "x-ms-activity-id"
 const ApplicationHeader = "x-ms-app"
 const UserHeader = "x-ms-user"
 const ClientVersionHeader = "x-ms-client-version"
@@ -230,7 +342,11 @@ const ClientVersionHeader = "x-ms-client-version"
 func (c *Conn) getHeaders(properties requestProperties) http.Header {
 	header := http.Header{}
 	header.Add("Accept", "application/json")
-	header.Add("Accept-Encoding", "gzip, deflate")
+	if properties.NoCompression {
+		header.Add("Accept-Encoding", "identity")
+	} else {
+		header.Add("Accept-Encoding", "gzip, deflate")
+	}
 	header.Add("Content-Type", "application/json; charset=utf-8")
 	header.Add("Connection", "Keep-Alive")
 	header.Add("x-ms-version", "2019-02-13")
@@ -254,9 +370,45 @@ func (c *Conn) getHeaders(properties requestProperties) http.Header {
 	}
 
 	header.Add(ClientVersionHeader, c.clientDetails.ClientVersionForTracing())
+
+	for key, values := range properties.Headers {
+		for _, v := range values {
+			header.Add(key, v)
+		}
+	}
 	return header
 }
 
+// customHeaderName is the pattern of the names of the headers that can be set with WithHTTPHeader.
+var customHeaderName = regexp.MustCompile(`^[xX]-[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// maxCustomHeaderSize is the maximum size of the value of a header set with WithHTTPHeader.
+const maxCustomHeaderSize = 1024
+
+// ValidateHTTPHeader checks that a custom header can be added to the requests of the client. Only "x-" headers are
+// allowed, so that the headers that the client, the HTTP transport and the authentication set can't be overridden,
+// except the "x-ms-" headers, which are reserved for the service. The value must be printable ASCII, of up to 1024
+// bytes.
+func ValidateHTTPHeader(key, value string) error {
+	if !customHeaderName.MatchString(key) {
+		return errors.ES(errors.OpQuery, errors.KClientArgs, "header %q is not allowed, only x- headers can be set", key).SetNoRetry()
+	}
+	if strings.HasPrefix(strings.ToLower(key), "x-ms-") {
+		return errors.ES(errors.OpQuery, errors.KClientArgs, "header %q is not allowed, x-ms- headers are reserved", key).SetNoRetry()
+	}
+	if len(value) > maxCustomHeaderSize {
+		return errors.ES(errors.OpQuery, errors.KClientArgs, "header %q has a value of %d bytes, the maximum is %d", key, len(value),
+			maxCustomHeaderSize).SetNoRetry()
+	}
+	for _, r := range value {
+		if r < ' ' || r > '~' {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "header %q has a value with the character %q, only printable ASCII is allowed",
+				key, r).SetNoRetry()
+		}
+	}
+	return nil
+}
+
 func (c *Conn) Close() error {
 	c.client.CloseIdleConnections()
 	return nil
@@ -2,10 +2,13 @@ package azkustodata
 
 import (
 	"context"
+	goErrors "errors"
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	"github.com/google/uuid"
 	"io"
+	"net/http"
 	"net/url"
 	"time"
 )
@@ -19,14 +22,57 @@ var (
 	streamingIngestDefaultTimeout = 10 * time.Minute
 )
 
+// StreamIngestInfo identifies a streaming ingestion request, so that support can trace it.
+type StreamIngestInfo struct {
+	// ClientRequestID is the client request id that was sent in the x-ms-client-request-id header.
+	ClientRequestID string
+	// EchoedClientRequestID is the client request id the service echoed in the x-ms-client-request-id header of its
+	// response. It is empty if the service didn't echo it, or didn't respond. It differs from ClientRequestID if the id
+	// was altered on its way, for instance because it has non-ASCII characters, which are replaced with '?'.
+	EchoedClientRequestID string
+	// ActivityID is the id the service gave to the request, from the x-ms-activity-id header of its response.
+	ActivityID string
+}
+
+// StreamIngest streams the payload into the table of the database. See StreamIngestWithInfo.
 func (c *Conn) StreamIngest(ctx context.Context, db, table string, payload io.Reader, format DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) error {
+	_, err := c.StreamIngestWithInfo(ctx, db, table, payload, format, mappingName, clientRequestId, isBlobUri)
+	return err
+}
+
+// StreamIngestWithInfo streams the payload into the table of the database, and returns the ids of the request, on
+// success and failure. A client request id is generated if clientRequestId is empty.
+// When the service rejects the request, the returned error wraps an *errors.HttpError that holds the ids as well.
+func (c *Conn) StreamIngestWithInfo(ctx context.Context, db, table string, payload io.Reader, format DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) (StreamIngestInfo, error) {
+	return c.StreamIngestWithOptions(ctx, db, table, payload, format, mappingName, clientRequestId, isBlobUri, StreamIngestOptions{})
+}
+
+// StreamIngestOptions are the optional settings of StreamIngestWithOptions.
+type StreamIngestOptions struct {
+	// Headers are custom headers, which must pass ValidateHTTPHeader.
+	Headers http.Header
+	// Uncompressed sends the payload without the gzip Content-Encoding, for payloads that are not compressed, which
+	// saves the CPU of the compression where it is scarcer than bandwidth. By default, the payload must be compressed
+	// with gzip.
+	Uncompressed bool
+}
+
+// StreamIngestWithOptions is StreamIngestWithInfo with options, such as custom headers.
+func (c *Conn) StreamIngestWithOptions(ctx context.Context, db, table string, payload io.Reader, format DataFormatForStreaming, mappingName string,
+	clientRequestId string, isBlobUri bool, options StreamIngestOptions) (StreamIngestInfo, error) {
+	header := options.Headers
+	if clientRequestId == "" {
+		clientRequestId = "KGC.executeStreaming;" + uuid.New().String()
+	}
+	info := StreamIngestInfo{ClientRequestID: clientRequestId}
+
 	streamUrl, err := url.Parse(c.endStreamIngest.String())
 	if err != nil {
-		return errors.ES(errors.OpIngestStream, errors.KClientArgs, "could not parse the stream endpoint(%s): %s", c.endStreamIngest.String(), err).SetNoRetry()
+		return info, errors.ES(errors.OpIngestStream, errors.KClientArgs, "could not parse the stream endpoint(%s): %s", c.endStreamIngest.String(), err).SetNoRetry()
 	}
 	path, err := url.JoinPath(streamUrl.Path, db, table)
 	if err != nil {
-		return errors.ES(errors.OpIngestStream, errors.KClientArgs, "could not join the stream endpoint(%s) with the db(%s) and table(%s): %s", c.endStreamIngest.String(), db, table, err).SetNoRetry()
+		return info, errors.ES(errors.OpIngestStream, errors.KClientArgs, "could not join the stream endpoint(%s) with the db(%s) and table(%s): %s", c.endStreamIngest.String(), db, table, err).SetNoRetry()
 	}
 	streamUrl.Path = path
 
@@ -46,15 +92,19 @@ func (c *Conn) StreamIngest(ctx context.Context, db, table string, payload io.Re
 		closeablePayload = io.NopCloser(payload)
 	}
 
-	if clientRequestId == "" {
-		clientRequestId = "KGC.executeStreaming;" + uuid.New().String()
+	for key, values := range header {
+		for _, v := range values {
+			if err := ValidateHTTPHeader(key, v); err != nil {
+				return info, errors.E(errors.OpIngestStream, errors.KClientArgs, err).SetNoRetry()
+			}
+		}
 	}
 
-	properties := requestProperties{}
+	properties := requestProperties{Headers: header}
 	properties.ClientRequestID = clientRequestId
 	headers := c.getHeaders(properties)
 	headers.Del("Content-Type")
-	if !isBlobUri {
+	if !isBlobUri && !options.Uncompressed {
 		headers.Add("Content-Encoding", "gzip")
 	}
 
@@ -62,14 +112,29 @@ func (c *Conn) StreamIngest(ctx context.Context, db, table string, payload io.Re
 		ctx, _ = context.WithTimeout(ctx, streamingIngestDefaultTimeout)
 	}
 
-	_, body, err := c.doRequestImpl(ctx, errors.OpIngestStream, streamUrl, closeablePayload, headers, fmt.Sprintf("With db: %s, table: %s, mappingName: %s, clientRequestId: %s", db, table, mappingName, clientRequestId))
+	respHeaders, body, err := c.doRequestImpl(ctx, errors.OpIngestStream, streamUrl, closeablePayload, headers, fmt.Sprintf("With db: %s, table: %s, mappingName: %s, clientRequestId: %s", db, table, mappingName, clientRequestId))
 	if body != nil {
 		body.Close()
 	}
 
+	var httpErr *errors.HttpError
+	switch {
+	case respHeaders != nil:
+		info.EchoedClientRequestID = respHeaders.Get(ClientRequestIdHeader)
+		info.ActivityID = respHeaders.Get(ActivityIdHeader)
+	case goErrors.As(err, &httpErr):
+		info.EchoedClientRequestID = httpErr.ClientRequestID
+		info.ActivityID = httpErr.ActivityID
+	}
+	if info.EchoedClientRequestID != "" && info.EchoedClientRequestID != info.ClientRequestID {
+		log.Writef(log.EventIngest, "the service echoed the client request id %q of a streaming ingestion into %s.%s as %q",
+			info.ClientRequestID, db, table, info.EchoedClientRequestID)
+	}
+
 	if err != nil {
-		return errors.ES(errors.OpIngestStream, errors.KHTTPError, "streaming ingestion failed: endpoint(%s): %s", streamUrl.String(), err)
+		// The response of the service is wrapped, so that the streaming errors can be identified from it.
+		return info, errors.E(errors.OpIngestStream, errors.KHTTPError, fmt.Errorf("streaming ingestion failed: endpoint(%s): %w", streamUrl.String(), err))
 	}
 
-	return nil
+	return info, nil
 }
@@ -1,13 +1,23 @@
 package azkustodata
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"encoding/json"
+	goErrors "errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHeaders(t *testing.T) {
@@ -85,6 +95,102 @@ func TestHeaders(t *testing.T) {
 	}
 }
 
+func TestCustomHeaders(t *testing.T) {
+	t.Parallel()
+
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(emptyMgmtResponse))
+	}))
+	defer server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	client := &Client{conn: conn}
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"),
+		WithHTTPHeader("x-route-cluster", "west"), WithHTTPHeader("X-Route-Hint", "a"), WithHTTPHeader("x-route-hint", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "west", received.Get("x-route-cluster"))
+	assert.Equal(t, []string{"a", "b"}, received.Values("x-route-hint"))
+	assert.NotEmpty(t, received.Get(ClientRequestIdHeader))
+
+	_, err = conn.StreamIngestWithOptions(context.Background(), "db", "table", strings.NewReader(""), csvStreamFormat{}, "", "", false,
+		StreamIngestOptions{Headers: http.Header{"X-Route-Cluster": {"east"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "east", received.Get("x-route-cluster"))
+
+	_, err = conn.StreamIngestWithOptions(context.Background(), "db", "table", strings.NewReader(""), csvStreamFormat{}, "", "", false,
+		StreamIngestOptions{Headers: http.Header{"Authorization": {"Bearer x"}}})
+	assert.Error(t, err)
+}
+
+func TestStreamIngestUncompressed(t *testing.T) {
+	t.Parallel()
+
+	var received http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(emptyMgmtResponse))
+	}))
+	defer server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+
+	_, err = conn.StreamIngestWithInfo(context.Background(), "db", "table", strings.NewReader("gzip"), csvStreamFormat{}, "", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", received.Get("Content-Encoding"))
+
+	_, err = conn.StreamIngestWithOptions(context.Background(), "db", "table", strings.NewReader("a,b"), csvStreamFormat{}, "", "", false,
+		StreamIngestOptions{Uncompressed: true, Headers: http.Header{"X-Route-Cluster": {"east"}}})
+	require.NoError(t, err)
+	assert.Empty(t, received.Get("Content-Encoding"))
+	assert.Equal(t, "east", received.Get("x-route-cluster"))
+	assert.Equal(t, "a,b", string(body))
+}
+
+func TestValidateHTTPHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key, value string
+		wantErr    bool
+	}{
+		{key: "x-route-cluster", value: "west-1"},
+		{key: "X-Custom-Header", value: "a=b; c"},
+		{key: "x-route", value: ""},
+		{key: "Authorization", value: "Bearer x", wantErr: true},
+		{key: "Host", value: "other", wantErr: true},
+		{key: "x-ms-client-request-id", value: "id", wantErr: true},
+		{key: "X-MS-App", value: "app", wantErr: true},
+		{key: "x-", value: "v", wantErr: true},
+		{key: "x-route cluster", value: "v", wantErr: true},
+		{key: "x-route", value: "a\r\nHost: other", wantErr: true},
+		{key: "x-route", value: "é", wantErr: true},
+		{key: "x-route", value: strings.Repeat("a", 1025), wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := ValidateHTTPHeader(test.key, test.value)
+		if test.wantErr {
+			assert.Error(t, err, test.key)
+			continue
+		}
+		assert.NoError(t, err, test.key)
+	}
+
+	_, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, WithHTTPHeader("Cookie", "a"))
+	assert.Error(t, err)
+}
+
 func TestSchemas(t *testing.T) {
 	t.Parallel()
 
@@ -199,3 +305,360 @@ func TestSetConnectorDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryV1(t *testing.T) {
+	t.Parallel()
+
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1],[2]]}]}`))
+	}))
+	defer server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	client := &Client{conn: conn}
+
+	dataset, err := client.QueryV1(context.Background(), "db", kql.New("T | take 2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/rest/query", path)
+	assert.Equal(t, "db", body["db"])
+	assert.Equal(t, "T | take 2", body["csl"])
+
+	require.Len(t, dataset.Tables(), 1)
+	rows := dataset.Tables()[0].Rows()
+	require.Len(t, rows, 2)
+	assert.Equal(t, "1", rows[0].Values()[0].String())
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":"LimitsExceeded","message":"throttled"}}`))
+	}))
+	defer server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	client := &Client{conn: conn}
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	var httpErr *errors.HttpError
+	require.ErrorAs(t, err, &httpErr)
+	assert.True(t, httpErr.IsThrottled())
+	assert.Equal(t, 7*time.Second, httpErr.RetryAfter)
+
+	header := http.Header{}
+	assert.Zero(t, retryAfter(header))
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, time.Hour, retryAfter(header), float64(time.Minute))
+	header.Set("Retry-After", "soon")
+	assert.Zero(t, retryAfter(header))
+}
+
+func TestGatewayError(t *testing.T) {
+	t.Parallel()
+
+	page := "<!DOCTYPE html>\n<html>\n  <head><title>502 Bad Gateway</title></head>\n  <body>" + strings.Repeat("nginx ", 200) + "</body>\n</html>"
+
+	tests := []struct {
+		desc        string
+		status      int
+		contentType string
+		body        string
+		wantType    string
+		wantHTTP    bool
+	}{
+		{desc: "html error page", status: http.StatusBadGateway, contentType: "text/html; charset=utf-8", body: page, wantType: "text/html; charset=utf-8", wantHTTP: true},
+		{desc: "html page with 200", status: http.StatusOK, contentType: "text/html", body: page, wantType: "text/html"},
+		{desc: "sniffed html without content type", status: http.StatusServiceUnavailable, body: "\n  " + page, wantType: "text/html; charset=utf-8", wantHTTP: true},
+		{desc: "markup as plain text", status: http.StatusOK, contentType: "text/plain", body: page, wantType: "text/plain"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.contentType != "" {
+					w.Header().Set("Content-Type", test.contentType)
+				}
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+			require.NoError(t, err)
+			conn.endpointValidated.Store(true)
+			client := &Client{conn: conn}
+
+			_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+			var gatewayErr *errors.GatewayError
+			require.ErrorAs(t, err, &gatewayErr)
+			assert.Equal(t, test.status, gatewayErr.StatusCode)
+			assert.Equal(t, test.wantType, gatewayErr.ContentType)
+			assert.True(t, strings.HasPrefix(gatewayErr.Snippet, "<!DOCTYPE html> <html> <head><title>502 Bad Gateway</title>"), gatewayErr.Snippet)
+			assert.Len(t, gatewayErr.Snippet, errors.GatewaySnippetSize+len("..."))
+			assert.ErrorContains(t, err, "proxy or gateway")
+
+			var httpErr *errors.HttpError
+			assert.Equal(t, test.wantHTTP, goErrors.As(err, &httpErr))
+		})
+	}
+}
+
+func TestIsGatewayPage(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, isGatewayPage("application/json", strings.NewReader("<html>")))
+	assert.True(t, isGatewayPage("application/xml", strings.NewReader("")))
+	// Bodies are only peeked if they can be peeked without consuming them.
+	assert.False(t, isGatewayPage("", strings.NewReader("<html>")))
+}
+
+func TestDatabase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		db              string
+		defaultDatabase string
+		options         []QueryOption
+		want            string
+	}{
+		{name: "database", db: "db", defaultDatabase: "default", want: "db"},
+		{name: "initial catalog", defaultDatabase: "default", want: "default"},
+		{name: "override", db: "db", defaultDatabase: "default", options: []QueryOption{OverrideDatabase("other")}, want: "other"},
+		{name: "none"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1]]}]}`))
+			}))
+			defer server.Close()
+
+			conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+			require.NoError(t, err)
+			conn.endpointValidated.Store(true)
+			client := &Client{conn: conn, defaultDatabase: test.defaultDatabase}
+
+			_, err = client.Mgmt(context.Background(), test.db, kql.New(".show tables"), test.options...)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, body["db"])
+		})
+	}
+
+	_, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, OverrideDatabase(" "))
+	assert.Error(t, err)
+}
+
+func TestResponseCompression(t *testing.T) {
+	t.Parallel()
+
+	const response = `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"String","ColumnType":"string"}],"Rows":[["aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"]]}]}`
+
+	encode := func(w io.Writer, encoding string) io.WriteCloser {
+		switch encoding {
+		case "gzip":
+			return gzip.NewWriter(w)
+		case "deflate":
+			return zlib.NewWriter(w)
+		case "raw-deflate":
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name           string
+		options        []QueryOption
+		encoding       string
+		wantAccept     string
+		wantEncoding   string
+		wantCompressed bool
+	}{
+		{name: "gzip", encoding: "gzip", wantAccept: "gzip, deflate", wantEncoding: "gzip", wantCompressed: true},
+		{name: "deflate", encoding: "deflate", wantAccept: "gzip, deflate", wantEncoding: "deflate", wantCompressed: true},
+		{name: "raw deflate", encoding: "raw-deflate", wantAccept: "gzip, deflate", wantEncoding: "deflate", wantCompressed: true},
+		{name: "not compressed", wantAccept: "gzip, deflate"},
+		{name: "disabled", options: []QueryOption{NoResponseCompression()}, wantAccept: "identity"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var accept string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept-Encoding")
+				encoder := encode(w, test.encoding)
+				if encoder == nil {
+					_, _ = w.Write([]byte(response))
+					return
+				}
+				w.Header().Set("Content-Encoding", test.wantEncoding)
+				_, _ = encoder.Write([]byte(response))
+				_ = encoder.Close()
+			}))
+			defer server.Close()
+
+			conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+			require.NoError(t, err)
+			conn.endpointValidated.Store(true)
+			client := &Client{conn: conn}
+
+			dataset, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"), test.options...)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantAccept, accept)
+
+			rows := dataset.Tables()[0].Rows()
+			require.Len(t, rows, 1)
+
+			stats := query.DatasetTransferStats(dataset)
+			assert.Equal(t, test.wantEncoding, stats.Encoding)
+			assert.Equal(t, int64(len(response)), stats.DecompressedBytes)
+			if test.wantCompressed {
+				assert.Less(t, stats.CompressedBytes, stats.DecompressedBytes)
+			} else {
+				assert.Equal(t, stats.DecompressedBytes, stats.CompressedBytes)
+			}
+		})
+	}
+}
+
+func TestListParameters(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]int64, 5000)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	query := kql.New("T | where Id in ").AddLongList(ids...).AddLiteral(" and Name == name")
+
+	opts, err := setQueryOptions(context.Background(), errors.OpQuery, query, queryCall, QueryParameters(kql.NewParameters().AddString("name", "a")))
+	require.NoError(t, err)
+	assert.Equal(t, 2, opts.requestProperties.QueryParameters.Count())
+	assert.Equal(t, `"a"`, opts.requestProperties.Parameters["name"])
+	assert.True(t, strings.HasPrefix(opts.requestProperties.Parameters["__kql_list_0"], "dynamic([0,1,2,"))
+
+	// Queries that are too long are rejected before they are sent.
+	_, err = setQueryOptions(context.Background(), errors.OpQuery, kql.New("T | where Id in ").InlineLists().AddLongList(ids...).
+		AddUnsafe(strings.Repeat(" ", maxQueryTextLength)), queryCall)
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KClientArgs, e.Kind)
+}
+
+// csvStreamFormat is the CSV format of streaming ingestions.
+type csvStreamFormat struct{}
+
+func (csvStreamFormat) CamelCase() string                        { return "Csv" }
+func (f csvStreamFormat) KnownOrDefault() DataFormatForStreaming { return f }
+
+func TestStreamIngestInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc            string
+		clientRequestID string
+		status          int
+		echo            func(sent string) string
+		want            StreamIngestInfo
+		wantErr         bool
+	}{
+		{
+			desc:            "Success",
+			clientRequestID: "MyApp;1",
+			status:          http.StatusOK,
+			echo:            func(sent string) string { return sent },
+			want:            StreamIngestInfo{ClientRequestID: "MyApp;1", EchoedClientRequestID: "MyApp;1", ActivityID: "activity"},
+		},
+		{
+			desc:            "Altered on its way",
+			clientRequestID: "MyApp;é",
+			status:          http.StatusOK,
+			echo:            func(sent string) string { return sent },
+			want:            StreamIngestInfo{ClientRequestID: "MyApp;é", EchoedClientRequestID: "MyApp;?", ActivityID: "activity"},
+		},
+		{
+			desc:            "Failure",
+			clientRequestID: "MyApp;2",
+			status:          http.StatusBadRequest,
+			echo:            func(sent string) string { return sent },
+			want:            StreamIngestInfo{ClientRequestID: "MyApp;2", EchoedClientRequestID: "MyApp;2", ActivityID: "activity"},
+			wantErr:         true,
+		},
+		{
+			desc:            "Not echoed",
+			clientRequestID: "MyApp;3",
+			status:          http.StatusOK,
+			echo:            func(string) string { return "" },
+			want:            StreamIngestInfo{ClientRequestID: "MyApp;3"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if echoed := test.echo(r.Header.Get(ClientRequestIdHeader)); echoed != "" {
+					w.Header().Set(ClientRequestIdHeader, echoed)
+					w.Header().Set(ActivityIdHeader, "activity")
+				}
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+			require.NoError(t, err)
+			conn.endpointValidated.Store(true)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			info, err := conn.StreamIngestWithInfo(ctx, "db", "table", strings.NewReader("a,b"), csvStreamFormat{}, "", test.clientRequestID, false)
+			assert.Equal(t, test.want, info)
+			if !test.wantErr {
+				require.NoError(t, err)
+				return
+			}
+
+			var httpErr *errors.HttpError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, test.want.EchoedClientRequestID, httpErr.ClientRequestID)
+			assert.Equal(t, test.want.ActivityID, httpErr.ActivityID)
+		})
+	}
+
+	// The generated id is reported when the service can't be reached.
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	info, err := conn.StreamIngestWithInfo(context.Background(), "db", "table", strings.NewReader(""), csvStreamFormat{}, "", "", false)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(info.ClientRequestID, "KGC.executeStreaming;"), "a client request id is generated")
+}
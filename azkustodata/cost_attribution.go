@@ -0,0 +1,64 @@
+package azkustodata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CostAttribution describes who a request should be attributed to. It is stamped on requests with the
+// AttributeCost QueryOption, and can then be used to aggregate the cost of requests in the `.show queries` and
+// `.show commands` outputs.
+type CostAttribution struct {
+	// Team is the team that owns the request.
+	Team string
+	// Pipeline is the pipeline or service that made the request.
+	Pipeline string
+	// Env is the environment of the caller, such as "prod" or "dev".
+	Env string
+}
+
+// String returns the standardized representation of the attribution, which is sent as the request_app_name property.
+// The format is "team=<team>;pipeline=<pipeline>;env=<env>", empty fields are omitted.
+func (c CostAttribution) String() string {
+	parts := make([]string, 0, 3)
+	for _, p := range c.parts() {
+		if p[1] != "" {
+			parts = append(parts, p[0]+"="+p[1])
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+func (c CostAttribution) parts() [][2]string {
+	return [][2]string{{"team", c.Team}, {"pipeline", c.Pipeline}, {"env", c.Env}}
+}
+
+func (c CostAttribution) validate() error {
+	for _, p := range c.parts() {
+		if strings.ContainsAny(p[1], ";=") {
+			return fmt.Errorf("cost attribution %s %q cannot contain ';' or '='", p[0], p[1])
+		}
+	}
+	if c.String() == "" {
+		return fmt.Errorf("cost attribution must have at least one field set")
+	}
+	return nil
+}
+
+// AttributeCost stamps the request with the given cost attribution, using the request_app_name property.
+// To attribute every request of a client from one spot, pass it to New() with WithDefaultQueryOptions:
+//
+//	client, err := azkustodata.New(kcsb, azkustodata.WithDefaultQueryOptions(azkustodata.AttributeCost(azkustodata.CostAttribution{
+//		Team:     "analytics",
+//		Pipeline: "daily-rollup",
+//		Env:      "prod",
+//	})))
+func AttributeCost(attribution CostAttribution) QueryOption {
+	return func(q *queryOptions) error {
+		if err := attribution.validate(); err != nil {
+			return err
+		}
+		q.requestProperties.Options[RequestAppNameValue] = attribution.String()
+		return nil
+	}
+}
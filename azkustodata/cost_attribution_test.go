@@ -0,0 +1,61 @@
+package azkustodata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeCost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		attribution CostAttribution
+		want        string
+		wantErr     bool
+	}{
+		{name: "all fields", attribution: CostAttribution{Team: "analytics", Pipeline: "rollup", Env: "prod"}, want: "team=analytics;pipeline=rollup;env=prod"},
+		{name: "partial", attribution: CostAttribution{Team: "analytics", Env: "dev"}, want: "team=analytics;env=dev"},
+		{name: "empty", attribution: CostAttribution{}, wantErr: true},
+		{name: "separator", attribution: CostAttribution{Team: "a;b"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, AttributeCost(test.attribution))
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, opts.requestProperties.Options[RequestAppNameValue])
+		})
+	}
+}
+
+func TestDefaultQueryOptions(t *testing.T) {
+	t.Parallel()
+
+	conn := &fakeMgmtConn{responses: []string{operationIdResponse, operationIdResponse}}
+	client := &Client{conn: conn}
+	WithDefaultQueryOptions(AttributeCost(CostAttribution{Team: "analytics"}), RequestDescription("default"))(client)
+
+	_, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	require.NoError(t, err)
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"), RequestDescription("override"))
+	require.NoError(t, err)
+
+	require.Len(t, conn.options, 2)
+	assert.Equal(t, "team=analytics", conn.options[0].requestProperties.Options[RequestAppNameValue])
+	assert.Equal(t, "default", conn.options[0].requestProperties.Options[RequestDescriptionValue])
+	assert.Equal(t, "team=analytics", conn.options[1].requestProperties.Options[RequestAppNameValue])
+	assert.Equal(t, "override", conn.options[1].requestProperties.Options[RequestDescriptionValue])
+}
@@ -64,6 +64,9 @@ Example for Management/Administration commands:
 
 To handle results, the package provides utilities to directly stream rows, fetch tables into memory, and map results to structs.
 
+A Client is safe for concurrent use by multiple goroutines. Create one per cluster and share it, instead of creating one
+per query.
+
 For complete documentation, please visit:
 https://github.com/Azure/azure-kusto-go
 https://pkg.go.dev/github.com/Azure/azure-kusto-go/azkustodata
@@ -18,6 +18,8 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Separator is the string used to separate nested errors. By
@@ -85,6 +87,13 @@ type KustoError = Error
 type HttpError struct {
 	KustoError
 	StatusCode int
+	// RetryAfter is the delay the service asked to wait before retrying, from the Retry-After header of the response,
+	// or 0 if it didn't set one.
+	RetryAfter time.Duration
+	// ClientRequestID and ActivityID are the ids of the request, as echoed by the service in the x-ms-client-request-id
+	// and x-ms-activity-id headers of the response, if it set them. Support uses them to trace the request.
+	ClientRequestID string
+	ActivityID      string
 }
 
 // UnmarshalREST will unmarshal an error message from the server if the message is in
@@ -304,6 +313,134 @@ func W(inner error, outer error) *Error {
 	return o
 }
 
+// FrameIdleTimeoutError is returned when no data was received from the service for longer than the idle timeout of the
+// client, while reading the response of a query.
+type FrameIdleTimeoutError struct {
+	KustoError
+	// Timeout is the idle timeout that was exceeded.
+	Timeout time.Duration
+	// RowsDelivered is the number of rows that were read from the response before it stalled.
+	RowsDelivered int64
+}
+
+// FrameIdleTimeout constructs a *FrameIdleTimeoutError for the given timeout.
+func FrameIdleTimeout(o Op, timeout time.Duration) *FrameIdleTimeoutError {
+	return &FrameIdleTimeoutError{
+		KustoError: KustoError{
+			Op:   o,
+			Kind: KTimeout,
+			Err:  fmt.Errorf("no data was received from the service for %s", timeout),
+		},
+		Timeout: timeout,
+	}
+}
+
+func (e *FrameIdleTimeoutError) Error() string {
+	return fmt.Sprintf("%s, after %d rows were delivered", e.KustoError.Error(), e.RowsDelivered)
+}
+
+func (e *FrameIdleTimeoutError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.KustoError.Unwrap()
+}
+
+// ResponseTooLargeError is returned when the response of a query is larger than the limit set with the
+// MaxResponseBytes option, and the client stopped reading it.
+type ResponseTooLargeError struct {
+	KustoError
+	// Limit is the maximum size of the response, in bytes.
+	Limit int64
+	// BytesRead is the number of bytes of the response that were read, after decompression.
+	BytesRead int64
+	// RowsDelivered is the number of rows that were read from the response before it was aborted.
+	RowsDelivered int64
+}
+
+// ResponseTooLarge constructs a *ResponseTooLargeError for the given limit.
+func ResponseTooLarge(o Op, limit int64, bytesRead int64) *ResponseTooLargeError {
+	return &ResponseTooLargeError{
+		KustoError: KustoError{
+			Op:        o,
+			Kind:      KLimitsExceeded,
+			Err:       fmt.Errorf("the response exceeded the limit of %d bytes", limit),
+			permanent: true,
+		},
+		Limit:     limit,
+		BytesRead: bytesRead,
+	}
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s, after %d bytes were read and %d rows were delivered", e.KustoError.Error(), e.BytesRead, e.RowsDelivered)
+}
+
+func (e *ResponseTooLargeError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.KustoError.Unwrap()
+}
+
+// GatewaySnippetSize is the maximum size of the Snippet of a GatewayError, in bytes.
+const GatewaySnippetSize = 512
+
+// GatewayError is returned when the response is not from the service, but a page of a proxy, gateway or firewall
+// between the client and the service, such as the HTML error page of a load balancer, whatever its status.
+type GatewayError struct {
+	KustoError
+	StatusCode int
+	// ContentType is the Content-Type of the response, which can be empty.
+	ContentType string
+	// Snippet is the start of the body of the response, with its whitespace collapsed, up to GatewaySnippetSize bytes.
+	Snippet string
+	// HTTP is the error of the status of the response, or nil if the status is 200 OK. It is returned by Unwrap, so that
+	// the status is handled like those of the service, such as retries after a 503 Service Unavailable.
+	HTTP *HttpError
+}
+
+// Gateway constructs a *GatewayError from the status, the content type and the start of the body of a response.
+func Gateway(o Op, status string, statusCode int, contentType string, body []byte) *GatewayError {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if len(snippet) > GatewaySnippetSize {
+		cut := GatewaySnippetSize
+		for cut > 0 && !utf8.RuneStart(snippet[cut]) {
+			cut--
+		}
+		snippet = snippet[:cut] + "..."
+	}
+	shownType := contentType
+	if shownType == "" {
+		shownType = "none"
+	}
+	return &GatewayError{
+		KustoError: KustoError{
+			Op:   o,
+			Kind: KHTTPError,
+			Err: fmt.Errorf("the response is not from Kusto, but likely from a proxy or gateway (status %s, content type %s): %s",
+				status, shownType, snippet),
+		},
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Snippet:     snippet,
+	}
+}
+
+func (e *GatewayError) Error() string {
+	return e.KustoError.Error()
+}
+
+func (e *GatewayError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	if e.HTTP != nil {
+		return e.HTTP
+	}
+	return e.KustoError.Unwrap()
+}
+
 func (e *HttpError) IsThrottled() bool {
 	return e != nil && (e.StatusCode == http.StatusTooManyRequests)
 }
@@ -326,6 +463,12 @@ func GetKustoError(err error) (*Error, bool) {
 	if err, ok := err.(*HttpError); ok {
 		return &err.KustoError, true
 	}
+	if err, ok := err.(*FrameIdleTimeoutError); ok {
+		return &err.KustoError, true
+	}
+	if err, ok := err.(*ResponseTooLargeError); ok {
+		return &err.KustoError, true
+	}
 	return nil, false
 }
 
@@ -389,3 +532,61 @@ func CombineErrors(errs ...error) error {
 	}
 	return combined.Unwrap()
 }
+
+// CellError is returned when a cell of a result can't be converted to the type of its column, and locates the cell.
+type CellError struct {
+	KustoError
+	// Table is the name of the table of the cell, if it is known.
+	Table string
+	// Row is the index of the row of the cell within its table.
+	Row int
+	// Column is the name of the column of the cell.
+	Column string
+}
+
+// Cell constructs a *CellError for the conversion error of a cell.
+func Cell(o Op, table string, row int, column string, err error) *CellError {
+	return &CellError{
+		KustoError: KustoError{
+			Op:        o,
+			Kind:      KFailedToParse,
+			Err:       err,
+			permanent: true,
+		},
+		Table:  table,
+		Row:    row,
+		Column: column,
+	}
+}
+
+func (e *CellError) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("%s, in row %d, column %q", e.KustoError.Error(), e.Row, e.Column)
+	}
+	return fmt.Sprintf("%s, in row %d, column %q of table %q", e.KustoError.Error(), e.Row, e.Column, e.Table)
+}
+
+func (e *CellError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.KustoError.Unwrap()
+}
+
+// RecordError locates the record of an ingestion payload that failed validation.
+type RecordError struct {
+	// Record is the 1-based index of the record in the payload.
+	Record int
+	// Line is the 1-based line of the payload the record starts at.
+	Line int
+	// Err describes what is wrong with the record.
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d (line %d) %s", e.Record, e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,71 @@
+package azkustodata
+
+// The values of QueryConsistency.
+const (
+	// StrongConsistency runs queries on the admin node of the cluster, which has the latest metadata.
+	StrongConsistency = "strongconsistency"
+	// WeakConsistency lets any node of the cluster run queries, with metadata that may lag behind.
+	WeakConsistency = "weakconsistency"
+	// AffinitizedWeakConsistency runs the queries of a client on the same node, with metadata that may lag behind.
+	AffinitizedWeakConsistency = "affinitizedweakconsistency"
+	// DatabaseAffinitizedWeakConsistency runs the queries of a database on the same node, with metadata that may lag
+	// behind.
+	DatabaseAffinitizedWeakConsistency = "databaseaffinitizedweakconsistency"
+)
+
+// FollowerOption is an option of WithFollowerDatabase.
+type FollowerOption func(f *followerOptions)
+
+type followerOptions struct {
+	consistency string
+	hotCache    bool
+}
+
+// FollowerConsistency sets the consistency of the queries of a follower database, in place of WeakConsistency.
+func FollowerConsistency(consistency string) FollowerOption {
+	return func(f *followerOptions) {
+		f.consistency = consistency
+	}
+}
+
+// FollowerHotCache limits the queries of a follower database to the data in its hot cache, like
+// `set query_datascope="hotcache"`, so that they don't read cold data from the storage of the leader.
+func FollowerHotCache() FollowerOption {
+	return func(f *followerOptions) {
+		f.hotCache = true
+	}
+}
+
+// WithFollowerDatabase marks db as a follower database, a read replica of a database of another cluster. The queries
+// of the database use weak consistency by default, since the metadata of a follower already lags behind its leader,
+// which spreads them over all the nodes of the cluster.
+// The options only apply to Query, IterativeQuery and QueryV1, and the QueryConsistency and QueryDataScope options of
+// a call take precedence over them.
+func WithFollowerDatabase(db string, options ...FollowerOption) Option {
+	f := followerOptions{consistency: WeakConsistency}
+	for _, o := range options {
+		o(&f)
+	}
+	return func(c *Client) {
+		if c.followers == nil {
+			c.followers = map[string]followerOptions{}
+		}
+		c.followers[db] = f
+	}
+}
+
+// applyFollower applies the options of the follower database db to the options of a query, if db is a follower.
+func (c *Client) applyFollower(db string, opts *queryOptions) {
+	f, ok := c.followers[db]
+	if !ok {
+		return
+	}
+
+	properties := opts.requestProperties.Options
+	if _, ok := properties[QueryConsistencyValue]; !ok && f.consistency != "" {
+		properties[QueryConsistencyValue] = f.consistency
+	}
+	if _, ok := properties[QueryDatascopeValue]; !ok && f.hotCache {
+		properties[QueryDatascopeValue] = string(DSHotCache)
+	}
+}
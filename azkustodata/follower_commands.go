@@ -0,0 +1,226 @@
+package azkustodata
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// FollowerDatabase is a row of `.show follower databases`, the configuration of a database that follows a database
+// of another cluster.
+// The databases are attached and detached with the Azure Resource Manager API of the follower cluster, there are no
+// management commands for it.
+type FollowerDatabase struct {
+	DatabaseName string
+	// LeaderClusterMetadataPath is the path of the metadata of the leader cluster in its storage.
+	LeaderClusterMetadataPath string
+	// CachingPolicyOverride and AuthorizedPrincipalsOverride are the overrides of the follower, as JSON.
+	CachingPolicyOverride        string
+	AuthorizedPrincipalsOverride string
+	// AuthorizedPrincipalsModificationKind and CachingPoliciesModificationKind are how the overrides are combined with
+	// the settings of the leader, see FollowerModificationKind.
+	AuthorizedPrincipalsModificationKind string
+	CachingPoliciesModificationKind      string
+	// IsAutoPrefetchEnabled is set if the follower prefetches new extents before they are queried, see
+	// SetFollowerPrefetchExtents.
+	IsAutoPrefetchEnabled bool
+	// TableMetadataOverrides are the overrides of the tables of the follower, as JSON.
+	TableMetadataOverrides string
+}
+
+// FollowerRole is a role that can be granted to the principals of a follower database.
+type FollowerRole string
+
+const (
+	FollowerAdmins   FollowerRole = "admins"
+	FollowerUsers    FollowerRole = "users"
+	FollowerViewers  FollowerRole = "viewers"
+	FollowerMonitors FollowerRole = "monitors"
+)
+
+// FollowerModificationKind is how the overrides of a follower database are combined with the settings of its leader.
+type FollowerModificationKind string
+
+const (
+	// FollowerModificationNone ignores the overrides, and keeps the settings of the leader.
+	FollowerModificationNone FollowerModificationKind = "none"
+	// FollowerModificationUnion adds the overrides to the settings of the leader.
+	FollowerModificationUnion FollowerModificationKind = "union"
+	// FollowerModificationReplace replaces the settings of the leader with the overrides.
+	FollowerModificationReplace FollowerModificationKind = "replace"
+)
+
+// ShowFollowerDatabases returns the follower databases of the cluster with `.show follower databases`, or only the
+// given ones.
+func (c *Client) ShowFollowerDatabases(ctx context.Context, databases []string, options ...QueryOption) ([]FollowerDatabase, error) {
+	cmd := kql.New(".show follower databases")
+	if len(databases) > 0 {
+		names := make([]string, len(databases))
+		for i, db := range databases {
+			if db == "" {
+				return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "ShowFollowerDatabases database names cannot be empty").SetNoRetry()
+			}
+			names[i] = kql.NormalizeName(db)
+		}
+		cmd.AddLiteral(" (").AddUnsafe(strings.Join(names, ", ")).AddLiteral(")")
+	}
+
+	dataset, err := c.Mgmt(ctx, "", cmd, options...)
+	if err != nil {
+		return nil, err
+	}
+	return query.ToStructs[FollowerDatabase](dataset)
+}
+
+// ShowFollowerDatabase returns the configuration of the follower database db, with `.show follower database`.
+func (c *Client) ShowFollowerDatabase(ctx context.Context, db string, options ...QueryOption) (*FollowerDatabase, error) {
+	if db == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "ShowFollowerDatabase requires a database").SetNoRetry()
+	}
+
+	dataset, err := c.Mgmt(ctx, "", kql.New(".show follower database ").AddUnsafe(kql.NormalizeName(db)), options...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := query.ToStructs[FollowerDatabase](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single row for follower database %s, got %d", db, len(rows))
+	}
+	return &rows[0], nil
+}
+
+// AddFollowerPrincipals grants role on the follower database db to the principals, such as
+// "aaduser=user@fabrikam.com", with `.add follower database`. notes are recorded with the grant, and are optional.
+// They are only applied if the principals modification kind of the database isn't FollowerModificationNone.
+func (c *Client) AddFollowerPrincipals(ctx context.Context, db string, role FollowerRole, principals []string, notes string, options ...QueryOption) error {
+	list, err := principalsList("AddFollowerPrincipals", db, role, principals)
+	if err != nil {
+		return err
+	}
+
+	cmd := kql.New(".add follower database ").AddUnsafe(kql.NormalizeName(db)).AddLiteral(" ").AddUnsafe(string(role)).AddLiteral(" ").AddUnsafe(list)
+	if notes != "" {
+		cmd.AddLiteral(" ").AddUnsafe(kql.QuoteString(notes, false))
+	}
+	return c.followerMgmt(ctx, cmd, options)
+}
+
+// DropFollowerPrincipals revokes role on the follower database db from the principals, with `.drop follower database`.
+func (c *Client) DropFollowerPrincipals(ctx context.Context, db string, role FollowerRole, principals []string, options ...QueryOption) error {
+	list, err := principalsList("DropFollowerPrincipals", db, role, principals)
+	if err != nil {
+		return err
+	}
+
+	cmd := kql.New(".drop follower database ").AddUnsafe(kql.NormalizeName(db)).AddLiteral(" ").AddUnsafe(string(role)).AddLiteral(" ").AddUnsafe(list)
+	return c.followerMgmt(ctx, cmd, options)
+}
+
+// SetFollowerPrincipalsModificationKind sets how the principals added to the follower database db are combined with the
+// principals of its leader, with `.alter follower database principals-modification-kind`.
+func (c *Client) SetFollowerPrincipalsModificationKind(ctx context.Context, db string, kind FollowerModificationKind, options ...QueryOption) error {
+	if err := validateModificationKind("SetFollowerPrincipalsModificationKind", db, kind); err != nil {
+		return err
+	}
+	return c.followerMgmt(ctx, alterFollower(db, " principals-modification-kind = ").AddUnsafe(string(kind)), options)
+}
+
+// SetFollowerCachingPoliciesModificationKind sets how the caching policies set on the follower database db are combined
+// with the caching policies of its leader, with `.alter follower database caching-policies-modification-kind`.
+func (c *Client) SetFollowerCachingPoliciesModificationKind(ctx context.Context, db string, kind FollowerModificationKind, options ...QueryOption) error {
+	if err := validateModificationKind("SetFollowerCachingPoliciesModificationKind", db, kind); err != nil {
+		return err
+	}
+	return c.followerMgmt(ctx, alterFollower(db, " caching-policies-modification-kind = ").AddUnsafe(string(kind)), options)
+}
+
+// SetFollowerPrefetchExtents sets whether the follower database db caches the new extents of its leader before they
+// are queried, with `.alter follower database prefetch-extents`.
+func (c *Client) SetFollowerPrefetchExtents(ctx context.Context, db string, enabled bool, options ...QueryOption) error {
+	if db == "" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "SetFollowerPrefetchExtents requires a database").SetNoRetry()
+	}
+	return c.followerMgmt(ctx, alterFollower(db, " prefetch-extents = ").AddBool(enabled), options)
+}
+
+// SetFollowerCachingPolicy overrides the hot cache period of the follower database db, or of its table if table isn't
+// empty, with `.alter follower database policy caching`.
+func (c *Client) SetFollowerCachingPolicy(ctx context.Context, db, table string, hot time.Duration, options ...QueryOption) error {
+	if db == "" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "SetFollowerCachingPolicy requires a database").SetNoRetry()
+	}
+	if hot < 0 {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "SetFollowerCachingPolicy hot cache period cannot be negative, got %s", hot).SetNoRetry()
+	}
+	return c.followerMgmt(ctx, alterFollower(db, tablePrefix(table)+" policy caching hot = ").AddTimespan(hot), options)
+}
+
+// DeleteFollowerCachingPolicy removes the caching policy override of the follower database db, or of its table if
+// table isn't empty, with `.delete follower database policy caching`.
+func (c *Client) DeleteFollowerCachingPolicy(ctx context.Context, db, table string, options ...QueryOption) error {
+	if db == "" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "DeleteFollowerCachingPolicy requires a database").SetNoRetry()
+	}
+	cmd := kql.New(".delete follower database ").AddUnsafe(kql.NormalizeName(db)).AddUnsafe(tablePrefix(table)).AddLiteral(" policy caching")
+	return c.followerMgmt(ctx, cmd, options)
+}
+
+func (c *Client) followerMgmt(ctx context.Context, cmd Statement, options []QueryOption) error {
+	_, err := c.Mgmt(ctx, "", cmd, options...)
+	return err
+}
+
+// alterFollower returns `.alter follower database db` followed by rest.
+func alterFollower(db string, rest string) *kql.Builder {
+	return kql.New(".alter follower database ").AddUnsafe(kql.NormalizeName(db)).AddUnsafe(rest)
+}
+
+// tablePrefix returns the ` table T` part of the commands on a table of a follower database, or nothing if table is
+// empty.
+func tablePrefix(table string) string {
+	if table == "" {
+		return ""
+	}
+	return " table " + kql.NormalizeName(table)
+}
+
+func principalsList(op string, db string, role FollowerRole, principals []string) (string, error) {
+	if db == "" {
+		return "", errors.ES(errors.OpMgmt, errors.KClientArgs, "%s requires a database", op).SetNoRetry()
+	}
+	switch role {
+	case FollowerAdmins, FollowerUsers, FollowerViewers, FollowerMonitors:
+	default:
+		return "", errors.ES(errors.OpMgmt, errors.KClientArgs, "%s role %q is not valid", op, role).SetNoRetry()
+	}
+	if len(principals) == 0 {
+		return "", errors.ES(errors.OpMgmt, errors.KClientArgs, "%s requires principals", op).SetNoRetry()
+	}
+
+	quoted := make([]string, len(principals))
+	for i, p := range principals {
+		if p == "" {
+			return "", errors.ES(errors.OpMgmt, errors.KClientArgs, "%s principals cannot be empty", op).SetNoRetry()
+		}
+		quoted[i] = kql.QuoteString(p, false)
+	}
+	return "(" + strings.Join(quoted, ", ") + ")", nil
+}
+
+func validateModificationKind(op string, db string, kind FollowerModificationKind) error {
+	if db == "" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "%s requires a database", op).SetNoRetry()
+	}
+	switch kind {
+	case FollowerModificationNone, FollowerModificationUnion, FollowerModificationReplace:
+		return nil
+	}
+	return errors.ES(errors.OpMgmt, errors.KClientArgs, "%s modification kind %q is not valid", op, kind).SetNoRetry()
+}
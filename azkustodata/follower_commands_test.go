@@ -0,0 +1,161 @@
+package azkustodata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const followerDatabasesResponse = `{"Tables":[{"TableName":"Table_0","Columns":[` +
+	`{"ColumnName":"DatabaseName","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"LeaderClusterMetadataPath","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"CachingPolicyOverride","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"AuthorizedPrincipalsOverride","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"AuthorizedPrincipalsModificationKind","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"IsAutoPrefetchEnabled","DataType":"Boolean","ColumnType":"bool"},` +
+	`{"ColumnName":"TableMetadataOverrides","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"CachingPoliciesModificationKind","DataType":"String","ColumnType":"string"}],` +
+	`"Rows":[` +
+	`["Logs","https://leader.blob.core.windows.net/cluster","null","[]","None",false,"","Union"],` +
+	`["Metrics","https://leader.blob.core.windows.net/cluster","{\"DataHotSpan\":\"7.00:00:00\"}","[]","Replace",true,"","None"]]}]}`
+
+func TestShowFollowerDatabases(t *testing.T) {
+	t.Parallel()
+
+	conn := &fakeMgmtConn{responses: []string{followerDatabasesResponse, followerDatabasesResponse, followerDatabasesResponse}}
+	client := &Client{conn: conn}
+
+	followers, err := client.ShowFollowerDatabases(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, followers, 2)
+	assert.Equal(t, FollowerDatabase{
+		DatabaseName:                         "Metrics",
+		LeaderClusterMetadataPath:            "https://leader.blob.core.windows.net/cluster",
+		CachingPolicyOverride:                `{"DataHotSpan":"7.00:00:00"}`,
+		AuthorizedPrincipalsOverride:         "[]",
+		AuthorizedPrincipalsModificationKind: "Replace",
+		CachingPoliciesModificationKind:      "None",
+		IsAutoPrefetchEnabled:                true,
+	}, followers[1])
+
+	_, err = client.ShowFollowerDatabases(context.Background(), []string{"Logs", "my-db"})
+	require.NoError(t, err)
+
+	// A single database must return a single row.
+	_, err = client.ShowFollowerDatabase(context.Background(), "Logs")
+	assert.ErrorContains(t, err, "got 2")
+
+	assert.Equal(t, []string{
+		".show follower databases",
+		`.show follower databases (Logs, ["my-db"])`,
+		".show follower database Logs",
+	}, conn.commands)
+
+	_, err = client.ShowFollowerDatabases(context.Background(), []string{""})
+	assert.Error(t, err)
+	_, err = client.ShowFollowerDatabase(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestFollowerCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		call func(c *Client) error
+		want string
+	}{
+		{
+			desc: "add principals",
+			call: func(c *Client) error {
+				return c.AddFollowerPrincipals(context.Background(), "Logs", FollowerViewers, []string{"aaduser=a@fabrikam.com", "aadapp=b"}, "readers")
+			},
+			want: `.add follower database Logs viewers ("aaduser=a@fabrikam.com", "aadapp=b") "readers"`,
+		},
+		{
+			desc: "drop principals",
+			call: func(c *Client) error {
+				return c.DropFollowerPrincipals(context.Background(), "Logs", FollowerAdmins, []string{"aaduser=a@fabrikam.com"})
+			},
+			want: `.drop follower database Logs admins ("aaduser=a@fabrikam.com")`,
+		},
+		{
+			desc: "principals modification kind",
+			call: func(c *Client) error {
+				return c.SetFollowerPrincipalsModificationKind(context.Background(), "Logs", FollowerModificationUnion)
+			},
+			want: ".alter follower database Logs principals-modification-kind = union",
+		},
+		{
+			desc: "caching policies modification kind",
+			call: func(c *Client) error {
+				return c.SetFollowerCachingPoliciesModificationKind(context.Background(), "Logs", FollowerModificationReplace)
+			},
+			want: ".alter follower database Logs caching-policies-modification-kind = replace",
+		},
+		{
+			desc: "prefetch extents",
+			call: func(c *Client) error {
+				return c.SetFollowerPrefetchExtents(context.Background(), "Logs", true)
+			},
+			want: ".alter follower database Logs prefetch-extents = bool(true)",
+		},
+		{
+			desc: "database caching policy",
+			call: func(c *Client) error {
+				return c.SetFollowerCachingPolicy(context.Background(), "Logs", "", 7*24*time.Hour)
+			},
+			want: ".alter follower database Logs policy caching hot = timespan(7.00:00:00.0000000)",
+		},
+		{
+			desc: "table caching policy",
+			call: func(c *Client) error {
+				return c.SetFollowerCachingPolicy(context.Background(), "Logs", "Events", time.Hour)
+			},
+			want: ".alter follower database Logs table Events policy caching hot = timespan(01:00:00.0000000)",
+		},
+		{
+			desc: "delete table caching policy",
+			call: func(c *Client) error {
+				return c.DeleteFollowerCachingPolicy(context.Background(), "Logs", "Events")
+			},
+			want: ".delete follower database Logs table Events policy caching",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &fakeMgmtConn{responses: []string{emptyMgmtResponse}}
+			require.NoError(t, test.call(&Client{conn: conn}))
+			assert.Equal(t, []string{test.want}, conn.commands)
+		})
+	}
+}
+
+func TestFollowerCommandsValidation(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &fakeMgmtConn{}}
+	ctx := context.Background()
+	for _, err := range []error{
+		client.AddFollowerPrincipals(ctx, "", FollowerAdmins, []string{"aaduser=a"}, ""),
+		client.AddFollowerPrincipals(ctx, "Logs", "owners", []string{"aaduser=a"}, ""),
+		client.AddFollowerPrincipals(ctx, "Logs", FollowerAdmins, nil, ""),
+		client.DropFollowerPrincipals(ctx, "Logs", FollowerAdmins, []string{""}),
+		client.SetFollowerPrincipalsModificationKind(ctx, "Logs", "all"),
+		client.SetFollowerCachingPoliciesModificationKind(ctx, "", FollowerModificationNone),
+		client.SetFollowerPrefetchExtents(ctx, "", true),
+		client.SetFollowerCachingPolicy(ctx, "Logs", "", -time.Hour),
+		client.DeleteFollowerCachingPolicy(ctx, "", ""),
+	} {
+		require.Error(t, err)
+		assert.False(t, errors.Retry(err))
+	}
+}
@@ -0,0 +1,117 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// optionsConn records the options of the calls, and returns a v2 or v1 response.
+type optionsConn struct {
+	options []*queryOptions
+}
+
+func (o *optionsConn) rawQuery(_ context.Context, call callType, _ string, _ Statement, options *queryOptions) (io.ReadCloser, error) {
+	o.options = append(o.options, options)
+	if call == queryCall {
+		return io.NopCloser(strings.NewReader(completeQueryResponse)), nil
+	}
+	return io.NopCloser(strings.NewReader(emptyMgmtResponse)), nil
+}
+
+func (o *optionsConn) Close() error {
+	return nil
+}
+
+func TestFollowerDatabase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		db      string
+		call    func(c *Client, db string) error
+		options []FollowerOption
+		want    map[string]interface{}
+	}{
+		{
+			desc: "Weak consistency by default",
+			db:   "follower",
+			call: func(c *Client, db string) error {
+				_, err := c.Query(context.Background(), db, kql.New("T"))
+				return err
+			},
+			want: map[string]interface{}{QueryConsistencyValue: WeakConsistency},
+		},
+		{
+			desc:    "Hot cache",
+			db:      "follower",
+			options: []FollowerOption{FollowerHotCache(), FollowerConsistency(AffinitizedWeakConsistency)},
+			call: func(c *Client, db string) error {
+				_, err := c.QueryV1(context.Background(), db, kql.New("T"))
+				return err
+			},
+			want: map[string]interface{}{QueryConsistencyValue: AffinitizedWeakConsistency, QueryDatascopeValue: "hotcache"},
+		},
+		{
+			desc:    "Call options take precedence",
+			db:      "follower",
+			options: []FollowerOption{FollowerHotCache()},
+			call: func(c *Client, db string) error {
+				_, err := c.Query(context.Background(), db, kql.New("T"), QueryConsistency(StrongConsistency), QueryDataScope(DSAll))
+				return err
+			},
+			want: map[string]interface{}{QueryConsistencyValue: StrongConsistency, QueryDatascopeValue: "all"},
+		},
+		{
+			desc: "Overridden database",
+			db:   "other",
+			call: func(c *Client, db string) error {
+				_, err := c.Query(context.Background(), db, kql.New("T"), OverrideDatabase("follower"))
+				return err
+			},
+			want: map[string]interface{}{QueryConsistencyValue: WeakConsistency},
+		},
+		{
+			desc: "Other database",
+			db:   "leader",
+			call: func(c *Client, db string) error {
+				_, err := c.Query(context.Background(), db, kql.New("T"))
+				return err
+			},
+			want: map[string]interface{}{},
+		},
+		{
+			desc: "Management commands",
+			db:   "follower",
+			call: func(c *Client, db string) error {
+				_, err := c.Mgmt(context.Background(), db, kql.New(".show tables"))
+				return err
+			},
+			want: map[string]interface{}{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &optionsConn{}
+			client := &Client{conn: conn}
+			WithFollowerDatabase("follower", test.options...)(client)
+
+			require.NoError(t, test.call(client, test.db))
+			require.Len(t, conn.options, 1)
+
+			got := conn.options[0].requestProperties.Options
+			for _, key := range []string{QueryConsistencyValue, QueryDatascopeValue} {
+				assert.Equal(t, test.want[key], got[key], key)
+			}
+		})
+	}
+}
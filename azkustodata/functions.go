@@ -0,0 +1,259 @@
+package azkustodata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// FunctionSchema is a stored function of a database, as returned by `.show functions`.
+type FunctionSchema struct {
+	Name string
+	// Parameters are the parameters of the function, in order.
+	Parameters []FunctionParameter
+	// Body is the KQL of the function.
+	Body string
+	// Folder is the folder of the function, if it has one.
+	Folder string
+	// DocString is the documentation of the function, if it has one.
+	DocString string
+}
+
+// FunctionParameter is a parameter of a stored function.
+type FunctionParameter struct {
+	Name string
+	// Type is the type of a scalar parameter. It is empty for tabular parameters.
+	Type types.Column
+	// Tabular reports whether the parameter is a table, whose schema is TabularSchema, such as "(x:long, y:string)" or
+	// "(*)".
+	Tabular       bool
+	TabularSchema string
+	// Default is the KQL of the default value of the parameter, if it has one. Parameters with a default value can be
+	// omitted from the end of a call.
+	Default string
+}
+
+// showFunctionsRow is a row of `.show functions`.
+type showFunctionsRow struct {
+	Name       string
+	Parameters string
+	Body       string
+	Folder     string
+	DocString  string
+}
+
+// functionCache keeps the functions of the databases, read with ShowFunctions.
+type functionCache struct {
+	mu   sync.Mutex
+	byDB map[string]map[string]*FunctionSchema
+}
+
+// ShowFunctions returns the stored functions of the database db with `.show functions`, with their parameters.
+// The functions are kept by the client to validate the calls built with kql.CallFunction. Calling ShowFunctions again
+// refreshes them.
+func (c *Client) ShowFunctions(ctx context.Context, db string, options ...QueryOption) ([]FunctionSchema, error) {
+	dataset, err := c.Mgmt(ctx, db, kql.New(".show functions"), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query.ToStructs[showFunctionsRow](dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	functions := make([]FunctionSchema, len(rows))
+	byName := make(map[string]*FunctionSchema, len(rows))
+	for i, row := range rows {
+		params, err := parseFunctionParameters(row.Parameters)
+		if err != nil {
+			return nil, errors.E(errors.OpMgmt, errors.KFailedToParse, fmt.Errorf("could not parse the parameters of function %s: %w", row.Name, err))
+		}
+		functions[i] = FunctionSchema{Name: row.Name, Parameters: params, Body: row.Body, Folder: row.Folder, DocString: row.DocString}
+		f := functions[i]
+		byName[row.Name] = &f
+	}
+
+	c.functions.mu.Lock()
+	defer c.functions.mu.Unlock()
+	if c.functions.byDB == nil {
+		c.functions.byDB = map[string]map[string]*FunctionSchema{}
+	}
+	c.functions.byDB[c.functionsKey(db)] = byName
+	return functions, nil
+}
+
+// functionsKey returns the key of the functions of db in the cache.
+func (c *Client) functionsKey(db string) string {
+	if db == "" {
+		return c.defaultDatabase
+	}
+	return db
+}
+
+// cachedFunction returns the function of db kept by the client, and whether the functions of db were read at all.
+func (c *Client) cachedFunction(db, name string) (*FunctionSchema, bool) {
+	c.functions.mu.Lock()
+	defer c.functions.mu.Unlock()
+	functions, ok := c.functions.byDB[c.functionsKey(db)]
+	return functions[name], ok
+}
+
+// validateFunctionCall validates the call of a query built with kql.CallFunction against the signature of the function.
+// The functions of db are read on the first call, and again when the function isn't known, as it may have been created
+// since.
+func (c *Client) validateFunctionCall(ctx context.Context, op errors.Op, db string, q Statement) error {
+	call := q.FunctionCall()
+	if call == nil {
+		return nil
+	}
+
+	f, _ := c.cachedFunction(db, call.Name)
+	if f == nil {
+		if _, err := c.ShowFunctions(ctx, db); err != nil {
+			return err
+		}
+		if f, _ = c.cachedFunction(db, call.Name); f == nil {
+			return errors.ES(op, errors.KClientArgs, "function %s was not found in database %s", call.Name, c.functionsKey(db)).SetNoRetry()
+		}
+	}
+
+	if err := f.ValidateCall(call.Args...); err != nil {
+		return errors.E(op, errors.KClientArgs, err).SetNoRetry()
+	}
+	return nil
+}
+
+// ValidateCall validates the number and the types of the arguments of a call of the function.
+// An argument may be of a narrower numeric type than its parameter, such as an int for a long, as the service widens
+// it. Functions with tabular parameters can't be validated, as their tables aren't passed as arguments.
+func (f *FunctionSchema) ValidateCall(args ...value.Kusto) error {
+	required := 0
+	for i, p := range f.Parameters {
+		if p.Tabular {
+			return fmt.Errorf("function %s has the tabular parameter %s, which can't be passed as an argument", f.Name, p.Name)
+		}
+		if p.Default == "" {
+			required = i + 1
+		}
+	}
+
+	if len(args) < required || len(args) > len(f.Parameters) {
+		if required == len(f.Parameters) {
+			return fmt.Errorf("function %s takes %d arguments, got %d", f.Name, len(f.Parameters), len(args))
+		}
+		return fmt.Errorf("function %s takes %d to %d arguments, got %d", f.Name, required, len(f.Parameters), len(args))
+	}
+
+	for i, arg := range args {
+		p := f.Parameters[i]
+		if arg == nil {
+			return fmt.Errorf("argument %d of function %s, for parameter %s, is nil", i, f.Name, p.Name)
+		}
+		if !assignable(arg.GetType(), p.Type) {
+			return fmt.Errorf("argument %d of function %s, for parameter %s of type %s, is of type %s", i, f.Name, p.Name, p.Type, arg.GetType())
+		}
+	}
+	return nil
+}
+
+// widening are the numeric types the service widens each numeric type to.
+var widening = map[types.Column][]types.Column{
+	types.Int:  {types.Long, types.Real, types.Decimal},
+	types.Long: {types.Real, types.Decimal},
+	types.Real: {types.Decimal},
+}
+
+// assignable reports whether a value of type from can be passed to a parameter of type to.
+func assignable(from, to types.Column) bool {
+	if from == to {
+		return true
+	}
+	for _, t := range widening[from] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFunctionParameters parses the Parameters column of `.show functions`, such as
+// `(since:datetime, level:string = "error", T:(x:long))`.
+func parseFunctionParameters(s string) ([]FunctionParameter, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("the parameters %q are not parenthesized", s)
+	}
+
+	var params []FunctionParameter
+	for _, part := range splitTopLevel(s[1:len(s)-1], ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		nameAndType := splitTopLevel(part, ':')
+		if len(nameAndType) < 2 {
+			return nil, fmt.Errorf("the parameter %q has no type", part)
+		}
+		p := FunctionParameter{Name: unquoteName(strings.TrimSpace(nameAndType[0]))}
+
+		typeAndDefault := splitTopLevel(strings.Join(nameAndType[1:], ":"), '=')
+		typ := strings.TrimSpace(typeAndDefault[0])
+		if len(typeAndDefault) > 1 {
+			p.Default = strings.TrimSpace(strings.Join(typeAndDefault[1:], "="))
+		}
+
+		if strings.HasPrefix(typ, "(") {
+			p.Tabular = true
+			p.TabularSchema = typ
+		} else if p.Type = types.NormalizeColumn(strings.ToLower(typ)); p.Type == "" {
+			return nil, fmt.Errorf("the parameter %s is of type %q, which is not valid", p.Name, typ)
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// splitTopLevel splits s around the separators that are neither parenthesized nor quoted.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquoteName returns the name of a parameter quoted as ['name'] or ["name"].
+func unquoteName(name string) string {
+	if len(name) >= 4 && strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		return name[2 : len(name)-2]
+	}
+	return name
+}
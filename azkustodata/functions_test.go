@@ -0,0 +1,187 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// functionsConn answers `.show functions` with the functions of showFunctionsResponse, and queries with a dataset, and
+// records the commands and the queries.
+type functionsConn struct {
+	mu       sync.Mutex
+	commands int
+	queries  []string
+}
+
+const showFunctionsResponse = `{"Tables":[{"TableName":"Table_0","Columns":[` +
+	`{"ColumnName":"Name","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"Parameters","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"Body","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"Folder","DataType":"String","ColumnType":"string"},` +
+	`{"ColumnName":"DocString","DataType":"String","ColumnType":"string"}],` +
+	`"Rows":[` +
+	`["EventsSince","(since:datetime, level:string = \"error\")","{ Events | where Timestamp > since and Level == level }","Events","The events since a time."],` +
+	`["Scale","(x:real)","{ print x * 2 }","",""],` +
+	`["Join","(T:(Id:long), id:long)","{ T | where Id == id }","",""]]}]}`
+
+func (c *functionsConn) rawQuery(_ context.Context, callType callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if callType == mgmtCall {
+		c.commands++
+		return io.NopCloser(strings.NewReader(showFunctionsResponse)), nil
+	}
+	c.queries = append(c.queries, query.String())
+	return io.NopCloser(strings.NewReader(concurrentQueryResponse)), nil
+}
+
+func (c *functionsConn) Close() error {
+	return nil
+}
+
+func TestShowFunctions(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &functionsConn{}}
+	functions, err := client.ShowFunctions(context.Background(), "db")
+	require.NoError(t, err)
+
+	assert.Equal(t, []FunctionSchema{
+		{
+			Name: "EventsSince",
+			Parameters: []FunctionParameter{
+				{Name: "since", Type: types.DateTime},
+				{Name: "level", Type: types.String, Default: `"error"`},
+			},
+			Body:      "{ Events | where Timestamp > since and Level == level }",
+			Folder:    "Events",
+			DocString: "The events since a time.",
+		},
+		{Name: "Scale", Parameters: []FunctionParameter{{Name: "x", Type: types.Real}}, Body: "{ print x * 2 }"},
+		{
+			Name: "Join",
+			Parameters: []FunctionParameter{
+				{Name: "T", Tabular: true, TabularSchema: "(Id:long)"},
+				{Name: "id", Type: types.Long},
+			},
+			Body: "{ T | where Id == id }",
+		},
+	}, functions)
+}
+
+func TestParseFunctionParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		params  string
+		want    []FunctionParameter
+		wantErr bool
+	}{
+		{desc: "No parameters", params: "()"},
+		{
+			desc:   "Quoted names and defaults",
+			params: `(['a b']:long, s:string = "x, y", t:timespan=time(1d))`,
+			want: []FunctionParameter{
+				{Name: "a b", Type: types.Long},
+				{Name: "s", Type: types.String, Default: `"x, y"`},
+				{Name: "t", Type: types.Timespan, Default: "time(1d)"},
+			},
+		},
+		{
+			desc:   "Tabular parameter of any schema",
+			params: "(T:(*))",
+			want:   []FunctionParameter{{Name: "T", Tabular: true, TabularSchema: "(*)"}},
+		},
+		{desc: "Unknown type", params: "(x:float8)", wantErr: true},
+		{desc: "Not parenthesized", params: "x:long", wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseFunctionParameters(test.params)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestFunctionCallValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		call    *kql.Builder
+		wantErr string
+	}{
+		{desc: "All arguments", call: kql.CallFunction("EventsSince", value.NewDateTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)), value.NewString("warning"))},
+		{desc: "Default argument omitted", call: kql.CallFunction("EventsSince", value.NewDateTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))},
+		{desc: "Widened argument", call: kql.CallFunction("Scale", value.NewInt(2))},
+		{desc: "Null argument", call: kql.CallFunction("Scale", value.NewNullReal())},
+		{desc: "Missing argument", call: kql.CallFunction("EventsSince"), wantErr: "takes 1 to 2 arguments, got 0"},
+		{desc: "Too many arguments", call: kql.CallFunction("Scale", value.NewReal(1), value.NewReal(2)), wantErr: "takes 1 arguments, got 2"},
+		{desc: "Wrong type", call: kql.CallFunction("Scale", value.NewString("2")), wantErr: "parameter x of type real, is of type string"},
+		{desc: "Tabular parameter", call: kql.CallFunction("Join", value.NewLong(1)), wantErr: "tabular parameter T"},
+		{desc: "Unknown function", call: kql.CallFunction("Missing"), wantErr: "function Missing was not found in database db"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &functionsConn{}
+			client := &Client{conn: conn}
+
+			_, err := client.Query(context.Background(), "db", test.call)
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				assert.Empty(t, conn.queries, "an invalid call isn't sent")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{test.call.String()}, conn.queries)
+		})
+	}
+}
+
+func TestFunctionSignaturesAreCached(t *testing.T) {
+	t.Parallel()
+
+	conn := &functionsConn{}
+	client := &Client{conn: conn}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Query(context.Background(), "db", kql.CallFunction("Scale", value.NewReal(1)))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, conn.commands)
+
+	// Unknown functions are looked up again, as they may have been created since.
+	_, err := client.Query(context.Background(), "db", kql.CallFunction("Missing"))
+	assert.Error(t, err)
+	assert.Equal(t, 2, conn.commands)
+
+	// Queries that don't call a function aren't validated.
+	_, err = client.Query(context.Background(), "db", kql.New("T"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, conn.commands)
+}
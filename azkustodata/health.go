@@ -0,0 +1,109 @@
+package azkustodata
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+)
+
+// DefaultPingTimeout is the timeout of Ping when its context has no earlier deadline.
+const DefaultPingTimeout = 10 * time.Second
+
+// The names of the checks of Ping.
+const (
+	// HealthCheckToken acquires a token for the endpoint.
+	HealthCheckToken = "token"
+	// HealthCheckQuery runs `print 1` with weak consistency.
+	HealthCheckQuery = "query"
+)
+
+// HealthCheck is a check of a health check.
+type HealthCheck struct {
+	// Name is the name of the check, such as HealthCheckToken.
+	Name string
+	// Endpoint is the endpoint the check ran against.
+	Endpoint string
+	// Latency is how long the check took.
+	Latency time.Duration
+	// Err is the reason the check failed, or nil if it passed.
+	Err error
+}
+
+// MarshalJSON implements json.Marshaler, so that the checks can be reported as is by a readiness probe.
+func (c HealthCheck) MarshalJSON() ([]byte, error) {
+	check := struct {
+		Name      string  `json:"name"`
+		Endpoint  string  `json:"endpoint"`
+		Healthy   bool    `json:"healthy"`
+		LatencyMS float64 `json:"latencyMs"`
+		Error     string  `json:"error,omitempty"`
+	}{Name: c.Name, Endpoint: c.Endpoint, Healthy: c.Err == nil, LatencyMS: float64(c.Latency) / float64(time.Millisecond)}
+	if c.Err != nil {
+		check.Error = c.Err.Error()
+	}
+	return json.Marshal(check)
+}
+
+// Health is the result of a health check, such as Client.Ping, made of the checks that ran. The checks stop at the
+// first one that fails.
+type Health struct {
+	Checks []HealthCheck `json:"checks"`
+}
+
+// Healthy reports whether all the checks passed.
+func (h Health) Healthy() bool {
+	return h.Err() == nil
+}
+
+// Err returns the error of the check that failed, or nil if all the checks passed.
+func (h Health) Err() error {
+	for _, c := range h.Checks {
+		if c.Err != nil {
+			return c.Err
+		}
+	}
+	return nil
+}
+
+// Check runs check, and adds it to the checks under name, unless a previous check failed. It returns whether all the
+// checks passed, so that the checks can be chained.
+func (h *Health) Check(name, endpoint string, check func() error) bool {
+	if !h.Healthy() {
+		return false
+	}
+	start := time.Now()
+	err := check()
+	h.Checks = append(h.Checks, HealthCheck{Name: name, Endpoint: endpoint, Latency: time.Since(start), Err: err})
+	return err == nil
+}
+
+// Ping checks that the client can reach the service, for readiness and liveness probes: it acquires a token, then runs
+// `print 1` with weak consistency, so that the check is cheap for the cluster. Ping times out after DefaultPingTimeout
+// unless ctx is done earlier.
+func (c *Client) Ping(ctx context.Context) Health {
+	ctx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+
+	health := Health{}
+	health.Check(HealthCheckToken, c.endpoint, func() error {
+		if c.auth.TokenProvider == nil || !c.auth.TokenProvider.AuthorizationRequired() {
+			return nil
+		}
+		if c.http != nil {
+			c.auth.TokenProvider.SetHttp(c.http)
+		}
+		if _, _, err := c.auth.TokenProvider.AcquireToken(ctx); err != nil {
+			return errors.ES(errors.OpQuery, errors.KInternal, "could not acquire a token: %s", err)
+		}
+		return nil
+	})
+	health.Check(HealthCheckQuery, c.endpoint, func() error {
+		// The server timeout follows the deadline of ctx.
+		_, err := c.Query(ctx, "", kql.New("print 1"), QueryConsistency(WeakConsistency))
+		return err
+	})
+	return health
+}
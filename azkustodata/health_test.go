@@ -0,0 +1,99 @@
+package azkustodata
+
+import (
+	"context"
+	"encoding/json"
+	goErrors "errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingConn answers queries with a dataset, or fails them with err, and records their text and consistency.
+type pingConn struct {
+	mu           sync.Mutex
+	err          error
+	queries      []string
+	consistency  []interface{}
+	hasDeadlines []bool
+}
+
+func (c *pingConn) rawQuery(ctx context.Context, _ callType, _ string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query.String())
+	c.consistency = append(c.consistency, options.requestProperties.Options[QueryConsistencyValue])
+	_, ok := ctx.Deadline()
+	c.hasDeadlines = append(c.hasDeadlines, ok)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return io.NopCloser(strings.NewReader(concurrentQueryResponse)), nil
+}
+
+func (c *pingConn) Close() error {
+	return nil
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	conn := &pingConn{}
+	client := &Client{conn: conn, endpoint: "https://mycluster.kusto.windows.net", auth: Authorization{TokenProvider: &TokenProvider{}}}
+
+	health := client.Ping(context.Background())
+	require.NoError(t, health.Err())
+	assert.True(t, health.Healthy())
+	require.Len(t, health.Checks, 2)
+	assert.Equal(t, HealthCheckToken, health.Checks[0].Name)
+	assert.Equal(t, HealthCheckQuery, health.Checks[1].Name)
+	assert.Equal(t, "https://mycluster.kusto.windows.net", health.Checks[1].Endpoint)
+
+	assert.Equal(t, []string{"print 1"}, conn.queries)
+	assert.Equal(t, []interface{}{WeakConsistency}, conn.consistency)
+	assert.Equal(t, []bool{true}, conn.hasDeadlines)
+}
+
+func TestPingFailure(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &pingConn{err: goErrors.New("connection refused")}, endpoint: "https://mycluster.kusto.windows.net"}
+
+	health := client.Ping(context.Background())
+	assert.False(t, health.Healthy())
+	assert.ErrorContains(t, health.Err(), "connection refused")
+
+	b, err := json.Marshal(health)
+	require.NoError(t, err)
+	var report struct {
+		Checks []struct {
+			Name    string
+			Healthy bool
+			Error   string
+		}
+	}
+	require.NoError(t, json.Unmarshal(b, &report))
+	require.Len(t, report.Checks, 2)
+	assert.True(t, report.Checks[0].Healthy)
+	assert.Equal(t, HealthCheckQuery, report.Checks[1].Name)
+	assert.False(t, report.Checks[1].Healthy)
+	assert.Contains(t, report.Checks[1].Error, "connection refused")
+}
+
+func TestHealthChecksStopAtFirstFailure(t *testing.T) {
+	t.Parallel()
+
+	health := Health{}
+	assert.False(t, health.Check("first", "", func() error { return goErrors.New("failed") }))
+	assert.False(t, health.Check("second", "", func() error {
+		t.Error("a check ran after a failure")
+		return nil
+	}))
+	require.Len(t, health.Checks, 1)
+	assert.GreaterOrEqual(t, health.Checks[0].Latency, time.Duration(0))
+}
@@ -0,0 +1,56 @@
+package azkustodata
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// idleTimeoutReader closes a response body when a read waits longer than the timeout, which unblocks the read.
+// The timer only runs during reads, so a slow consumer doesn't trigger it.
+type idleTimeoutReader struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newIdleTimeoutReader(body io.ReadCloser, timeout time.Duration) *idleTimeoutReader {
+	r := &idleTimeoutReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		_ = r.body.Close()
+	})
+	r.timer.Stop()
+	return r
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	if r.timedOut.Load() {
+		return 0, errors.FrameIdleTimeout(errors.OpQuery, r.timeout)
+	}
+
+	r.timer.Reset(r.timeout)
+	n, err := r.body.Read(p)
+	if !r.timer.Stop() {
+		// The timer fired, so the body was closed while reading.
+		r.timedOut.Store(true)
+		return n, errors.FrameIdleTimeout(errors.OpQuery, r.timeout)
+	}
+	return n, err
+}
+
+// TransferStats implements query.TransferStatsReporter, with the statistics of the body.
+func (r *idleTimeoutReader) TransferStats() query.TransferStats {
+	if stats, ok := r.body.(query.TransferStatsReporter); ok {
+		return stats.TransferStats()
+	}
+	return query.TransferStats{}
+}
+
+func (r *idleTimeoutReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
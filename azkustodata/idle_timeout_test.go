@@ -0,0 +1,99 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stalledQueryResponse = `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"A","ColumnType":"int"}]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[1],[2]]}
+`
+
+// bodyConn returns a response body from a function.
+type bodyConn struct {
+	body func() io.ReadCloser
+}
+
+func (b bodyConn) rawQuery(context.Context, callType, string, Statement, *queryOptions) (io.ReadCloser, error) {
+	return b.body(), nil
+}
+
+func (b bodyConn) Close() error {
+	return nil
+}
+
+func TestFrameIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The response stalls after the first fragment.
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte(stalledQueryResponse))
+	}()
+	client := &Client{conn: bodyConn{body: func() io.ReadCloser { return reader }}}
+	WithFrameIdleTimeout(50 * time.Millisecond)(client)
+
+	dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T"))
+	require.NoError(t, err)
+
+	var rows int
+	var rowErr error
+	for tableResult := range dataset.Tables() {
+		if tableResult.Err() != nil {
+			rowErr = tableResult.Err()
+			continue
+		}
+		for rowResult := range tableResult.Table().Rows() {
+			if rowResult.Err() != nil {
+				rowErr = rowResult.Err()
+				continue
+			}
+			rows++
+		}
+	}
+
+	assert.Equal(t, 2, rows)
+	var idle *errors.FrameIdleTimeoutError
+	require.True(t, goErrors.As(rowErr, &idle), "unexpected error: %v", rowErr)
+	assert.Equal(t, 50*time.Millisecond, idle.Timeout)
+	assert.Equal(t, int64(2), idle.RowsDelivered)
+	assert.Equal(t, errors.KTimeout, idle.Kind)
+	assert.Contains(t, idle.Error(), "after 2 rows were delivered")
+}
+
+func TestFrameIdleTimeoutSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	response := strings.Replace(stalledQueryResponse, `"Rows":[[1],[2]]}`, `"Rows":[[1],[2]]}
+,{"FrameType":"TableCompletion","TableId":1,"RowCount":2}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`, 1)
+	client := &Client{conn: bodyConn{body: func() io.ReadCloser { return io.NopCloser(strings.NewReader(response)) }}}
+	WithFrameIdleTimeout(10 * time.Millisecond)(client)
+
+	dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T"), V2RowCapacity(1))
+	require.NoError(t, err)
+
+	var rows int
+	for tableResult := range dataset.Tables() {
+		require.NoError(t, tableResult.Err())
+		for rowResult := range tableResult.Table().Rows() {
+			require.NoError(t, rowResult.Err())
+			// Waiting for the consumer doesn't count as idle.
+			time.Sleep(30 * time.Millisecond)
+			rows++
+		}
+	}
+	assert.Equal(t, 2, rows)
+}
@@ -0,0 +1,221 @@
+package azkustodata
+
+// ingest_from_query.go holds IngestFromQuery, which ingests the results of a query into a table on the same cluster.
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/poll"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/google/uuid"
+)
+
+// operationPollInterval is the interval between the first two status checks in Operation.Wait().
+var operationPollInterval = 5 * time.Second
+
+// operationMaxPollInterval is the maximum interval between two status checks in Operation.Wait().
+const operationMaxPollInterval = time.Minute
+
+type ingestFromQueryOptions struct {
+	appendOnly   bool
+	distributed  bool
+	creationTime time.Time
+	tags         []string
+	queryOptions []QueryOption
+}
+
+// IngestFromQueryOption is an option for IngestFromQuery.
+type IngestFromQueryOption func(o *ingestFromQueryOptions)
+
+// AppendOnly makes IngestFromQuery use the `.append` command, which fails if the target table doesn't exist,
+// instead of `.set-or-append`, which creates it.
+func AppendOnly() IngestFromQueryOption {
+	return func(o *ingestFromQueryOptions) {
+		o.appendOnly = true
+	}
+}
+
+// Distributed sets the `distributed` property, which makes all the nodes executing the query write the results in parallel.
+// Use it when the query produces a large amount of data.
+func Distributed() IngestFromQueryOption {
+	return func(o *ingestFromQueryOptions) {
+		o.distributed = true
+	}
+}
+
+// CreationTime sets the `creationTime` property, which overrides the creation time of the new extents.
+// This is useful for backfilling data, as the retention policy is based on the creation time.
+func CreationTime(t time.Time) IngestFromQueryOption {
+	return func(o *ingestFromQueryOptions) {
+		o.creationTime = t
+	}
+}
+
+// Tags sets the `tags` property, which tags the extents created by the ingestion.
+func Tags(tags ...string) IngestFromQueryOption {
+	return func(o *ingestFromQueryOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithQueryOptions passes QueryOptions to the management command that starts the ingestion.
+func WithQueryOptions(options ...QueryOption) IngestFromQueryOption {
+	return func(o *ingestFromQueryOptions) {
+		o.queryOptions = append(o.queryOptions, options...)
+	}
+}
+
+// IngestFromQuery ingests the results of sourceQuery into targetTable, using an async `.set-or-append` command.
+// The data is copied on the server side, so this is the fastest way to transform data between tables of the same cluster.
+// The returned Operation can be used to track the ingestion.
+func (c *Client) IngestFromQuery(ctx context.Context, db string, targetTable string, sourceQuery Statement, options ...IngestFromQueryOption) (*Operation, error) {
+	opts := &ingestFromQueryOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	cmd, err := buildIngestFromQueryCommand(targetTable, sourceQuery, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, err := c.Mgmt(ctx, db, cmd, opts.queryOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := query.ToStructs[struct{ OperationId uuid.UUID }](dataset.Tables()[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single operation id from the service, got %d", len(ids))
+	}
+
+	return &Operation{ID: ids[0].OperationId, db: db, client: c}, nil
+}
+
+func buildIngestFromQueryCommand(targetTable string, sourceQuery Statement, opts *ingestFromQueryOptions) (*kql.Builder, error) {
+	if targetTable == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "target table cannot be empty").SetNoRetry()
+	}
+	if sourceQuery == nil || sourceQuery.String() == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "source query cannot be empty").SetNoRetry()
+	}
+
+	var cmd *kql.Builder
+	if opts.appendOnly {
+		cmd = kql.New(".append async ")
+	} else {
+		cmd = kql.New(".set-or-append async ")
+	}
+	cmd.AddTable(targetTable)
+
+	var props []func()
+	if opts.distributed {
+		props = append(props, func() { cmd.AddLiteral("distributed=true") })
+	}
+	if !opts.creationTime.IsZero() {
+		props = append(props, func() { cmd.AddLiteral("creationTime=").AddString(opts.creationTime.UTC().Format(time.RFC3339Nano)) })
+	}
+	if len(opts.tags) > 0 {
+		tags, err := json.Marshal(opts.tags)
+		if err != nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not serialize tags: %s", err).SetNoRetry()
+		}
+		props = append(props, func() { cmd.AddLiteral("tags=").AddString(string(tags)) })
+	}
+
+	if len(props) > 0 {
+		cmd.AddLiteral(" with (")
+		for i, p := range props {
+			if i > 0 {
+				cmd.AddLiteral(", ")
+			}
+			p()
+		}
+		cmd.AddLiteral(")")
+	}
+
+	return cmd.AddLiteral(" <| ").AddUnsafe(sourceQuery.String()), nil
+}
+
+// Operation is a handle to an asynchronous management operation running on the service.
+type Operation struct {
+	// ID is the id of the operation, as returned by the service.
+	ID uuid.UUID
+
+	db     string
+	client *Client
+}
+
+// OperationStatus is a row of `.show operations`.
+type OperationStatus struct {
+	OperationId   uuid.UUID
+	Operation     string
+	StartedOn     time.Time
+	LastUpdatedOn time.Time
+	Duration      time.Duration
+	State         string
+	Status        string
+	ShouldRetry   bool
+	Database      string
+}
+
+// Done returns true if the operation is no longer running.
+func (s OperationStatus) Done() bool {
+	return s.State != "InProgress" && s.State != "Scheduled"
+}
+
+// Succeeded returns true if the operation completed successfully.
+func (s OperationStatus) Succeeded() bool {
+	return s.State == "Completed"
+}
+
+// Status fetches the current status of the operation.
+func (o *Operation) Status(ctx context.Context) (*OperationStatus, error) {
+	dataset, err := o.client.Mgmt(ctx, o.db, kql.New(".show operations ").AddUnsafe(o.ID.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := query.ToStructs[OperationStatus](dataset.Tables()[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "operation %s was not found", o.ID)
+	}
+
+	// The last row holds the most recent state.
+	return &statuses[len(statuses)-1], nil
+}
+
+// Wait polls the status of the operation until it is done, or ctx is done.
+// The status is polled with an exponential backoff, starting at 5 seconds and capped at a minute, see the poll package.
+// It returns the final status, and an error if the operation did not succeed. Check OperationStatus.ShouldRetry to
+// know if the operation can be retried.
+func (o *Operation) Wait(ctx context.Context) (*OperationStatus, error) {
+	var status *OperationStatus
+	p := poll.New(poll.Interval(operationPollInterval), poll.MaxInterval(operationMaxPollInterval))
+	err := p.Poll(ctx, func(ctx context.Context) (bool, error) {
+		s, err := o.Status(ctx)
+		if err != nil {
+			return false, err
+		}
+		status = s
+		return status.Done(), nil
+	})
+	if err != nil {
+		return status, err
+	}
+
+	if !status.Succeeded() {
+		return status, errors.ES(errors.OpMgmt, errors.KOther, "operation %s ended in state %s: %s", o.ID, status.State, status.Status)
+	}
+	return status, nil
+}
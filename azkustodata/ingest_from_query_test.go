@@ -0,0 +1,165 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildIngestFromQueryCommand(t *testing.T) {
+	t.Parallel()
+
+	creationTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		table   string
+		query   Statement
+		options []IngestFromQueryOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "default",
+			table: "Target",
+			query: kql.New("Source | take 10"),
+			want:  ".set-or-append async Target <| Source | take 10",
+		},
+		{
+			name:    "append",
+			table:   "Target",
+			query:   kql.New("Source"),
+			options: []IngestFromQueryOption{AppendOnly()},
+			want:    ".append async Target <| Source",
+		},
+		{
+			name:    "all properties",
+			table:   "My Table",
+			query:   kql.New("Source"),
+			options: []IngestFromQueryOption{Distributed(), CreationTime(creationTime), Tags("a", "b")},
+			want:    `.set-or-append async ["My Table"] with (distributed=true, creationTime="2024-01-02T03:04:05Z", tags="[\"a\",\"b\"]") <| Source`,
+		},
+		{
+			name:    "empty table",
+			query:   kql.New("Source"),
+			wantErr: true,
+		},
+		{
+			name:    "empty query",
+			table:   "Target",
+			query:   kql.New(""),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := &ingestFromQueryOptions{}
+			for _, o := range test.options {
+				o(opts)
+			}
+
+			cmd, err := buildIngestFromQueryCommand(test.table, test.query, opts)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.False(t, errors.Retry(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, cmd.String())
+		})
+	}
+}
+
+// fakeMgmtConn returns the given v1 responses in order, and records the commands and options it received.
+type fakeMgmtConn struct {
+	responses []string
+	commands  []string
+	options   []*queryOptions
+}
+
+func (f *fakeMgmtConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	f.commands = append(f.commands, query.String())
+	f.options = append(f.options, options)
+	res := f.responses[0]
+	f.responses = f.responses[1:]
+	return io.NopCloser(strings.NewReader(res)), nil
+}
+
+func (f *fakeMgmtConn) Close() error {
+	return nil
+}
+
+const operationIdResponse = `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"OperationId","DataType":"Guid","ColumnType":"guid"}],"Rows":[["a3a5e36f-1ac4-4e94-8d3a-2f5c0e8f0b6e"]]}]}`
+
+func operationStatusResponse(state string, shouldRetry bool) string {
+	retry := "false"
+	if shouldRetry {
+		retry = "true"
+	}
+	return `{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"OperationId","DataType":"Guid","ColumnType":"guid"},` +
+		`{"ColumnName":"Operation","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"State","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"Status","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"ShouldRetry","DataType":"Boolean","ColumnType":"bool"}],` +
+		`"Rows":[["a3a5e36f-1ac4-4e94-8d3a-2f5c0e8f0b6e","TableSetOrAppend","` + state + `","details",` + retry + `]]}]}`
+}
+
+func TestIngestFromQueryWait(t *testing.T) {
+	// Not parallel, as it changes operationPollInterval.
+	prev := operationPollInterval
+	operationPollInterval = time.Millisecond
+	defer func() { operationPollInterval = prev }()
+
+	tests := []struct {
+		name    string
+		states  []string
+		retry   bool
+		wantErr bool
+	}{
+		{name: "completed", states: []string{"InProgress", "Completed"}},
+		{name: "failed", states: []string{"Scheduled", "InProgress", "Failed"}, wantErr: true},
+		{name: "throttled", states: []string{"Throttled"}, retry: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &fakeMgmtConn{responses: []string{operationIdResponse}}
+			for _, s := range test.states {
+				conn.responses = append(conn.responses, operationStatusResponse(s, test.retry))
+			}
+			client := &Client{conn: conn}
+
+			op, err := client.IngestFromQuery(context.Background(), "db", "Target", kql.New("Source"))
+			require.NoError(t, err)
+			assert.Equal(t, "a3a5e36f-1ac4-4e94-8d3a-2f5c0e8f0b6e", op.ID.String())
+			assert.Equal(t, ".set-or-append async Target <| Source", conn.commands[0])
+
+			status, err := op.Wait(context.Background())
+			require.NotNil(t, status)
+			assert.Equal(t, test.states[len(test.states)-1], status.State)
+			assert.Equal(t, "TableSetOrAppend", status.Operation)
+			assert.Len(t, conn.commands, len(test.states)+1)
+			assert.Equal(t, ".show operations a3a5e36f-1ac4-4e94-8d3a-2f5c0e8f0b6e", conn.commands[1])
+
+			if !test.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "details")
+			assert.Equal(t, test.retry, status.ShouldRetry)
+		})
+	}
+}
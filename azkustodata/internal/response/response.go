@@ -1,51 +1,120 @@
 package response
 
 import (
+	"bufio"
 	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
 )
 
-type originalCloser struct {
+// Body is the body of a response, decoded according to its Content-Encoding.
+// It counts the bytes read from the network and the decoded bytes, and reports them with TransferStats.
+type Body struct {
 	original io.ReadCloser
-	wrapper  io.ReadCloser
+	reader   io.Reader
+	// decoder is the decoder of the body, or nil if the body is not encoded.
+	decoder  io.Closer
+	encoding string
+
+	compressed   atomic.Int64
+	decompressed atomic.Int64
+}
+
+func (b *Body) Read(p []byte) (n int, err error) {
+	n, err = b.reader.Read(p)
+	b.decompressed.Add(int64(n))
+	return n, err
+}
+
+func (b *Body) Close() error {
+	if b.decoder != nil {
+		if err := b.decoder.Close(); err != nil {
+			return err
+		}
+	}
+	return b.original.Close()
 }
 
-func (o *originalCloser) Read(p []byte) (n int, err error) {
-	return o.wrapper.Read(p)
+// Peek returns the next n decoded bytes of the body without consuming them. It returns fewer bytes, with an error, if
+// the body is shorter.
+func (b *Body) Peek(n int) ([]byte, error) {
+	buffered, ok := b.reader.(*bufio.Reader)
+	if !ok || buffered.Size() < n {
+		buffered = bufio.NewReaderSize(b.reader, n)
+		b.reader = buffered
+	}
+	return buffered.Peek(n)
 }
 
-func (o *originalCloser) Close() error {
-	if err := o.wrapper.Close(); err != nil {
-		return err
+// TransferStats implements query.TransferStatsReporter.
+func (b *Body) TransferStats() query.TransferStats {
+	return query.TransferStats{
+		Encoding:          b.encoding,
+		CompressedBytes:   b.compressed.Load(),
+		DecompressedBytes: b.decompressed.Load(),
 	}
-	return o.original.Close()
 }
 
+// countingReader counts the bytes read from the network into a Body.
+type countingReader struct {
+	reader io.Reader
+	body   *Body
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.body.compressed.Add(int64(n))
+	return n, err
+}
+
+// TranslateBody returns the body of resp, decoded according to its Content-Encoding. The returned io.ReadCloser is a
+// *Body.
 func TranslateBody(resp *http.Response, op errors.Op) (io.ReadCloser, error) {
-	body := resp.Body
-	var wrapper io.ReadCloser
-	switch enc := strings.ToLower(resp.Header.Get("Content-Encoding")); enc {
-	case "":
-		return body, nil
+	enc := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	body := &Body{original: resp.Body, encoding: enc}
+	counted := countingReader{reader: resp.Body, body: body}
+
+	switch enc {
+	case "", "identity":
+		body.reader = counted
 	case "gzip":
-		var err error
-		wrapper, err = gzip.NewReader(resp.Body)
+		reader, err := gzip.NewReader(counted)
 		if err != nil {
 			return nil, errors.E(op, errors.KInternal, fmt.Errorf("gzip reader error: %w", err))
 		}
+		body.reader, body.decoder = reader, reader
 	case "deflate":
-		wrapper = flate.NewReader(resp.Body)
+		reader, err := newDeflateReader(counted)
+		if err != nil {
+			return nil, errors.E(op, errors.KInternal, fmt.Errorf("deflate reader error: %w", err))
+		}
+		body.reader, body.decoder = reader, reader
 	default:
 		return nil, errors.ES(op, errors.KInternal, "Content-Encoding was unrecognized: %s", enc)
 	}
-	return &originalCloser{
-		original: body,
-		wrapper:  wrapper,
-	}, nil
+	return body, nil
+}
+
+// newDeflateReader decodes a deflate encoded body. The deflate Content-Encoding is defined as zlib data, but some
+// servers send raw deflate data, so both are supported.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// A zlib header uses the deflate method (8), and is a multiple of 31.
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
 }
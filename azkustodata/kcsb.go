@@ -1,6 +1,7 @@
 package azkustodata
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
 	"strconv"
@@ -39,6 +40,17 @@ type ConnectionStringBuilder struct {
 	ApplicationForTracing            string
 	UserForTracing                   string
 	TokenCredential                  azcore.TokenCredential
+	// InitialCatalog is the default database of the client, used by the calls that don't specify a database.
+	InitialCatalog string
+	// TLSClientCertificates are presented when the cluster, or a gateway in front of it, requests a client certificate
+	// during the TLS handshake. They are distinct from the application certificates used to authenticate with Azure AD.
+	TLSClientCertificates []tls.Certificate
+	// TLSClientCertificateFile and TLSClientKeyFile are the PEM files of a TLS client certificate, loaded by New in
+	// addition to TLSClientCertificates.
+	TLSClientCertificateFile string
+	TLSClientKeyFile         string
+	// ProxyRules route the requests to some hosts through proxies, see WithProxy.
+	ProxyRules []ProxyRule
 }
 
 const (
@@ -55,6 +67,7 @@ const (
 	sendCertificateChain             string = "SendCertificateChain"
 	interactiveLogin                 string = "InteractiveLogin"
 	domainHint                       string = "RedirectURL"
+	initialCatalog                   string = "InitialCatalog"
 )
 
 const (
@@ -74,6 +87,7 @@ var csMapping = map[string]string{"datasource": dataSource, "data source": dataS
 	"user token": userToken, "usertoken": userToken, "usrtoken": userToken,
 	"interactive login": interactiveLogin, "interactivelogin": interactiveLogin,
 	"domain hint": domainHint, "domainhint": domainHint,
+	"initial catalog": initialCatalog, "initialcatalog": initialCatalog, "database": initialCatalog,
 }
 
 func requireNonEmpty(key string, value string) {
@@ -115,6 +129,8 @@ func assignValue(kcsb *ConnectionStringBuilder, rawKey string, value string) err
 		kcsb.InteractiveLogin = bval
 	case domainHint:
 		kcsb.RedirectURL = value
+	case initialCatalog:
+		kcsb.InitialCatalog = value
 	}
 	return nil
 }
@@ -343,6 +359,40 @@ func (kcsb *ConnectionStringBuilder) WithTokenCredential(tokenCredential azcore.
 	return kcsb
 }
 
+// WithTLSClientCertificate adds a certificate for mutual TLS with the cluster, for clusters behind gateways that require
+// a client certificate in addition to Azure AD authentication. It is used at the TLS layer only, and doesn't change
+// how the client authenticates. Use tls.LoadX509KeyPair or tls.X509KeyPair to load the certificate and its key.
+// It only applies to the default http client of the clients, and is ignored with WithHttpClient.
+func (kcsb *ConnectionStringBuilder) WithTLSClientCertificate(cert tls.Certificate) *ConnectionStringBuilder {
+	kcsb.TLSClientCertificates = append(kcsb.TLSClientCertificates, cert)
+	return kcsb
+}
+
+// WithTLSClientCertificateFiles is like WithTLSClientCertificate, with the PEM files of the certificate and its key,
+// which are loaded when the client is created.
+func (kcsb *ConnectionStringBuilder) WithTLSClientCertificateFiles(certFile, keyFile string) *ConnectionStringBuilder {
+	requireNonEmpty("TLSClientCertificateFile", certFile)
+	requireNonEmpty("TLSClientKeyFile", keyFile)
+	kcsb.TLSClientCertificateFile = certFile
+	kcsb.TLSClientKeyFile = keyFile
+	return kcsb
+}
+
+// tlsClientCertificates returns the TLS client certificates of the connection string builder, with the one of its
+// files, if any.
+func (kcsb *ConnectionStringBuilder) tlsClientCertificates() ([]tls.Certificate, error) {
+	certs := append([]tls.Certificate(nil), kcsb.TLSClientCertificates...)
+	if kcsb.TLSClientCertificateFile == "" && kcsb.TLSClientKeyFile == "" {
+		return certs, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(kcsb.TLSClientCertificateFile, kcsb.TLSClientKeyFile)
+	if err != nil {
+		return nil, kustoErrors.ES(kustoErrors.OpServConn, kustoErrors.KClientArgs, "could not load the TLS client certificate: %s", err).SetNoRetry()
+	}
+	return append(certs, cert), nil
+}
+
 // Method to be used for generating TokenCredential
 func (kcsb *ConnectionStringBuilder) newTokenProvider() (*TokenProvider, error) {
 	tkp := &TokenProvider{}
@@ -22,6 +22,14 @@ func TestGetConnectionStringBuilder(t *testing.T) {
 				DataSource: "https://endpoint",
 			},
 		},
+		{
+			name:             "test_conn_string_initial_catalog",
+			connectionString: "https://endpoint;Initial Catalog=Samples",
+			want: ConnectionStringBuilder{
+				DataSource:     "https://endpoint",
+				InitialCatalog: "Samples",
+			},
+		},
 		{
 			name:             "test_conn_string_emptyconnstr",
 			connectionString: "",
@@ -22,6 +22,17 @@ func (s stringConstant) String() string {
 
 type Builder struct {
 	builder strings.Builder
+
+	// lists holds the lists added with AddList that were converted into query parameters.
+	lists         *Parameters
+	inlineLists   bool
+	listThreshold int
+
+	// sets are the set statements added with Set, that precede the query.
+	sets []setStatement
+
+	// call is the invocation of the stored function the query was built with, see CallFunction.
+	call *FunctionCall
 }
 
 func New(value stringConstant) *Builder {
@@ -31,12 +42,20 @@ func New(value stringConstant) *Builder {
 }
 
 func FromBuilder(builder *Builder) *Builder {
-	return New(stringConstant(builder.String()))
+	b := New(stringConstant(builder.builder.String()))
+	b.sets = append([]setStatement(nil), builder.sets...)
+	b.inlineLists = builder.inlineLists
+	b.listThreshold = builder.listThreshold
+	b.call = builder.call
+	if builder.lists != nil {
+		b.lists = NewParameters().Merge(builder.lists)
+	}
+	return b
 }
 
-// String implements fmt.Stringer.
+// String implements fmt.Stringer. It returns the query text, preceded by the set statements added with Set.
 func (b *Builder) String() string {
-	return b.builder.String()
+	return b.setPrefix() + b.builder.String()
 }
 func (b *Builder) addBase(value fmt.Stringer) *Builder {
 	b.builder.WriteString(value.String())
@@ -111,7 +130,9 @@ func (b *Builder) SupportsInlineParameters() bool {
 	return false
 }
 
-// Reset resets the stringBuilder
+// Reset resets the stringBuilder, the lists converted into query parameters, and the set statements.
 func (b *Builder) Reset() {
 	b.builder.Reset()
+	b.lists = nil
+	b.sets = nil
 }
@@ -71,6 +71,20 @@ func TestBuilder(t *testing.T) {
 			).AddTimespan(49*time.Hour + 2*time.Minute + 3*time.Second + 4*time.Microsecond),
 			"MyTable | where i != timespan(2.01:02:03.0000040)",
 		},
+		{
+			"Test add negative duration",
+			New(
+				"MyTable | where i != ",
+			).AddTimespan(-(49*time.Hour + 2*time.Minute + 3*time.Second + 4*time.Microsecond + 500*time.Nanosecond)),
+			"MyTable | where i != timespan(-2.01:02:03.0000045)",
+		},
+		{
+			"Test add duration with ticks",
+			New(
+				"MyTable | where i != ",
+			).AddTimespan(3*time.Second + 1234567*100*time.Nanosecond),
+			"MyTable | where i != timespan(00:00:03.1234567)",
+		},
 		{
 			"Test add dynamic",
 			New(
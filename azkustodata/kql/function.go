@@ -0,0 +1,40 @@
+package kql
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// FunctionCall is the invocation of a stored function, built with CallFunction.
+type FunctionCall struct {
+	// Name is the name of the function.
+	Name string
+	// Args are the arguments of the call, in order.
+	Args []value.Kusto
+}
+
+// CallFunction returns a query that invokes the stored function name with args, added as literals:
+//
+//	kql.CallFunction("EventsSince", value.NewDateTime(since), value.NewString("error"))
+//	// EventsSince(datetime(2024-01-02T00:00:00Z), "error")
+//
+// More operators can be added to the query, such as AddLiteral("\n| take 10").
+// Client.Query, IterativeQuery and QueryV1 validate the number and the types of the arguments against the signature of
+// the function, as returned by Client.ShowFunctions, before running the query.
+func CallFunction(name string, args ...value.Kusto) *Builder {
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		literals[i] = QuoteValue(arg)
+	}
+
+	b := New("").AddFunction(name)
+	b.builder.WriteString("(" + strings.Join(literals, ", ") + ")")
+	b.call = &FunctionCall{Name: name, Args: append([]value.Kusto(nil), args...)}
+	return b
+}
+
+// FunctionCall returns the invocation of the stored function the query was built with, with CallFunction, or nil.
+func (b *Builder) FunctionCall() *FunctionCall {
+	return b.call
+}
@@ -0,0 +1,19 @@
+package kql
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallFunction(t *testing.T) {
+	t.Parallel()
+
+	b := CallFunction("My Func", value.NewLong(1), value.NewString("ab")).AddLiteral("\n| take 10")
+	assert.Equal(t, "[\"My Func\"](long(1), \"ab\")\n| take 10", b.String())
+	assert.Equal(t, &FunctionCall{Name: "My Func", Args: []value.Kusto{value.NewLong(1), value.NewString("ab")}}, b.FunctionCall())
+	assert.Equal(t, b.FunctionCall(), FromBuilder(b).FunctionCall())
+
+	assert.Nil(t, New("T").FunctionCall())
+}
@@ -1,6 +1,22 @@
 package kql
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// keywords are the reserved words of KQL that QuoteIdentifier quotes, as they can't be used as bare identifiers.
+var keywords = map[string]bool{
+	"access": true, "alias": true, "and": true, "as": true, "asc": true, "between": true, "bool": true, "by": true,
+	"contains": true, "count": true, "datatable": true, "datetime": true, "decimal": true, "declare": true, "desc": true,
+	"distinct": true, "dynamic": true, "evaluate": true, "extend": true, "false": true, "guid": true, "has": true,
+	"in": true, "int": true, "invoke": true, "join": true, "kind": true, "let": true, "limit": true, "long": true,
+	"materialize": true, "not": true, "null": true, "of": true, "on": true, "or": true, "order": true, "pattern": true,
+	"print": true, "project": true, "range": true, "real": true, "restrict": true, "set": true, "sort": true,
+	"string": true, "summarize": true, "take": true, "timespan": true, "to": true, "top": true, "true": true,
+	"typeof": true, "union": true, "where": true, "with": true,
+}
 
 func (b *Builder) AddDatabase(database string) *Builder {
 	return b.addBase(stringConstant(fmt.Sprintf("%s(%s)", "database", QuoteString(database, false))))
@@ -25,6 +41,18 @@ func (b *Builder) AddFunction(function string) *Builder {
 	return b.addBase(stringConstant(NormalizeName(function)))
 }
 
+// QuoteIdentifier returns name as an identifier that can be spliced into a query or a command, for the names of tables,
+// columns, functions and databases: it is returned as is if it is a plain identifier, made of letters, digits and
+// underscores, or quoted in brackets, such as ["my-table"], with the escaping of QuoteStringLiteral.
+// Unlike NormalizeName, it also quotes the names that start with a digit and the keywords of KQL, such as "where", and
+// returns [""] for an empty name instead of an empty string, so that the fragment is always a single identifier.
+func QuoteIdentifier(name string) string {
+	if name != "" && !RequiresQuoting(name) && !unicode.IsDigit([]rune(name)[0]) && !keywords[strings.ToLower(name)] {
+		return name
+	}
+	return "[" + QuoteStringLiteral(name) + "]"
+}
+
 // NormalizeName normalizes a string in order to be used safely in the engine - given "query" will produce [\"query\"].
 func NormalizeName(name string) string {
 	if name == "" {
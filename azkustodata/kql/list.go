@@ -0,0 +1,139 @@
+package kql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// DefaultListParameterThreshold is the size, in bytes, above which the lists added with AddList are sent as query
+// parameters instead of literals in the query text.
+const DefaultListParameterThreshold = 16 * 1024
+
+// listParameterPrefix is the prefix of the names of the query parameters that hold lists.
+const listParameterPrefix = "__kql_list_"
+
+// AddList adds a parenthesized list of values, such as the right side of the in() and has_any() operators:
+//
+//	kql.New("Events | where Id in ").AddList(ids...)
+//
+// The query text is limited to 2MB, so lists larger than the threshold set with SetListParameterThreshold are sent as
+// dynamic query parameters instead, and referenced by the query. The query parameters are declared and sent by the
+// client along with the query. Only lists of non-null strings, ints, longs, reals or bools are converted; other lists
+// are always added as literals. Use InlineLists to always add the lists as literals.
+func (b *Builder) AddList(values ...value.Kusto) *Builder {
+	literals := make([]string, len(values))
+	size := 0
+	for i, v := range values {
+		literals[i] = QuoteValue(v)
+		size += len(literals[i]) + len(", ")
+	}
+
+	threshold := b.listThreshold
+	if threshold == 0 {
+		threshold = DefaultListParameterThreshold
+	}
+
+	if !b.inlineLists && size > threshold {
+		if array, ok := dynamicArray(values); ok {
+			if b.lists == nil {
+				b.lists = NewParameters()
+			}
+			name := fmt.Sprintf("%s%d", listParameterPrefix, b.lists.Count())
+			b.lists.AddValue(name, value.NewDynamic(array))
+			b.builder.WriteString("(" + name + ")")
+			return b
+		}
+	}
+
+	b.builder.WriteString("(" + strings.Join(literals, ", ") + ")")
+	return b
+}
+
+// AddStringList adds a parenthesized list of strings. See AddList.
+func (b *Builder) AddStringList(values ...string) *Builder {
+	list := make([]value.Kusto, len(values))
+	for i, v := range values {
+		list[i] = value.NewString(v)
+	}
+	return b.AddList(list...)
+}
+
+// AddLongList adds a parenthesized list of longs. See AddList.
+func (b *Builder) AddLongList(values ...int64) *Builder {
+	list := make([]value.Kusto, len(values))
+	for i, v := range values {
+		list[i] = value.NewLong(v)
+	}
+	return b.AddList(list...)
+}
+
+// InlineLists makes the lists added with AddList literals of the query text, whatever their size.
+func (b *Builder) InlineLists() *Builder {
+	b.inlineLists = true
+	return b
+}
+
+// SetListParameterThreshold sets the size, in bytes, above which the lists added with AddList are sent as query
+// parameters. The default is DefaultListParameterThreshold.
+func (b *Builder) SetListParameterThreshold(size int) *Builder {
+	b.listThreshold = size
+	return b
+}
+
+// ListParameters returns the query parameters that hold the lists added with AddList, or nil if there are none.
+// The client sends them along with the query.
+func (b *Builder) ListParameters() *Parameters {
+	return b.lists
+}
+
+// dynamicArray returns the JSON array of values, if they all have the same type and a JSON representation that a
+// dynamic array compares equal to the literals.
+func dynamicArray(values []value.Kusto) ([]byte, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	items := make([]interface{}, len(values))
+	t := values[0].GetType()
+	for i, v := range values {
+		if v.GetType() != t {
+			return nil, false
+		}
+
+		switch val := v.GetValue().(type) {
+		case string:
+			items[i] = val
+		case *int32:
+			if val == nil {
+				return nil, false
+			}
+			items[i] = *val
+		case *int64:
+			if val == nil {
+				return nil, false
+			}
+			items[i] = *val
+		case *float64:
+			if val == nil {
+				return nil, false
+			}
+			items[i] = *val
+		case *bool:
+			if val == nil {
+				return nil, false
+			}
+			items[i] = *val
+		default:
+			return nil, false
+		}
+	}
+
+	array, err := json.Marshal(items)
+	if err != nil {
+		return nil, false
+	}
+	return array, true
+}
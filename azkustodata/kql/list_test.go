@@ -0,0 +1,93 @@
+package kql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		b          *Builder
+		expected   string
+		parameters map[string]string
+	}{
+		{
+			name:     "small list",
+			b:        New("T | where Id in ").AddLongList(1, 2, 3),
+			expected: "T | where Id in (long(1), long(2), long(3))",
+		},
+		{
+			name:     "empty list",
+			b:        New("T | where Id in ").AddList(),
+			expected: "T | where Id in ()",
+		},
+		{
+			name:       "large list",
+			b:          New("T | where Name in ").SetListParameterThreshold(10).AddStringList("a", "b\"c"),
+			expected:   "T | where Name in (__kql_list_0)",
+			parameters: map[string]string{"__kql_list_0": `dynamic(["a","b\"c"])`},
+		},
+		{
+			name: "several large lists",
+			b: New("T | where Id in ").SetListParameterThreshold(10).AddLongList(1, 2, 3).
+				AddLiteral(" and Ok in ").AddList(value.NewBool(true), value.NewBool(false)),
+			expected: "T | where Id in (__kql_list_0) and Ok in (__kql_list_1)",
+			parameters: map[string]string{
+				"__kql_list_0": "dynamic([1,2,3])",
+				"__kql_list_1": "dynamic([true,false])",
+			},
+		},
+		{
+			name:     "inline lists",
+			b:        New("T | where Id in ").SetListParameterThreshold(10).InlineLists().AddLongList(1, 2, 3),
+			expected: "T | where Id in (long(1), long(2), long(3))",
+		},
+		{
+			name:     "mixed types",
+			b:        New("T | where Id in ").SetListParameterThreshold(10).AddList(value.NewLong(1), value.NewInt(2)),
+			expected: "T | where Id in (long(1), int(2))",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.b.String())
+			if test.parameters == nil {
+				assert.Nil(t, test.b.ListParameters())
+				return
+			}
+			require.NotNil(t, test.b.ListParameters())
+			assert.Equal(t, test.parameters, test.b.ListParameters().ToParameterCollection())
+		})
+	}
+}
+
+func TestAddListDefaultThreshold(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]string, 2000)
+	for i := range ids {
+		ids[i] = strings.Repeat("x", 10)
+	}
+
+	b := New("T | where Id in ").AddStringList(ids...)
+	assert.Equal(t, "T | where Id in (__kql_list_0)", b.String())
+
+	// Copies keep the lists.
+	c := FromBuilder(b).AddLiteral(" | take 1")
+	assert.Equal(t, "T | where Id in (__kql_list_0) | take 1", c.String())
+	assert.Equal(t, b.ListParameters().ToParameterCollection(), c.ListParameters().ToParameterCollection())
+
+	b.Reset()
+	assert.Nil(t, b.ListParameters())
+}
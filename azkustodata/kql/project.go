@@ -0,0 +1,75 @@
+package kql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AddProject adds a `| project` operator that keeps the columns, in order.
+func (b *Builder) AddProject(columns ...string) *Builder {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = NormalizeName(c)
+	}
+	b.builder.WriteString(" | project " + strings.Join(names, ", "))
+	return b
+}
+
+// ProjectFromStruct adds a `| project` operator that keeps the columns the fields of T are decoded from, so that the
+// query only returns the columns that are needed:
+//
+//	type Event struct {
+//		ID   int64  `kusto:"EventId"`
+//		Name string
+//	}
+//
+//	b, err := kql.ProjectFromStruct[Event](kql.New("Events | where Level == 'Error'"))
+//	// Events | where Level == 'Error' | project EventId, Name
+//
+// The columns follow the rules of query.ToStructs, see StructColumns. A misspelled tag fails the query with a
+// semantic error, instead of leaving the field with its zero value.
+func ProjectFromStruct[T any](b *Builder) (*Builder, error) {
+	columns, err := StructColumns[T]()
+	if err != nil {
+		return nil, err
+	}
+	return b.AddProject(columns...), nil
+}
+
+// StructColumns returns the names of the columns the fields of T, a struct or a pointer to a struct, are decoded
+// from by query.ToStructs: the exported fields, named after the field or the name in their `kusto:"<column>"` tag.
+// Fields tagged with `kusto:"-"` are skipped. Fields that select their column by index, with a `kusto:"#<index>"` tag,
+// don't name their column and are refused.
+func StructColumns[T any]() ([]string, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("StructColumns() requires a struct, got %s", t)
+	}
+
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.TrimSpace(field.Tag.Get("kusto")); tag == "-" {
+			continue
+		} else if strings.HasPrefix(tag, "#") {
+			return nil, fmt.Errorf("StructColumns() cannot project the field %s.%s, which selects its column by index with the tag %q", t, field.Name, tag)
+		} else if tag != "" {
+			name = tag
+		}
+		columns = append(columns, name)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("StructColumns() found no columns in %s", t)
+	}
+	return columns, nil
+}
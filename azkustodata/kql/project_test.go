@@ -0,0 +1,43 @@
+package kql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectFromStruct(t *testing.T) {
+	t.Parallel()
+
+	type event struct {
+		ID       int64 `kusto:"EventId"`
+		Name     string
+		Level    string `kusto:"log level"`
+		Ignored  string `kusto:"-"`
+		internal string
+	}
+	type byIndex struct {
+		First string `kusto:"#0"`
+	}
+	type empty struct {
+		internal string
+	}
+
+	b, err := ProjectFromStruct[event](New("Events | where Level == 'Error'"))
+	require.NoError(t, err)
+	assert.Equal(t, `Events | where Level == 'Error' | project EventId, Name, ["log level"]`, b.String())
+
+	columns, err := StructColumns[*event]()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"EventId", "Name", "log level"}, columns)
+
+	_, err = StructColumns[byIndex]()
+	assert.ErrorContains(t, err, "by index")
+	_, err = StructColumns[empty]()
+	assert.Error(t, err)
+	_, err = ProjectFromStruct[string](New("Events"))
+	assert.Error(t, err)
+
+	assert.Equal(t, "Events | project a, b", New("Events").AddProject("a", "b").String())
+}
@@ -105,6 +105,17 @@ func (q *Parameters) ToParameterCollection() map[string]string {
 	return parameters
 }
 
+// Merge adds the parameters of other to q, and returns q.
+func (q *Parameters) Merge(other *Parameters) *Parameters {
+	if q.parameters == nil {
+		q.parameters = make(map[string]value.Kusto)
+	}
+	for key, v := range other.parameters {
+		q.parameters[key] = v
+	}
+	return q
+}
+
 // Reset resets the parameters map
 func (q *Parameters) Reset() {
 	q.parameters = make(map[string]value.Kusto)
@@ -0,0 +1,105 @@
+package kql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// Set adds a `set` statement, which sets a request property for the query, before the query text:
+//
+//	kql.New("Events").Set("notruncation").Set("query_take_max_records", 5000)
+//	// set notruncation;
+//	// set query_take_max_records=5000;
+//	// Events
+//
+// Some properties can only be set with a statement, others can also be set with the query options of the client.
+// The option must be a valid property name, and take at most one value: a bool, an int, an int32, an int64, a float64,
+// a string, a time.Duration or a value.Kusto. Set panics otherwise, like AddKeyword.
+// Setting an option again replaces its value.
+func (b *Builder) Set(option string, v ...interface{}) *Builder {
+	if !isSetOption(option) {
+		panic(fmt.Sprintf("Invalid set option %q. Set options are names made of letters, digits and underscores.", option))
+	}
+	if len(v) > 1 {
+		panic(fmt.Sprintf("Invalid set option %q. Set options take at most one value, got %d.", option, len(v)))
+	}
+
+	statement := "set " + option
+	if len(v) == 1 {
+		statement += "=" + setValue(option, v[0])
+	}
+
+	for i, s := range b.sets {
+		if s.option == option {
+			b.sets[i].statement = statement
+			return b
+		}
+	}
+	b.sets = append(b.sets, setStatement{option: option, statement: statement})
+	return b
+}
+
+// setStatement is a `set` statement added with Set.
+type setStatement struct {
+	option    string
+	statement string
+}
+
+// isSetOption reports whether option is a valid request property name.
+func isSetOption(option string) bool {
+	if option == "" {
+		return false
+	}
+	for i, c := range option {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setValue returns the literal of the value of a set statement.
+func setValue(option string, v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		if v == "" {
+			return `""`
+		}
+		return QuoteString(v, false)
+	case time.Duration:
+		return QuoteValue(value.NewTimespan(v))
+	case value.Kusto:
+		return QuoteValue(v)
+	}
+	panic(fmt.Sprintf("Invalid value for set option %q. Unsupported type %T.", option, v))
+}
+
+// setPrefix returns the set statements of the builder, each followed by a newline.
+func (b *Builder) setPrefix() string {
+	if len(b.sets) == 0 {
+		return ""
+	}
+	prefix := strings.Builder{}
+	for _, s := range b.sets {
+		prefix.WriteString(s.statement)
+		prefix.WriteString(";\n")
+	}
+	return prefix.String()
+}
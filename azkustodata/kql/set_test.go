@@ -0,0 +1,58 @@
+package kql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		b        *Builder
+		expected string
+	}{
+		{"No value", New("Events").Set("notruncation"), "set notruncation;\nEvents"},
+		{"Int", New("Events").Set("query_take_max_records", 5000), "set query_take_max_records=5000;\nEvents"},
+		{"Bool", New("Events").Set("query_results_cache_force_refresh", true), "set query_results_cache_force_refresh=true;\nEvents"},
+		{"String", New("Events").Set("query_datascope", `hot"cache`), "set query_datascope=\"hot\\\"cache\";\nEvents"},
+		{"Empty string", New("Events").Set("request_app_name", ""), "set request_app_name=\"\";\nEvents"},
+		{"Duration", New("Events").Set("query_results_cache_max_age", 5*time.Minute), "set query_results_cache_max_age=timespan(00:05:00.0000000);\nEvents"},
+		{"Kusto value", New("Events").Set("maxmemoryconsumptionperiterator", value.NewLong(1<<30)), "set maxmemoryconsumptionperiterator=long(1073741824);\nEvents"},
+		{"Several", New("Events").Set("notruncation").AddLiteral(" | take 10").Set("truncationmaxsize", int64(1024)),
+			"set notruncation;\nset truncationmaxsize=1024;\nEvents | take 10"},
+		{"Replaced", New("Events").Set("truncationmaxsize", 1).Set("truncationmaxsize", 2), "set truncationmaxsize=2;\nEvents"},
+		{"Copied", FromBuilder(New("Events").Set("notruncation")).Set("truncationmaxsize", 1), "set notruncation;\nset truncationmaxsize=1;\nEvents"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, test.b.String())
+		})
+	}
+
+	b := New("Events").Set("notruncation")
+	b.Reset()
+	assert.Equal(t, "", b.String())
+}
+
+func TestSetInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, set := range []func(){
+		func() { New("Events").Set("") },
+		func() { New("Events").Set("no truncation") },
+		func() { New("Events").Set("notruncation; .drop table Events") },
+		func() { New("Events").Set("1option") },
+		func() { New("Events").Set("truncationmaxsize", 1, 2) },
+		func() { New("Events").Set("truncationmaxsize", struct{}{}) },
+	} {
+		assert.Panics(t, set)
+	}
+}
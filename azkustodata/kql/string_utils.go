@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf16"
 )
 
 // RequiresQuoting checks whether a given string is an identifier
@@ -25,11 +26,24 @@ func RequiresQuoting(value string) bool {
 	return false
 }
 
+// QuoteString returns value as a string literal, like QuoteStringLiteral, that is obfuscated in the logs of the service
+// if hidden is true, such as h"secret". It returns an empty string for an empty value.
 func QuoteString(value string, hidden bool) string {
 	if value == "" {
 		return value
 	}
+	return quoteString(value, hidden)
+}
+
+// QuoteStringLiteral returns value as a double-quoted string literal that can be spliced into a query or a command, such
+// as "it\'s", with its quotes, backslashes and control characters escaped, and the runes outside of Latin-1 escaped as
+// \uXXXX. Invalid UTF-8 sequences are replaced by U+FFFD.
+// Prefer the Builder and query parameters to build queries: this is for the fragments that are assembled outside of it.
+func QuoteStringLiteral(value string) string {
+	return quoteString(value, false)
+}
 
+func quoteString(value string, hidden bool) string {
 	var literal strings.Builder
 
 	if hidden {
@@ -74,6 +88,9 @@ func QuoteString(value string, hidden bool) string {
 		default:
 			if !ShouldBeEscaped(c) {
 				literal.WriteString(string(c))
+			} else if r1, r2 := utf16.EncodeRune(c); r1 != unicode.ReplacementChar {
+				// \u takes 4 hex digits, so the runes outside of the BMP are escaped as a surrogate pair.
+				literal.WriteString(fmt.Sprintf("\\u%04x\\u%04x", r1, r2))
 			} else {
 				literal.WriteString(fmt.Sprintf("\\u%04x", c))
 			}
@@ -93,25 +110,27 @@ func ShouldBeEscaped(c int32) bool {
 	return true
 }
 
+// FormatTimespan formats a duration as the value of a timespan literal, [-][d.]hh:mm:ss.fffffff.
+// Timespans have a precision of a tick (100ns), so the nanoseconds below it are truncated.
 func FormatTimespan(duration time.Duration) string {
-	// Calculate the number of days in the duration
-	days := duration / (24 * time.Hour)
+	sign := ""
+	abs := uint64(duration)
+	if duration < 0 {
+		sign = "-"
+		// Negating math.MinInt64 overflows, so negate through the unsigned value.
+		abs = -abs
+	}
 
-	// Calculate the remaining time after subtracting the days
-	remaining := duration - days*24*time.Hour
+	ticks := abs / uint64(100*time.Nanosecond)
+	fraction := ticks % 10_000_000
+	seconds := ticks / 10_000_000
 
-	daysStr := ""
-	if days > 0 {
-		daysStr = fmt.Sprintf("%d.", days)
+	days := ""
+	if seconds >= 24*60*60 {
+		days = fmt.Sprintf("%d.", seconds/(24*60*60))
 	}
 
-	// Use the `fmt.Sprintf()` function to format the duration as a string
-	return fmt.Sprintf("%s%02d:%02d:%02d.%07d",
-		daysStr,
-		int(remaining.Hours()),
-		int(remaining.Minutes())%60,
-		int(remaining.Seconds())%60,
-		int((remaining.Nanoseconds())%1000000000/100))
+	return fmt.Sprintf("%s%s%02d:%02d:%02d.%07d", sign, days, seconds/(60*60)%24, seconds/60%60, seconds%60, fraction)
 }
 
 func FormatDatetime(datetime time.Time) string {
@@ -0,0 +1,130 @@
+package kql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteStringLiteral(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", `""`},
+		{"foo", `"foo"`},
+		{`it's "quoted"`, `"it\'s \"quoted\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"a\nb\tc\x00", `"a\nb\tc\0"`},
+		{"é", `"é"`},
+		{"\u1234", `"\u1234"`},
+		{"😀", `"\ud83d\ude00"`},
+		{"\xff", `"\ufffd"`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, QuoteStringLiteral(tt.value), tt.value)
+	}
+
+	assert.Equal(t, "", QuoteString("", true))
+	assert.Equal(t, `h"secret"`, QuoteString("secret", true))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Events", "Events"},
+		{"_my_table2", "_my_table2"},
+		{"", `[""]`},
+		{"my-table", `["my-table"]`},
+		{"my table", `["my table"]`},
+		{"2024", `["2024"]`},
+		{"where", `["where"]`},
+		{"Count", `["Count"]`},
+		{`a"]; .drop table T`, `["a\"]; .drop table T"]`},
+		{"tábla", "tábla"},
+		{"表", `["\u8868"]`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, QuoteIdentifier(tt.name), tt.name)
+	}
+}
+
+// unquoteStringLiteral parses a literal of QuoteStringLiteral.
+func unquoteStringLiteral(t *testing.T, literal string) string {
+	require.True(t, len(literal) >= 2 && literal[0] == '"' && literal[len(literal)-1] == '"', literal)
+	body := []rune(literal[1 : len(literal)-1])
+
+	var sb strings.Builder
+	var pending []uint16
+	flush := func() {
+		sb.WriteString(string(utf16.Decode(pending)))
+		pending = nil
+	}
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		require.NotContains(t, []rune{'"', '\'', '\n', '\r'}, c, "unescaped %q in %s", c, literal)
+		if c != '\\' {
+			flush()
+			sb.WriteRune(c)
+			continue
+		}
+		require.Less(t, i+1, len(body), literal)
+		i++
+		if body[i] == 'u' {
+			require.LessOrEqual(t, i+5, len(body), literal)
+			u, err := strconv.ParseUint(string(body[i+1:i+5]), 16, 16)
+			require.NoError(t, err, literal)
+			pending = append(pending, uint16(u))
+			i += 4
+			continue
+		}
+		flush()
+		escape, ok := map[rune]string{'\'': "'", '"': `"`, '\\': `\`, '0': "\x00", 'a': "\a", 'b': "\b", 'f': "\f",
+			'n': "\n", 'r': "\r", 't': "\t", 'v': "\v"}[body[i]]
+		require.True(t, ok, "unknown escape %q in %s", body[i], literal)
+		sb.WriteString(escape)
+	}
+	flush()
+	return sb.String()
+}
+
+func FuzzQuoteStringLiteral(f *testing.F) {
+	for _, s := range []string{"", "foo", `it's "quoted"`, `\`, "a\nb", "\x00\x7f", "é", "\u1234", "😀", "\"]; .drop table T"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		if !utf8.ValidString(value) {
+			t.Skip()
+		}
+		assert.Equal(t, value, unquoteStringLiteral(t, QuoteStringLiteral(value)))
+	})
+}
+
+var plainIdentifier = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*$`)
+
+func FuzzQuoteIdentifier(f *testing.F) {
+	for _, s := range []string{"", "Events", "my-table", "2024", "where", "a]b", `a"]; .drop table T`, "表"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if !utf8.ValidString(name) {
+			t.Skip()
+		}
+		quoted := QuoteIdentifier(name)
+		if quoted == name {
+			assert.Regexp(t, plainIdentifier, name)
+			assert.False(t, RequiresQuoting(name))
+			return
+		}
+		require.True(t, strings.HasPrefix(quoted, "[") && strings.HasSuffix(quoted, "]"), quoted)
+		assert.Equal(t, name, unquoteStringLiteral(t, quoted[1:len(quoted)-1]))
+	})
+}
@@ -12,7 +12,7 @@ import (
 func QuoteValue(v value.Kusto) string {
 	val := v.GetValue()
 	t := v.GetType()
-	if val == nil {
+	if value.IsNull(v) {
 		return fmt.Sprintf("%v(null)", t)
 	}
 
@@ -3,6 +3,7 @@ package azkustodata
 import (
 	"context"
 	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/metrics"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
 	queryv2 "github.com/Azure/azure-kusto-go/azkustodata/query/v2"
@@ -34,12 +35,36 @@ const (
 )
 
 // Client is a client to a Kusto instance.
+// A Client is safe for concurrent use by multiple goroutines, and should be shared instead of created per call, so
+// that connections and tokens are reused.
 type Client struct {
 	conn          queryer
 	endpoint      string
 	auth          Authorization
 	http          *http.Client
 	clientDetails *ClientDetails
+	// defaultOptions are applied to every request, before the options of the request.
+	defaultOptions []QueryOption
+	// frameIdleTimeout is the longest time to wait for data while reading a query response, or 0 to wait forever.
+	frameIdleTimeout time.Duration
+	// onClaimsChallenge is called for every claims challenge returned by the service, see WithClaimsChallengeHook.
+	onClaimsChallenge func(ClaimsChallenge)
+	// auditor is called before every query and management command is sent, see WithAuditHook.
+	auditor *auditor
+	// defaultDatabase is the initial catalog of the connection string, used by the calls that don't specify a database.
+	defaultDatabase string
+	// transport tunes the transport of the default http client, see WithHTTP2 and WithIdleConnections.
+	transport transportOptions
+	// followers are the options of the follower databases, see WithFollowerDatabase.
+	followers map[string]followerOptions
+	// scheduler limits the concurrent requests of the client, see WithScheduler.
+	scheduler *scheduler
+	// capabilities are the capabilities of the service, see Capabilities.
+	capabilities negotiator
+	// functions are the stored functions of the databases, see ShowFunctions.
+	functions functionCache
+	// metrics records the metrics of the requests, see WithMetrics.
+	metrics *metrics.Registry
 }
 
 // Option is an optional argument type for New().
@@ -56,13 +81,29 @@ func New(kcsb *ConnectionStringBuilder, options ...Option) (*Client, error) {
 	}
 	endpoint := kcsb.DataSource
 
-	client := &Client{auth: *auth, endpoint: endpoint, clientDetails: NewClientDetails(kcsb.ApplicationForTracing, kcsb.UserForTracing)}
+	client := &Client{auth: *auth, endpoint: endpoint, clientDetails: NewClientDetails(kcsb.ApplicationForTracing, kcsb.UserForTracing),
+		defaultDatabase: kcsb.InitialCatalog}
 	for _, o := range options {
 		o(client)
 	}
 
+	client.transport.clientCertificates, err = kcsb.tlsClientCertificates()
+	if err != nil {
+		return nil, err
+	}
+	client.transport.proxies, err = kcsb.proxyRules()
+	if err != nil {
+		return nil, err
+	}
+	if client.scheduler != nil {
+		if err := client.scheduler.validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	if client.http == nil {
 		client.http = &http.Client{
+			Transport: client.transport.roundTripper(),
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
@@ -73,6 +114,8 @@ func New(kcsb *ConnectionStringBuilder, options ...Option) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	conn.onClaimsChallenge = client.onClaimsChallenge
+	conn.auditor = client.auditor
 	client.conn = conn
 
 	return client, nil
@@ -84,6 +127,24 @@ func WithHttpClient(client *http.Client) Option {
 	}
 }
 
+// WithDefaultQueryOptions sets QueryOptions that are applied to every query and management command made by the client.
+// Options passed to a specific call are applied after them, and take precedence.
+func WithDefaultQueryOptions(options ...QueryOption) Option {
+	return func(c *Client) {
+		c.defaultOptions = append(c.defaultOptions, options...)
+	}
+}
+
+// WithFrameIdleTimeout aborts a query when no data was received from the service for longer than d while reading its
+// response, for example when the service stalls in the middle of the results. The query then fails with an
+// *errors.FrameIdleTimeoutError, which holds the number of rows that were already delivered.
+// Time spent waiting for the caller to consume the results is not counted. By default, there is no idle timeout.
+func WithFrameIdleTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.frameIdleTimeout = d
+	}
+}
+
 // QueryOption is an option type for a call to Query().
 type QueryOption func(q *queryOptions) error
 
@@ -99,9 +160,13 @@ func (c *Client) Endpoint() string {
 
 type callType int8
 
+// maxQueryTextLength is the maximum length of the text of a query accepted by the service.
+const maxQueryTextLength = 2 * 1024 * 1024
+
 const (
-	queryCall = 1
-	mgmtCall  = 2
+	queryCall   = 1
+	mgmtCall    = 2
+	queryV1Call = 3
 )
 
 func (c *Client) Mgmt(ctx context.Context, db string, kqlQuery Statement, options ...QueryOption) (v1.Dataset, error) {
@@ -109,11 +174,12 @@ func (c *Client) Mgmt(ctx context.Context, db string, kqlQuery Statement, option
 
 	opQuery := errors.OpMgmt
 	call := mgmtCall
-	opts, err := setQueryOptions(ctx, opQuery, kqlQuery, call, options...)
+	opts, err := setQueryOptions(ctx, opQuery, kqlQuery, call, c.withDefaultOptions(options)...)
 	if err != nil {
 		return nil, err
 	}
 
+	db = c.database(db, opts)
 	conn, err := c.getConn(callType(call), connOptions{queryOptions: opts})
 	if err != nil {
 		return nil, err
@@ -126,9 +192,11 @@ func (c *Client) Mgmt(ctx context.Context, db string, kqlQuery Statement, option
 		return nil, err
 	}
 
-	return v1.NewDatasetFromReader(ctx, opQuery, res)
+	return v1.NewDatasetFromReader(ctx, opQuery, limitResponse(res, opQuery, opts))
 }
 
+// Query runs a query in the database db, and returns its results once they were fully read.
+// db can be empty when the connection string has an initial catalog, or when the OverrideDatabase option is used.
 func (c *Client) Query(ctx context.Context, db string, kqlQuery Statement, options ...QueryOption) (query.Dataset, error) {
 	ds, err := c.IterativeQuery(ctx, db, kqlQuery, options...)
 	if err != nil {
@@ -138,15 +206,61 @@ func (c *Client) Query(ctx context.Context, db string, kqlQuery Statement, optio
 	return ds.ToDataset()
 }
 
+// QueryV1 runs a query with the v1 REST API (/v1/rest/query), and returns the result as a v1.Dataset.
+// Prefer Query, which uses the v2 API. QueryV1 is for proxies and emulators that only implement the v1 API.
+func (c *Client) QueryV1(ctx context.Context, db string, kqlQuery Statement, options ...QueryOption) (v1.Dataset, error) {
+	ctx, cancel := contextSetup(ctx)
+
+	opQuery := errors.OpQuery
+	call := queryV1Call
+	opts, err := setQueryOptions(ctx, opQuery, kqlQuery, call, c.withDefaultOptions(options)...)
+	if err != nil {
+		return nil, err
+	}
+
+	db = c.database(db, opts)
+	if err := c.validateFunctionCall(ctx, opQuery, db, kqlQuery); err != nil {
+		cancel()
+		return nil, err
+	}
+	c.applyFollower(db, opts)
+	conn, err := c.getConn(callType(call), connOptions{queryOptions: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := conn.rawQuery(ctx, callType(call), db, kqlQuery, opts)
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return v1.NewDatasetFromReader(ctx, opQuery, limitResponse(res, opQuery, opts))
+}
+
 func (c *Client) IterativeQuery(ctx context.Context, db string, kqlQuery Statement, options ...QueryOption) (query.IterativeDataset, error) {
+	opts, dataset, err := c.iterativeQuery(ctx, db, kqlQuery, options)
+	if err != nil || opts.resume == nil {
+		return dataset, err
+	}
+	return newResumingDataset(ctx, dataset, kqlQuery, *opts.resume, func(ctx context.Context, q Statement) (query.IterativeDataset, error) {
+		_, dataset, err := c.iterativeQuery(ctx, db, q, options)
+		return dataset, err
+	}), nil
+}
+
+func (c *Client) iterativeQuery(ctx context.Context, db string, kqlQuery Statement, options []QueryOption) (*queryOptions, query.IterativeDataset, error) {
 	options = append(options, V2NewlinesBetweenFrames())
 	options = append(options, V2FragmentPrimaryTables())
-	options = append(options, ResultsErrorReportingPlacement(ResultsErrorReportingPlacementEndOfTable))
+	options = append(options, c.negotiateInStreamErrors())
+	options = append(options, endOfTableErrorsUnlessInStream())
 
 	opts, res, err := c.rawV2(ctx, db, kqlQuery, options)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	c.learnInStreamErrors(opts)
 
 	frameCapacity := queryv2.DefaultIoCapacity
 	if opts.v2IoCapacity != -1 {
@@ -163,7 +277,8 @@ func (c *Client) IterativeQuery(ctx context.Context, db string, kqlQuery Stateme
 		fragmentCapacity = opts.v2TableCapacity
 	}
 
-	return queryv2.NewIterativeDataset(ctx, res, frameCapacity, rowCapacity, fragmentCapacity)
+	dataset, err := queryv2.NewIterativeDataset(ctx, res, frameCapacity, rowCapacity, fragmentCapacity, opts.datasetOptions...)
+	return opts, dataset, err
 }
 
 func (c *Client) RawV2(ctx context.Context, db string, kqlQuery Statement, options []QueryOption) (io.ReadCloser, error) {
@@ -177,11 +292,17 @@ func (c *Client) RawV2(ctx context.Context, db string, kqlQuery Statement, optio
 func (c *Client) rawV2(ctx context.Context, db string, kqlQuery Statement, options []QueryOption) (*queryOptions, io.ReadCloser, error) {
 	ctx, cancel := contextSetup(ctx)
 	opQuery := errors.OpQuery
-	opts, err := setQueryOptions(ctx, opQuery, kqlQuery, queryCall, options...)
+	opts, err := setQueryOptions(ctx, opQuery, kqlQuery, queryCall, c.withDefaultOptions(options)...)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	db = c.database(db, opts)
+	if err := c.validateFunctionCall(ctx, opQuery, db, kqlQuery); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	c.applyFollower(db, opts)
 	conn, err := c.getConn(queryCall, connOptions{queryOptions: opts})
 	if err != nil {
 		return nil, nil, err
@@ -193,6 +314,10 @@ func (c *Client) rawV2(ctx context.Context, db string, kqlQuery Statement, optio
 		cancel()
 		return nil, nil, err
 	}
+	res = limitResponse(res, opQuery, opts)
+	if c.frameIdleTimeout > 0 {
+		res = newIdleTimeoutReader(res, c.frameIdleTimeout)
+	}
 	return opts, res, nil
 }
 
@@ -210,6 +335,29 @@ func (c *Client) QueryToJson(ctx context.Context, db string, query Statement, op
 	return string(all), nil
 }
 
+// database returns the database of a call: the database of the OverrideDatabase option, then db, then the initial
+// catalog of the connection string. It is empty if none is set, in which case the service picks the database.
+func (c *Client) database(db string, opts *queryOptions) string {
+	switch {
+	case opts.database != "":
+		return opts.database
+	case db != "":
+		return db
+	default:
+		return c.defaultDatabase
+	}
+}
+
+// withDefaultOptions returns the default options of the client followed by options.
+func (c *Client) withDefaultOptions(options []QueryOption) []QueryOption {
+	if len(c.defaultOptions) == 0 {
+		return options
+	}
+	all := make([]QueryOption, 0, len(c.defaultOptions)+len(options))
+	all = append(all, c.defaultOptions...)
+	return append(all, options...)
+}
+
 func setQueryOptions(ctx context.Context, op errors.Op, query Statement, queryType int, options ...QueryOption) (*queryOptions, error) {
 	opt := &queryOptions{
 		requestProperties: &requestProperties{
@@ -226,6 +374,17 @@ func setQueryOptions(ctx context.Context, op errors.Op, query Statement, queryTy
 		}
 	}
 
+	if lists := query.ListParameters(); lists != nil {
+		params := kql.NewParameters().Merge(&opt.requestProperties.QueryParameters).Merge(lists)
+		opt.requestProperties.QueryParameters = *params
+		opt.requestProperties.Parameters = params.ToParameterCollection()
+	}
+
+	if queryType != mgmtCall && len(query.String()) > maxQueryTextLength {
+		return nil, errors.ES(op, errors.KClientArgs, "the query text is %d bytes long, which exceeds the limit of %d bytes. "+
+			"Add large lists of values with kql.Builder.AddList, which sends them as query parameters", len(query.String()), maxQueryTextLength).SetNoRetry()
+	}
+
 	CalculateTimeout(ctx, opt, queryType)
 
 	if query.SupportsInlineParameters() {
@@ -259,7 +418,7 @@ func CalculateTimeout(ctx context.Context, opt *queryOptions, queryType int) {
 
 	var timeout time.Duration
 	switch queryType {
-	case queryCall:
+	case queryCall, queryV1Call:
 		timeout = defaultQueryTimeout
 	case mgmtCall:
 		timeout = defaultMgmtTimeout
@@ -270,13 +429,20 @@ func CalculateTimeout(ctx context.Context, opt *queryOptions, queryType int) {
 func (c *Client) getConn(callType callType, options connOptions) (queryer, error) {
 	switch callType {
 	case queryCall:
-		return c.conn, nil
-	case mgmtCall:
+	case mgmtCall, queryV1Call:
 		delete(options.queryOptions.requestProperties.Options, "results_progressive_enabled")
-		return c.conn, nil
 	default:
 		return nil, errors.ES(errors.OpServConn, errors.KInternal, "an unknown calltype was passed to getConn()")
 	}
+
+	conn := c.conn
+	if c.metrics != nil {
+		conn = meteredConn{queryer: conn, registry: c.metrics, endpoint: c.endpoint}
+	}
+	if c.scheduler != nil {
+		return scheduledConn{queryer: conn, scheduler: c.scheduler}, nil
+	}
+	return conn, nil
 }
 
 func contextSetup(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -0,0 +1,30 @@
+/*
+Package kustotesting provides helpers for the integration tests of projects that use azkustodata and azkustoingest:
+creating test tables that are dropped when the test ends, waiting for ingested data to be queryable, and assertions
+on the results of queries.
+
+	func TestIngestion(t *testing.T) {
+		client, err := azkustodata.New(kcsb)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		kustotesting.CreateTestTable(t, client, "database", "Events", "(Id:long, Name:string)")
+
+		// Ingest 3 rows into Events...
+
+		kustotesting.WaitForIngest(t, client, "database", "Events", 3, 5*time.Minute)
+
+		dataset, err := client.Query(ctx, "database", kql.New("Events"))
+		require.NoError(t, err)
+		kustotesting.AssertTableCount(t, dataset, 1)
+		kustotesting.AssertSchema(t, dataset.Tables()[0], "(Id:long, Name:string)")
+	}
+
+The helpers report failures to the testing.TB they are given, and mark themselves as test helpers, so failures are
+reported at the line of the caller, except WaitForRows, which returns an error that describes the last poll, so that it
+can be used outside of tests and with custom conditions:
+
+	got, err := kustotesting.WaitForRows(ctx, client, "database", kql.New("Events | where Name == 'a'"), 3,
+		kustotesting.WaitTimeout(5*time.Minute), kustotesting.PollInterval(5*time.Second))
+*/
+package kustotesting
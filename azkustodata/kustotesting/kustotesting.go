@@ -0,0 +1,162 @@
+package kustotesting
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+)
+
+// waitPollInterval is the interval at which WaitForIngest counts the rows of the table.
+const waitPollInterval = 2 * time.Second
+
+// Client is the part of azkustodata.Client that the helpers use.
+type Client interface {
+	Query(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (query.Dataset, error)
+	Mgmt(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error)
+}
+
+// clearStreamingCacheStatement refreshes the table schemas cached by streaming ingestion, so that the new table can be
+// streamed to right away.
+var clearStreamingCacheStatement = kql.New(".clear database cache streamingingestion schema")
+
+// CreateTestTable creates the table with the schema, in the "(name:type, ...)" format of .create table, dropping any
+// existing table with the same name first. The table is dropped when the test and its subtests complete.
+// Additional commands, such as the creation of ingestion mappings, are run after the table is created.
+func CreateTestTable(t testing.TB, client Client, db, table, schema string, commands ...azkustodata.Statement) {
+	t.Helper()
+
+	drop := kql.New(".drop table ").AddTable(table).AddLiteral(" ifexists")
+	create := kql.New(".create table ").AddTable(table).AddUnsafe(schema)
+
+	t.Cleanup(func() {
+		if _, err := client.Mgmt(context.Background(), db, drop); err != nil {
+			t.Logf("kustotesting: failed to drop table %s: %s", table, err)
+		}
+	})
+
+	all := append([]azkustodata.Statement{drop, create}, commands...)
+	all = append(all, clearStreamingCacheStatement)
+	for _, cmd := range all {
+		if _, err := client.Mgmt(context.Background(), db, cmd); err != nil {
+			t.Fatalf("kustotesting: failed to create table %s, command %q failed: %s", table, cmd.String(), err)
+			return
+		}
+	}
+}
+
+// countResult is the result of a count query.
+type countResult struct {
+	Count int64
+}
+
+// Count returns the number of rows in the table.
+func Count(t testing.TB, client Client, db, table string) int64 {
+	t.Helper()
+
+	count, err := count(client, db, table)
+	if err != nil {
+		t.Fatalf("kustotesting: failed to count the rows of table %s: %s", table, err)
+	}
+	return count
+}
+
+func count(client Client, db, table string) (int64, error) {
+	dataset, err := client.Query(context.Background(), db, kql.New("").AddTable(table).AddLiteral(" | count"))
+	if err != nil {
+		return 0, err
+	}
+	if len(dataset.Tables()) == 0 {
+		return 0, nil
+	}
+
+	rows, err := query.ToStructs[countResult](dataset.Tables()[0])
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Count, nil
+}
+
+// WaitForIngest waits until the table has at least want rows, and fails the test if it doesn't within the timeout.
+// Queued ingestion is batched, so the timeout should allow for the batching time span of the table.
+func WaitForIngest(t testing.TB, client Client, db, table string, want int64, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	var got int64
+	var err error
+	for {
+		got, err = count(client, db, table)
+		if err == nil && got >= want {
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		if remaining > waitPollInterval {
+			remaining = waitPollInterval
+		}
+		time.Sleep(remaining)
+	}
+
+	if err != nil {
+		t.Fatalf("kustotesting: table %s did not reach %d rows within %s: %s", table, want, timeout, err)
+		return
+	}
+	t.Fatalf("kustotesting: table %s did not reach %d rows within %s, it has %d rows", table, want, timeout, got)
+}
+
+// AssertTableCount reports an error if the dataset doesn't have want tables, and returns whether it has.
+func AssertTableCount(t testing.TB, dataset query.Dataset, want int) bool {
+	t.Helper()
+
+	if got := len(dataset.Tables()); got != want {
+		t.Errorf("kustotesting: expected %d tables in the dataset, got %d", want, got)
+		return false
+	}
+	return true
+}
+
+// AssertRowCount reports an error if the table doesn't have want rows, and returns whether it has.
+func AssertRowCount(t testing.TB, table query.Table, want int) bool {
+	t.Helper()
+
+	if got := len(table.Rows()); got != want {
+		t.Errorf("kustotesting: expected %d rows in table %s, got %d", want, table.Name(), got)
+		return false
+	}
+	return true
+}
+
+// AssertSchema reports an error if the columns of the table don't match the schema, in the "(name:type, ...)" format
+// of .create table, and returns whether they match. Spaces are ignored.
+func AssertSchema(t testing.TB, table query.BaseTable, want string) bool {
+	t.Helper()
+
+	got := Schema(table)
+	if normalizeSchema(got) != normalizeSchema(want) {
+		t.Errorf("kustotesting: expected the schema %s for table %s, got %s", want, table.Name(), got)
+		return false
+	}
+	return true
+}
+
+// Schema returns the columns of the table in the "(name:type, ...)" format of .create table.
+func Schema(table query.BaseTable) string {
+	columns := make([]string, len(table.Columns()))
+	for i, c := range table.Columns() {
+		columns[i] = c.Name() + ":" + string(c.Type())
+	}
+	return "(" + strings.Join(columns, ", ") + ")"
+}
+
+func normalizeSchema(schema string) string {
+	return strings.Join(strings.Fields(schema), "")
+}
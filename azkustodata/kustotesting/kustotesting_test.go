@@ -0,0 +1,207 @@
+package kustotesting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient records the commands, and returns the count of the rows of any queried table.
+type fakeClient struct {
+	mu       sync.Mutex
+	commands []string
+	counts   []int64
+	failMgmt bool
+}
+
+func (f *fakeClient) Query(ctx context.Context, _ string, _ azkustodata.Statement, _ ...azkustodata.QueryOption) (query.Dataset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count := f.counts[0]
+	if len(f.counts) > 1 {
+		f.counts = f.counts[1:]
+	}
+	return countDataset(ctx, count), nil
+}
+
+func (f *fakeClient) Mgmt(_ context.Context, _ string, query azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands = append(f.commands, query.String())
+	if f.failMgmt {
+		return nil, fmt.Errorf("forbidden")
+	}
+	return nil, nil
+}
+
+func countDataset(ctx context.Context, count int64) query.Dataset {
+	base := query.NewBaseDataset(ctx, errors.OpQuery, "PrimaryResult")
+	table := query.NewBaseTable(base, 0, "0", "Table_0", "PrimaryResult", []query.Column{query.NewColumn(0, "Count", types.Long)})
+	return query.NewDataset(base, []query.Table{
+		query.NewTable(table, []query.Row{query.NewRow(table, 0, value.Values{value.NewLong(count)})}),
+	})
+}
+
+// recordingTB records the failures of the helpers instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors   []string
+	fatal    bool
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Logf(string, ...interface{}) {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func TestCreateTestTable(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	tb := &recordingTB{}
+	CreateTestTable(tb, client, "db", "my table", "(Id:long)", kql.New(".create table ").AddTable("my table").AddLiteral(" ingestion json mapping 'm' '[]'"))
+	assert.Empty(t, tb.errors)
+	assert.Equal(t, []string{
+		`.drop table ["my table"] ifexists`,
+		`.create table ["my table"](Id:long)`,
+		`.create table ["my table"] ingestion json mapping 'm' '[]'`,
+		`.clear database cache streamingingestion schema`,
+	}, client.commands)
+
+	assert.Len(t, tb.cleanups, 1)
+	tb.cleanups[0]()
+	assert.Equal(t, `.drop table ["my table"] ifexists`, client.commands[len(client.commands)-1])
+
+	failing := &recordingTB{}
+	CreateTestTable(failing, &fakeClient{failMgmt: true}, "db", "table", "(Id:long)")
+	assert.True(t, failing.fatal)
+	assert.Len(t, failing.cleanups, 1)
+}
+
+func TestWaitForIngest(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{counts: []int64{3}}
+	tb := &recordingTB{}
+	assert.Equal(t, int64(3), Count(tb, client, "db", "table"))
+	WaitForIngest(tb, client, "db", "table", 3, time.Minute)
+	assert.Empty(t, tb.errors)
+
+	client = &fakeClient{counts: []int64{1}}
+	tb = &recordingTB{}
+	WaitForIngest(tb, client, "db", "table", 3, 10*time.Millisecond)
+	assert.True(t, tb.fatal)
+	assert.Contains(t, tb.errors[0], "it has 1 rows")
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	dataset := countDataset(context.Background(), 1)
+	table := dataset.Tables()[0]
+	assert.Equal(t, "(Count:long)", Schema(table))
+
+	tb := &recordingTB{}
+	assert.True(t, AssertTableCount(tb, dataset, 1))
+	assert.True(t, AssertRowCount(tb, table, 1))
+	assert.True(t, AssertSchema(tb, table, "( Count: long )"))
+	assert.Empty(t, tb.errors)
+
+	assert.False(t, AssertTableCount(tb, dataset, 2))
+	assert.False(t, AssertRowCount(tb, table, 2))
+	assert.False(t, AssertSchema(tb, table, "(Count:int)"))
+	assert.Len(t, tb.errors, 3)
+	assert.False(t, tb.fatal)
+}
+
+// rowsClient returns datasets of the given numbers of rows, or the error if it is set.
+type rowsClient struct {
+	fakeClient
+	rows []int
+	err  error
+}
+
+func (r *rowsClient) Query(ctx context.Context, _ string, _ azkustodata.Statement, _ ...azkustodata.QueryOption) (query.Dataset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	n := r.rows[0]
+	if len(r.rows) > 1 {
+		r.rows = r.rows[1:]
+	}
+
+	base := query.NewBaseDataset(ctx, errors.OpQuery, "PrimaryResult")
+	table := query.NewBaseTable(base, 0, "0", "Table_0", "PrimaryResult", []query.Column{query.NewColumn(0, "Id", types.Long)})
+	rows := make([]query.Row, n)
+	for i := range rows {
+		rows[i] = query.NewRow(table, i, value.Values{value.NewLong(int64(i))})
+	}
+	return query.NewDataset(base, []query.Table{query.NewTable(table, rows)}), nil
+}
+
+func TestWaitForRows(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stmt := kql.New("T | where Id > 0")
+	poll := PollInterval(time.Millisecond)
+
+	got, err := WaitForRows(ctx, &rowsClient{rows: []int{0, 1, 3}}, "db", stmt, 2, poll)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), got)
+
+	got, err = WaitForRows(ctx, &rowsClient{rows: []int{3, 2}}, "db", stmt, 2, poll, CompareCount(func(got, want int64) bool { return got == want }))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), got)
+
+	got, err = WaitForRows(ctx, &rowsClient{rows: []int{1}}, "db", stmt, 2, poll, WaitTimeout(20*time.Millisecond))
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr)
+	assert.Equal(t, int64(1), got)
+	assert.Equal(t, int64(1), waitErr.Got)
+	assert.Equal(t, int64(2), waitErr.Want)
+	assert.Greater(t, waitErr.Polls, 1)
+	assert.Nil(t, waitErr.LastErr)
+	assert.Contains(t, err.Error(), "the last one returned 1 rows")
+
+	queryErr := fmt.Errorf("table not found")
+	_, err = WaitForRows(ctx, &rowsClient{err: queryErr}, "db", stmt, 2, poll, WaitTimeout(20*time.Millisecond))
+	assert.ErrorAs(t, err, &waitErr)
+	assert.ErrorIs(t, err, queryErr)
+	assert.Equal(t, int64(-1), waitErr.Got)
+	assert.Contains(t, err.Error(), "the last one failed: table not found")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = WaitForRows(canceled, &rowsClient{rows: []int{1}}, "db", stmt, 2, poll)
+	assert.ErrorIs(t, err, context.Canceled)
+}
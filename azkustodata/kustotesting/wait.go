@@ -0,0 +1,138 @@
+package kustotesting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+)
+
+// DefaultWaitTimeout is the timeout of WaitForRows, unless set with WaitTimeout or by the deadline of its context.
+const DefaultWaitTimeout = 5 * time.Minute
+
+type waitOptions struct {
+	timeout      time.Duration
+	pollInterval time.Duration
+	compare      func(got, want int64) bool
+	queryOptions []azkustodata.QueryOption
+}
+
+// WaitOption is an option for WaitForRows.
+type WaitOption func(o *waitOptions)
+
+// WaitTimeout sets how long WaitForRows waits. The default is DefaultWaitTimeout.
+func WaitTimeout(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.timeout = d
+	}
+}
+
+// PollInterval sets the interval at which WaitForRows runs the query. The default is 2 seconds.
+func PollInterval(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.pollInterval = d
+	}
+}
+
+// CompareCount sets how WaitForRows compares the number of rows to the wanted number, such as got == want to wait for
+// an exact count. The default is got >= want.
+func CompareCount(compare func(got, want int64) bool) WaitOption {
+	return func(o *waitOptions) {
+		o.compare = compare
+	}
+}
+
+// WaitQueryOptions passes QueryOptions to the query of WaitForRows, such as the QueryParameters of the statement.
+func WaitQueryOptions(options ...azkustodata.QueryOption) WaitOption {
+	return func(o *waitOptions) {
+		o.queryOptions = append(o.queryOptions, options...)
+	}
+}
+
+// WaitError is returned by WaitForRows when the rows didn't reach the wanted number in time.
+type WaitError struct {
+	// Want is the wanted number of rows, and Got the number of rows returned by the last query that succeeded, or -1 if
+	// none did.
+	Want int64
+	Got  int64
+	// Polls is the number of times the query ran.
+	Polls int
+	// Elapsed is how long WaitForRows waited.
+	Elapsed time.Duration
+	// LastErr is the error of the last query, if it failed.
+	LastErr error
+}
+
+func (e *WaitError) Error() string {
+	msg := fmt.Sprintf("kustotesting: the query did not return %d rows after %d polls in %s", e.Want, e.Polls, e.Elapsed.Round(time.Millisecond))
+	if e.Got >= 0 {
+		msg += fmt.Sprintf(", the last one returned %d rows", e.Got)
+	}
+	if e.LastErr != nil {
+		msg += fmt.Sprintf(", the last one failed: %s", e.LastErr)
+	}
+	return msg
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.LastErr
+}
+
+// WaitForRows runs stmt until its first table has want rows, as compared by CompareCount, and returns the number of
+// rows it got. The query runs every PollInterval until WaitTimeout or ctx is done, and failed queries are retried
+// until then, as tables created by a test can take a moment to be queryable. On timeout, it returns a *WaitError with
+// the last number of rows and error, and the number of polls.
+// It is the wait of tests that ingest data then query it: use a statement that returns few rows, such as a count
+// summarized per key, as every poll reads the whole result.
+func WaitForRows(ctx context.Context, client Client, db string, stmt azkustodata.Statement, want int64, options ...WaitOption) (int64, error) {
+	opts := waitOptions{
+		timeout:      DefaultWaitTimeout,
+		pollInterval: waitPollInterval,
+		compare:      func(got, want int64) bool { return got >= want },
+	}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	start := time.Now()
+	waitErr := &WaitError{Want: want, Got: -1}
+	for {
+		got, err := rowCount(ctx, client, db, stmt, opts.queryOptions)
+		waitErr.Polls++
+		waitErr.LastErr = err
+		if err == nil {
+			waitErr.Got = got
+			if opts.compare(got, want) {
+				return got, nil
+			}
+		}
+
+		timer := time.NewTimer(opts.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			waitErr.Elapsed = time.Since(start)
+			if waitErr.LastErr == nil && ctx.Err() != context.DeadlineExceeded {
+				waitErr.LastErr = ctx.Err()
+			}
+			return waitErr.Got, waitErr
+		case <-timer.C:
+		}
+	}
+}
+
+// rowCount returns the number of rows of the first table of the result of stmt.
+func rowCount(ctx context.Context, client Client, db string, stmt azkustodata.Statement, options []azkustodata.QueryOption) (int64, error) {
+	dataset, err := client.Query(ctx, db, stmt, options...)
+	if err != nil {
+		return 0, err
+	}
+	if len(dataset.Tables()) == 0 {
+		return 0, nil
+	}
+	return int64(len(dataset.Tables()[0].Rows())), nil
+}
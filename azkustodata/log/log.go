@@ -0,0 +1,108 @@
+/*
+Package log routes the diagnostics of the Kusto clients through the logging of the Azure SDK for Go, so that the events
+of the Kusto clients and of the Azure SDK are configured, and received, in the same place.
+
+Limitation: the listener set with azcore/log.SetListener doesn't receive the events of the Kusto clients, as the Azure
+SDK only lets its own modules write to it. Applications that already call azcore/log.SetListener must call SetListener
+of this package instead - it sets the listener of the Azure SDK too, so that the one listener receives the events of
+both. The same goes for SetEvents.
+
+	log.SetListener(func(event log.Event, msg string) {
+		fmt.Printf("%s: %s\n", event, msg)
+	})
+	log.SetEvents(log.EventQuery, log.EventIngest, azlog.EventRetryPolicy)
+
+Like the Azure SDK, the events are written to stderr when the AZURE_SDK_GO_LOGGING environment variable is set to "all".
+*/
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+)
+
+// Event is the class of a log entry, shared with the Azure SDK.
+type Event = azlog.Event
+
+const (
+	// EventQuery entries contain information about queries and management commands: the endpoint, the database, the
+	// client request id, the duration and the error, if any. The text of the queries isn't logged.
+	EventQuery Event = "KustoQuery"
+
+	// EventIngest entries contain information about ingestions: the database and table, the source and its outcome.
+	EventIngest Event = "KustoIngest"
+
+	// EventAuth entries contain information about the tokens acquired by the clients and the claims challenges of the
+	// service. Tokens aren't logged.
+	EventAuth Event = "KustoAuth"
+
+	// EventResourceRefresh entries contain information about the fetches of the ingestion resources.
+	EventResourceRefresh Event = "KustoResourceRefresh"
+)
+
+type logger struct {
+	// events are the events to log, or nil to log all of them.
+	events map[Event]bool
+	lst    func(Event, string)
+}
+
+var current atomic.Pointer[logger]
+
+func init() {
+	l := &logger{}
+	if os.Getenv("AZURE_SDK_GO_LOGGING") == "all" {
+		l.lst = func(e Event, msg string) {
+			fmt.Fprintf(os.Stderr, "[%s] %s: %s\n", time.Now().Format(time.StampMicro), e, msg)
+		}
+	}
+	current.Store(l)
+}
+
+// SetListener sets the function that receives the log entries of the Kusto clients and of the Azure SDK, replacing
+// any listener set with azcore/log.SetListener. Pass nil to stop logging.
+func SetListener(lst func(Event, string)) {
+	l := *current.Load()
+	l.lst = lst
+	current.Store(&l)
+	azlog.SetListener(lst)
+}
+
+// SetEvents restricts the entries passed to the listener to the given events, of the Kusto clients and of the Azure
+// SDK. By default, or if no events are given, all the events are passed.
+func SetEvents(events ...Event) {
+	l := *current.Load()
+	l.events = nil
+	if len(events) > 0 {
+		l.events = make(map[Event]bool, len(events))
+		for _, e := range events {
+			l.events[e] = true
+		}
+	}
+	current.Store(&l)
+	azlog.SetEvents(events...)
+}
+
+// Should reports whether entries of the event are passed to a listener. It can be used to skip expensive work when
+// they aren't.
+func Should(e Event) bool {
+	l := current.Load()
+	return l.lst != nil && (l.events == nil || l.events[e])
+}
+
+// Write passes a log entry to the listener, if the event should be logged.
+func Write(e Event, msg string) {
+	if l := current.Load(); l.lst != nil && (l.events == nil || l.events[e]) {
+		l.lst(e, msg)
+	}
+}
+
+// Writef is like Write, with a formatted message. The message is only formatted if the event should be logged.
+func Writef(e Event, format string, a ...interface{}) {
+	if Should(e) {
+		Write(e, fmt.Sprintf(format, a...))
+	}
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"testing"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener(t *testing.T) {
+	type entry struct {
+		event Event
+		msg   string
+	}
+	var entries []entry
+	SetListener(func(e Event, msg string) {
+		entries = append(entries, entry{e, msg})
+	})
+	defer SetListener(nil)
+
+	assert.True(t, Should(EventQuery))
+	Write(EventQuery, "query")
+	Writef(EventIngest, "ingest %d", 1)
+	assert.Equal(t, []entry{{EventQuery, "query"}, {EventIngest, "ingest 1"}}, entries)
+
+	entries = nil
+	SetEvents(EventAuth, azlog.EventRetryPolicy)
+	defer SetEvents()
+	assert.False(t, Should(EventQuery))
+	Write(EventQuery, "query")
+	Writef(EventAuth, "auth")
+	assert.Equal(t, []entry{{EventAuth, "auth"}}, entries)
+
+	SetListener(nil)
+	assert.False(t, Should(EventAuth))
+}
@@ -0,0 +1,74 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/metrics"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// WithMetrics records the latency, the errors and the in-flight count of every query and management command of the
+// client in registry, per endpoint and database. A request is in flight from when it is sent until its response is
+// fully read or closed, and its latency covers that time, but not the time it waits for the scheduler of WithScheduler.
+// The errors are those of the requests and of reading their responses, errors reported within the results of a query
+// aren't counted.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(c *Client) {
+		c.metrics = registry
+	}
+}
+
+// meteredConn records the metrics of the requests of a queryer.
+type meteredConn struct {
+	queryer
+	registry *metrics.Registry
+	endpoint string
+}
+
+func (c meteredConn) rawQuery(ctx context.Context, callType callType, db string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	key := metrics.Key{Endpoint: c.endpoint, Database: db, Operation: metrics.OperationQuery}
+	if callType == mgmtCall {
+		key.Operation = metrics.OperationMgmt
+	}
+	done := c.registry.Start(key)
+
+	body, err := c.queryer.rawQuery(ctx, callType, db, query, options)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	return &meteredBody{body: body, done: done}, nil
+}
+
+// meteredBody records the end of its request once it is fully read, fails or is closed.
+type meteredBody struct {
+	body io.ReadCloser
+	done func(err error)
+	once sync.Once
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	switch {
+	case err == io.EOF:
+		b.once.Do(func() { b.done(nil) })
+	case err != nil:
+		b.once.Do(func() { b.done(err) })
+	}
+	return n, err
+}
+
+// TransferStats implements query.TransferStatsReporter, with the statistics of the body.
+func (b *meteredBody) TransferStats() query.TransferStats {
+	if stats, ok := b.body.(query.TransferStatsReporter); ok {
+		return stats.TransferStats()
+	}
+	return query.TransferStats{}
+}
+
+func (b *meteredBody) Close() error {
+	b.once.Do(func() { b.done(nil) })
+	return b.body.Close()
+}
@@ -0,0 +1,214 @@
+/*
+Package metrics records the latencies, errors and in-flight requests of the queries and management commands of a
+client, per endpoint and database, for dashboards and alerts:
+
+	registry := metrics.NewRegistry()
+	client, err := azkustodata.New(kcsb, azkustodata.WithMetrics(registry))
+	...
+	http.Handle("/metrics", metrics.PrometheusHandler(registry))
+	metrics.PublishExpvar("kusto", registry)
+
+The latencies are kept in histograms with exponential buckets, so that both fast queries and long commands are
+measured with a constant relative precision. A registry can be shared by several clients.
+*/
+package metrics
+
+import (
+	"errors"
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+
+	kustoErrors "github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// Operations of a Key.
+const (
+	OperationQuery = "query"
+	OperationMgmt  = "mgmt"
+)
+
+// Key identifies the requests a Series is recorded for.
+type Key struct {
+	Endpoint string
+	// Database is the database of the requests, or empty for the requests that don't specify one.
+	Database string
+	// Operation is OperationQuery or OperationMgmt.
+	Operation string
+}
+
+// Series are the metrics of the requests of a Key.
+type Series struct {
+	Key
+	// InFlight is the number of requests that were sent and whose response wasn't fully read yet.
+	InFlight int64
+	// Requests is the number of completed requests, including the failed ones.
+	Requests uint64
+	// Errors is the number of failed requests by the kind of their error, such as "KHTTPError" - see errors.Kind.
+	Errors map[string]uint64
+	// Latency is the distribution of the durations of the completed requests.
+	Latency Histogram
+}
+
+// Histogram is a distribution of durations.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets, in increasing order. The last bucket has no upper bound.
+	Bounds []time.Duration
+	// Counts are the number of durations in every bucket, with one more bucket than Bounds. A duration is in the first
+	// bucket whose bound is greater than or equal to it.
+	Counts []uint64
+	// Count and Sum are the number and the total of the durations.
+	Count uint64
+	Sum   time.Duration
+}
+
+// Quantile returns an estimate of the q quantile of the durations, such as 0.99 for the 99th percentile, by linear
+// interpolation within its bucket. Durations in the last bucket are estimated at the last bound. It returns 0 for an
+// empty histogram.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := q * float64(h.Count)
+	var seen uint64
+	for i, c := range h.Counts {
+		if c == 0 || float64(seen+c) < rank {
+			seen += c
+			continue
+		}
+		if i == len(h.Bounds) {
+			break
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = h.Bounds[i-1]
+		}
+		return lower + time.Duration(float64(h.Bounds[i]-lower)*(rank-float64(seen))/float64(c))
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// Collector returns the series of a registry. *Registry implements it.
+type Collector interface {
+	Collect() []Series
+}
+
+// Option is an option of NewRegistry.
+type Option func(r *Registry)
+
+// ExponentialBuckets sets the bounds of the buckets of the latency histograms to count bounds, starting at start and
+// multiplied by factor. It panics unless start and count are positive and factor is greater than 1.
+// The default is 20 buckets from 1ms with a factor of 2, up to about 9 minutes.
+func ExponentialBuckets(start time.Duration, factor float64, count int) Option {
+	if start <= 0 || factor <= 1 || count <= 0 {
+		panic("metrics.ExponentialBuckets requires a positive start and count, and a factor greater than 1")
+	}
+	bounds := make([]time.Duration, count)
+	bound := float64(start)
+	for i := range bounds {
+		bounds[i] = time.Duration(bound)
+		bound *= factor
+	}
+	return func(r *Registry) {
+		r.bounds = bounds
+	}
+}
+
+// Registry records the metrics of requests, and is safe for concurrent use.
+type Registry struct {
+	bounds []time.Duration
+
+	mu     sync.Mutex
+	series map[Key]*Series
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry(options ...Option) *Registry {
+	r := &Registry{series: map[Key]*Series{}}
+	ExponentialBuckets(time.Millisecond, 2, 20)(r)
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// Start records the start of a request, and returns the function that records its end, with the error it failed with
+// or nil. The function must be called exactly once.
+func (r *Registry) Start(key Key) func(err error) {
+	start := time.Now()
+	r.mu.Lock()
+	r.seriesLocked(key).InFlight++
+	r.mu.Unlock()
+
+	return func(err error) {
+		elapsed := time.Since(start)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		s := r.seriesLocked(key)
+		s.InFlight--
+		s.Requests++
+		if err != nil {
+			s.Errors[errorKind(err)]++
+		}
+		s.Latency.Counts[sort.Search(len(s.Latency.Bounds), func(i int) bool { return s.Latency.Bounds[i] >= elapsed })]++
+		s.Latency.Count++
+		s.Latency.Sum += elapsed
+	}
+}
+
+// Collect returns a copy of the series of the registry, sorted by endpoint, database and operation.
+func (r *Registry) Collect() []Series {
+	r.mu.Lock()
+	result := make([]Series, 0, len(r.series))
+	for _, s := range r.series {
+		c := *s
+		c.Errors = make(map[string]uint64, len(s.Errors))
+		for k, v := range s.Errors {
+			c.Errors[k] = v
+		}
+		c.Latency.Counts = append([]uint64(nil), s.Latency.Counts...)
+		result = append(result, c)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Key, result[j].Key
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		return a.Operation < b.Operation
+	})
+	return result
+}
+
+func (r *Registry) seriesLocked(key Key) *Series {
+	s, ok := r.series[key]
+	if !ok {
+		s = &Series{
+			Key:     key,
+			Errors:  map[string]uint64{},
+			Latency: Histogram{Bounds: r.bounds, Counts: make([]uint64, len(r.bounds)+1)},
+		}
+		r.series[key] = s
+	}
+	return s
+}
+
+// PublishExpvar publishes the series of c as the expvar variable name, served as JSON at /debug/vars.
+// Like expvar.Publish, it panics if the name is already published.
+func PublishExpvar(name string, c Collector) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return c.Collect() }))
+}
+
+// errorKind returns the kind of err, or KOther for errors that aren't *errors.Error.
+func errorKind(err error) string {
+	var kustoErr *kustoErrors.Error
+	if errors.As(err, &kustoErr) {
+		return kustoErr.Kind.String()
+	}
+	return kustoErrors.KOther.String()
+}
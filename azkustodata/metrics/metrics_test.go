@@ -0,0 +1,115 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry(metrics.ExponentialBuckets(time.Hour, 10, 3))
+	query := metrics.Key{Endpoint: "https://a", Database: "db", Operation: metrics.OperationQuery}
+	mgmt := metrics.Key{Endpoint: "https://a", Database: "db", Operation: metrics.OperationMgmt}
+
+	done := r.Start(query)
+	inFlight := r.Start(query)
+	series := r.Collect()
+	require.Len(t, series, 1)
+	assert.Equal(t, int64(2), series[0].InFlight)
+
+	done(nil)
+	r.Start(mgmt)(errors.ES(errors.OpMgmt, errors.KHTTPError, "failed"))
+	r.Start(mgmt)(fmt.Errorf("plain"))
+	inFlight(errors.ES(errors.OpQuery, errors.KTimeout, "timeout"))
+
+	series = r.Collect()
+	require.Len(t, series, 2)
+	assert.Equal(t, mgmt, series[0].Key)
+	assert.Equal(t, uint64(2), series[0].Requests)
+	assert.Equal(t, map[string]uint64{"KHTTPError": 1, "KOther": 1}, series[0].Errors)
+
+	assert.Equal(t, query, series[1].Key)
+	assert.Zero(t, series[1].InFlight)
+	assert.Equal(t, uint64(2), series[1].Requests)
+	assert.Equal(t, map[string]uint64{"KTimeout": 1}, series[1].Errors)
+	assert.Equal(t, []time.Duration{time.Hour, 10 * time.Hour, 100 * time.Hour}, series[1].Latency.Bounds)
+	assert.Equal(t, []uint64{2, 0, 0, 0}, series[1].Latency.Counts)
+	assert.Equal(t, uint64(2), series[1].Latency.Count)
+
+	// Collect returns copies.
+	series[1].Latency.Counts[0] = 100
+	assert.Equal(t, uint64(2), r.Collect()[1].Latency.Counts[0])
+
+	assert.Panics(t, func() { metrics.ExponentialBuckets(time.Second, 1, 3) })
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	h := metrics.Histogram{
+		Bounds: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		Counts: []uint64{5, 5, 0, 0},
+		Count:  10,
+	}
+	assert.Equal(t, time.Second, h.Quantile(0.5))
+	assert.Equal(t, 1500*time.Millisecond, h.Quantile(0.75))
+	assert.Equal(t, 2*time.Second, h.Quantile(1))
+
+	h.Counts = []uint64{0, 0, 0, 10}
+	assert.Equal(t, 4*time.Second, h.Quantile(0.5))
+	assert.Zero(t, metrics.Histogram{}.Quantile(0.5))
+}
+
+func TestPrometheus(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry(metrics.ExponentialBuckets(time.Hour, 2, 2))
+	key := metrics.Key{Endpoint: "https://a", Database: `d"b`, Operation: metrics.OperationQuery}
+	r.Start(key)(nil)
+	r.Start(key)(errors.ES(errors.OpQuery, errors.KHTTPError, "failed"))
+	r.Start(key)
+
+	rec := httptest.NewRecorder()
+	metrics.PrometheusHandler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	labels := `endpoint="https://a",database="d\"b",operation="query"`
+	for _, line := range []string{
+		"# TYPE kusto_request_duration_seconds histogram",
+		`kusto_request_duration_seconds_bucket{` + labels + `,le="3600"} 2`,
+		`kusto_request_duration_seconds_bucket{` + labels + `,le="7200"} 2`,
+		`kusto_request_duration_seconds_bucket{` + labels + `,le="+Inf"} 2`,
+		`kusto_request_duration_seconds_count{` + labels + `} 2`,
+		`kusto_requests_total{` + labels + `} 2`,
+		`kusto_request_errors_total{` + labels + `,kind="KHTTPError"} 1`,
+		`kusto_requests_in_flight{` + labels + `} 1`,
+	} {
+		assert.Contains(t, strings.Split(body, "\n"), line)
+	}
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	r := metrics.NewRegistry()
+	r.Start(metrics.Key{Endpoint: "https://a", Operation: metrics.OperationMgmt})(nil)
+	metrics.PublishExpvar("kusto_metrics_test", r)
+
+	var series []metrics.Series
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("kusto_metrics_test").String()), &series))
+	require.Len(t, series, 1)
+	assert.Equal(t, uint64(1), series[0].Requests)
+	assert.Equal(t, "https://a", series[0].Endpoint)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Names of the metrics written by WritePrometheus.
+const (
+	PrometheusLatency  = "kusto_request_duration_seconds"
+	PrometheusRequests = "kusto_requests_total"
+	PrometheusErrors   = "kusto_request_errors_total"
+	PrometheusInFlight = "kusto_requests_in_flight"
+)
+
+// PrometheusHandler returns a handler that serves the series of c in the Prometheus text format, to be scraped by
+// Prometheus without depending on its client library.
+func PrometheusHandler(c Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w, c)
+	})
+}
+
+// WritePrometheus writes the series of c to w in the Prometheus text format. The series are labeled with their
+// endpoint, database and operation, and the errors with their kind too.
+func WritePrometheus(w io.Writer, c Collector) error {
+	series := c.Collect()
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# HELP %s Latency of the Kusto queries and management commands, until their response is read.\n", PrometheusLatency)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", PrometheusLatency)
+	for _, s := range series {
+		labels := keyLabels(s.Key)
+		var cumulative uint64
+		for i, bound := range s.Latency.Bounds {
+			cumulative += s.Latency.Counts[i]
+			fmt.Fprintf(b, "%s_bucket{%s,le=%q} %d\n", PrometheusLatency, labels, formatFloat(bound.Seconds()), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", PrometheusLatency, labels, s.Latency.Count)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", PrometheusLatency, labels, formatFloat(s.Latency.Sum.Seconds()))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", PrometheusLatency, labels, s.Latency.Count)
+	}
+
+	fmt.Fprintf(b, "# HELP %s Number of completed Kusto queries and management commands.\n", PrometheusRequests)
+	fmt.Fprintf(b, "# TYPE %s counter\n", PrometheusRequests)
+	for _, s := range series {
+		fmt.Fprintf(b, "%s{%s} %d\n", PrometheusRequests, keyLabels(s.Key), s.Requests)
+	}
+
+	fmt.Fprintf(b, "# HELP %s Number of failed Kusto queries and management commands, by error kind.\n", PrometheusErrors)
+	fmt.Fprintf(b, "# TYPE %s counter\n", PrometheusErrors)
+	for _, s := range series {
+		kinds := make([]string, 0, len(s.Errors))
+		for kind := range s.Errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(b, "%s{%s,kind=%s} %d\n", PrometheusErrors, keyLabels(s.Key), quoteLabel(kind), s.Errors[kind])
+		}
+	}
+
+	fmt.Fprintf(b, "# HELP %s Number of Kusto queries and management commands whose response isn't fully read.\n", PrometheusInFlight)
+	fmt.Fprintf(b, "# TYPE %s gauge\n", PrometheusInFlight)
+	for _, s := range series {
+		fmt.Fprintf(b, "%s{%s} %d\n", PrometheusInFlight, keyLabels(s.Key), s.InFlight)
+	}
+
+	return b.Flush()
+}
+
+func keyLabels(k Key) string {
+	return "endpoint=" + quoteLabel(k.Endpoint) + ",database=" + quoteLabel(k.Database) + ",operation=" + quoteLabel(k.Operation)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value, escaping it as the Prometheus text format requires.
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
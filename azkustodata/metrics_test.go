@@ -0,0 +1,57 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingConn fails the requests whose text contains "fail", and returns an empty management response otherwise.
+type failingConn struct{}
+
+func (failingConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	if strings.Contains(query.String(), "fail") {
+		return nil, errors.ES(errors.OpMgmt, errors.KHTTPError, "failed")
+	}
+	return io.NopCloser(strings.NewReader(emptyMgmtResponse)), nil
+}
+
+func (failingConn) Close() error {
+	return nil
+}
+
+func TestWithMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	client := &Client{conn: failingConn{}, endpoint: "https://cluster"}
+	WithMetrics(registry)(client)
+
+	_, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	require.NoError(t, err)
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show fail"))
+	require.Error(t, err)
+	_, err = client.QueryV1(context.Background(), "other", kql.New("T"))
+	require.NoError(t, err)
+
+	series := registry.Collect()
+	require.Len(t, series, 2)
+
+	assert.Equal(t, metrics.Key{Endpoint: "https://cluster", Database: "db", Operation: metrics.OperationMgmt}, series[0].Key)
+	assert.Equal(t, uint64(2), series[0].Requests)
+	assert.Equal(t, map[string]uint64{"KHTTPError": 1}, series[0].Errors)
+	assert.Zero(t, series[0].InFlight)
+	assert.Equal(t, uint64(2), series[0].Latency.Count)
+
+	assert.Equal(t, metrics.Key{Endpoint: "https://cluster", Database: "other", Operation: metrics.OperationQuery}, series[1].Key)
+	assert.Equal(t, uint64(1), series[1].Requests)
+	assert.Empty(t, series[1].Errors)
+	assert.Zero(t, series[1].InFlight)
+}
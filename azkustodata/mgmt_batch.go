@@ -0,0 +1,111 @@
+package azkustodata
+
+// mgmt_batch.go holds MgmtBatch, which runs a sequence of management commands as a unit.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+)
+
+// MgmtResult is the outcome of one command of MgmtBatch.
+type MgmtResult struct {
+	// Index is the index of the command in the batch.
+	Index int
+	// Statement is the command.
+	Statement Statement
+	// Dataset is the response of the command, if it succeeded.
+	Dataset v1.Dataset
+	// Err is the error of the command, if it failed.
+	Err error
+	// Skipped is true if the command wasn't run, because a previous command failed with stopOnError set, or because
+	// the context was done.
+	Skipped bool
+}
+
+// Succeeded reports whether the command was run and succeeded.
+func (r MgmtResult) Succeeded() bool {
+	return !r.Skipped && r.Err == nil
+}
+
+// MgmtBatchError is the error of MgmtBatch when some of its commands failed or were skipped.
+type MgmtBatchError struct {
+	// Failed are the results of the commands that failed, in order.
+	Failed []MgmtResult
+	// Skipped is the number of commands that weren't run.
+	Skipped int
+	// Ctx is the error of the context, if commands were skipped because it was done.
+	Ctx error
+}
+
+func (e *MgmtBatchError) Error() string {
+	parts := make([]string, 0, len(e.Failed)+1)
+	for _, r := range e.Failed {
+		parts = append(parts, fmt.Sprintf("command %d (%s) failed: %s", r.Index, r.Statement.String(), r.Err))
+	}
+	if e.Skipped > 0 {
+		skipped := fmt.Sprintf("%d commands were skipped", e.Skipped)
+		if e.Ctx != nil {
+			skipped += ": " + e.Ctx.Error()
+		}
+		parts = append(parts, skipped)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap returns the errors of the failed commands, and the error of the context, if any.
+func (e *MgmtBatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	for _, r := range e.Failed {
+		errs = append(errs, r.Err)
+	}
+	if e.Ctx != nil {
+		errs = append(errs, e.Ctx)
+	}
+	return errs
+}
+
+// MgmtBatch runs a sequence of management commands in the database db, one after the other, with the same options.
+// This is useful for provisioning flows that run as a unit, such as dropping and creating a table, creating its
+// ingestion mapping and clearing the schema cache.
+//
+// The result of every command is returned, in the order of the commands. If stopOnError is set, the commands after a
+// failed command are skipped. The commands are not transactional: the commands that succeeded before a failure are not
+// rolled back. The commands after the context is done are skipped.
+//
+// The error is a *MgmtBatchError if any command failed or was skipped, and matches the errors of the failed commands
+// and of the context with errors.Is and errors.As.
+func (c *Client) MgmtBatch(ctx context.Context, db string, stmts []Statement, stopOnError bool, options ...QueryOption) ([]MgmtResult, error) {
+	if len(stmts) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "no commands to run").SetNoRetry()
+	}
+
+	results := make([]MgmtResult, len(stmts))
+	batchErr := &MgmtBatchError{}
+	stopped := false
+	for i, stmt := range stmts {
+		results[i] = MgmtResult{Index: i, Statement: stmt}
+		if !stopped && ctx.Err() != nil {
+			batchErr.Ctx = ctx.Err()
+		}
+		if stopped || batchErr.Ctx != nil {
+			results[i].Skipped = true
+			batchErr.Skipped++
+			continue
+		}
+
+		results[i].Dataset, results[i].Err = c.Mgmt(ctx, db, stmt, options...)
+		if results[i].Err != nil {
+			batchErr.Failed = append(batchErr.Failed, results[i])
+			stopped = stopOnError
+		}
+	}
+
+	if len(batchErr.Failed) > 0 || batchErr.Skipped > 0 {
+		return results, batchErr
+	}
+	return results, nil
+}
@@ -0,0 +1,130 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMgmtBatch(t *testing.T) {
+	t.Parallel()
+
+	errCreate := errors.ES(errors.OpMgmt, errors.KHTTPError, "table already exists")
+	stmts := []Statement{
+		kql.New(".drop table T ifexists"),
+		kql.New(".create table T (A: string)"),
+		kql.New(".create table T ingestion json mapping 'm' '[]'"),
+		kql.New(".clear database cache query_results"),
+	}
+
+	tests := []struct {
+		desc        string
+		stopOnError bool
+		fail        func(command string, attempt int) error
+		wantRan     []string
+		wantSkipped []bool
+		wantFailed  []int
+	}{
+		{
+			desc:        "All succeed",
+			wantRan:     []string{stmts[0].String(), stmts[1].String(), stmts[2].String(), stmts[3].String()},
+			wantSkipped: []bool{false, false, false, false},
+		},
+		{
+			desc:        "Stop on error",
+			stopOnError: true,
+			fail: func(command string, _ int) error {
+				if command == stmts[1].String() {
+					return errCreate
+				}
+				return nil
+			},
+			wantRan:     []string{stmts[0].String()},
+			wantSkipped: []bool{false, false, true, true},
+			wantFailed:  []int{1},
+		},
+		{
+			desc: "Go on after errors",
+			fail: func(command string, _ int) error {
+				if command == stmts[1].String() || command == stmts[3].String() {
+					return errCreate
+				}
+				return nil
+			},
+			wantRan:     []string{stmts[0].String(), stmts[2].String()},
+			wantSkipped: []bool{false, false, false, false},
+			wantFailed:  []int{1, 3},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &journalConn{fail: test.fail}
+			client := &Client{conn: conn}
+
+			results, err := client.MgmtBatch(context.Background(), "db", stmts, test.stopOnError)
+			require.Len(t, results, len(stmts))
+			assert.Equal(t, test.wantRan, conn.ran())
+
+			var failed []int
+			for i, r := range results {
+				assert.Equal(t, i, r.Index)
+				assert.Equal(t, stmts[i], r.Statement)
+				assert.Equal(t, test.wantSkipped[i], r.Skipped, i)
+				if r.Err != nil {
+					failed = append(failed, i)
+					assert.Nil(t, r.Dataset)
+				}
+				if r.Succeeded() {
+					assert.NotNil(t, r.Dataset)
+				}
+			}
+			assert.Equal(t, test.wantFailed, failed)
+
+			if len(test.wantFailed) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var batchErr *MgmtBatchError
+			require.ErrorAs(t, err, &batchErr)
+			assert.Len(t, batchErr.Failed, len(test.wantFailed))
+			assert.True(t, goErrors.Is(err, errCreate))
+			assert.ErrorContains(t, err, "command 1 (.create table T (A: string)) failed: ")
+		})
+	}
+}
+
+func TestMgmtBatchContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &journalConn{fail: func(string, int) error {
+		cancel()
+		return nil
+	}}
+	client := &Client{conn: conn}
+
+	results, err := client.MgmtBatch(ctx, "db", []Statement{kql.New(".show tables"), kql.New(".show functions")}, false)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Succeeded())
+	assert.True(t, results[1].Skipped)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "1 commands were skipped")
+}
+
+func TestMgmtBatchEmpty(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &journalConn{}}
+	_, err := client.MgmtBatch(context.Background(), "db", nil, true)
+	assert.Error(t, err)
+	assert.False(t, errors.Retry(err))
+}
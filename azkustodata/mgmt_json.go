@@ -0,0 +1,103 @@
+package azkustodata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	goErrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// mgmtJSONSnippet is the length of the part of a payload that is quoted in the errors of MgmtJSON.
+const mgmtJSONSnippet = 200
+
+// MgmtJSON runs the management command stmt in db, and unmarshals the JSON payload in the column of the first row of
+// its primary result into a T. Most commands that return a single object, such as `.show cluster policy caching` or
+// `.show table T policy retention`, return it this way:
+//
+//	type retention struct {
+//		SoftDeletePeriod string
+//		Recoverability   string
+//	}
+//	policy, err := azkustodata.MgmtJSON[retention](ctx, client, "db", kql.New(".show table T policy retention"), "Policy")
+//
+// The column can be of type string or dynamic. It returns nil if the payload is null or empty, such as the policy of
+// an entity that has none. The errors name the command and the column, and quote the payload that couldn't be parsed.
+func MgmtJSON[T any](ctx context.Context, client *Client, db string, stmt Statement, column string, options ...QueryOption) (*T, error) {
+	if column == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "MgmtJSON requires a column").SetNoRetry()
+	}
+
+	dataset, err := client.Mgmt(ctx, db, stmt, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := dataset.Tables()
+	if len(tables) == 0 || len(tables[0].Rows()) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "command %q returned no rows", commandName(stmt))
+	}
+	table := tables[0]
+	if table.ColumnByName(column) == nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "command %q has no column %q, its columns are %s",
+			commandName(stmt), column, strings.Join(query.ColumnNames(table.Columns()), ", ")).SetNoRetry()
+	}
+
+	cell, err := table.Rows()[0].ValueByName(column)
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	switch v := cell.(type) {
+	case *value.String:
+		payload = []byte(v.Value)
+	case *value.Dynamic:
+		payload = v.Value
+	default:
+		return nil, errors.ES(errors.OpMgmt, errors.KWrongColumnType, "column %q of command %q is of type %s, expected a string or a dynamic",
+			column, commandName(stmt), cell.GetType()).SetNoRetry()
+	}
+
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("null")) {
+		return nil, nil
+	}
+
+	result := new(T)
+	if err := json.Unmarshal(payload, result); err != nil {
+		return nil, errors.E(errors.OpMgmt, errors.KFailedToParse, fmt.Errorf("could not parse column %q of command %q into %T: %s: %w",
+			column, commandName(stmt), *result, describeJSONError(payload, err), err))
+	}
+	return result, nil
+}
+
+// commandName returns the first line of the text of stmt, to name it in errors.
+func commandName(stmt Statement) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(stmt.String()), "\n")
+	return name
+}
+
+// describeJSONError returns where err happened in payload, with the payload, or its beginning if it is long.
+func describeJSONError(payload []byte, err error) string {
+	var where string
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case goErrors.As(err, &syntaxErr):
+		where = fmt.Sprintf("at offset %d", syntaxErr.Offset)
+	case goErrors.As(err, &typeErr) && typeErr.Field != "":
+		where = fmt.Sprintf("at field %s", typeErr.Field)
+	default:
+		where = "in"
+	}
+
+	if len(payload) > mgmtJSONSnippet {
+		return fmt.Sprintf("%s %s...", where, payload[:mgmtJSONSnippet])
+	}
+	return fmt.Sprintf("%s %s", where, payload)
+}
@@ -0,0 +1,111 @@
+package azkustodata
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type retentionPolicy struct {
+	SoftDeletePeriod string
+	Recoverability   string
+}
+
+// policyResponse returns a v1 response with a Policy column of type columnType holding policy, which is a JSON
+// literal.
+func policyResponse(columnType, policy string) string {
+	return `{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"PolicyName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"Policy","DataType":"Object","ColumnType":"` + columnType + `"}],` +
+		`"Rows":[["RetentionPolicy",` + policy + `]]}]}`
+}
+
+func TestMgmtJSON(t *testing.T) {
+	t.Parallel()
+
+	want := &retentionPolicy{SoftDeletePeriod: "365.00:00:00", Recoverability: "Enabled"}
+	tests := []struct {
+		desc     string
+		response string
+		column   string
+		want     *retentionPolicy
+		wantErr  string
+	}{
+		{
+			desc:     "string column",
+			response: policyResponse("string", `"{\"SoftDeletePeriod\":\"365.00:00:00\",\"Recoverability\":\"Enabled\"}"`),
+			column:   "Policy",
+			want:     want,
+		},
+		{
+			desc:     "dynamic column",
+			response: policyResponse("dynamic", `{"SoftDeletePeriod":"365.00:00:00","Recoverability":"Enabled"}`),
+			column:   "Policy",
+			want:     want,
+		},
+		{
+			desc:     "null policy",
+			response: policyResponse("string", `"null"`),
+			column:   "Policy",
+		},
+		{
+			desc:     "null dynamic",
+			response: policyResponse("dynamic", `null`),
+			column:   "Policy",
+		},
+		{
+			desc:     "missing column",
+			response: policyResponse("string", `"{}"`),
+			column:   "Policies",
+			wantErr:  `command ".show table T policy retention" has no column "Policies", its columns are PolicyName, Policy`,
+		},
+		{
+			desc:     "no rows",
+			response: emptyMgmtResponse,
+			column:   "Policy",
+			wantErr:  "returned no rows",
+		},
+		{
+			desc:     "syntax error",
+			response: policyResponse("string", `"{\"SoftDeletePeriod\":"`),
+			column:   "Policy",
+			wantErr:  `could not parse column "Policy" of command ".show table T policy retention" into azkustodata.retentionPolicy: at offset 20 {"SoftDeletePeriod":`,
+		},
+		{
+			desc:     "type error",
+			response: policyResponse("string", `"{\"SoftDeletePeriod\":1}"`),
+			column:   "Policy",
+			wantErr:  `at field SoftDeletePeriod {"SoftDeletePeriod":1}`,
+		},
+		{
+			desc:     "long payload",
+			response: policyResponse("string", `"{\"SoftDeletePeriod\":1,\"Recoverability\":\"`+strings.Repeat("a", 300)+`\"}"`),
+			column:   "Policy",
+			wantErr:  strings.Repeat("a", 150) + "...",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := &Client{conn: &fakeMgmtConn{responses: []string{test.response}}}
+			got, err := MgmtJSON[retentionPolicy](context.Background(), client, "db", kql.New(".show table T policy retention"), test.column)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+
+	_, err := MgmtJSON[retentionPolicy](context.Background(), &Client{conn: &fakeMgmtConn{}}, "db", kql.New(".show cluster policy caching"), "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package azkustodata
+
+// mgmt_once.go holds MgmtOnce, which guards non-idempotent management commands against running twice.
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+)
+
+// The states of a command in the `.show commands` output.
+const (
+	CommandInProgress = "InProgress"
+	CommandCompleted  = "Completed"
+	CommandFailed     = "Failed"
+)
+
+// CommandRun is a run of a management command, as listed by `.show commands`.
+type CommandRun struct {
+	// ClientActivityId is the client request id of the command.
+	ClientActivityId string
+	// State is the state of the command: CommandInProgress, CommandCompleted or CommandFailed.
+	State         string
+	StartedOn     time.Time
+	LastUpdatedOn time.Time
+	// FailureReason is the error of the command, if it failed.
+	FailureReason string
+}
+
+// LastCommandRun returns the last run of the management command sent with the given client request id, or nil if
+// `.show commands` doesn't list one.
+// `.show commands` only lists the commands of the last 30 days, and only the commands of the caller unless they are a
+// database monitor or admin.
+func (c *Client) LastCommandRun(ctx context.Context, db, clientRequestID string) (*CommandRun, error) {
+	if clientRequestID == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "a client request id is required").SetNoRetry()
+	}
+
+	cmd := kql.New(".show commands | where ClientActivityId == ").AddString(clientRequestID).
+		AddLiteral(" | top 1 by StartedOn desc | project ClientActivityId, State, StartedOn, LastUpdatedOn, FailureReason")
+	dataset, err := c.Mgmt(ctx, db, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := query.ToStructs[CommandRun](dataset)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// MgmtOnce runs a management command that must not run twice, such as `.append` or `.set-or-append`, so that it can be
+// retried safely. The idempotency key is sent as the client request id of the command, and is looked up with
+// LastCommandRun before the command is sent:
+//   - If a command with the key completed, the command isn't sent again, and ran is false.
+//   - If a command with the key is still in progress, an error is returned, and MgmtOnce can be called again later.
+//   - Otherwise, including when a command with the key failed, the command is sent, and ran is true.
+//
+// The key must be unique to the command and stable across retries, for instance derived from the id of the job that
+// runs it. A ClientRequestID in options is replaced by the key.
+// The check doesn't prevent concurrent callers with the same key from running the command twice, and it only sees the
+// commands that `.show commands` lists, see LastCommandRun.
+func (c *Client) MgmtOnce(ctx context.Context, db, key string, stmt Statement, options ...QueryOption) (dataset v1.Dataset, ran bool, err error) {
+	if key == "" {
+		return nil, false, errors.ES(errors.OpMgmt, errors.KClientArgs, "MgmtOnce requires an idempotency key").SetNoRetry()
+	}
+
+	last, err := c.LastCommandRun(ctx, db, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if last != nil {
+		switch last.State {
+		case CommandCompleted:
+			return nil, false, nil
+		case CommandInProgress:
+			return nil, false, errors.ES(errors.OpMgmt, errors.KOther, "a command with the idempotency key %q is in progress since %s",
+				key, last.StartedOn)
+		}
+	}
+
+	options = append(options[:len(options):len(options)], ClientRequestID(key))
+	dataset, err = c.Mgmt(ctx, db, stmt, options...)
+	return dataset, true, err
+}
@@ -0,0 +1,114 @@
+package azkustodata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandsConn is a fake conn that lists a run of a command with the given state in `.show commands`, and records the
+// client request ids of the other commands.
+type commandsConn struct {
+	mu         sync.Mutex
+	state      string
+	commands   []string
+	requestIDs []string
+}
+
+func (c *commandsConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, query.String())
+
+	if !strings.HasPrefix(query.String(), ".show commands") {
+		c.requestIDs = append(c.requestIDs, options.requestProperties.ClientRequestID)
+		return io.NopCloser(strings.NewReader(emptyMgmtResponse)), nil
+	}
+
+	rows := ""
+	if c.state != "" {
+		rows = fmt.Sprintf(`["key","%s","2024-01-02T03:04:05Z","2024-01-02T03:04:06Z",""]`, c.state)
+	}
+	return io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"ClientActivityId","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"State","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"StartedOn","DataType":"DateTime","ColumnType":"datetime"},` +
+		`{"ColumnName":"LastUpdatedOn","DataType":"DateTime","ColumnType":"datetime"},` +
+		`{"ColumnName":"FailureReason","DataType":"String","ColumnType":"string"}],` +
+		`"Rows":[` + rows + `]}]}`)), nil
+}
+
+func (c *commandsConn) Close() error {
+	return nil
+}
+
+func TestMgmtOnce(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		state   string
+		wantRan bool
+		wantErr bool
+	}{
+		{desc: "never ran", wantRan: true},
+		{desc: "failed", state: CommandFailed, wantRan: true},
+		{desc: "completed", state: CommandCompleted},
+		{desc: "in progress", state: CommandInProgress, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &commandsConn{state: test.state}
+			client := &Client{conn: conn}
+
+			_, ran, err := client.MgmtOnce(context.Background(), "db", "key", kql.New(".append T <| print 1"), ClientRequestID("other"))
+			assert.Equal(t, test.wantRan, ran)
+			if test.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "in progress")
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, ".show commands | where ClientActivityId == \"key\" | top 1 by StartedOn desc"+
+				" | project ClientActivityId, State, StartedOn, LastUpdatedOn, FailureReason", conn.commands[0])
+			if test.wantRan {
+				assert.Equal(t, []string{"key"}, conn.requestIDs)
+			} else {
+				assert.Empty(t, conn.requestIDs)
+			}
+		})
+	}
+}
+
+func TestLastCommandRun(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &commandsConn{}}
+	run, err := client.LastCommandRun(context.Background(), "db", "key")
+	require.NoError(t, err)
+	assert.Nil(t, run)
+
+	client = &Client{conn: &commandsConn{state: CommandFailed}}
+	run, err = client.LastCommandRun(context.Background(), "db", "key")
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, "key", run.ClientActivityId)
+	assert.Equal(t, CommandFailed, run.State)
+
+	_, err = client.LastCommandRun(context.Background(), "db", "")
+	assert.Error(t, err)
+	_, _, err = client.MgmtOnce(context.Background(), "db", "", kql.New(".append T <| print 1"))
+	assert.Error(t, err)
+}
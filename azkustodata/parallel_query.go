@@ -0,0 +1,238 @@
+package azkustodata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// Names of the query parameters that hold the time range of a shard of ParallelByTimeRange.
+const (
+	TimeRangeFromParameter = "_from"
+	TimeRangeToParameter   = "_to"
+)
+
+// ShardMerge is how ParallelByTimeRange merges the rows of its shards.
+type ShardMerge int
+
+const (
+	// MergeUnordered delivers the rows of all the shards as they arrive, interleaved.
+	MergeUnordered ShardMerge = iota
+	// MergeByShard delivers all the rows of a shard before those of the next one, in the order of the time ranges, so
+	// that the rows are sorted by time when every shard is sorted by time ascending. The rows of the later shards are
+	// buffered in memory until they are delivered.
+	MergeByShard
+)
+
+// ParallelByTimeRange speeds up a scan of a large time window by splitting [from, to) into shards ranges of equal
+// length, running stmt once per range concurrently, and merging the rows of their primary results into a single
+// channel, as set by merge.
+//
+// stmt receives the range of its shard as the datetime query parameters _from (inclusive) and _to (exclusive), which
+// are declared by the client, and which it must filter on so that every row is returned by exactly one shard:
+//
+//	stmt := kql.New("Events | where Timestamp >= _from and Timestamp < _to")
+//
+// The channel is closed once all the rows were delivered. The first error of a shard is delivered in place of a row,
+// cancels the other shards, and closes the channel. The channel must be read until it is closed, or ctx canceled.
+// Other query parameters can be passed with the QueryParameters option. It can't be combined with ReuseRows.
+func (c *Client) ParallelByTimeRange(ctx context.Context, db string, stmt Statement, from, to time.Time, shards int, merge ShardMerge,
+	options ...QueryOption) (<-chan query.RowResult, error) {
+	if shards <= 0 {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "ParallelByTimeRange requires a positive number of shards, got %d", shards).SetNoRetry()
+	}
+	if !to.After(from) {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "ParallelByTimeRange requires a time range that ends after it starts, got [%s, %s)",
+			from, to).SetNoRetry()
+	}
+	if to.Sub(from) < time.Duration(shards) {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "ParallelByTimeRange can't split [%s, %s) into %d shards", from, to, shards).SetNoRetry()
+	}
+	if merge != MergeUnordered && merge != MergeByShard {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "ParallelByTimeRange merge %d is not valid", merge).SetNoRetry()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &parallelQuery{ctx: ctx, cancel: cancel, out: make(chan query.RowResult, shards)}
+	if merge == MergeByShard {
+		p.queues = make([]*shardQueue, shards)
+		for i := range p.queues {
+			p.queues[i] = &shardQueue{notify: make(chan struct{}, 1)}
+		}
+	}
+
+	step := to.Sub(from) / time.Duration(shards)
+	p.wg.Add(shards)
+	for i := 0; i < shards; i++ {
+		start, end := from.Add(time.Duration(i)*step), from.Add(time.Duration(i+1)*step)
+		if i == shards-1 {
+			end = to
+		}
+		shardOptions := append(append([]QueryOption{}, options...), timeRange(start, end), noReuseRows())
+		go p.runShard(i, func() (query.IterativeDataset, error) {
+			return c.IterativeQuery(ctx, db, stmt, shardOptions...)
+		})
+	}
+
+	go func() {
+		if merge == MergeByShard {
+			p.forwardByShard()
+		}
+		p.wg.Wait()
+		cancel()
+		close(p.out)
+	}()
+	return p.out, nil
+}
+
+// parallelQuery merges the rows of the shards of ParallelByTimeRange.
+type parallelQuery struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    chan query.RowResult
+	wg     sync.WaitGroup
+	// queues hold the rows of every shard with MergeByShard, and are nil with MergeUnordered.
+	queues []*shardQueue
+
+	failOnce sync.Once
+}
+
+// runShard runs a shard, and delivers its rows.
+func (p *parallelQuery) runShard(shard int, run func() (query.IterativeDataset, error)) {
+	defer p.wg.Done()
+	if p.queues != nil {
+		defer p.queues[shard].close()
+	}
+
+	dataset, err := run()
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	defer dataset.Close()
+
+	for tr := range dataset.Tables() {
+		if tr.Err() != nil {
+			p.fail(tr.Err())
+			return
+		}
+		table := tr.Table()
+		primary := table.IsPrimaryResult()
+		for rr := range table.Rows() {
+			if rr.Err() != nil {
+				p.fail(rr.Err())
+				return
+			}
+			if !primary {
+				continue
+			}
+			if p.queues != nil {
+				p.queues[shard].push(rr)
+			} else if !p.send(rr) {
+				return
+			}
+		}
+	}
+}
+
+// forwardByShard delivers the rows of the queues, one shard after the other.
+func (p *parallelQuery) forwardByShard() {
+	for _, q := range p.queues {
+		for {
+			rows, done := q.take()
+			for _, rr := range rows {
+				if !p.send(rr) {
+					return
+				}
+			}
+			if done {
+				break
+			}
+			select {
+			case <-q.notify:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// send delivers a row, and reports whether the query is still running.
+func (p *parallelQuery) send(rr query.RowResult) bool {
+	select {
+	case p.out <- rr:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// fail delivers the first error of the shards, and cancels the others.
+func (p *parallelQuery) fail(err error) {
+	p.failOnce.Do(func() {
+		p.send(query.RowResultError(err))
+		p.cancel()
+	})
+}
+
+// shardQueue buffers the rows of a shard until they are delivered.
+type shardQueue struct {
+	mu     sync.Mutex
+	rows   []query.RowResult
+	done   bool
+	notify chan struct{}
+}
+
+func (q *shardQueue) push(rr query.RowResult) {
+	q.mu.Lock()
+	q.rows = append(q.rows, rr)
+	q.mu.Unlock()
+	q.wake()
+}
+
+func (q *shardQueue) close() {
+	q.mu.Lock()
+	q.done = true
+	q.mu.Unlock()
+	q.wake()
+}
+
+// take returns the buffered rows, and whether the shard is done.
+func (q *shardQueue) take() ([]query.RowResult, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	rows := q.rows
+	q.rows = nil
+	return rows, q.done
+}
+
+func (q *shardQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// timeRange adds the time range of a shard to the query parameters set by the other options.
+func timeRange(from, to time.Time) QueryOption {
+	return func(q *queryOptions) error {
+		params := kql.NewParameters().Merge(&q.requestProperties.QueryParameters).
+			Merge(kql.NewParameters().AddDateTime(TimeRangeFromParameter, from).AddDateTime(TimeRangeToParameter, to))
+		q.requestProperties.QueryParameters = *params
+		q.requestProperties.Parameters = params.ToParameterCollection()
+		return nil
+	}
+}
+
+func noReuseRows() QueryOption {
+	return func(q *queryOptions) error {
+		if q.reuseRows {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "ParallelByTimeRange can't be combined with ReuseRows()").SetNoRetry()
+		}
+		return nil
+	}
+}
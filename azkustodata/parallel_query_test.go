@@ -0,0 +1,173 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shardConn answers the shards of ParallelByTimeRange, identified by their _from parameter, with the keys
+// shard*10+1..shard*10+3. The first shard waits for release, and the shard fail fails.
+type shardConn struct {
+	starts  map[string]int
+	release chan struct{}
+	fail    int
+
+	mu     sync.Mutex
+	params []map[string]string
+}
+
+func newShardConn(from time.Time, step time.Duration, shards int) *shardConn {
+	c := &shardConn{starts: map[string]int{}, release: make(chan struct{}), fail: -1}
+	for i := 0; i < shards; i++ {
+		start := kql.NewParameters().AddDateTime(TimeRangeFromParameter, from.Add(time.Duration(i)*step)).ToParameterCollection()
+		c.starts[start[TimeRangeFromParameter]] = i
+	}
+	return c
+}
+
+func (c *shardConn) rawQuery(ctx context.Context, _ callType, _ string, _ Statement, options *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	c.params = append(c.params, options.requestProperties.Parameters)
+	c.mu.Unlock()
+
+	shard := c.starts[options.requestProperties.Parameters[TimeRangeFromParameter]]
+	if shard == 0 {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if shard == c.fail {
+		return resumeResponse(shard*10+1, shard*10+3, io.ErrUnexpectedEOF), nil
+	}
+	return resumeResponse(shard*10+1, shard*10+3, nil), nil
+}
+
+func (c *shardConn) Close() error {
+	return nil
+}
+
+// shardKeys reads the keys of the rows, releasing the first shard once the other shards were delivered when release.
+func shardKeys(t *testing.T, rows <-chan query.RowResult, conn *shardConn, release bool) ([]int64, error) {
+	var keys []int64
+	var err error
+	for rr := range rows {
+		if rr.Err() != nil {
+			err = rr.Err()
+			continue
+		}
+		key, kerr := rr.Row().LongByName("Key")
+		require.NoError(t, kerr)
+		keys = append(keys, *key)
+		if release && len(keys) == 9 {
+			close(conn.release)
+		}
+	}
+	return keys, err
+}
+
+func TestParallelByTimeRange(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(4 * time.Hour)
+
+	t.Run("Unordered", func(t *testing.T) {
+		t.Parallel()
+		conn := newShardConn(from, time.Hour, 4)
+		client := &Client{conn: conn}
+
+		rows, err := client.ParallelByTimeRange(context.Background(), "db", kql.New("T | where Timestamp >= _from and Timestamp < _to"), from, to, 4,
+			MergeUnordered, QueryParameters(kql.NewParameters().AddString("name", "x")))
+		require.NoError(t, err)
+
+		// The first shard is released once the other shards were delivered, so they can't wait for it.
+		keys, err := shardKeys(t, rows, conn, true)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, keys[9:])
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		assert.Equal(t, []int64{1, 2, 3, 11, 12, 13, 21, 22, 23, 31, 32, 33}, keys)
+
+		require.Len(t, conn.params, 4)
+		ends := map[string]bool{}
+		for _, p := range conn.params {
+			assert.Equal(t, `"x"`, p["name"])
+			ends[p[TimeRangeToParameter]] = true
+		}
+		assert.True(t, ends[kql.NewParameters().AddDateTime(TimeRangeToParameter, to).ToParameterCollection()[TimeRangeToParameter]])
+	})
+
+	t.Run("ByShard", func(t *testing.T) {
+		t.Parallel()
+		conn := newShardConn(from, time.Hour, 4)
+		client := &Client{conn: conn}
+
+		rows, err := client.ParallelByTimeRange(context.Background(), "db", kql.New("T"), from, to, 4, MergeByShard)
+		require.NoError(t, err)
+
+		// The later shards are buffered while the first one waits.
+		require.Eventually(t, func() bool {
+			conn.mu.Lock()
+			defer conn.mu.Unlock()
+			return len(conn.params) == 4
+		}, 5*time.Second, time.Millisecond)
+		close(conn.release)
+
+		keys, err := shardKeys(t, rows, conn, false)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3, 11, 12, 13, 21, 22, 23, 31, 32, 33}, keys)
+	})
+
+	t.Run("Failed shard", func(t *testing.T) {
+		t.Parallel()
+		conn := newShardConn(from, time.Hour, 4)
+		conn.fail = 2
+		client := &Client{conn: conn}
+
+		rows, err := client.ParallelByTimeRange(context.Background(), "db", kql.New("T"), from, to, 4, MergeByShard)
+		require.NoError(t, err)
+
+		_, err = shardKeys(t, rows, conn, false)
+		assert.True(t, goErrors.Is(err, io.ErrUnexpectedEOF), "got %v", err)
+	})
+
+	t.Run("ReuseRows", func(t *testing.T) {
+		t.Parallel()
+		conn := newShardConn(from, time.Hour, 4)
+		close(conn.release)
+		client := &Client{conn: conn}
+
+		rows, err := client.ParallelByTimeRange(context.Background(), "db", kql.New("T"), from, to, 4, MergeUnordered, ReuseRows())
+		require.NoError(t, err)
+		_, err = shardKeys(t, rows, conn, false)
+		assert.ErrorContains(t, err, "can't be combined with ReuseRows()")
+	})
+}
+
+func TestParallelByTimeRangeArgs(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &shardConn{}}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	_, err := client.ParallelByTimeRange(ctx, "db", kql.New("T"), from, from.Add(time.Hour), 0, MergeUnordered)
+	assert.Error(t, err)
+	_, err = client.ParallelByTimeRange(ctx, "db", kql.New("T"), from, from, 2, MergeUnordered)
+	assert.Error(t, err)
+	_, err = client.ParallelByTimeRange(ctx, "db", kql.New("T"), from, from.Add(time.Nanosecond), 2, MergeUnordered)
+	assert.Error(t, err)
+	_, err = client.ParallelByTimeRange(ctx, "db", kql.New("T"), from, from.Add(time.Hour), 2, ShardMerge(5))
+	assert.Error(t, err)
+}
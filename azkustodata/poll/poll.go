@@ -0,0 +1,208 @@
+/*
+Package poll polls the state of long-running work, such as asynchronous operations and queued ingestions, with an
+exponential backoff between the checks. It honors the delays the service asks for when it throttles a check, and
+doesn't wait past the deadline of the context.
+
+It is used by the waits of the Kusto clients, and can be used to poll the state of work started with management
+commands:
+
+	p := poll.New(poll.Interval(2*time.Second), poll.MaxInterval(time.Minute), poll.MaxErrors(3))
+	err := p.Poll(ctx, func(ctx context.Context) (bool, error) {
+		dataset, err := client.Mgmt(ctx, db, kql.New(".show operations ").AddUnsafe(id))
+		if err != nil {
+			return false, err
+		}
+		return isDone(dataset), nil
+	})
+*/
+package poll
+
+import (
+	"context"
+	goErrors "errors"
+	"math/rand"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+const (
+	// DefaultInterval is the delay before the second check.
+	DefaultInterval = time.Second
+	// DefaultMaxInterval is the maximum delay between two checks, unless the service asks for a longer one.
+	DefaultMaxInterval = 30 * time.Second
+	// DefaultMultiplier is the factor the delay is multiplied by after every check.
+	DefaultMultiplier = 2.0
+)
+
+// CheckFunc checks the state of the polled work, and reports whether it is done.
+type CheckFunc func(ctx context.Context) (done bool, err error)
+
+// Poller calls a CheckFunc until the work is done. A Poller can be used by several goroutines at once.
+type Poller struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	multiplier  float64
+	jitter      time.Duration
+	maxErrors   int
+	waitFirst   bool
+}
+
+// Option is an option of New.
+type Option func(p *Poller)
+
+// Interval sets the delay before the second check, which is then multiplied after every check. Defaults to
+// DefaultInterval.
+func Interval(d time.Duration) Option {
+	return func(p *Poller) {
+		p.interval = d
+	}
+}
+
+// MaxInterval caps the delay between two checks. Defaults to DefaultMaxInterval. A delay the service asks for is
+// honored even if it is longer.
+func MaxInterval(d time.Duration) Option {
+	return func(p *Poller) {
+		p.maxInterval = d
+	}
+}
+
+// Multiplier sets the factor the delay is multiplied by after every check. A multiplier of 1 polls at a fixed
+// interval. Defaults to DefaultMultiplier.
+func Multiplier(m float64) Option {
+	return func(p *Poller) {
+		p.multiplier = m
+	}
+}
+
+// Jitter adds a random delay of up to d to every delay, so that many concurrent pollers don't check at the same
+// time. Defaults to no jitter.
+func Jitter(d time.Duration) Option {
+	return func(p *Poller) {
+		p.jitter = d
+	}
+}
+
+// MaxErrors sets the number of failed checks in a row that are retried before Poll fails, or -1 to retry failed
+// checks until the context is done. Defaults to 0 - the first failed check fails Poll.
+// Checks the service throttled with a retry hint, see RetryAfter, are always retried after the delay it asked for,
+// and are not counted.
+func MaxErrors(n int) Option {
+	return func(p *Poller) {
+		p.maxErrors = n
+	}
+}
+
+// WaitFirst waits for the interval before the first check, instead of checking right away.
+func WaitFirst() Option {
+	return func(p *Poller) {
+		p.waitFirst = true
+	}
+}
+
+// New creates a Poller.
+func New(options ...Option) *Poller {
+	p := &Poller{
+		interval:    DefaultInterval,
+		maxInterval: DefaultMaxInterval,
+		multiplier:  DefaultMultiplier,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	if p.interval <= 0 {
+		p.interval = DefaultInterval
+	}
+	if p.maxInterval < p.interval {
+		p.maxInterval = p.interval
+	}
+	if p.multiplier < 1 {
+		p.multiplier = 1
+	}
+	return p
+}
+
+// Poll calls check until it reports that the work is done, or it fails more than MaxErrors times in a row, or ctx is
+// done. It returns nil once the work is done, the error of the last check if the checks failed, and the error of ctx
+// otherwise. If the deadline of ctx is before the next check, Poll returns context.DeadlineExceeded right away.
+func (p *Poller) Poll(ctx context.Context, check CheckFunc) error {
+	delay := p.interval
+	errs := 0
+
+	if p.waitFirst {
+		if err := p.wait(ctx, p.withJitter(delay)); err != nil {
+			return err
+		}
+		delay = p.next(delay)
+	}
+
+	for {
+		done, err := check(ctx)
+		if err == nil && done {
+			return nil
+		}
+
+		wait := p.withJitter(delay)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if hint := RetryAfter(err); hint > 0 {
+				if hint > wait {
+					wait = hint
+				}
+			} else if errs++; p.maxErrors >= 0 && errs > p.maxErrors {
+				return err
+			}
+		} else {
+			errs = 0
+		}
+
+		if err := p.wait(ctx, wait); err != nil {
+			return err
+		}
+		delay = p.next(delay)
+	}
+}
+
+// next returns the delay after delay.
+func (p *Poller) next(delay time.Duration) time.Duration {
+	next := time.Duration(float64(delay) * p.multiplier)
+	if next > p.maxInterval || next <= 0 {
+		return p.maxInterval
+	}
+	return next
+}
+
+func (p *Poller) withJitter(delay time.Duration) time.Duration {
+	if p.jitter <= 0 {
+		return delay
+	}
+	return delay + time.Duration(rand.Int63n(int64(p.jitter)))
+}
+
+// wait waits for d, or until ctx is done. It returns right away if the deadline of ctx is before d elapses.
+func (p *Poller) wait(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryAfter returns the delay the service asked to wait before retrying the request that failed with err, from the
+// Retry-After header of a throttled response, or 0 if it didn't ask for one.
+func RetryAfter(err error) time.Duration {
+	var httpErr *errors.HttpError
+	if goErrors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
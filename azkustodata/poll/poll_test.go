@@ -0,0 +1,127 @@
+package poll
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollBackoff(t *testing.T) {
+	t.Parallel()
+
+	p := New(Interval(time.Millisecond), MaxInterval(4*time.Millisecond), Multiplier(2))
+	delays := []time.Duration{}
+	delay := p.interval
+	for i := 0; i < 4; i++ {
+		delays = append(delays, delay)
+		delay = p.next(delay)
+	}
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}, delays)
+
+	checks := 0
+	err := p.Poll(context.Background(), func(context.Context) (bool, error) {
+		checks++
+		return checks == 3, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, checks)
+}
+
+func TestPollErrors(t *testing.T) {
+	t.Parallel()
+
+	failure := fmt.Errorf("failure")
+	tests := []struct {
+		desc       string
+		maxErrors  int
+		failures   int
+		wantChecks int
+		wantErr    error
+	}{
+		{desc: "no retries", failures: 2, wantChecks: 1, wantErr: failure},
+		{desc: "retried", maxErrors: 2, failures: 2, wantChecks: 3},
+		{desc: "too many", maxErrors: 1, failures: 2, wantChecks: 2, wantErr: failure},
+		{desc: "unlimited", maxErrors: -1, failures: 5, wantChecks: 6},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			checks := 0
+			p := New(Interval(time.Millisecond), MaxErrors(test.maxErrors))
+			err := p.Poll(context.Background(), func(context.Context) (bool, error) {
+				checks++
+				if checks <= test.failures {
+					return false, failure
+				}
+				return true, nil
+			})
+			assert.Equal(t, test.wantErr, err)
+			assert.Equal(t, test.wantChecks, checks)
+		})
+	}
+}
+
+func TestPollRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	throttled := &errors.HttpError{KustoError: *errors.ES(errors.OpQuery, errors.KHTTPError, "throttled"), StatusCode: 429,
+		RetryAfter: 20 * time.Millisecond}
+	assert.Equal(t, 20*time.Millisecond, RetryAfter(throttled))
+	assert.Equal(t, 20*time.Millisecond, RetryAfter(fmt.Errorf("wrapped: %w", throttled)))
+	assert.Zero(t, RetryAfter(fmt.Errorf("failure")))
+
+	// Throttled checks are retried after the hint, even without MaxErrors.
+	checks := 0
+	start := time.Now()
+	err := New(Interval(time.Millisecond)).Poll(context.Background(), func(context.Context) (bool, error) {
+		checks++
+		if checks == 1 {
+			return false, throttled
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, checks)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestPollContext(t *testing.T) {
+	t.Parallel()
+
+	// The deadline is before the next check, so Poll returns right away.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	checks := 0
+	start := time.Now()
+	err := New(Interval(2*time.Hour)).Poll(ctx, func(context.Context) (bool, error) {
+		checks++
+		return false, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, checks)
+	assert.Less(t, time.Since(start), time.Minute)
+
+	// With WaitFirst, there is no check at all.
+	checks = 0
+	err = New(Interval(2*time.Hour), WaitFirst()).Poll(ctx, func(context.Context) (bool, error) {
+		checks++
+		return true, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, checks)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	err = New(Interval(time.Millisecond)).Poll(ctx, func(context.Context) (bool, error) {
+		cancel()
+		return false, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
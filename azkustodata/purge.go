@@ -0,0 +1,240 @@
+package azkustodata
+
+// purge.go holds the two-step `.purge table records` workflow, which permanently deletes the records of a table.
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/poll"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/google/uuid"
+)
+
+// verificationToken is the pattern of the verification tokens of purges, which are base64 strings.
+var verificationToken = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// PurgePlan is the first step of a purge, returned by PlanPurge: the estimate of the records that the predicate
+// matches, and the token that confirms it to Execute. Nothing is deleted until Execute is called.
+type PurgePlan struct {
+	Database  string
+	Table     string
+	Predicate string
+	// NumRecordsToPurge is the estimate of the number of records that match the predicate.
+	NumRecordsToPurge int64
+	// EstimatedPurgeExecutionTime is the estimate of how long the purge will take.
+	EstimatedPurgeExecutionTime time.Duration
+	// VerificationToken is the token that Execute passes to the second step of the purge.
+	VerificationToken string
+
+	client *Client
+}
+
+// PurgeStatus is a row of `.show purges`, the status of a purge operation.
+type PurgeStatus struct {
+	OperationId       uuid.UUID
+	DatabaseName      string
+	TableName         string
+	ScheduledTime     time.Time
+	Duration          time.Duration
+	LastUpdatedOn     time.Time
+	EngineOperationId string
+	State             string
+	StateDetails      string
+	EngineStartTime   time.Time
+	EngineDuration    time.Duration
+	Retries           int
+	ClientRequestId   string
+	Principal         string
+}
+
+// Done returns true if the purge is no longer running.
+func (s PurgeStatus) Done() bool {
+	return s.State != "Scheduled" && s.State != "InProgress"
+}
+
+// Succeeded returns true if the purge completed successfully.
+func (s PurgeStatus) Succeeded() bool {
+	return s.State == "Completed"
+}
+
+// PurgeOperation is a handle to a purge running on the service, returned by PurgePlan.Execute.
+type PurgeOperation struct {
+	// ID is the id of the purge operation, as returned by the service.
+	ID uuid.UUID
+	// Plan is the plan that was executed.
+	Plan *PurgePlan
+}
+
+// PlanPurge runs the first step of a purge of the records of table that match predicate, which returns the number of
+// records to purge without deleting them. predicate must be a single `where` clause, such as
+// `where CustomerId in ('X', 'Y') and Timestamp < datetime(2024-01-01)`: it is checked before it is sent, and the
+// service refuses predicates that are too complex.
+// Purges run on the data management endpoint of the cluster, so c must be a client of the "ingest-" endpoint, and the
+// principal of the client must be a database admin. Purges are irreversible: review the plan, then call Execute.
+func (c *Client) PlanPurge(ctx context.Context, db, table string, predicate Statement, options ...QueryOption) (*PurgePlan, error) {
+	if db == "" || table == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "PlanPurge requires a database and a table").SetNoRetry()
+	}
+	if predicate == nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "PlanPurge requires a predicate").SetNoRetry()
+	}
+	pred := strings.TrimSpace(predicate.String())
+	if err := validatePurgePredicate(pred); err != nil {
+		return nil, err
+	}
+
+	dataset, err := c.Mgmt(ctx, db, purgeCommand(db, table, pred, ""), options...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := query.ToStructs[struct {
+		NumRecordsToPurge           int64
+		EstimatedPurgeExecutionTime time.Duration
+		VerificationToken           string
+	}](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single row for the purge of %s, got %d", table, len(rows))
+	}
+	if !verificationToken.MatchString(rows[0].VerificationToken) {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "the purge of %s returned an invalid verification token", table)
+	}
+
+	return &PurgePlan{
+		Database:                    db,
+		Table:                       table,
+		Predicate:                   pred,
+		NumRecordsToPurge:           rows[0].NumRecordsToPurge,
+		EstimatedPurgeExecutionTime: rows[0].EstimatedPurgeExecutionTime,
+		VerificationToken:           rows[0].VerificationToken,
+		client:                      c,
+	}, nil
+}
+
+// Execute runs the second step of the purge, which deletes the records. confirmedRecords is the number of records to
+// purge that the caller reviewed, and must be the NumRecordsToPurge of the plan, as an explicit confirmation.
+// The purge runs asynchronously: the returned PurgeOperation tracks it.
+func (p *PurgePlan) Execute(ctx context.Context, confirmedRecords int64, options ...QueryOption) (*PurgeOperation, error) {
+	if confirmedRecords != p.NumRecordsToPurge {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "the purge of %s was confirmed for %d records, but the plan purges %d",
+			p.Table, confirmedRecords, p.NumRecordsToPurge).SetNoRetry()
+	}
+
+	dataset, err := p.client.Mgmt(ctx, p.Database, purgeCommand(p.Database, p.Table, p.Predicate, p.VerificationToken), options...)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := query.ToStructs[PurgeStatus](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single operation id for the purge of %s, got %d", p.Table, len(statuses))
+	}
+	return &PurgeOperation{ID: statuses[0].OperationId, Plan: p}, nil
+}
+
+// Status fetches the current status of the purge, with `.show purges`.
+func (o *PurgeOperation) Status(ctx context.Context) (*PurgeStatus, error) {
+	dataset, err := o.Plan.client.Mgmt(ctx, o.Plan.Database, kql.New(".show purges ").AddUnsafe(o.ID.String()))
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := query.ToStructs[PurgeStatus](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "purge operation %s was not found", o.ID)
+	}
+	return &statuses[len(statuses)-1], nil
+}
+
+// Wait polls the status of the purge until it is done, or ctx is done, like Operation.Wait. Purges can take hours.
+// It returns the final status, and an error if the purge did not succeed.
+func (o *PurgeOperation) Wait(ctx context.Context) (*PurgeStatus, error) {
+	var status *PurgeStatus
+	p := poll.New(poll.Interval(operationPollInterval), poll.MaxInterval(operationMaxPollInterval))
+	err := p.Poll(ctx, func(ctx context.Context) (bool, error) {
+		s, err := o.Status(ctx)
+		if err != nil {
+			return false, err
+		}
+		status = s
+		return status.Done(), nil
+	})
+	if err != nil {
+		return status, err
+	}
+
+	if !status.Succeeded() {
+		return status, errors.ES(errors.OpMgmt, errors.KOther, "purge operation %s ended in state %s: %s", o.ID, status.State, status.StateDetails)
+	}
+	return status, nil
+}
+
+// Verify counts the records of the table that still match the predicate of the purge, which is 0 once it completed.
+// engine must be a client of the engine endpoint of the cluster, as the data management endpoint doesn't run queries.
+func (o *PurgeOperation) Verify(ctx context.Context, engine *Client, options ...QueryOption) (int64, error) {
+	stmt := kql.New("").AddTable(o.Plan.Table).AddLiteral(" | ").AddUnsafe(o.Plan.Predicate).AddLiteral(" | count")
+	dataset, err := engine.Query(ctx, o.Plan.Database, stmt, options...)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := query.ToStructs[struct{ Count int64 }](dataset)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) != 1 {
+		return 0, errors.ES(errors.OpQuery, errors.KInternal, "expected a single count for the purge of %s, got %d", o.Plan.Table, len(rows))
+	}
+	return rows[0].Count, nil
+}
+
+// purgeCommand returns the `.purge table records` command of the first step of a purge, or of the second one if token
+// isn't empty.
+func purgeCommand(db, table, predicate, token string) *kql.Builder {
+	cmd := kql.New(".purge table ").AddUnsafe(kql.NormalizeName(table)).AddLiteral(" records in database ").AddUnsafe(kql.NormalizeName(db))
+	if token != "" {
+		cmd.AddLiteral(" with (verificationtoken=h'").AddUnsafe(token).AddLiteral("')")
+	}
+	return cmd.AddLiteral(" <| ").AddUnsafe(predicate)
+}
+
+// validatePurgePredicate checks that predicate is a single `where` clause: it starts with `where`, and has no pipe or
+// semicolon outside of its string literals, so that it can't run other operators or commands.
+func validatePurgePredicate(predicate string) error {
+	fields := strings.Fields(predicate)
+	if len(fields) < 2 || fields[0] != "where" {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "purge predicate must be a where clause, got %q", predicate).SetNoRetry()
+	}
+
+	var quote rune
+	escaped := false
+	for _, r := range predicate {
+		switch {
+		case quote != 0 && escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '|' || r == ';':
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "purge predicate must be a single where clause, got %q", predicate).SetNoRetry()
+		}
+	}
+	if quote != 0 {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "purge predicate has an unterminated string literal: %q", predicate).SetNoRetry()
+	}
+	return nil
+}
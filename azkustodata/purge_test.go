@@ -0,0 +1,130 @@
+package azkustodata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const purgePlanResponse = `{"Tables":[{"TableName":"Table_0","Columns":[` +
+	`{"ColumnName":"NumRecordsToPurge","DataType":"Int64","ColumnType":"long"},` +
+	`{"ColumnName":"EstimatedPurgeExecutionTime","DataType":"TimeSpan","ColumnType":"timespan"},` +
+	`{"ColumnName":"VerificationToken","DataType":"String","ColumnType":"string"}],` +
+	`"Rows":[[1596,"00:00:02","e43c7184ed22f4f23c7a9d7b124d196be2e570096987e5baadf65057fa65736b"]]}]}`
+
+func purgeStatusResponse(state string) string {
+	return `{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"OperationId","DataType":"Guid","ColumnType":"guid"},` +
+		`{"ColumnName":"DatabaseName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"TableName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"ScheduledTime","DataType":"DateTime","ColumnType":"datetime"},` +
+		`{"ColumnName":"State","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"StateDetails","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"Retries","DataType":"Int32","ColumnType":"int"}],` +
+		`"Rows":[["c9651d74-3b80-4183-90bb-bbe9e42eadc4","Users","Events","2024-01-02T03:04:05Z","` + state + `","details",0]]}]}`
+}
+
+const purgeCountResponse = resumeHeader +
+	`,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"Count","ColumnType":"long"}]}` + "\n" +
+	`,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[0]]}` + "\n" +
+	`,{"FrameType":"TableCompletion","TableId":1,"RowCount":1}` + "\n" +
+	`,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}` + "\n" +
+	`]`
+
+func TestPurge(t *testing.T) {
+	// Not parallel, as it changes operationPollInterval.
+	prev := operationPollInterval
+	operationPollInterval = time.Millisecond
+	defer func() { operationPollInterval = prev }()
+
+	conn := &fakeMgmtConn{responses: []string{
+		purgePlanResponse,
+		purgeStatusResponse("Scheduled"),
+		purgeStatusResponse("InProgress"),
+		purgeStatusResponse("Completed"),
+		purgeCountResponse,
+	}}
+	client := &Client{conn: conn}
+
+	plan, err := client.PlanPurge(context.Background(), "Users", "Events", kql.New("where UserId == 'X'"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1596), plan.NumRecordsToPurge)
+	assert.Equal(t, 2*time.Second, plan.EstimatedPurgeExecutionTime)
+
+	// The purge must be confirmed with the number of records of the plan.
+	_, err = plan.Execute(context.Background(), 1000)
+	assert.ErrorContains(t, err, "confirmed for 1000 records")
+	assert.Len(t, conn.commands, 1)
+
+	op, err := plan.Execute(context.Background(), plan.NumRecordsToPurge)
+	require.NoError(t, err)
+	assert.Equal(t, "c9651d74-3b80-4183-90bb-bbe9e42eadc4", op.ID.String())
+
+	status, err := op.Wait(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Succeeded())
+
+	count, err := op.Verify(context.Background(), client)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	assert.Equal(t, []string{
+		".purge table Events records in database Users <| where UserId == 'X'",
+		".purge table Events records in database Users with (verificationtoken=h'e43c7184ed22f4f23c7a9d7b124d196be2e570096987e5baadf65057fa65736b') <| where UserId == 'X'",
+		".show purges c9651d74-3b80-4183-90bb-bbe9e42eadc4",
+		".show purges c9651d74-3b80-4183-90bb-bbe9e42eadc4",
+		"Events | where UserId == 'X' | count",
+	}, conn.commands)
+}
+
+func TestPurgeFailed(t *testing.T) {
+	// Not parallel, as it changes operationPollInterval.
+	prev := operationPollInterval
+	operationPollInterval = time.Millisecond
+	defer func() { operationPollInterval = prev }()
+
+	conn := &fakeMgmtConn{responses: []string{purgeStatusResponse("Failed")}}
+	op := &PurgeOperation{Plan: &PurgePlan{Database: "Users", Table: "Events", client: &Client{conn: conn}}}
+
+	status, err := op.Wait(context.Background())
+	assert.ErrorContains(t, err, "ended in state Failed: details")
+	assert.False(t, status.Succeeded())
+}
+
+func TestValidatePurgePredicate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		predicate string
+		wantErr   bool
+	}{
+		{predicate: "where UserId == 'X'"},
+		{predicate: "where UserId in ('a|b', \"c;d\") and Timestamp < datetime(2024-01-01)"},
+		{predicate: `where Name == 'it\'s | fine'`},
+		{predicate: "", wantErr: true},
+		{predicate: "where", wantErr: true},
+		{predicate: "UserId == 'X'", wantErr: true},
+		{predicate: "where UserId == 'X' | where Age > 3", wantErr: true},
+		{predicate: "where UserId == 'X'; .drop table Events", wantErr: true},
+		{predicate: "where UserId == 'X", wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := validatePurgePredicate(test.predicate)
+		if test.wantErr {
+			assert.Error(t, err, test.predicate)
+			continue
+		}
+		assert.NoError(t, err, test.predicate)
+	}
+
+	client := &Client{conn: &fakeMgmtConn{}}
+	_, err := client.PlanPurge(context.Background(), "Users", "", kql.New("where UserId == 'X'"))
+	assert.Error(t, err)
+	_, err = client.PlanPurge(context.Background(), "Users", "Events", nil)
+	assert.Error(t, err)
+}
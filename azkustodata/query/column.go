@@ -8,8 +8,30 @@ type Column interface {
 	Index() int
 	// Name returns the column's name.
 	Name() string
-	// Type returns the column's kusto data type.
+	// Type returns the column's kusto data type, which is its CSL type, such as "long".
 	Type() types.Column
 }
 
+// ColumnMetadata is implemented by the columns of this package that can have more metadata than their CSL type, such
+// as the columns of v1 responses and of table schemas. Other implementations of Column don't need to: type-assert
+// columns to it.
+type ColumnMetadata interface {
+	// DataType returns the .NET type of the column, such as "Int64", if the response has it, as v1 responses do.
+	// It is empty otherwise.
+	DataType() string
+	// DocString returns the documentation of the column, which is only known for the columns of a table schema, see
+	// azkustodata.Client.ShowTableSchema. It is empty otherwise.
+	DocString() string
+}
+
+// ColumnNames returns the names of columns, in order, such as the names of the columns of a table in the order of the
+// response.
+func ColumnNames(columns []Column) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name()
+	}
+	return names
+}
+
 type Columns []Column
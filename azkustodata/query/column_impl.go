@@ -9,6 +9,8 @@ type column struct {
 	index     int
 	name      string
 	kustoType types.Column
+	dataType  string
+	docString string
 }
 
 func (c column) Index() int {
@@ -23,10 +25,25 @@ func (c column) Type() types.Column {
 	return c.kustoType
 }
 
+func (c column) DataType() string {
+	return c.dataType
+}
+
+func (c column) DocString() string {
+	return c.docString
+}
+
 func NewColumn(ordinal int, name string, kustoType types.Column) Column {
+	return NewColumnWithMetadata(ordinal, name, kustoType, "", "")
+}
+
+// NewColumnWithMetadata creates a column with its .NET type and its documentation, see ColumnMetadata.
+func NewColumnWithMetadata(ordinal int, name string, kustoType types.Column, dataType string, docString string) Column {
 	return &column{
 		index:     ordinal,
 		name:      name,
 		kustoType: kustoType,
+		dataType:  dataType,
+		docString: docString,
 	}
 }
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"reflect"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// ColumnValues returns all the values of the column name of a table, converted to T in a single pass over the rows.
+// T follows the conversion rules of ToStructs fields: it can be the value type of the column (such as int64 for a long
+// column), a pointer to it to tell nulls apart, or a type of the value package. Nulls are converted to the zero value
+// of non-pointer types.
+//
+//	timestamps, err := query.ColumnValues[time.Time](table, "Timestamp")
+//	counts, err := query.ColumnValues[*int64](table, "Count")
+//
+// This is much faster than ToStructs for analytics on a few columns of wide tables.
+func ColumnValues[T any](t Table, name string) ([]T, error) {
+	col := t.ColumnByName(name)
+	if col == nil {
+		return nil, columnNotFoundError(name)
+	}
+	return columnValues[T](t.Rows(), col)
+}
+
+// ColumnValuesByIndex is like ColumnValues, for the column at index i.
+func ColumnValuesByIndex[T any](t Table, i int) ([]T, error) {
+	columns := t.Columns()
+	if i < 0 || i >= len(columns) {
+		return nil, errors.ES(errors.OpTableAccess, errors.KOther, "column index %d is out of range, the table has %d columns", i, len(columns))
+	}
+	return columnValues[T](t.Rows(), columns[i])
+}
+
+func columnValues[T any](rows []Row, col Column) ([]T, error) {
+	out := make([]T, len(rows))
+	for i, r := range rows {
+		val, err := r.Value(col.Index())
+		if err != nil {
+			return nil, err
+		}
+
+		// Avoid reflection for the types the values hold: pointers to value types, strings and dynamic bytes.
+		switch v := val.GetValue().(type) {
+		case T:
+			out[i] = v
+			continue
+		case *T:
+			if v != nil {
+				out[i] = *v
+			}
+			continue
+		}
+
+		if err := val.Convert(reflect.ValueOf(&out[i]).Elem()); err != nil {
+			return nil, errors.ES(errors.OpTableAccess, errors.KWrongColumnType, "column %s of type %s cannot be converted to %T: %s",
+				col.Name(), col.Type(), out[i], err)
+		}
+	}
+	return out, nil
+}
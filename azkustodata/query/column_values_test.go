@@ -0,0 +1,66 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnValues(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	cols := Columns{
+		NewColumn(0, "Name", types.String),
+		NewColumn(1, "Count", types.Long),
+		NewColumn(2, "Timestamp", types.DateTime),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	tb := NewTable(base, []Row{
+		NewRow(base, 0, value.Values{value.NewString("a"), value.NewLong(1), value.NewDateTime(now)}),
+		NewRow(base, 1, value.Values{value.NewString("b"), value.NewNullLong(), value.NewDateTime(now.Add(time.Hour))}),
+	})
+
+	names, err := ColumnValues[string](tb, "Name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	counts, err := ColumnValues[int64](tb, "Count")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, counts)
+
+	one := int64(1)
+	pointers, err := ColumnValues[*int64](tb, "Count")
+	require.NoError(t, err)
+	assert.Equal(t, []*int64{&one, nil}, pointers)
+
+	// Conversions that need reflection.
+	ints, err := ColumnValues[int](tb, "Count")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, ints)
+
+	kustoValues, err := ColumnValues[value.Long](tb, "Count")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *kustoValues[0].Ptr())
+	assert.Nil(t, kustoValues[1].Ptr())
+
+	timestamps, err := ColumnValuesByIndex[time.Time](tb, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{now, now.Add(time.Hour)}, timestamps)
+
+	_, err = ColumnValues[string](tb, "Missing")
+	assert.Error(t, err)
+
+	_, err = ColumnValuesByIndex[string](tb, 3)
+	assert.Error(t, err)
+
+	_, err = ColumnValues[time.Time](tb, "Name")
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KWrongColumnType, e.Kind)
+}
@@ -15,11 +15,71 @@ type BaseDataset interface {
 	PrimaryResultKind() string
 }
 
+// TransferStats are statistics of the transfer of the response of a query from the service.
+type TransferStats struct {
+	// Encoding is the Content-Encoding of the response, such as "gzip", or empty if it was not compressed.
+	Encoding string
+	// CompressedBytes is the number of bytes of the response read from the network.
+	CompressedBytes int64
+	// DecompressedBytes is the number of bytes of the response after decompression. It is equal to CompressedBytes if
+	// the response was not compressed.
+	DecompressedBytes int64
+}
+
+// TransferStatsReporter is implemented by response bodies that track the statistics of their transfer, and by the
+// datasets of this package. Other implementations of BaseDataset don't need to: use DatasetTransferStats to read them.
+type TransferStatsReporter interface {
+	// TransferStats returns the statistics of the transfer of the response from the service. They are final once the
+	// response was fully read.
+	TransferStats() TransferStats
+}
+
+// DatasetTransferStats returns the statistics of the transfer of the response of d, or empty statistics if d doesn't
+// implement TransferStatsReporter. They are final once the dataset was fully read.
+func DatasetTransferStats(d BaseDataset) TransferStats {
+	if r, ok := d.(TransferStatsReporter); ok {
+		return r.TransferStats()
+	}
+	return TransferStats{}
+}
+
 type Dataset interface {
 	BaseDataset
+	// Tables returns the tables of the dataset. The primary results are always in the order of the query, but the
+	// position of the other tables, and whether they are returned at all, depends on the protocol: prefer TableByID and
+	// PrimaryByOrdinal to relying on positions.
 	Tables() []Table
 }
 
+// TableByID returns the table of tables whose Index is id, or nil if there is none, such as the table with a TableId of
+// Dataset.Tables.
+func TableByID(tables []Table, id int64) Table {
+	for _, t := range tables {
+		if t.Index() == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// PrimaryByOrdinal returns the primary result of tables at position n, starting at 0, or nil if there is none, such as
+// the nth primary result of Dataset.Tables.
+func PrimaryByOrdinal(tables []Table, n int) Table {
+	if n < 0 {
+		return nil
+	}
+	for _, t := range tables {
+		if !t.IsPrimaryResult() {
+			continue
+		}
+		if n == 0 {
+			return t
+		}
+		n--
+	}
+	return nil
+}
+
 // IterativeDataset represents an iterative result from kusto - where the tables are streamed as they are received from the service.
 type IterativeDataset interface {
 	BaseDataset
@@ -2,6 +2,8 @@ package query
 
 import (
 	"context"
+	"io"
+
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 )
 
@@ -10,6 +12,7 @@ type baseDataset struct {
 	ctx                context.Context
 	op                 errors.Op
 	primaryResultsKind string
+	stats              TransferStatsReporter
 }
 
 func (d *baseDataset) Context() context.Context {
@@ -24,6 +27,13 @@ func (d *baseDataset) PrimaryResultKind() string {
 	return d.primaryResultsKind
 }
 
+func (d *baseDataset) TransferStats() TransferStats {
+	if d.stats == nil {
+		return TransferStats{}
+	}
+	return d.stats.TransferStats()
+}
+
 func NewBaseDataset(ctx context.Context, op errors.Op, primaryResultsKind string) BaseDataset {
 	return &baseDataset{
 		ctx:                ctx,
@@ -32,11 +42,27 @@ func NewBaseDataset(ctx context.Context, op errors.Op, primaryResultsKind string
 	}
 }
 
+// NewBaseDatasetFromReader creates a BaseDataset for a dataset decoded from r. If r is a TransferStatsReporter, its
+// statistics are returned by TransferStats.
+func NewBaseDatasetFromReader(ctx context.Context, op errors.Op, primaryResultsKind string, r io.Reader) BaseDataset {
+	stats, _ := r.(TransferStatsReporter)
+	return &baseDataset{
+		ctx:                ctx,
+		op:                 op,
+		primaryResultsKind: primaryResultsKind,
+		stats:              stats,
+	}
+}
+
 type dataset struct {
 	BaseDataset
 	tables []Table
 }
 
+func (d *dataset) TransferStats() TransferStats {
+	return DatasetTransferStats(d.BaseDataset)
+}
+
 func NewDataset(base BaseDataset, tables []Table) Dataset {
 	return &dataset{
 		BaseDataset: base,
@@ -47,3 +73,8 @@ func NewDataset(base BaseDataset, tables []Table) Dataset {
 func (d *dataset) Tables() []Table {
 	return d.tables
 }
+
+// MarshalJSON implements json.Marshaler, see MarshalDataset.
+func (d *dataset) MarshalJSON() ([]byte, error) {
+	return MarshalDataset(d)
+}
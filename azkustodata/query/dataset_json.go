@@ -0,0 +1,203 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// DatasetJSONVersion is the version of the JSON structure written by MarshalDataset.
+const DatasetJSONVersion = 1
+
+// datasetJSON is the JSON structure of a dataset, documented on MarshalDataset.
+type datasetJSON struct {
+	Version           int         `json:"version"`
+	PrimaryResultKind string      `json:"primaryResultKind"`
+	Tables            []tableJSON `json:"tables"`
+}
+
+type tableJSON struct {
+	ID      string              `json:"id"`
+	Index   int64               `json:"index"`
+	Ordinal int                 `json:"ordinal"`
+	Name    string              `json:"name"`
+	Kind    string              `json:"kind"`
+	Columns []columnJSON        `json:"columns"`
+	Rows    [][]json.RawMessage `json:"rows"`
+}
+
+type columnJSON struct {
+	Name      string       `json:"name"`
+	Type      types.Column `json:"type"`
+	DataType  string       `json:"dataType,omitempty"`
+	DocString string       `json:"docString,omitempty"`
+}
+
+// MarshalDataset returns the JSON of the tables of d, in their order, for caches and API responses. It is distinct
+// from the frames of the service, and stable across the versions of the package:
+//
+//	{
+//	  "version": 1,
+//	  "primaryResultKind": "PrimaryResult",
+//	  "tables": [{
+//	    "id": "1", "index": 1, "ordinal": 0, "name": "PrimaryResult", "kind": "PrimaryResult",
+//	    "columns": [{"name": "Id", "type": "long"}, {"name": "At", "type": "datetime"}],
+//	    "rows": [[1, "2024-01-01T00:00:00Z"], [null, null]]
+//	  }]
+//	}
+//
+// Nulls are null, bools, ints, longs and reals are JSON values, dynamics are their JSON as is, and the other types are
+// strings: decimals without loss of precision, datetimes in RFC3339 with nanoseconds, timespans in the
+// [-][d.]hh:mm:ss[.fffffff] format of the service, GUIDs in their canonical form, and the reals that have no JSON
+// equivalent as "NaN", "+Inf" and "-Inf". Columns have a dataType and a docString if they are known.
+// The transfer statistics and the metadata of v1 datasets, such as their status, are not included.
+func MarshalDataset(d Dataset) ([]byte, error) {
+	ds := datasetJSON{Version: DatasetJSONVersion, PrimaryResultKind: d.PrimaryResultKind(), Tables: make([]tableJSON, 0, len(d.Tables()))}
+	for _, t := range d.Tables() {
+		tj := tableJSON{
+			ID:      t.Id(),
+			Index:   t.Index(),
+			Ordinal: TableOrdinal(t),
+			Name:    t.Name(),
+			Kind:    t.Kind(),
+			Columns: make([]columnJSON, 0, len(t.Columns())),
+			Rows:    make([][]json.RawMessage, 0, len(t.Rows())),
+		}
+		for _, c := range t.Columns() {
+			cj := columnJSON{Name: c.Name(), Type: c.Type()}
+			if m, ok := c.(ColumnMetadata); ok {
+				cj.DataType, cj.DocString = m.DataType(), m.DocString()
+			}
+			tj.Columns = append(tj.Columns, cj)
+		}
+		for _, r := range t.Rows() {
+			values := r.Values()
+			row := make([]json.RawMessage, len(values))
+			for i, v := range values {
+				b, err := json.Marshal(datasetJSONValue(v))
+				if err != nil {
+					return nil, errors.ES(errors.OpTableAccess, errors.KInternal, "could not marshal the value of column %d of table %s: %s", i, t.Name(), err).SetNoRetry()
+				}
+				row[i] = b
+			}
+			tj.Rows = append(tj.Rows, row)
+		}
+		ds.Tables = append(ds.Tables, tj)
+	}
+	return json.Marshal(ds)
+}
+
+// datasetJSONValue returns the JSON value of a value in MarshalDataset, which is the one of the JSON exports, but for
+// decimals, which are strings so that they keep their precision in all JSON parsers.
+func datasetJSONValue(v value.Kusto) interface{} {
+	if d, ok := v.(*value.Decimal); ok && !value.IsNull(v) {
+		return d.String()
+	}
+	return exportJSON(v)
+}
+
+// UnmarshalDataset returns the Dataset of the JSON of MarshalDataset, to replay results from a cache, or in tests.
+// ctx is the context of the dataset, and Op is OpQuery.
+func UnmarshalDataset(ctx context.Context, data []byte) (Dataset, error) {
+	var ds datasetJSON
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "could not unmarshal the dataset: %s", err).SetNoRetry()
+	}
+	if ds.Version != DatasetJSONVersion {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "unsupported dataset JSON version %d", ds.Version).SetNoRetry()
+	}
+
+	base := NewBaseDataset(ctx, errors.OpQuery, ds.PrimaryResultKind)
+	tables := make([]Table, 0, len(ds.Tables))
+	for _, tj := range ds.Tables {
+		columns := make([]Column, len(tj.Columns))
+		for i, c := range tj.Columns {
+			if value.Default(c.Type) == nil {
+				return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "column %s of table %s has an unknown type %q", c.Name, tj.Name, c.Type).SetNoRetry()
+			}
+			columns[i] = NewColumnWithMetadata(i, c.Name, c.Type, c.DataType, c.DocString)
+		}
+
+		table := NewBaseTableWithOrdinal(base, tj.Index, tj.Ordinal, tj.ID, tj.Name, tj.Kind, columns)
+		rows := make([]Row, 0, len(tj.Rows))
+		for i, rj := range tj.Rows {
+			if len(rj) != len(columns) {
+				return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "row %d of table %s has %d values, but the table has %d columns",
+					i, tj.Name, len(rj), len(columns)).SetNoRetry()
+			}
+			values := make(value.Values, len(rj))
+			for j, raw := range rj {
+				v, err := unmarshalDatasetValue(columns[j].Type(), raw)
+				if err != nil {
+					return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "could not unmarshal the value of column %s of row %d of table %s: %s",
+						columns[j].Name(), i, tj.Name, err).SetNoRetry()
+				}
+				values[j] = v
+			}
+			rows = append(rows, NewRow(table, i, values))
+		}
+		tables = append(tables, NewTable(table, rows))
+	}
+	return NewDataset(base, tables), nil
+}
+
+// unmarshalDatasetValue returns the value of type t of a JSON value of MarshalDataset.
+func unmarshalDatasetValue(t types.Column, raw json.RawMessage) (value.Kusto, error) {
+	v := value.Default(t)
+	if t == types.Dynamic {
+		if string(bytes.TrimSpace(raw)) != "null" {
+			v = value.NewDynamic(bytes.Clone(raw))
+		}
+		return v, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var i interface{}
+	if err := dec.Decode(&i); err != nil {
+		return nil, err
+	}
+	if t == types.Decimal {
+		// Decimals are strings, but accept the numbers of other writers.
+		if n, ok := i.(json.Number); ok {
+			i = n.String()
+		}
+	}
+	if err := v.Unmarshal(i); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DatasetJSON holds a Dataset that is marshaled to and from JSON with MarshalDataset and UnmarshalDataset, to embed
+// datasets in other JSON documents, such as the entries of a cache. The datasets it unmarshals have a background
+// context.
+type DatasetJSON struct {
+	Dataset
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d DatasetJSON) MarshalJSON() ([]byte, error) {
+	if d.Dataset == nil {
+		return []byte("null"), nil
+	}
+	return MarshalDataset(d.Dataset)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *DatasetJSON) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		d.Dataset = nil
+		return nil
+	}
+	ds, err := UnmarshalDataset(context.Background(), data)
+	if err != nil {
+		return err
+	}
+	d.Dataset = ds
+	return nil
+}
@@ -0,0 +1,156 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonTestDataset() Dataset {
+	base := NewBaseDataset(context.Background(), errors.OpQuery, "PrimaryResult")
+	cols := Columns{
+		NewColumn(0, "Bool", types.Bool),
+		NewColumn(1, "Int", types.Int),
+		NewColumn(2, "Long", types.Long),
+		NewColumn(3, "Real", types.Real),
+		NewColumn(4, "Decimal", types.Decimal),
+		NewColumn(5, "String", types.String),
+		NewColumnWithMetadata(6, "Dynamic", types.Dynamic, "Object", "the properties"),
+		NewColumn(7, "DateTime", types.DateTime),
+		NewColumn(8, "Timespan", types.Timespan),
+		NewColumn(9, "GUID", types.GUID),
+	}
+	primary := NewBaseTableWithOrdinal(base, 1, 0, "1", "PrimaryResult", "PrimaryResult", cols)
+	rows := []Row{
+		NewRow(primary, 0, value.Values{
+			value.NewBool(true), value.NewInt(-3), value.NewLong(math.MaxInt64), value.NewReal(1.5),
+			value.NewDecimal(decimal.RequireFromString("123456789012345678901234567890.123")), value.NewString("it's \"quoted\""),
+			value.NewDynamic([]byte(`{"a":[1,2]}`)), value.NewDateTime(time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)),
+			value.NewTimespan(26*time.Hour + 100*time.Nanosecond), value.NewGUID(uuid.MustParse("f7a3b1e6-0c4d-4b5e-9f8a-1d2c3b4a5e6f")),
+		}),
+		NewRow(primary, 1, value.Values{
+			value.NewNullBool(), value.NewNullInt(), value.NewNullLong(), value.NewReal(math.Inf(-1)), value.NewNullDecimal(),
+			value.NewString(""), value.NewNullDynamic(), value.NewNullDateTime(), value.NewNullTimespan(), value.NewNullGUID(),
+		}),
+	}
+	completion := NewBaseTable(base, 2, "2", "QueryCompletionInformation", "QueryCompletionInformation", Columns{NewColumn(0, "Message", types.String)})
+	return NewDataset(base, []Table{
+		NewTable(primary, rows),
+		NewTable(completion, []Row{NewRow(completion, 0, value.Values{value.NewString("done")})}),
+	})
+}
+
+func TestDatasetJSON(t *testing.T) {
+	t.Parallel()
+
+	ds := jsonTestDataset()
+	b, err := json.Marshal(ds)
+	require.NoError(t, err)
+
+	var doc struct {
+		Version int
+		Tables  []struct {
+			Kind    string
+			Ordinal int
+			Columns []map[string]string
+			Rows    [][]interface{}
+		}
+	}
+	require.NoError(t, json.Unmarshal(b, &doc))
+	assert.Equal(t, DatasetJSONVersion, doc.Version)
+	require.Len(t, doc.Tables, 2)
+	assert.Equal(t, "PrimaryResult", doc.Tables[0].Kind)
+	assert.Equal(t, -1, doc.Tables[1].Ordinal)
+	assert.Equal(t, map[string]string{"name": "Dynamic", "type": "dynamic", "dataType": "Object", "docString": "the properties"}, doc.Tables[0].Columns[6])
+	assert.Equal(t, []interface{}{
+		true, -3.0, float64(math.MaxInt64), 1.5, "123456789012345678901234567890.123", `it's "quoted"`,
+		map[string]interface{}{"a": []interface{}{1.0, 2.0}}, "2024-01-02T03:04:05.0000006Z", "1.02:00:00.0000001", "f7a3b1e6-0c4d-4b5e-9f8a-1d2c3b4a5e6f",
+	}, doc.Tables[0].Rows[0])
+	assert.Equal(t, []interface{}{nil, nil, nil, "-Inf", nil, "", nil, nil, nil, nil}, doc.Tables[0].Rows[1])
+
+	got, err := UnmarshalDataset(context.Background(), b)
+	require.NoError(t, err)
+	assert.Equal(t, "PrimaryResult", got.PrimaryResultKind())
+	require.Len(t, got.Tables(), 2)
+	for i, want := range ds.Tables() {
+		table := got.Tables()[i]
+		assert.Equal(t, want.Id(), table.Id())
+		assert.Equal(t, want.Index(), table.Index())
+		assert.Equal(t, TableOrdinal(want), TableOrdinal(table))
+		assert.Equal(t, want.Name(), table.Name())
+		assert.Equal(t, want.Kind(), table.Kind())
+		assert.Equal(t, want.IsPrimaryResult(), table.IsPrimaryResult())
+		assert.Equal(t, want.Columns(), table.Columns())
+		require.Equal(t, len(want.Rows()), len(table.Rows()))
+		for j, row := range want.Rows() {
+			for k, v := range row.Values() {
+				gv := table.Rows()[j].Values()[k]
+				assert.Equal(t, value.IsNull(v), value.IsNull(gv), "row %d, column %d", j, k)
+				assert.Equal(t, v.String(), gv.String(), "row %d, column %d", j, k)
+			}
+		}
+	}
+	assert.Same(t, got.Tables()[0], PrimaryByOrdinal(got.Tables(), 0))
+
+	// The JSON of a reconstructed dataset is the same.
+	again, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(b), string(again))
+}
+
+func TestDatasetJSONWrapper(t *testing.T) {
+	t.Parallel()
+
+	type entry struct {
+		Key     string      `json:"key"`
+		Dataset DatasetJSON `json:"dataset"`
+	}
+	b, err := json.Marshal(entry{Key: "k", Dataset: DatasetJSON{jsonTestDataset()}})
+	require.NoError(t, err)
+
+	var e entry
+	require.NoError(t, json.Unmarshal(b, &e))
+	require.NotNil(t, e.Dataset.Dataset)
+	assert.Len(t, e.Dataset.Tables(), 2)
+
+	b, err = json.Marshal(entry{Key: "empty"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"empty","dataset":null}`, string(b))
+	require.NoError(t, json.Unmarshal(b, &e))
+	assert.Nil(t, e.Dataset.Dataset)
+}
+
+func TestUnmarshalDatasetErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"invalid", `{`, "could not unmarshal the dataset"},
+		{"version", `{"version":2,"tables":[]}`, "unsupported dataset JSON version 2"},
+		{"type", `{"version":1,"tables":[{"name":"T","columns":[{"name":"A","type":"blob"}],"rows":[]}]}`, `unknown type "blob"`},
+		{"width", `{"version":1,"tables":[{"name":"T","columns":[{"name":"A","type":"long"}],"rows":[[1,2]]}]}`, "row 0 of table T has 2 values"},
+		{"value", `{"version":1,"tables":[{"name":"T","columns":[{"name":"A","type":"guid"}],"rows":[["nope"]]}]}`, "column A of row 0 of table T"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := UnmarshalDataset(context.Background(), []byte(tt.json))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/shopspring/decimal"
+)
+
+// DiffOption is an option for Diff.
+type DiffOption func(o *diffOptions)
+
+type diffOptions struct {
+	realTolerance     float64
+	dateTimeTolerance time.Duration
+	ignoreColumnOrder bool
+	ignoreRowOrder    bool
+}
+
+// RealTolerance considers real values equal when they differ by at most tolerance.
+func RealTolerance(tolerance float64) DiffOption {
+	return func(o *diffOptions) {
+		o.realTolerance = math.Abs(tolerance)
+	}
+}
+
+// DateTimeTolerance considers datetime values equal when they differ by at most tolerance.
+func DateTimeTolerance(tolerance time.Duration) DiffOption {
+	return func(o *diffOptions) {
+		if tolerance < 0 {
+			tolerance = -tolerance
+		}
+		o.dateTimeTolerance = tolerance
+	}
+}
+
+// IgnoreColumnOrder matches the columns of the tables by name only, instead of reporting columns in a different order.
+func IgnoreColumnOrder() DiffOption {
+	return func(o *diffOptions) {
+		o.ignoreColumnOrder = true
+	}
+}
+
+// IgnoreRowOrder compares the rows of the tables as sets, for queries without a sort.
+func IgnoreRowOrder() DiffOption {
+	return func(o *diffOptions) {
+		o.ignoreRowOrder = true
+	}
+}
+
+// Diff compares the tables of two datasets, in order, and returns a human-readable report of their differences, or an
+// empty string if they are equal. It reports missing and unexpected tables, columns and rows, columns of different types
+// or in a different order, and the values that differ. It is meant for tests:
+//
+//	if diff := query.Diff(want, got, query.RealTolerance(1e-9)); diff != "" {
+//		t.Errorf("unexpected results (-want +got):\n%s", diff)
+//	}
+//
+// Columns are matched by name, and the values of columns of different types are not compared. Dynamic values are
+// compared as JSON, so formatting and the order of the properties of objects don't matter.
+func Diff(want, got Dataset, options ...DiffOption) string {
+	opts := diffOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	d := &differ{opts: opts}
+	wantTables, gotTables := want.Tables(), got.Tables()
+	for i := 0; i < len(wantTables) || i < len(gotTables); i++ {
+		switch {
+		case i >= len(gotTables):
+			d.printf("- table %d (%s): missing", i, wantTables[i].Name())
+		case i >= len(wantTables):
+			d.printf("+ table %d (%s): unexpected", i, gotTables[i].Name())
+		default:
+			d.diffTable(i, wantTables[i], gotTables[i])
+		}
+	}
+	return d.b.String()
+}
+
+type differ struct {
+	opts diffOptions
+	b    strings.Builder
+	// header is written before the next difference, so that only tables with differences are listed.
+	header string
+}
+
+func (d *differ) printf(format string, args ...interface{}) {
+	if d.header != "" {
+		d.b.WriteString(d.header + "\n")
+		d.header = ""
+	}
+	d.b.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+// diffColumn is a column of both tables, with the same name and type.
+type diffColumn struct {
+	name      string
+	want, got int
+}
+
+func (d *differ) diffTable(i int, want, got Table) {
+	d.header = fmt.Sprintf("table %d (%s):", i, want.Name())
+	defer func() { d.header = "" }()
+
+	if want.Name() != got.Name() {
+		d.printf("  name: want %s, got %s", want.Name(), got.Name())
+	}
+
+	columns := d.diffColumns(want.Columns(), got.Columns())
+	if d.opts.ignoreRowOrder {
+		d.diffRowSets(want.Rows(), got.Rows(), columns)
+	} else {
+		d.diffRows(want.Rows(), got.Rows(), columns)
+	}
+}
+
+// diffColumns reports the differences between the columns of the tables, and returns the columns whose values can be
+// compared.
+func (d *differ) diffColumns(want, got []Column) []diffColumn {
+	gotByName := make(map[string]Column, len(got))
+	for _, c := range got {
+		gotByName[c.Name()] = c
+	}
+	wantByName := make(map[string]Column, len(want))
+	for _, c := range want {
+		wantByName[c.Name()] = c
+	}
+
+	var columns []diffColumn
+	for _, w := range want {
+		g, ok := gotByName[w.Name()]
+		switch {
+		case !ok:
+			d.printf("- column %s:%s: missing", w.Name(), w.Type())
+		case w.Type() != g.Type():
+			d.printf("  column %s: want type %s, got type %s", w.Name(), w.Type(), g.Type())
+		default:
+			columns = append(columns, diffColumn{name: w.Name(), want: w.Index(), got: g.Index()})
+		}
+	}
+	for _, g := range got {
+		if _, ok := wantByName[g.Name()]; !ok {
+			d.printf("+ column %s:%s: unexpected", g.Name(), g.Type())
+		}
+	}
+
+	if !d.opts.ignoreColumnOrder {
+		var wantOrder, gotOrder []string
+		for _, c := range want {
+			if _, ok := gotByName[c.Name()]; ok {
+				wantOrder = append(wantOrder, c.Name())
+			}
+		}
+		for _, c := range got {
+			if _, ok := wantByName[c.Name()]; ok {
+				gotOrder = append(gotOrder, c.Name())
+			}
+		}
+		if !reflect.DeepEqual(wantOrder, gotOrder) {
+			d.printf("  column order: want (%s), got (%s)", strings.Join(wantOrder, ", "), strings.Join(gotOrder, ", "))
+		}
+	}
+	return columns
+}
+
+// diffRows compares the rows of the tables by position.
+func (d *differ) diffRows(want, got []Row, columns []diffColumn) {
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			d.printf("- row %d: missing: %s", i, formatRow(want[i]))
+		case i >= len(want):
+			d.printf("+ row %d: unexpected: %s", i, formatRow(got[i]))
+		default:
+			wantValues, gotValues := want[i].Values(), got[i].Values()
+			for _, c := range columns {
+				w, g := wantValues[c.want], gotValues[c.got]
+				if !d.equal(w, g) {
+					d.printf("  row %d, column %s: want %s, got %s", i, c.name, formatValue(w), formatValue(g))
+				}
+			}
+		}
+	}
+}
+
+// diffRowSets matches every row of want to an equal row of got, in any order.
+func (d *differ) diffRowSets(want, got []Row, columns []diffColumn) {
+	matched := make([]bool, len(got))
+	for i, w := range want {
+		found := false
+		for j, g := range got {
+			if !matched[j] && d.equalRows(w, g, columns) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			d.printf("- row %d: missing: %s", i, formatRow(w))
+		}
+	}
+	for j, g := range got {
+		if !matched[j] {
+			d.printf("+ row %d: unexpected: %s", j, formatRow(g))
+		}
+	}
+}
+
+func (d *differ) equalRows(want, got Row, columns []diffColumn) bool {
+	wantValues, gotValues := want.Values(), got.Values()
+	for _, c := range columns {
+		if !d.equal(wantValues[c.want], gotValues[c.got]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equal compares two values of the same column type.
+func (d *differ) equal(want, got value.Kusto) bool {
+	wantNull, gotNull := value.IsNull(want), value.IsNull(got)
+	if wantNull || gotNull {
+		return wantNull == gotNull
+	}
+
+	switch w := want.GetValue().(type) {
+	case *float64:
+		g, ok := got.GetValue().(*float64)
+		if !ok {
+			return false
+		}
+		if math.IsNaN(*w) || math.IsNaN(*g) {
+			return math.IsNaN(*w) && math.IsNaN(*g)
+		}
+		return *w == *g || math.Abs(*w-*g) <= d.opts.realTolerance
+	case *time.Time:
+		g, ok := got.GetValue().(*time.Time)
+		if !ok {
+			return false
+		}
+		diff := w.Sub(*g)
+		if diff < 0 {
+			diff = -diff
+		}
+		return diff <= d.opts.dateTimeTolerance
+	case *decimal.Decimal:
+		g, ok := got.GetValue().(*decimal.Decimal)
+		return ok && w.Equal(*g)
+	case []byte:
+		g, ok := got.GetValue().([]byte)
+		return ok && equalJSON(w, g)
+	default:
+		return want.String() == got.String()
+	}
+}
+
+// equalJSON compares two JSON documents semantically, or byte by byte if they are not valid JSON.
+func equalJSON(want, got []byte) bool {
+	if bytes.Equal(want, got) {
+		return true
+	}
+	var w, g interface{}
+	if json.Unmarshal(want, &w) != nil || json.Unmarshal(got, &g) != nil {
+		return false
+	}
+	return reflect.DeepEqual(w, g)
+}
+
+func formatRow(r Row) string {
+	values := r.Values()
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = formatValue(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func formatValue(v value.Kusto) string {
+	if value.IsNull(v) {
+		return "null"
+	}
+	if _, ok := v.(*value.String); ok {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return v.String()
+}
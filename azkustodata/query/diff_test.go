@@ -0,0 +1,116 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+)
+
+// diffDataset returns a dataset with a single table of the columns and rows.
+func diffDataset(columns []Column, rows ...value.Values) Dataset {
+	base := NewBaseDataset(context.Background(), errors.OpQuery, "PrimaryResult")
+	table := NewBaseTable(base, 0, "0", "PrimaryResult", "PrimaryResult", columns)
+	tableRows := make([]Row, len(rows))
+	for i, r := range rows {
+		tableRows[i] = NewRow(table, i, r)
+	}
+	return NewDataset(base, []Table{NewTable(table, tableRows)})
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []Column{
+		NewColumn(0, "Id", types.Long),
+		NewColumn(1, "Name", types.String),
+		NewColumn(2, "Score", types.Real),
+		NewColumn(3, "Time", types.DateTime),
+		NewColumn(4, "Props", types.Dynamic),
+	}
+	row := func(id int64, name string, score float64, ts time.Time, props string) value.Values {
+		return value.Values{value.NewLong(id), value.NewString(name), value.NewReal(score), value.NewDateTime(ts), value.NewDynamic([]byte(props))}
+	}
+	want := diffDataset(columns, row(1, "a", 0.5, now, `{"a":1,"b":2}`), row(2, "b", 1.5, now, `[]`))
+
+	tests := []struct {
+		desc    string
+		got     Dataset
+		options []DiffOption
+		want    string
+	}{
+		{
+			desc: "equal",
+			got:  diffDataset(columns, row(1, "a", 0.5, now, `{"b": 2, "a": 1}`), row(2, "b", 1.5, now, `[]`)),
+		},
+		{
+			desc: "values",
+			got:  diffDataset(columns, row(1, "x", 0.5, now, `{"a":1,"b":2}`), row(2, "b", 1.6, now, `[]`)),
+			want: "table 0 (PrimaryResult):\n" +
+				"  row 0, column Name: want \"a\", got \"x\"\n" +
+				"  row 1, column Score: want 1.5, got 1.6\n",
+		},
+		{
+			desc:    "tolerance",
+			got:     diffDataset(columns, row(1, "a", 0.5+1e-12, now.Add(time.Millisecond), `{"a":1,"b":2}`), row(2, "b", 1.5, now, `[]`)),
+			options: []DiffOption{RealTolerance(1e-9), DateTimeTolerance(time.Second)},
+		},
+		{
+			desc: "rows",
+			got:  diffDataset(columns, row(1, "a", 0.5, now, `{"a":1,"b":2}`)),
+			want: "table 0 (PrimaryResult):\n" +
+				"- row 1: missing: (2, \"b\", 1.5, 2024-01-01T00:00:00Z, [])\n",
+		},
+		{
+			desc:    "row order ignored",
+			got:     diffDataset(columns, row(2, "b", 1.5, now, `[]`), row(1, "a", 0.5, now, `{"a":1,"b":2}`)),
+			options: []DiffOption{IgnoreRowOrder()},
+		},
+		{
+			desc:    "row sets",
+			got:     diffDataset(columns, row(2, "b", 1.5, now, `[]`), row(3, "c", 0, now, `null`)),
+			options: []DiffOption{IgnoreRowOrder()},
+			want: "table 0 (PrimaryResult):\n" +
+				"- row 0: missing: (1, \"a\", 0.5, 2024-01-01T00:00:00Z, {\"a\":1,\"b\":2})\n" +
+				"+ row 1: unexpected: (3, \"c\", 0, 2024-01-01T00:00:00Z, null)\n",
+		},
+		{
+			desc: "columns",
+			got: diffDataset([]Column{
+				NewColumn(0, "Name", types.String),
+				NewColumn(1, "Id", types.Int),
+				NewColumn(2, "Score", types.Real),
+				NewColumn(3, "Extra", types.Bool),
+			},
+				value.Values{value.NewString("a"), value.NewInt(1), value.NewNullReal(), value.NewBool(true)},
+				value.Values{value.NewString("b"), value.NewInt(2), value.NewReal(1.5), value.NewBool(true)},
+			),
+			want: "table 0 (PrimaryResult):\n" +
+				"  column Id: want type long, got type int\n" +
+				"- column Time:datetime: missing\n" +
+				"- column Props:dynamic: missing\n" +
+				"+ column Extra:bool: unexpected\n" +
+				"  column order: want (Id, Name, Score), got (Name, Id, Score)\n" +
+				"  row 0, column Score: want 0.5, got null\n",
+		},
+		{
+			desc:    "tables",
+			got:     NewDataset(NewBaseDataset(context.Background(), errors.OpQuery, "PrimaryResult"), nil),
+			options: []DiffOption{IgnoreColumnOrder()},
+			want:    "- table 0 (PrimaryResult): missing\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, Diff(want, test.got, test.options...))
+		})
+	}
+}
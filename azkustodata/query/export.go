@@ -0,0 +1,413 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// defaultCheckpointEvery is the default number of rows between two checkpoints of ExportToFile.
+const defaultCheckpointEvery = 100_000
+
+// ExportFormat is the format of the file written by ExportToFile.
+type ExportFormat int
+
+const (
+	// ExportCSV writes the rows as CSV, after a header with the names of the columns.
+	ExportCSV ExportFormat = iota
+	// ExportJSON writes every row as a JSON object on its own line, with the names of the columns as keys.
+	ExportJSON
+)
+
+// String implements fmt.Stringer.
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportCSV:
+		return "csv"
+	case ExportJSON:
+		return "json"
+	}
+	return fmt.Sprintf("ExportFormat(%d)", int(f))
+}
+
+type exportOptions struct {
+	checkpointEvery int64
+	resumeInQuery   bool
+}
+
+// ExportOption is an option for ExportToFile.
+type ExportOption func(o *exportOptions)
+
+// CheckpointEvery sets the number of rows between two checkpoints. Defaults to 100,000.
+// Every checkpoint flushes the file to the disk, so a small value slows the export down.
+func CheckpointEvery(rows int64) ExportOption {
+	return func(o *exportOptions) {
+		o.checkpointEvery = rows
+	}
+}
+
+// ResumeInQuery tells ExportToFile that the dataset of a resumed export only holds the rows after the checkpoint, as
+// the query was changed to skip the exported rows, for instance with a filter on the last exported key. By default, the
+// query is expected to return all the rows again, in the same order, and the rows already exported are skipped.
+func ResumeInQuery() ExportOption {
+	return func(o *exportOptions) {
+		o.resumeInQuery = true
+	}
+}
+
+// ExportCheckpoint is the progress of an export, saved next to the file while the export runs.
+type ExportCheckpoint struct {
+	// Rows is the number of rows in the file at the checkpoint.
+	Rows int64
+	// Offset is the size of the file at the checkpoint. Anything written after it is discarded when the export resumes.
+	Offset int64
+	// Format is the format of the file.
+	Format ExportFormat
+	// Columns are the names of the columns of the exported table.
+	Columns []string
+}
+
+// ExportResult is the outcome of an ExportToFile call.
+type ExportResult struct {
+	// Rows is the number of rows in the file.
+	Rows int64
+	// ResumedRows is the number of rows that were exported by previous calls, before the checkpoint the export resumed
+	// from.
+	ResumedRows int64
+}
+
+// checkpointPath returns the path of the checkpoint of the export to path.
+func checkpointPath(path string) string {
+	return path + ".checkpoint"
+}
+
+// ReadExportCheckpoint returns the checkpoint of an interrupted export to path, or nil if there is none.
+// Use it to resume the export with a query that skips the rows that were already exported, with ResumeInQuery.
+func ReadExportCheckpoint(path string) (*ExportCheckpoint, error) {
+	b, err := os.ReadFile(checkpointPath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not read the export checkpoint: %s", err).SetNoRetry()
+	}
+
+	var cp ExportCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "the export checkpoint %s is corrupted: %s", checkpointPath(path), err).SetNoRetry()
+	}
+	return &cp, nil
+}
+
+// ExportToFile writes the rows of the first primary result table of the dataset to a file, for the extraction of
+// results too large to hold in memory. The other tables are skipped. The dataset is closed when the export ends.
+//
+// While the export runs, a checkpoint is saved next to the file, at path + ".checkpoint", every CheckpointEvery rows,
+// after the rows were flushed to the disk. If the export stops, because of a crash, a failed query or the cancellation
+// of ctx, calling ExportToFile again with the same path resumes it from the last checkpoint: the rows written after it
+// are discarded, and the rows up to it are skipped from the dataset, so the query must return its rows in a stable
+// order, with a sort. To skip them in the query instead, read the checkpoint with ReadExportCheckpoint and use the
+// ResumeInQuery option. The checkpoint is removed once the export completes.
+func ExportToFile(ctx context.Context, dataset IterativeDataset, path string, format ExportFormat, options ...ExportOption) (*ExportResult, error) {
+	defer dataset.Close()
+
+	opts := exportOptions{checkpointEvery: defaultCheckpointEvery}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.checkpointEvery <= 0 {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "CheckpointEvery() requires a positive number of rows, got %d", opts.checkpointEvery).SetNoRetry()
+	}
+	if format != ExportCSV && format != ExportJSON {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "unsupported export format %s", format).SetNoRetry()
+	}
+
+	cp, err := ReadExportCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil && cp.Format != format {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "the export to %s was started with the format %s, and cannot be resumed with the format %s",
+			path, cp.Format, format).SetNoRetry()
+	}
+
+	for tr := range dataset.Tables() {
+		if tr.Err() != nil {
+			return nil, tr.Err()
+		}
+		table := tr.Table()
+		if !table.IsPrimaryResult() {
+			if err := skipRows(table); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		w, err := newExportWriter(path, format, table.Columns(), cp, opts)
+		if err != nil {
+			return nil, err
+		}
+		return w.export(ctx, table)
+	}
+
+	return nil, errors.ES(errors.OpTableAccess, errors.KOther, "the dataset has no primary result table to export").SetNoRetry()
+}
+
+func skipRows(table IterativeTable) error {
+	for rr := range table.Rows() {
+		if rr.Err() != nil {
+			return rr.Err()
+		}
+	}
+	return nil
+}
+
+// exportWriter writes the rows of a table to the export file.
+type exportWriter struct {
+	path   string
+	format ExportFormat
+	names  []string
+	opts   exportOptions
+
+	file *os.File
+	buf  *bufio.Writer
+	csv  *csv.Writer
+	// offset is the size of the file once buf is flushed.
+	offset int64
+	rows   int64
+	// skip is the number of rows of the dataset that were exported before the checkpoint.
+	skip    int64
+	resumed int64
+}
+
+func newExportWriter(path string, format ExportFormat, columns []Column, cp *ExportCheckpoint, opts exportOptions) (*exportWriter, error) {
+	w := &exportWriter{path: path, format: format, opts: opts}
+	for _, c := range columns {
+		w.names = append(w.names, c.Name())
+	}
+
+	var err error
+	if cp == nil {
+		w.file, err = os.Create(path)
+	} else {
+		if !reflect.DeepEqual(cp.Columns, w.names) {
+			return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "the export to %s was started with the columns %v, and cannot be resumed with the columns %v",
+				path, cp.Columns, w.names).SetNoRetry()
+		}
+		w.file, err = os.OpenFile(path, os.O_RDWR, 0)
+		if err == nil {
+			err = w.file.Truncate(cp.Offset)
+		}
+		if err == nil {
+			_, err = w.file.Seek(cp.Offset, io.SeekStart)
+		}
+		w.offset, w.rows, w.resumed = cp.Offset, cp.Rows, cp.Rows
+		if !opts.resumeInQuery {
+			w.skip = cp.Rows
+		}
+	}
+	if err != nil {
+		if w.file != nil {
+			_ = w.file.Close()
+		}
+		return nil, errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not open the export file: %s", err).SetNoRetry()
+	}
+
+	w.buf = bufio.NewWriter(&countingWriter{w: w.file, n: &w.offset})
+	if format == ExportCSV {
+		w.csv = csv.NewWriter(w.buf)
+		if cp == nil {
+			if err := w.csv.Write(w.names); err != nil {
+				_ = w.file.Close()
+				return nil, errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export file: %s", err).SetNoRetry()
+			}
+		}
+	}
+	return w, nil
+}
+
+func (w *exportWriter) export(ctx context.Context, table IterativeTable) (*ExportResult, error) {
+	defer w.file.Close()
+
+	fail := func(err error) (*ExportResult, error) {
+		// The rows written so far are complete, so the export can resume after them.
+		if cpErr := w.checkpoint(); cpErr != nil {
+			return nil, errors.CombineErrors(err, cpErr)
+		}
+		return nil, err
+	}
+
+	for rr := range table.Rows() {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		if rr.Err() != nil {
+			return fail(rr.Err())
+		}
+
+		if w.skip > 0 {
+			w.skip--
+			continue
+		}
+		if err := w.write(rr.Row()); err != nil {
+			return fail(errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export file: %s", err).SetNoRetry())
+		}
+		w.rows++
+
+		if (w.rows-w.resumed)%w.opts.checkpointEvery == 0 {
+			if err := w.checkpoint(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if w.skip > 0 {
+		return fail(errors.ES(errors.OpTableAccess, errors.KClientArgs, "the query returned %d rows less than the %d rows of the checkpoint of the export, it must return the same rows in the same order to be resumed",
+			w.skip, w.resumed).SetNoRetry())
+	}
+	if err := w.flush(); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(checkpointPath(w.path)); err != nil && !os.IsNotExist(err) {
+		return nil, errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not remove the export checkpoint: %s", err).SetNoRetry()
+	}
+	return &ExportResult{Rows: w.rows, ResumedRows: w.resumed}, nil
+}
+
+func (w *exportWriter) write(row Row) error {
+	values := row.Values()
+	if w.format == ExportCSV {
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = exportText(v)
+		}
+		return w.csv.Write(record)
+	}
+
+	return writeJSONRow(w.buf, w.names, values)
+}
+
+// flush writes the buffered rows to the disk.
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export file: %s", err).SetNoRetry()
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export file: %s", err).SetNoRetry()
+	}
+	if err := w.file.Sync(); err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export file: %s", err).SetNoRetry()
+	}
+	return nil
+}
+
+// checkpoint flushes the rows and saves the checkpoint. The checkpoint is replaced atomically, so that a crash leaves
+// either the previous or the new one.
+func (w *exportWriter) checkpoint() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(ExportCheckpoint{Rows: w.rows, Offset: w.offset, Format: w.format, Columns: w.names})
+	if err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KOther, "could not serialize the export checkpoint: %s", err).SetNoRetry()
+	}
+	tmp := checkpointPath(w.path) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export checkpoint: %s", err).SetNoRetry()
+	}
+	if err := os.Rename(tmp, checkpointPath(w.path)); err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KLocalFileSystem, "could not write the export checkpoint: %s", err).SetNoRetry()
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// exportText returns the CSV text of a value. Nulls are empty, and timespans use the Kusto format, so that the file can
+// be ingested back.
+func exportText(v value.Kusto) string {
+	if value.IsNull(v) {
+		return ""
+	}
+	if t, ok := v.(*value.Timespan); ok {
+		return t.Marshal()
+	}
+	return v.String()
+}
+
+// writeJSONRow writes the values of a row as a JSON object on its own line, with the names of the columns as keys.
+func writeJSONRow(buf *bufio.Writer, names []string, values value.Values) error {
+	if err := buf.WriteByte('{'); err != nil {
+		return err
+	}
+	for i, v := range values {
+		name, err := json.Marshal(names[i])
+		if err != nil {
+			return err
+		}
+		val, err := json.Marshal(exportJSON(v))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			_ = buf.WriteByte(',')
+		}
+		_, _ = buf.Write(name)
+		_ = buf.WriteByte(':')
+		if _, err := buf.Write(val); err != nil {
+			return err
+		}
+	}
+	_, err := buf.WriteString("}\n")
+	return err
+}
+
+// exportJSON returns the JSON value of a value. Dynamics are written as is, decimals as numbers without loss of
+// precision, and the other values that have no JSON equivalent as their text.
+func exportJSON(v value.Kusto) interface{} {
+	if value.IsNull(v) {
+		return nil
+	}
+
+	switch val := v.(type) {
+	case *value.Dynamic:
+		return json.RawMessage(val.Value)
+	case *value.Decimal:
+		return json.Number(val.String())
+	case *value.Real:
+		if f := *val.Ptr(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return val.String()
+		}
+		return *val.Ptr()
+	case *value.Bool:
+		return *val.Ptr()
+	case *value.Int:
+		return *val.Ptr()
+	case *value.Long:
+		return *val.Ptr()
+	}
+	return exportText(v)
+}
@@ -0,0 +1,249 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIterativeTable streams its rows, and then its error if any.
+type fakeIterativeTable struct {
+	BaseTable
+	rows []Row
+	err  error
+}
+
+func (f *fakeIterativeTable) Rows() <-chan RowResult {
+	ch := make(chan RowResult, len(f.rows)+1)
+	for _, r := range f.rows {
+		ch <- RowResultSuccess(r)
+	}
+	if f.err != nil {
+		ch <- RowResultError(f.err)
+	}
+	close(ch)
+	return ch
+}
+
+func (f *fakeIterativeTable) ToTable() (Table, error) {
+	panic("not implemented")
+}
+
+type fakeIterativeDataset struct {
+	BaseDataset
+	tables []IterativeTable
+	closed bool
+}
+
+func (f *fakeIterativeDataset) Tables() <-chan TableResult {
+	ch := make(chan TableResult, len(f.tables))
+	for _, t := range f.tables {
+		ch <- TableResultSuccess(t)
+	}
+	close(ch)
+	return ch
+}
+
+func (f *fakeIterativeDataset) ToDataset() (Dataset, error) {
+	panic("not implemented")
+}
+
+func (f *fakeIterativeDataset) Close() error {
+	f.closed = true
+	return nil
+}
+
+// exportDataset returns a dataset with a secondary table and a primary table with the rows first to last-1, followed
+// by err if it is not nil.
+func exportDataset(first, last int, err error) *fakeIterativeDataset {
+	base := NewBaseDataset(context.Background(), errors.OpQuery, "PrimaryResult")
+	columns := []Column{
+		NewColumn(0, "Id", types.Long),
+		NewColumn(1, "Name", types.String),
+		NewColumn(2, "Span", types.Timespan),
+		NewColumn(3, "Props", types.Dynamic),
+	}
+	primary := NewBaseTable(base, 1, "1", "PrimaryResult", "PrimaryResult", columns)
+	table := &fakeIterativeTable{BaseTable: primary, err: err}
+	for i := first; i < last; i++ {
+		props := value.NewNullDynamic()
+		if i%2 == 0 {
+			props = value.NewDynamic([]byte(fmt.Sprintf(`{"i":%d}`, i)))
+		}
+		table.rows = append(table.rows, NewRow(primary, i, value.Values{
+			value.NewLong(int64(i)), value.NewString(fmt.Sprintf("n,%d", i)), value.NewTimespan(time.Duration(i) * time.Minute), props,
+		}))
+	}
+
+	secondary := NewBaseTable(base, 0, "0", "QueryProperties", "QueryProperties", []Column{NewColumn(0, "Key", types.String)})
+	return &fakeIterativeDataset{
+		BaseDataset: base,
+		tables: []IterativeTable{
+			&fakeIterativeTable{BaseTable: secondary, rows: []Row{NewRow(secondary, 0, value.Values{value.NewString("k")})}},
+			table,
+		},
+	}
+}
+
+const exportCSV = "Id,Name,Span,Props\n" +
+	"0,\"n,0\",00:00:00,\"{\"\"i\"\":0}\"\n" +
+	"1,\"n,1\",00:01:00,\n" +
+	"2,\"n,2\",00:02:00,\"{\"\"i\"\":2}\"\n" +
+	"3,\"n,3\",00:03:00,\n" +
+	"4,\"n,4\",00:04:00,\"{\"\"i\"\":4}\"\n"
+
+func TestExportToFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "out.csv")
+		ds := exportDataset(0, 5, nil)
+
+		result, err := ExportToFile(context.Background(), ds, path, ExportCSV, CheckpointEvery(2))
+		require.NoError(t, err)
+		assert.Equal(t, &ExportResult{Rows: 5}, result)
+		assert.True(t, ds.closed)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, exportCSV, string(b))
+
+		cp, err := ReadExportCheckpoint(path)
+		require.NoError(t, err)
+		assert.Nil(t, cp)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "out.json")
+
+		_, err := ExportToFile(context.Background(), exportDataset(0, 2, nil), path, ExportJSON)
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `{"Id":0,"Name":"n,0","Span":"00:00:00","Props":{"i":0}}`+"\n"+
+			`{"Id":1,"Name":"n,1","Span":"00:01:00","Props":null}`+"\n", string(b))
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "out.csv")
+
+		_, err := ExportToFile(context.Background(), exportDataset(0, 3, fmt.Errorf("connection reset")), path, ExportCSV, CheckpointEvery(2))
+		assert.EqualError(t, err, "connection reset")
+
+		cp, err := ReadExportCheckpoint(path)
+		require.NoError(t, err)
+		require.NotNil(t, cp)
+		assert.Equal(t, int64(3), cp.Rows)
+		assert.Equal(t, []string{"Id", "Name", "Span", "Props"}, cp.Columns)
+
+		// Rows written after the checkpoint, before a crash, are discarded.
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = f.WriteString("3,\"n,")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		_, err = ExportToFile(context.Background(), exportDataset(0, 5, nil), path, ExportJSON)
+		assert.Error(t, err)
+
+		result, err := ExportToFile(context.Background(), exportDataset(0, 5, nil), path, ExportCSV)
+		require.NoError(t, err)
+		assert.Equal(t, &ExportResult{Rows: 5, ResumedRows: 3}, result)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, exportCSV, string(b))
+	})
+
+	t.Run("resume in query", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "out.csv")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := ExportToFile(ctx, exportDataset(0, 5, nil), path, ExportCSV)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		cp, err := ReadExportCheckpoint(path)
+		require.NoError(t, err)
+		require.NotNil(t, cp)
+		assert.Equal(t, int64(0), cp.Rows)
+
+		_, err = ExportToFile(context.Background(), exportDataset(int(cp.Rows), 5, nil), path, ExportCSV, ResumeInQuery())
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, exportCSV, string(b))
+	})
+
+	t.Run("short query", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "out.csv")
+
+		_, err := ExportToFile(context.Background(), exportDataset(0, 3, fmt.Errorf("timeout")), path, ExportCSV)
+		assert.Error(t, err)
+		_, err = ExportToFile(context.Background(), exportDataset(0, 2, nil), path, ExportCSV)
+		assert.Error(t, err)
+	})
+}
+
+// flushRecorder records the content of the buffer at every flush.
+type flushRecorder struct {
+	bytes.Buffer
+	flushes []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes = append(f.flushes, f.String())
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{
+		`{"Id":0,"Name":"n,0","Span":"00:00:00","Props":{"i":0}}` + "\n",
+		`{"Id":1,"Name":"n,1","Span":"00:01:00","Props":null}` + "\n",
+		`{"Id":2,"Name":"n,2","Span":"00:02:00","Props":{"i":2}}` + "\n",
+	}
+
+	w := &flushRecorder{}
+	ds := exportDataset(0, 3, nil)
+	rows, err := WriteNDJSON(ds, w)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rows)
+	assert.True(t, ds.closed)
+	assert.Equal(t, strings.Join(lines, ""), w.String())
+	assert.Equal(t, []string{w.String()}, w.flushes)
+
+	w = &flushRecorder{}
+	rows, err = WriteNDJSON(exportDataset(0, 3, nil), w, FlushEvery(2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rows)
+	assert.Equal(t, []string{lines[0] + lines[1], strings.Join(lines, "")}, w.flushes)
+
+	// The rows before a failure are written.
+	w = &flushRecorder{}
+	rows, err = WriteNDJSON(exportDataset(0, 2, fmt.Errorf("failure")), w)
+	assert.EqualError(t, err, "failure")
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, lines[0]+lines[1], w.String())
+
+	_, err = WriteNDJSON(exportDataset(0, 1, nil), w, FlushEvery(-1))
+	assert.Error(t, err)
+}
@@ -3,34 +3,233 @@ package query
 import (
 	kustoErrors "github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/unicode/norm"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// structField is an exported field of a struct that can be decoded into. Fields of embedded and flattened nested
+// structs are fields of the outer struct, see newFields.
+type structField struct {
+	// name is the name of the field, prefixed with the names of the structs it is nested in, e.g. "Address.City".
+	name string
+	// index is the index sequence of the field, as used by reflect.Value.FieldByIndex.
+	index []int
+	// column is the name of the column decoded into the field.
+	column string
+	// columnIndex is the index of the column decoded into the field when set with a `kusto:"#<index>"` tag, otherwise -1.
+	columnIndex int
+}
+
 type fieldMap struct {
-	colNameToFieldName map[string]string
+	fields []structField
 }
 
 var typeMapper = map[reflect.Type]fieldMap{}
 var typeMapperLock = sync.RWMutex{}
 
-// decodeToStruct takes a list of columns and a row to decode into "p" which will be a pointer
-// to a struct (enforce in the decoder).
-func decodeToStruct(cols []Column, row value.Values, p interface{}) error {
-	t := reflect.TypeOf(p)
-	v := reflect.ValueOf(p)
-	fields := newFields(t)
+// DecodeOption is an option for decoding rows into structs.
+type DecodeOption func(o *decodeOptions)
+
+type decodeOptions struct {
+	caseInsensitive   bool
+	normalizeUnicode  bool
+	disallowUnmatched bool
+}
+
+// CaseInsensitive matches column names to struct fields (or their tags) ignoring case.
+func CaseInsensitive() DecodeOption {
+	return func(o *decodeOptions) {
+		o.caseInsensitive = true
+	}
+}
+
+// NormalizeUnicode matches column names to struct fields (or their tags) after applying Unicode NFC normalization to both,
+// so that names that are composed differently but render the same will match.
+func NormalizeUnicode() DecodeOption {
+	return func(o *decodeOptions) {
+		o.normalizeUnicode = true
+	}
+}
+
+// DisallowUnmatched fails the decoding if a column has no matching field, or is a duplicate of a previous column,
+// instead of dropping its value.
+func DisallowUnmatched() DecodeOption {
+	return func(o *decodeOptions) {
+		o.disallowUnmatched = true
+	}
+}
+
+// DecodeReport describes how the columns of a row were matched to the fields of a struct.
+type DecodeReport struct {
+	// UnmatchedColumns are the columns that have no matching field. Their values were not decoded.
+	UnmatchedColumns []Column
+	// DuplicateColumns are the columns that matched a field that was already matched by a previous column.
+	// Their values were not decoded. Use a `kusto:"#<index>"` tag to decode them.
+	DuplicateColumns []Column
+	// UnmatchedFields are the names of the struct fields that have no matching column. They were left untouched.
+	UnmatchedFields []string
+}
+
+// Complete returns true if every column was decoded into a field.
+func (r *DecodeReport) Complete() bool {
+	return len(r.UnmatchedColumns) == 0 && len(r.DuplicateColumns) == 0
+}
+
+// decoder decodes rows with a fixed set of columns into a struct type.
+type decoder struct {
+	columns []Column
+	fields  []structField
+	// fieldForColumn is the index in fields of the field each column is decoded into, or -1.
+	fieldForColumn []int
+	report         *DecodeReport
+}
+
+// newDecoder matches columns to the fields of the struct type ptr points to.
+func newDecoder(cols []Column, ptr reflect.Type, options ...DecodeOption) (*decoder, error) {
+	opts := decodeOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	key := func(name string) string {
+		if opts.normalizeUnicode {
+			name = norm.NFC.String(name)
+		}
+		if opts.caseInsensitive {
+			name = strings.ToLower(name)
+		}
+		return name
+	}
+
+	fields := newFields(ptr).fields
+	byKey := make(map[string]int, len(fields))
+	byIndex := make(map[int]int)
+	// ambiguous holds, for the columns of two fields at the same depth, the second field.
+	ambiguous := make(map[string]int)
+	for i, f := range fields {
+		if f.columnIndex >= 0 {
+			byIndex[f.columnIndex] = i
+			continue
+		}
+		// As with Go's promoted fields, a field of an embedded struct is shadowed by a field of the same name in an outer
+		// struct, and fields at the same depth can't share a column.
+		k := key(f.column)
+		prev, ok := byKey[k]
+		switch {
+		case !ok || len(fields[prev].index) > len(f.index):
+			byKey[k] = i
+			delete(ambiguous, k)
+		case len(fields[prev].index) == len(f.index):
+			if _, ok := ambiguous[k]; !ok {
+				ambiguous[k] = i
+			}
+		}
+	}
+	for _, f := range fields {
+		if other, ok := ambiguous[key(f.column)]; ok && f.columnIndex < 0 {
+			return nil, kustoErrors.ES(kustoErrors.OpTableAccess, kustoErrors.KClientArgs, "fields %s.%s and %s.%s are both decoded from column %s",
+				ptr.Elem(), fields[byKey[key(f.column)]].name, ptr.Elem(), fields[other].name, f.column).SetNoRetry()
+		}
+	}
+
+	d := &decoder{
+		columns:        cols,
+		fields:         fields,
+		fieldForColumn: make([]int, len(cols)),
+		report:         &DecodeReport{},
+	}
+	matched := make([]bool, len(fields))
 
 	for i, col := range cols {
-		if err := fields.convert(col, row[i], v); err != nil {
-			return err
+		d.fieldForColumn[i] = -1
+
+		f, ok := byIndex[i]
+		if !ok {
+			f, ok = byKey[key(col.Name())]
+		}
+		if !ok {
+			d.report.UnmatchedColumns = append(d.report.UnmatchedColumns, col)
+			continue
+		}
+		if matched[f] {
+			d.report.DuplicateColumns = append(d.report.DuplicateColumns, col)
+			continue
+		}
+
+		matched[f] = true
+		d.fieldForColumn[i] = f
+	}
+
+	for i, f := range fields {
+		if !matched[i] {
+			d.report.UnmatchedFields = append(d.report.UnmatchedFields, f.name)
+		}
+	}
+
+	if opts.disallowUnmatched && !d.report.Complete() {
+		names := make([]string, 0, len(d.report.UnmatchedColumns)+len(d.report.DuplicateColumns))
+		for _, c := range d.report.UnmatchedColumns {
+			names = append(names, c.Name())
+		}
+		for _, c := range d.report.DuplicateColumns {
+			names = append(names, c.Name()+"#"+strconv.Itoa(c.Index()))
+		}
+		return nil, kustoErrors.ES(kustoErrors.OpTableAccess, kustoErrors.KClientArgs, "columns [%s] have no matching field in %s", strings.Join(names, ", "), ptr.Elem()).SetNoRetry()
+	}
+
+	return d, nil
+}
+
+// decode decodes a row into "p" which will be a pointer to a struct (enforce in the decoder).
+func (d *decoder) decode(row value.Values, p interface{}) error {
+	v := reflect.ValueOf(p).Elem()
+	for i, col := range d.columns {
+		f := d.fieldForColumn[i]
+		if f < 0 {
+			continue
+		}
+		field := d.fields[f]
+		if err := row[i].Convert(fieldByIndex(v, field.index)); err != nil {
+			return kustoErrors.ES(kustoErrors.OpTableAccess, kustoErrors.KWrongColumnType, "column %s could not store in struct.%s: %s", col.Name(), field.name, err.Error())
 		}
 	}
 	return nil
 }
 
-// newFields takes in the Columns from our row and the reflect.Type of our *struct.
+// fieldByIndex returns the field of the struct v with the index sequence index, allocating the nil pointers to
+// embedded or nested structs on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// decodeToStruct takes a list of columns and a row to decode into "p" which will be a pointer
+// to a struct (enforce in the decoder).
+func decodeToStruct(cols []Column, row value.Values, p interface{}, options ...DecodeOption) (*DecodeReport, error) {
+	d, err := newDecoder(cols, reflect.TypeOf(p), options...)
+	if err != nil {
+		return nil, err
+	}
+	return d.report, d.decode(row, p)
+}
+
+// newFields takes the reflect.Type of our *struct and returns its decodable fields.
+// The fields of embedded structs are promoted, as if they were fields of the outer struct. Nested structs with a
+// `kusto:"<prefix>_"` tag - a tag ending with an underscore - are flattened the same way, the names of the columns
+// of their fields being prefixed with the tag. Embedded structs can be given a prefix the same way.
 func newFields(ptr reflect.Type) fieldMap {
 	typeMapperLock.RLock()
 	f, ok := typeMapper[ptr]
@@ -40,35 +239,86 @@ func newFields(ptr reflect.Type) fieldMap {
 	} else {
 		typeMapperLock.Lock()
 		defer typeMapperLock.Unlock()
-		nFields := fieldMap{colNameToFieldName: make(map[string]string, ptr.Elem().NumField())}
-		for i := 0; i < ptr.Elem().NumField(); i++ {
-			field := ptr.Elem().Field(i)
-			if tag := field.Tag.Get("kusto"); strings.TrimSpace(tag) != "" {
-				nFields.colNameToFieldName[tag] = field.Name
-			} else {
-				nFields.colNameToFieldName[field.Name] = field.Name
-			}
-		}
+		nFields := fieldMap{fields: appendFields(nil, ptr.Elem(), nil, "", "", map[reflect.Type]bool{})}
 		typeMapper[ptr] = nFields
 		return nFields
 	}
 }
 
-// convert converts a KustoValue that is for Column col into "v" reflect.Value with reflect.Type "t".
-func (f fieldMap) convert(col Column, k value.Kusto, v reflect.Value) error {
-	fieldName, ok := f.colNameToFieldName[col.Name()]
-	if !ok {
-		return nil
+// appendFields appends the decodable fields of the struct type t to fields. index is the index sequence of t in the
+// outer struct, name the prefix of the names of its fields, and prefix the prefix of the names of their columns.
+// visiting are the types of the structs t is nested in, to stop at recursive types.
+func appendFields(fields []structField, t reflect.Type, index []int, name, prefix string, visiting map[reflect.Type]bool) []structField {
+	if visiting[t] {
+		return fields
 	}
+	visiting[t] = true
+	defer delete(visiting, t)
 
-	if fieldName == "-" {
-		return nil
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.TrimSpace(field.Tag.Get("kusto"))
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if nested, ok := flattened(field, tag); ok {
+			fields = appendFields(fields, nested, fieldIndex, name+field.Name+".", prefix+tag, visiting)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		sf := structField{name: name + field.Name, index: fieldIndex, column: prefix + field.Name, columnIndex: -1}
+		if index, ok := parseIndexTag(tag); ok {
+			sf.columnIndex = index
+		} else if tag != "" {
+			sf.column = prefix + tag
+		}
+		fields = append(fields, sf)
 	}
+	return fields
+}
 
-	err := k.Convert(v.Elem().FieldByName(fieldName))
-	if err != nil {
-		return kustoErrors.ES(kustoErrors.OpTableAccess, kustoErrors.KWrongColumnType, "column %s could not store in struct.%s: %s", col.Name(), fieldName, err.Error())
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+)
+
+// flattened returns the struct type of field if its fields are decoded from their own columns: if the field is an
+// embedded struct without a tag, or a struct with a `kusto:"<prefix>_"` tag. Other struct fields, such as time.Time
+// fields, are decoded from a single column.
+func flattened(field reflect.StructField, tag string) (reflect.Type, bool) {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t == decimalType {
+		return nil, false
+	}
+	if field.Anonymous && tag == "" {
+		// A nil pointer to an unexported struct can't be allocated.
+		if field.Type.Kind() == reflect.Ptr && !field.IsExported() {
+			return nil, false
+		}
+		return t, true
 	}
+	if field.IsExported() && strings.HasSuffix(tag, "_") {
+		return t, true
+	}
+	return nil, false
+}
 
-	return nil
+// parseIndexTag parses a `kusto:"#<index>"` tag, which selects a column by its index.
+func parseIndexTag(tag string) (int, bool) {
+	if !strings.HasPrefix(tag, "#") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(tag[1:])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
 }
@@ -0,0 +1,268 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRow(names []string, values value.Values) Row {
+	cols := make(Columns, len(names))
+	for i, n := range names {
+		cols[i] = NewColumn(i, n, values[i].GetType())
+	}
+	return NewRowFromParts(cols, func(name string) Column {
+		for _, c := range cols {
+			if c.Name() == name {
+				return c
+			}
+		}
+		return nil
+	}, 0, values)
+}
+
+func TestToStructWithReport(t *testing.T) {
+	t.Parallel()
+
+	type exact struct {
+		Name  string
+		Count int32
+	}
+	type tagged struct {
+		First  string `kusto:"Name"`
+		Second string `kusto:"#1"`
+		Ignore string `kusto:"-"`
+	}
+
+	// "é" as an "e" followed by a combining accent. The struct tags below use the precomposed "é".
+	decomposed := "cafe\u0301"
+
+	tests := []struct {
+		name        string
+		columns     []string
+		values      value.Values
+		options     []DecodeOption
+		dest        interface{}
+		want        interface{}
+		wantErr     bool
+		unmatched   []string
+		duplicates  []int
+		fieldsUnset []string
+	}{
+		{
+			name:    "exact",
+			columns: []string{"Name", "Count"},
+			values:  value.Values{value.NewString("a"), value.NewInt(1)},
+			dest:    &exact{},
+			want:    &exact{Name: "a", Count: 1},
+		},
+		{
+			name:        "case sensitive by default",
+			columns:     []string{"name", "Count"},
+			values:      value.Values{value.NewString("a"), value.NewInt(1)},
+			dest:        &exact{},
+			want:        &exact{Count: 1},
+			unmatched:   []string{"name"},
+			fieldsUnset: []string{"Name"},
+		},
+		{
+			name:    "case insensitive",
+			columns: []string{"name", "COUNT"},
+			values:  value.Values{value.NewString("a"), value.NewInt(1)},
+			options: []DecodeOption{CaseInsensitive()},
+			dest:    &exact{},
+			want:    &exact{Name: "a", Count: 1},
+		},
+		{
+			name:       "duplicates keep the first column",
+			columns:    []string{"Name", "Name", "Count"},
+			values:     value.Values{value.NewString("a"), value.NewString("b"), value.NewInt(1)},
+			dest:       &exact{},
+			want:       &exact{Name: "a", Count: 1},
+			duplicates: []int{1},
+		},
+		{
+			name:    "duplicates by index",
+			columns: []string{"Name", "Name", "Ignore"},
+			values:  value.Values{value.NewString("a"), value.NewString("b"), value.NewString("c")},
+			dest:    &tagged{},
+			want:    &tagged{First: "a", Second: "b"},
+			// Fields tagged with "-" are not decodable, so the column is unmatched.
+			unmatched: []string{"Ignore"},
+		},
+		{
+			name:    "unicode not normalized by default",
+			columns: []string{decomposed},
+			values:  value.Values{value.NewString("a")},
+			dest: &struct {
+				Name string `kusto:"café"`
+			}{},
+			want: &struct {
+				Name string `kusto:"café"`
+			}{},
+			unmatched:   []string{decomposed},
+			fieldsUnset: []string{"Name"},
+		},
+		{
+			name:    "unicode normalized",
+			columns: []string{decomposed},
+			values:  value.Values{value.NewString("a")},
+			options: []DecodeOption{NormalizeUnicode()},
+			dest: &struct {
+				Name string `kusto:"café"`
+			}{},
+			want: &struct {
+				Name string `kusto:"café"`
+			}{Name: "a"},
+		},
+		{
+			name:    "disallow unmatched",
+			columns: []string{"Name", "Other"},
+			values:  value.Values{value.NewString("a"), value.NewString("b")},
+			options: []DecodeOption{DisallowUnmatched()},
+			dest:    &exact{},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			report, err := ToStructWithReport(newTestRow(test.columns, test.values), test.dest, test.options...)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, test.dest)
+
+			var unmatched []string
+			for _, c := range report.UnmatchedColumns {
+				unmatched = append(unmatched, c.Name())
+			}
+			var duplicates []int
+			for _, c := range report.DuplicateColumns {
+				duplicates = append(duplicates, c.Index())
+			}
+			assert.Equal(t, test.unmatched, unmatched)
+			assert.Equal(t, test.duplicates, duplicates)
+			assert.Equal(t, test.fieldsUnset, report.UnmatchedFields)
+			assert.Equal(t, len(unmatched) == 0 && len(duplicates) == 0, report.Complete())
+		})
+	}
+}
+
+type Audit struct {
+	Created time.Time
+	Name    string
+}
+
+type address struct {
+	City string
+	Zip  string `kusto:"PostalCode"`
+}
+
+type nestedRec struct {
+	*Audit
+	Name    string
+	Home    address  `kusto:"Home_"`
+	Work    *address `kusto:"Work_"`
+	Details address
+}
+
+func TestToStructNested(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	details := value.NewDynamic([]byte(`{"City":"Paris"}`))
+	row := newTestRow(
+		[]string{"Created", "Name", "Home_City", "Home_PostalCode", "Work_City", "Details", "Other"},
+		value.Values{value.NewDateTime(now), value.NewString("name"), value.NewString("Seattle"), value.NewString("98101"),
+			value.NewString("Redmond"), details, value.NewString("other")},
+	)
+
+	got := &nestedRec{}
+	report, err := ToStructWithReport(row, got)
+	require.NoError(t, err)
+	// Name is shadowed by the field of the outer struct, like in Go, and structs without a prefix tag are decoded from
+	// a single column.
+	assert.Equal(t, &nestedRec{
+		Audit:   &Audit{Created: now},
+		Name:    "name",
+		Home:    address{City: "Seattle", Zip: "98101"},
+		Work:    &address{City: "Redmond"},
+		Details: address{City: "Paris"},
+	}, got)
+	require.Len(t, report.UnmatchedColumns, 1)
+	assert.Equal(t, "Other", report.UnmatchedColumns[0].Name())
+	assert.Equal(t, []string{"Audit.Name", "Work.Zip"}, report.UnmatchedFields)
+
+	type recursive struct {
+		Name string
+		Next *recursive `kusto:"Next_"`
+	}
+	rec := &recursive{}
+	require.NoError(t, newTestRow([]string{"Name", "Next_Name"}, value.Values{value.NewString("a"), value.NewString("b")}).ToStruct(rec))
+	// A struct is not flattened into itself, so Next_Name has no matching field.
+	assert.Equal(t, &recursive{Name: "a"}, rec)
+
+	// Fields at the same depth can't share a column, unless a field of an outer struct shadows them.
+	type other struct {
+		Name string
+	}
+	type ambiguous struct {
+		Audit
+		other
+	}
+	nameRow := newTestRow([]string{"Name"}, value.Values{value.NewString("a")})
+	err = nameRow.ToStruct(&ambiguous{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fields query.ambiguous.Audit.Name and query.ambiguous.other.Name")
+	_, err = ToStructs[ambiguous]([]Row{nameRow})
+	assert.Error(t, err)
+
+	type shadowed struct {
+		Audit
+		other
+		Name string
+	}
+	got2 := &shadowed{}
+	require.NoError(t, nameRow.ToStruct(got2))
+	assert.Equal(t, "a", got2.Name)
+}
+
+func TestToStructsWithReport(t *testing.T) {
+	t.Parallel()
+
+	type rec struct {
+		A int32
+	}
+
+	cols := Columns{NewColumn(0, "A", types.Int), NewColumn(1, "B", types.String)}
+	byName := func(string) Column { return nil }
+	rows := []Row{
+		NewRowFromParts(cols, byName, 0, value.Values{value.NewInt(1), value.NewString("x")}),
+		NewRowFromParts(cols, byName, 1, value.Values{value.NewInt(2), value.NewString("y")}),
+	}
+
+	out, report, err := ToStructsWithReport[rec](rows)
+	require.NoError(t, err)
+	assert.Equal(t, []rec{{A: 1}, {A: 2}}, out)
+	require.Len(t, report.UnmatchedColumns, 1)
+	assert.Equal(t, "B", report.UnmatchedColumns[0].Name())
+
+	_, err = ToStructs[rec](rows, DisallowUnmatched())
+	assert.Error(t, err)
+
+	// T must be a struct: a decoder isn't built for other types.
+	_, err = ToStructs[int](rows)
+	assert.Error(t, err)
+	_, _, err = ToStructsWithReport[string](rows)
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+type ndjsonOptions struct {
+	flushEvery int
+}
+
+// NDJSONOption is an option for WriteNDJSON.
+type NDJSONOption func(o *ndjsonOptions)
+
+// FlushEvery flushes the written rows to the writer every n rows, so that the consumer receives them as they are read.
+// By default, rows are buffered and written in large chunks, and flushed at the end of every table.
+// Flushing also calls the Flush method of the writer, if it has one - as http.ResponseWriter, bufio.Writer and
+// gzip.Writer do.
+func FlushEvery(rows int) NDJSONOption {
+	return func(o *ndjsonOptions) {
+		o.flushEvery = rows
+	}
+}
+
+// WriteNDJSON writes the rows of the primary result tables of the dataset to w as NDJSON: every row as a JSON object on
+// its own line, with the names of the columns as keys. The values are converted to their JSON types as with
+// ExportToFile and ExportJSON - dynamics are written as is, numbers as numbers, and the other types as their text.
+// The other tables are skipped. The dataset is closed when WriteNDJSON returns.
+// It returns the number of rows written, and stops at the first error of the dataset or of w.
+func WriteNDJSON(dataset IterativeDataset, w io.Writer, options ...NDJSONOption) (int64, error) {
+	defer dataset.Close()
+
+	opts := ndjsonOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.flushEvery < 0 {
+		return 0, errors.ES(errors.OpTableAccess, errors.KClientArgs, "FlushEvery() requires a positive number of rows, got %d", opts.flushEvery).SetNoRetry()
+	}
+
+	nw := &ndjsonWriter{w: w, buf: bufio.NewWriter(w), flushEvery: opts.flushEvery}
+	for tr := range dataset.Tables() {
+		if tr.Err() != nil {
+			return nw.rows, tr.Err()
+		}
+		table := tr.Table()
+		if !table.IsPrimaryResult() {
+			if err := skipRows(table); err != nil {
+				return nw.rows, err
+			}
+			continue
+		}
+		if err := nw.write(table); err != nil {
+			return nw.rows, err
+		}
+	}
+	return nw.rows, nil
+}
+
+// ndjsonWriter writes the rows of tables to w.
+type ndjsonWriter struct {
+	w          io.Writer
+	buf        *bufio.Writer
+	flushEvery int
+	rows       int64
+}
+
+func (nw *ndjsonWriter) write(table IterativeTable) error {
+	names := make([]string, 0, len(table.Columns()))
+	for _, c := range table.Columns() {
+		names = append(names, c.Name())
+	}
+
+	for rr := range table.Rows() {
+		if rr.Err() != nil {
+			// The rows read so far are complete, so they are written before failing.
+			_ = nw.flush()
+			return rr.Err()
+		}
+		if err := writeJSONRow(nw.buf, names, rr.Row().Values()); err != nil {
+			return errors.ES(errors.OpTableAccess, errors.KIO, "could not write the NDJSON rows: %s", err).SetNoRetry()
+		}
+		nw.rows++
+
+		if nw.flushEvery > 0 && nw.rows%int64(nw.flushEvery) == 0 {
+			if err := nw.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nw.flush()
+}
+
+// flush writes the buffered rows to w, and flushes w if it can be.
+func (nw *ndjsonWriter) flush() error {
+	if err := nw.buf.Flush(); err != nil {
+		return errors.ES(errors.OpTableAccess, errors.KIO, "could not write the NDJSON rows: %s", err).SetNoRetry()
+	}
+	switch f := nw.w.(type) {
+	case interface{ Flush() error }:
+		if err := f.Flush(); err != nil {
+			return errors.ES(errors.OpTableAccess, errors.KIO, "could not flush the NDJSON rows: %s", err).SetNoRetry()
+		}
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -34,6 +34,9 @@ type Row interface {
 	// String returns a string representation of the row.
 	String() string
 
+	// The typed getters return an error if the column is not of the type. Null values are returned as nil pointers,
+	// and as nil slices for dynamics. Kusto strings are never null, so StringByIndex and StringByName return an empty
+	// string for strings that were not set.
 	BoolByIndex(i int) (*bool, error)
 	IntByIndex(i int) (*int32, error)
 	LongByIndex(i int) (*int64, error)
@@ -56,3 +59,21 @@ type Row interface {
 	TimespanByName(name string) (*time.Duration, error)
 	GuidByName(name string) (*uuid.UUID, error)
 }
+
+// NullChecker checks whether the values of a row are null. The rows of this package implement it, and other
+// implementations of Row don't need to: type-assert rows to it, or use value.IsNull on their values.
+type NullChecker interface {
+	// IsNull reports whether the value at the specified index is null. See value.IsNull.
+	IsNull(i int) (bool, error)
+
+	// IsNullByName reports whether the value with the specified column name is null. See value.IsNull.
+	IsNullByName(name string) (bool, error)
+}
+
+// Cloner copies rows. The rows of this package implement it, and other implementations of Row don't need to:
+// type-assert rows to it to keep them past their reuse, see v2.ReuseRows.
+type Cloner interface {
+	// Clone returns a copy of the row that stays valid after the row is reused.
+	// The cells of lazy rows are decoded by the copy, with the cells that fail to decode copied as nulls.
+	Clone() Row
+}
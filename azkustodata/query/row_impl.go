@@ -17,6 +17,8 @@ type row struct {
 	columnByName func(string) Column
 	values       value.Values
 	ordinal      int
+	// lazy holds the cells that aren't decoded yet, for rows created with NewLazyRow.
+	lazy *lazyCells
 }
 
 func NewRow(t BaseTable, ordinal int, values value.Values) Row {
@@ -41,6 +43,9 @@ func (r *row) Index() int {
 }
 
 func (r *row) Values() value.Values {
+	if r.lazy != nil {
+		r.lazy.decodeAll(r)
+	}
 	return r.values
 }
 
@@ -49,6 +54,11 @@ func (r *row) Value(i int) (value.Kusto, error) {
 		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "index %d out of range", i)
 	}
 
+	if r.lazy != nil {
+		if err := r.lazy.decode(r, i); err != nil {
+			return nil, err
+		}
+	}
 	return r.values[i], nil
 }
 
@@ -71,23 +81,75 @@ func (r *row) ValueByName(name string) (value.Kusto, error) {
 //     'column_name' into the field. A special case is the `column_name: "-"`
 //     tag, which instructs ToStruct to ignore the field during decoding.
 //
-//  2. Otherwise, if the name of a field matches the name of a column (ignoring case),
-//     decode the column into the field.
+//  2. If a field has a `kusto: "#<index>"` tag, then decode the column at that index into the field.
+//     This disambiguates columns that share the same name.
+//
+//  3. Otherwise, if the name of a field matches the name of a column, decode the column into the field.
+//     Use ToStructWithReport with the CaseInsensitive or NormalizeUnicode options for looser matching.
+//
+// If several columns match the same field, only the first one is decoded. Columns with no matching field are ignored,
+// use ToStructWithReport to find out which columns were not decoded.
 //
 // Slice and pointer fields will be set to nil if the source column is a null value, and a
 // non-nil value if the column is not NULL. To decode NULL values of other types, use
 // one of the kusto types (Int, Long, Dynamic, ...) as the type of the destination field.
 // You can check the .Valid field of those types to see if the value was set.
 func (r *row) ToStruct(p interface{}) error {
-	// Check if p is a pointer to a struct
+	if err := checkStructPointer(p); err != nil {
+		return err
+	}
+	if len(r.Columns()) != len(r.Values()) {
+		return errors.ES(errors.OpTableAccess, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values()), len(r.Columns()))
+	}
+
+	_, err := decodeToStruct(r.Columns(), r.Values(), p)
+	return err
+}
+
+// checkStructPointer returns a KClientArgs error if p is not a pointer to a struct.
+func checkStructPointer(p interface{}) error {
 	if t := reflect.TypeOf(p); t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
 		return errors.ES(errors.OpTableAccess, errors.KClientArgs, "type %T is not a pointer to a struct", p)
 	}
+	return nil
+}
+
+// ToStructWithReport decodes a row into a struct like Row.ToStruct, with the given options, and returns a report of the
+// columns and fields that could not be matched.
+func ToStructWithReport(r Row, p interface{}, options ...DecodeOption) (*DecodeReport, error) {
+	if err := checkStructPointer(p); err != nil {
+		return nil, err
+	}
 	if len(r.Columns()) != len(r.Values()) {
-		return errors.ES(errors.OpTableAccess, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values()), len(r.Columns()))
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values()), len(r.Columns()))
+	}
+
+	return decodeToStruct(r.Columns(), r.Values(), p, options...)
+}
+
+// Clone implements Cloner. The values are copied shallowly: unmarshalling a value replaces the data it points to
+// instead of writing over it, so the copies keep their data when the values of the row are reused.
+func (r *row) Clone() Row {
+	values := r.Values()
+	clone := make(value.Values, len(values))
+	for i, v := range values {
+		clone[i] = cloneValue(v)
 	}
+	return NewRowFromParts(r.columns, r.columnByName, r.ordinal, clone)
+}
 
-	return decodeToStruct(r.Columns(), r.Values(), p)
+// cloneValue returns a shallow copy of v, which is a pointer to a value struct.
+func cloneValue(v value.Kusto) value.Kusto {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return v
+	}
+	c := reflect.New(rv.Elem().Type())
+	c.Elem().Set(rv.Elem())
+	return c.Interface().(value.Kusto)
 }
 
 // String implements fmt.Stringer for a Row. This simply outputs a CSV version of the row.
@@ -114,6 +176,24 @@ func columnNotFoundError(name string) error {
 	return errors.ES(errors.OpTableAccess, errors.KOther, "column %s not found", name)
 }
 
+// IsNull implements NullChecker.IsNull.
+func (r *row) IsNull(i int) (bool, error) {
+	val, err := r.Value(i)
+	if err != nil {
+		return false, err
+	}
+	return value.IsNull(val), nil
+}
+
+// IsNullByName implements NullChecker.IsNullByName.
+func (r *row) IsNullByName(name string) (bool, error) {
+	val, err := r.ValueByName(name)
+	if err != nil {
+		return false, err
+	}
+	return value.IsNull(val), nil
+}
+
 // contains all types *bool, etc
 type kustoTypeGeneric interface {
 	*bool | *int32 | *int64 | *float64 | *decimal.Decimal | string | interface{} | *time.Time | *time.Duration
@@ -221,10 +301,43 @@ func (r *row) GuidByName(name string) (*uuid.UUID, error) {
 
 // ToStructs converts a table, a non-iterative dataset or a slice of rows into a slice of structs.
 // If a dataset is provided, it should contain exactly one table.
-func ToStructs[T any](data interface{}) ([]T, error) {
-	var rows []Row
+func ToStructs[T any](data interface{}, options ...DecodeOption) ([]T, error) {
+	out, _, err := ToStructsWithReport[T](data, options...)
+	return out, err
+}
+
+// ToStructsWithReport converts data into a slice of structs like ToStructs, and returns a report of the columns and
+// fields that could not be matched. The report is nil if there are no rows.
+func ToStructsWithReport[T any](data interface{}, options ...DecodeOption) ([]T, *DecodeReport, error) {
 	var errs error
 
+	rows, err := rowsOf(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rows == nil || len(rows) == 0 {
+		return nil, nil, errs
+	}
+
+	out := make([]T, len(rows))
+	cache := decoderCache{options: options}
+	for i, r := range rows {
+		if err := cache.decode(r, &out[i]); err != nil {
+			out = out[:i]
+			if len(out) == 0 {
+				out = nil
+			}
+			return out, cache.report, err
+		}
+	}
+
+	return out, cache.report, errs
+}
+
+// rowsOf returns the rows of a table, a non-iterative dataset with a primary result, a slice of rows or a row.
+func rowsOf(data interface{}) ([]Row, error) {
+	var rows []Row
 	switch v := data.(type) {
 	case Table:
 		rows = v.Rows()
@@ -251,22 +364,49 @@ func ToStructs[T any](data interface{}) ([]T, error) {
 		return nil, errors.ES(errors.OpUnknown, errors.KInternal, "invalid data type - expected Dataset, Table, BaseTable or []Row")
 	}
 
-	if rows == nil || len(rows) == 0 {
-		return nil, errs
+	return rows, nil
+}
+
+// decoderCache reuses the decoder of the previous row when rows share the same columns, which is the case for all the
+// rows of a table. The report of the first decoder is kept.
+type decoderCache struct {
+	options []DecodeOption
+	decoder *decoder
+	report  *DecodeReport
+}
+
+func (c *decoderCache) decode(r Row, p interface{}) error {
+	if err := checkStructPointer(p); err != nil {
+		return err
+	}
+	if len(r.Columns()) != len(r.Values()) {
+		return errors.ES(errors.OpTableAccess, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values()), len(r.Columns()))
 	}
 
-	out := make([]T, len(rows))
-	for i, r := range rows {
-		if err := r.ToStruct(&out[i]); err != nil {
-			out = out[:i]
-			if len(out) == 0 {
-				out = nil
-			}
-			return out, err
+	if c.decoder == nil || !sameColumns(c.decoder.columns, r.Columns()) {
+		d, err := newDecoder(r.Columns(), reflect.TypeOf(p), c.options...)
+		if err != nil {
+			return err
+		}
+		c.decoder = d
+		if c.report == nil {
+			c.report = d.report
 		}
 	}
 
-	return out, errs
+	return c.decoder.decode(r.Values(), p)
+}
+
+func sameColumns(a, b []Column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 type StructResult[T any] struct {
@@ -274,17 +414,18 @@ type StructResult[T any] struct {
 	Err error
 }
 
-func ToStructsIterative[T any](tb IterativeTable) chan StructResult[T] {
+func ToStructsIterative[T any](tb IterativeTable, options ...DecodeOption) chan StructResult[T] {
 	out := make(chan StructResult[T])
 
 	go func() {
 		defer close(out)
+		cache := decoderCache{options: options}
 		for rowResult := range tb.Rows() {
 			if rowResult.Err() != nil {
 				out <- StructResult[T]{Err: rowResult.Err()}
 			} else {
 				var s T
-				if err := rowResult.Row().ToStruct(&s); err != nil {
+				if err := cache.decode(rowResult.Row(), &s); err != nil {
 					out <- StructResult[T]{Err: err}
 				} else {
 					out <- StructResult[T]{Out: s}
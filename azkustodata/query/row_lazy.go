@@ -0,0 +1,78 @@
+package query
+
+import (
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// lazyCells holds the raw JSON values of the cells of a row, which are converted to Kusto values on first access.
+type lazyCells struct {
+	mu sync.Mutex
+	// raw are the raw values of the cells, as returned by a json.Decoder with UseNumber. A cell's raw value is released
+	// once it is decoded.
+	raw     []interface{}
+	decoded []bool
+	// errs are the errors of the cells that failed to decode, if any did.
+	errs []error
+}
+
+// NewLazyRow creates a row whose cells are kept as raw JSON values, as returned by a json.Decoder with UseNumber, and
+// converted to Kusto values only when they are accessed. Decoded values are cached, so every cell is decoded at most
+// once. This saves time and memory for wide rows of which only a few columns are read.
+// A cell that fails to decode is returned as an error by Value, ValueByName and the typed getters, and as a null
+// value of its column type by Values.
+func NewLazyRow(c Columns, columnByName func(string) Column, ordinal int, raw []interface{}) Row {
+	return &row{
+		columns:      c,
+		columnByName: columnByName,
+		ordinal:      ordinal,
+		values:       make(value.Values, len(raw)),
+		lazy: &lazyCells{
+			raw:     raw,
+			decoded: make([]bool, len(raw)),
+		},
+	}
+}
+
+// decode decodes the cell at index i into the values of r, if it isn't decoded yet, and returns its error if it failed.
+func (l *lazyCells) decode(r *row, i int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.decoded[i] {
+		l.decodeLocked(r, i)
+	}
+	if l.errs != nil {
+		return l.errs[i]
+	}
+	return nil
+}
+
+// decodeAll decodes all the cells that aren't decoded yet into the values of r.
+func (l *lazyCells) decodeAll(r *row) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.raw {
+		if !l.decoded[i] {
+			l.decodeLocked(r, i)
+		}
+	}
+}
+
+func (l *lazyCells) decodeLocked(r *row, i int) {
+	column := r.columns[i]
+	v := value.Default(column.Type())
+	if err := v.Unmarshal(l.raw[i]); err != nil {
+		if l.errs == nil {
+			l.errs = make([]error, len(l.raw))
+		}
+		l.errs[i] = errors.Cell(errors.OpTableAccess, "", r.ordinal, column.Name(), err)
+		v = value.Default(column.Type())
+	}
+	r.values[i] = v
+	l.raw[i] = nil
+	l.decoded[i] = true
+}
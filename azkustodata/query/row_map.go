@@ -0,0 +1,157 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DecodeOptions controls how ToMap and ToMaps decode rows into maps.
+type DecodeOptions struct {
+	// OmitNulls leaves the columns with null values out of the map, instead of setting them to nil.
+	OmitNulls bool
+	// RawDynamic keeps dynamic values as their JSON, in a json.RawMessage, instead of decoding them.
+	RawDynamic bool
+	// JSONNumbers keeps the numbers of decoded dynamic values as json.Number, instead of int64 for integers that fit
+	// in one and float64 for the others.
+	JSONNumbers bool
+}
+
+// ToMap decodes a row into a map of the names of its columns to their values, for code that doesn't know the schema
+// of the rows at compile time. The values have the Go type of their column, and never change type with their content:
+//
+//	bool      bool
+//	int       int32
+//	long      int64
+//	real      float64
+//	decimal   decimal.Decimal
+//	string    string
+//	datetime  time.Time
+//	timespan  time.Duration
+//	guid      uuid.UUID
+//	dynamic   the decoded JSON: nil, bool, string, int64 or float64, []interface{} or map[string]interface{}
+//
+// Null values are nil. If several columns share the same name, only the first one is decoded.
+func ToMap(r Row, opts DecodeOptions) (map[string]interface{}, error) {
+	if len(r.Columns()) != len(r.Values()) {
+		return nil, errors.ES(errors.OpTableAccess, errors.KClientArgs, "row does not have the correct number of values(%d) for the number of columns(%d)", len(r.Values()), len(r.Columns()))
+	}
+
+	out := make(map[string]interface{}, len(r.Columns()))
+	for i, col := range r.Columns() {
+		if _, ok := out[col.Name()]; ok {
+			continue
+		}
+
+		v, err := mapValue(r.Values()[i], opts)
+		if err != nil {
+			return nil, errors.ES(errors.OpTableAccess, errors.KFailedToParse, "column %s of type %s could not be decoded: %s", col.Name(), col.Type(), err)
+		}
+		if v == nil && opts.OmitNulls {
+			continue
+		}
+		out[col.Name()] = v
+	}
+	return out, nil
+}
+
+// ToMaps decodes a table, a non-iterative dataset, a slice of rows or a row into maps, like ToMap.
+// If a dataset is provided, its first table should be a primary result.
+func ToMaps(data interface{}, opts DecodeOptions) ([]map[string]interface{}, error) {
+	rows, err := rowsOf(data)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	out := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		if out[i], err = ToMap(r, opts); err != nil {
+			return out[:i], err
+		}
+	}
+	return out, nil
+}
+
+// mapValue returns the value of v for ToMap.
+func mapValue(v value.Kusto, opts DecodeOptions) (interface{}, error) {
+	if value.IsNull(v) {
+		return nil, nil
+	}
+
+	switch val := v.GetValue().(type) {
+	case string:
+		return val, nil
+	case []byte:
+		if len(val) == 0 {
+			return nil, nil
+		}
+		if opts.RawDynamic {
+			return json.RawMessage(val), nil
+		}
+		return decodeDynamic(val, opts.JSONNumbers)
+	case *bool:
+		return *val, nil
+	case *int32:
+		return *val, nil
+	case *int64:
+		return *val, nil
+	case *float64:
+		return *val, nil
+	case *decimal.Decimal:
+		return *val, nil
+	case *time.Time:
+		return *val, nil
+	case *time.Duration:
+		return *val, nil
+	case *uuid.UUID:
+		return *val, nil
+	}
+	return nil, errors.ES(errors.OpTableAccess, errors.KWrongColumnType, "unsupported value of type %T", v)
+}
+
+// decodeDynamic decodes the JSON of a dynamic value. Numbers are kept as json.Number until they are converted, so that
+// integers don't lose precision as float64.
+func decodeDynamic(b []byte, jsonNumbers bool) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.ES(errors.OpTableAccess, errors.KFailedToParse, "dynamic value has data after its JSON value")
+	}
+
+	if jsonNumbers {
+		return v, nil
+	}
+	return convertNumbers(v), nil
+}
+
+// convertNumbers replaces the json.Number values in v with int64 for integers that fit in one, and float64 otherwise.
+func convertNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case []interface{}:
+		for i := range val {
+			val[i] = convertNumbers(val[i])
+		}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = convertNumbers(val[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMap(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	id := uuid.New()
+	cols := Columns{
+		NewColumn(0, "Bool", types.Bool),
+		NewColumn(1, "Int", types.Int),
+		NewColumn(2, "Long", types.Long),
+		NewColumn(3, "Real", types.Real),
+		NewColumn(4, "Decimal", types.Decimal),
+		NewColumn(5, "String", types.String),
+		NewColumn(6, "DateTime", types.DateTime),
+		NewColumn(7, "Timespan", types.Timespan),
+		NewColumn(8, "Guid", types.GUID),
+		NewColumn(9, "Dynamic", types.Dynamic),
+		NewColumn(10, "Null", types.Long),
+		NewColumn(11, "Long", types.String),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	row := NewRow(base, 0, value.Values{
+		value.NewBool(true),
+		value.NewInt(1),
+		value.NewLong(9007199254740993),
+		value.NewReal(1),
+		value.NewDecimal(decimal.RequireFromString("1.10")),
+		value.NewString("a"),
+		value.NewDateTime(now),
+		value.NewTimespan(time.Minute),
+		value.NewGUID(id),
+		value.NewDynamic([]byte(`{"big":9007199254740993,"real":1.5,"list":[1,"b",null]}`)),
+		value.NewNullLong(),
+		value.NewString("shadowed"),
+	})
+
+	m, err := ToMap(row, DecodeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Bool":     true,
+		"Int":      int32(1),
+		"Long":     int64(9007199254740993),
+		"Real":     float64(1),
+		"Decimal":  decimal.RequireFromString("1.10"),
+		"String":   "a",
+		"DateTime": now,
+		"Timespan": time.Minute,
+		"Guid":     id,
+		"Dynamic": map[string]interface{}{
+			"big":  int64(9007199254740993),
+			"real": 1.5,
+			"list": []interface{}{int64(1), "b", nil},
+		},
+		"Null": nil,
+	}, m)
+
+	m, err = ToMap(row, DecodeOptions{OmitNulls: true, JSONNumbers: true})
+	require.NoError(t, err)
+	assert.NotContains(t, m, "Null")
+	assert.Equal(t, json.Number("9007199254740993"), m["Dynamic"].(map[string]interface{})["big"])
+
+	m, err = ToMap(row, DecodeOptions{RawDynamic: true})
+	require.NoError(t, err)
+	assert.Equal(t, json.RawMessage(`{"big":9007199254740993,"real":1.5,"list":[1,"b",null]}`), m["Dynamic"])
+}
+
+func TestToMaps(t *testing.T) {
+	t.Parallel()
+
+	cols := Columns{NewColumn(0, "Dynamic", types.Dynamic)}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	tb := NewTable(base, []Row{
+		NewRow(base, 0, value.Values{value.NewDynamic([]byte(`[1]`))}),
+		NewRow(base, 1, value.Values{value.NewNullDynamic()}),
+		NewRow(base, 2, value.Values{value.NewDynamic([]byte(`{`))}),
+	})
+
+	maps, err := ToMaps(tb, DecodeOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"Dynamic": []interface{}{int64(1)}},
+		{"Dynamic": nil},
+	}, maps)
+
+	maps, err = ToMaps(tb.Rows()[:1], DecodeOptions{})
+	require.NoError(t, err)
+	assert.Len(t, maps, 1)
+
+	_, err = ToMaps("not rows", DecodeOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// RowBuffer decodes raw rows into rows that are reused, instead of allocating a row, its values and a Kusto value per
+// cell for every row. It alternates between two rows, so a row returned by Decode stays valid until Decode is called
+// twice more. Use Cloner.Clone to keep a row for longer.
+type RowBuffer struct {
+	rows [2]*row
+	next int
+}
+
+// NewRowBuffer creates a RowBuffer for the rows of a table with the given columns.
+func NewRowBuffer(c Columns, columnByName func(string) Column) *RowBuffer {
+	b := &RowBuffer{}
+	for i := range b.rows {
+		values := make(value.Values, len(c))
+		for j, col := range c {
+			values[j] = value.Default(col.Type())
+		}
+		b.rows[i] = &row{columns: c, columnByName: columnByName, values: values}
+	}
+	return b
+}
+
+// Decode decodes raw, the cells of a row as returned by a json.Decoder with UseNumber, into the next reused row.
+func (b *RowBuffer) Decode(ordinal int, raw []interface{}) (Row, error) {
+	r := b.rows[b.next]
+	if len(raw) != len(r.values) {
+		return nil, errors.ES(errors.OpQuery, errors.KInternal, "row %d has %d values, expected %d", ordinal, len(raw), len(r.values))
+	}
+	for i, cell := range raw {
+		if err := r.values[i].Unmarshal(cell); err != nil {
+			return nil, errors.Cell(errors.OpQuery, "", ordinal, r.columns[i].Name(), err)
+		}
+	}
+	r.ordinal = ordinal
+	b.next = 1 - b.next
+	return r, nil
+}
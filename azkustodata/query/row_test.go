@@ -0,0 +1,116 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowIsNull(t *testing.T) {
+	t.Parallel()
+
+	cols := Columns{
+		NewColumn(0, "Count", types.Long),
+		NewColumn(1, "Name", types.String),
+		NewColumn(2, "Props", types.Dynamic),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	nulls := NewRow(base, 0, value.Values{value.NewNullLong(), value.NewString(""), value.NewNullDynamic()})
+	zeros := NewRow(base, 1, value.Values{value.NewLong(0), value.NewString(""), value.NewDynamic([]byte("{}"))})
+
+	for i, want := range []bool{true, false, true} {
+		isNull, err := nulls.(NullChecker).IsNull(i)
+		require.NoError(t, err)
+		assert.Equal(t, want, isNull, "column %d", i)
+
+		isNull, err = zeros.(NullChecker).IsNullByName(cols[i].Name())
+		require.NoError(t, err)
+		assert.False(t, isNull, "column %d", i)
+	}
+
+	count, err := nulls.LongByName("Count")
+	require.NoError(t, err)
+	assert.Nil(t, count)
+	count, err = zeros.LongByName("Count")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), *count)
+
+	props, err := nulls.DynamicByIndex(2)
+	require.NoError(t, err)
+	assert.Nil(t, props)
+
+	_, err = nulls.(NullChecker).IsNull(3)
+	assert.Error(t, err)
+	_, err = nulls.(NullChecker).IsNullByName("Missing")
+	assert.Error(t, err)
+}
+
+func TestLazyRow(t *testing.T) {
+	t.Parallel()
+
+	cols := Columns{
+		NewColumn(0, "Count", types.Long),
+		NewColumn(1, "Name", types.String),
+		NewColumn(2, "Bad", types.Int),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	r := NewLazyRow(cols, base.ColumnByName, 0, []interface{}{json.Number("5"), "a", "not an int"})
+
+	count, err := r.LongByName("Count")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *count)
+	name, err := r.Value(1)
+	require.NoError(t, err)
+	assert.Equal(t, "a", name.String())
+
+	_, err = r.Value(2)
+	assert.Error(t, err)
+	_, err = r.IntByName("Bad")
+	assert.Error(t, err)
+	assert.Equal(t, value.Values{value.NewLong(5), value.NewString("a"), value.NewNullInt()}, r.Values())
+
+	_, err = r.Value(3)
+	assert.Error(t, err)
+}
+
+func TestRowBuffer(t *testing.T) {
+	t.Parallel()
+
+	cols := Columns{
+		NewColumn(0, "Count", types.Long),
+		NewColumn(1, "Name", types.String),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	b := NewRowBuffer(cols, base.ColumnByName)
+
+	first, err := b.Decode(0, []interface{}{json.Number("1"), "a"})
+	require.NoError(t, err)
+	kept := first.(Cloner).Clone()
+	second, err := b.Decode(1, []interface{}{nil, "b"})
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, value.Values{value.NewLong(1), value.NewString("a")}, first.Values())
+
+	// The third row reuses the first.
+	third, err := b.Decode(2, []interface{}{json.Number("3"), "c"})
+	require.NoError(t, err)
+	assert.Same(t, first, third)
+	assert.Equal(t, 2, third.Index())
+	assert.Equal(t, value.Values{value.NewLong(3), value.NewString("c")}, third.Values())
+	assert.Equal(t, value.Values{value.NewNullLong(), value.NewString("b")}, second.Values())
+
+	assert.Equal(t, 0, kept.Index())
+	assert.Equal(t, value.Values{value.NewLong(1), value.NewString("a")}, kept.Values())
+	name, err := kept.StringByName("Name")
+	require.NoError(t, err)
+	assert.Equal(t, "a", name)
+
+	_, err = b.Decode(3, []interface{}{"not a long", "d"})
+	assert.Error(t, err)
+	_, err = b.Decode(3, []interface{}{json.Number("4")})
+	assert.Error(t, err)
+}
@@ -3,7 +3,10 @@ package query
 import "github.com/Azure/azure-kusto-go/azkustodata/errors"
 
 type BaseTable interface {
+	// Id returns the id of the table: the TableId as a string in v2, and the id of the table of contents in v1.
 	Id() string
+	// Index returns the TableId of the table in v2, and its ordinal in the table of contents in v1. It identifies the
+	// table in its dataset, see TableByID.
 	Index() int64
 	Name() string
 	Columns() []Column
@@ -18,6 +21,43 @@ type Table interface {
 	Rows() []Row
 }
 
+// TableSizer is implemented by the tables of this package, which cache their size. Other implementations of Table don't
+// need to: use TableDecodedSize to read it.
+type TableSizer interface {
+	// RowCount returns the number of rows of the table.
+	RowCount() int
+	// DecodedSize returns the approximate size of the decoded values of the table, in bytes: the length of the strings
+	// and the dynamic values, and the size of the other types. It doesn't count the overhead of the rows and the
+	// values, and is meant to enforce result size budgets and to log the magnitude of results.
+	DecodedSize() int64
+}
+
+// TableDecodedSize returns the approximate size of the decoded values of t, see TableSizer.DecodedSize. It is computed
+// from the rows of the tables that don't implement TableSizer.
+func TableDecodedSize(t Table) int64 {
+	if s, ok := t.(TableSizer); ok {
+		return s.DecodedSize()
+	}
+	return rowsDecodedSize(t.Rows())
+}
+
+// OrdinalReporter is implemented by the tables of this package, which know their position among the primary results of
+// their dataset. Other implementations of BaseTable don't need to: use TableOrdinal to read it.
+type OrdinalReporter interface {
+	// Ordinal returns the position of the table among the primary results of its dataset, starting at 0, or -1 for the
+	// other tables or if it isn't known. See PrimaryByOrdinal.
+	Ordinal() int
+}
+
+// TableOrdinal returns the position of t among the primary results of its dataset, starting at 0, or -1 for the other
+// tables or if it isn't known, such as for tables that don't implement OrdinalReporter.
+func TableOrdinal(t BaseTable) int {
+	if o, ok := t.(OrdinalReporter); ok {
+		return o.Ordinal()
+	}
+	return -1
+}
+
 // IterativeTable is a table that returns rows one at a time.
 type IterativeTable interface {
 	BaseTable
@@ -1,12 +1,17 @@
 package query
 
 import (
+	"sync"
+
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
 )
 
 type baseTable struct {
 	dataSet       BaseDataset
 	index         int64
+	ordinal       int
 	id            string
 	name          string
 	kind          string
@@ -14,10 +19,18 @@ type baseTable struct {
 	columnsByName map[string]Column
 }
 
+// NewBaseTable creates a BaseTable without an ordinal. See NewBaseTableWithOrdinal.
 func NewBaseTable(ds BaseDataset, index int64, id string, name string, kind string, columns []Column) BaseTable {
+	return NewBaseTableWithOrdinal(ds, index, -1, id, name, kind, columns)
+}
+
+// NewBaseTableWithOrdinal creates a BaseTable, which is the primary result at position ordinal of its dataset, or
+// another table if ordinal is -1.
+func NewBaseTableWithOrdinal(ds BaseDataset, index int64, ordinal int, id string, name string, kind string, columns []Column) BaseTable {
 	b := &baseTable{
 		dataSet: ds,
 		index:   index,
+		ordinal: ordinal,
 		id:      id,
 		name:    name,
 		kind:    kind,
@@ -39,6 +52,10 @@ func (t *baseTable) Index() int64 {
 	return t.index
 }
 
+func (t *baseTable) Ordinal() int {
+	return t.ordinal
+}
+
 func (t *baseTable) Name() string {
 	return t.name
 }
@@ -73,6 +90,9 @@ func (t *baseTable) Op() errors.Op {
 type table struct {
 	BaseTable
 	rows []Row
+
+	sizeOnce sync.Once
+	size     int64
 }
 
 func NewTable(base BaseTable, rows []Row) Table {
@@ -82,6 +102,58 @@ func NewTable(base BaseTable, rows []Row) Table {
 	}
 }
 
+func (t *table) Ordinal() int {
+	return TableOrdinal(t.BaseTable)
+}
+
 func (t *table) Rows() []Row {
 	return t.rows
 }
+
+func (t *table) RowCount() int {
+	return len(t.rows)
+}
+
+// DecodedSize is computed the first time it is called, and cached.
+func (t *table) DecodedSize() int64 {
+	t.sizeOnce.Do(func() {
+		t.size = rowsDecodedSize(t.rows)
+	})
+	return t.size
+}
+
+// rowsDecodedSize returns the approximate size of the decoded values of rows, in bytes.
+func rowsDecodedSize(rows []Row) int64 {
+	var size int64
+	for _, r := range rows {
+		for _, v := range r.Values() {
+			size += decodedSize(v)
+		}
+	}
+	return size
+}
+
+// fixedSizes are the sizes of the decoded values of the types that don't have a variable length.
+var fixedSizes = map[types.Column]int64{
+	types.Bool:     1,
+	types.Int:      4,
+	types.Long:     8,
+	types.Real:     8,
+	types.Decimal:  16,
+	types.DateTime: 24,
+	types.Timespan: 8,
+	types.GUID:     16,
+}
+
+// decodedSize returns the approximate size of a decoded value, in bytes.
+func decodedSize(v value.Kusto) int64 {
+	switch v := v.(type) {
+	case *value.String:
+		return int64(len(v.Value))
+	case *value.Dynamic:
+		return int64(len(v.Value))
+	case nil:
+		return 0
+	}
+	return fixedSizes[v.GetType()]
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableSize(t *testing.T) {
+	t.Parallel()
+
+	cols := Columns{
+		NewColumn(0, "Count", types.Long),
+		NewColumn(1, "Name", types.String),
+		NewColumn(2, "Props", types.Dynamic),
+		NewColumn(3, "Id", types.GUID),
+		NewColumn(4, "Time", types.DateTime),
+	}
+	base := NewBaseTable(nil, 0, "", "Table_0", "", cols)
+	rows := []Row{
+		NewRow(base, 0, value.Values{value.NewLong(1), value.NewString("hello"), value.NewDynamic([]byte(`{"a":1}`)), value.NewGUID(uuid.New()), value.NewDateTime(time.Now())}),
+		NewRow(base, 1, value.Values{value.NewNullLong(), value.NewString(""), value.NewNullDynamic(), value.NewNullGUID(), value.NewNullDateTime()}),
+	}
+
+	table := NewTable(base, rows).(TableSizer)
+	assert.Equal(t, 2, table.RowCount())
+	// 8 + 5 + 7 + 16 + 24 for the first row, and the fixed sizes of the types for the nulls of the second.
+	assert.Equal(t, int64(60+8+16+24), table.DecodedSize())
+	assert.Equal(t, int64(60+8+16+24), table.DecodedSize())
+
+	empty := NewTable(base, nil).(TableSizer)
+	assert.Zero(t, empty.RowCount())
+	assert.Zero(t, empty.DecodedSize())
+
+	// Tables that don't implement TableSizer have their size computed from their rows.
+	assert.Equal(t, int64(60+8+16+24), TableDecodedSize(struct{ Table }{NewTable(base, rows)}))
+}
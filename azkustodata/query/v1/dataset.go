@@ -59,12 +59,16 @@ func NewDatasetFromReader(ctx context.Context, op errors.Op, reader io.ReadClose
 		return nil, err
 	}
 
-	return NewDataset(ctx, op, *v1)
+	return newDataset(query.NewBaseDatasetFromReader(ctx, op, PrimaryResultKind, reader), *v1)
 }
 
 func NewDataset(ctx context.Context, op errors.Op, v1 V1) (Dataset, error) {
+	return newDataset(query.NewBaseDataset(ctx, op, PrimaryResultKind), v1)
+}
+
+func newDataset(base query.BaseDataset, v1 V1) (Dataset, error) {
 	d := &dataset{
-		BaseDataset: query.NewBaseDataset(ctx, op, PrimaryResultKind),
+		BaseDataset: base,
 	}
 
 	if len(v1.Tables) == 0 {
@@ -77,7 +81,7 @@ func NewDataset(ctx context.Context, op errors.Op, v1 V1) (Dataset, error) {
 			return nil, errors.ES(d.Op(), errors.KInternal, "exceptions: %v", v1.Exceptions)
 		}
 
-		table, err := NewTable(d, &v1.Tables[0], primaryResultIndexRow)
+		table, err := newTable(d, &v1.Tables[0], primaryResultIndexRow, 0)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +115,7 @@ func NewDataset(ctx context.Context, op errors.Op, v1 V1) (Dataset, error) {
 			}
 			d.info = queryInfo
 		} else if r.Kind == "QueryResult" {
-			table, err := NewTable(d, &v1.Tables[i], &r)
+			table, err := newTable(d, &v1.Tables[i], &r, len(d.results))
 			if err != nil {
 				return nil, err
 			}
@@ -145,10 +149,19 @@ func parseTable[T any](rawTable *RawTable, d *dataset, index *TableIndexRow) ([]
 	return rows, nil
 }
 
+func (d *dataset) TransferStats() query.TransferStats {
+	return query.DatasetTransferStats(d.BaseDataset)
+}
+
 func (d *dataset) Tables() []query.Table {
 	return d.results
 }
 
+// MarshalJSON implements json.Marshaler, see query.MarshalDataset. The index, status and info tables are not included.
+func (d *dataset) MarshalJSON() ([]byte, error) {
+	return query.MarshalDataset(d)
+}
+
 func (d *dataset) Index() []TableIndexRow {
 	return d.index
 }
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"io"
@@ -114,6 +115,18 @@ func TestDatasetSuccess(t *testing.T) {
 			assert.Nil(t, errs)
 
 			assert.EqualValues(t, expectedTable2Rows, table2)
+			assert.Equal(t, []string{"a", "b"}, query.ColumnNames(ds.Tables()[1].Columns()))
+			assert.Equal(t, "Int32", ds.Tables()[1].Columns()[1].(query.ColumnMetadata).DataType())
+			assert.Equal(t, types.Int, ds.Tables()[1].Columns()[1].Type())
+
+			for i, tb := range ds.Tables() {
+				assert.Equal(t, i, query.TableOrdinal(tb))
+				assert.Equal(t, tb, query.PrimaryByOrdinal(ds.Tables(), i))
+				assert.Equal(t, tb, query.TableByID(ds.Tables(), tb.Index()))
+			}
+			assert.Nil(t, query.PrimaryByOrdinal(ds.Tables(), 2))
+			assert.Nil(t, query.PrimaryByOrdinal(ds.Tables(), -1))
+			assert.Nil(t, query.TableByID(ds.Tables(), 3))
 		})
 	}
 }
@@ -160,3 +173,19 @@ func TestBoolAsInt(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, nullBool)
 }
+
+func TestCellError(t *testing.T) {
+	t.Parallel()
+
+	reader := io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"Id","DataType":"Guid","ColumnType":"guid"}],"Rows":[["123e27de-1e4e-49d9-b579-fe0b331d3642"],["not a guid"]]}]}`))
+	_, err := NewDatasetFromReader(context.Background(), errors.OpMgmt, reader)
+
+	var cellErr *errors.CellError
+	if assert.ErrorAs(t, err, &cellErr) {
+		// A single table is named after the kind of its results.
+		assert.Equal(t, "QueryResult", cellErr.Table)
+		assert.Equal(t, 1, cellErr.Row)
+		assert.Equal(t, "Id", cellErr.Column)
+	}
+}
@@ -9,22 +9,28 @@ import (
 )
 
 func NewTable(d query.BaseDataset, dt *RawTable, index *TableIndexRow) (query.Table, error) {
+	return newTable(d, dt, index, -1)
+}
+
+// newTable creates a table, which is the primary result at position ordinal of the dataset, or another table if
+// ordinal is -1.
+func newTable(d query.BaseDataset, dt *RawTable, index *TableIndexRow, ordinal int) (query.Table, error) {
 	var id string
 	var kind string
 	var name string
-	var ordinal int64
+	var tocOrdinal int64
 
 	if index != nil {
 		id = index.Id
 		kind = index.Kind
 		name = index.Name
-		ordinal = index.Ordinal
+		tocOrdinal = index.Ordinal
 	} else {
 		// this case exists for the index table itself
 		id = ""
 		kind = ""
 		name = dt.TableName
-		ordinal = 0
+		tocOrdinal = 0
 	}
 
 	op := d.Op()
@@ -41,10 +47,10 @@ func NewTable(d query.BaseDataset, dt *RawTable, index *TableIndexRow) (query.Ta
 			return nil, errors.ES(op, errors.KClientArgs, "column[%d] is of type %q, which is not valid", i, c.ColumnType)
 		}
 
-		columns[i] = query.NewColumn(i, c.ColumnName, normal)
+		columns[i] = query.NewColumnWithMetadata(i, c.ColumnName, normal, c.DataType, "")
 	}
 
-	baseTable := query.NewBaseTable(d, ordinal, id, name, kind, columns)
+	baseTable := query.NewBaseTableWithOrdinal(d, tocOrdinal, ordinal, id, name, kind, columns)
 
 	rows := make([]query.Row, 0, len(dt.Rows))
 
@@ -66,7 +72,7 @@ func NewTable(d query.BaseDataset, dt *RawTable, index *TableIndexRow) (query.Ta
 			if v != nil {
 				err := parsed.Unmarshal(v)
 				if err != nil {
-					return nil, errors.ES(op, errors.KInternal, "unable to unmarshal column %s into a %s value: %s", columns[j].Name(), columns[j].Type(), err)
+					return nil, errors.Cell(op, name, i, columns[j].Name(), err)
 				}
 			}
 			values[j] = parsed
@@ -0,0 +1,67 @@
+package v2
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// DataReplacePolicy is how an iterative dataset handles the DataReplace fragments of progressive queries, whose rows
+// replace all the rows of the table that were received before them.
+type DataReplacePolicy int
+
+const (
+	// DataReplaceFail fails the dataset on a DataReplace fragment, as the rows that were already delivered can't be
+	// taken back. It is the default.
+	DataReplaceFail DataReplacePolicy = iota
+	// DataReplaceRestart reports a *RowsReplacedError in the rows of the table, then delivers the new rows, whose
+	// Index starts over at 0. The rows delivered before the error must be discarded. The table goes on after the error,
+	// and ToTable keeps only the rows that come after it.
+	DataReplaceRestart
+	// DataReplaceIgnore delivers the rows of DataReplace fragments as if they were appended to the table, so the table
+	// has the rows of every fragment, including those that were replaced.
+	DataReplaceIgnore
+)
+
+// RowsReplacedError is reported in the rows of a table, with DataReplaceRestart, when a DataReplace fragment replaces
+// the rows that were delivered before it. It is an event rather than a failure: the rows of the table go on after it.
+type RowsReplacedError struct {
+	// TableId and TableName identify the table whose rows are replaced.
+	TableId   int
+	TableName string
+	// Replaced is the number of rows of the table that were delivered before, and are replaced.
+	Replaced int
+}
+
+func (e *RowsReplacedError) Error() string {
+	return fmt.Sprintf("the %d rows of table %s (%d) were replaced by a DataReplace fragment, discard them", e.Replaced, e.TableName, e.TableId)
+}
+
+// DataReplace sets how the dataset handles DataReplace fragments, see DataReplacePolicy.
+func DataReplace(policy DataReplacePolicy) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.dataReplace = policy
+	}
+}
+
+// OnDataReplace sets the DataReplaceRestart policy, and calls f with the table whose rows are replaced, before its
+// *RowsReplacedError is reported. f is called from the goroutine that decodes the dataset, and must not block. As the
+// rows of a table are buffered, rows received before the replace may still be delivered after f is called: the
+// *RowsReplacedError is what marks the rows to discard in the stream.
+func OnDataReplace(f func(table query.BaseTable, replaced int)) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.dataReplace = DataReplaceRestart
+		d.onDataReplace = f
+	}
+}
+
+// replaceRows restarts the current table with DataReplaceRestart, before the rows of a DataReplace fragment are added.
+func (d *iterativeDataset) replaceRows() {
+	t := d.currentTable
+	replaced := t.RowCount()
+	if d.onDataReplace != nil {
+		d.onDataReplace(t, replaced)
+	}
+	t.setRowCount(0)
+	t.reportError(&RowsReplacedError{TableId: int(t.Index()), TableName: t.Name(), Replaced: replaced})
+}
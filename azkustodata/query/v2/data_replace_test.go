@@ -0,0 +1,104 @@
+package v2
+
+import (
+	"context"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withDataReplace makes twoTables progressive, and replaces the rows of its first table with 7 and 8 after they were
+// sent.
+func withDataReplace() string {
+	s := strings.Replace(twoTables, `"IsProgressive":false`, `"IsProgressive":true`, 1)
+	return strings.Replace(s, `"Rows":[[2], [3]]`, `"Rows":[[2], [3]]}`+"\n"+`,{"FrameType":"TableFragment","TableFragmentType":"DataReplace","TableId":1,"Rows":[[7], [8]]`, 1)
+}
+
+// firstTableEvents returns the rows of the first table of d as "A@Index", and its errors.
+func firstTableEvents(t *testing.T, d query.IterativeDataset) ([]string, []error) {
+	defer d.Close()
+	tr := <-d.Tables()
+	require.NoError(t, tr.Err())
+
+	var rows []string
+	var errs []error
+	for rr := range tr.Table().Rows() {
+		if rr.Err() != nil {
+			errs = append(errs, rr.Err())
+			rows = append(rows, "replace")
+			continue
+		}
+		a, err := rr.Row().IntByIndex(0)
+		require.NoError(t, err)
+		rows = append(rows, fmt.Sprintf("%d@%d", *a, rr.Row().Index()))
+	}
+	return rows, errs
+}
+
+func TestStreamingDataSet_DataReplaceRestart(t *testing.T) {
+	t.Parallel()
+
+	for _, reuse := range []bool{false, true} {
+		options := []DatasetOption{DataReplace(DataReplaceRestart)}
+		if reuse {
+			options = append(options, ReuseRows())
+		}
+		d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(withDataReplace())), DefaultIoCapacity, DefaultRowCapacity,
+			DefaultTableCapacity, options...)
+		require.NoError(t, err)
+
+		rows, errs := firstTableEvents(t, d)
+		assert.Equal(t, []string{"1@0", "2@1", "3@2", "replace", "7@0", "8@1"}, rows, "reuse %t", reuse)
+		require.Len(t, errs, 1)
+		var replaced *RowsReplacedError
+		require.True(t, goErrors.As(errs[0], &replaced))
+		assert.Equal(t, &RowsReplacedError{TableId: 1, TableName: "PrimaryResult", Replaced: 3}, replaced)
+	}
+
+	// ToDataset keeps the rows after the replace.
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(withDataReplace())), DefaultIoCapacity, DefaultRowCapacity,
+		DefaultTableCapacity, DataReplace(DataReplaceRestart))
+	require.NoError(t, err)
+	full, err := d.ToDataset()
+	require.NoError(t, err)
+	got, err := query.ToStructs[table1](full.Tables()[0])
+	require.NoError(t, err)
+	assert.Equal(t, []table1{{A: 7}, {A: 8}}, got)
+	assert.Len(t, full.Tables()[1].Rows(), 3)
+}
+
+func TestStreamingDataSet_OnDataReplace(t *testing.T) {
+	t.Parallel()
+
+	type call struct {
+		table    string
+		replaced int
+	}
+	calls := make(chan call, 1)
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(withDataReplace())), DefaultIoCapacity, DefaultRowCapacity,
+		DefaultTableCapacity, OnDataReplace(func(table query.BaseTable, replaced int) { calls <- call{table.Name(), replaced} }))
+	require.NoError(t, err)
+
+	rows, errs := firstTableEvents(t, d)
+	assert.Equal(t, []string{"1@0", "2@1", "3@2", "replace", "7@0", "8@1"}, rows)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, call{"PrimaryResult", 3}, <-calls)
+}
+
+func TestStreamingDataSet_DataReplaceIgnore(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(withDataReplace())), DefaultIoCapacity, DefaultRowCapacity,
+		DefaultTableCapacity, DataReplace(DataReplaceIgnore))
+	require.NoError(t, err)
+
+	rows, errs := firstTableEvents(t, d)
+	assert.Equal(t, []string{"1@0", "2@1", "3@2", "7@3", "8@4"}, rows)
+	assert.Empty(t, errs)
+}
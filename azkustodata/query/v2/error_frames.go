@@ -45,3 +45,26 @@ func (e *ErrorMessage) String() string {
 func (e *ErrorContext) String() string {
 	return fmt.Sprintf("ErrorContext(Timestamp=%s, ServiceAlias=%s, MachineName=%s, ProcessName=%s, ProcessId=%d, ThreadId=%d, ClientRequestId=%s, ActivityId=%s, SubActivityId=%s, ActivityType=%s, ParentActivityId=%s, ActivityStack=%s)", e.Timestamp, e.ServiceAlias, e.MachineName, e.ProcessName, e.ProcessId, e.ThreadId, e.ClientRequestId, e.ActivityId, e.SubActivityId, e.ActivityType, e.ParentActivityId, e.ActivityStack)
 }
+
+// TableStreamError is an error reported in the stream by a TableError frame, while the dataset is read. It is sent
+// inline, in the rows of the table it happened in, or in the tables of the dataset if that table isn't being read.
+// The dataset goes on after it.
+type TableStreamError struct {
+	// TableId is the id of the table the error happened in.
+	TableId int
+	// TableName is the name of the table, if the table was being read.
+	TableName string
+	// Err are the errors of the frame.
+	Err error
+}
+
+func (e *TableStreamError) Error() string {
+	if e.TableName != "" {
+		return fmt.Sprintf("error in table %d (%s): %s", e.TableId, e.TableName, e.Err)
+	}
+	return fmt.Sprintf("error in table %d: %s", e.TableId, e.Err)
+}
+
+func (e *TableStreamError) Unwrap() error {
+	return e.Err
+}
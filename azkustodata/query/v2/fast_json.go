@@ -3,6 +3,7 @@ package v2
 import (
 	"bytes"
 	"encoding/json"
+	goErrors "errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"github.com/Azure/azure-kusto-go/azkustodata/types"
@@ -23,11 +24,24 @@ func newDecoder(r io.Reader) *json.Decoder {
 func (t *TableFragment) UnmarshalJSON(b []byte) error {
 	decoder := newDecoder(bytes.NewReader(b))
 
-	rows, err := decodeTableFragment(b, decoder, t.Columns, t.PreviousIndex)
+	if t.reuse {
+		data, err := decodeRowsData(b, decoder, &t.TableFragmentType)
+		if err != nil {
+			return err
+		}
+		t.raw, err = currentRowsDecoder().DecodeRows(data)
+		return err
+	}
+
+	data, err := decodeRowsData(b, decoder, &t.TableFragmentType)
+	if err != nil {
+		return err
+	}
+	// The type of the fragment is read before its rows, so that their index can start over if they replace the others.
+	t.Rows, err = decodeRows(data, t.Columns, t.startIndex(), t.lazy)
 	if err != nil {
 		return err
 	}
-	t.Rows = rows
 
 	return nil
 }
@@ -42,15 +56,24 @@ func (q *DataTable) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	rows, err := decodeTableFragment(b, decoder, q.Header.Columns, 0)
+	rows, err := decodeTableFragment(b, decoder, q.Header.Columns, 0, nil, q.lazy)
 	if err != nil {
-		return err
+		return inTable(err, q.Header.TableName)
 	}
 	q.Rows = rows
 
 	return nil
 }
 
+// inTable sets the table of the cell error in err, if there is one, as the rows are decoded without knowing their table.
+func inTable(err error, table string) error {
+	var cellErr *errors.CellError
+	if goErrors.As(err, &cellErr) {
+		cellErr.Table = table
+	}
+	return err
+}
+
 // UnmarshalJSON implements the json.Unmarshaler interface for DataSetHeader.
 // We need to decode this manually to set the correct Columns, in order to save on allocations later on.
 func (t *TableHeader) UnmarshalJSON(b []byte) error {
@@ -105,7 +128,20 @@ func decodeHeader(decoder *json.Decoder, t *TableHeader, frameType FrameType) er
 }
 
 // decodeTableFragment decodes the common part of a TableFragment and DataTable - the rows.
-func decodeTableFragment(b []byte, decoder *json.Decoder, columns []query.Column, previousIndex int) ([]query.Row, error) {
+// If fragmentType is not nil, it is set to the TableFragmentType property of the frame.
+// If lazy is set, the cells of the rows are decoded on access, see query.NewLazyRow.
+func decodeTableFragment(b []byte, decoder *json.Decoder, columns []query.Column, previousIndex int, fragmentType *string, lazy bool) ([]query.Row, error) {
+	data, err := decodeRowsData(b, decoder, fragmentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRows(data, columns, previousIndex, lazy)
+}
+
+// decodeRowsData skips the properties of a TableFragment or DataTable until the Rows property, and returns its JSON array.
+// If fragmentType is not nil, it is set to the TableFragmentType property of the frame.
+func decodeRowsData(b []byte, decoder *json.Decoder, fragmentType *string) ([]byte, error) {
 
 	// skip properties until we reach the Rows property (guaranteed to be the last one)
 	for {
@@ -116,14 +152,14 @@ func decodeTableFragment(b []byte, decoder *json.Decoder, columns []query.Column
 		if tok == json.Token("Rows") {
 			break
 		}
+		if fragmentType != nil && tok == json.Token("TableFragmentType") {
+			if *fragmentType, err = getStringValue(decoder); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	rows, err := decodeRows(b, decoder, columns, previousIndex)
-	if err != nil {
-		return nil, err
-	}
-
-	return rows, nil
+	return rowsData(b, decoder.InputOffset())
 }
 
 // decodeColumns decodes the columns of a table from the JSON.
@@ -164,87 +200,57 @@ func decodeColumns(decoder *json.Decoder) ([]query.Column, error) {
 // In V2 Fragmented, it's guaranteed that no errors will appear in the middle of the array, only at the end of the table.
 // This function:
 // 1. Creates a cached map of column names to columns for faster lookup
-// 2. Decodes the rows into a slice of query.Rows
-func decodeRows(b []byte, decoder *json.Decoder, cols []query.Column, startIndex int) ([]query.Row, error) {
-	const RowArrayAllocSize = 10
-	var rows = make([]query.Row, 0, RowArrayAllocSize)
-
-	columnsByName := make(map[string]query.Column, len(cols))
-	for _, c := range cols {
-		columnsByName[c.Name()] = c
-	}
-
-	err := assertToken(decoder, json.Delim('['))
+// 2. Decodes the JSON with the current RowsDecoder
+// 3. Unmarshals the values into the correct types, as indicated by the columns, or keeps them for lazy rows
+func decodeRows(data []byte, cols []query.Column, startIndex int, lazy bool) ([]query.Row, error) {
+	raw, err := currentRowsDecoder().DecodeRows(data)
 	if err != nil {
 		return nil, err
 	}
 
-	for i := startIndex; decoder.More(); i++ {
-		rowValues, err := decodeRow(b, decoder, cols)
-		if err != nil {
-			return nil, err
-		}
-
-		row := query.NewRowFromParts(cols, func(name string) query.Column { return columnsByName[name] }, i, rowValues)
-		rows = append(rows, row)
-	}
-
-	if err := assertToken(decoder, json.Delim(']')); err != nil {
-		return nil, err
-	}
-	return rows, nil
-}
-
-// decodeRow decodes a single row from the JSON.
-// A row is an array of values of the types from kusto, as indicated by the columns.
-// For dynamic values, they can appear as nested arrays or objects, so we need to handle them.
-// Otherwise, we just unmarshal the value into the correct type.
-func decodeRow(
-	buffer []byte,
-	decoder *json.Decoder,
-	cols []query.Column) (value.Values, error) {
-
-	err := assertToken(decoder, json.Delim('['))
-	if err != nil {
-		return nil, err
+	columnsByName := make(map[string]query.Column, len(cols))
+	for _, c := range cols {
+		columnsByName[c.Name()] = c
 	}
+	byName := func(name string) query.Column { return columnsByName[name] }
 
-	values := make([]value.Kusto, 0, len(cols))
-
-	field := 0
-
-	for ; decoder.More(); field++ {
-		t, err := decoder.Token()
-		if err != nil {
-			return nil, err
+	rows := make([]query.Row, 0, len(raw))
+	for i, rawRow := range raw {
+		if len(rawRow) != len(cols) {
+			return nil, errors.ES(errors.OpQuery, errors.KInternal, "row %d has %d values, expected %d", startIndex+i, len(rawRow), len(cols))
 		}
 
-		// Handle nested values
-		if t == json.Delim('[') || t == json.Delim('{') {
-			t, err = decodeNestedValue(decoder, buffer)
-			if err != nil {
-				return nil, err
-			}
+		if lazy {
+			rows = append(rows, query.NewLazyRow(cols, byName, startIndex+i, rawRow))
+			continue
 		}
 
-		// Create a new value of the correct type
-		kustoValue := value.Default(cols[field].Type())
+		values := make([]value.Kusto, len(cols))
+		for field, t := range rawRow {
+			// Create a new value of the correct type
+			kustoValue := value.Default(cols[field].Type())
 
-		// Unmarshal the value
-		err = kustoValue.Unmarshal(t)
-		if err != nil {
-			return nil, err
+			// Unmarshal the value
+			if err := kustoValue.Unmarshal(t); err != nil {
+				return nil, errors.Cell(errors.OpQuery, "", startIndex+i, cols[field].Name(), err)
+			}
+			values[field] = kustoValue
 		}
 
-		values = append(values, kustoValue)
+		rows = append(rows, query.NewRowFromParts(cols, byName, startIndex+i, values))
 	}
 
-	err = assertToken(decoder, json.Delim(']'))
-	if err != nil {
-		return nil, err
-	}
+	return rows, nil
+}
 
-	return values, nil
+// rowsData returns the JSON array of the Rows property, given the offset right after the "Rows" key.
+// Rows is guaranteed to be the last property, so the array ends right before the closing brace of the frame.
+func rowsData(b []byte, offset int64) ([]byte, error) {
+	data := bytes.TrimSpace(b[offset:])
+	if len(data) < 2 || data[0] != ':' || data[len(data)-1] != '}' {
+		return nil, errors.ES(errors.OpQuery, errors.KInternal, "invalid Rows property in frame")
+	}
+	return bytes.TrimSpace(data[1 : len(data)-1]), nil
 }
 
 // decodeNestedValue decodes a nested value from the JSON into a byte array inside a json.Token.
@@ -286,35 +292,55 @@ func decodeNestedValue(decoder *json.Decoder, buffer []byte) (json.Token, error)
 }
 
 // validateDataSetHeader makes sure the dataset header is valid for V2 Fragmented Query.
-func validateDataSetHeader(dec *json.Decoder) error {
+// Both progressive and non-progressive datasets are accepted, and the returned header tells them apart.
+func validateDataSetHeader(dec *json.Decoder) (DataSetHeader, error) {
 	const HeaderVersion = "v2.0"
-	const NotProgressive = false
 	const IsFragmented = true
-	const ErrorReportingEndOfTable = "EndOfTable"
+
+	header := DataSetHeader{Version: HeaderVersion, IsFragmented: IsFragmented, ErrorReportingPlacement: ErrorReportingEndOfTable}
 
 	if err := assertToken(dec, json.Delim('{')); err != nil {
-		return err
+		return header, err
 	}
 
 	if err := assertStringProperty(dec, "FrameType", json.Token(string(DataSetHeaderFrameType))); err != nil {
-		return err
+		return header, err
 	}
 
-	if err := assertStringProperty(dec, "IsProgressive", json.Token(NotProgressive)); err != nil {
-		return err
+	if err := assertToken(dec, json.Token("IsProgressive")); err != nil {
+		return header, err
 	}
+	t, err := dec.Token()
+	if err != nil {
+		return header, err
+	}
+	progressive, ok := t.(bool)
+	if !ok {
+		return header, errors.ES(errors.OpUnknown, errors.KInternal, "Expected bool, got %v", t)
+	}
+	header.IsProgressive = progressive
 
-	if err := assertStringProperty(dec, "Version", json.Token(HeaderVersion)); err != nil {
-		return err
+	version, err := getStringProperty(dec, "Version")
+	if err != nil {
+		return header, err
+	}
+	if version != HeaderVersion && version != HeaderVersionInStreamErrors {
+		return header, errors.ES(errors.OpUnknown, errors.KInternal, "Expected %v, got %v", HeaderVersion, version)
 	}
+	header.Version = version
 
 	if err := assertStringProperty(dec, "IsFragmented", json.Token(IsFragmented)); err != nil {
-		return err
+		return header, err
 	}
 
-	if err := assertStringProperty(dec, "ErrorReportingPlacement", json.Token(ErrorReportingEndOfTable)); err != nil {
-		return err
+	placement, err := getStringProperty(dec, "ErrorReportingPlacement")
+	if err != nil {
+		return header, err
 	}
+	if placement != ErrorReportingEndOfTable && placement != ErrorReportingInData {
+		return header, errors.ES(errors.OpUnknown, errors.KInternal, "Expected %v, got %v", ErrorReportingEndOfTable, placement)
+	}
+	header.ErrorReportingPlacement = placement
 
-	return nil
+	return header, nil
 }
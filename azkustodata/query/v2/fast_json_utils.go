@@ -3,6 +3,7 @@ package v2
 import (
 	"encoding/json"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"strconv"
 )
 
 // assertToken asserts that the next token in the decoder is the expected token.
@@ -33,6 +34,11 @@ func getStringProperty(dec *json.Decoder, name string) (string, error) {
 	if err := assertToken(dec, json.Token(name)); err != nil {
 		return "", err
 	}
+	return getStringValue(dec)
+}
+
+// getStringValue reads a string value from the decoder.
+func getStringValue(dec *json.Decoder) (string, error) {
 	t, err := dec.Token()
 	if err != nil {
 		return "", err
@@ -53,11 +59,8 @@ func getIntProperty(dec *json.Decoder, name string) (int, error) {
 		return 0, err
 	}
 	if s, ok := t.(json.Number); ok {
-		i, err := s.Int64()
-		if err != nil {
-			return 0, err
-		}
-		return int(i), nil
+		// Atoi fails instead of truncating values that don't fit an int on 32-bit platforms.
+		return strconv.Atoi(string(s))
 	}
 	return 0, errors.ES(errors.OpUnknown, errors.KInternal, "Expected string, got %v", t)
 }
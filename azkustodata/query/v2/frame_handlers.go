@@ -0,0 +1,64 @@
+package v2
+
+// FrameHandler handles a frame of a type the iterative dataset doesn't decode itself, such as a frame type added to the
+// protocol after this version of the SDK, or a preview frame type. frame is the raw JSON of the frame, and is owned by
+// the handler. Returning an error fails the dataset.
+// Handlers are called from the goroutine that decodes the dataset, in the order of the frames, and must not block.
+type FrameHandler func(frameType FrameType, frame []byte) error
+
+// knownFrameTypes are the frame types decoded by the iterative dataset.
+var knownFrameTypes = map[FrameType]bool{
+	DataSetHeaderFrameType:     true,
+	DataTableFrameType:         true,
+	TableHeaderFrameType:       true,
+	TableFragmentFrameType:     true,
+	TableCompletionFrameType:   true,
+	TableProgressFrameType:     true,
+	DataSetCompletionFrameType: true,
+	TableErrorFrameType:        true,
+}
+
+// WithFrameHandler registers the handler of a frame type the dataset doesn't decode itself. It is ignored for the
+// frame types the dataset decodes.
+func WithFrameHandler(frameType FrameType, handler FrameHandler) DatasetOption {
+	return func(d *iterativeDataset) {
+		if knownFrameTypes[frameType] {
+			return
+		}
+		if d.frameHandlers == nil {
+			d.frameHandlers = map[FrameType]FrameHandler{}
+		}
+		d.frameHandlers[frameType] = handler
+	}
+}
+
+// WithUnknownFrameHandler sets the handler of the frames of unknown types that have no handler registered with
+// WithFrameHandler. Without it, these frames are skipped, and counted in FrameStats.SkippedFrames.
+func WithUnknownFrameHandler(handler FrameHandler) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.unknownFrameHandler = handler
+	}
+}
+
+// handleUnknownFrame passes a frame of an unknown type to its handler, or skips it.
+func (d *iterativeDataset) handleUnknownFrame(frameType FrameType, frame []byte) error {
+	if handler, ok := d.frameHandlers[frameType]; ok {
+		return handler(frameType, frame)
+	}
+	if d.unknownFrameHandler != nil {
+		return d.unknownFrameHandler(frameType, frame)
+	}
+	if d.stats != nil {
+		d.stats.SkippedFrames++
+	}
+	return nil
+}
+
+// WithDataSetHeaderHandler calls handler with the DataSetHeader of the dataset once it is read, for instance to learn
+// the version of the protocol the service answered with. It is called from the goroutine that decodes the dataset, and
+// must not block.
+func WithDataSetHeaderHandler(handler func(DataSetHeader)) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.onHeader = handler
+	}
+}
@@ -3,6 +3,7 @@ package v2
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"io"
 )
@@ -14,21 +15,65 @@ import (
 // 3. We then read each line as a separate frame.
 // 4. When we reach the end of the array, it means we have reached the end of the response, and we return io.EOF.
 // 5. For every line we read, we check if the context has been cancelled, and if so, return the error.
+//
+// If the response wasn't requested with newlines between frames (results_v2_newlines_between_frames), the frames
+// can't be split by lines. This is detected from the first frame, and the frames are then read with a JSON decoder,
+// which is slower but works with any framing.
 type frameReader struct {
 	orig   io.ReadCloser
-	reader bufio.Reader
+	reader *bufio.Reader
 	ctx    context.Context
+	// array is set when the response is not newline-delimited, and decodes the frames from the JSON array.
+	array *json.Decoder
 }
 
+// maxHeaderLineLength is the most bytes peeked to find the end of the first frame, which is a small DataSetHeader.
+const maxHeaderLineLength = 4096
+
 func newFrameReader(r io.ReadCloser, ctx context.Context) (*frameReader, error) {
-	br := bufio.NewReader(r)
+	br := bufio.NewReaderSize(r, maxHeaderLineLength)
 
 	err := validateJsonResponse(br)
 	if err != nil {
 		return nil, err
 	}
 
-	return &frameReader{orig: r, reader: *br, ctx: ctx}, nil
+	fr := &frameReader{orig: r, reader: br, ctx: ctx}
+	if !isNewlineDelimited(br) {
+		fr.array = json.NewDecoder(br)
+		// Consume the opening bracket of the array.
+		if err := assertToken(fr.array, json.Delim('[')); err != nil {
+			return nil, err
+		}
+	}
+
+	return fr, nil
+}
+
+// isNewlineDelimited checks if the first frame is followed by a newline. The response is peeked a read at a time
+// until the end of the first frame, so that it doesn't wait for more of the response than the first frame.
+func isNewlineDelimited(br *bufio.Reader) bool {
+	checked := 1
+	for {
+		peek, _ := br.Peek(br.Buffered())
+		for ; checked < len(peek); checked++ {
+			if peek[checked] != '}' || !json.Valid(peek[1:checked+1]) {
+				continue
+			}
+			// The first frame ends here, and the line must end with it.
+			if checked+1 < len(peek) {
+				return peek[checked+1] == '\n' || peek[checked+1] == '\r'
+			}
+			break
+		}
+		if len(peek) >= maxHeaderLineLength {
+			return false
+		}
+		// Read at least one more byte.
+		if _, err := br.Peek(len(peek) + 1); err != nil && br.Buffered() == len(peek) {
+			return false
+		}
+	}
 }
 
 // validateJsonResponse reads the first byte of the response to determine if it is in fact valid JSON.
@@ -60,6 +105,10 @@ func (fr *frameReader) advance() ([]byte, error) {
 		return nil, fr.ctx.Err()
 	}
 
+	if fr.array != nil {
+		return fr.advanceArray()
+	}
+
 	// Read until the end of the current line, which is the entire frame.
 	line, err := fr.reader.ReadBytes('\n')
 	if err != nil {
@@ -92,6 +141,19 @@ func (fr *frameReader) advance() ([]byte, error) {
 	return line, nil
 }
 
+// advanceArray reads the next frame from a response that is not newline-delimited.
+func (fr *frameReader) advanceArray() ([]byte, error) {
+	if !fr.array.More() {
+		return nil, io.EOF
+	}
+
+	var frame json.RawMessage
+	if err := fr.array.Decode(&frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
 // Close closes the underlying reader.
 func (fr *frameReader) close() error {
 	return fr.orig.Close()
@@ -6,7 +6,9 @@ import (
 	_ "embed"
 	"github.com/stretchr/testify/require"
 	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 //go:embed testData/validFrames.json
@@ -45,6 +47,78 @@ func TestDecodeValidFrames(t *testing.T) {
 	}
 }
 
+func TestDecodeValidFramesShortReads(t *testing.T) {
+	t.Parallel()
+
+	readAll := func(r io.Reader) []string {
+		f, err := newFrameReader(io.NopCloser(r), context.Background())
+		require.NoError(t, err)
+		var frames []string
+		for {
+			line, err := f.advance()
+			if err == io.EOF {
+				return frames
+			}
+			require.NoError(t, err)
+			frames = append(frames, string(line))
+		}
+	}
+
+	want := readAll(strings.NewReader(validFrames))
+	require.Len(t, want, 7)
+	require.Equal(t, want, readAll(iotest.HalfReader(strings.NewReader(validFrames))))
+	require.Equal(t, want, readAll(iotest.OneByteReader(strings.NewReader(validFrames))))
+}
+
+func TestDetectFramingFromFirstFrame(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		first string
+		array bool
+	}{
+		{"[" + `{"FrameType":"DataSetHeader","IsProgressive":false}` + "\n", false},
+		{"[" + `{"FrameType":"DataSetHeader","IsProgressive":false}` + ",", true},
+	} {
+		// The rest of the response doesn't arrive, so the framing must be detected from the first frame alone.
+		r, w := io.Pipe()
+		go func() {
+			_, _ = w.Write([]byte(tt.first))
+		}()
+		f, err := newFrameReader(r, context.Background())
+		require.NoError(t, err)
+		require.Equal(t, tt.array, f.array != nil)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestDecodeFramesWithoutNewlines(t *testing.T) {
+	frames := []string{
+		`{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}`,
+		`{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"T","Columns":[{"ColumnName":"a","ColumnType":"string"}]}`,
+		`{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[["line\nbreak"]]}`,
+		`{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}`,
+	}
+
+	for _, input := range []string{
+		"[" + strings.Join(frames, ",") + "]",
+		"[\n" + strings.Join(frames, ",\n") + "\n]\n",
+	} {
+		f, err := newFrameReader(io.NopCloser(strings.NewReader(input)), context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, f.array)
+
+		for _, e := range frames {
+			line, err := f.advance()
+			require.NoError(t, err)
+			require.Equal(t, e, string(line))
+		}
+
+		_, err = f.advance()
+		require.Equal(t, io.EOF, err)
+	}
+}
+
 func TestInvalidJsonEmptyLine(t *testing.T) {
 	reader := bytes.NewReader([]byte("[{}\n\n"))
 	f, err := newFrameReader(io.NopCloser(reader), context.Background())
@@ -0,0 +1,70 @@
+package v2
+
+// FrameStats describes the frames of a v2 dataset, as decoded by an iterative dataset.
+// It is reported once the dataset is fully read, failed or closed, when the WithFrameStats option is set.
+type FrameStats struct {
+	// Progressive is set if the dataset was progressive.
+	Progressive bool
+	// Frames is the number of frames decoded, by type.
+	Frames map[FrameType]int
+	// DataReplaceFragments is the number of DataReplace fragments received. Replacing rows isn't supported when
+	// iterating, so the first one fails the dataset.
+	DataReplaceFragments int
+	// SkippedFrames is the number of frames of unknown types skipped, because no handler was registered for them.
+	SkippedFrames int
+	// Tables are the statistics of the primary tables, in order.
+	Tables []TableFrameStats
+	// Err is the error the dataset failed with, if any.
+	Err error
+}
+
+// TableFrameStats describes the frames of a primary table.
+type TableFrameStats struct {
+	TableId   int
+	TableName string
+	// Fragments is the number of TableFragment frames of the table.
+	Fragments int
+	// RowsDelivered is the number of rows sent to the table.
+	RowsDelivered int
+	// CompletionRowCount is the row count of the TableCompletion frame of the table, or -1 if the table wasn't completed.
+	CompletionRowCount int
+	// RowCapacity is the capacity of the buffer of rows of the table chosen by AutoRowCapacity, or 0 without it.
+	RowCapacity int
+}
+
+// DatasetOption is an option for NewIterativeDataset.
+type DatasetOption func(d *iterativeDataset)
+
+// WithFrameStats calls callback with the statistics of the frames of the dataset, once it is fully read, failed or
+// closed. The callback is called from the goroutine that decodes the dataset, and must not block.
+func WithFrameStats(callback func(FrameStats)) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.onStats = callback
+		d.stats = &FrameStats{Frames: map[FrameType]int{}}
+	}
+}
+
+// countFrame counts a decoded frame.
+func (d *iterativeDataset) countFrame(frameType FrameType) {
+	if d.stats != nil {
+		d.stats.Frames[frameType]++
+	}
+}
+
+// currentTableStats returns the statistics of the table being read, or nil if statistics aren't collected.
+func (d *iterativeDataset) currentTableStats() *TableFrameStats {
+	if d.stats == nil || len(d.stats.Tables) == 0 {
+		return nil
+	}
+	return &d.stats.Tables[len(d.stats.Tables)-1]
+}
+
+// reportStats calls the statistics callback, if set.
+func (d *iterativeDataset) reportStats(err error) {
+	if d.onStats == nil {
+		return
+	}
+	d.stats.Progressive = d.header.IsProgressive
+	d.stats.Err = err
+	d.onStats(*d.stats)
+}
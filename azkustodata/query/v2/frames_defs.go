@@ -26,6 +26,8 @@ func (f FrameColumn) Type() types.Column {
 type DataTable struct {
 	Header TableHeader
 	Rows   []query.Row
+	// lazy decodes the cells of the rows on access, see LazyRows.
+	lazy bool
 }
 
 type FrameType string
@@ -36,9 +38,24 @@ const (
 	TableHeaderFrameType       FrameType = "TableHeader"
 	TableFragmentFrameType     FrameType = "TableFragment"
 	TableCompletionFrameType   FrameType = "TableCompletion"
+	TableProgressFrameType     FrameType = "TableProgress"
 	DataSetCompletionFrameType FrameType = "DataSetCompletion"
+	// TableErrorFrameType frames are only sent in datasets whose errors are reported in the stream.
+	TableErrorFrameType FrameType = "TableError"
 )
 
+// HeaderVersionInStreamErrors is the Version of the DataSetHeader of datasets whose errors are reported in the stream,
+// in TableError frames, as soon as they happen.
+const HeaderVersionInStreamErrors = "v2.1"
+
+// ErrorReportingEndOfTable is the ErrorReportingPlacement of datasets whose errors are reported in the TableCompletion
+// frame of the table they happened in.
+const ErrorReportingEndOfTable = "EndOfTable"
+
+// ErrorReportingInData is the ErrorReportingPlacement of datasets whose errors are reported in the stream, in TableError
+// frames, as soon as they happen.
+const ErrorReportingInData = "InData"
+
 type DataSetHeader struct {
 	IsProgressive           bool
 	Version                 string
@@ -53,10 +70,47 @@ type TableHeader struct {
 	Columns   []query.Column
 }
 
+// TableFragmentDataAppend is the TableFragmentType of fragments whose rows are appended to the previous rows of the table.
+const TableFragmentDataAppend = "DataAppend"
+
+// TableFragmentDataReplace is the TableFragmentType of fragments whose rows replace all the previous rows of the table.
+// They are only sent in progressive datasets.
+const TableFragmentDataReplace = "DataReplace"
+
 type TableFragment struct {
-	Columns       []query.Column
-	Rows          []query.Row
-	PreviousIndex int
+	Columns           []query.Column
+	Rows              []query.Row
+	PreviousIndex     int
+	TableFragmentType string
+	// lazy decodes the cells of the rows on access, see LazyRows.
+	lazy bool
+	// reuse keeps the raw cells of the rows in raw instead of decoding them to Rows, see ReuseRows.
+	reuse bool
+	raw   [][]interface{}
+	// restartOnReplace starts the index of the rows of a DataReplace fragment over at 0, see DataReplaceRestart.
+	restartOnReplace bool
+}
+
+// startIndex returns the index of the first row of the fragment.
+func (t TableFragment) startIndex() int {
+	if t.restartOnReplace && t.TableFragmentType == TableFragmentDataReplace {
+		return 0
+	}
+	return t.PreviousIndex
+}
+
+// rowCount returns the number of rows of the fragment.
+func (t TableFragment) rowCount() int {
+	if t.reuse {
+		return len(t.raw)
+	}
+	return len(t.Rows)
+}
+
+// TableProgress is sent in progressive datasets, to report the progress of a table.
+type TableProgress struct {
+	TableId       int
+	TableProgress float64
 }
 
 type TableCompletion struct {
@@ -65,6 +119,13 @@ type TableCompletion struct {
 	OneApiErrors []OneApiError
 }
 
+// TableError is sent in datasets whose errors are reported in the stream, as soon as the errors happen. TableId is the
+// table the errors happened in, and the table goes on until its TableCompletion frame.
+type TableError struct {
+	TableId      int
+	OneApiErrors []OneApiError
+}
+
 type DataSetCompletion struct {
 	HasErrors    bool
 	Cancelled    bool
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	goErrors "errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"io"
@@ -28,6 +29,10 @@ type iterativeDataset struct {
 
 	// rowCapacity is the amount of rows to buffer per table.
 	rowCapacity int
+	// autoRowBufferBytes sizes the buffers of rows from the rows of the tables, see AutoRowCapacity.
+	autoRowBufferBytes int
+	// frameSize is the size of the last frame read, in bytes.
+	frameSize int
 
 	// cancel is a function to cancel the reading of the dataset, and is called when the dataset is closed.
 	cancel context.CancelFunc
@@ -40,18 +45,51 @@ type iterativeDataset struct {
 
 	// jsonData is a channel that receives the raw JSON data from the Kusto service.
 	jsonData chan interface{}
+
+	// header is the DataSetHeader of the dataset, set once it is read.
+	header DataSetHeader
+	// onHeader is called with the header once it is read, see WithDataSetHeaderHandler.
+	onHeader func(DataSetHeader)
+
+	// primaryTables is the number of primary tables read so far.
+	primaryTables int
+
+	// rowsDelivered is the number of rows sent to the tables so far.
+	rowsDelivered int64
+
+	// err is the error the dataset failed with, set before results is closed. The error is not always sent to results,
+	// as the dataset stops sending once its context is done.
+	err error
+
+	// stats are the statistics of the frames, collected only if onStats is set.
+	stats   *FrameStats
+	onStats func(FrameStats)
+
+	// frameHandlers and unknownFrameHandler handle the frames of types the dataset doesn't decode.
+	frameHandlers       map[FrameType]FrameHandler
+	unknownFrameHandler FrameHandler
+
+	// lazyRows decodes the cells of the rows on access, see LazyRows.
+	lazyRows bool
+	// reuseRows decodes the rows of the primary tables into reused rows, see ReuseRows.
+	reuseRows bool
+
+	// dataReplace is how DataReplace fragments are handled, and onDataReplace is called when rows are replaced, see
+	// DataReplace and OnDataReplace.
+	dataReplace   DataReplacePolicy
+	onDataReplace func(table query.BaseTable, replaced int)
 }
 
 // NewIterativeDataset creates a new IterativeDataset from a ReadCloser.
 // ioCapacity is the amount of buffered rows to keep in memory.
 // tableCapacity is the amount of tables to buffer.
 // rowCapacity is the amount of rows to buffer per table.
-func NewIterativeDataset(ctx context.Context, r io.ReadCloser, ioCapacity int, rowCapacity int, tableCapacity int) (query.IterativeDataset, error) {
+func NewIterativeDataset(ctx context.Context, r io.ReadCloser, ioCapacity int, rowCapacity int, tableCapacity int, options ...DatasetOption) (query.IterativeDataset, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
 	d := &iterativeDataset{
-		BaseDataset:     query.NewBaseDataset(ctx, errors.OpQuery, PrimaryResultTableKind),
+		BaseDataset:     query.NewBaseDatasetFromReader(ctx, errors.OpQuery, PrimaryResultTableKind, r),
 		results:         make(chan query.TableResult, tableCapacity),
 		rowCapacity:     rowCapacity,
 		cancel:          cancel,
@@ -59,6 +97,9 @@ func NewIterativeDataset(ctx context.Context, r io.ReadCloser, ioCapacity int, r
 		queryProperties: nil,
 		jsonData:        make(chan interface{}, ioCapacity),
 	}
+	for _, o := range options {
+		o(d)
+	}
 
 	// This ctor will fail if we get a non-json response
 	// In this case, we want to return it immediately
@@ -75,6 +116,25 @@ func NewIterativeDataset(ctx context.Context, r io.ReadCloser, ioCapacity int, r
 	return d, nil
 }
 
+// LazyRows keeps the cells of the rows as raw JSON values, and converts a cell to its Kusto value only when it is
+// accessed, caching the result, see query.NewLazyRow. It saves time and memory for tables with many columns, of which
+// only a few are read.
+func LazyRows() DatasetOption {
+	return func(d *iterativeDataset) {
+		d.lazyRows = true
+	}
+}
+
+// ReuseRows decodes the rows of the primary tables into two rows per table that are reused in turn, instead of
+// allocating a row, its values and a Kusto value per cell for every row. The rows are sent unbuffered, so a row received
+// from Rows stays valid until the next row of its table is received: call query.Cloner.Clone to keep it for longer.
+// ToTable and ToDataset clone the rows. LazyRows has no effect on the primary tables with ReuseRows.
+func ReuseRows() DatasetOption {
+	return func(d *iterativeDataset) {
+		d.reuseRows = true
+	}
+}
+
 // readRoutine reads the frames from the Kusto service and sends them to the buffered channel.
 // This is so we could keep up if the IO is faster than the consumption of the frames.
 func readRoutine(reader *frameReader, d *iterativeDataset) {
@@ -114,7 +174,16 @@ func readRoutine(reader *frameReader, d *iterativeDataset) {
 func parseRoutine(d *iterativeDataset, cancel context.CancelFunc) {
 
 	err := readDataSet(d)
+	var idle *errors.FrameIdleTimeoutError
+	if goErrors.As(err, &idle) {
+		idle.RowsDelivered = d.rowsDelivered
+	}
+	var tooLarge *errors.ResponseTooLargeError
+	if goErrors.As(err, &tooLarge) {
+		tooLarge.RowsDelivered = d.rowsDelivered
+	}
 	if err != nil {
+		d.err = err
 		select {
 		case d.results <- query.TableResultError(err):
 		case <-d.Context().Done():
@@ -122,23 +191,27 @@ func parseRoutine(d *iterativeDataset, cancel context.CancelFunc) {
 		cancel()
 	}
 
-	if d.currentTable != nil {
+	// A table held back by AutoRowCapacity was never sent, so it has no rows to finish.
+	if d.currentTable != nil && d.currentTable.rows != nil {
 		d.currentTable.finishTable([]OneApiError{}, err)
 	}
 
+	d.reportStats(err)
+
 	cancel()
 	close(d.results)
 }
 
 func readDataSet(d *iterativeDataset) error {
 
-	var err error
-
-	// The first frame should be a DataSetHeader. We don't need to save it - just validate it.
+	// The first frame should be a DataSetHeader. We validate it, and keep it to know whether the dataset is progressive.
 	if header, _, err := nextFrame(d); err == nil {
-		if err = validateDataSetHeader(header); err != nil {
+		if d.header, err = validateDataSetHeader(header); err != nil {
 			return err
 		}
+		if d.onHeader != nil {
+			d.onHeader(d.header)
+		}
 	} else {
 		return err
 	}
@@ -161,7 +234,12 @@ func readDataSet(d *iterativeDataset) error {
 	// If we get a TableHeader, we read the table.
 	// If we get a DataTable, it means we have reached QueryCompletionInformation
 	// If we get a DataSetCompletion, we are done.
-	for decoder, frameType, err := nextFrame(d); err == nil; decoder, frameType, err = nextFrame(d) {
+	for {
+		decoder, frameType, err := nextFrame(d)
+		if err != nil {
+			return err
+		}
+
 		if frameType == DataTableFrameType {
 			if err = handleDataTable(d, decoder); err != nil {
 				return err
@@ -176,6 +254,13 @@ func readDataSet(d *iterativeDataset) error {
 			continue
 		}
 
+		if frameType == TableErrorFrameType {
+			if err = handleTableError(d, decoder); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if frameType == DataSetCompletionFrameType {
 			err = readDataSetCompletion(decoder)
 			if err != nil {
@@ -184,35 +269,43 @@ func readDataSet(d *iterativeDataset) error {
 			return nil
 		}
 
-		return errors.ES(errors.OpQuery, errors.KInternal, "unexpected frame type %s, expected DataTable, TableHeader, or DataSetCompletion", frameType)
+		return errors.ES(errors.OpQuery, errors.KInternal, "unexpected frame type %s, expected DataTable, TableHeader, TableError or DataSetCompletion", frameType)
 	}
-
-	return err
 }
 
 // nextFrame reads the next frame from the buffered channel.
 // It doesn't parse the frame yet, but peeks the frame type to determine how to handle it.
+// Frames of unknown types are passed to their handler or skipped, so that new frame types don't break older clients.
 func nextFrame(d *iterativeDataset) (*json.Decoder, FrameType, error) {
-	var line []byte
-	select {
-	case <-d.Context().Done():
-		return nil, "", errors.ES(errors.OpQuery, errors.KInternal, "context cancelled")
-	case val := <-d.jsonData:
-		if val == nil {
-			return nil, "", errors.ES(errors.OpQuery, errors.KInternal, "nil value received from channel")
+	for {
+		var line []byte
+		select {
+		case <-d.Context().Done():
+			return nil, "", errors.ES(errors.OpQuery, errors.KInternal, "context cancelled")
+		case val := <-d.jsonData:
+			if val == nil {
+				return nil, "", errors.ES(errors.OpQuery, errors.KInternal, "nil value received from channel")
+			}
+			if err, ok := val.(error); ok {
+				return nil, "", err
+			}
+			line = val.([]byte)
 		}
-		if err, ok := val.(error); ok {
+
+		frameType, err := peekFrameType(line)
+		if err != nil {
 			return nil, "", err
 		}
-		line = val.([]byte)
-	}
+		d.countFrame(frameType)
 
-	frameType, err := peekFrameType(line)
-	if err != nil {
-		return nil, "", err
+		if knownFrameTypes[frameType] {
+			d.frameSize = len(line)
+			return json.NewDecoder(bytes.NewReader(line)), frameType, nil
+		}
+		if err := d.handleUnknownFrame(frameType, line); err != nil {
+			return nil, "", err
+		}
 	}
-
-	return json.NewDecoder(bytes.NewReader(line)), frameType, nil
 }
 
 // readDataSetCompletion reads the DataSetCompletion frame, and returns any errors it might contain.
@@ -259,13 +352,47 @@ func readPrimaryTable(d *iterativeDataset, dec *json.Decoder) error {
 			return err
 		}
 		if frameType == TableFragmentFrameType {
-			fragment := TableFragment{Columns: header.Columns, PreviousIndex: i}
+			fragment := TableFragment{Columns: header.Columns, PreviousIndex: i, lazy: d.lazyRows, reuse: d.reuseRows,
+				restartOnReplace: d.dataReplace == DataReplaceRestart}
 			err = dec.Decode(&fragment)
 			if err != nil {
-				return err
+				return inTable(err, header.TableName)
+			}
+			if stats := d.currentTableStats(); stats != nil {
+				stats.Fragments++
 			}
-			i += len(fragment.Rows)
+			if d.autoRowBufferBytes > 0 {
+				d.openTable(autoRowCapacity(d.autoRowBufferBytes, d.frameSize, fragment.rowCount()))
+			}
+			if fragment.TableFragmentType == TableFragmentDataReplace {
+				if d.stats != nil {
+					d.stats.DataReplaceFragments++
+				}
+				switch d.dataReplace {
+				case DataReplaceRestart:
+					d.replaceRows()
+					i = 0
+				case DataReplaceIgnore:
+				default:
+					// Rows that were already sent can't be taken back, so replacing them fails unless a policy is set.
+					return errors.ES(errors.OpQuery, errors.KInternal, "received a DataReplace fragment for table %d, which is not supported - "+
+						"disable progressive results for this query, or set a DataReplace policy", header.TableId)
+				}
+			}
+			i += fragment.rowCount()
 			if err = handleTableFragment(d, fragment); err != nil {
+				return inTable(err, header.TableName)
+			}
+			continue
+		}
+
+		// Progress frames are only sent in progressive datasets, and carry no rows.
+		if frameType == TableProgressFrameType && d.header.IsProgressive {
+			continue
+		}
+
+		if frameType == TableErrorFrameType {
+			if err = handleTableError(d, dec); err != nil {
 				return err
 			}
 			continue
@@ -278,6 +405,9 @@ func readPrimaryTable(d *iterativeDataset, dec *json.Decoder) error {
 				return err
 			}
 
+			if stats := d.currentTableStats(); stats != nil {
+				stats.CompletionRowCount = completion.RowCount
+			}
 			if err = handleTableCompletion(d, completion); err != nil {
 				return err
 			}
@@ -285,7 +415,7 @@ func readPrimaryTable(d *iterativeDataset, dec *json.Decoder) error {
 			break
 		}
 
-		return errors.ES(errors.OpQuery, errors.KInternal, "unexpected frame type %s, expected TableFragment or TableCompletion", frameType)
+		return errors.ES(errors.OpQuery, errors.KInternal, "unexpected frame type %s, expected TableFragment, TableProgress, TableError or TableCompletion", frameType)
 	}
 
 	return nil
@@ -294,7 +424,7 @@ func readPrimaryTable(d *iterativeDataset, dec *json.Decoder) error {
 // handleDataTable reads a DataTable frame from the dataset, which aren't iterative.
 // In Fragmented V2, these are only the metadata tables - QueryProperties and QueryCompletionInformation.
 func handleDataTable(d *iterativeDataset, dec *json.Decoder) error {
-	var dt DataTable
+	dt := DataTable{lazy: d.lazyRows}
 	if err := dec.Decode(&dt); err != nil {
 		return err
 	}
@@ -337,6 +467,7 @@ func handleTableCompletion(d *iterativeDataset, tc TableCompletion) error {
 		return errors.ES(d.Op(), errors.KInternal, "received a TableCompletion frame for table %d while table %d was open", tc.TableId, int((d.currentTable).Index()))
 	}
 
+	d.openTable(d.rowCapacity)
 	d.currentTable.finishTable(tc.OneApiErrors, nil)
 
 	d.currentTable = nil
@@ -344,12 +475,49 @@ func handleTableCompletion(d *iterativeDataset, tc TableCompletion) error {
 	return nil
 }
 
+// handleTableError reads a TableError frame, and reports its errors inline: in the rows of the table they happened in
+// if it is being read, or in the tables of the dataset otherwise. Unlike the errors of the completion frames, they don't
+// end the table or the dataset.
+func handleTableError(d *iterativeDataset, dec *json.Decoder) error {
+	te := TableError{}
+	if err := dec.Decode(&te); err != nil {
+		return err
+	}
+	if len(te.OneApiErrors) == 0 {
+		return errors.ES(d.Op(), errors.KInternal, "received a TableError frame for table %d without errors", te.TableId)
+	}
+
+	streamErr := &TableStreamError{TableId: te.TableId, Err: combineOneApiErrors(te.OneApiErrors)}
+	if d.currentTable != nil && int(d.currentTable.Index()) == te.TableId {
+		streamErr.TableName = d.currentTable.Name()
+		d.openTable(d.rowCapacity)
+		d.currentTable.reportError(streamErr)
+		return nil
+	}
+
+	select {
+	case d.results <- query.TableResultError(streamErr):
+	case <-d.Context().Done():
+	}
+	return nil
+}
+
 func handleTableFragment(d *iterativeDataset, tf TableFragment) error {
 	if d.currentTable == nil {
 		return errors.ES(d.Op(), errors.KInternal, "received a TableFragment frame while no streaming table was open")
 	}
 
-	d.currentTable.addRawRows(tf.Rows)
+	if tf.reuse {
+		if err := d.currentTable.addReusedRows(tf.startIndex(), tf.raw); err != nil {
+			return err
+		}
+	} else {
+		d.currentTable.addRawRows(tf.Rows)
+	}
+	d.rowsDelivered += int64(tf.rowCount())
+	if stats := d.currentTableStats(); stats != nil {
+		stats.RowsDelivered += tf.rowCount()
+	}
 
 	return nil
 }
@@ -367,7 +535,13 @@ func handleTableHeader(d *iterativeDataset, th TableHeader) error {
 	}
 
 	d.currentTable = t.(*iterativeTable)
-	d.sendTable(d.currentTable)
+	if d.stats != nil {
+		d.stats.Tables = append(d.stats.Tables, TableFrameStats{TableId: th.TableId, TableName: th.TableName, CompletionRowCount: -1})
+	}
+	// With AutoRowCapacity, the table is sent once the size of its rows is known, see openTable.
+	if d.currentTable.rows != nil {
+		d.sendTable(d.currentTable)
+	}
 
 	return nil
 }
@@ -382,6 +556,10 @@ func (d *iterativeDataset) sendTable(tb query.IterativeTable) {
 	}
 }
 
+func (d *iterativeDataset) TransferStats() query.TransferStats {
+	return query.DatasetTransferStats(d.BaseDataset)
+}
+
 // Tables returns a channel that sends the tables as they are parsed.
 func (d *iterativeDataset) Tables() <-chan query.TableResult {
 	return d.results
@@ -411,5 +589,10 @@ func (d *iterativeDataset) ToDataset() (query.Dataset, error) {
 		tables = append(tables, table)
 	}
 
+	// The error isn't sent to the results when the context is done, such as when the query is cancelled.
+	if d.err != nil {
+		return nil, d.err
+	}
+
 	return query.NewDataset(d, tables), nil
 }
@@ -2,6 +2,9 @@ package v2
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"github.com/Azure/azure-kusto-go/azkustodata/value"
 	"github.com/google/uuid"
@@ -54,6 +57,7 @@ func TestStreamingDataSet_DecodeTables_GetRows(t *testing.T) {
 		reader io.Reader
 	}{{name: "validFrames", reader: strings.NewReader(validFrames)},
 		{name: "aliases", reader: strings.NewReader(aliases)},
+		{name: "withoutNewlines", reader: strings.NewReader(strings.ReplaceAll(validFrames, "\n", ""))},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			reader := tt.reader
@@ -214,6 +218,37 @@ func TestStreamingDataSet_DecodeTables_GetRows(t *testing.T) {
 	}
 }
 
+func TestStreamingDataSet_TableLookups(t *testing.T) {
+	t.Parallel()
+	d, err := defaultDataset(strings.NewReader(twoTables))
+	require.NoError(t, err)
+
+	full, err := d.ToDataset()
+	require.NoError(t, err)
+
+	type lookup struct {
+		Index   int64
+		Ordinal int
+		Kind    string
+	}
+	var got []lookup
+	for _, tb := range full.Tables() {
+		got = append(got, lookup{Index: tb.Index(), Ordinal: query.TableOrdinal(tb), Kind: tb.Kind()})
+	}
+	assert.Equal(t, []lookup{
+		{Index: 1, Ordinal: 0, Kind: PrimaryResultTableKind},
+		{Index: 2, Ordinal: 1, Kind: PrimaryResultTableKind},
+		{Index: 0, Ordinal: -1, Kind: QueryPropertiesKind},
+		{Index: 3, Ordinal: -1, Kind: QueryCompletionInformationKind},
+	}, got)
+
+	assert.Equal(t, int64(2), query.PrimaryByOrdinal(full.Tables(), 1).Index())
+	assert.Nil(t, query.PrimaryByOrdinal(full.Tables(), 2))
+	assert.Equal(t, QueryPropertiesKind, query.TableByID(full.Tables(), 0).Kind())
+	assert.Equal(t, 1, query.TableOrdinal(query.TableByID(full.Tables(), 2)))
+	assert.Nil(t, query.TableByID(full.Tables(), 4))
+}
+
 func TestStreamingDataSet_MultiplePrimaryTables(t *testing.T) {
 	t.Parallel()
 	reader := strings.NewReader(twoTables)
@@ -254,6 +289,270 @@ func TestStreamingDataSet_MultiplePrimaryTables(t *testing.T) {
 	}
 }
 
+func TestStreamingDataSet_Progressive(t *testing.T) {
+	t.Parallel()
+	s := strings.Replace(twoTables, `"IsProgressive":false`, `"IsProgressive":true`, 1)
+	s = strings.Replace(s, "\n,{\"FrameType\":\"TableCompletion\"", "\n,{\"FrameType\":\"TableProgress\",\"TableId\":1,\"TableProgress\":50.0}\n,{\"FrameType\":\"TableCompletion\"", 1)
+	require.Contains(t, s, "TableProgress")
+
+	d, err := defaultDataset(strings.NewReader(s))
+	require.NoError(t, err)
+
+	full, err := d.ToDataset()
+	require.NoError(t, err)
+
+	rows, err := query.ToStructs[table1](full.Tables()[0])
+	require.NoError(t, err)
+	assert.Equal(t, []table1{{A: 1}, {A: 2}, {A: 3}}, rows)
+}
+
+func TestStreamingDataSet_ProgressiveDataReplace(t *testing.T) {
+	t.Parallel()
+	s := strings.Replace(twoTables, `"IsProgressive":false`, `"IsProgressive":true`, 1)
+	s = strings.Replace(s, `"TableFragmentType":"DataAppend"`, `"TableFragmentType":"DataReplace"`, 1)
+
+	d, err := defaultDataset(strings.NewReader(s))
+	require.NoError(t, err)
+
+	_, err = d.ToDataset()
+	require.ErrorContains(t, err, "DataReplace")
+}
+
+// withInStreamErrors makes twoTables report its errors in the stream, with an error inside the first table, and an error
+// of a table that isn't being read between the two tables.
+func withInStreamErrors() string {
+	const tableError = `{"FrameType":"TableError","TableId":%d,"OneApiErrors":[{"error":{"code":"LimitsExceeded","message":"Request is invalid and cannot be executed.","@type":"Kusto.Data.Exceptions.KustoServicePartialQueryFailureLimitsExceededException","@message":"Query execution has exceeded the allowed limits (80DA0003): .","@permanent":false}}]}`
+
+	s := strings.Replace(twoTables, `"Version":"v2.0"`, `"Version":"v2.1"`, 1)
+	s = strings.Replace(s, `"ErrorReportingPlacement":"EndOfTable"`, `"ErrorReportingPlacement":"InData"`, 1)
+	s = strings.Replace(s, "\n,{\"FrameType\":\"TableFragment\",\"TableFragmentType\":\"DataAppend\",\"TableId\":1,\"Rows\":[[2]", "\n,"+fmt.Sprintf(tableError, 1)+"\n,{\"FrameType\":\"TableFragment\",\"TableFragmentType\":\"DataAppend\",\"TableId\":1,\"Rows\":[[2]", 1)
+	return strings.Replace(s, "\n,{\"FrameType\":\"TableHeader\",\"TableId\":2", "\n,"+fmt.Sprintf(tableError, 5)+"\n,{\"FrameType\":\"TableHeader\",\"TableId\":2", 1)
+}
+
+func TestStreamingDataSet_InStreamErrors(t *testing.T) {
+	t.Parallel()
+
+	s := withInStreamErrors()
+	require.Equal(t, 2, strings.Count(s, "TableError"))
+
+	d, err := defaultDataset(strings.NewReader(s))
+	require.NoError(t, err)
+
+	type event struct {
+		Table int64
+		Row   int32
+		Err   int
+	}
+	var got []event
+	for result := range d.Tables() {
+		if result.Err() != nil {
+			var streamErr *TableStreamError
+			require.ErrorAs(t, result.Err(), &streamErr)
+			assert.Empty(t, streamErr.TableName)
+			got = append(got, event{Err: streamErr.TableId})
+			continue
+		}
+
+		tb := result.Table()
+		for row := range tb.Rows() {
+			if row.Err() != nil {
+				var streamErr *TableStreamError
+				require.ErrorAs(t, row.Err(), &streamErr)
+				assert.Equal(t, "PrimaryResult", streamErr.TableName)
+				assert.ErrorContains(t, row.Err(), "LimitsExceeded")
+				got = append(got, event{Table: tb.Index(), Err: streamErr.TableId})
+				continue
+			}
+			if tb.Index() == 1 {
+				v, err := row.Row().IntByName("A")
+				require.NoError(t, err)
+				got = append(got, event{Table: tb.Index(), Row: *v})
+			}
+		}
+	}
+
+	// The errors are reported where they happened, and the dataset goes on.
+	assert.Equal(t, []event{
+		{Table: 1, Row: 1},
+		{Table: 1, Err: 1},
+		{Table: 1, Row: 2},
+		{Table: 1, Row: 3},
+		{Err: 5},
+	}, got)
+}
+
+func TestStreamingDataSet_InStreamErrors_ToDataset(t *testing.T) {
+	t.Parallel()
+
+	d, err := defaultDataset(strings.NewReader(withInStreamErrors()))
+	require.NoError(t, err)
+
+	_, err = d.ToDataset()
+	var streamErr *TableStreamError
+	require.ErrorAs(t, err, &streamErr)
+	assert.Equal(t, 1, streamErr.TableId)
+}
+
+func TestStreamingDataSet_ErrorReportingPlacement(t *testing.T) {
+	t.Parallel()
+
+	s := strings.Replace(twoTables, `"ErrorReportingPlacement":"EndOfTable"`, `"ErrorReportingPlacement":"EndOfDataSet"`, 1)
+	d, err := defaultDataset(strings.NewReader(s))
+	require.NoError(t, err)
+
+	_, err = d.ToDataset()
+	assert.ErrorContains(t, err, "EndOfDataSet")
+}
+
+func frameStatsDataset(t *testing.T, s string) (query.IterativeDataset, <-chan FrameStats) {
+	stats := make(chan FrameStats, 1)
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(s)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity,
+		WithFrameStats(func(s FrameStats) { stats <- s }))
+	require.NoError(t, err)
+	return d, stats
+}
+
+func TestStreamingDataSet_FrameStats(t *testing.T) {
+	t.Parallel()
+	s := strings.Replace(twoTables, `"IsProgressive":false`, `"IsProgressive":true`, 1)
+	s = strings.Replace(s, "\n,{\"FrameType\":\"TableCompletion\"", "\n,{\"FrameType\":\"TableProgress\",\"TableId\":1,\"TableProgress\":50.0}\n,{\"FrameType\":\"TableCompletion\"", 1)
+
+	d, stats := frameStatsDataset(t, s)
+	_, err := d.ToDataset()
+	require.NoError(t, err)
+
+	assert.Equal(t, FrameStats{
+		Progressive: true,
+		Frames: map[FrameType]int{
+			DataSetHeaderFrameType:     1,
+			DataTableFrameType:         2,
+			TableHeaderFrameType:       2,
+			TableFragmentFrameType:     4,
+			TableProgressFrameType:     1,
+			TableCompletionFrameType:   2,
+			DataSetCompletionFrameType: 1,
+		},
+		Tables: []TableFrameStats{
+			{TableId: 1, TableName: "PrimaryResult", Fragments: 2, RowsDelivered: 3, CompletionRowCount: 3},
+			{TableId: 2, TableName: "PrimaryResult", Fragments: 2, RowsDelivered: 3, CompletionRowCount: 3},
+		},
+	}, <-stats)
+}
+
+func TestStreamingDataSet_FrameStatsDataReplace(t *testing.T) {
+	t.Parallel()
+	s := strings.Replace(twoTables, `"IsProgressive":false`, `"IsProgressive":true`, 1)
+	s = strings.Replace(s, `"Rows":[[2], [3]]`, `"Rows":[[2], [3]]}`+"\n"+`,{"FrameType":"TableFragment","TableFragmentType":"DataReplace","TableId":1,"Rows":[[1], [2], [3]]`, 1)
+
+	d, stats := frameStatsDataset(t, s)
+	_, err := d.ToDataset()
+	require.ErrorContains(t, err, "DataReplace")
+
+	got := <-stats
+	assert.Equal(t, 1, got.DataReplaceFragments)
+	assert.Equal(t, 3, got.Frames[TableFragmentFrameType])
+	assert.Equal(t, []TableFrameStats{{TableId: 1, TableName: "PrimaryResult", Fragments: 3, RowsDelivered: 3, CompletionRowCount: -1}}, got.Tables)
+	assert.ErrorContains(t, got.Err, "DataReplace")
+}
+
+// withPreviewFrames adds frames of an unknown type to twoTables, between two frames of the dataset and inside a table.
+func withPreviewFrames() string {
+	s := strings.Replace(twoTables, "\n,{\"FrameType\":\"TableHeader\",\"TableId\":1", "\n,{\"FrameType\":\"Preview\",\"Value\":1}\n,{\"FrameType\":\"TableHeader\",\"TableId\":1", 1)
+	return strings.Replace(s, "\n,{\"FrameType\":\"TableCompletion\",\"TableId\":2", "\n,{\"FrameType\":\"Preview\",\"Value\":2}\n,{\"FrameType\":\"TableCompletion\",\"TableId\":2", 1)
+}
+
+func TestStreamingDataSet_UnknownFrames(t *testing.T) {
+	t.Parallel()
+
+	type preview struct {
+		FrameType FrameType
+		Value     int
+	}
+
+	tests := []struct {
+		desc       string
+		options    func(got *[]preview) []DatasetOption
+		wantFrames []preview
+		wantErr    string
+		wantSkip   int
+	}{
+		{
+			desc:     "Skipped by default",
+			options:  func(got *[]preview) []DatasetOption { return nil },
+			wantSkip: 2,
+		},
+		{
+			desc: "Registered handler",
+			options: func(got *[]preview) []DatasetOption {
+				return []DatasetOption{WithFrameHandler("Preview", func(frameType FrameType, frame []byte) error {
+					p := preview{}
+					if err := json.Unmarshal(frame, &p); err != nil {
+						return err
+					}
+					*got = append(*got, p)
+					return nil
+				})}
+			},
+			wantFrames: []preview{{FrameType: "Preview", Value: 1}, {FrameType: "Preview", Value: 2}},
+		},
+		{
+			desc: "Unknown frame handler",
+			options: func(got *[]preview) []DatasetOption {
+				return []DatasetOption{WithUnknownFrameHandler(func(frameType FrameType, frame []byte) error {
+					*got = append(*got, preview{FrameType: frameType})
+					return nil
+				})}
+			},
+			wantFrames: []preview{{FrameType: "Preview"}, {FrameType: "Preview"}},
+		},
+		{
+			desc: "Handler error",
+			options: func(got *[]preview) []DatasetOption {
+				return []DatasetOption{WithUnknownFrameHandler(func(frameType FrameType, frame []byte) error {
+					return fmt.Errorf("unsupported frame %s", frameType)
+				})}
+			},
+			wantErr: "unsupported frame Preview",
+		},
+		{
+			desc: "Handlers of known frames are ignored",
+			options: func(got *[]preview) []DatasetOption {
+				return []DatasetOption{WithFrameHandler(TableProgressFrameType, func(frameType FrameType, frame []byte) error {
+					*got = append(*got, preview{FrameType: frameType})
+					return nil
+				})}
+			},
+			wantSkip: 2,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var got []preview
+			stats := make(chan FrameStats, 1)
+			options := append(test.options(&got), WithFrameStats(func(s FrameStats) { stats <- s }))
+			d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(withPreviewFrames())), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, options...)
+			require.NoError(t, err)
+
+			full, err := d.ToDataset()
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, full.Tables(), 4)
+
+			assert.Equal(t, test.wantFrames, got)
+			s := <-stats
+			assert.Equal(t, test.wantSkip, s.SkippedFrames)
+			assert.Equal(t, 2, s.Frames["Preview"])
+		})
+	}
+}
+
 func TestStreamingDataSet_DecodeTables_WithInvalidDataSetHeader(t *testing.T) {
 	t.Parallel()
 	s := twoTables
@@ -364,3 +663,59 @@ func TestStreamingDataSet_Context_Canceled(t *testing.T) {
 	assert.NoError(t, err)
 	cancel()
 }
+
+func TestStreamingDataSet_LazyRows(t *testing.T) {
+	t.Parallel()
+
+	eager, err := defaultDataset(strings.NewReader(validFrames))
+	require.NoError(t, err)
+	want, err := eager.ToDataset()
+	require.NoError(t, err)
+
+	lazy, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(validFrames)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, LazyRows())
+	require.NoError(t, err)
+	got, err := lazy.ToDataset()
+	require.NoError(t, err)
+
+	require.Len(t, got.Tables(), len(want.Tables()))
+	for i, table := range got.Tables() {
+		wantRows := want.Tables()[i].Rows()
+		require.Len(t, table.Rows(), len(wantRows))
+		for j, row := range table.Rows() {
+			// Access a single cell first, so that the rest of the row is decoded after it.
+			last := len(row.Columns()) - 1
+			v, err := row.Value(last)
+			require.NoError(t, err)
+			assert.Equal(t, wantRows[j].Values()[last], v)
+
+			assert.Equal(t, wantRows[j].Values(), row.Values())
+			assert.Equal(t, wantRows[j].Index(), row.Index())
+		}
+	}
+}
+
+func TestStreamingDataSet_CellError(t *testing.T) {
+	t.Parallel()
+
+	s := strings.Replace(validFrames, `"123e27de-1e4e-49d9-b579-fe0b331d3642"`, `"not a guid"`, 1)
+
+	d, err := defaultDataset(strings.NewReader(s))
+	require.NoError(t, err)
+	_, err = d.ToDataset()
+	var cellErr *errors.CellError
+	require.ErrorAs(t, err, &cellErr)
+	assert.Equal(t, "AllDataTypes", cellErr.Table)
+	assert.Equal(t, 0, cellErr.Row)
+	assert.Equal(t, "vguid", cellErr.Column)
+	assert.Contains(t, err.Error(), `in row 0, column "vguid" of table "AllDataTypes"`)
+
+	// Lazy rows fail when the cell is accessed.
+	d, err = NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(s)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, LazyRows())
+	require.NoError(t, err)
+	dataset, err := d.ToDataset()
+	require.NoError(t, err)
+	_, err = dataset.Tables()[0].Rows()[0].ValueByName("vguid")
+	require.ErrorAs(t, err, &cellErr)
+	assert.Equal(t, 0, cellErr.Row)
+	assert.Equal(t, "vguid", cellErr.Column)
+}
@@ -2,6 +2,7 @@ package v2
 
 import (
 	"context"
+	goErrors "errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"sync/atomic"
 )
@@ -17,6 +18,8 @@ type iterativeTable struct {
 	rowCount atomic.Uint32
 	// a context for the table
 	ctx context.Context
+	// buffer holds the reused rows of the table, see ReuseRows.
+	buffer *query.RowBuffer
 }
 
 // addRawRows is called by the dataset to add rows to the table.
@@ -30,6 +33,22 @@ func (t *iterativeTable) addRawRows(rows []query.Row) {
 	}
 }
 
+// addReusedRows is called by the dataset to decode raw rows into the reused rows of the table, and add them to it.
+// The rows channel is unbuffered, so a row is decoded again only after the row sent after it is received.
+func (t *iterativeTable) addReusedRows(startIndex int, raw [][]interface{}) error {
+	for i, cells := range raw {
+		row, err := t.buffer.Decode(startIndex+i, cells)
+		if err != nil {
+			return err
+		}
+		if !t.reportRow(row) {
+			return nil
+		}
+		t.rowCount.Add(1)
+	}
+	return nil
+}
+
 // RowCount returns the current number of rows in the table.
 func (t *iterativeTable) RowCount() int {
 	return int(t.rowCount.Load())
@@ -40,7 +59,13 @@ func (t *iterativeTable) setRowCount(rowCount int) {
 }
 
 func NewIterativeTable(dataset *iterativeDataset, th TableHeader) (query.IterativeTable, error) {
-	baseTable, err := newBaseTableFromHeader(dataset, th)
+	ordinal := -1
+	if th.TableKind == PrimaryResultTableKind {
+		ordinal = dataset.primaryTables
+		dataset.primaryTables++
+	}
+
+	baseTable, err := newBaseTableFromHeader(dataset, th, ordinal)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +75,12 @@ func NewIterativeTable(dataset *iterativeDataset, th TableHeader) (query.Iterati
 		ctx:       dataset.Context(),
 		rows:      make(chan query.RowResult, dataset.rowCapacity),
 	}
+	if dataset.reuseRows {
+		t.rows = make(chan query.RowResult)
+		t.buffer = query.NewRowBuffer(baseTable.Columns(), baseTable.ColumnByName)
+	} else if dataset.autoRowBufferBytes > 0 {
+		t.rows = nil
+	}
 
 	return t, nil
 }
@@ -81,6 +112,10 @@ func (t *iterativeTable) reportError(err error) bool {
 	}
 }
 
+func (t *iterativeTable) Ordinal() int {
+	return query.TableOrdinal(t.BaseTable)
+}
+
 // Rows returns a channel of rows and errors.
 func (t *iterativeTable) Rows() <-chan query.RowResult {
 	return t.rows
@@ -90,10 +125,19 @@ func (t *iterativeTable) Rows() <-chan query.RowResult {
 func (t *iterativeTable) ToTable() (query.Table, error) {
 	var rows []query.Row
 	for r := range t.rows {
+		var replaced *RowsReplacedError
+		if goErrors.As(r.Err(), &replaced) {
+			rows = rows[:0]
+			continue
+		}
 		if r.Err() != nil {
 			return nil, r.Err()
 		} else {
-			rows = append(rows, r.Row())
+			row := r.Row()
+			if c, ok := row.(query.Cloner); ok && t.buffer != nil {
+				row = c.Clone()
+			}
+			rows = append(rows, row)
 		}
 	}
 
@@ -0,0 +1,151 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeDataset builds a fragmented dataset with a primary table of fragments*rows rows of typical values, and the
+// secondary tables of validFrames.
+func largeDataset(fragments int, rows int) string {
+	lines := strings.Split(validFrames, "\n")
+	var sb strings.Builder
+	sb.WriteString(lines[0] + "\n" + lines[1] + "\n")
+	sb.WriteString(`,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"Events","Columns":[{"ColumnName":"Id","ColumnType":"long"},{"ColumnName":"Time","ColumnType":"datetime"},{"ColumnName":"Message","ColumnType":"string"},{"ColumnName":"Props","ColumnType":"dynamic"},{"ColumnName":"Score","ColumnType":"real"},{"ColumnName":"Ok","ColumnType":"bool"},{"ColumnName":"Key","ColumnType":"guid"}]}` + "\n")
+	for f := 0; f < fragments; f++ {
+		sb.WriteString(",")
+		sb.Write(largeFragment(rows))
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, `,{"FrameType":"TableCompletion","TableId":1,"RowCount":%d}`+"\n", fragments*rows)
+	sb.WriteString(strings.Join(lines[5:], "\n"))
+	return sb.String()
+}
+
+// primaryRows returns the rows of the single primary table of d.
+func primaryRows(t *testing.T, d query.Dataset) []query.Row {
+	for _, table := range d.Tables() {
+		if table.IsPrimaryResult() {
+			return table.Rows()
+		}
+	}
+	require.Fail(t, "no primary table")
+	return nil
+}
+
+func TestStreamingDataSet_ReuseRows(t *testing.T) {
+	t.Parallel()
+
+	data := largeDataset(2, 3)
+
+	eager, err := defaultDataset(strings.NewReader(data))
+	require.NoError(t, err)
+	want, err := eager.ToDataset()
+	require.NoError(t, err)
+	wantRows := primaryRows(t, want)
+
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(data)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, ReuseRows())
+	require.NoError(t, err)
+	var received []query.Row
+	var kept []query.Row
+	for tr := range d.Tables() {
+		require.NoError(t, tr.Err())
+		if !tr.Table().IsPrimaryResult() {
+			continue
+		}
+		for r := range tr.Table().Rows() {
+			require.NoError(t, r.Err())
+			assert.Equal(t, wantRows[len(received)].Values(), r.Row().Values())
+			assert.Equal(t, len(received), r.Row().Index())
+			received = append(received, r.Row())
+			kept = append(kept, r.Row().(query.Cloner).Clone())
+		}
+	}
+
+	require.Len(t, received, len(wantRows))
+	// The rows alternate between two reused rows, while the clones keep their values.
+	for i := 2; i < len(received); i++ {
+		assert.Same(t, received[i-2], received[i])
+	}
+	assert.NotSame(t, received[0], received[1])
+	for i, row := range kept {
+		assert.Equal(t, wantRows[i].Values(), row.Values())
+		assert.Equal(t, i, row.Index())
+	}
+
+	// ToDataset clones the rows.
+	d, err = NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(data)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, ReuseRows())
+	require.NoError(t, err)
+	got, err := d.ToDataset()
+	require.NoError(t, err)
+	require.Len(t, got.Tables(), len(want.Tables()))
+	gotRows := primaryRows(t, got)
+	require.Len(t, gotRows, len(wantRows))
+	for i, row := range gotRows {
+		assert.Equal(t, wantRows[i].Values(), row.Values())
+	}
+}
+
+func TestStreamingDataSet_ReuseRowsCellError(t *testing.T) {
+	t.Parallel()
+
+	s := strings.Replace(validFrames, `"123e27de-1e4e-49d9-b579-fe0b331d3642"`, `"not a guid"`, 1)
+
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(s)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, ReuseRows())
+	require.NoError(t, err)
+	_, err = d.ToDataset()
+	var cellErr *errors.CellError
+	require.ErrorAs(t, err, &cellErr)
+	assert.Equal(t, "AllDataTypes", cellErr.Table)
+	assert.Equal(t, 0, cellErr.Row)
+	assert.Equal(t, "vguid", cellErr.Column)
+}
+
+// BenchmarkIterateRows compares the allocations of iterating over the rows of a large table, reading every cell, with
+// the default rows, LazyRows and ReuseRows.
+func BenchmarkIterateRows(b *testing.B) {
+	data := largeDataset(10, 1000)
+
+	for _, mode := range []struct {
+		name    string
+		options []DatasetOption
+	}{
+		{name: "Default"},
+		{name: "LazyRows", options: []DatasetOption{LazyRows()}},
+		{name: "ReuseRows", options: []DatasetOption{ReuseRows()}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(data)), DefaultIoCapacity, DefaultRowCapacity, DefaultTableCapacity, mode.options...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for tr := range d.Tables() {
+					if tr.Err() != nil {
+						b.Fatal(tr.Err())
+					}
+					for r := range tr.Table().Rows() {
+						if r.Err() != nil {
+							b.Fatal(r.Err())
+						}
+						for c := range r.Row().Columns() {
+							if _, err := r.Row().Value(c); err != nil {
+								b.Fatal(err)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
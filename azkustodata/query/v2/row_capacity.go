@@ -0,0 +1,58 @@
+package v2
+
+import "github.com/Azure/azure-kusto-go/azkustodata/query"
+
+// Bounds of the row capacity chosen by AutoRowCapacity.
+const (
+	MinAutoRowCapacity = 100
+	MaxAutoRowCapacity = 100_000
+)
+
+// DefaultAutoRowBufferBytes is a size of the row buffers of AutoRowCapacity that keeps up with fast networks.
+const DefaultAutoRowBufferBytes = 8 << 20
+
+// AutoRowCapacity sizes the buffer of rows of every primary table from the average size of the rows of its first
+// fragment, as they are in the response, so that the buffer holds about bufferBytes of rows: tables of small rows get a
+// larger buffer, which keeps the decoder ahead of the consumer on fast networks, and tables of large rows a smaller one,
+// which bounds the memory used. The capacity is between MinAutoRowCapacity and MaxAutoRowCapacity, and tables without
+// rows use the row capacity of the dataset.
+// The primary tables are sent once their first fragment is read, instead of their header. It has no effect with
+// ReuseRows, whose rows aren't buffered.
+func AutoRowCapacity(bufferBytes int) DatasetOption {
+	return func(d *iterativeDataset) {
+		d.autoRowBufferBytes = bufferBytes
+	}
+}
+
+// autoRowCapacity returns the row capacity of a table whose first fragment holds rows rows in size bytes.
+func autoRowCapacity(bufferBytes, size, rows int) int {
+	if rows <= 0 || size <= 0 {
+		return MinAutoRowCapacity
+	}
+	rowSize := size / rows
+	if rowSize == 0 {
+		rowSize = 1
+	}
+	capacity := bufferBytes / rowSize
+	if capacity < MinAutoRowCapacity {
+		return MinAutoRowCapacity
+	}
+	if capacity > MaxAutoRowCapacity {
+		return MaxAutoRowCapacity
+	}
+	return capacity
+}
+
+// openTable creates the buffer of rows of the current table with capacity, and sends the table, if it was held back
+// by AutoRowCapacity until then.
+func (d *iterativeDataset) openTable(capacity int) {
+	t := d.currentTable
+	if t == nil || t.rows != nil {
+		return
+	}
+	t.rows = make(chan query.RowResult, capacity)
+	if stats := d.currentTableStats(); stats != nil {
+		stats.RowCapacity = capacity
+	}
+	d.sendTable(t)
+}
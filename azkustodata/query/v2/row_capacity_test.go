@@ -0,0 +1,130 @@
+package v2
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoRowCapacity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		bufferBytes int
+		size, rows  int
+		want        int
+	}{
+		{desc: "small rows", bufferBytes: DefaultAutoRowBufferBytes, size: 1000, rows: 100, want: MaxAutoRowCapacity},
+		{desc: "large rows", bufferBytes: DefaultAutoRowBufferBytes, size: 10 << 20, rows: 10, want: MinAutoRowCapacity},
+		{desc: "in between", bufferBytes: DefaultAutoRowBufferBytes, size: 1_000_000, rows: 1000, want: 8388},
+		{desc: "no rows", bufferBytes: DefaultAutoRowBufferBytes, size: 100, rows: 0, want: MinAutoRowCapacity},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, autoRowCapacity(test.bufferBytes, test.size, test.rows), test.desc)
+	}
+}
+
+func TestStreamingDataSet_AutoRowCapacity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		bufferBytes int
+		check       func(t *testing.T, first, second int)
+	}{
+		{
+			desc:        "sized by rows",
+			bufferBytes: 20_000,
+			check: func(t *testing.T, first, second int) {
+				assert.Greater(t, first, MinAutoRowCapacity)
+				// The rows of the second table are larger.
+				assert.Greater(t, first, second)
+			},
+		},
+		{
+			desc:        "minimum",
+			bufferBytes: 1,
+			check: func(t *testing.T, first, second int) {
+				assert.Equal(t, MinAutoRowCapacity, first)
+				assert.Equal(t, MinAutoRowCapacity, second)
+			},
+		},
+		{
+			desc:        "maximum",
+			bufferBytes: 1 << 30,
+			check: func(t *testing.T, first, second int) {
+				assert.Equal(t, MaxAutoRowCapacity, first)
+				assert.Equal(t, MaxAutoRowCapacity, second)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			stats := make(chan FrameStats, 1)
+			d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(twoTables)), DefaultIoCapacity, DefaultRowCapacity,
+				DefaultTableCapacity, AutoRowCapacity(test.bufferBytes), WithFrameStats(func(s FrameStats) { stats <- s }))
+			require.NoError(t, err)
+
+			var capacities []int
+			var values []interface{}
+			for tr := range d.Tables() {
+				require.NoError(t, tr.Err())
+				rows := tr.Table().Rows()
+				if tr.Table().IsPrimaryResult() {
+					capacities = append(capacities, cap(rows))
+				}
+				for rr := range rows {
+					require.NoError(t, rr.Err())
+					if tr.Table().IsPrimaryResult() {
+						values = append(values, rr.Row().Values()[0].GetValue())
+					}
+				}
+			}
+
+			s := <-stats
+			require.Len(t, s.Tables, 2)
+			assert.Equal(t, []int{s.Tables[0].RowCapacity, s.Tables[1].RowCapacity}, capacities)
+			test.check(t, capacities[0], capacities[1])
+			assert.Len(t, values, 6)
+		})
+	}
+}
+
+func TestStreamingDataSet_AutoRowCapacityWithoutFragments(t *testing.T) {
+	t.Parallel()
+
+	// The first table has no fragments, and the dataset fails before the first fragment of the second one.
+	s := strings.Replace(twoTables, "\n,{\"FrameType\":\"TableFragment\",\"TableFragmentType\":\"DataAppend\",\"TableId\":1,\"Rows\":[[1]]}", "", 1)
+	s = strings.Replace(s, "\n,{\"FrameType\":\"TableFragment\",\"TableFragmentType\":\"DataAppend\",\"TableId\":1,\"Rows\":[[2], [3]]}", "", 1)
+	s = s[:strings.Index(s, "\n,{\"FrameType\":\"TableFragment\",\"TableFragmentType\":\"DataAppend\",\"TableId\":2")]
+
+	d, err := NewIterativeDataset(context.Background(), io.NopCloser(strings.NewReader(s)), DefaultIoCapacity, DefaultRowCapacity,
+		DefaultTableCapacity, AutoRowCapacity(DefaultAutoRowBufferBytes))
+	require.NoError(t, err)
+
+	var tables int
+	var lastErr error
+	for tr := range d.Tables() {
+		if tr.Err() != nil {
+			lastErr = tr.Err()
+			continue
+		}
+		tables++
+		assert.Equal(t, DefaultRowCapacity, cap(tr.Table().Rows()))
+		for rr := range tr.Table().Rows() {
+			require.NoError(t, rr.Err())
+		}
+	}
+	assert.Equal(t, 1, tables)
+	assert.Error(t, lastErr)
+}
@@ -0,0 +1,405 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// RowsDecoder decodes the rows of TableFragment and DataTable frames, which is where most of the time is spent when
+// reading large results. It can be replaced with SetRowsDecoder, for example to use a high-performance JSON library.
+type RowsDecoder interface {
+	// DecodeRows decodes data, a JSON array of rows where every row is a JSON array of values.
+	// The values are passed to value.Kusto.Unmarshal, so they must have the types returned by a json.Decoder with
+	// UseNumber(): nil, bool, string and json.Number. Nested arrays and objects must be returned as their raw JSON, in
+	// a []byte.
+	DecodeRows(data []byte) ([][]interface{}, error)
+}
+
+var rowsDecoderLock sync.RWMutex
+var rowsDecoder RowsDecoder = StandardRowsDecoder{}
+
+// SetRowsDecoder replaces the RowsDecoder used by all datasets. It should be called once, before running queries:
+//
+//	v2.SetRowsDecoder(v2.ScanningRowsDecoder{})
+func SetRowsDecoder(d RowsDecoder) {
+	rowsDecoderLock.Lock()
+	defer rowsDecoderLock.Unlock()
+	rowsDecoder = d
+}
+
+func currentRowsDecoder() RowsDecoder {
+	rowsDecoderLock.RLock()
+	defer rowsDecoderLock.RUnlock()
+	return rowsDecoder
+}
+
+// StandardRowsDecoder is the default RowsDecoder, which reads the rows token by token with encoding/json.
+type StandardRowsDecoder struct{}
+
+func (StandardRowsDecoder) DecodeRows(data []byte) ([][]interface{}, error) {
+	decoder := newDecoder(bytes.NewReader(data))
+
+	if err := assertToken(decoder, json.Delim('[')); err != nil {
+		return nil, err
+	}
+
+	var rows [][]interface{}
+	for decoder.More() {
+		if err := assertToken(decoder, json.Delim('[')); err != nil {
+			return nil, err
+		}
+
+		var row []interface{}
+		for decoder.More() {
+			t, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			// Handle nested values
+			if t == json.Delim('[') || t == json.Delim('{') {
+				t, err = decodeNestedValue(decoder, data)
+				if err != nil {
+					return nil, err
+				}
+			}
+			row = append(row, t)
+		}
+
+		if err := assertToken(decoder, json.Delim(']')); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	if err := assertToken(decoder, json.Delim(']')); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.ES(errors.OpQuery, errors.KFailedToParse, "unexpected data after the rows")
+	}
+	return rows, nil
+}
+
+// ScanningRowsDecoder is a RowsDecoder that scans the rows directly, without the overhead of a tokenizing decoder.
+// It is about twice as fast as StandardRowsDecoder on large fragments, and allocates less. It validates the JSON less
+// strictly, for example it doesn't check the syntax of numbers, which are validated when the values are unmarshalled.
+type ScanningRowsDecoder struct{}
+
+func (ScanningRowsDecoder) DecodeRows(data []byte) ([][]interface{}, error) {
+	s := rowScanner{data: data}
+
+	if err := s.expect('['); err != nil {
+		return nil, err
+	}
+
+	var rows [][]interface{}
+	if s.peekIs(']') {
+		s.pos++
+		return rows, s.end()
+	}
+
+	for {
+		row, err := s.row()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+
+		more, err := s.next(']')
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return rows, s.end()
+		}
+	}
+}
+
+// rowScanner holds the state of ScanningRowsDecoder.
+type rowScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *rowScanner) errorf(format string, args ...interface{}) error {
+	return errors.ES(errors.OpQuery, errors.KFailedToParse, "invalid rows at offset %d: "+format, append([]interface{}{s.pos}, args...)...)
+}
+
+func (s *rowScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *rowScanner) peekIs(c byte) bool {
+	s.skipSpace()
+	return s.pos < len(s.data) && s.data[s.pos] == c
+}
+
+func (s *rowScanner) expect(c byte) error {
+	if !s.peekIs(c) {
+		return s.errorf("expected '%c'", c)
+	}
+	s.pos++
+	return nil
+}
+
+// next consumes a comma, returning true, or the closing delimiter, returning false.
+func (s *rowScanner) next(closing byte) (bool, error) {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return false, s.errorf("unexpected end of data")
+	}
+	switch s.data[s.pos] {
+	case ',':
+		s.pos++
+		return true, nil
+	case closing:
+		s.pos++
+		return false, nil
+	}
+	return false, s.errorf("expected ',' or '%c', got '%c'", closing, s.data[s.pos])
+}
+
+// end makes sure there's nothing but whitespace after the rows.
+func (s *rowScanner) end() error {
+	s.skipSpace()
+	if s.pos != len(s.data) {
+		return s.errorf("unexpected data after the rows")
+	}
+	return nil
+}
+
+func (s *rowScanner) row() ([]interface{}, error) {
+	if err := s.expect('['); err != nil {
+		return nil, err
+	}
+
+	row := make([]interface{}, 0, 8)
+	if s.peekIs(']') {
+		s.pos++
+		return row, nil
+	}
+
+	for {
+		v, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, v)
+
+		more, err := s.next(']')
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return row, nil
+		}
+	}
+}
+
+func (s *rowScanner) value() (interface{}, error) {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return nil, s.errorf("unexpected end of data")
+	}
+
+	switch c := s.data[s.pos]; {
+	case c == '"':
+		return s.string()
+	case c == '[' || c == '{':
+		return s.nested()
+	case c == 'n':
+		return nil, s.literal("null")
+	case c == 't':
+		return true, s.literal("true")
+	case c == 'f':
+		return false, s.literal("false")
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := s.pos
+		for s.pos < len(s.data) && isNumberByte(s.data[s.pos]) {
+			s.pos++
+		}
+		return json.Number(s.data[start:s.pos]), nil
+	default:
+		return nil, s.errorf("unexpected character '%c'", c)
+	}
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
+
+func (s *rowScanner) literal(lit string) error {
+	if !bytes.HasPrefix(s.data[s.pos:], []byte(lit)) {
+		return s.errorf("expected %s", lit)
+	}
+	s.pos += len(lit)
+	return nil
+}
+
+// string reads a string. Strings without escape sequences are sliced directly, others are unquoted.
+func (s *rowScanner) string() (string, error) {
+	start := s.pos
+	escaped, err := s.skipString()
+	if err != nil {
+		return "", err
+	}
+
+	raw := s.data[start:s.pos]
+	// Invalid UTF-8 is replaced by json.Unmarshal, like encoding/json does.
+	if !escaped && utf8.Valid(raw) {
+		return string(raw[1 : len(raw)-1]), nil
+	}
+
+	if str, ok := unescape(raw[1 : len(raw)-1]); ok {
+		return str, nil
+	}
+
+	// Fall back to encoding/json for invalid input, so the error (or replacement) is the same as the standard decoder.
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return "", s.errorf("%s", err)
+	}
+	return str, nil
+}
+
+// unescape decodes the escape sequences of a JSON string. It returns false if the string is not valid UTF-8, or has
+// invalid escape sequences.
+func unescape(b []byte) (string, bool) {
+	if !utf8.Valid(b) {
+		return "", false
+	}
+
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c != '\\' {
+			if c < ' ' {
+				return "", false
+			}
+			out = append(out, c)
+			continue
+		}
+
+		i++
+		if i >= len(b) {
+			return "", false
+		}
+		switch b[i] {
+		case '"', '\\', '/':
+			out = append(out, b[i])
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			r, ok := hexRune(b[i+1:])
+			if !ok {
+				return "", false
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				// A surrogate pair is two escape sequences. A lone surrogate is replaced, like encoding/json does.
+				r2, ok := rune(-1), false
+				if i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+					r2, ok = hexRune(b[i+3:])
+				}
+				if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+					r = dec
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			out = utf8.AppendRune(out, r)
+		default:
+			return "", false
+		}
+	}
+	return string(out), true
+}
+
+// hexRune parses the 4 hex digits of a \u escape sequence.
+func hexRune(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
+
+// skipString moves past a string, and reports whether it contains escape sequences.
+func (s *rowScanner) skipString() (bool, error) {
+	escaped := false
+	s.pos++
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			return escaped, nil
+		}
+		s.pos++
+	}
+	return false, s.errorf("unterminated string")
+}
+
+// nested returns the raw JSON of a nested array or object.
+func (s *rowScanner) nested() ([]byte, error) {
+	start := s.pos
+	depth := 0
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '"':
+			if _, err := s.skipString(); err != nil {
+				return nil, err
+			}
+			continue
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				s.pos++
+				return s.data[start:s.pos], nil
+			}
+		}
+		s.pos++
+	}
+	return nil, s.errorf("unterminated value starting at offset %d", start)
+}
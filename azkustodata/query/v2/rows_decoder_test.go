@@ -0,0 +1,133 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rowsDecoders = []struct {
+	name    string
+	decoder RowsDecoder
+}{
+	{"standard", StandardRowsDecoder{}},
+	{"scanning", ScanningRowsDecoder{}},
+}
+
+func TestRowsDecoders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  [][]interface{}
+	}{
+		{name: "empty", input: `[]`, want: nil},
+		{name: "empty row", input: `[[]]`, want: [][]interface{}{nil}},
+		{
+			name:  "scalars",
+			input: `[[1,-2.5e3,"a",true,false,null],[0,1E2,"",false,true,null]]`,
+			want: [][]interface{}{
+				{json.Number("1"), json.Number("-2.5e3"), "a", true, false, nil},
+				{json.Number("0"), json.Number("1E2"), "", false, true, nil},
+			},
+		},
+		{
+			name:  "whitespace",
+			input: " [ [ 1 , \"a\" ] ,\n [ 2 , \"b\" ] ] ",
+			want:  [][]interface{}{{json.Number("1"), "a"}, {json.Number("2"), "b"}},
+		},
+		{
+			name:  "escapes",
+			input: `[["a\"b","\\","line\nbreak","\u00e9\ud83d\ude00","é","\/\b\f\r\t","\ud83d"]]`,
+			want:  [][]interface{}{{"a\"b", "\\", "line\nbreak", "é😀", "é", "/\b\f\r\t", "\ufffd"}},
+		},
+		{
+			name:  "nested",
+			input: `[[{"a":[1,2],"b":"]}"},[1,[2]],"x"]]`,
+			want:  [][]interface{}{{[]byte(`{"a":[1,2],"b":"]}"}`), []byte(`[1,[2]]`), "x"}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		for _, d := range rowsDecoders {
+			d := d // capture
+			t.Run(test.name+"/"+d.name, func(t *testing.T) {
+				t.Parallel()
+
+				rows, err := d.decoder.DecodeRows([]byte(test.input))
+				require.NoError(t, err)
+
+				// The decoders may allocate empty rows differently.
+				for i := range rows {
+					if len(rows[i]) == 0 {
+						rows[i] = nil
+					}
+				}
+				assert.Equal(t, test.want, rows)
+			})
+		}
+	}
+}
+
+func TestRowsDecodersErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		``,
+		`[`,
+		`[[1,2]`,
+		`[[1 2]]`,
+		`[["unterminated]]`,
+		`[[{"a":1]]`,
+		`[[nul]]`,
+		`[[1]]x`,
+	} {
+		input := input // capture
+		for _, d := range rowsDecoders {
+			d := d // capture
+			t.Run(fmt.Sprintf("%q/%s", input, d.name), func(t *testing.T) {
+				t.Parallel()
+
+				_, err := d.decoder.DecodeRows([]byte(input))
+				assert.Error(t, err)
+			})
+		}
+	}
+}
+
+// largeFragment builds a TableFragment with n rows of typical values.
+func largeFragment(n int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `[%d,"2020-03-04T14:05:01.3109965Z","Event number %d, with \"quotes\"",{"state":"WA","count":%d},%d.5,true,"123e27de-1e4e-49d9-b579-fe0b331d3642"]`, i, i, i, i)
+	}
+	sb.WriteString("]}")
+	return []byte(sb.String())
+}
+
+func BenchmarkRowsDecoders(b *testing.B) {
+	data, err := rowsData(largeFragment(10000), int64(len(`{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows"`)))
+	require.NoError(b, err)
+
+	for _, d := range rowsDecoders {
+		b.Run(d.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := d.decoder.DecodeRows(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
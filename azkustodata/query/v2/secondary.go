@@ -1,6 +1,7 @@
 package v2
 
 import (
+	"encoding/json"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/query"
 	"github.com/google/uuid"
@@ -32,9 +33,15 @@ type QueryCompletionInformation struct {
 	Payload          string
 }
 
+// QueryCompletionInformationTable holds the rows of the query completion information table.
+type QueryCompletionInformationTable []QueryCompletionInformation
+
 const QueryPropertiesKind = "QueryProperties"
 const QueryCompletionInformationKind = "QueryCompletionInformation"
 
+// WorkloadGroupEventType is the EventTypeName of the QueryCompletionInformation row that holds the workload group of the query.
+const WorkloadGroupEventType = "WorkloadGroup"
+
 func AsQueryProperties(table query.BaseTable) ([]QueryProperties, error) {
 	if table.Kind() != QueryPropertiesKind {
 		return nil, errors.ES(errors.OpQuery, errors.KWrongTableKind, "expected QueryProperties table, got %s", table.Kind())
@@ -43,10 +50,35 @@ func AsQueryProperties(table query.BaseTable) ([]QueryProperties, error) {
 	return query.ToStructs[QueryProperties](table)
 }
 
-func AsQueryCompletionInformation(table query.BaseTable) ([]QueryCompletionInformation, error) {
+func AsQueryCompletionInformation(table query.BaseTable) (QueryCompletionInformationTable, error) {
 	if table.Kind() != QueryCompletionInformationKind {
 		return nil, errors.ES(errors.OpQuery, errors.KWrongTableKind, "expected QueryCompletionInformation table, got %s", table.Kind())
 	}
 
 	return query.ToStructs[QueryCompletionInformation](table)
 }
+
+// WorkloadGroup returns the workload group the query executed under, if the row is a WorkloadGroup event.
+func (q QueryCompletionInformation) WorkloadGroup() (string, bool) {
+	if q.EventTypeName != WorkloadGroupEventType {
+		return "", false
+	}
+
+	var payload struct {
+		Text string
+	}
+	if err := json.Unmarshal([]byte(q.Payload), &payload); err != nil || payload.Text == "" {
+		return "", false
+	}
+	return payload.Text, true
+}
+
+// WorkloadGroup returns the workload group the query executed under, from the first WorkloadGroup event of the table.
+func (t QueryCompletionInformationTable) WorkloadGroup() (string, bool) {
+	for _, info := range t {
+		if group, ok := info.WorkloadGroup(); ok {
+			return group, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package v2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completionFrames(payload string) string {
+	return `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"A","ColumnType":"int"}]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[1]]}
+,{"FrameType":"TableCompletion","TableId":1,"RowCount":1}
+,{"FrameType":"DataTable","TableId":2,"TableKind":"QueryCompletionInformation","TableName":"QueryCompletionInformation","Columns":[{"ColumnName":"EventTypeName","ColumnType":"string"},{"ColumnName":"Payload","ColumnType":"string"}],"Rows":[["QueryInfo","{\"Count\":1}"],["WorkloadGroup",` + payload + `]]}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+}
+
+func TestWorkloadGroup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{name: "default", payload: `"{\"Count\":1,\"Text\":\"default\"}"`, want: "default"},
+		{name: "custom", payload: `"{\"Count\":1,\"Text\":\"reports\"}"`, want: "reports"},
+		{name: "no text", payload: `"{\"Count\":1}"`, want: ""},
+		{name: "invalid payload", payload: `"not json"`, want: ""},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := defaultDataset(strings.NewReader(completionFrames(test.payload)))
+			require.NoError(t, err)
+			dataset, err := d.ToDataset()
+			require.NoError(t, err)
+
+			table := query.TableByID(dataset.Tables(), 2)
+			require.NotNil(t, table)
+			infos, err := AsQueryCompletionInformation(table)
+			require.NoError(t, err)
+			group, ok := infos.WorkloadGroup()
+			assert.Equal(t, test.want != "", ok)
+			assert.Equal(t, test.want, group)
+		})
+	}
+}
@@ -6,16 +6,17 @@ import (
 	"strconv"
 )
 
-func newBaseTable(dataset query.BaseDataset, id int, name string, kind string, columns []query.Column) (query.BaseTable, error) {
-	return query.NewBaseTable(dataset, int64(id), strconv.Itoa(id), name, kind, columns), nil
+func newBaseTable(dataset query.BaseDataset, id int, ordinal int, name string, kind string, columns []query.Column) (query.BaseTable, error) {
+	return query.NewBaseTableWithOrdinal(dataset, int64(id), ordinal, strconv.Itoa(id), name, kind, columns), nil
 }
 
-func newBaseTableFromHeader(dataset query.BaseDataset, th TableHeader) (query.BaseTable, error) {
-	return newBaseTable(dataset, th.TableId, th.TableName, th.TableKind, th.Columns)
+func newBaseTableFromHeader(dataset query.BaseDataset, th TableHeader, ordinal int) (query.BaseTable, error) {
+	return newBaseTable(dataset, th.TableId, ordinal, th.TableName, th.TableKind, th.Columns)
 }
 
+// newTable creates a secondary table from a DataTable frame.
 func newTable(dataset query.BaseDataset, dt DataTable) (query.Table, error) {
-	base, err := newBaseTable(dataset, dt.Header.TableId, dt.Header.TableName, dt.Header.TableKind, dt.Header.Columns)
+	base, err := newBaseTable(dataset, dt.Header.TableId, -1, dt.Header.TableName, dt.Header.TableKind, dt.Header.Columns)
 	if err != nil {
 		return nil, err
 	}
@@ -31,6 +32,8 @@ func (f iterativeWrapper) Id() string { return f.table.Id() }
 
 func (f iterativeWrapper) Index() int64 { return f.table.Index() }
 
+func (f iterativeWrapper) Ordinal() int { return query.TableOrdinal(f.table) }
+
 func (f iterativeWrapper) Name() string { return f.table.Name() }
 
 func (f iterativeWrapper) Columns() []query.Column { return f.table.Columns() }
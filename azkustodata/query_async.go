@@ -0,0 +1,50 @@
+package azkustodata
+
+import (
+	"context"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// QueryHandle is a query started by QueryAsync. It is safe for concurrent use by multiple goroutines.
+type QueryHandle struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// result and err are set before done is closed.
+	result query.Dataset
+	err    error
+}
+
+// QueryAsync starts a query like Query, without waiting for its results, and returns a handle to it.
+// The query runs in its own goroutine until its results are fully read, it fails, ctx is done or the handle is
+// cancelled. The resources of the query are released when it ends, the handle only holds its results.
+func (c *Client) QueryAsync(ctx context.Context, db string, kqlQuery Statement, options ...QueryOption) *QueryHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &QueryHandle{done: make(chan struct{}), cancel: cancel}
+
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		h.result, h.err = c.Query(ctx, db, kqlQuery, options...)
+	}()
+
+	return h
+}
+
+// Done returns a channel that is closed when the query ends.
+func (h *QueryHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Result waits for the query to end, and returns its results or its error.
+func (h *QueryHandle) Result() (query.Dataset, error) {
+	<-h.done
+	return h.result, h.err
+}
+
+// Cancel cancels the query. If the query didn't end yet, Result returns an error once it stops.
+// Cancel doesn't wait for the query to end.
+func (h *QueryHandle) Cancel() {
+	h.cancel()
+}
@@ -0,0 +1,102 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryAsync(t *testing.T) {
+	t.Parallel()
+
+	response := stalledQueryResponse + `,{"FrameType":"TableCompletion","TableId":1,"RowCount":2}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+	// The response is only sent once the test releases it.
+	release := make(chan struct{})
+	client := &Client{conn: bodyConn{body: func() io.ReadCloser {
+		<-release
+		return io.NopCloser(strings.NewReader(response))
+	}}}
+
+	h := client.QueryAsync(context.Background(), "db", kql.New("T"))
+	select {
+	case <-h.Done():
+		t.Fatal("the query ended before its response was sent")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-h.Done()
+	dataset, err := h.Result()
+	require.NoError(t, err)
+
+	rows, err := query.ToStructs[struct{ A int32 }](dataset.Tables()[0])
+	require.NoError(t, err)
+	assert.Equal(t, []struct{ A int32 }{{A: 1}, {A: 2}}, rows)
+
+	// Cancelling an ended query keeps its results.
+	h.Cancel()
+	again, err := h.Result()
+	require.NoError(t, err)
+	assert.Equal(t, dataset, again)
+}
+
+// stalledConn returns a response that stalls after the first fragment, and fails once the context of the request is
+// done, like the body of an HTTP response.
+type stalledConn struct{}
+
+func (stalledConn) rawQuery(ctx context.Context, _ callType, _ string, _ Statement, _ *queryOptions) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte(stalledQueryResponse))
+		<-ctx.Done()
+		writer.CloseWithError(ctx.Err())
+	}()
+	return reader, nil
+}
+
+func (stalledConn) Close() error {
+	return nil
+}
+
+func TestQueryAsyncCancel(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: stalledConn{}}
+	h := client.QueryAsync(context.Background(), "db", kql.New("T"))
+	select {
+	case <-h.Done():
+		t.Fatal("the stalled query ended")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	h.Cancel()
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the query did not end after it was cancelled")
+	}
+	dataset, err := h.Result()
+	assert.Error(t, err)
+	assert.Nil(t, dataset)
+}
+
+func TestQueryAsyncContext(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: stalledConn{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	h := client.QueryAsync(ctx, "db", kql.New("T"))
+	cancel()
+
+	_, err := h.Result()
+	assert.Error(t, err)
+}
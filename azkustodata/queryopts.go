@@ -4,9 +4,15 @@ package azkustodata
 // it clogs up the main kusto.go file.
 
 import (
-	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	queryv2 "github.com/Azure/azure-kusto-go/azkustodata/query/v2"
+
 	"github.com/Azure/azure-kusto-go/azkustodata/value"
 )
 
@@ -20,6 +26,9 @@ type requestProperties struct {
 	User            string         `json:"-"`
 	QueryParameters kql.Parameters `json:"-"`
 	ClientRequestID string         `json:"-"`
+	NoCompression   bool           `json:"-"`
+	// Headers are the custom headers of the request, see WithHTTPHeader.
+	Headers http.Header `json:"-"`
 }
 
 type queryOptions struct {
@@ -28,6 +37,18 @@ type queryOptions struct {
 	v2IoCapacity      int
 	v2RowCapacity     int
 	v2TableCapacity   int
+	// database overrides the database of the call, see OverrideDatabase.
+	database string
+	// datasetOptions are the options of the iterative dataset of a v2 query.
+	datasetOptions []queryv2.DatasetOption
+	// maxResponseBytes is the largest response read, see MaxResponseBytes, or 0 for no limit.
+	maxResponseBytes int64
+	// tenant is the tenant of the request for the scheduler of the client, see Tenant.
+	tenant string
+	// resume resumes iterative queries when the connection is lost, see ResumeOnConnectionLoss.
+	resume *resumeOptions
+	// reuseRows is set by ReuseRows, which can't be combined with ResumeOnConnectionLoss.
+	reuseRows bool
 }
 
 const ResultsProgressiveEnabledValue = "results_progressive_enabled"
@@ -69,6 +90,8 @@ const QueryResultsProgressiveUpdatePeriodValue = "query_results_progressive_upda
 const QueryTakeMaxRecordsValue = "query_take_max_records"
 const QueryConsistencyValue = "queryconsistency"
 const RequestAppNameValue = "request_app_name"
+const RequestPriorityValue = "priority"
+const WorkloadGroupValue = "workload_group"
 const RequestBlockRowLevelSecurityValue = "request_block_row_level_security"
 const RequestCalloutDisabledValue = "request_callout_disabled"
 const RequestDescriptionValue = "request_description"
@@ -108,6 +131,21 @@ func V2RowCapacity(i int) QueryOption {
 	}
 }
 
+// V2AutoRowCapacity sizes the buffer of data rows of every table of Query and IterativeQuery from the average size of
+// the rows of its first fragment, so that it holds about bufferBytes of rows, such as
+// queryv2.DefaultAutoRowBufferBytes, instead of the fixed number of rows of V2RowCapacity. Small rows get a larger
+// buffer, which keeps the decoder ahead of the consumer on fast networks. See queryv2.AutoRowCapacity.
+// Mgmt and QueryV1 ignore it.
+func V2AutoRowCapacity(bufferBytes int) QueryOption {
+	return func(q *queryOptions) error {
+		if bufferBytes <= 0 {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "V2AutoRowCapacity() requires a positive size, got %d", bufferBytes).SetNoRetry()
+		}
+		q.datasetOptions = append(q.datasetOptions, queryv2.AutoRowCapacity(bufferBytes))
+		return nil
+	}
+}
+
 // V2TableCapacity sets the capacity of the buffer of data fragments in the result set.
 func V2TableCapacity(i int) QueryOption {
 	return func(q *queryOptions) error {
@@ -116,7 +154,117 @@ func V2TableCapacity(i int) QueryOption {
 	}
 }
 
+// MaxResponseBytes aborts the call once more than n bytes of its response were read, after decompression, to bound
+// the memory used by queries that return more data than expected, whatever the truncation settings of the service.
+// The rows already delivered are kept, and the call fails with an *errors.ResponseTooLargeError, which holds the number
+// of bytes read and of rows delivered.
+func MaxResponseBytes(n int64) QueryOption {
+	return func(q *queryOptions) error {
+		if n <= 0 {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "MaxResponseBytes() requires a positive size, got %d", n).SetNoRetry()
+		}
+		q.maxResponseBytes = n
+		return nil
+	}
+}
+
+// FrameStats calls callback with the statistics of the frames decoded by Query and IterativeQuery: the frames by type, the
+// DataReplace fragments, and the rows delivered to every table next to the row count of its completion frame.
+// It is called once the dataset is fully read, failed or closed, from the goroutine that decodes it, and must not block.
+// Mgmt and QueryV1 ignore it.
+func FrameStats(callback func(queryv2.FrameStats)) QueryOption {
+	return func(q *queryOptions) error {
+		q.datasetOptions = append(q.datasetOptions, queryv2.WithFrameStats(callback))
+		return nil
+	}
+}
+
+// FrameHandler registers the handler of a frame type that Query and IterativeQuery don't decode, such as a preview
+// frame type. It is ignored for the frame types they decode. Mgmt and QueryV1 ignore it.
+func FrameHandler(frameType queryv2.FrameType, handler queryv2.FrameHandler) QueryOption {
+	return func(q *queryOptions) error {
+		if handler == nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "FrameHandler() requires a handler").SetNoRetry()
+		}
+		q.datasetOptions = append(q.datasetOptions, queryv2.WithFrameHandler(frameType, handler))
+		return nil
+	}
+}
+
+// UnknownFrameHandler sets the handler of the frames of unknown types that have no handler registered with
+// FrameHandler. Without it, Query and IterativeQuery skip these frames, and count them in the FrameStats.
+// Mgmt and QueryV1 ignore it.
+func UnknownFrameHandler(handler queryv2.FrameHandler) QueryOption {
+	return func(q *queryOptions) error {
+		if handler == nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "UnknownFrameHandler() requires a handler").SetNoRetry()
+		}
+		q.datasetOptions = append(q.datasetOptions, queryv2.WithUnknownFrameHandler(handler))
+		return nil
+	}
+}
+
+// DataReplace sets how Query and IterativeQuery handle the DataReplace fragments of progressive queries, whose rows
+// replace the rows of the table that were already delivered. By default, the query fails. With
+// queryv2.DataReplaceRestart, a *queryv2.RowsReplacedError is reported in the rows of the table, which goes on with the
+// new rows, and Query keeps only the new rows. Mgmt and QueryV1 ignore it.
+func DataReplace(policy queryv2.DataReplacePolicy) QueryOption {
+	return func(q *queryOptions) error {
+		switch policy {
+		case queryv2.DataReplaceFail, queryv2.DataReplaceRestart, queryv2.DataReplaceIgnore:
+		default:
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "DataReplace() policy %d is not valid", policy).SetNoRetry()
+		}
+		q.datasetOptions = append(q.datasetOptions, queryv2.DataReplace(policy))
+		return nil
+	}
+}
+
+// OnDataReplace sets the queryv2.DataReplaceRestart policy of DataReplace, and calls f with the table whose rows are
+// replaced, from the goroutine that decodes the dataset, before the replace is reported in its rows. f must not block.
+// Mgmt and QueryV1 ignore it.
+func OnDataReplace(f func(table query.BaseTable, replaced int)) QueryOption {
+	return func(q *queryOptions) error {
+		if f == nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "OnDataReplace() requires a function").SetNoRetry()
+		}
+		q.datasetOptions = append(q.datasetOptions, queryv2.OnDataReplace(f))
+		return nil
+	}
+}
+
+// LazyRows makes Query and IterativeQuery decode the cells of the rows only when they are accessed, by index or by
+// name, and cache them, instead of decoding every cell of a row upfront. It saves time and memory for tables with
+// hundreds of columns, of which only a few are read. A cell that fails to decode is returned as an error when it is
+// accessed, instead of failing the query. Mgmt and QueryV1 ignore it.
+func LazyRows() QueryOption {
+	return func(q *queryOptions) error {
+		q.datasetOptions = append(q.datasetOptions, queryv2.LazyRows())
+		return nil
+	}
+}
+
+// ReuseRows makes IterativeQuery decode the rows of each primary table into two rows that are reused in turn, instead
+// of allocating a row, its values and a Kusto value per cell for every row, which saves most of the allocations of
+// iterating over large results. A row received from the Rows channel of a table stays valid only until the next row of
+// the table is received: call query.Cloner.Clone to keep it, or its values, for longer. The rows are sent unbuffered, so
+// V2RowCapacity is ignored, and LazyRows has no effect on the primary tables.
+// ToTable and ToDataset clone the rows, so Query gains nothing from it. It can't be combined with
+// ResumeOnConnectionLoss. Mgmt and QueryV1 ignore it.
+func ReuseRows() QueryOption {
+	return func(q *queryOptions) error {
+		if q.resume != nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "ReuseRows() can't be combined with ResumeOnConnectionLoss()").SetNoRetry()
+		}
+		q.reuseRows = true
+		q.datasetOptions = append(q.datasetOptions, queryv2.ReuseRows())
+		return nil
+	}
+}
+
 // V2NewlinesBetweenFrames Adds new lines between frames in the results, in order to make it easier to parse them.
+// IterativeQuery and Query always set it, and read the frames line by line. Responses without it are still decoded,
+// but more slowly.
 func V2NewlinesBetweenFrames() QueryOption {
 	return func(q *queryOptions) error {
 		q.requestProperties.Options[V2NewlinesBetweenFramesValue] = true
@@ -143,6 +291,30 @@ func ResultsErrorReportingPlacement(s string) QueryOption {
 	}
 }
 
+// ResultsErrorsInStream enables the progressive query stream, with the errors reported in the stream as soon as they
+// happen, rather than at the end of the table they happened in.
+// IterativeQuery reports these errors inline, as a *v2.TableStreamError in the rows of the table they happened in, or in
+// the tables of the dataset if that table isn't being read, and goes on reading the dataset.
+// IterativeQuery ignores it once the service is known not to report errors in the stream, see Client.Capabilities.
+func ResultsErrorsInStream() QueryOption {
+	return func(q *queryOptions) error {
+		q.requestProperties.Options[ResultsProgressiveEnabledValue] = true
+		q.requestProperties.Options[ResultsErrorReportingPlacementValue] = ResultsErrorReportingPlacementInData
+		return nil
+	}
+}
+
+// endOfTableErrorsUnlessInStream places the errors at the end of their table, which is where IterativeQuery expects them,
+// unless ResultsErrorsInStream was set.
+func endOfTableErrorsUnlessInStream() QueryOption {
+	return func(q *queryOptions) error {
+		if q.requestProperties.Options[ResultsErrorReportingPlacementValue] != ResultsErrorReportingPlacementInData {
+			q.requestProperties.Options[ResultsErrorReportingPlacementValue] = ResultsErrorReportingPlacementEndOfTable
+		}
+		return nil
+	}
+}
+
 // ClientRequestID sets the x-ms-client-request-id header, and can be used to identify the request in the `.show queries` output.
 func ClientRequestID(clientRequestID string) QueryOption {
 	return func(q *queryOptions) error {
@@ -151,6 +323,22 @@ func ClientRequestID(clientRequestID string) QueryOption {
 	}
 }
 
+// WithHTTPHeader adds a custom header to the request, such as the routing header of a gateway in front of a private
+// deployment. The header must pass ValidateHTTPHeader. It can be passed multiple times, to add several headers or
+// several values of a header.
+func WithHTTPHeader(key, value string) QueryOption {
+	return func(q *queryOptions) error {
+		if err := ValidateHTTPHeader(key, value); err != nil {
+			return err
+		}
+		if q.requestProperties.Headers == nil {
+			q.requestProperties.Headers = http.Header{}
+		}
+		q.requestProperties.Headers.Add(key, value)
+		return nil
+	}
+}
+
 // Application sets the x-ms-app header, and can be used to identify the application making the request in the `.show queries` output.
 func Application(appName string) QueryOption {
 	return func(q *queryOptions) error {
@@ -184,6 +372,16 @@ func NoRequestTimeout() QueryOption {
 	}
 }
 
+// NoResponseCompression asks the service not to compress the response. Responses are compressed with gzip or
+// deflate by default, which saves bandwidth on large results, but adds latency to small and latency-critical queries.
+// The encoding of a response is reported by the TransferStats of its dataset.
+func NoResponseCompression() QueryOption {
+	return func(q *queryOptions) error {
+		q.requestProperties.NoCompression = true
+		return nil
+	}
+}
+
 // NoTruncation enables suppressing truncation of the query results returned to the caller.
 func NoTruncation() QueryOption {
 	return func(q *queryOptions) error {
@@ -193,6 +391,9 @@ func NoTruncation() QueryOption {
 }
 
 // ResultsProgressiveEnabled enables the progressive query stream.
+// Progressive datasets are decoded like regular ones, and their progress frames are skipped. Queries whose results are
+// replaced while they are computed (DataReplace fragments) fail, as rows that were already returned can't be taken back,
+// unless another policy is set with DataReplace.
 func ResultsProgressiveEnabled() QueryOption {
 	return func(q *queryOptions) error {
 		q.requestProperties.Options[ResultsProgressiveEnabledValue] = true
@@ -517,7 +718,8 @@ func QueryTakeMaxRecords(i int64) QueryOption {
 	}
 }
 
-// QueryConsistency Controls query consistency
+// QueryConsistency Controls query consistency, one of StrongConsistency, WeakConsistency, AffinitizedWeakConsistency
+// or DatabaseAffinitizedWeakConsistency.
 func QueryConsistency(c string) QueryOption {
 	return func(q *queryOptions) error {
 		q.requestProperties.Options[QueryConsistencyValue] = c
@@ -525,6 +727,60 @@ func QueryConsistency(c string) QueryOption {
 	}
 }
 
+// Priority is used with RequestPriority() to set the priority of a request.
+type Priority interface {
+	isPriority()
+}
+
+type priority string
+
+func (priority) isPriority() {}
+
+const (
+	// PriorityNormal is the default priority of requests.
+	PriorityNormal priority = "normal"
+	// PriorityLow lowers the priority of a request, so that it yields to the requests with the normal priority,
+	// such as interactive queries, when the cluster is loaded.
+	PriorityLow priority = "low"
+)
+
+// RequestPriority sets the priority of the request. ['normal' or 'low']
+// Batch analytics can use PriorityLow to demote themselves without changes to the workload policies of the cluster.
+func RequestPriority(p Priority) QueryOption {
+	return func(q *queryOptions) error {
+		if p == nil {
+			return nil
+		}
+		q.requestProperties.Options[RequestPriorityValue] = string(p.(priority))
+		return nil
+	}
+}
+
+// WorkloadGroup requests that the request is classified into the named workload group, which can limit its resources
+// and concurrency. The classification function of the cluster decides whether the request lands in it.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/workload-groups
+func WorkloadGroup(name string) QueryOption {
+	return func(q *queryOptions) error {
+		if strings.TrimSpace(name) == "" {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "WorkloadGroup() requires a workload group name").SetNoRetry()
+		}
+		q.requestProperties.Options[WorkloadGroupValue] = name
+		return nil
+	}
+}
+
+// OverrideDatabase runs the call in the database db, instead of the database passed to the call or the initial catalog
+// of the connection string. Cross-database references, such as database('Other').Table, are resolved from it.
+func OverrideDatabase(db string) QueryOption {
+	return func(q *queryOptions) error {
+		if strings.TrimSpace(db) == "" {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "OverrideDatabase() requires a database name").SetNoRetry()
+		}
+		q.database = db
+		return nil
+	}
+}
+
 // RequestAppName Request application name to be used in the reporting (e.g. show queries).
 // Does not set the `Application` property in `.show queries`, see `Application` for that.
 func RequestAppName(s string) QueryOption {
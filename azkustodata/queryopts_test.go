@@ -0,0 +1,133 @@
+package azkustodata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	queryv2 "github.com/Azure/azure-kusto-go/azkustodata/query/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []QueryOption
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "low priority", options: []QueryOption{RequestPriority(PriorityLow)}, want: map[string]interface{}{RequestPriorityValue: "low"}},
+		{name: "normal priority", options: []QueryOption{RequestPriority(PriorityNormal)}, want: map[string]interface{}{RequestPriorityValue: "normal"}},
+		{name: "nil priority", options: []QueryOption{RequestPriority(nil)}, want: map[string]interface{}{}},
+		{name: "workload group", options: []QueryOption{WorkloadGroup("batch")}, want: map[string]interface{}{WorkloadGroupValue: "batch"}},
+		{
+			name:    "both",
+			options: []QueryOption{RequestPriority(PriorityLow), WorkloadGroup("batch")},
+			want:    map[string]interface{}{RequestPriorityValue: "low", WorkloadGroupValue: "batch"},
+		},
+		{name: "empty workload group", options: []QueryOption{WorkloadGroup(" ")}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, test.options...)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for k, v := range test.want {
+				assert.Equal(t, v, opts.requestProperties.Options[k])
+			}
+			if len(test.want) == 0 {
+				assert.NotContains(t, opts.requestProperties.Options, RequestPriorityValue)
+			}
+		})
+	}
+}
+
+func TestResultsErrorsInStream(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []QueryOption
+		want    map[string]interface{}
+	}{
+		{
+			name: "end of table by default",
+			want: map[string]interface{}{ResultsErrorReportingPlacementValue: ResultsErrorReportingPlacementEndOfTable},
+		},
+		{
+			name:    "end of table overrides other placements",
+			options: []QueryOption{ResultsErrorReportingPlacement(ResultsErrorReportingPlacementEndOfDataset)},
+			want:    map[string]interface{}{ResultsErrorReportingPlacementValue: ResultsErrorReportingPlacementEndOfTable},
+		},
+		{
+			name:    "in stream",
+			options: []QueryOption{ResultsErrorsInStream()},
+			want: map[string]interface{}{
+				ResultsErrorReportingPlacementValue: ResultsErrorReportingPlacementInData,
+				ResultsProgressiveEnabledValue:      true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &optionsConn{}
+			client := &Client{conn: conn}
+
+			_, err := client.Query(context.Background(), "db", kql.New("T"), test.options...)
+			require.NoError(t, err)
+			require.Len(t, conn.options, 1)
+			for k, v := range test.want {
+				assert.Equal(t, v, conn.options[0].requestProperties.Options[k], k)
+			}
+		})
+	}
+}
+
+func TestDatasetOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []QueryOption
+		want    int
+		wantErr bool
+	}{
+		{name: "restart", options: []QueryOption{DataReplace(queryv2.DataReplaceRestart)}, want: 1},
+		{name: "callback", options: []QueryOption{OnDataReplace(func(query.BaseTable, int) {})}, want: 1},
+		{name: "invalid policy", options: []QueryOption{DataReplace(queryv2.DataReplacePolicy(42))}, wantErr: true},
+		{name: "nil callback", options: []QueryOption{OnDataReplace(nil)}, wantErr: true},
+		{name: "auto row capacity", options: []QueryOption{V2AutoRowCapacity(queryv2.DefaultAutoRowBufferBytes)}, want: 1},
+		{name: "no auto row capacity", options: []QueryOption{V2AutoRowCapacity(0)}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, test.options...)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, opts.datasetOptions, test.want)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package azkustodata
+
+import (
+	"io"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// responseLimitReader fails the reads of a response body once more than limit bytes were read.
+type responseLimitReader struct {
+	body  io.ReadCloser
+	op    errors.Op
+	limit int64
+	read  int64
+}
+
+func newResponseLimitReader(body io.ReadCloser, op errors.Op, limit int64) *responseLimitReader {
+	return &responseLimitReader{body: body, op: op, limit: limit}
+}
+
+func (r *responseLimitReader) Read(p []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, errors.ResponseTooLarge(r.op, r.limit, r.read)
+	}
+
+	// At most one byte past the limit is read, to tell a response of exactly limit bytes from a larger one.
+	if remaining := r.limit - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		// The body is closed, so that the rest of the response isn't downloaded.
+		_ = r.body.Close()
+		return n - int(r.read-r.limit), errors.ResponseTooLarge(r.op, r.limit, r.read)
+	}
+	return n, err
+}
+
+// TransferStats implements query.TransferStatsReporter, with the statistics of the body.
+func (r *responseLimitReader) TransferStats() query.TransferStats {
+	if stats, ok := r.body.(query.TransferStatsReporter); ok {
+		return stats.TransferStats()
+	}
+	return query.TransferStats{}
+}
+
+func (r *responseLimitReader) Close() error {
+	return r.body.Close()
+}
+
+// limitResponse applies the MaxResponseBytes option to a response body.
+func limitResponse(body io.ReadCloser, op errors.Op, opts *queryOptions) io.ReadCloser {
+	if opts.maxResponseBytes <= 0 {
+		return body
+	}
+	return newResponseLimitReader(body, op, opts.maxResponseBytes)
+}
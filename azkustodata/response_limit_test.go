@@ -0,0 +1,90 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const completeQueryResponse = stalledQueryResponse + `,{"FrameType":"TableCompletion","TableId":1,"RowCount":2}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+
+func TestMaxResponseBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc  string
+		limit int64
+		err   bool
+	}{
+		{desc: "Exceeded after the rows", limit: int64(len(stalledQueryResponse)) + 10, err: true},
+		{desc: "Exact size", limit: int64(len(completeQueryResponse))},
+		{desc: "Larger", limit: 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := &Client{conn: bodyConn{body: func() io.ReadCloser { return io.NopCloser(strings.NewReader(completeQueryResponse)) }}}
+			dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T"), MaxResponseBytes(test.limit))
+			require.NoError(t, err)
+
+			var rows int
+			var rowErr error
+			for tableResult := range dataset.Tables() {
+				if tableResult.Err() != nil {
+					rowErr = tableResult.Err()
+					continue
+				}
+				for rowResult := range tableResult.Table().Rows() {
+					if rowResult.Err() != nil {
+						rowErr = rowResult.Err()
+						continue
+					}
+					rows++
+				}
+			}
+			assert.Equal(t, 2, rows)
+
+			if !test.err {
+				assert.NoError(t, rowErr)
+				return
+			}
+			var tooLarge *errors.ResponseTooLargeError
+			require.True(t, goErrors.As(rowErr, &tooLarge), "unexpected error: %v", rowErr)
+			assert.Equal(t, test.limit, tooLarge.Limit)
+			assert.Equal(t, test.limit+1, tooLarge.BytesRead)
+			assert.Equal(t, int64(2), tooLarge.RowsDelivered)
+			assert.Equal(t, errors.KLimitsExceeded, tooLarge.Kind)
+			assert.False(t, errors.Retry(tooLarge))
+			assert.Contains(t, tooLarge.Error(), "2 rows were delivered")
+		})
+	}
+}
+
+func TestMaxResponseBytesMgmt(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: bodyConn{body: func() io.ReadCloser { return io.NopCloser(strings.NewReader(emptyMgmtResponse)) }}}
+	_, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"), MaxResponseBytes(10))
+	var tooLarge *errors.ResponseTooLargeError
+	require.True(t, goErrors.As(err, &tooLarge), "unexpected error: %v", err)
+	assert.Equal(t, int64(11), tooLarge.BytesRead)
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"), MaxResponseBytes(int64(len(emptyMgmtResponse))))
+	assert.NoError(t, err)
+
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"), MaxResponseBytes(0))
+	require.Error(t, err)
+	assert.False(t, errors.Retry(err))
+}
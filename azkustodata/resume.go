@@ -0,0 +1,372 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// resumeOptions are the options of ResumeOnConnectionLoss.
+type resumeOptions struct {
+	cursor     string
+	maxResumes int
+}
+
+// ResumeOnConnectionLoss resumes IterativeQuery, and Query, when the connection to the service is lost while the primary
+// result is read: the query is issued again, continuing after the last row that was delivered, and its rows are
+// delivered in the same table, as if the connection was never lost. The query is resumed up to maxResumes times.
+//
+// The rows are identified by cursor, a column whose values are unique and sorted in ascending order: the query must end
+// with `| sort by cursor asc`, and return a single primary result. The query is resumed by appending
+// `| where cursor > last`, with the last value of cursor that was delivered, so it must not end with a semicolon.
+// Rows are never delivered twice, but the Index of the rows starts over in every resumed query.
+//
+// A connection is lost when reading the response fails with a network error, or stalls for longer than the
+// WithFrameIdleTimeout of the client. Errors reported by the service are not resumed.
+// It can't be combined with ReuseRows.
+func ResumeOnConnectionLoss(cursor string, maxResumes int) QueryOption {
+	return func(q *queryOptions) error {
+		if cursor == "" {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "ResumeOnConnectionLoss() requires a cursor column").SetNoRetry()
+		}
+		if maxResumes <= 0 {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "ResumeOnConnectionLoss() requires a positive number of resumes, got %d", maxResumes).SetNoRetry()
+		}
+		if q.reuseRows {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "ResumeOnConnectionLoss() can't be combined with ReuseRows()").SetNoRetry()
+		}
+		q.resume = &resumeOptions{cursor: cursor, maxResumes: maxResumes}
+		return nil
+	}
+}
+
+// connectionLost reports whether err is a failure of the connection while reading a response.
+func connectionLost(err error) bool {
+	var idle *errors.FrameIdleTimeoutError
+	var netErr net.Error
+	return goErrors.Is(err, io.ErrUnexpectedEOF) || goErrors.Is(err, syscall.ECONNRESET) ||
+		goErrors.As(err, &idle) || goErrors.As(err, &netErr)
+}
+
+// reissueFunc issues a query again, with the options of the original call.
+type reissueFunc func(ctx context.Context, q Statement) (query.IterativeDataset, error)
+
+// resumingDataset is the dataset of a query with ResumeOnConnectionLoss. When the connection is lost while the primary
+// result is read, it issues the query again, and delivers the rows of the new query in the primary result.
+type resumingDataset struct {
+	first   query.IterativeDataset
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	query   Statement
+	reissue reissueFunc
+	options resumeOptions
+	results chan query.TableResult
+
+	mu      sync.Mutex
+	current query.IterativeDataset
+	resumes int
+}
+
+func newResumingDataset(parent context.Context, first query.IterativeDataset, q Statement, options resumeOptions, reissue reissueFunc) *resumingDataset {
+	ctx, cancel := context.WithCancel(parent)
+	d := &resumingDataset{
+		first:   first,
+		parent:  parent,
+		ctx:     ctx,
+		cancel:  cancel,
+		query:   q,
+		reissue: reissue,
+		options: options,
+		results: make(chan query.TableResult, 1),
+		current: first,
+	}
+	go d.run()
+	return d
+}
+
+func (d *resumingDataset) Context() context.Context { return d.ctx }
+
+func (d *resumingDataset) Op() errors.Op { return d.first.Op() }
+
+func (d *resumingDataset) PrimaryResultKind() string { return d.first.PrimaryResultKind() }
+
+// TransferStats returns the statistics of the transfer of the last query that was issued.
+func (d *resumingDataset) TransferStats() query.TransferStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return query.DatasetTransferStats(d.current)
+}
+
+func (d *resumingDataset) Tables() <-chan query.TableResult {
+	return d.results
+}
+
+func (d *resumingDataset) Close() error {
+	d.cancel()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.current.Close()
+}
+
+func (d *resumingDataset) ToDataset() (query.Dataset, error) {
+	defer d.Close()
+
+	var tables []query.Table
+	for tb := range d.Tables() {
+		if tb.Err() != nil {
+			return nil, tb.Err()
+		}
+		table, err := tb.Table().ToTable()
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	// The errors aren't sent to the results once the query is cancelled.
+	if err := d.parent.Err(); err != nil {
+		return nil, errors.ES(errors.OpQuery, errors.KTimeout, "the query was cancelled: %s", err)
+	}
+	return query.NewDataset(d, tables), nil
+}
+
+// run sends the tables of the queries to the results. It waits for the rows of every primary result to be delivered
+// before going on, since a lost connection switches to the tables of another query.
+func (d *resumingDataset) run() {
+	defer d.cancel()
+	defer close(d.results)
+
+	ds := d.first
+	primary := 0
+	var pending query.TableResult
+	for {
+		tr := pending
+		pending = nil
+		if tr == nil {
+			var ok bool
+			if tr, ok = d.next(ds); !ok {
+				return
+			}
+		}
+
+		if err := tr.Err(); err != nil {
+			// No row was delivered yet, so the query is issued again from the start.
+			if primary == 0 && d.canResume(err) {
+				if next, err := d.resume(nil); err == nil {
+					ds = next
+					continue
+				}
+			}
+			if !d.send(tr) {
+				return
+			}
+			continue
+		}
+
+		if !tr.Table().IsPrimaryResult() {
+			if !d.send(tr) {
+				return
+			}
+			continue
+		}
+
+		t := &resumingTable{IterativeTable: tr.Table(), dataset: d, resumable: primary == 0, rows: make(chan query.RowResult)}
+		primary++
+		if !d.send(query.TableResultSuccess(t)) {
+			return
+		}
+		ds, pending = t.forward(ds)
+	}
+}
+
+// next returns the next table of ds, or false once there are no more tables.
+func (d *resumingDataset) next(ds query.IterativeDataset) (query.TableResult, bool) {
+	select {
+	case tr, ok := <-ds.Tables():
+		return tr, ok
+	case <-d.ctx.Done():
+		return nil, false
+	}
+}
+
+func (d *resumingDataset) send(tr query.TableResult) bool {
+	select {
+	case d.results <- tr:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+// canResume reports whether the query can be resumed after err.
+func (d *resumingDataset) canResume(err error) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ctx.Err() == nil && d.resumes < d.options.maxResumes && connectionLost(err)
+}
+
+// resume issues the query again, after the row whose cursor is last, or from the start if last is nil.
+func (d *resumingDataset) resume(last value.Kusto) (query.IterativeDataset, error) {
+	d.mu.Lock()
+	d.resumes++
+	resumes := d.resumes
+	previous := d.current
+	d.mu.Unlock()
+	_ = previous.Close()
+
+	q := kql.FromBuilder(d.query)
+	if last != nil {
+		q.AddLiteral("\n| where ").AddColumn(d.options.cursor).AddLiteral(" > ").AddValue(last)
+		log.Writef(log.EventQuery, "the connection was lost while reading the results, resuming the query after %s == %s (%d of %d)",
+			d.options.cursor, last, resumes, d.options.maxResumes)
+	} else {
+		log.Writef(log.EventQuery, "the connection was lost before reading the results, issuing the query again (%d of %d)",
+			resumes, d.options.maxResumes)
+	}
+
+	ds, err := d.reissue(d.ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.current = ds
+	d.mu.Unlock()
+	return ds, nil
+}
+
+// resumePrimary issues the query again after the row whose cursor is last, and returns the rows of its primary result.
+func (d *resumingDataset) resumePrimary(last value.Kusto) (query.IterativeDataset, <-chan query.RowResult, error) {
+	for {
+		ds, err := d.resume(last)
+		if err == nil {
+			err = errors.ES(errors.OpQuery, errors.KInternal, "the resumed query returned no primary result")
+			for tr := range ds.Tables() {
+				if tr.Err() != nil {
+					err = tr.Err()
+					break
+				}
+				if tr.Table().IsPrimaryResult() {
+					return ds, tr.Table().Rows(), nil
+				}
+			}
+		}
+		if !d.canResume(err) {
+			return nil, nil, err
+		}
+	}
+}
+
+// resumingTable is a primary result of a resumingDataset, whose rows may come from several queries.
+type resumingTable struct {
+	query.IterativeTable
+	dataset   *resumingDataset
+	resumable bool
+	rows      chan query.RowResult
+}
+
+func (t *resumingTable) Rows() <-chan query.RowResult {
+	return t.rows
+}
+
+func (t *resumingTable) ToTable() (query.Table, error) {
+	var rows []query.Row
+	for r := range t.rows {
+		if r.Err() != nil {
+			return nil, r.Err()
+		}
+		rows = append(rows, r.Row())
+	}
+	return query.NewTable(t.IterativeTable, rows), nil
+}
+
+// forward delivers the rows of the table read from ds, and resumes the query if the connection is lost. It returns the
+// dataset the rest of the tables are read from, and the next table of the dataset if it was already read.
+func (t *resumingTable) forward(ds query.IterativeDataset) (query.IterativeDataset, query.TableResult) {
+	defer close(t.rows)
+
+	d := t.dataset
+	cursor := -1
+	if c := t.IterativeTable.ColumnByName(d.options.cursor); c != nil {
+		cursor = c.Index()
+	} else if t.resumable {
+		err := errors.ES(errors.OpQuery, errors.KClientArgs, "ResumeOnConnectionLoss() requires the cursor column %s in the primary result", d.options.cursor).SetNoRetry()
+		t.send(query.RowResultError(err))
+		_ = ds.Close()
+		return ds, query.TableResultError(err)
+	}
+
+	var last value.Kusto
+	rows := t.IterativeTable.Rows()
+	// resume switches to the rows of a resumed query if err is a lost connection, and returns err otherwise.
+	resume := func(err error) error {
+		if !t.resumable || (last != nil && value.IsNull(last)) || !d.canResume(err) {
+			return err
+		}
+		next, nextRows, err := d.resumePrimary(last)
+		if err != nil {
+			return err
+		}
+		ds, rows = next, nextRows
+		return nil
+	}
+
+	for {
+		var rr query.RowResult
+		var ok bool
+		select {
+		case rr, ok = <-rows:
+		case <-d.ctx.Done():
+			return ds, nil
+		}
+
+		if !ok {
+			if !t.resumable {
+				return ds, nil
+			}
+			// The rows of a table may end without the error of the dataset, which is then only sent to its tables.
+			tr, ok := d.next(ds)
+			if !ok {
+				return ds, nil
+			}
+			if tr.Err() == nil {
+				return ds, tr
+			}
+			if err := resume(tr.Err()); err != nil {
+				return ds, query.TableResultError(err)
+			}
+			continue
+		}
+
+		if err := rr.Err(); err != nil {
+			if err := resume(err); err != nil && !t.send(query.RowResultError(err)) {
+				return ds, nil
+			}
+			continue
+		}
+
+		if t.resumable {
+			last = rr.Row().Values()[cursor]
+		}
+		if !t.send(rr) {
+			return ds, nil
+		}
+	}
+}
+
+func (t *resumingTable) send(rr query.RowResult) bool {
+	select {
+	case t.rows <- rr:
+		return true
+	case <-t.dataset.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,213 @@
+package azkustodata
+
+import (
+	"context"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resumeHeader = `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+`
+
+// resumeResponse returns a response with the keys from..to in its primary result, which fails with err after the rows
+// if err isn't nil.
+func resumeResponse(from, to int, err error) io.ReadCloser {
+	rows := make([]string, 0, to-from+1)
+	for k := from; k <= to; k++ {
+		rows = append(rows, fmt.Sprintf(`[%d,"v%d"]`, k, k))
+	}
+	body := resumeHeader +
+		`,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"Key","ColumnType":"long"},{"ColumnName":"Value","ColumnType":"string"}]}` + "\n" +
+		`,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[` + strings.Join(rows, ",") + `]}` + "\n"
+	if err != nil {
+		return io.NopCloser(io.MultiReader(strings.NewReader(body), &failingReader{err: err}))
+	}
+	body += fmt.Sprintf(`,{"FrameType":"TableCompletion","TableId":1,"RowCount":%d}`, len(rows)) + "\n" +
+		`,{"FrameType":"DataTable","TableId":2,"TableKind":"QueryCompletionInformation","TableName":"QueryCompletionInformation","Columns":[{"ColumnName":"EventTypeName","ColumnType":"string"}],"Rows":[]}` + "\n" +
+		`,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}` + "\n" +
+		`]`
+	return io.NopCloser(strings.NewReader(body))
+}
+
+// failingReader fails every read with err.
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// resumeConn answers the queries with the responses of respond, and records their text.
+type resumeConn struct {
+	mu      sync.Mutex
+	queries []string
+	respond func(call int, query string) io.ReadCloser
+}
+
+func (c *resumeConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query.String())
+	return c.respond(len(c.queries)-1, query.String()), nil
+}
+
+func (c *resumeConn) Close() error {
+	return nil
+}
+
+type resumeRow struct {
+	Key   int64
+	Value string
+}
+
+func TestResumeOnConnectionLoss(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		respond     func(call int, query string) io.ReadCloser
+		wantKeys    []int64
+		wantQueries []string
+		wantErr     string
+	}{
+		{
+			desc: "Resumed after the last row",
+			respond: func(call int, _ string) io.ReadCloser {
+				switch call {
+				case 0:
+					return resumeResponse(1, 3, io.ErrUnexpectedEOF)
+				case 1:
+					return resumeResponse(4, 5, io.ErrUnexpectedEOF)
+				default:
+					return resumeResponse(6, 7, nil)
+				}
+			},
+			wantKeys: []int64{1, 2, 3, 4, 5, 6, 7},
+			wantQueries: []string{
+				"T | sort by Key asc",
+				"T | sort by Key asc\n| where Key > long(3)",
+				"T | sort by Key asc\n| where Key > long(5)",
+			},
+		},
+		{
+			desc: "Lost before the primary result",
+			respond: func(call int, _ string) io.ReadCloser {
+				if call == 0 {
+					return io.NopCloser(io.MultiReader(strings.NewReader(resumeHeader), &failingReader{err: io.ErrUnexpectedEOF}))
+				}
+				return resumeResponse(1, 2, nil)
+			},
+			wantKeys:    []int64{1, 2},
+			wantQueries: []string{"T | sort by Key asc", "T | sort by Key asc"},
+		},
+		{
+			desc: "Too many resumes",
+			respond: func(call int, _ string) io.ReadCloser {
+				return resumeResponse(call+1, call+1, io.ErrUnexpectedEOF)
+			},
+			wantQueries: []string{
+				"T | sort by Key asc",
+				"T | sort by Key asc\n| where Key > long(1)",
+				"T | sort by Key asc\n| where Key > long(2)",
+				"T | sort by Key asc\n| where Key > long(3)",
+			},
+			wantErr: "unexpected EOF",
+		},
+		{
+			desc: "Other errors are not resumed",
+			respond: func(call int, _ string) io.ReadCloser {
+				return resumeResponse(1, 3, goErrors.New("the response is malformed"))
+			},
+			wantQueries: []string{"T | sort by Key asc"},
+			wantErr:     "the response is malformed",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &resumeConn{respond: test.respond}
+			client := &Client{conn: conn}
+
+			dataset, err := client.Query(context.Background(), "db", kql.New("T | sort by Key asc"), ResumeOnConnectionLoss("Key", 3))
+			assert.Equal(t, test.wantQueries, conn.queries)
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			rows, err := query.ToStructs[resumeRow](query.PrimaryByOrdinal(dataset.Tables(), 0))
+			require.NoError(t, err)
+			keys := make([]int64, 0, len(rows))
+			for _, r := range rows {
+				assert.Equal(t, fmt.Sprintf("v%d", r.Key), r.Value)
+				keys = append(keys, r.Key)
+			}
+			assert.Equal(t, test.wantKeys, keys)
+			assert.NotNil(t, query.TableByID(dataset.Tables(), 2), "the secondary tables of the last query are kept")
+		})
+	}
+}
+
+func TestResumeOnConnectionLossIterative(t *testing.T) {
+	t.Parallel()
+
+	conn := &resumeConn{respond: func(call int, _ string) io.ReadCloser {
+		if call == 0 {
+			return resumeResponse(1, 2, io.ErrUnexpectedEOF)
+		}
+		return resumeResponse(3, 3, nil)
+	}}
+	client := &Client{conn: conn}
+
+	dataset, err := client.IterativeQuery(context.Background(), "db", kql.New("T | sort by Key asc"), ResumeOnConnectionLoss("Key", 1))
+	require.NoError(t, err)
+	defer dataset.Close()
+
+	var keys []int64
+	for tr := range dataset.Tables() {
+		require.NoError(t, tr.Err())
+		if !tr.Table().IsPrimaryResult() {
+			continue
+		}
+		for rr := range tr.Table().Rows() {
+			require.NoError(t, rr.Err())
+			var row resumeRow
+			require.NoError(t, rr.Row().ToStruct(&row))
+			keys = append(keys, row.Key)
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3}, keys)
+}
+
+func TestResumeOnConnectionLossOptions(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{conn: &resumeConn{respond: func(int, string) io.ReadCloser { return resumeResponse(1, 1, nil) }}}
+
+	_, err := client.IterativeQuery(context.Background(), "db", kql.New("T"), ResumeOnConnectionLoss("", 1))
+	assert.Error(t, err)
+	_, err = client.IterativeQuery(context.Background(), "db", kql.New("T"), ResumeOnConnectionLoss("Key", 0))
+	assert.Error(t, err)
+	_, err = client.IterativeQuery(context.Background(), "db", kql.New("T"), ReuseRows(), ResumeOnConnectionLoss("Key", 1))
+	assert.ErrorContains(t, err, "ReuseRows")
+	_, err = client.IterativeQuery(context.Background(), "db", kql.New("T"), ResumeOnConnectionLoss("Key", 1), ReuseRows())
+	assert.ErrorContains(t, err, "ResumeOnConnectionLoss")
+
+	_, err = client.Query(context.Background(), "db", kql.New("T"), ResumeOnConnectionLoss("Missing", 1))
+	assert.ErrorContains(t, err, "cursor column Missing")
+}
@@ -0,0 +1,252 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// SchedulerLimits are the limits of the scheduler of a Client, see WithScheduler.
+type SchedulerLimits struct {
+	// MaxConcurrent is the maximum number of requests of the client that run at the same time. It must be positive.
+	MaxConcurrent int
+	// MaxPerTenant is the maximum number of requests of a tenant that run at the same time, so that a tenant can't
+	// use all the slots even when the other tenants are idle. It defaults to MaxConcurrent.
+	MaxPerTenant int
+}
+
+// WithScheduler limits the number of concurrent queries and management commands of the client, and shares the slots
+// fairly between the tenants of the requests, set with the Tenant QueryOption, so that a tenant with many requests
+// can't starve the others in a multi-tenant service.
+// Requests wait for a slot in a queue per tenant, and the queues are served in turn, one request at a time. Requests
+// without a tenant share the queue of the empty tenant. A request holds its slot until its response is fully read or
+// closed, so iterative queries must be read to the end or closed.
+// New fails if MaxConcurrent isn't positive.
+func WithScheduler(limits SchedulerLimits) Option {
+	return func(c *Client) {
+		c.scheduler = newScheduler(limits)
+	}
+}
+
+// Tenant sets the tenant of a request for the scheduler of the client, see WithScheduler. It is ignored by clients
+// without a scheduler.
+func Tenant(key string) QueryOption {
+	return func(q *queryOptions) error {
+		q.tenant = key
+		return nil
+	}
+}
+
+// scheduler shares a number of slots between the requests of tenants, round-robin between the tenants.
+type scheduler struct {
+	limits SchedulerLimits
+
+	mu      sync.Mutex
+	running int
+	// perTenant is the number of running requests of every tenant.
+	perTenant map[string]int
+	// queues are the waiting requests of every tenant with waiting requests, which are in turns.
+	queues map[string][]*schedulerWaiter
+	turns  []string
+	// next is the index in turns of the tenant that is served next.
+	next int
+}
+
+type schedulerWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newScheduler(limits SchedulerLimits) *scheduler {
+	if limits.MaxPerTenant <= 0 || limits.MaxPerTenant > limits.MaxConcurrent {
+		limits.MaxPerTenant = limits.MaxConcurrent
+	}
+	return &scheduler{limits: limits, perTenant: map[string]int{}, queues: map[string][]*schedulerWaiter{}}
+}
+
+func (s *scheduler) validate() error {
+	if s.limits.MaxConcurrent <= 0 {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "the scheduler requires a positive MaxConcurrent, got %d", s.limits.MaxConcurrent).SetNoRetry()
+	}
+	return nil
+}
+
+// acquire waits for a slot for a request of the tenant.
+func (s *scheduler) acquire(ctx context.Context, tenant string) error {
+	s.mu.Lock()
+	if len(s.turns) == 0 && s.available(tenant) {
+		s.grantLocked(tenant)
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := &schedulerWaiter{ready: make(chan struct{})}
+	if _, ok := s.queues[tenant]; !ok {
+		s.turns = append(s.turns, tenant)
+	}
+	s.queues[tenant] = append(s.queues[tenant], w)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// The slot was granted while the context was done, pass it on.
+		s.releaseLocked(tenant)
+	} else {
+		s.removeLocked(tenant, w)
+	}
+	return ctx.Err()
+}
+
+// release releases a slot of the tenant.
+func (s *scheduler) release(tenant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(tenant)
+}
+
+func (s *scheduler) available(tenant string) bool {
+	return s.running < s.limits.MaxConcurrent && s.perTenant[tenant] < s.limits.MaxPerTenant
+}
+
+func (s *scheduler) grantLocked(tenant string) {
+	s.running++
+	s.perTenant[tenant]++
+}
+
+func (s *scheduler) releaseLocked(tenant string) {
+	s.running--
+	if s.perTenant[tenant]--; s.perTenant[tenant] <= 0 {
+		delete(s.perTenant, tenant)
+	}
+	s.dispatchLocked()
+}
+
+// dispatchLocked grants the free slots to the waiting requests, one request of every tenant in turn.
+func (s *scheduler) dispatchLocked() {
+	for s.running < s.limits.MaxConcurrent && len(s.turns) > 0 {
+		granted := false
+		for i := 0; i < len(s.turns); i++ {
+			idx := (s.next + i) % len(s.turns)
+			tenant := s.turns[idx]
+			if !s.available(tenant) {
+				continue
+			}
+
+			queue := s.queues[tenant]
+			w := queue[0]
+			s.grantLocked(tenant)
+			w.granted = true
+			close(w.ready)
+
+			if len(queue) == 1 {
+				delete(s.queues, tenant)
+				s.turns = append(s.turns[:idx], s.turns[idx+1:]...)
+				s.next = idx
+			} else {
+				s.queues[tenant] = queue[1:]
+				s.next = idx + 1
+			}
+			if len(s.turns) > 0 {
+				s.next %= len(s.turns)
+			} else {
+				s.next = 0
+			}
+			granted = true
+			break
+		}
+		if !granted {
+			return
+		}
+	}
+}
+
+// removeLocked removes a waiting request that gave up.
+func (s *scheduler) removeLocked(tenant string, w *schedulerWaiter) {
+	queue := s.queues[tenant]
+	for i, q := range queue {
+		if q == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		s.queues[tenant] = queue
+		return
+	}
+
+	delete(s.queues, tenant)
+	for i, t := range s.turns {
+		if t == tenant {
+			s.turns = append(s.turns[:i], s.turns[i+1:]...)
+			if s.next > i {
+				s.next--
+			}
+			break
+		}
+	}
+	if s.next >= len(s.turns) {
+		s.next = 0
+	}
+}
+
+// scheduledConn runs the requests of a queryer within the slots of a scheduler.
+type scheduledConn struct {
+	queryer
+	scheduler *scheduler
+}
+
+func (c scheduledConn) rawQuery(ctx context.Context, callType callType, db string, query Statement, options *queryOptions) (io.ReadCloser, error) {
+	op := errors.OpQuery
+	if callType == mgmtCall {
+		op = errors.OpMgmt
+	}
+	if err := c.scheduler.acquire(ctx, options.tenant); err != nil {
+		return nil, errors.E(op, errors.KOther, err)
+	}
+
+	body, err := c.queryer.rawQuery(ctx, callType, db, query, options)
+	if err != nil {
+		c.scheduler.release(options.tenant)
+		return nil, err
+	}
+	return &scheduledBody{body: body, release: func() { c.scheduler.release(options.tenant) }}, nil
+}
+
+// scheduledBody releases the slot of its request once it is fully read or closed.
+type scheduledBody struct {
+	body    io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *scheduledBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+// TransferStats implements query.TransferStatsReporter, with the statistics of the body.
+func (b *scheduledBody) TransferStats() query.TransferStats {
+	if stats, ok := b.body.(query.TransferStatsReporter); ok {
+		return stats.TransferStats()
+	}
+	return query.TransferStats{}
+}
+
+func (b *scheduledBody) Close() error {
+	b.once.Do(b.release)
+	return b.body.Close()
+}
@@ -0,0 +1,153 @@
+package azkustodata
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waiting returns the number of requests waiting for a slot.
+func (s *scheduler) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// enqueue starts a request of the tenant that waits for a slot, and sends name to granted once it gets one.
+func enqueue(t *testing.T, s *scheduler, tenant, name string, granted chan<- string) {
+	before := s.waiting()
+	go func() {
+		if err := s.acquire(context.Background(), tenant); err == nil {
+			granted <- name
+		}
+	}()
+	require.Eventually(t, func() bool { return s.waiting() == before+1 }, time.Second, time.Millisecond)
+}
+
+func TestSchedulerFairness(t *testing.T) {
+	t.Parallel()
+
+	s := newScheduler(SchedulerLimits{MaxConcurrent: 1})
+	require.NoError(t, s.acquire(context.Background(), "a"))
+
+	granted := make(chan string)
+	enqueue(t, s, "a", "a1", granted)
+	enqueue(t, s, "a", "a2", granted)
+	enqueue(t, s, "a", "a3", granted)
+	enqueue(t, s, "b", "b1", granted)
+	enqueue(t, s, "c", "c1", granted)
+
+	var order []string
+	s.release("a")
+	for i := 0; i < 5; i++ {
+		name := <-granted
+		order = append(order, name)
+		s.release(name[:1])
+	}
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "a3"}, order)
+	assert.Zero(t, s.running)
+	assert.Empty(t, s.turns)
+}
+
+func TestSchedulerPerTenant(t *testing.T) {
+	t.Parallel()
+
+	s := newScheduler(SchedulerLimits{MaxConcurrent: 3, MaxPerTenant: 1})
+	require.NoError(t, s.acquire(context.Background(), "a"))
+
+	granted := make(chan string, 2)
+	enqueue(t, s, "a", "a1", granted)
+	// Another tenant gets a free slot even though a request of "a" waits before it.
+	require.NoError(t, s.acquire(context.Background(), "b"))
+	assert.Equal(t, 1, s.waiting())
+
+	s.release("a")
+	assert.Equal(t, "a1", <-granted)
+}
+
+func TestSchedulerCanceled(t *testing.T) {
+	t.Parallel()
+
+	s := newScheduler(SchedulerLimits{MaxConcurrent: 1})
+	require.NoError(t, s.acquire(context.Background(), "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.acquire(ctx, "b"), context.DeadlineExceeded)
+	assert.Zero(t, s.waiting())
+	assert.Empty(t, s.turns)
+
+	s.release("a")
+	require.NoError(t, s.acquire(context.Background(), "b"))
+}
+
+// blockingConn is a fake conn that counts the requests in progress, until their response is closed.
+type blockingConn struct {
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (c *blockingConn) rawQuery(context.Context, callType, string, Statement, *queryOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active++
+	if c.active > c.maxSeen {
+		c.maxSeen = c.active
+	}
+	return &closeHook{Reader: strings.NewReader(emptyMgmtResponse), onClose: func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.active--
+	}}, nil
+}
+
+func (c *blockingConn) Close() error {
+	return nil
+}
+
+type closeHook struct {
+	io.Reader
+	onClose func()
+}
+
+func (c *closeHook) Close() error {
+	c.onClose()
+	return nil
+}
+
+func TestWithScheduler(t *testing.T) {
+	t.Parallel()
+
+	conn := &blockingConn{}
+	client := &Client{conn: conn, scheduler: newScheduler(SchedulerLimits{MaxConcurrent: 2})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		tenant := []string{"a", "b"}[i%2]
+		go func() {
+			defer wg.Done()
+			_, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"), Tenant(tenant))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, conn.maxSeen, 2)
+	assert.Zero(t, client.scheduler.running)
+
+	_, err := New(NewConnectionStringBuilder("https://help.kusto.windows.net"), WithScheduler(SchedulerLimits{}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of entity of a Change.
+type Kind string
+
+const (
+	KindTable            Kind = "table"
+	KindColumn           Kind = "column"
+	KindMaterializedView Kind = "materialized view"
+	KindFunction         Kind = "function"
+)
+
+// Change is an entity that was added, dropped or altered between two schemas.
+type Change struct {
+	Kind Kind
+	// Name is the name of the entity. The name of a column is qualified by its table or materialized view, as
+	// "Table.Column".
+	Name string
+	// Details describe what was altered, such as "type changed from int to long". They are empty for added and dropped
+	// entities.
+	Details []string
+}
+
+// String returns a description of the change, such as `table T: folder changed from "a" to "b"`.
+func (c Change) String() string {
+	if len(c.Details) == 0 {
+		return fmt.Sprintf("%s %s", c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Name, strings.Join(c.Details, ", "))
+}
+
+// Changes are the differences between two schemas, returned by Diff. The changes are ordered by kind - tables and
+// their columns, materialized views and their columns, then functions - and by name.
+type Changes struct {
+	Added   []Change
+	Dropped []Change
+	Altered []Change
+}
+
+// Empty reports whether the schemas are the same.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Dropped) == 0 && len(c.Altered) == 0
+}
+
+// Diff returns the changes that turn schema a into schema b. The columns of tables and materialized views that exist
+// in both schemas are compared one by one: columns that were added or dropped are reported as such, and columns whose
+// type or documentation changed as altered. A nil schema is empty.
+func Diff(a, b *Database) Changes {
+	if a == nil {
+		a = &Database{}
+	}
+	if b == nil {
+		b = &Database{}
+	}
+
+	var c Changes
+	for _, name := range unionKeys(a.Tables, b.Tables) {
+		from, to := a.Tables[name], b.Tables[name]
+		switch {
+		case from == nil:
+			c.Added = append(c.Added, Change{Kind: KindTable, Name: name})
+		case to == nil:
+			c.Dropped = append(c.Dropped, Change{Kind: KindTable, Name: name})
+		default:
+			var details []string
+			details = diffString(details, "folder", from.Folder, to.Folder)
+			details = diffString(details, "docstring", from.DocString, to.DocString)
+			c.diffColumns(KindTable, name, &details, from.Columns, to.Columns)
+		}
+	}
+
+	for _, name := range unionKeys(a.MaterializedViews, b.MaterializedViews) {
+		from, to := a.MaterializedViews[name], b.MaterializedViews[name]
+		switch {
+		case from == nil:
+			c.Added = append(c.Added, Change{Kind: KindMaterializedView, Name: name})
+		case to == nil:
+			c.Dropped = append(c.Dropped, Change{Kind: KindMaterializedView, Name: name})
+		default:
+			var details []string
+			details = diffString(details, "source table", from.SourceTable, to.SourceTable)
+			if from.Query != to.Query {
+				details = append(details, "query changed")
+			}
+			details = diffString(details, "folder", from.Folder, to.Folder)
+			details = diffString(details, "docstring", from.DocString, to.DocString)
+			c.diffColumns(KindMaterializedView, name, &details, from.Columns, to.Columns)
+		}
+	}
+
+	for _, name := range unionKeys(a.Functions, b.Functions) {
+		from, to := a.Functions[name], b.Functions[name]
+		switch {
+		case from == nil:
+			c.Added = append(c.Added, Change{Kind: KindFunction, Name: name})
+		case to == nil:
+			c.Dropped = append(c.Dropped, Change{Kind: KindFunction, Name: name})
+		default:
+			var details []string
+			if signature(from.Parameters) != signature(to.Parameters) {
+				details = append(details, fmt.Sprintf("parameters changed from (%s) to (%s)", signature(from.Parameters), signature(to.Parameters)))
+			}
+			if from.Body != to.Body {
+				details = append(details, "body changed")
+			}
+			details = diffString(details, "folder", from.Folder, to.Folder)
+			details = diffString(details, "docstring", from.DocString, to.DocString)
+			if len(details) > 0 {
+				c.Altered = append(c.Altered, Change{Kind: KindFunction, Name: name, Details: details})
+			}
+		}
+	}
+
+	return c
+}
+
+// diffColumns adds the changes of the columns of the table or materialized view name, after the change of the entity
+// itself, if details, to which a change of the order of the columns is added, isn't empty.
+func (c *Changes) diffColumns(kind Kind, name string, details *[]string, from, to []Column) {
+	var added, dropped, altered []Change
+	var kept, keptTo []string
+	for _, col := range from {
+		other := columnByName(to, col.Name)
+		if other == nil {
+			dropped = append(dropped, Change{Kind: KindColumn, Name: name + "." + col.Name})
+			continue
+		}
+		kept = append(kept, col.Name)
+		var colDetails []string
+		colDetails = diffString(colDetails, "type", string(col.Type), string(other.Type))
+		colDetails = diffString(colDetails, "docstring", col.DocString, other.DocString)
+		if len(colDetails) > 0 {
+			altered = append(altered, Change{Kind: KindColumn, Name: name + "." + col.Name, Details: colDetails})
+		}
+	}
+	for _, col := range to {
+		if columnByName(from, col.Name) == nil {
+			added = append(added, Change{Kind: KindColumn, Name: name + "." + col.Name})
+			continue
+		}
+		keptTo = append(keptTo, col.Name)
+	}
+	if strings.Join(kept, ",") != strings.Join(keptTo, ",") {
+		*details = append(*details, "columns reordered")
+	}
+
+	if len(*details) > 0 {
+		c.Altered = append(c.Altered, Change{Kind: kind, Name: name, Details: *details})
+	}
+	c.Added = append(c.Added, added...)
+	c.Dropped = append(c.Dropped, dropped...)
+	c.Altered = append(c.Altered, altered...)
+}
+
+func diffString(details []string, what, from, to string) []string {
+	if from == to {
+		return details
+	}
+	return append(details, fmt.Sprintf("%s changed from %q to %q", what, from, to))
+}
+
+// signature returns the parameters as they are declared in KQL, such as `x:long, T:(a:string)`.
+func signature(parameters []Parameter) string {
+	parts := make([]string, len(parameters))
+	for i, p := range parameters {
+		var sb strings.Builder
+		sb.WriteString(p.Name)
+		sb.WriteString(":")
+		if p.Tabular {
+			sb.WriteString("(")
+			if len(p.Columns) == 0 {
+				sb.WriteString("*")
+			}
+			for j, col := range p.Columns {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				sb.WriteString(col.Name + ":" + string(col.Type))
+			}
+			sb.WriteString(")")
+		} else {
+			sb.WriteString(string(p.Type))
+		}
+		if p.Default != "" {
+			sb.WriteString(" = " + p.Default)
+		}
+		parts[i] = sb.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func unionKeys[T any](a, b map[string]T) []string {
+	union := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		union[k] = struct{}{}
+	}
+	for k := range b {
+		union[k] = struct{}{}
+	}
+	return sortedKeys(union)
+}
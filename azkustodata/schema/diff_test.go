@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	a, err := Parse([]byte(dbSchema), "db")
+	require.NoError(t, err)
+	assert.True(t, Diff(a, a).Empty())
+
+	b, err := Parse([]byte(dbSchema), "db")
+	require.NoError(t, err)
+
+	// Tables: Logs is dropped, Metrics is added, and Events has a column altered, dropped, added and a new folder.
+	delete(b.Tables, "Logs")
+	b.Tables["Metrics"] = &Table{Name: "Metrics"}
+	events := b.Table("Events")
+	events.Folder = "curated"
+	events.Columns = []Column{{Name: "Id", Type: types.Int, DocString: "The id"}, {Name: "Level", Type: types.Int}}
+	// Materialized views: Latest has a new query and reordered columns.
+	view := b.MaterializedView("Latest")
+	view.Query = "Events | summarize arg_max(Id, *) by Message"
+	view.Columns = append(view.Columns, Column{Name: "Message", Type: types.String})
+	a.MaterializedView("Latest").Columns = []Column{{Name: "Message", Type: types.String}, {Name: "Id", Type: types.Long}}
+	// Functions: Recent has a new default, Old is dropped.
+	a.Functions["Old"] = &Function{Name: "Old"}
+	b.Function("Recent").Parameters[0].Default = "20"
+
+	c := Diff(a, b)
+	assert.Equal(t, []Change{
+		{Kind: KindColumn, Name: "Events.Level"},
+		{Kind: KindTable, Name: "Metrics"},
+	}, c.Added)
+	assert.Equal(t, []Change{
+		{Kind: KindColumn, Name: "Events.Message"},
+		{Kind: KindTable, Name: "Logs"},
+		{Kind: KindFunction, Name: "Old"},
+	}, c.Dropped)
+	assert.Equal(t, []Change{
+		{Kind: KindTable, Name: "Events", Details: []string{`folder changed from "raw" to "curated"`}},
+		{Kind: KindColumn, Name: "Events.Id", Details: []string{`type changed from "long" to "int"`}},
+		{Kind: KindMaterializedView, Name: "Latest", Details: []string{"query changed", "columns reordered"}},
+		{Kind: KindFunction, Name: "Recent", Details: []string{"parameters changed from (n:long = 10, T:(Id:long)) to (n:long = 20, T:(Id:long))"}},
+	}, c.Altered)
+	assert.False(t, c.Empty())
+	assert.Equal(t, `column Events.Id: type changed from "long" to "int"`, c.Altered[1].String())
+	assert.Equal(t, "table Metrics", c.Added[1].String())
+
+	// A nil schema is empty.
+	assert.Len(t, Diff(nil, a).Added, 5)
+	assert.Len(t, Diff(a, nil).Dropped, 5)
+}
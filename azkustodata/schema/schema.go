@@ -0,0 +1,332 @@
+/*
+Package schema decodes the schema of a database, as returned by `.show database DB schema as json`, into a model that
+can be navigated and compared, for tools that detect schema drift between databases or over time:
+
+	before, err := schema.Load(ctx, client, "db")
+	...
+	after, err := schema.Load(ctx, client, "db")
+	...
+	for _, change := range schema.Diff(before, after).Altered {
+		fmt.Println(change)
+	}
+*/
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+)
+
+// MgmtClient runs management commands. *azkustodata.Client implements it.
+type MgmtClient interface {
+	Mgmt(ctx context.Context, db string, kqlQuery azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error)
+}
+
+// Database is the schema of a database.
+type Database struct {
+	Name string
+	// Tables, MaterializedViews and Functions are keyed by their names.
+	Tables            map[string]*Table
+	MaterializedViews map[string]*MaterializedView
+	Functions         map[string]*Function
+}
+
+// Table is the schema of a table.
+type Table struct {
+	Name      string
+	Folder    string
+	DocString string
+	// Columns are the columns of the table, in order.
+	Columns []Column
+}
+
+// Column is a column of a table or a materialized view, or of a tabular parameter of a function.
+type Column struct {
+	Name      string
+	Type      types.Column
+	DocString string
+}
+
+// MaterializedView is the schema of a materialized view.
+type MaterializedView struct {
+	Name        string
+	SourceTable string
+	Query       string
+	Folder      string
+	DocString   string
+	// Columns are the columns of the view, in order.
+	Columns []Column
+}
+
+// Function is the schema of a stored function.
+type Function struct {
+	Name      string
+	Body      string
+	Folder    string
+	DocString string
+	// Parameters are the parameters of the function, in order.
+	Parameters []Parameter
+}
+
+// Parameter is a parameter of a function.
+type Parameter struct {
+	Name string
+	// Type is the type of a scalar parameter, and empty for tabular ones.
+	Type types.Column
+	// Default is the default value of the parameter, as a KQL literal, or empty if it has none.
+	Default string
+	// Tabular is set for tabular parameters, with the columns they require in Columns, if any.
+	Tabular bool
+	Columns []Column
+}
+
+// Table returns the table with the given name, or nil if there is none.
+func (d *Database) Table(name string) *Table {
+	return d.Tables[name]
+}
+
+// MaterializedView returns the materialized view with the given name, or nil if there is none.
+func (d *Database) MaterializedView(name string) *MaterializedView {
+	return d.MaterializedViews[name]
+}
+
+// Function returns the function with the given name, or nil if there is none.
+func (d *Database) Function(name string) *Function {
+	return d.Functions[name]
+}
+
+// TableNames returns the names of the tables, sorted.
+func (d *Database) TableNames() []string {
+	return sortedKeys(d.Tables)
+}
+
+// MaterializedViewNames returns the names of the materialized views, sorted.
+func (d *Database) MaterializedViewNames() []string {
+	return sortedKeys(d.MaterializedViews)
+}
+
+// FunctionNames returns the names of the functions, sorted.
+func (d *Database) FunctionNames() []string {
+	return sortedKeys(d.Functions)
+}
+
+// Column returns the column with the given name, or nil if there is none.
+func (t *Table) Column(name string) *Column {
+	return columnByName(t.Columns, name)
+}
+
+// Column returns the column with the given name, or nil if there is none.
+func (v *MaterializedView) Column(name string) *Column {
+	return columnByName(v.Columns, name)
+}
+
+// Load returns the schema of database db, with `.show database db schema as json`.
+func Load(ctx context.Context, client MgmtClient, db string, options ...azkustodata.QueryOption) (*Database, error) {
+	if db == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "schema.Load requires a database").SetNoRetry()
+	}
+
+	dataset, err := client.Mgmt(ctx, db, kql.New(".show database ").AddUnsafe(kql.NormalizeName(db)).AddLiteral(" schema as json"), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query.ToStructs[schemaRow](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single row for the schema of database %s, got %d", db, len(rows))
+	}
+
+	return Parse([]byte(rows[0].DatabaseSchema), db)
+}
+
+// Parse decodes the DatabaseSchema column of `.show database db schema as json` or `.show databases schema as json`,
+// and returns the schema of database db. If db is empty, the JSON must hold a single database.
+func Parse(b []byte, db string) (*Database, error) {
+	var clusterSchema jsonCluster
+	if err := json.Unmarshal(b, &clusterSchema); err != nil {
+		return nil, errors.E(errors.OpMgmt, errors.KFailedToParse, fmt.Errorf("could not parse the database schema: %w", err))
+	}
+
+	var dbSchema *jsonDatabase
+	switch {
+	case db != "":
+		dbSchema = clusterSchema.Databases[db]
+		if dbSchema == nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KFailedToParse, "the schema has no database %s", db)
+		}
+	case len(clusterSchema.Databases) == 1:
+		for name, s := range clusterSchema.Databases {
+			db, dbSchema = name, s
+		}
+	default:
+		return nil, errors.ES(errors.OpMgmt, errors.KFailedToParse, "the schema has %d databases, expected a single one", len(clusterSchema.Databases))
+	}
+
+	return dbSchema.toDatabase(db)
+}
+
+// schemaRow is the row of `.show database db schema as json`.
+type schemaRow struct {
+	DatabaseSchema string
+}
+
+type jsonCluster struct {
+	Databases map[string]*jsonDatabase
+}
+
+type jsonDatabase struct {
+	Name              string
+	Tables            map[string]jsonTable
+	MaterializedViews map[string]jsonMaterializedView
+	Functions         map[string]jsonFunction
+}
+
+type jsonTable struct {
+	Name           string
+	Folder         string
+	DocString      string
+	OrderedColumns []jsonColumn
+}
+
+type jsonMaterializedView struct {
+	Name           string
+	SourceTable    string
+	Query          string
+	Folder         string
+	DocString      string
+	OrderedColumns []jsonColumn
+}
+
+type jsonFunction struct {
+	Name            string
+	Body            string
+	Folder          string
+	DocString       string
+	InputParameters []jsonParameter
+}
+
+type jsonColumn struct {
+	Name      string
+	CslType   string
+	DocString string
+}
+
+type jsonParameter struct {
+	Name            string
+	CslType         string
+	CslDefaultValue *string
+	// Columns are the columns of a tabular parameter.
+	Columns []jsonColumn
+}
+
+func (s *jsonDatabase) toDatabase(db string) (*Database, error) {
+	d := &Database{
+		Name:              s.Name,
+		Tables:            make(map[string]*Table, len(s.Tables)),
+		MaterializedViews: make(map[string]*MaterializedView, len(s.MaterializedViews)),
+		Functions:         make(map[string]*Function, len(s.Functions)),
+	}
+	if d.Name == "" {
+		d.Name = db
+	}
+
+	for key, t := range s.Tables {
+		name := nameOr(t.Name, key)
+		columns, err := toColumns(t.OrderedColumns, "table "+name)
+		if err != nil {
+			return nil, err
+		}
+		d.Tables[name] = &Table{Name: name, Folder: t.Folder, DocString: t.DocString, Columns: columns}
+	}
+
+	for key, v := range s.MaterializedViews {
+		name := nameOr(v.Name, key)
+		columns, err := toColumns(v.OrderedColumns, "materialized view "+name)
+		if err != nil {
+			return nil, err
+		}
+		d.MaterializedViews[name] = &MaterializedView{
+			Name:        name,
+			SourceTable: v.SourceTable,
+			Query:       v.Query,
+			Folder:      v.Folder,
+			DocString:   v.DocString,
+			Columns:     columns,
+		}
+	}
+
+	for key, f := range s.Functions {
+		name := nameOr(f.Name, key)
+		parameters := make([]Parameter, len(f.InputParameters))
+		for i, p := range f.InputParameters {
+			parameter := Parameter{Name: p.Name}
+			if p.CslDefaultValue != nil {
+				parameter.Default = *p.CslDefaultValue
+			}
+			if p.CslType == "" || p.Columns != nil {
+				columns, err := toColumns(p.Columns, fmt.Sprintf("parameter %s of function %s", p.Name, name))
+				if err != nil {
+					return nil, err
+				}
+				parameter.Tabular = true
+				parameter.Columns = columns
+			} else if parameter.Type = types.NormalizeColumn(p.CslType); parameter.Type == "" {
+				return nil, errors.ES(errors.OpMgmt, errors.KFailedToParse, "parameter %s of function %s is of type %q, which is not valid",
+					p.Name, name, p.CslType)
+			}
+			parameters[i] = parameter
+		}
+		d.Functions[name] = &Function{Name: name, Body: f.Body, Folder: f.Folder, DocString: f.DocString, Parameters: parameters}
+	}
+
+	return d, nil
+}
+
+func toColumns(cols []jsonColumn, of string) ([]Column, error) {
+	columns := make([]Column, len(cols))
+	for i, c := range cols {
+		kustoType := types.NormalizeColumn(c.CslType)
+		if kustoType == "" {
+			return nil, errors.ES(errors.OpMgmt, errors.KFailedToParse, "column %s of %s is of type %q, which is not valid", c.Name, of, c.CslType)
+		}
+		columns[i] = Column{Name: c.Name, Type: kustoType, DocString: c.DocString}
+	}
+	return columns, nil
+}
+
+func nameOr(name, key string) string {
+	if name != "" {
+		return name
+	}
+	return key
+}
+
+func columnByName(columns []Column, name string) *Column {
+	for i := range columns {
+		if columns[i].Name == name {
+			return &columns[i]
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
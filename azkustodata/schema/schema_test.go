@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dbSchema = `{"Plugins":[],"Databases":{"db":{"Name":"db","Tables":{
+	"Events":{"Name":"Events","Folder":"raw","DocString":"All the events","OrderedColumns":[
+		{"Name":"Id","Type":"System.Int64","CslType":"long","DocString":"The id"},
+		{"Name":"Message","Type":"System.String","CslType":"string"}]},
+	"Logs":{"Name":"Logs","OrderedColumns":[{"Name":"Line","Type":"System.String","CslType":"string"}]}},
+	"MaterializedViews":{"Latest":{"Name":"Latest","SourceTable":"Events","Query":"Events | summarize arg_max(Id, *)","OrderedColumns":[
+		{"Name":"Id","Type":"System.Int64","CslType":"long"}]}},
+	"Functions":{"Recent":{"Name":"Recent","Body":"{ Events | take n }","Folder":"helpers","InputParameters":[
+		{"Name":"n","Type":"System.Int64","CslType":"long","CslDefaultValue":"10"},
+		{"Name":"T","Columns":[{"Name":"Id","Type":"System.Int64","CslType":"long"}]}]}},
+	"MajorVersion":1,"MinorVersion":2}}}`
+
+type fakeClient struct {
+	db      string
+	command string
+	schema  string
+}
+
+func (f *fakeClient) Mgmt(ctx context.Context, db string, kqlQuery azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+	f.db = db
+	f.command = kqlQuery.String()
+	cell, err := json.Marshal(f.schema)
+	if err != nil {
+		return nil, err
+	}
+	res := `{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"DatabaseSchema","DataType":"String","ColumnType":"string"}],"Rows":[[` + string(cell) + `]]}]}`
+	return v1.NewDatasetFromReader(ctx, errors.OpMgmt, io.NopCloser(strings.NewReader(res)))
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{schema: dbSchema}
+	d, err := Load(context.Background(), client, "db")
+	require.NoError(t, err)
+	assert.Equal(t, "db", client.db)
+	assert.Equal(t, ".show database db schema as json", client.command)
+
+	assert.Equal(t, "db", d.Name)
+	assert.Equal(t, []string{"Events", "Logs"}, d.TableNames())
+	events := d.Table("Events")
+	require.NotNil(t, events)
+	assert.Equal(t, "raw", events.Folder)
+	assert.Equal(t, "All the events", events.DocString)
+	assert.Equal(t, []Column{{Name: "Id", Type: types.Long, DocString: "The id"}, {Name: "Message", Type: types.String}}, events.Columns)
+	assert.Equal(t, types.String, events.Column("Message").Type)
+	assert.Nil(t, events.Column("Missing"))
+	assert.Nil(t, d.Table("Missing"))
+
+	assert.Equal(t, []string{"Latest"}, d.MaterializedViewNames())
+	view := d.MaterializedView("Latest")
+	assert.Equal(t, "Events", view.SourceTable)
+	assert.Equal(t, types.Long, view.Column("Id").Type)
+
+	assert.Equal(t, []string{"Recent"}, d.FunctionNames())
+	recent := d.Function("Recent")
+	assert.Equal(t, "{ Events | take n }", recent.Body)
+	assert.Equal(t, []Parameter{
+		{Name: "n", Type: types.Long, Default: "10"},
+		{Name: "T", Tabular: true, Columns: []Column{{Name: "Id", Type: types.Long}}},
+	}, recent.Parameters)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Load(context.Background(), &fakeClient{schema: dbSchema}, "")
+	assert.Error(t, err)
+	_, err = Load(context.Background(), &fakeClient{schema: dbSchema}, "other")
+	assert.ErrorContains(t, err, "no database other")
+	_, err = Load(context.Background(), &fakeClient{schema: "not json"}, "db")
+	assert.Error(t, err)
+	_, err = Load(context.Background(), &fakeClient{schema: strings.Replace(dbSchema, `"CslType":"string"`, `"CslType":"text"`, 1)}, "db")
+	assert.ErrorContains(t, err, `column Message of table Events is of type "text"`)
+}
+
+func TestParseSingleDatabase(t *testing.T) {
+	t.Parallel()
+
+	d, err := Parse([]byte(dbSchema), "")
+	require.NoError(t, err)
+	assert.Equal(t, "db", d.Name)
+
+	_, err = Parse([]byte(`{"Databases":{"a":{},"b":{}}}`), "")
+	assert.ErrorContains(t, err, "2 databases")
+}
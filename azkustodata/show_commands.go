@@ -0,0 +1,294 @@
+package azkustodata
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/google/uuid"
+)
+
+// defaultPageSize is the number of rows fetched by every command of a MgmtPager, unless set with PageSize.
+const defaultPageSize = 10000
+
+// PagerOption is an option for a MgmtPager.
+type PagerOption func(p *pagerOptions)
+
+type pagerOptions struct {
+	pageSize     int
+	queryOptions []QueryOption
+}
+
+// PageSize sets the maximum number of rows fetched by every command. The default is 10,000.
+func PageSize(n int) PagerOption {
+	return func(p *pagerOptions) {
+		p.pageSize = n
+	}
+}
+
+// PageQueryOptions sets the QueryOptions passed to every command.
+func PageQueryOptions(options ...QueryOption) PagerOption {
+	return func(p *pagerOptions) {
+		p.queryOptions = append(p.queryOptions, options...)
+	}
+}
+
+// pageCursor tracks the position of a MgmtPager in the results of a command.
+type pageCursor[T any] interface {
+	// command returns the command of the next page, and the number of rows it requests.
+	command(pageSize int) (*kql.Builder, int)
+	// advance moves the cursor past the rows of a page, and returns the rows that were not returned by previous pages.
+	advance(rows []T) []T
+}
+
+// MgmtPager fetches the results of a management command page by page, with a separate command for every page.
+// Management commands return their whole result in a single response, so commands such as `.show extents` or
+// `.show journal` on large databases would otherwise hold millions of rows in memory.
+// A MgmtPager is not safe for concurrent use.
+type MgmtPager[T any] struct {
+	client  *Client
+	db      string
+	cursor  pageCursor[T]
+	options pagerOptions
+	done    bool
+}
+
+func newMgmtPager[T any](client *Client, db string, cursor pageCursor[T], options []PagerOption) *MgmtPager[T] {
+	opts := pagerOptions{pageSize: defaultPageSize}
+	for _, o := range options {
+		o(&opts)
+	}
+	return &MgmtPager[T]{client: client, db: db, cursor: cursor, options: opts}
+}
+
+// Done returns true once all the pages were fetched.
+func (p *MgmtPager[T]) Done() bool {
+	return p.done
+}
+
+// Next fetches the next page. It returns an empty page once Done is true.
+func (p *MgmtPager[T]) Next(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+	if p.options.pageSize <= 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "page size must be positive, got %d", p.options.pageSize).SetNoRetry()
+	}
+
+	cmd, requested := p.cursor.command(p.options.pageSize)
+	dataset, err := p.client.Mgmt(ctx, p.db, cmd, p.options.queryOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query.ToStructs[T](dataset.Tables()[0])
+	if err != nil {
+		return nil, err
+	}
+
+	p.done = len(rows) < requested
+	return p.cursor.advance(rows), nil
+}
+
+// All fetches all the remaining pages, and returns their rows.
+// This holds all the rows in memory, use Next to process large results page by page.
+func (p *MgmtPager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for !p.Done() {
+		rows, err := p.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, rows...)
+	}
+	return all, nil
+}
+
+// Extent is a row of `.show extents`.
+type Extent struct {
+	ExtentId       uuid.UUID
+	DatabaseName   string
+	TableName      string
+	MaxCreatedOn   time.Time
+	MinCreatedOn   time.Time
+	OriginalSize   float64
+	ExtentSize     float64
+	CompressedSize float64
+	IndexSize      float64
+	RowCount       int64
+	Tags           string
+}
+
+// ExtentsFilter selects the extents listed by ShowExtents.
+type ExtentsFilter struct {
+	// Table limits the extents to those of a table. The extents of all the tables of the database are listed if empty.
+	Table string
+	// Tags limits the extents to those that have all the given tags.
+	Tags []string
+	// CreatedAfter limits the extents to those with data created after it, according to their MaxCreatedOn.
+	CreatedAfter time.Time
+}
+
+// ShowExtents lists the extents of a database, or of a table, with `.show extents`.
+// The extents are fetched in pages ordered by their id, see MgmtPager.
+func (c *Client) ShowExtents(db string, filter ExtentsFilter, options ...PagerOption) *MgmtPager[Extent] {
+	return newMgmtPager[Extent](c, db, &extentsCursor{db: db, filter: filter}, options)
+}
+
+// IngestByTagPrefix is the prefix of the ingest-by tags, which mark the extents of an ingestion so that it can be
+// skipped if it is attempted again.
+// For more information see: https://docs.microsoft.com/en-us/azure/kusto/management/extents-overview#ingest-by-extent-tags
+const IngestByTagPrefix = "ingest-by:"
+
+// HasIngestByTag reports whether a table has an extent tagged with the ingest-by tag, with
+// `.show table extents where tags has`. tag can be given with or without the "ingest-by:" prefix.
+// Together with the IfNotExists and IngestByTags ingestion options, it allows coordinating ingestions that must happen
+// once. Note that the tags of extents that were merged or dropped may be gone.
+func (c *Client) HasIngestByTag(ctx context.Context, db, table, tag string) (bool, error) {
+	if table == "" {
+		return false, errors.ES(errors.OpMgmt, errors.KClientArgs, "HasIngestByTag requires a table").SetNoRetry()
+	}
+	if strings.TrimPrefix(tag, IngestByTagPrefix) == "" {
+		return false, errors.ES(errors.OpMgmt, errors.KClientArgs, "HasIngestByTag requires a tag").SetNoRetry()
+	}
+	if !strings.HasPrefix(tag, IngestByTagPrefix) {
+		tag = IngestByTagPrefix + tag
+	}
+
+	extents, err := c.ShowExtents(db, ExtentsFilter{Table: table, Tags: []string{tag}}, PageSize(1)).Next(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(extents) > 0, nil
+}
+
+type extentsCursor struct {
+	db     string
+	filter ExtentsFilter
+	last   *uuid.UUID
+}
+
+func (e *extentsCursor) command(pageSize int) (*kql.Builder, int) {
+	cmd := kql.New("")
+	if e.filter.Table != "" {
+		cmd.AddLiteral(".show table ").AddTable(e.filter.Table).AddLiteral(" extents")
+	} else {
+		cmd.AddLiteral(".show database ").AddUnsafe(kql.NormalizeName(e.db)).AddLiteral(" extents")
+	}
+
+	for i, tag := range e.filter.Tags {
+		if i == 0 {
+			cmd.AddLiteral(" where tags has ")
+		} else {
+			cmd.AddLiteral(" and tags has ")
+		}
+		cmd.AddString(tag)
+	}
+
+	if !e.filter.CreatedAfter.IsZero() {
+		cmd.AddLiteral(" | where MaxCreatedOn > ").AddDateTime(e.filter.CreatedAfter)
+	}
+	// Extent ids are unique, so the next page starts right after the last id.
+	if e.last != nil {
+		cmd.AddLiteral(" | where strcmp(tostring(ExtentId), ").AddString(e.last.String()).AddLiteral(") > 0")
+	}
+
+	cmd.AddLiteral(" | order by tostring(ExtentId) asc | take ").AddLong(int64(pageSize))
+	return cmd, pageSize
+}
+
+func (e *extentsCursor) advance(rows []Extent) []Extent {
+	if len(rows) > 0 {
+		e.last = &rows[len(rows)-1].ExtentId
+	}
+	return rows
+}
+
+// JournalEntry is a row of `.show journal`.
+type JournalEntry struct {
+	Event               string
+	EventTimestamp      time.Time
+	Database            string
+	EntityName          string
+	UpdatedEntityName   string
+	EntityVersion       string
+	EntityContainerName string
+	OriginalEntityState string
+	UpdatedEntityState  string
+	ChangeCommand       string
+	Principal           string
+}
+
+// JournalFilter selects the entries listed by ShowJournal.
+type JournalFilter struct {
+	// Since limits the entries to those at or after it.
+	Since time.Time
+	// Events limits the entries to the given event types, such as "CREATE-TABLE".
+	Events []string
+	// EntityName limits the entries to those of an entity, such as a table.
+	EntityName string
+}
+
+// ShowJournal lists the metadata operations of a database, with `.show database journal`.
+// The entries are fetched in pages ordered by their EventTimestamp, see MgmtPager.
+func (c *Client) ShowJournal(db string, filter JournalFilter, options ...PagerOption) *MgmtPager[JournalEntry] {
+	return newMgmtPager[JournalEntry](c, db, &journalCursor{db: db, filter: filter, since: filter.Since}, options)
+}
+
+type journalCursor struct {
+	db     string
+	filter JournalFilter
+	since  time.Time
+	// seen is the number of entries at "since" that were already returned.
+	seen int
+}
+
+func (j *journalCursor) command(pageSize int) (*kql.Builder, int) {
+	cmd := kql.New(".show database ").AddUnsafe(kql.NormalizeName(j.db)).AddLiteral(" journal")
+
+	if !j.since.IsZero() {
+		cmd.AddLiteral(" | where EventTimestamp >= ").AddDateTime(j.since)
+	}
+	if len(j.filter.Events) > 0 {
+		cmd.AddLiteral(" | where Event in (")
+		for i, event := range j.filter.Events {
+			if i > 0 {
+				cmd.AddLiteral(", ")
+			}
+			cmd.AddString(event)
+		}
+		cmd.AddLiteral(")")
+	}
+	if j.filter.EntityName != "" {
+		cmd.AddLiteral(" | where EntityName == ").AddString(j.filter.EntityName)
+	}
+
+	// Timestamps are not unique, so the next page starts at the last timestamp, and the entries of the previous
+	// pages at that timestamp are fetched again and skipped.
+	requested := pageSize + j.seen
+	cmd.AddLiteral(" | order by EventTimestamp asc, Event asc, EntityName asc, EntityVersion asc | take ").AddLong(int64(requested))
+	return cmd, requested
+}
+
+func (j *journalCursor) advance(rows []JournalEntry) []JournalEntry {
+	if j.seen > len(rows) {
+		j.seen = len(rows)
+	}
+	rows = rows[j.seen:]
+	if len(rows) == 0 {
+		return rows
+	}
+
+	last := rows[len(rows)-1].EventTimestamp
+	if !last.Equal(j.since) {
+		j.since = last
+		j.seen = 0
+	}
+	for i := len(rows) - 1; i >= 0 && rows[i].EventTimestamp.Equal(last); i-- {
+		j.seen++
+	}
+	return rows
+}
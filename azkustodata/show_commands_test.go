@@ -0,0 +1,160 @@
+package azkustodata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func extentsResponse(ids ...string) string {
+	rows := make([]string, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, `["`+id+`","db","T","2024-01-02T03:04:05Z",100.0,10]`)
+	}
+	return `{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"ExtentId","DataType":"Guid","ColumnType":"guid"},` +
+		`{"ColumnName":"DatabaseName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"TableName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"MaxCreatedOn","DataType":"DateTime","ColumnType":"datetime"},` +
+		`{"ColumnName":"OriginalSize","DataType":"Double","ColumnType":"real"},` +
+		`{"ColumnName":"RowCount","DataType":"Int64","ColumnType":"long"}],` +
+		`"Rows":[` + strings.Join(rows, ",") + `]}]}`
+}
+
+func journalResponse(entries ...[2]string) string {
+	rows := make([]string, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, fmt.Sprintf(`["CREATE-TABLE","%s","db","%s"]`, e[0], e[1]))
+	}
+	return `{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"Event","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"EventTimestamp","DataType":"DateTime","ColumnType":"datetime"},` +
+		`{"ColumnName":"Database","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"EntityName","DataType":"String","ColumnType":"string"}],` +
+		`"Rows":[` + strings.Join(rows, ",") + `]}]}`
+}
+
+const (
+	extentA = "00000000-0000-0000-0000-00000000000a"
+	extentB = "00000000-0000-0000-0000-00000000000b"
+	extentC = "00000000-0000-0000-0000-00000000000c"
+)
+
+func TestShowExtents(t *testing.T) {
+	t.Parallel()
+
+	conn := &fakeMgmtConn{responses: []string{extentsResponse(extentA, extentB), extentsResponse(extentC)}}
+	client := &Client{conn: conn}
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pager := client.ShowExtents("db", ExtentsFilter{Table: "T", Tags: []string{"drop-by:a", "b"}, CreatedAfter: created}, PageSize(2))
+
+	extents, err := pager.All(context.Background())
+	require.NoError(t, err)
+	assert.True(t, pager.Done())
+
+	require.Len(t, extents, 3)
+	assert.Equal(t, extentA, extents[0].ExtentId.String())
+	assert.Equal(t, extentC, extents[2].ExtentId.String())
+	assert.Equal(t, "T", extents[0].TableName)
+	assert.Equal(t, 100.0, extents[0].OriginalSize)
+	assert.Equal(t, int64(10), extents[0].RowCount)
+
+	assert.Equal(t, []string{
+		`.show table T extents where tags has "drop-by:a" and tags has "b" | where MaxCreatedOn > datetime(2024-01-01T00:00:00Z) | order by tostring(ExtentId) asc | take long(2)`,
+		`.show table T extents where tags has "drop-by:a" and tags has "b" | where MaxCreatedOn > datetime(2024-01-01T00:00:00Z) | where strcmp(tostring(ExtentId), "` + extentB + `") > 0 | order by tostring(ExtentId) asc | take long(2)`,
+	}, conn.commands)
+}
+
+func TestShowExtentsDatabase(t *testing.T) {
+	t.Parallel()
+
+	// A full last page needs another command to know that there are no more rows.
+	conn := &fakeMgmtConn{responses: []string{extentsResponse(extentA), extentsResponse()}}
+	client := &Client{conn: conn}
+
+	pager := client.ShowExtents("db", ExtentsFilter{}, PageSize(1))
+
+	page, err := pager.Next(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.False(t, pager.Done())
+
+	page, err = pager.Next(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	assert.True(t, pager.Done())
+
+	assert.Equal(t, `.show database db extents | order by tostring(ExtentId) asc | take long(1)`, conn.commands[0])
+
+	_, err = client.ShowExtents("db", ExtentsFilter{}, PageSize(0)).Next(context.Background())
+	assert.Error(t, err)
+}
+
+func TestShowJournal(t *testing.T) {
+	t.Parallel()
+
+	const (
+		t1 = "2024-01-01T00:00:00Z"
+		t2 = "2024-01-02T00:00:00Z"
+		t3 = "2024-01-03T00:00:00Z"
+	)
+
+	// The entries at t2 span pages, so they are fetched again and skipped.
+	conn := &fakeMgmtConn{responses: []string{
+		journalResponse([2]string{t1, "A"}, [2]string{t2, "B"}),
+		journalResponse([2]string{t2, "B"}, [2]string{t2, "C"}, [2]string{t2, "D"}),
+		journalResponse([2]string{t2, "B"}, [2]string{t2, "C"}, [2]string{t2, "D"}, [2]string{t3, "E"}),
+	}}
+	client := &Client{conn: conn}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pager := client.ShowJournal("db", JournalFilter{Since: since, Events: []string{"CREATE-TABLE", "DROP-TABLE"}, EntityName: "T"}, PageSize(2))
+
+	entries, err := pager.All(context.Background())
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.EntityName)
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D", "E"}, names)
+
+	const filters = ` | where Event in ("CREATE-TABLE", "DROP-TABLE") | where EntityName == "T" | order by EventTimestamp asc, Event asc, EntityName asc, EntityVersion asc | take `
+	assert.Equal(t, []string{
+		`.show database db journal | where EventTimestamp >= datetime(2024-01-01T00:00:00Z)` + filters + `long(2)`,
+		`.show database db journal | where EventTimestamp >= datetime(2024-01-02T00:00:00Z)` + filters + `long(3)`,
+		`.show database db journal | where EventTimestamp >= datetime(2024-01-02T00:00:00Z)` + filters + `long(5)`,
+	}, conn.commands)
+}
+
+func TestHasIngestByTag(t *testing.T) {
+	t.Parallel()
+
+	conn := &fakeMgmtConn{responses: []string{extentsResponse(extentA), extentsResponse()}}
+	client := &Client{conn: conn}
+
+	found, err := client.HasIngestByTag(context.Background(), "db", "T", "batch-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = client.HasIngestByTag(context.Background(), "db", "T", "ingest-by:batch-2")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	assert.Equal(t, []string{
+		`.show table T extents where tags has "ingest-by:batch-1" | order by tostring(ExtentId) asc | take long(1)`,
+		`.show table T extents where tags has "ingest-by:batch-2" | order by tostring(ExtentId) asc | take long(1)`,
+	}, conn.commands)
+
+	for _, args := range [][2]string{{"", "batch"}, {"T", ""}, {"T", "ingest-by:"}} {
+		_, err = client.HasIngestByTag(context.Background(), "db", args[0], args[1])
+		assert.Error(t, err)
+	}
+	assert.Len(t, conn.commands, 2)
+}
@@ -0,0 +1,102 @@
+package azkustodata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+)
+
+// TableSchema is the schema of a table, as returned by `.show table schema as json`.
+type TableSchema struct {
+	Database string
+	Name     string
+	// Folder is the folder of the table, if it has one.
+	Folder string
+	// DocString is the documentation of the table, if it has one.
+	DocString string
+	// Columns are the columns of the table, in order. They implement query.ColumnMetadata, with their .NET types and
+	// documentation.
+	Columns []query.Column
+}
+
+// ColumnByName returns the column with the given name, or nil if there is none.
+func (s *TableSchema) ColumnByName(name string) query.Column {
+	for _, c := range s.Columns {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// tableSchemaRow is a row of `.show table schema as json`.
+type tableSchemaRow struct {
+	TableName    string
+	Schema       string
+	DatabaseName string
+	Folder       string
+	DocString    string
+}
+
+// orderedColumns is the Schema column of `.show table schema as json`.
+type orderedColumns struct {
+	OrderedColumns []struct {
+		Name      string
+		Type      string
+		CslType   string
+		DocString string
+	}
+}
+
+// ShowTableSchema returns the schema of a table with `.show table schema as json`, with the documentation of the
+// table and its columns, and the folder of the table.
+func (c *Client) ShowTableSchema(ctx context.Context, db, table string, options ...QueryOption) (*TableSchema, error) {
+	if table == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "ShowTableSchema requires a table").SetNoRetry()
+	}
+
+	dataset, err := c.Mgmt(ctx, db, kql.New(".show table ").AddTable(table).AddLiteral(" schema as json"), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := query.ToStructs[tableSchemaRow](dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected a single row for the schema of table %s, got %d", table, len(rows))
+	}
+
+	return parseTableSchema(rows[0])
+}
+
+func parseTableSchema(row tableSchemaRow) (*TableSchema, error) {
+	var schema orderedColumns
+	if err := json.Unmarshal([]byte(row.Schema), &schema); err != nil {
+		return nil, errors.E(errors.OpMgmt, errors.KFailedToParse, fmt.Errorf("could not parse the schema of table %s: %w", row.TableName, err))
+	}
+
+	columns := make([]query.Column, len(schema.OrderedColumns))
+	for i, col := range schema.OrderedColumns {
+		kustoType := types.NormalizeColumn(col.CslType)
+		if kustoType == "" {
+			return nil, errors.ES(errors.OpMgmt, errors.KFailedToParse, "column %s of table %s is of type %q, which is not valid",
+				col.Name, row.TableName, col.CslType)
+		}
+		columns[i] = query.NewColumnWithMetadata(i, col.Name, kustoType, col.Type, col.DocString)
+	}
+
+	return &TableSchema{
+		Database:  row.DatabaseName,
+		Name:      row.TableName,
+		Folder:    row.Folder,
+		DocString: row.DocString,
+		Columns:   columns,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package azkustodata
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaConn is a fake conn that returns a schema for `.show table schema as json`.
+type schemaConn struct {
+	schema   string
+	commands []string
+}
+
+func (c *schemaConn) rawQuery(_ context.Context, _ callType, _ string, query Statement, _ *queryOptions) (io.ReadCloser, error) {
+	c.commands = append(c.commands, query.String())
+
+	schema, _ := json.Marshal(c.schema)
+	return io.NopCloser(strings.NewReader(`{"Tables":[{"TableName":"Table_0","Columns":[` +
+		`{"ColumnName":"TableName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"Schema","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"DatabaseName","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"Folder","DataType":"String","ColumnType":"string"},` +
+		`{"ColumnName":"DocString","DataType":"String","ColumnType":"string"}],` +
+		`"Rows":[["Events",` + string(schema) + `,"db","Telemetry","The events"]]}]}`)), nil
+}
+
+func (c *schemaConn) Close() error {
+	return nil
+}
+
+func TestShowTableSchema(t *testing.T) {
+	t.Parallel()
+
+	conn := &schemaConn{schema: `{"Name":"Events","OrderedColumns":[` +
+		`{"Name":"Timestamp","Type":"System.DateTime","CslType":"datetime","DocString":"When it happened"},` +
+		`{"Name":"Count","Type":"System.Int64","CslType":"long"}]}`}
+	client := &Client{conn: conn}
+
+	schema, err := client.ShowTableSchema(context.Background(), "db", "Events")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".show table Events schema as json"}, conn.commands)
+
+	assert.Equal(t, "db", schema.Database)
+	assert.Equal(t, "Events", schema.Name)
+	assert.Equal(t, "Telemetry", schema.Folder)
+	assert.Equal(t, "The events", schema.DocString)
+	require.Len(t, schema.Columns, 2)
+
+	timestamp := schema.ColumnByName("Timestamp")
+	require.NotNil(t, timestamp)
+	assert.Equal(t, 0, timestamp.Index())
+	assert.Equal(t, types.DateTime, timestamp.Type())
+	assert.Equal(t, "System.DateTime", timestamp.(query.ColumnMetadata).DataType())
+	assert.Equal(t, "When it happened", timestamp.(query.ColumnMetadata).DocString())
+
+	count := schema.Columns[1]
+	assert.Equal(t, "Count", count.Name())
+	assert.Equal(t, types.Long, count.Type())
+	assert.Empty(t, count.(query.ColumnMetadata).DocString())
+	assert.Nil(t, schema.ColumnByName("Missing"))
+
+	_, err = client.ShowTableSchema(context.Background(), "db", "")
+	assert.Error(t, err)
+
+	client = &Client{conn: &schemaConn{schema: `{"OrderedColumns":[{"Name":"A","CslType":"nope"}]}`}}
+	_, err = client.ShowTableSchema(context.Background(), "db", "Events")
+	assert.Error(t, err)
+}
@@ -1,3 +1,5 @@
+// Package testshared holds the scaffolding of the end-to-end tests of this repository, and is not supported for other
+// projects. Use the kustotesting package in integration tests instead.
 package testshared
 
 import (
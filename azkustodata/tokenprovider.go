@@ -24,7 +24,17 @@ type TokenProvider struct {
 
 // tokenProvider need to be received as reference, to reflect updations to the structs
 func (tkp *TokenProvider) AcquireToken(ctx context.Context) (string, string, error) {
+	return tkp.AcquireTokenWithClaims(ctx, "")
+}
+
+// AcquireTokenWithClaims acquires a token that satisfies the claims of a claims challenge returned by the service,
+// such as when Continuous Access Evaluation revokes a token. The claims must be decoded.
+// Custom tokens can't satisfy claims, so an error is returned for them unless claims is empty.
+func (tkp *TokenProvider) AcquireTokenWithClaims(ctx context.Context, claims string) (string, string, error) {
 	if !isEmpty(tkp.customToken) {
+		if claims != "" {
+			return "", "", fmt.Errorf("Error: a custom token cannot satisfy a claims challenge, a new token is required")
+		}
 		return tkp.customToken, tkp.tokenScheme, nil
 	}
 
@@ -36,7 +46,8 @@ func (tkp *TokenProvider) AcquireToken(ctx context.Context) (string, string, err
 	}
 
 	if tkp.tokenCred != nil {
-		token, err := tkp.tokenCred.GetToken(ctx, policy.TokenRequestOptions{Scopes: tkp.scopes})
+		// The client handles claims challenges, so it can use tokens that support Continuous Access Evaluation.
+		token, err := tkp.tokenCred.GetToken(ctx, policy.TokenRequestOptions{Scopes: tkp.scopes, Claims: claims, EnableCAE: true})
 		if err != nil {
 			return "", "", err
 		}
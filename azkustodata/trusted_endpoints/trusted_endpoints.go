@@ -7,6 +7,7 @@ import (
 	"math"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/samber/lo"
@@ -57,11 +58,16 @@ func createInstance() *TrustedEndpoints {
 
 // SetOverridePolicy Set a policy to override all other trusted rules
 func (trusted *TrustedEndpoints) SetOverridePolicy(matcher func(string) bool) {
+	trusted.mu.Lock()
+	defer trusted.mu.Unlock()
 	trusted.overrideMatcher = matcher
 }
 
+// TrustedEndpoints validates that endpoints are trusted Kusto endpoints. It is safe for concurrent use.
 type TrustedEndpoints struct {
-	matchers          map[string]*FastSuffixMatcher
+	matchers map[string]*FastSuffixMatcher
+
+	mu                sync.RWMutex
 	additionalMatcher *FastSuffixMatcher
 	overrideMatcher   func(string) bool
 }
@@ -71,6 +77,12 @@ type MatchRule struct {
 	exact  bool
 }
 
+// NewMatchRule creates a rule that trusts a hostname if exact is true, or all the hostnames that end with suffix
+// otherwise.
+func NewMatchRule(suffix string, exact bool) MatchRule {
+	return MatchRule{suffix: strings.ToLower(suffix), exact: exact}
+}
+
 type FastSuffixMatcher struct {
 	suffixLength int
 	rules        map[string][]MatchRule
@@ -162,6 +174,9 @@ func createFastSuffixMatcherFromExisting(rules []MatchRule, existing *FastSuffix
 
 // AddTrustedHosts Add or set a list of trusted endpoints rules
 func (trusted *TrustedEndpoints) AddTrustedHosts(rules []MatchRule, replace bool) error {
+	trusted.mu.Lock()
+	defer trusted.mu.Unlock()
+
 	if rules == nil || len(rules) == 0 {
 		if replace {
 			trusted.additionalMatcher = nil
@@ -174,8 +189,11 @@ func (trusted *TrustedEndpoints) AddTrustedHosts(rules []MatchRule, replace bool
 	}
 
 	matcher, err := createFastSuffixMatcherFromExisting(rules, trusted.additionalMatcher)
+	if err != nil {
+		return err
+	}
 	trusted.additionalMatcher = matcher
-	return err
+	return nil
 }
 
 // ValidateTrustedEndpoint Validates the endpoint uri trusted
@@ -185,7 +203,7 @@ func (trusted *TrustedEndpoints) ValidateTrustedEndpoint(endpoint string, loginE
 		return err
 	}
 
-	host := u.Host
+	host := u.Hostname()
 	if host == "" {
 		host = endpoint
 	}
@@ -217,6 +235,9 @@ func (trusted *TrustedEndpoints) validateHostnameIsTrusted(host string, loginEnd
 		return nil
 	}
 
+	trusted.mu.RLock()
+	defer trusted.mu.RUnlock()
+
 	// Either check the override matcher OR the matcher:
 	override := trusted.overrideMatcher
 	if override != nil && override(host) {
@@ -236,6 +257,7 @@ func (trusted *TrustedEndpoints) validateHostnameIsTrusted(host string, loginEnd
 	return errors.ES(
 		errors.OpUnknown,
 		errors.KClientArgs,
-		fmt.Sprintf("Can't communicate with '%s' as this hostname is currently not trusted; please see https://aka.ms/kustotrustedendpoints.", host),
+		fmt.Sprintf("Can't communicate with '%s' as this hostname is currently not trusted; please see https://aka.ms/kustotrustedendpoints. "+
+			"If the host is a Kusto endpoint, trust it with azkustodata.AddTrustedHosts().", host),
 	).SetNoRetry()
 }
@@ -0,0 +1,35 @@
+package azkustodata
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	truestedEndpoints "github.com/Azure/azure-kusto-go/azkustodata/trusted_endpoints"
+)
+
+// AddTrustedHosts adds hosts to the allow-list of trusted endpoints.
+// Before sending a token to an endpoint, clients make sure that it is a well-known Kusto endpoint of its cloud, to
+// prevent leaking tokens to mistyped or malicious hosts. Endpoints of clusters behind proxies or custom domains must be
+// added to the allow-list, or they are rejected with a KClientArgs error.
+//
+// A host that starts with a dot, such as ".kusto.contoso.com", trusts all the hosts that end with it. Other hosts are
+// trusted as exact hostnames, such as "kusto.contoso.com". The loopback addresses are always trusted.
+// The allow-list is shared by all the clients of the process, and can be called concurrently with running clients.
+func AddTrustedHosts(hosts ...string) error {
+	rules := make([]truestedEndpoints.MatchRule, 0, len(hosts))
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" || host == "." || strings.ContainsAny(host, "/:") {
+			return errors.ES(errors.OpServConn, errors.KClientArgs, "invalid trusted host %q, expected a hostname or a suffix that starts with a dot", host).SetNoRetry()
+		}
+		rules = append(rules, truestedEndpoints.NewMatchRule(host, !strings.HasPrefix(host, ".")))
+	}
+
+	return truestedEndpoints.Instance.AddTrustedHosts(rules, false)
+}
+
+// ResetTrustedHosts removes all the hosts added with AddTrustedHosts from the allow-list of trusted endpoints.
+// Endpoints that were already validated by a client stay trusted by that client.
+func ResetTrustedHosts() {
+	_ = truestedEndpoints.Instance.AddTrustedHosts(nil, true)
+}
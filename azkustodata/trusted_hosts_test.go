@@ -0,0 +1,98 @@
+package azkustodata
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedHosts(t *testing.T) {
+	// Not parallel, as the allow-list is shared by the whole process.
+	defer ResetTrustedHosts()
+
+	var queries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataPath {
+			_, _ = w.Write([]byte(`{"AzureAD":{"LoginEndpoint":"https://login.microsoftonline.com"}}`))
+			return
+		}
+		queries.Add(1)
+		_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1]]}]}`))
+	}))
+	defer server.Close()
+
+	// Every host is resolved to the test server.
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	mgmt := func(endpoint string) error {
+		conn, err := NewConn(endpoint, Authorization{TokenProvider: &TokenProvider{}}, httpClient, NewClientDetails("", ""))
+		require.NoError(t, err)
+		client := &Client{conn: conn}
+		_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+		return err
+	}
+
+	// Untrusted hosts are rejected before sending the request.
+	err := mgmt("http://kusto.trusted-hosts-test.net")
+	require.Error(t, err)
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KClientArgs, e.Kind)
+	assert.Contains(t, err.Error(), "AddTrustedHosts")
+	assert.Equal(t, int32(0), queries.Load())
+
+	require.NoError(t, AddTrustedHosts("exact.trusted-hosts-test.net", ".suffix-trusted-hosts-test.net"))
+	assert.NoError(t, mgmt("http://exact.trusted-hosts-test.net"))
+	assert.NoError(t, mgmt("http://cluster.suffix-trusted-hosts-test.net:8080"))
+	assert.Error(t, mgmt("http://other.trusted-hosts-test.net"))
+	assert.Equal(t, int32(2), queries.Load())
+
+	ResetTrustedHosts()
+	assert.Error(t, mgmt("http://cluster2.suffix-trusted-hosts-test.net"))
+
+	for _, host := range []string{"", ".", "https://kusto.contoso.com", "kusto.contoso.com:443"} {
+		assert.Error(t, AddTrustedHosts(host), host)
+	}
+}
+
+func TestTrustedHostsWithoutMetadata(t *testing.T) {
+	t.Parallel()
+
+	var queries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataPath {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		queries.Add(1)
+		_, _ = w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"A","DataType":"Int32","ColumnType":"int"}],"Rows":[[1]]}]}`))
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	conn, err := NewConn("http://kusto.no-metadata-test.net", Authorization{TokenProvider: &TokenProvider{}}, httpClient, NewClientDetails("", ""))
+	require.NoError(t, err)
+	client := &Client{conn: conn}
+
+	// The endpoint can't be validated without its cloud metadata, which doesn't fail the request.
+	_, err = client.Mgmt(context.Background(), "db", kql.New(".show tables"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), queries.Load())
+	assert.False(t, conn.endpointValidated.Load())
+}
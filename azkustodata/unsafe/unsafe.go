@@ -1,7 +1,7 @@
 // Package unsafe provides methods and types that loosen the native protections of the Kusto package.
 package unsafe
 
-// Stmt can be used in optional arguments to azkustodata.NewStmt() to allow the use of unsafe
+// Stmt can be used in optional arguments to compat.NewStmt() to allow the use of unsafe
 // methods on that object.
 type Stmt struct {
 	// Adds indicates if a Stmt is allowed to use Unsafe.Add().
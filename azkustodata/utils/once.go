@@ -79,9 +79,11 @@ func (o *once[Out]) Do(f func() (Out, error)) (Out, error) {
 }
 
 func (o *once[Out]) Done() bool {
-	return o.done != 0
+	return atomic.LoadUint32(&o.done) != 0
 }
 
 func (o *once[Out]) Result() (bool, Out, error) {
-	return o.Done(), o.result, o.err
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.done != 0, o.result, o.err
 }
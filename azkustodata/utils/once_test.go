@@ -2,6 +2,8 @@ package utils
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -108,3 +110,32 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func TestOnceConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	once := NewOnceWithInit[int](func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 1, nil
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := once.DoWithInit()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, result)
+			assert.True(t, once.Done())
+			isDone, result, err := once.Result()
+			assert.True(t, isDone)
+			assert.Equal(t, 1, result)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
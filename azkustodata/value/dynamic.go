@@ -73,7 +73,9 @@ func (d *Dynamic) Convert(v reflect.Value) error {
 		t = t.Elem()
 	}
 
+	// Like the other types, null values reset the receiver to its zero value, which is nil for pointers.
 	if d.Value == nil {
+		v.Set(reflect.Zero(v.Type()))
 		return nil
 	}
 
@@ -0,0 +1,172 @@
+package value
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DynamicElement are the types of the elements of the slices that DynamicAs converts dynamic arrays to.
+type DynamicElement interface {
+	string | bool | int32 | int64 | float64 | decimal.Decimal | time.Time | time.Duration | uuid.UUID
+}
+
+// DynamicAs converts a dynamic array to a slice of T, such as the result of make_list() or make_set().
+// The elements must all be of the JSON type that holds T: strings for string, time.Time (ISO 8601), time.Duration
+// (Kusto timespans) and uuid.UUID, numbers for int32, int64 and float64, numbers or strings for decimal.Decimal, and
+// booleans for bool. Integers must fit in T. A null dynamic returns a nil slice, and a dynamic that isn't an array, or
+// has an element of another type or a null element, returns an error naming the element.
+func DynamicAs[T DynamicElement](d *Dynamic) ([]T, error) {
+	if d == nil || d.Value == nil {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(d.Value))
+	dec.UseNumber()
+	var elements []interface{}
+	if err := dec.Decode(&elements); err != nil {
+		return nil, errors.ES(errors.OpTableAccess, errors.KWrongColumnType, "dynamic value is not an array: %s", err)
+	}
+	if elements == nil {
+		return nil, nil
+	}
+
+	result := make([]T, len(elements))
+	for i, e := range elements {
+		if err := convertElement(e, &result[i]); err != nil {
+			return nil, errors.ES(errors.OpTableAccess, errors.KWrongColumnType, "element %d of the dynamic array: %s", i, err)
+		}
+	}
+	return result, nil
+}
+
+// AsStringSlice converts a dynamic array of strings to a slice, see DynamicAs.
+func (d *Dynamic) AsStringSlice() ([]string, error) {
+	return DynamicAs[string](d)
+}
+
+// AsInt64Slice converts a dynamic array of integers to a slice, see DynamicAs.
+func (d *Dynamic) AsInt64Slice() ([]int64, error) {
+	return DynamicAs[int64](d)
+}
+
+// AsFloat64Slice converts a dynamic array of numbers to a slice, see DynamicAs.
+func (d *Dynamic) AsFloat64Slice() ([]float64, error) {
+	return DynamicAs[float64](d)
+}
+
+// AsBoolSlice converts a dynamic array of booleans to a slice, see DynamicAs.
+func (d *Dynamic) AsBoolSlice() ([]bool, error) {
+	return DynamicAs[bool](d)
+}
+
+// AsTimeSlice converts a dynamic array of datetimes to a slice, see DynamicAs.
+func (d *Dynamic) AsTimeSlice() ([]time.Time, error) {
+	return DynamicAs[time.Time](d)
+}
+
+// convertElement converts a JSON value, as decoded with UseNumber, to the type of target.
+// The values are parsed like the cells of the Kusto type of the element, once their JSON type is checked.
+func convertElement[T DynamicElement](e interface{}, target *T) error {
+	if e == nil {
+		return fmt.Errorf("null, expected %T", *target)
+	}
+
+	switch t := any(target).(type) {
+	case *string:
+		s, ok := e.(string)
+		if !ok {
+			return elementTypeError(e, "a string")
+		}
+		*t = s
+	case *bool:
+		b, ok := e.(bool)
+		if !ok {
+			return elementTypeError(e, "a boolean")
+		}
+		*t = b
+	case *int32:
+		v := Int{}
+		if err := unmarshalElement(&v, e, "an integer"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *int64:
+		v := Long{}
+		if err := unmarshalElement(&v, e, "an integer"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *float64:
+		v := Real{}
+		if err := unmarshalElement(&v, e, "a number"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *decimal.Decimal:
+		// Decimals are strings in dynamic values, unless they were converted to reals.
+		if n, ok := e.(json.Number); ok {
+			e = n.String()
+		}
+		v := Decimal{}
+		if err := unmarshalElement(&v, e, "a decimal string"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *time.Time:
+		v := DateTime{}
+		if err := unmarshalElement(&v, e, "a datetime string"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *time.Duration:
+		v := Timespan{}
+		if err := unmarshalElement(&v, e, "a timespan string"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	case *uuid.UUID:
+		v := GUID{}
+		if err := unmarshalElement(&v, e, "a guid string"); err != nil {
+			return err
+		}
+		*t = *v.Ptr()
+	}
+	return nil
+}
+
+// unmarshalElement unmarshals e into v, after checking that e is of the JSON type that v unmarshals, a number for the
+// numeric types and a string for the others.
+func unmarshalElement(v Kusto, e interface{}, expected string) error {
+	switch v.(type) {
+	case *Int, *Long, *Real:
+		if _, ok := e.(json.Number); !ok {
+			return elementTypeError(e, expected)
+		}
+	default:
+		if _, ok := e.(string); !ok {
+			return elementTypeError(e, expected)
+		}
+	}
+	return v.Unmarshal(e)
+}
+
+func elementTypeError(e interface{}, expected string) error {
+	kind := "an object"
+	switch e.(type) {
+	case string:
+		kind = "a string"
+	case json.Number:
+		kind = "a number"
+	case bool:
+		kind = "a boolean"
+	case []interface{}:
+		kind = "an array"
+	}
+	return fmt.Errorf("%s, expected %s", kind, expected)
+}
@@ -0,0 +1,133 @@
+package value_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicSlices(t *testing.T) {
+	t.Parallel()
+
+	strs, err := value.NewDynamic([]byte(`["a","b"]`)).AsStringSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, strs)
+
+	longs, err := value.NewDynamic([]byte(`[1, 9223372036854775807, -3]`)).AsInt64Slice()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 9223372036854775807, -3}, longs)
+
+	reals, err := value.NewDynamic([]byte(`[1, 2.5]`)).AsFloat64Slice()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 2.5}, reals)
+
+	bools, err := value.NewDynamic([]byte(`[true, false]`)).AsBoolSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, bools)
+
+	times, err := value.NewDynamic([]byte(`["2020-03-04T14:05:01.3109965Z"]`)).AsTimeSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(2020, 3, 4, 14, 5, 1, 310996500, time.UTC)}, times)
+
+	ints, err := value.DynamicAs[int32](value.NewDynamic([]byte(`[1, 2]`)))
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2}, ints)
+
+	spans, err := value.DynamicAs[time.Duration](value.NewDynamic([]byte(`["01:00:00", "1.00:00:30"]`)))
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Hour, 24*time.Hour + 30*time.Second}, spans)
+
+	ids, err := value.DynamicAs[uuid.UUID](value.NewDynamic([]byte(`["123e27de-1e4e-49d9-b579-fe0b331d3642"]`)))
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{uuid.MustParse("123e27de-1e4e-49d9-b579-fe0b331d3642")}, ids)
+
+	decimals, err := value.DynamicAs[decimal.Decimal](value.NewDynamic([]byte(`["1.10", 2.5]`)))
+	require.NoError(t, err)
+	require.Len(t, decimals, 2)
+	assert.True(t, decimal.RequireFromString("1.1").Equal(decimals[0]))
+	assert.True(t, decimal.RequireFromString("2.5").Equal(decimals[1]))
+
+	// Null dynamics and arrays are nil, empty arrays are empty.
+	strs, err = value.NewNullDynamic().AsStringSlice()
+	require.NoError(t, err)
+	assert.Nil(t, strs)
+	strs, err = value.NewDynamic([]byte(`null`)).AsStringSlice()
+	require.NoError(t, err)
+	assert.Nil(t, strs)
+	strs, err = value.NewDynamic([]byte(`[]`)).AsStringSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, strs)
+}
+
+func TestDynamicSlicesErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc  string
+		value string
+		call  func(d *value.Dynamic) error
+		want  string
+	}{
+		{
+			desc:  "not an array",
+			value: `{"a":1}`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsStringSlice(); return err },
+			want:  "not an array",
+		},
+		{
+			desc:  "mixed types",
+			value: `["a", 1]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsStringSlice(); return err },
+			want:  "element 1 of the dynamic array: a number, expected a string",
+		},
+		{
+			desc:  "null element",
+			value: `[1, null]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsInt64Slice(); return err },
+			want:  "element 1 of the dynamic array: null",
+		},
+		{
+			desc:  "number as string",
+			value: `["1"]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsInt64Slice(); return err },
+			want:  "a string, expected an integer",
+		},
+		{
+			desc:  "fraction",
+			value: `[1.5]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsInt64Slice(); return err },
+			want:  "element 0",
+		},
+		{
+			desc:  "out of range",
+			value: `[2147483648]`,
+			call:  func(d *value.Dynamic) error { _, err := value.DynamicAs[int32](d); return err },
+			want:  "element 0",
+		},
+		{
+			desc:  "bad datetime",
+			value: `["yesterday"]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsTimeSlice(); return err },
+			want:  "element 0",
+		},
+		{
+			desc:  "number as bool",
+			value: `[1]`,
+			call:  func(d *value.Dynamic) error { _, err := d.AsBoolSlice(); return err },
+			want:  "a number, expected a boolean",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.ErrorContains(t, test.call(value.NewDynamic([]byte(test.value))), test.want)
+		})
+	}
+}
@@ -68,8 +68,8 @@ func (in *Int) Unmarshal(i interface{}) error {
 		return convertError(in, i)
 	}
 
-	if myInt > math.MaxInt32 {
-		return parseError(in, i, fmt.Errorf("value was too large for int32"))
+	if myInt > math.MaxInt32 || myInt < math.MinInt32 {
+		return parseError(in, i, fmt.Errorf("value was out of range for int32"))
 	}
 	val := int32(myInt)
 	in.value = &val
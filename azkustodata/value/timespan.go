@@ -3,6 +3,7 @@ package value
 import (
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -74,24 +75,19 @@ func (t *Timespan) Marshal() string {
 	val = val - (seconds * time.Second)
 	sb.WriteString(fmt.Sprintf("%02d:%02d:%02d", int(hours), int(minutes), int(seconds)))
 
-	// Add our sub-second string representation that is proceeded with a ".".
-	milliseconds := val / time.Millisecond
-	val = val - (milliseconds * time.Millisecond)
+	// Add our sub-second string representation that is proceeded with a ".", in ticks, without the trailing 0's.
 	ticks := val / tick
-	if milliseconds > 0 || ticks > 0 {
-		sb.WriteString(fmt.Sprintf(".%03d%d", milliseconds, ticks))
+	if ticks > 0 {
+		sb.WriteString(strings.TrimRight(fmt.Sprintf(".%07d", int64(ticks)), "0"))
 	}
 
-	// Remove any trailing 0's.
-	str := strings.TrimRight(sb.String(), "0")
-	if strings.HasSuffix(str, ":") {
-		str = str + "00"
-	}
-
-	return str
+	return sb.String()
 }
 
 // Unmarshal unmarshals i into Timespan. i must be a string representing a Values timespan or nil.
+// Besides the [-][d.]hh:mm:ss[.fffffff] format of the service, it accepts the forms of timespan literals, such as "1.5h",
+// "-100ms", "time(1s)" or "timespan(1.02:03:04)". See
+// https://learn.microsoft.com/azure/data-explorer/kusto/query/scalar-data-types/timespan
 func (t *Timespan) Unmarshal(i interface{}) error {
 	const (
 		hoursIndex   = 0
@@ -109,6 +105,14 @@ func (t *Timespan) Unmarshal(i interface{}) error {
 		return convertError(t, i)
 	}
 
+	if inner, ok := timespanLiteral(v); ok {
+		if inner == "null" {
+			t.value = nil
+			return nil
+		}
+		v = inner
+	}
+
 	negative := false
 	if len(v) > 1 {
 		if string(v[0]) == "-" {
@@ -117,6 +121,18 @@ func (t *Timespan) Unmarshal(i interface{}) error {
 		}
 	}
 
+	if !strings.Contains(v, ":") {
+		d, err := unmarshalTimespanUnits(v)
+		if err != nil {
+			return parseError(t, v, err)
+		}
+		if negative {
+			d = -d
+		}
+		t.value = &d
+		return nil
+	}
+
 	sp := strings.Split(v, ":")
 	if len(sp) != 3 {
 		return parseError(v, sp, fmt.Errorf("value to unmarshal into Timespan does not seem to fit format '00:00:00', where values are decimal(%s)", v))
@@ -153,6 +169,72 @@ func (t *Timespan) Unmarshal(i interface{}) error {
 
 var day = 24 * time.Hour
 
+// timespanUnits are the units of timespan literals.
+var timespanUnits = map[string]time.Duration{
+	"":             day,
+	"d":            day,
+	"day":          day,
+	"days":         day,
+	"h":            time.Hour,
+	"hr":           time.Hour,
+	"hrs":          time.Hour,
+	"hour":         time.Hour,
+	"hours":        time.Hour,
+	"m":            time.Minute,
+	"min":          time.Minute,
+	"minute":       time.Minute,
+	"minutes":      time.Minute,
+	"s":            time.Second,
+	"sec":          time.Second,
+	"second":       time.Second,
+	"seconds":      time.Second,
+	"ms":           time.Millisecond,
+	"milli":        time.Millisecond,
+	"millis":       time.Millisecond,
+	"millisecond":  time.Millisecond,
+	"milliseconds": time.Millisecond,
+	"microsecond":  time.Microsecond,
+	"microseconds": time.Microsecond,
+	"tick":         tick,
+	"ticks":        tick,
+}
+
+// timespanLiteral returns the value of a time(...) or timespan(...) literal.
+func timespanLiteral(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	for _, prefix := range []string{"time(", "timespan("} {
+		if strings.HasPrefix(s, prefix) {
+			return strings.TrimSpace(s[len(prefix) : len(s)-1]), true
+		}
+	}
+	return "", false
+}
+
+// unmarshalTimespanUnits parses a number followed by a unit, such as "1.5h". A number without a unit is a number of
+// days. The number is parsed exactly, and truncated to the nanosecond.
+func unmarshalTimespanUnits(s string) (time.Duration, error) {
+	end := strings.LastIndexAny(s, "0123456789.") + 1
+	unit, ok := timespanUnits[strings.ToLower(strings.TrimSpace(s[end:]))]
+	if !ok || end == 0 {
+		return 0, fmt.Errorf("timespan literal must be a number followed by a unit, such as 1.5h, was %s", s)
+	}
+
+	n, ok := new(big.Rat).SetString(s[:end])
+	if !ok || n.Sign() < 0 {
+		return 0, fmt.Errorf("timespan literal's number was incorrect, was %s", s)
+	}
+	n.Mul(n, new(big.Rat).SetInt64(int64(unit)))
+
+	d := new(big.Int).Quo(n.Num(), n.Denom())
+	if !d.IsInt64() {
+		return 0, fmt.Errorf("timespan literal is out of range, was %s", s)
+	}
+	return time.Duration(d.Int64()), nil
+}
+
 func (t *Timespan) unmarshalDaysHours(s string) (time.Duration, error) {
 	sp := strings.Split(s, ".")
 	switch len(sp) {
@@ -248,15 +330,18 @@ func (t *Timespan) Convert(v reflect.Value) error {
 	pt := v.Type()
 	switch {
 	case pt.AssignableTo(reflect.TypeOf(time.Duration(0))):
-		if t.value != nil {
-			v.Set(reflect.ValueOf(*t.value))
+		if t.value == nil {
+			v.Set(reflect.Zero(pt))
+			return nil
 		}
+		v.Set(reflect.ValueOf(*t.value))
 		return nil
 	case pt.ConvertibleTo(reflect.TypeOf(new(time.Duration))):
-		if t.value != nil {
-			pt := t.value
-			v.Set(reflect.ValueOf(pt))
+		if t.value == nil {
+			v.Set(reflect.Zero(pt))
+			return nil
 		}
+		v.Set(reflect.ValueOf(t.value))
 		return nil
 	case pt.ConvertibleTo(reflect.TypeOf(Timespan{})):
 		v.Set(reflect.ValueOf(*t))
@@ -139,6 +139,25 @@ type Kusto interface {
 	Unmarshal(interface{}) error
 }
 
+// IsNull reports whether v is a null value. Every Kusto type but string can be null: strings that were not set are
+// empty instead. Dynamic values are null when they hold no JSON at all, which is distinct from an empty JSON string,
+// array or object.
+func IsNull(v Kusto) bool {
+	if v == nil {
+		return true
+	}
+
+	switch val := v.GetValue().(type) {
+	case string:
+		return false
+	case []byte:
+		return val == nil
+	default:
+		ref := reflect.ValueOf(val)
+		return !ref.IsValid() || (ref.Kind() == reflect.Ptr && ref.IsNil())
+	}
+}
+
 func Default(t types.Column) Kusto {
 	switch t {
 	case types.Bool:
@@ -3,12 +3,15 @@ package value
 import (
 	"encoding/json"
 	"math"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBool(t *testing.T) {
@@ -238,7 +241,12 @@ func TestInt(t *testing.T) {
 		},
 		{
 			desc: "value is greater than int32",
-			i:    math.MaxInt32 + 1,
+			i:    float64(math.MaxInt32 + 1),
+			err:  true,
+		},
+		{
+			desc: "value is less than int32",
+			i:    json.Number("-2147483649"),
 			err:  true,
 		},
 		{
@@ -545,6 +553,88 @@ func TestTimespan(t *testing.T) {
 	}
 }
 
+func TestTimespanLiterals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		i    string
+		err  bool
+		null bool
+		want time.Duration
+	}{
+		{i: "1d", want: day},
+		{i: "1.5h", want: 90 * time.Minute},
+		{i: "30m", want: 30 * time.Minute},
+		{i: "10s", want: 10 * time.Second},
+		{i: "0.1s", want: 100 * time.Millisecond},
+		{i: "100ms", want: 100 * time.Millisecond},
+		{i: "10microsecond", want: 10 * time.Microsecond},
+		{i: "1tick", want: 100 * time.Nanosecond},
+		{i: "-2hours", want: -2 * time.Hour},
+		{i: "time(1s)", want: time.Second},
+		{i: "time(2)", want: 2 * day},
+		{i: "timespan(-1.5d)", want: -36 * time.Hour},
+		{i: "timespan(1.02:03:04.5678901)", want: day + 2*time.Hour + 3*time.Minute + 4*time.Second + 5678901*100*time.Nanosecond},
+		{i: "time(-00:00:00.0000001)", want: -100 * time.Nanosecond},
+		{i: "time(null)", null: true},
+		{i: "1x", err: true},
+		{i: "h", err: true},
+		{i: "time()", err: true},
+		{i: "10000000000d", err: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.i, func(t *testing.T) {
+			t.Parallel()
+			got, err := TimespanFromString(test.i)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if test.null {
+				assert.Nil(t, got.Ptr())
+				return
+			}
+			assert.Equal(t, test.want, *got.Ptr())
+		})
+	}
+}
+
+func TestTimespanMarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "00:00:00"},
+		{d: 10 * time.Second, want: "00:00:10"},
+		{d: 20 * time.Minute, want: "00:20:00"},
+		{d: 2*day + 10*time.Hour, want: "2.10:00:00"},
+		{d: 100 * time.Millisecond, want: "00:00:00.1"},
+		{d: 40 * time.Microsecond, want: "00:00:00.00004"},
+		{d: 1*time.Millisecond + 100*time.Nanosecond, want: "00:00:00.0010001"},
+		{d: time.Second + 150*time.Nanosecond, want: "00:00:01.0000001"},
+		{d: -(day + 2*time.Hour + 3*time.Minute + 4*time.Second + 5678901*100*time.Nanosecond), want: "-1.02:03:04.5678901"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.want, func(t *testing.T) {
+			t.Parallel()
+			got := NewTimespan(test.d).Marshal()
+			assert.Equal(t, test.want, got)
+
+			back, err := TimespanFromString(got)
+			require.NoError(t, err)
+			assert.Equal(t, test.d.Truncate(100*time.Nanosecond), *back.Ptr())
+		})
+	}
+}
+
 func removeLeadingZeros(s string) string {
 	if len(s) == 0 {
 		return s
@@ -601,3 +691,73 @@ func timeMustParse(layout string, p string) time.Time {
 	}
 	return t
 }
+
+func TestNullSemantics(t *testing.T) {
+	t.Parallel()
+
+	// For every type, null values convert to the zero value of plain receivers and to nil pointers, while the zero
+	// values convert to non-nil pointers.
+	tests := []struct {
+		desc      string
+		null      Kusto
+		zero      Kusto
+		other     Kusto
+		plain     reflect.Type
+		kusto     reflect.Type
+		zeroPlain interface{}
+	}{
+		{desc: "bool", null: NewNullBool(), zero: NewBool(false), other: NewBool(true), plain: reflect.TypeOf(false), kusto: reflect.TypeOf(Bool{}), zeroPlain: false},
+		{desc: "int", null: NewNullInt(), zero: NewInt(0), other: NewInt(1), plain: reflect.TypeOf(int32(0)), kusto: reflect.TypeOf(Int{}), zeroPlain: int32(0)},
+		{desc: "long", null: NewNullLong(), zero: NewLong(0), other: NewLong(1), plain: reflect.TypeOf(int64(0)), kusto: reflect.TypeOf(Long{}), zeroPlain: int64(0)},
+		{desc: "real", null: NewNullReal(), zero: NewReal(0), other: NewReal(1), plain: reflect.TypeOf(float64(0)), kusto: reflect.TypeOf(Real{}), zeroPlain: float64(0)},
+		{desc: "decimal", null: NewNullDecimal(), zero: NewDecimal(decimal.Zero), other: NewDecimal(decimal.NewFromInt(1)), plain: reflect.TypeOf(decimal.Decimal{}), kusto: reflect.TypeOf(Decimal{}), zeroPlain: decimal.Zero},
+		{desc: "datetime", null: NewNullDateTime(), zero: NewDateTime(time.Time{}), other: NewDateTime(time.Unix(1, 0)), plain: reflect.TypeOf(time.Time{}), kusto: reflect.TypeOf(DateTime{}), zeroPlain: time.Time{}},
+		{desc: "timespan", null: NewNullTimespan(), zero: NewTimespan(0), other: NewTimespan(time.Second), plain: reflect.TypeOf(time.Duration(0)), kusto: reflect.TypeOf(Timespan{}), zeroPlain: time.Duration(0)},
+		{desc: "guid", null: NewNullGUID(), zero: NewGUID(uuid.Nil), other: NewGUID(uuid.New()), plain: reflect.TypeOf(uuid.UUID{}), kusto: reflect.TypeOf(GUID{}), zeroPlain: uuid.Nil},
+		{desc: "dynamic", null: NewNullDynamic(), zero: NewDynamic([]byte{}), other: NewDynamic([]byte(`{"a":1}`)), plain: reflect.TypeOf([]byte{}), kusto: reflect.TypeOf(Dynamic{}), zeroPlain: []byte{}},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, IsNull(test.null))
+			assert.False(t, IsNull(test.zero))
+			assert.False(t, IsNull(test.other))
+
+			// Plain receivers get the zero value for nulls, even when they were set before.
+			plain := reflect.New(test.plain).Elem()
+			assert.NoError(t, test.other.Convert(plain))
+			assert.NoError(t, test.null.Convert(plain))
+			assert.True(t, plain.IsZero())
+			assert.NoError(t, test.zero.Convert(plain))
+			assert.Equal(t, test.zeroPlain, plain.Interface())
+
+			// Pointer receivers are nil for nulls only.
+			ptr := reflect.New(reflect.PointerTo(test.plain)).Elem()
+			assert.NoError(t, test.other.Convert(ptr))
+			assert.NoError(t, test.null.Convert(ptr))
+			assert.True(t, ptr.IsNil())
+			assert.NoError(t, test.zero.Convert(ptr))
+			if assert.False(t, ptr.IsNil()) {
+				assert.Equal(t, test.zeroPlain, ptr.Elem().Interface())
+			}
+
+			// Value receivers keep the null.
+			kusto := reflect.New(test.kusto)
+			assert.NoError(t, test.other.Convert(kusto.Elem()))
+			assert.NoError(t, test.null.Convert(kusto.Elem()))
+			assert.True(t, IsNull(kusto.Interface().(Kusto)))
+			assert.NoError(t, test.zero.Convert(kusto.Elem()))
+			assert.False(t, IsNull(kusto.Interface().(Kusto)))
+		})
+	}
+
+	// Strings are never null, the strings that were not set are empty.
+	var s String
+	assert.NoError(t, s.Unmarshal(nil))
+	assert.False(t, IsNull(&s))
+	assert.Equal(t, "", s.Value)
+	assert.True(t, IsNull(nil))
+}
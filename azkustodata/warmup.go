@@ -0,0 +1,150 @@
+package azkustodata
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// transportOptions tune the transport of the default http client of a Client.
+type transportOptions struct {
+	// http2 forces HTTP/2 on or off, or keeps the default of net/http if nil.
+	http2 *bool
+	// maxIdleConnsPerHost and idleConnTimeout override the defaults of net/http if not 0.
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	// clientCertificates are presented for mutual TLS, see ConnectionStringBuilder.WithTLSClientCertificate.
+	clientCertificates []tls.Certificate
+	// proxies route the requests to some hosts through proxies, see ConnectionStringBuilder.WithProxy.
+	proxies []proxyRule
+}
+
+// roundTripper returns the transport of the default http client, or nil to use http.DefaultTransport.
+func (o transportOptions) roundTripper() http.RoundTripper {
+	if o.http2 == nil && o.maxIdleConnsPerHost == 0 && o.idleConnTimeout == 0 && len(o.clientCertificates) == 0 && len(o.proxies) == 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.http2 != nil {
+		transport.ForceAttemptHTTP2 = *o.http2
+		if !*o.http2 {
+			// A non-nil empty map disables HTTP/2.
+			transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+	}
+	if o.maxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+		if transport.MaxIdleConns != 0 && transport.MaxIdleConns < o.maxIdleConnsPerHost {
+			transport.MaxIdleConns = o.maxIdleConnsPerHost
+		}
+	}
+	if o.idleConnTimeout != 0 {
+		transport.IdleConnTimeout = o.idleConnTimeout
+	}
+	if len(o.clientCertificates) > 0 {
+		transport.TLSClientConfig = &tls.Config{Certificates: o.clientCertificates}
+	}
+	if len(o.proxies) > 0 {
+		transport.Proxy = proxyFunc(o.proxies)
+	}
+	return transport
+}
+
+// WithHTTP2 enables or disables HTTP/2. HTTP/2 is used by default when the cluster supports it, some proxies require
+// disabling it to use HTTP/1.1 instead.
+// It only applies to the default http client, and is ignored with WithHttpClient.
+func WithHTTP2(enabled bool) Option {
+	return func(c *Client) {
+		c.transport.http2 = &enabled
+	}
+}
+
+// WithIdleConnections sets how many idle connections to the cluster are kept open for reuse, and for how long, in place
+// of the defaults of net/http (2 connections, for 90 seconds). Clients that run many concurrent calls over HTTP/1.1
+// should keep more idle connections, so that the connections are reused instead of reopened.
+// It only applies to the default http client, and is ignored with WithHttpClient.
+func WithIdleConnections(maxPerHost int, timeout time.Duration) Option {
+	return func(c *Client) {
+		c.transport.maxIdleConnsPerHost = maxPerHost
+		c.transport.idleConnTimeout = timeout
+	}
+}
+
+// warmer is implemented by the connections that can be warmed up.
+type warmer interface {
+	warmup(ctx context.Context, n int) error
+}
+
+// Warmup prepares the client for its first calls, so that they don't pay for the setup of the client: it fetches the
+// cloud metadata of the cluster, acquires a token, and opens up to n connections to the cluster.
+// The connections are only kept open within the idle limits of the http client, see WithIdleConnections. With HTTP/2,
+// concurrent calls share a connection, so fewer connections may be opened. With an n of 0, no connection is opened,
+// and a negative n is an error.
+func (c *Client) Warmup(ctx context.Context, n int) error {
+	if n < 0 {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "Warmup() requires a number of connections of 0 or more, got %d", n).SetNoRetry()
+	}
+	if w, ok := c.conn.(warmer); ok {
+		return w.warmup(ctx, n)
+	}
+	return nil
+}
+
+func (c *Conn) warmup(ctx context.Context, n int) error {
+	op := errors.OpServConn
+	if err := c.validateEndpoint(); err != nil {
+		if e, ok := errors.GetKustoError(err); ok && e.Kind == errors.KClientArgs {
+			return e
+		}
+		return errors.E(op, errors.KInternal, fmt.Errorf("could not validate endpoint: %w", err))
+	}
+
+	if c.auth.TokenProvider != nil && c.auth.TokenProvider.AuthorizationRequired() {
+		c.auth.TokenProvider.SetHttp(c.client)
+		if _, _, err := c.auth.TokenProvider.AcquireToken(ctx); err != nil {
+			return errors.ES(op, errors.KInternal, "Error while getting token : %s", err)
+		}
+	}
+
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return errors.ES(op, errors.KClientArgs, "could not parse the endpoint(%s): %s", c.endpoint, err).SetNoRetry()
+	}
+	// The metadata endpoint doesn't require authentication, and is cheap to call.
+	u.Path = metadataPath
+	u.RawQuery = ""
+
+	// Concurrent requests open a connection each, unless one is idle.
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			resp, err := c.client.Do(req)
+			if err != nil {
+				errs[i] = errors.E(op, errors.KHTTPError, fmt.Errorf("could not open a connection: %w", err))
+				return
+			}
+			// The body must be read for the connection to be reused.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	return errors.CombineErrors(errs...)
+}
@@ -0,0 +1,177 @@
+package azkustodata
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+
+	const n = 4
+
+	mu := sync.Mutex{}
+	remotes := map[string]bool{}
+	arrived := make(chan struct{}, n)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, metadataPath, r.URL.Path)
+		mu.Lock()
+		remotes[r.RemoteAddr] = true
+		mu.Unlock()
+
+		// Holding the requests until they all arrived makes them use a connection each.
+		arrived <- struct{}{}
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	go func() {
+		for i := 0; i < n; i++ {
+			<-arrived
+		}
+		close(release)
+	}()
+
+	httpClient := &http.Client{Transport: transportOptions{maxIdleConnsPerHost: n}.roundTripper()}
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, httpClient, NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	client := &Client{conn: conn}
+
+	require.NoError(t, client.Warmup(context.Background(), n))
+	assert.Len(t, remotes, n)
+}
+
+func TestWarmupError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	conn, err := NewConn(server.URL, Authorization{TokenProvider: &TokenProvider{}}, server.Client(), NewClientDetails("", ""))
+	require.NoError(t, err)
+	conn.endpointValidated.Store(true)
+	client := &Client{conn: conn}
+
+	assert.Error(t, client.Warmup(context.Background(), 2))
+
+	err = client.Warmup(context.Background(), -1)
+	require.Error(t, err)
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KClientArgs, e.Kind)
+}
+
+func TestTransportOptions(t *testing.T) {
+	t.Parallel()
+
+	kcsb := NewConnectionStringBuilder("https://help.kusto.windows.net")
+
+	client, err := New(kcsb)
+	require.NoError(t, err)
+	assert.Nil(t, client.http.Transport)
+
+	client, err = New(kcsb, WithHTTP2(false), WithIdleConnections(50, time.Minute))
+	require.NoError(t, err)
+	transport, ok := client.http.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+	assert.Equal(t, 50, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+
+	client, err = New(kcsb, WithHTTP2(true))
+	require.NoError(t, err)
+	transport = client.http.Transport.(*http.Transport)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+
+	// The options don't apply to a custom http client.
+	custom := &http.Client{}
+	client, err = New(kcsb, WithHttpClient(custom), WithHTTP2(false))
+	require.NoError(t, err)
+	assert.Same(t, custom, client.http)
+	assert.Nil(t, custom.Transport)
+}
+
+// selfSignedPEM returns the PEM of a self-signed certificate and of its key.
+func selfSignedPEM(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	var peers int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peers = len(r.TLS.PeerCertificates)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certPEM, keyPEM := selfSignedPEM(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+	client, err := New(NewConnectionStringBuilder(server.URL).WithTLSClientCertificateFiles(certFile, keyFile))
+	require.NoError(t, err)
+	transport, ok := client.http.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Len(t, transport.TLSClientConfig.Certificates, 1)
+
+	// Trust the certificate of the test server, and check that the client certificate is presented.
+	transport.TLSClientConfig.RootCAs = x509.NewCertPool()
+	transport.TLSClientConfig.RootCAs.AddCert(server.Certificate())
+	resp, err := client.http.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, 1, peers)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	client, err = New(NewConnectionStringBuilder(server.URL).WithTLSClientCertificate(cert).WithTLSClientCertificateFiles(certFile, keyFile))
+	require.NoError(t, err)
+	assert.Len(t, client.http.Transport.(*http.Transport).TLSClientConfig.Certificates, 2)
+
+	_, err = New(NewConnectionStringBuilder(server.URL).WithTLSClientCertificateFiles(certFile, filepath.Join(dir, "missing.key")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package azkustoingest
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+)
+
+// tableColumnsTTL is the time the columns of a table are cached before they're fetched again.
+const tableColumnsTTL = 10 * time.Minute
+
+// AutoMapping generates an inline ingestion mapping from the schema of the destination table, so that tables with a
+// straightforward layout can be ingested without creating an ingestion mapping first:
+//   - CSV-like formats are mapped by position, the n-th field of a record being ingested into the n-th column.
+//   - JSON, Avro, Parquet and ORC formats are mapped by name, the property of a record with the name of a column being
+//     ingested into the column.
+//
+// The schema is fetched from the engine of the cluster with `.show table schema as json`, and cached by the client
+// for 10 minutes. The format is the one set with FileFormat, or else the one of the extension of the file, or else
+// the one detected from the content of readers, and defaults to CSV for blobs.
+// AutoMapping can't be used with IngestionMapping or IngestionMappingRef, nor with the W3CLogFile format.
+// Streaming ingestions of the managed client don't support inline mappings, and are ingested without it - which maps
+// the records the same way for these formats.
+func AutoMapping() FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			p.Source.AutoMapping = true
+			return nil
+		},
+		clientScopes: QueuedClient | ManagedClient,
+		sourceScope:  FromFile | FromReader | FromBlob,
+		name:         "AutoMapping",
+	}
+}
+
+// applyAutoMapping sets the ingestion mapping generated from the schema of the table, if AutoMapping is set.
+// source is the path of the file or blob, used to infer the format when it isn't set.
+func (i *Ingestion) applyAutoMapping(ctx context.Context, props *properties.All, source string) error {
+	if !props.Source.AutoMapping {
+		return nil
+	}
+	props.Source.AutoMapping = false
+
+	additional := &props.Ingestion.Additional
+	if additional.IngestionMapping != "" || additional.IngestionMappingRef != "" {
+		return errors.ES(errors.OpFileIngest, errors.KClientArgs, "AutoMapping() cannot be used with IngestionMapping() or IngestionMappingRef()").SetNoRetry()
+	}
+
+	format := additional.Format
+	if format == DFUnknown && source != "" {
+		format = properties.DataFormatDiscovery(source)
+	}
+	if format == DFUnknown {
+		format = CSV
+	}
+
+	columns, err := i.tableColumns.get(ctx, props.Ingestion.DatabaseName, props.Ingestion.TableName)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := autoMapping(columns, format)
+	if err != nil {
+		return err
+	}
+	return IngestionMapping(mapping, format).Run(props, QueuedClient, FromFile)
+}
+
+// autoMapping generates the ingestion mapping of the columns of a table for the format.
+func autoMapping(columns []string, format DataFormat) ([]jsonColumnMapping, error) {
+	mapping := make([]jsonColumnMapping, 0, len(columns))
+	switch format.MappingKind() {
+	case CSV:
+		for ordinal, c := range columns {
+			mapping = append(mapping, jsonColumnMapping{
+				Column:     c,
+				Properties: map[string]string{"Ordinal": strconv.Itoa(ordinal)},
+			})
+		}
+	case JSON, AVRO, Parquet, ORC:
+		for _, c := range columns {
+			path, err := json.Marshal(c)
+			if err != nil {
+				return nil, err
+			}
+			mapping = append(mapping, jsonColumnMapping{
+				Column:     c,
+				Properties: map[string]string{"Path": "$[" + string(path) + "]"},
+			})
+		}
+	default:
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "AutoMapping() does not support the format %v", format).SetNoRetry()
+	}
+	return mapping, nil
+}
+
+// tableColumnsCache fetches the names of the columns of tables from the engine, and caches them.
+type tableColumnsCache struct {
+	engine *engineClient
+
+	mu      sync.Mutex
+	columns map[tableColumnsKey]cachedTableColumns
+}
+
+type tableColumnsKey struct {
+	db    string
+	table string
+}
+
+type cachedTableColumns struct {
+	columns []string
+	expires time.Time
+}
+
+func newTableColumnsCache(engine *engineClient) *tableColumnsCache {
+	return &tableColumnsCache{engine: engine, columns: map[tableColumnsKey]cachedTableColumns{}}
+}
+
+// tableSchemaRow is a row of the results of `.show table schema as json`.
+type tableSchemaRow struct {
+	Schema string
+}
+
+// get returns the names of the columns of a table, in order.
+func (c *tableColumnsCache) get(ctx context.Context, db, table string) ([]string, error) {
+	client, err := c.engine.get()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "AutoMapping() requires a client of the engine of the cluster").SetNoRetry()
+	}
+	key := tableColumnsKey{db: db, table: table}
+
+	c.mu.Lock()
+	cached, ok := c.columns[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.columns, nil
+	}
+
+	dataset, err := client.Mgmt(ctx, db, kql.New(".show table ").AddTable(table).AddLiteral(" schema as json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(dataset.Tables()) == 0 {
+		return nil, errors.ES(errors.OpFileIngest, errors.KInternal, "the schema of table %s was not returned", table)
+	}
+	rows, err := query.ToStructs[tableSchemaRow](dataset.Tables()[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpFileIngest, errors.KInternal, "expected a single row for the schema of table %s, got %d", table, len(rows))
+	}
+
+	var schema struct {
+		OrderedColumns []struct {
+			Name string
+		}
+	}
+	if err := json.Unmarshal([]byte(rows[0].Schema), &schema); err != nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KInternal, "could not parse the schema of table %s: %s", table, err)
+	}
+	columns := make([]string, 0, len(schema.OrderedColumns))
+	for _, col := range schema.OrderedColumns {
+		columns = append(columns, col.Name)
+	}
+
+	c.mu.Lock()
+	c.columns[key] = cachedTableColumns{columns: columns, expires: time.Now().Add(tableColumnsTTL)}
+	c.mu.Unlock()
+	return columns, nil
+}
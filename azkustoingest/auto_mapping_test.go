@@ -0,0 +1,140 @@
+package azkustoingest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaDataset returns the results of a `.show table schema as json` command for a table with the given columns.
+func schemaDataset(ctx context.Context, columns ...string) (v1.Dataset, error) {
+	schema := `{"Name":"T","OrderedColumns":[`
+	for i, c := range columns {
+		if i > 0 {
+			schema += ","
+		}
+		name, _ := json.Marshal(c)
+		schema += `{"Name":` + string(name) + `,"Type":"System.String","CslType":"string"}`
+	}
+	schema += `]}`
+
+	return v1.NewDataset(ctx, errors.OpMgmt, v1.V1{
+		Tables: []v1.RawTable{
+			{
+				TableName: "Table_0",
+				Columns: []v1.RawColumn{
+					{ColumnName: "TableName", ColumnType: string(types.String)},
+					{ColumnName: "Schema", ColumnType: string(types.String)},
+					{ColumnName: "DatabaseName", ColumnType: string(types.String)},
+					{ColumnName: "Folder", ColumnType: string(types.String)},
+					{ColumnName: "DocString", ColumnType: string(types.String)},
+				},
+				Rows: []v1.RawRow{{Row: []interface{}{"T", schema, "db", "", ""}}},
+			},
+		}})
+}
+
+func TestAutoMapping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		options []FileOption
+		source  string
+		want    string
+		kind    DataFormat
+		wantErr bool
+	}{
+		{
+			desc: "default csv",
+			want: `[{"column":"a","Properties":{"Ordinal":"0"}},{"column":"b c","Properties":{"Ordinal":"1"}}]`,
+			kind: CSV,
+		},
+		{
+			desc:    "tsv",
+			options: []FileOption{FileFormat(TSV)},
+			want:    `[{"column":"a","Properties":{"Ordinal":"0"}},{"column":"b c","Properties":{"Ordinal":"1"}}]`,
+			kind:    CSV,
+		},
+		{
+			desc:   "json from the file name",
+			source: "data.json.gz",
+			want:   `[{"column":"a","Properties":{"Path":"$[\"a\"]"}},{"column":"b c","Properties":{"Path":"$[\"b c\"]"}}]`,
+			kind:   JSON,
+		},
+		{
+			desc:    "with a mapping",
+			options: []FileOption{IngestionMappingRef("m", CSV)},
+			wantErr: true,
+		},
+		{
+			desc:    "unsupported format",
+			options: []FileOption{FileFormat(W3CLogFile)},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := newMockClient()
+			client.onMgmt = func(ctx context.Context, db string, query azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+				assert.Equal(t, "db", db)
+				assert.Equal(t, `.show table ["my table"] schema as json`, query.String())
+				return schemaDataset(ctx, "a", "b c")
+			}
+			i := &Ingestion{tableColumns: newTableColumnsCache(&engineClient{client: client})}
+
+			props := properties.All{Ingestion: properties.Ingestion{DatabaseName: "db", TableName: "my table"}}
+			for _, o := range append(test.options, AutoMapping()) {
+				require.NoError(t, o.Run(&props, QueuedClient, FromReader))
+			}
+
+			err := i.applyAutoMapping(context.Background(), &props, test.source)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, props.Ingestion.Additional.IngestionMapping)
+			assert.Equal(t, test.kind, props.Ingestion.Additional.IngestionMappingType)
+			assert.False(t, props.Source.AutoMapping)
+		})
+	}
+}
+
+func TestTableColumnsCache(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	commands := 0
+	client := newMockClient()
+	client.onMgmt = func(ctx context.Context, _ string, _ azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+		mu.Lock()
+		commands++
+		mu.Unlock()
+		return schemaDataset(ctx, "x", "y", "z")
+	}
+
+	cache := newTableColumnsCache(&engineClient{client: client})
+	for i := 0; i < 2; i++ {
+		columns, err := cache.get(context.Background(), "db", "T")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x", "y", "z"}, columns)
+	}
+	assert.Equal(t, 1, commands)
+
+	_, err := newTableColumnsCache(nil).get(context.Background(), "db", "T")
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package azkustoingest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+)
+
+// batchingPolicyTTL is the time a batching policy is cached before it's fetched again.
+const batchingPolicyTTL = 10 * time.Minute
+
+// DefaultBatchingPolicy is the ingestion batching policy of tables and databases that don't set one.
+var DefaultBatchingPolicy = BatchingPolicy{
+	MaximumBatchingTimeSpan: 5 * time.Minute,
+	MaximumNumberOfItems:    500,
+	MaximumRawDataSizeMB:    1024,
+}
+
+// BatchingPolicy is the ingestion batching policy of a table. Queued ingestions are aggregated into batches, which are
+// ingested when the first of the limits of the policy is reached.
+// See https://learn.microsoft.com/en-us/azure/data-explorer/kusto/management/batching-policy
+type BatchingPolicy struct {
+	// MaximumBatchingTimeSpan is the maximum time a batch is kept open.
+	MaximumBatchingTimeSpan time.Duration
+	// MaximumNumberOfItems is the maximum number of blobs in a batch.
+	MaximumNumberOfItems int
+	// MaximumRawDataSizeMB is the maximum uncompressed size of a batch, in MB.
+	MaximumRawDataSizeMB int
+}
+
+// UnmarshalJSON implements json.Unmarshaler, for the policies returned by `.show policy ingestionbatching` commands.
+func (b *BatchingPolicy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MaximumBatchingTimeSpan string
+		MaximumNumberOfItems    int
+		MaximumRawDataSizeMB    int
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*b = DefaultBatchingPolicy
+	if raw.MaximumBatchingTimeSpan != "" {
+		span, err := value.TimespanFromString(raw.MaximumBatchingTimeSpan)
+		if err != nil {
+			return err
+		}
+		b.MaximumBatchingTimeSpan = *span.Ptr()
+	}
+	if raw.MaximumNumberOfItems != 0 {
+		b.MaximumNumberOfItems = raw.MaximumNumberOfItems
+	}
+	if raw.MaximumRawDataSizeMB != 0 {
+		b.MaximumRawDataSizeMB = raw.MaximumRawDataSizeMB
+	}
+	return nil
+}
+
+// batchingPolicyCache fetches the batching policies of tables from the engine, and caches them.
+// Policies are only fetched when a Result is asked for them, and failures to fetch them are not cached.
+// Without an engine, all the tables have the default policy.
+type batchingPolicyCache struct {
+	engine *engineClient
+
+	mu       sync.Mutex
+	policies map[batchingPolicyKey]cachedBatchingPolicy
+}
+
+type batchingPolicyKey struct {
+	db    string
+	table string
+}
+
+type cachedBatchingPolicy struct {
+	policy  BatchingPolicy
+	expires time.Time
+}
+
+func newBatchingPolicyCache(engine *engineClient) *batchingPolicyCache {
+	return &batchingPolicyCache{engine: engine, policies: map[batchingPolicyKey]cachedBatchingPolicy{}}
+}
+
+// get returns the effective batching policy of a table: the policy of the table, or else of its database, or else
+// the default policy.
+func (c *batchingPolicyCache) get(ctx context.Context, db, table string) (BatchingPolicy, error) {
+	client, err := c.engine.get()
+	if err != nil {
+		return BatchingPolicy{}, err
+	}
+	if client == nil {
+		return DefaultBatchingPolicy, nil
+	}
+	key := batchingPolicyKey{db: db, table: table}
+
+	c.mu.Lock()
+	cached, ok := c.policies[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.policy, nil
+	}
+
+	policy, ok, err := fetchBatchingPolicy(ctx, client, db, kql.New(".show table ").AddTable(table).AddLiteral(" policy ingestionbatching"))
+	if err == nil && !ok {
+		policy, ok, err = fetchBatchingPolicy(ctx, client, db, kql.New(".show database ").AddUnsafe(kql.NormalizeName(db)).AddLiteral(" policy ingestionbatching"))
+	}
+	if err != nil {
+		return BatchingPolicy{}, err
+	}
+	if !ok {
+		policy = DefaultBatchingPolicy
+	}
+
+	c.mu.Lock()
+	c.policies[key] = cachedBatchingPolicy{policy: policy, expires: time.Now().Add(batchingPolicyTTL)}
+	c.mu.Unlock()
+	return policy, nil
+}
+
+// batchingPolicyRow is a row of the results of `.show policy ingestionbatching` commands.
+type batchingPolicyRow struct {
+	Policy string
+}
+
+// fetchBatchingPolicy runs a `.show policy ingestionbatching` command, and reports whether it returned a policy.
+func fetchBatchingPolicy(ctx context.Context, client QueryClient, db string, cmd *kql.Builder) (BatchingPolicy, bool, error) {
+	dataset, err := client.Mgmt(ctx, db, cmd)
+	if err != nil {
+		return BatchingPolicy{}, false, err
+	}
+	if len(dataset.Tables()) == 0 {
+		return BatchingPolicy{}, false, nil
+	}
+
+	rows, err := query.ToStructs[batchingPolicyRow](dataset.Tables()[0])
+	if err != nil {
+		return BatchingPolicy{}, false, err
+	}
+	if len(rows) == 0 || rows[0].Policy == "" || rows[0].Policy == "null" {
+		return BatchingPolicy{}, false, nil
+	}
+
+	var policy BatchingPolicy
+	if err := json.Unmarshal([]byte(rows[0].Policy), &policy); err != nil {
+		return BatchingPolicy{}, false, errors.ES(errors.OpFileIngest, errors.KFailedToParse, "could not parse the batching policy: %s", err).SetNoRetry()
+	}
+	return policy, true, nil
+}
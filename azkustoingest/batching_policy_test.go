@@ -0,0 +1,202 @@
+package azkustoingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// policyDataset returns the results of a `.show policy ingestionbatching` command with the given policy.
+func policyDataset(ctx context.Context, policy interface{}) (v1.Dataset, error) {
+	return v1.NewDataset(ctx, errors.OpMgmt, v1.V1{
+		Tables: []v1.RawTable{
+			{
+				TableName: "Table_0",
+				Columns: []v1.RawColumn{
+					{ColumnName: "PolicyName", ColumnType: string(types.String)},
+					{ColumnName: "EntityName", ColumnType: string(types.String)},
+					{ColumnName: "Policy", ColumnType: string(types.String)},
+				},
+				Rows: []v1.RawRow{{Row: []interface{}{"IngestionBatchingPolicy", "[db]", policy}}},
+			},
+		}})
+}
+
+func TestBatchingPolicyCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc          string
+		tablePolicy   interface{}
+		dbPolicy      interface{}
+		fail          bool
+		want          BatchingPolicy
+		wantCommands  int
+		wantLastQuery string
+	}{
+		{
+			desc:          "table policy",
+			tablePolicy:   `{"MaximumBatchingTimeSpan": "00:00:30", "MaximumNumberOfItems": 20, "MaximumRawDataSizeMB": 300}`,
+			want:          BatchingPolicy{MaximumBatchingTimeSpan: 30 * time.Second, MaximumNumberOfItems: 20, MaximumRawDataSizeMB: 300},
+			wantCommands:  1,
+			wantLastQuery: `.show table ["my table"] policy ingestionbatching`,
+		},
+		{
+			desc:          "database policy",
+			tablePolicy:   nil,
+			dbPolicy:      `{"MaximumBatchingTimeSpan": "1.00:00:00"}`,
+			want:          BatchingPolicy{MaximumBatchingTimeSpan: 24 * time.Hour, MaximumNumberOfItems: 500, MaximumRawDataSizeMB: 1024},
+			wantCommands:  2,
+			wantLastQuery: `.show database ["my db"] policy ingestionbatching`,
+		},
+		{
+			desc:          "default policy",
+			tablePolicy:   "null",
+			dbPolicy:      nil,
+			want:          DefaultBatchingPolicy,
+			wantCommands:  2,
+			wantLastQuery: `.show database ["my db"] policy ingestionbatching`,
+		},
+		{
+			desc: "forbidden",
+			fail: true,
+			// Failures are not cached.
+			wantCommands:  2,
+			wantLastQuery: `.show table ["my table"] policy ingestionbatching`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var commands []string
+			client := newMockClient()
+			client.onMgmt = func(ctx context.Context, db string, query azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+				mu.Lock()
+				commands = append(commands, query.String())
+				mu.Unlock()
+
+				assert.Equal(t, "my db", db)
+				if test.fail {
+					return nil, fmt.Errorf("forbidden")
+				}
+				if strings.HasPrefix(query.String(), ".show table") {
+					return policyDataset(ctx, test.tablePolicy)
+				}
+				return policyDataset(ctx, test.dbPolicy)
+			}
+
+			cache := newBatchingPolicyCache(&engineClient{client: client})
+			for i := 0; i < 2; i++ {
+				policy, err := cache.get(context.Background(), "my db", "my table")
+				if test.fail {
+					assert.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				// The policy is cached.
+				assert.Equal(t, test.want, policy)
+			}
+
+			assert.Len(t, commands, test.wantCommands)
+			assert.Equal(t, test.wantLastQuery, commands[len(commands)-1])
+		})
+	}
+}
+
+func TestResultEstimatedReadyTime(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	commands := 0
+	client := newMockClient()
+	client.onMgmt = func(ctx context.Context, _ string, query azkustodata.Statement, _ ...azkustodata.QueryOption) (v1.Dataset, error) {
+		mu.Lock()
+		commands++
+		mu.Unlock()
+		return policyDataset(ctx, `{"MaximumBatchingTimeSpan": "00:01:00", "MaximumNumberOfItems": 10, "MaximumRawDataSizeMB": 100}`)
+	}
+	cache := newBatchingPolicyCache(&engineClient{client: client})
+	policy := BatchingPolicy{MaximumBatchingTimeSpan: time.Minute, MaximumNumberOfItems: 10, MaximumRawDataSizeMB: 100}
+
+	tests := []struct {
+		desc      string
+		props     properties.All
+		wantDelay time.Duration
+	}{
+		{
+			desc:      "batched",
+			wantDelay: time.Minute,
+		},
+		{
+			desc:      "flush immediately",
+			props:     properties.All{Ingestion: properties.Ingestion{FlushImmediately: true}},
+			wantDelay: 0,
+		},
+		{
+			desc:      "larger than a batch",
+			props:     properties.All{Ingestion: properties.Ingestion{RawDataSize: 200 * 1024 * 1024}},
+			wantDelay: 0,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			result := newResult()
+			result.putBatching(cache, test.props)
+
+			got, err := result.BatchingPolicy(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, policy, got)
+			delay, err := result.EstimatedBatchingDelay(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.wantDelay, delay)
+			ready, err := result.EstimatedReadyTime(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, result.created.Add(test.wantDelay), ready)
+		})
+	}
+
+	// Streaming ingestions are not batched.
+	result := newResult()
+	got, err := result.BatchingPolicy(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, got)
+	ready, err := result.EstimatedReadyTime(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, result.created, ready)
+}
+
+func TestIngestionBatchingPolicyIsLazy(t *testing.T) {
+	t.Parallel()
+
+	// The engine client isn't created until a policy is needed.
+	engine := &engineClient{kcsb: azkustodata.NewConnectionStringBuilder("https://cluster.kusto.windows.net")}
+	queuedClient, err := newFromClient(newMockClient(), &Ingestion{engine: engine})
+	require.NoError(t, err)
+	result := newResult()
+	result.putBatching(queuedClient.batching, properties.All{})
+	assert.Nil(t, engine.client)
+
+	require.NoError(t, queuedClient.Close())
+	_, err = result.EstimatedReadyTime(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, engine.client)
+}
@@ -0,0 +1,51 @@
+package azkustoingest
+
+import (
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/quota"
+)
+
+// BufferQuotaStats are the statistics of the global buffer quota, returned by GlobalBufferQuotaStats.
+type BufferQuotaStats struct {
+	// Quota is the current quota in bytes, or 0 if there is none.
+	Quota int64
+	// InUse is the amount of bytes held by ingestions, and PeakInUse is the highest it has been.
+	InUse     int64
+	PeakInUse int64
+	// Acquisitions is the number of times an ingestion acquired buffers while a quota was set.
+	Acquisitions int64
+	// Waits is the number of acquisitions that blocked until other ingestions released their buffers, Waiting is the
+	// number of ingestions that are blocked now, and WaitTime is the total time they were blocked.
+	Waits    int64
+	Waiting  int64
+	WaitTime time.Duration
+	// Canceled is the number of ingestions whose context was done while they were blocked.
+	Canceled int64
+}
+
+// SetGlobalBufferQuota caps the memory of the buffers used by all the ingestion clients of the process, queued,
+// streaming and managed alike: the upload buffers of queued ingestions, the buffers of the payloads that managed
+// ingestions read before streaming them, and the compression buffers. An ingestion that would exceed the quota blocks
+// until other ingestions release their buffers, in the order they were called, or until its context is done.
+// An ingestion whose buffers are larger than the quota waits for the whole quota instead.
+// 0, the default, removes the quota. The quota can be changed at any time, and is applied to the ingestions that are
+// blocked.
+func SetGlobalBufferQuota(bytes int64) {
+	quota.Global.Set(bytes)
+}
+
+// GlobalBufferQuotaStats returns the statistics of the global buffer quota set with SetGlobalBufferQuota.
+func GlobalBufferQuotaStats() BufferQuotaStats {
+	s := quota.Global.Stats()
+	return BufferQuotaStats{
+		Quota:        s.Quota,
+		InUse:        s.InUse,
+		PeakInUse:    s.PeakInUse,
+		Acquisitions: s.Acquisitions,
+		Waits:        s.Waits,
+		Waiting:      s.Waiting,
+		WaitTime:     s.WaitTime,
+		Canceled:     s.Canceled,
+	}
+}
@@ -0,0 +1,143 @@
+package azkustoingest
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+)
+
+// mappingOptions are the options that set the ingestion mapping. An option of a call overrides all the default
+// options that set the mapping, not only the ones with the same name.
+var mappingOptions = map[string]bool{
+	"IngestionMapping":    true,
+	"IngestionMappingRef": true,
+	"AvroSchema":          true,
+	"AvroSchemaFrom":      true,
+	"AutoMapping":         true,
+}
+
+// WithDefaultOptions sets options that are applied to every ingestion of the client, before the options of the call,
+// such as the format, the mapping, the tags or FlushImmediately:
+//   - An option of a call overrides the default option with the same name, which is not applied. An option of a call
+//     that sets the ingestion mapping - IngestionMapping, IngestionMappingRef, AvroSchema or AutoMapping - overrides
+//     all the default options that set it.
+//   - Default options that don't apply to the source of a call, such as DeleteSource for readers, are skipped.
+//   - The default options must apply to the client, or its constructor fails.
+//
+// Use ResolveOptions to see which options apply to an ingestion.
+func WithDefaultOptions(options ...FileOption) Option {
+	return func(s *Ingestion) {
+		s.defaultOptions = append(s.defaultOptions, options...)
+	}
+}
+
+// validateDefaultOptions returns a KClientArgs error if any of the default options doesn't apply to the client.
+func validateDefaultOptions(options []FileOption, client ClientScope) error {
+	var invalid []string
+	for _, o := range options {
+		if o.ClientScopes()&client == 0 {
+			invalid = append(invalid, o.String())
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return errors.ES(errors.OpServConn, errors.KClientArgs, "default options [%s] are not valid for client '%s'", strings.Join(invalid, ", "), client).SetNoRetry()
+}
+
+// ResolvedOptions describes the options an ingestion is made with, once merged with the default options of the client.
+type ResolvedOptions struct {
+	// Applied are the names of the options that are applied, in order: the default options, then the options of the
+	// call.
+	Applied []string
+	// Overridden are the names of the default options that are not applied, as options of the call override them.
+	Overridden []string
+	// Skipped are the names of the default options that are not applied, as they don't apply to the source.
+	Skipped []string
+	// Properties are the ingestion properties the options result in, as JSON. The format and the mapping kind are
+	// the ones set by the options, before they're inferred from the source.
+	Properties json.RawMessage
+}
+
+// withDefaults returns the default options that apply to an ingestion from source, followed by options, and describes
+// them in a ResolvedOptions.
+func withDefaults(defaults, options []FileOption, source SourceScope) ([]FileOption, *ResolvedOptions) {
+	resolved := &ResolvedOptions{}
+	if len(defaults) == 0 {
+		for _, o := range options {
+			resolved.Applied = append(resolved.Applied, o.String())
+		}
+		return options, resolved
+	}
+
+	overridden := map[string]bool{}
+	for _, o := range options {
+		overridden[o.String()] = true
+		if mappingOptions[o.String()] {
+			for name := range mappingOptions {
+				overridden[name] = true
+			}
+		}
+	}
+
+	all := make([]FileOption, 0, len(defaults)+len(options))
+	for _, o := range defaults {
+		switch {
+		case o.SourceScopes()&source == 0:
+			resolved.Skipped = append(resolved.Skipped, o.String())
+		case overridden[o.String()]:
+			resolved.Overridden = append(resolved.Overridden, o.String())
+		default:
+			all = append(all, o)
+		}
+	}
+	all = append(all, options...)
+
+	for _, o := range all {
+		resolved.Applied = append(resolved.Applied, o.String())
+	}
+	return all, resolved
+}
+
+// resolveOptions returns the ResolvedOptions of an ingestion from source with options.
+func resolveOptions(props properties.All, defaults, options []FileOption, client ClientScope, source SourceScope) (*ResolvedOptions, error) {
+	options, resolved := withDefaults(defaults, options, source)
+	if err := validateOptions(options, client, source); err != nil {
+		return nil, err
+	}
+	for _, o := range options {
+		if err := o.Run(&props, client, source); err != nil {
+			return nil, err
+		}
+	}
+	if err := completeMappingKind(&props); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(props.Ingestion)
+	if err != nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KInternal, "could not serialize the ingestion properties: %s", err)
+	}
+	resolved.Properties = b
+	return resolved, nil
+}
+
+// ResolveOptions returns the options an ingestion from source with options is made with, once merged with the default
+// options of the client, and the ingestion properties they result in, to debug which options apply.
+func (i *Ingestion) ResolveOptions(source SourceScope, options ...FileOption) (*ResolvedOptions, error) {
+	return resolveOptions(i.newProp(), i.defaultOptions, options, QueuedClient, source)
+}
+
+// ResolveOptions returns the options an ingestion from source with options is made with, once merged with the default
+// options of the client, and the ingestion properties they result in, to debug which options apply.
+func (i *Streaming) ResolveOptions(source SourceScope, options ...FileOption) (*ResolvedOptions, error) {
+	return resolveOptions(i.newProp(), i.defaultOptions, options, StreamingClient, source)
+}
+
+// ResolveOptions returns the options an ingestion from source with options is made with, once merged with the default
+// options of the client, and the ingestion properties they result in, to debug which options apply.
+func (m *Managed) ResolveOptions(source SourceScope, options ...FileOption) (*ResolvedOptions, error) {
+	return resolveOptions(m.newProp(), m.defaultOptions, options, ManagedClient, source)
+}
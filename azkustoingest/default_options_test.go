@@ -0,0 +1,127 @@
+package azkustoingest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDefaults(t *testing.T) {
+	t.Parallel()
+
+	defaults := []FileOption{FileFormat(CSV), IngestionMappingRef("csvMapping", CSV), Tags([]string{"default"}), DeleteSource()}
+
+	tests := []struct {
+		desc           string
+		options        []FileOption
+		source         SourceScope
+		wantApplied    []string
+		wantOverridden []string
+		wantSkipped    []string
+	}{
+		{
+			desc:        "Defaults only",
+			source:      FromFile,
+			wantApplied: []string{"FileFormat", "IngestionMappingRef", "Tags", "DeleteSource"},
+		},
+		{
+			desc:           "Override by name",
+			options:        []FileOption{Tags([]string{"call"})},
+			source:         FromFile,
+			wantApplied:    []string{"FileFormat", "IngestionMappingRef", "DeleteSource", "Tags"},
+			wantOverridden: []string{"Tags"},
+		},
+		{
+			desc:           "A mapping overrides the default mapping",
+			options:        []FileOption{IngestionMapping(`[{"column":"a"}]`, JSON), FileFormat(JSON)},
+			source:         FromFile,
+			wantApplied:    []string{"Tags", "DeleteSource", "IngestionMapping", "FileFormat"},
+			wantOverridden: []string{"FileFormat", "IngestionMappingRef"},
+		},
+		{
+			desc:        "Defaults that don't apply to the source are skipped",
+			source:      FromReader,
+			wantApplied: []string{"FileFormat", "IngestionMappingRef", "Tags"},
+			wantSkipped: []string{"DeleteSource"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			options, resolved := withDefaults(defaults, test.options, test.source)
+			assert.Len(t, options, len(test.wantApplied))
+			assert.Equal(t, test.wantApplied, resolved.Applied)
+			assert.Equal(t, test.wantOverridden, resolved.Overridden)
+			assert.Equal(t, test.wantSkipped, resolved.Skipped)
+		})
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	t.Parallel()
+
+	mockClient := mockClient{
+		endpoint: "https://test.kusto.windows.net",
+		auth:     azkustodata.Authorization{},
+		onMgmt: func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+			return nil, nil
+		},
+	}
+	ingestion, err := newFromClient(mockClient, getOptions([]Option{
+		WithDefaultOptions(FileFormat(CSV), IngestionMappingRef("csvMapping", CSV), Tags([]string{"default"}), FlushImmediately()),
+	}))
+	require.NoError(t, err)
+	ingestion.db, ingestion.table = "db", "table"
+
+	resolved, err := ingestion.ResolveOptions(FromReader, Tags([]string{"call"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tags"}, resolved.Overridden)
+
+	var props struct {
+		DatabaseName         string
+		TableName            string
+		FlushImmediately     bool
+		AdditionalProperties struct {
+			Format              string   `json:"format"`
+			IngestionMappingRef string   `json:"ingestionMappingReference"`
+			Tags                []string `json:"tags"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(resolved.Properties, &props))
+	assert.Equal(t, "db", props.DatabaseName)
+	assert.Equal(t, "table", props.TableName)
+	assert.True(t, props.FlushImmediately)
+	assert.Equal(t, "csv", props.AdditionalProperties.Format)
+	assert.Equal(t, "csvMapping", props.AdditionalProperties.IngestionMappingRef)
+	assert.Equal(t, []string{"call"}, props.AdditionalProperties.Tags)
+
+	_, err = ingestion.ResolveOptions(FromReader, FileFormat(JSON))
+	assert.Error(t, err, "the default mapping ref doesn't match the format of the call")
+}
+
+func TestValidateDefaultOptions(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateDefaultOptions([]FileOption{FileFormat(CSV), Tags([]string{"a"})}, QueuedClient))
+	assert.NoError(t, validateDefaultOptions([]FileOption{Tags([]string{"a"})}, ManagedClient))
+
+	err := validateDefaultOptions([]FileOption{FileFormat(CSV), Tags([]string{"a"})}, StreamingClient)
+	require.Error(t, err)
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KClientArgs, e.Kind)
+	assert.Contains(t, err.Error(), "Tags")
+
+	_, err = NewStreaming(azkustodata.NewConnectionStringBuilder("https://test.kusto.windows.net"),
+		WithDefaultOptions(FlushImmediately()))
+	assert.Error(t, err)
+}
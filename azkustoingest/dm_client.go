@@ -0,0 +1,166 @@
+package azkustoingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/google/uuid"
+)
+
+// dmDatabase is the database the commands of the Data Management service run in.
+const dmDatabase = "NetDefaultDB"
+
+// DMClient runs the commands used to inspect queued ingestion. The resource commands run on the Data Management
+// service of the cluster (the "ingest-" endpoint), and the commands about the ingestion mappings and failures run on
+// the engine, which owns them.
+// A DMClient is safe for concurrent use by multiple goroutines.
+type DMClient struct {
+	dm     QueryClient
+	engine QueryClient
+}
+
+// NewDMClient is the constructor for DMClient. The connection string can point to either the engine or the Data
+// Management endpoint of the cluster, the other endpoint is derived from it by adding or removing the "ingest-" prefix,
+// unless WithoutEndpointCorrection is set. The other options are ignored.
+func NewDMClient(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*DMClient, error) {
+	i := getOptions(options)
+
+	dmKcsb, engineKcsb := *kcsb, *kcsb
+	if !i.withoutEndpointCorrection {
+		dmKcsb.DataSource = addIngestPrefix(kcsb.DataSource)
+		engineKcsb.DataSource = removeIngestPrefix(kcsb.DataSource)
+	}
+
+	dm, err := azkustodata.New(&dmKcsb)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := azkustodata.New(&engineKcsb)
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+	return &DMClient{dm: dm, engine: engine}, nil
+}
+
+// IngestionResource is a row of `.get ingestion resources`: a storage resource used by queued ingestion.
+type IngestionResource struct {
+	// ResourceTypeName is the kind of the resource, such as "TempStorage" or "SecuredReadyForAggregationQueue".
+	ResourceTypeName string
+	// StorageRoot is the URI of the resource, including its SAS token.
+	StorageRoot string
+}
+
+// IngestionResources lists the storage resources of the Data Management service, with `.get ingestion resources`.
+func (d *DMClient) IngestionResources(ctx context.Context) ([]IngestionResource, error) {
+	return dmRows[IngestionResource](ctx, d.dm, dmDatabase, kql.New(".get ingestion resources"))
+}
+
+// IngestionMappingInfo is a row of `.show ingestion mappings`.
+type IngestionMappingInfo struct {
+	Name          string
+	Kind          string
+	Mapping       string
+	LastUpdatedOn time.Time
+	Database      string
+	Table         string
+}
+
+// IngestionMappings lists the ingestion mappings of a table, or of all the tables of the database if table is empty,
+// with `.show ingestion mappings`.
+func (d *DMClient) IngestionMappings(ctx context.Context, db, table string) ([]IngestionMappingInfo, error) {
+	cmd := kql.New("")
+	if table != "" {
+		cmd.AddLiteral(".show table ").AddTable(table).AddLiteral(" ingestion mappings")
+	} else {
+		cmd.AddLiteral(".show database ").AddUnsafe(kql.NormalizeName(db)).AddLiteral(" ingestion mappings")
+	}
+	return dmRows[IngestionMappingInfo](ctx, d.engine, db, cmd)
+}
+
+// IngestionFailure is a row of `.show ingestion failures`.
+type IngestionFailure struct {
+	OperationId                uuid.UUID
+	Database                   string
+	Table                      string
+	FailedOn                   time.Time
+	IngestionSourcePath        string
+	Details                    string
+	FailureKind                string
+	RootActivityId             uuid.UUID
+	OperationKind              string
+	OriginatesFromUpdatePolicy bool
+	ErrorCode                  string
+	Principal                  string
+	ShouldRetry                bool
+	User                       string
+	IngestionProperties        string
+}
+
+// IngestionFailuresFilter selects the failures listed by IngestionFailures.
+type IngestionFailuresFilter struct {
+	// Table limits the failures to those of a table. The failures of all the tables of the database are listed if empty.
+	Table string
+	// OperationID limits the failures to those of an ingestion operation, such as the OperationID of a StatusRecord.
+	OperationID uuid.UUID
+	// FailedAfter limits the failures to those that happened after it.
+	FailedAfter time.Time
+}
+
+// IngestionFailures lists the ingestion failures of a database, most recent first, with `.show ingestion failures`.
+func (d *DMClient) IngestionFailures(ctx context.Context, db string, filter IngestionFailuresFilter) ([]IngestionFailure, error) {
+	if db == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "IngestionFailures() requires a database").SetNoRetry()
+	}
+
+	cmd := kql.New(".show ingestion failures | where Database == ").AddString(db)
+	if filter.Table != "" {
+		cmd.AddLiteral(" and Table == ").AddString(filter.Table)
+	}
+	if filter.OperationID != uuid.Nil {
+		cmd.AddLiteral(" and OperationId == ").AddGUID(filter.OperationID)
+	}
+	if !filter.FailedAfter.IsZero() {
+		cmd.AddLiteral(" and FailedOn > ").AddDateTime(filter.FailedAfter)
+	}
+	cmd.AddLiteral(" | order by FailedOn desc")
+	return dmRows[IngestionFailure](ctx, d.engine, db, cmd)
+}
+
+// ErrorDetails returns the failures of the ingestion of a StatusRecord, such as the one returned by Result.Wait, with
+// `.show ingestion failures`. Details holds the error of each failure, which is the error details reported by the
+// service, as the ingestion service doesn't share the reports of the individual records of a failed ingestion.
+// The failures are listed once the engine reports them, which can be a few minutes after the status record.
+func (d *DMClient) ErrorDetails(ctx context.Context, record StatusRecord) ([]IngestionFailure, error) {
+	if record.OperationID == uuid.Nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "ErrorDetails() requires a status record with an operation ID").SetNoRetry()
+	}
+	return d.IngestionFailures(ctx, record.Database, IngestionFailuresFilter{Table: record.Table, OperationID: record.OperationID})
+}
+
+// Mgmt runs a management command on the Data Management service, such as `.get kusto identity token`.
+func (d *DMClient) Mgmt(ctx context.Context, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+	return d.dm.Mgmt(ctx, dmDatabase, query, options...)
+}
+
+// Close closes the connections of the client.
+func (d *DMClient) Close() error {
+	return errors.CombineErrors(d.dm.Close(), d.engine.Close())
+}
+
+// dmRows runs a command and decodes the rows of its first table.
+func dmRows[T any](ctx context.Context, client QueryClient, db string, cmd azkustodata.Statement) ([]T, error) {
+	dataset, err := client.Mgmt(ctx, db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataset.Tables()) == 0 {
+		return nil, nil
+	}
+	return query.ToStructs[T](dataset.Tables()[0])
+}
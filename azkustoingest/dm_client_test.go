@@ -0,0 +1,145 @@
+package azkustoingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dmCommand is a command run by a DMClient, on one of its clients.
+type dmCommand struct {
+	client  string
+	db      string
+	command string
+}
+
+func newTestDMClient(commands *[]dmCommand, columns []v1.RawColumn, rows []v1.RawRow) *DMClient {
+	mock := func(name string) mockClient {
+		return mockClient{
+			onMgmt: func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+				*commands = append(*commands, dmCommand{client: name, db: db, command: query.String()})
+				return v1.NewDataset(ctx, errors.OpMgmt, v1.V1{Tables: []v1.RawTable{{TableName: "Table_0", Columns: columns, Rows: rows}}})
+			},
+		}
+	}
+	return &DMClient{dm: mock("dm"), engine: mock("engine")}
+}
+
+func TestDMClientIngestionResources(t *testing.T) {
+	t.Parallel()
+
+	var commands []dmCommand
+	client := newTestDMClient(&commands,
+		[]v1.RawColumn{
+			{ColumnName: "ResourceTypeName", ColumnType: string(types.String)},
+			{ColumnName: "StorageRoot", ColumnType: string(types.String)},
+		},
+		[]v1.RawRow{{Row: []interface{}{"TempStorage", "https://account.blob.core.windows.net/container?sas"}}},
+	)
+
+	resources, err := client.IngestionResources(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []IngestionResource{{ResourceTypeName: "TempStorage", StorageRoot: "https://account.blob.core.windows.net/container?sas"}}, resources)
+	assert.Equal(t, []dmCommand{{client: "dm", db: "NetDefaultDB", command: ".get ingestion resources"}}, commands)
+}
+
+func TestDMClientIngestionMappings(t *testing.T) {
+	t.Parallel()
+
+	var commands []dmCommand
+	client := newTestDMClient(&commands,
+		[]v1.RawColumn{
+			{ColumnName: "Name", ColumnType: string(types.String)},
+			{ColumnName: "Kind", ColumnType: string(types.String)},
+			{ColumnName: "Mapping", ColumnType: string(types.String)},
+			{ColumnName: "LastUpdatedOn", ColumnType: string(types.DateTime)},
+			{ColumnName: "Database", ColumnType: string(types.String)},
+			{ColumnName: "Table", ColumnType: string(types.String)},
+		},
+		[]v1.RawRow{{Row: []interface{}{"m", "Json", "[]", "2024-01-02T03:04:05Z", "db", "my table"}}},
+	)
+
+	mappings, err := client.IngestionMappings(context.Background(), "db", "my table")
+	require.NoError(t, err)
+	assert.Equal(t, []IngestionMappingInfo{{
+		Name: "m", Kind: "Json", Mapping: "[]", LastUpdatedOn: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Database: "db", Table: "my table",
+	}}, mappings)
+
+	_, err = client.IngestionMappings(context.Background(), "my db", "")
+	require.NoError(t, err)
+	assert.Equal(t, []dmCommand{
+		{client: "engine", db: "db", command: `.show table ["my table"] ingestion mappings`},
+		{client: "engine", db: "my db", command: `.show database ["my db"] ingestion mappings`},
+	}, commands)
+}
+
+func TestDMClientIngestionFailures(t *testing.T) {
+	t.Parallel()
+
+	operation := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	var commands []dmCommand
+	client := newTestDMClient(&commands,
+		[]v1.RawColumn{
+			{ColumnName: "OperationId", ColumnType: string(types.GUID)},
+			{ColumnName: "Database", ColumnType: string(types.String)},
+			{ColumnName: "Table", ColumnType: string(types.String)},
+			{ColumnName: "FailedOn", ColumnType: string(types.DateTime)},
+			{ColumnName: "Details", ColumnType: string(types.String)},
+			{ColumnName: "ShouldRetry", ColumnType: string(types.Bool)},
+		},
+		[]v1.RawRow{{Row: []interface{}{operation.String(), "db", "t", "2024-01-02T03:04:05Z", "bad format", false}}},
+	)
+
+	failures, err := client.IngestionFailures(context.Background(), "db", IngestionFailuresFilter{
+		Table:       "t",
+		OperationID: operation,
+		FailedAfter: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []IngestionFailure{{
+		OperationId: operation, Database: "db", Table: "t", FailedOn: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Details: "bad format",
+	}}, failures)
+	assert.Equal(t, []dmCommand{{
+		client: "engine",
+		db:     "db",
+		command: `.show ingestion failures | where Database == "db" and Table == "t" and OperationId == guid(11111111-2222-3333-4444-555555555555)` +
+			` and FailedOn > datetime(2024-01-01T00:00:00Z) | order by FailedOn desc`,
+	}}, commands)
+
+	_, err = client.IngestionFailures(context.Background(), "", IngestionFailuresFilter{})
+	assert.Error(t, err)
+}
+
+func TestDMClientErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	operation := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	var commands []dmCommand
+	client := newTestDMClient(&commands,
+		[]v1.RawColumn{
+			{ColumnName: "OperationId", ColumnType: string(types.GUID)},
+			{ColumnName: "Details", ColumnType: string(types.String)},
+		},
+		[]v1.RawRow{{Row: []interface{}{operation.String(), "Stream_WrongNumberOfFields: the record 3 has 2 fields"}}},
+	)
+
+	failures, err := client.ErrorDetails(context.Background(), StatusRecord{Status: Failed, Database: "db", Table: "t", OperationID: operation})
+	require.NoError(t, err)
+	assert.Equal(t, []IngestionFailure{{OperationId: operation, Details: "Stream_WrongNumberOfFields: the record 3 has 2 fields"}}, failures)
+	assert.Equal(t, []dmCommand{{
+		client:  "engine",
+		db:      "db",
+		command: `.show ingestion failures | where Database == "db" and Table == "t" and OperationId == guid(11111111-2222-3333-4444-555555555555) | order by FailedOn desc`,
+	}}, commands)
+
+	_, err = client.ErrorDetails(context.Background(), StatusRecord{Status: Failed, Database: "db", Table: "t"})
+	assert.Error(t, err)
+}
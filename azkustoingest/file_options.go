@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
 	"github.com/cenkalti/backoff/v4"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-kusto-go/azkustodata"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/avro"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 )
 
@@ -43,6 +47,8 @@ func (s ClientScope) String() string {
 		return "QueuedClient"
 	case StreamingClient:
 		return "StreamingClient"
+	case ManagedClient:
+		return "ManagedClient"
 	default:
 		panic(fmt.Sprintf("unknown ClientScope %d", s))
 	}
@@ -58,6 +64,45 @@ type FileOption interface {
 	Run(p *properties.All, clientType ClientScope, sourceType SourceScope) error
 }
 
+// OptionsError is the error of an ingestion with options that don't apply to its client or its source.
+// It lists all the offending options, and is wrapped in a KClientArgs error. Use errors.As to retrieve it.
+type OptionsError struct {
+	// Client is the client of the ingestion.
+	Client ClientScope
+	// Source is the source of the ingestion.
+	Source SourceScope
+	// Options are the options that don't apply to the ingestion.
+	Options []FileOption
+}
+
+func (e *OptionsError) Error() string {
+	names := make([]string, 0, len(e.Options))
+	for _, o := range e.Options {
+		names = append(names, o.String())
+	}
+	return fmt.Sprintf("options [%s] are not valid for ingestion source type '%s' for client '%s'", strings.Join(names, ", "), e.Source, e.Client)
+}
+
+// validateOptions returns an *OptionsError wrapped in a KClientArgs error if any of the options doesn't apply to the
+// client or the source.
+func validateOptions(options []FileOption, clientType ClientScope, sourceType SourceScope) error {
+	var invalid []FileOption
+	for _, o := range options {
+		if o.ClientScopes()&clientType == 0 || o.SourceScopes()&sourceType == 0 {
+			invalid = append(invalid, o)
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	op := errors.OpFileIngest
+	if clientType&StreamingClient != 0 {
+		op = errors.OpIngestStream
+	}
+	return errors.E(op, errors.KClientArgs, &OptionsError{Client: clientType, Source: sourceType, Options: invalid}).SetNoRetry()
+}
+
 type option struct {
 	run          func(p *properties.All) error
 	clientScopes ClientScope
@@ -273,17 +318,85 @@ func IngestionMapping(mapping interface{}, format DataFormat) FileOption {
 	}
 }
 
+// AvroSchema ingests Avro data written with the record schema, given as JSON. An inline ingestion mapping is generated
+// from the fields of the schema, each field being ingested into the column with the same name, and the format is set
+// to AVRO.
+// Avro files and readers are validated while they are uploaded: the schema of the file must have every field of the
+// schema, with the same type, and the blocks of the file must be well-formed. Records are not decoded.
+func AvroSchema(schema string) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			return applyAvroSchema(p, schema)
+		},
+		clientScopes: QueuedClient | ManagedClient,
+		sourceScope:  FromFile | FromReader | FromBlob,
+		name:         "AvroSchema",
+	}
+}
+
+// AvroSchemaFetcher returns an Avro record schema as JSON, for example from a schema registry.
+type AvroSchemaFetcher func() (string, error)
+
+// AvroSchemaFrom is like AvroSchema, with the schema returned by fetch. fetch is called once per ingestion.
+func AvroSchemaFrom(fetch AvroSchemaFetcher) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			schema, err := fetch()
+			if err != nil {
+				return errors.ES(errors.OpFileIngest, errors.KClientArgs, "unable to fetch the Avro schema: %s", err).SetNoRetry()
+			}
+			return applyAvroSchema(p, schema)
+		},
+		clientScopes: QueuedClient | ManagedClient,
+		sourceScope:  FromFile | FromReader | FromBlob,
+		name:         "AvroSchemaFrom",
+	}
+}
+
+// applyAvroSchema sets the Avro schema of the properties, and the ingestion mapping generated from it.
+func applyAvroSchema(p *properties.All, schema string) error {
+	parsed, err := avro.ParseSchema(schema)
+	if err != nil {
+		return errors.ES(errors.OpFileIngest, errors.KClientArgs, "invalid Avro schema: %s", err).SetNoRetry()
+	}
+
+	mapping := make([]jsonColumnMapping, 0, len(parsed.Fields))
+	for _, f := range parsed.Fields {
+		path, err := json.Marshal(f.Name)
+		if err != nil {
+			return err
+		}
+		mapping = append(mapping, jsonColumnMapping{
+			Column:     f.Name,
+			Properties: map[string]string{"Path": "$[" + string(path) + "]"},
+		})
+	}
+
+	if err := IngestionMapping(mapping, AVRO).Run(p, QueuedClient, FromFile); err != nil {
+		return err
+	}
+	p.Source.AvroSchema = schema
+	return nil
+}
+
 // IngestionMappingRef provides the name of a pre-created mapping for the data being imported to the fields in the table.
 // For more details, see: https://docs.microsoft.com/azure/kusto/management/create-ingestion-mapping-command
 // The formatparameter will also automatically set the FileOption.Format option.
+// If format is DFUnknown, the format is left unchanged, and the kind of the mapping is inferred from the format set with
+// FileFormat, from the extension of the file, or from the content of readers.
+// The kind of the mapping must match the format of the data, and the ingestion is refused before any upload otherwise.
 func IngestionMappingRef(refName string, format DataFormat) FileOption {
 	return option{
 		run: func(p *properties.All) error {
+			p.Ingestion.Additional.IngestionMappingRef = refName
+			if format == DFUnknown {
+				return nil
+			}
+
 			kind := format.MappingKind()
 			if kind == DFUnknown {
 				return errors.ES(errors.OpUnknown, errors.KClientArgs, "IngestionMappingRef() option does not support EncodingType %v", format).SetNoRetry()
 			}
-			p.Ingestion.Additional.IngestionMappingRef = refName
 			p.Ingestion.Additional.IngestionMappingType = kind
 			p.Ingestion.Additional.Format = format
 			return nil
@@ -294,6 +407,46 @@ func IngestionMappingRef(refName string, format DataFormat) FileOption {
 	}
 }
 
+// completeMappingKind infers the kind of the ingestion mapping from the format of the data when it was not set, and
+// verifies that they match otherwise. The format is the one set by the options, or else the one of the extension of
+// the source file. The kind is left unset when the format is not known yet.
+func completeMappingKind(props *properties.All) error {
+	additional := &props.Ingestion.Additional
+	if additional.IngestionMappingRef == "" && additional.IngestionMapping == "" {
+		return nil
+	}
+
+	format := additional.Format
+	if format == DFUnknown && props.Source.OriginalSource != "" {
+		format = properties.DataFormatDiscovery(props.Source.OriginalSource)
+	}
+	if format == DFUnknown {
+		return nil
+	}
+
+	mapping := "inline ingestion mapping"
+	if additional.IngestionMappingRef != "" {
+		mapping = fmt.Sprintf("ingestion mapping %q", additional.IngestionMappingRef)
+	}
+
+	if format.MappingKind() == DFUnknown {
+		return errors.ES(errors.OpUnknown, errors.KClientArgs, "the %s cannot be used with the format %s, which does not support ingestion mappings",
+			mapping, format).SetNoRetry()
+	}
+
+	if additional.IngestionMappingType == DFUnknown {
+		additional.IngestionMappingType = format.MappingKind()
+		return nil
+	}
+
+	if additional.IngestionMappingType != format.MappingKind() {
+		return errors.ES(errors.OpUnknown, errors.KClientArgs,
+			"the %s is of kind %s, which does not match the format %s of the data (it needs a mapping of kind %s)",
+			mapping, additional.IngestionMappingType, format, format.MappingKind()).SetNoRetry()
+	}
+	return nil
+}
+
 // DeleteSource deletes the source file from when it has been uploaded to Kusto.
 func DeleteSource() FileOption {
 	return option{
@@ -348,6 +501,67 @@ func IfNotExists(ingestByTag string) FileOption {
 	}
 }
 
+// IngestByTags tags the ingested data with an ingest-by: tag for every value. The values can then be passed to
+// IfNotExists, to skip ingesting the same data again, or checked with azkustodata's Client.HasIngestByTag.
+// The tags are added to those set with Tags, which must come before this option as it replaces the tags.
+// For more information see: https://docs.microsoft.com/en-us/azure/kusto/management/extents-overview#ingest-by-extent-tags
+func IngestByTags(values ...string) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			tags := make([]string, 0, len(p.Ingestion.Additional.Tags)+len(values))
+			tags = append(tags, p.Ingestion.Additional.Tags...)
+			for _, v := range values {
+				if v == "" {
+					return errors.ES(errors.OpFileIngest, errors.KClientArgs, "IngestByTags values cannot be empty").SetNoRetry()
+				}
+				tags = append(tags, azkustodata.IngestByTagPrefix+strings.TrimPrefix(v, azkustodata.IngestByTagPrefix))
+			}
+			p.Ingestion.Additional.Tags = tags
+			return nil
+		},
+		sourceScope:  FromFile | FromReader | FromBlob,
+		clientScopes: QueuedClient | ManagedClient,
+		name:         "IngestByTags",
+	}
+}
+
+// RetryChunks makes FromFileSplit queue the chunks that fail again, up to retries times each: the chunks that fail to
+// be queued with an error that can be retried, when they are queued, and the chunks whose ingestion fails with a
+// status that can be retried, when the SplitResult is waited for.
+// Every chunk is tagged with an ingest-by: tag of its own, and ingested with IfNotExists on it, so that a chunk that
+// was ingested even though it failed isn't ingested twice when it is retried. The tag of a chunk is in its ChunkResult.
+// It can't be combined with IfNotExists. The other calls ignore it.
+func RetryChunks(retries int) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			if retries < 0 {
+				return errors.ES(errors.OpFileIngest, errors.KClientArgs, "RetryChunks() retries cannot be negative, got %d", retries).SetNoRetry()
+			}
+			p.Source.ChunkRetries = retries
+			return nil
+		},
+		// The chunks are ingested from readers, with the options of the call.
+		sourceScope:  FromFile | FromReader,
+		clientScopes: QueuedClient,
+		name:         "RetryChunks",
+	}
+}
+
+// chunkIngestByTag tags a chunk of FromFileSplit with an ingest-by: tag, and ingests it only if the tag doesn't exist.
+// It is added after the options of the call, so that it isn't replaced by them.
+func chunkIngestByTag(tag string) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			p.Ingestion.Additional.Tags = append(p.Ingestion.Additional.Tags, azkustodata.IngestByTagPrefix+tag)
+			p.Ingestion.Additional.IngestIfNotExists = tag
+			return nil
+		},
+		sourceScope:  FromFile | FromReader | FromBlob,
+		clientScopes: QueuedClient,
+		name:         "chunkIngestByTag",
+	}
+}
+
 // ReportResultToTable option requests that the ingestion status will be tracked in an Azure table.
 // Note using Table status reporting is not recommended for high capacity ingestions, as it could slow down the ingestion.
 // In such cases, it's recommended to enable it temporarily for debugging failed ingestions.
@@ -451,6 +665,9 @@ func FileFormat(et DataFormat) FileOption {
 }
 
 // ClientRequestId is an identifier for the ingestion, that can later be queried.
+// It is sent in the x-ms-client-request-id header of the streaming request, and reported with the activity id of the
+// request in the StatusRecord of the Result. When the service rejects the request, the ids are in the errors.HttpError
+// the error wraps.
 func ClientRequestId(clientRequestId string) FileOption {
 	return option{
 		run: func(p *properties.All) error {
@@ -463,6 +680,27 @@ func ClientRequestId(clientRequestId string) FileOption {
 	}
 }
 
+// WithHTTPHeader adds a custom header to the streaming request, such as the routing header of a gateway in front of a
+// private deployment. The header must pass azkustodata.ValidateHTTPHeader. The Managed client only sends it with
+// streaming ingestion, not when it falls back to queued ingestion.
+func WithHTTPHeader(key, value string) FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			if err := azkustodata.ValidateHTTPHeader(key, value); err != nil {
+				return errors.E(errors.OpFileIngest, errors.KClientArgs, err).SetNoRetry()
+			}
+			if p.Streaming.Headers == nil {
+				p.Streaming.Headers = http.Header{}
+			}
+			p.Streaming.Headers.Add(key, value)
+			return nil
+		},
+		sourceScope:  FromFile | FromReader | FromBlob,
+		clientScopes: StreamingClient | ManagedClient,
+		name:         "WithHTTPHeader",
+	}
+}
+
 // CompressionType sets the compression type of the data.
 // Use this if the file name does not expose the compression type.
 // This sets DontCompress to true for compressed data.
@@ -491,3 +729,21 @@ func RawDataSize(size int64) FileOption {
 		name:         "RawDataSize",
 	}
 }
+
+// ValidatePayload validates the structure of the data while it is being uploaded, so that a malformed payload fails
+// on the client as soon as the problem is read, with the offending record and line number, instead of failing later
+// in the service. CSV based formats are checked for a consistent amount of fields per record, JSON and MultiJSON for
+// well-formed JSON objects, and Avro for the header and the framing of the blocks of the file. Other formats and already
+// compressed payloads are not validated.
+// The errors of invalid CSV and JSON records wrap an *errors.RecordError, with the index and the line of the record.
+func ValidatePayload() FileOption {
+	return option{
+		run: func(p *properties.All) error {
+			p.Source.ValidatePayload = true
+			return nil
+		},
+		clientScopes: QueuedClient | StreamingClient | ManagedClient,
+		sourceScope:  FromFile | FromReader,
+		name:         "ValidatePayload",
+	}
+}
@@ -3,6 +3,7 @@ package azkustoingest
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"testing"
 
@@ -155,10 +156,10 @@ func TestFileFormatAndMapping(t *testing.T) {
 		err                 error
 	}{
 		{
-			desc:                "Reader defaults to csv",
+			desc:                "Reader format is detected from the content",
 			options:             []FileOption{},
 			source:              FromReader,
-			expectedFormat:      CSV,
+			expectedFormat:      DFUnknown,
 			expectedMappingType: 0,
 		},
 		{
@@ -198,9 +199,50 @@ func TestFileFormatAndMapping(t *testing.T) {
 			err: errors.ES(
 				errors.OpUnknown,
 				errors.KClientArgs,
-				"format and ingestion mapping type must match (hint: using ingestion mapping sets the format automatically)",
+				"the inline ingestion mapping is of kind json, which does not match the format avro of the data (it needs a mapping of kind avro)",
 			).SetNoRetry(),
 		},
+		{
+			desc:    "Test non-matching mapping ref",
+			options: []FileOption{IngestionMappingRef("mapping", JSON), FileFormat(CSV)},
+			source:  FromFile,
+			err: errors.ES(
+				errors.OpUnknown,
+				errors.KClientArgs,
+				`the ingestion mapping "mapping" is of kind json, which does not match the format csv of the data (it needs a mapping of kind csv)`,
+			).SetNoRetry(),
+		},
+		{
+			desc:    "Test mapping ref with a format without mappings",
+			options: []FileOption{IngestionMappingRef("mapping", DFUnknown), FileFormat(SStream)},
+			source:  FromFile,
+			err: errors.ES(
+				errors.OpUnknown,
+				errors.KClientArgs,
+				`the ingestion mapping "mapping" cannot be used with the format sstream, which does not support ingestion mappings`,
+			).SetNoRetry(),
+		},
+		{
+			desc:                "Test mapping kind inferred from the format",
+			options:             []FileOption{FileFormat(Parquet), IngestionMappingRef("mapping", DFUnknown)},
+			source:              FromFile,
+			expectedFormat:      Parquet,
+			expectedMappingType: Parquet,
+		},
+		{
+			desc:                "Test mapping kind inferred from the format set after",
+			options:             []FileOption{IngestionMappingRef("mapping", DFUnknown), FileFormat(TSV)},
+			source:              FromFile,
+			expectedFormat:      TSV,
+			expectedMappingType: CSV,
+		},
+		{
+			desc:                "Test mapping kind not inferred without a format",
+			options:             []FileOption{IngestionMappingRef("mapping", DFUnknown)},
+			source:              FromReader,
+			expectedFormat:      DFUnknown,
+			expectedMappingType: DFUnknown,
+		},
 		{
 			desc:                "Test multijson with default",
 			options:             []FileOption{IngestionMapping("mapping", MultiJSON)},
@@ -250,3 +292,150 @@ func TestFileFormatAndMapping(t *testing.T) {
 	}
 
 }
+
+func TestAvroSchema(t *testing.T) {
+	t.Parallel()
+
+	const schema = `{"type": "record", "name": "Event", "fields": [{"name": "Id", "type": "long"}, {"name": "Name", "type": ["null", "string"]}]}`
+
+	tests := []struct {
+		desc        string
+		option      FileOption
+		wantMapping string
+		wantErr     string
+	}{
+		{
+			desc:        "Schema",
+			option:      AvroSchema(schema),
+			wantMapping: `[{"column":"Id","Properties":{"Path":"$[\"Id\"]"}},{"column":"Name","Properties":{"Path":"$[\"Name\"]"}}]`,
+		},
+		{
+			desc:        "Fetched schema",
+			option:      AvroSchemaFrom(func() (string, error) { return schema, nil }),
+			wantMapping: `[{"column":"Id","Properties":{"Path":"$[\"Id\"]"}},{"column":"Name","Properties":{"Path":"$[\"Name\"]"}}]`,
+		},
+		{
+			desc:    "Fetch error",
+			option:  AvroSchemaFrom(func() (string, error) { return "", fmt.Errorf("registry unavailable") }),
+			wantErr: "unable to fetch the Avro schema: registry unavailable",
+		},
+		{
+			desc:    "Invalid schema",
+			option:  AvroSchema(`{"type": "enum", "name": "E", "symbols": ["A"]}`),
+			wantErr: `invalid Avro schema: the Avro schema must be a record, was "enum"`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			props := properties.All{}
+			err := test.option.Run(&props, QueuedClient, FromFile)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				assert.False(t, errors.Retry(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, AVRO, props.Ingestion.Additional.Format)
+			assert.Equal(t, AVRO, props.Ingestion.Additional.IngestionMappingType)
+			assert.Equal(t, test.wantMapping, props.Ingestion.Additional.IngestionMapping)
+			assert.Equal(t, schema, props.Source.AvroSchema)
+		})
+	}
+}
+
+func TestIngestByTags(t *testing.T) {
+	t.Parallel()
+
+	props := properties.All{}
+	for _, o := range []FileOption{Tags([]string{"drop-by:old"}), IngestByTags("a", "ingest-by:b"), IngestByTags("c")} {
+		require.NoError(t, o.Run(&props, QueuedClient, FromFile))
+	}
+	assert.Equal(t, []string{"drop-by:old", "ingest-by:a", "ingest-by:b", "ingest-by:c"}, props.Ingestion.Additional.Tags)
+
+	err := IngestByTags("").Run(&properties.All{}, QueuedClient, FromFile)
+	assert.Error(t, err)
+	assert.False(t, errors.Retry(err))
+}
+
+func TestMappingKindFromFileName(t *testing.T) {
+	t.Parallel()
+
+	props := properties.All{}
+	props.Ingestion.Additional.IngestionMappingRef = "mapping"
+	props.Source.OriginalSource = "/data/events.json.gz"
+	require.NoError(t, completeMappingKind(&props))
+	assert.Equal(t, JSON, props.Ingestion.Additional.IngestionMappingType)
+
+	props.Source.OriginalSource = "/data/events.csv"
+	props.Ingestion.Additional.IngestionMappingType = JSON
+	err := completeMappingKind(&props)
+	assert.EqualError(t, err, `Kind(KClientArgs): the ingestion mapping "mapping" is of kind json, which does not match the format csv of the data (it needs a mapping of kind csv)`)
+}
+
+func TestOptionsError(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+
+	queuedClient, err := newFromClient(client, &Ingestion{})
+	require.NoError(t, err)
+
+	streamingClient, err := newStreamingFromClient(client, &Ingestion{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		desc     string
+		ingestor Ingestor
+		options  []FileOption
+		client   ClientScope
+		source   SourceScope
+		invalid  []string
+	}{
+		{
+			desc:     "queued options for streaming ingestor",
+			ingestor: streamingClient,
+			options:  []FileOption{FlushImmediately(), FileFormat(CSV), Tags([]string{"a"}), DeleteSource()},
+			client:   StreamingClient,
+			source:   FromReader,
+			invalid:  []string{"FlushImmediately", "Tags", "DeleteSource"},
+		},
+		{
+			desc:     "streaming options for queued ingestor",
+			ingestor: queuedClient,
+			options:  []FileOption{ClientRequestId("1234"), Database("db"), DeleteSource()},
+			client:   QueuedClient,
+			source:   FromReader,
+			invalid:  []string{"ClientRequestId", "DeleteSource"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := test.ingestor.FromReader(context.Background(), bytes.NewReader([]byte{}), test.options...)
+
+			var optionsErr *OptionsError
+			require.ErrorAs(t, err, &optionsErr)
+			assert.Equal(t, test.client, optionsErr.Client)
+			assert.Equal(t, test.source, optionsErr.Source)
+
+			var names []string
+			for _, o := range optionsErr.Options {
+				names = append(names, o.String())
+			}
+			assert.Equal(t, test.invalid, names)
+
+			e, ok := errors.GetKustoError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.KClientArgs, e.Kind)
+			assert.False(t, errors.Retry(err))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package azkustoingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// defaultGlobConcurrency is the default number of files FromGlob ingests at the same time.
+const defaultGlobConcurrency = 8
+
+// FileResult is the outcome of the ingestion of one file of FromGlob.
+type FileResult struct {
+	// Path is the path of the file.
+	Path string
+	// Result is the result of the ingestion of the file, or nil if it failed.
+	Result *Result
+	// Err is the error that made the file fail, if any.
+	Err error
+}
+
+// GlobResult is the outcome of a FromGlob call.
+type GlobResult struct {
+	// Files are the files that matched, in lexical order.
+	// If the ingestion stopped early, the files that have neither a Result nor an Err were not ingested.
+	Files []FileResult
+}
+
+// Failed returns the files that failed to ingest.
+func (r *GlobResult) Failed() []FileResult {
+	var failed []FileResult
+	for _, f := range r.Files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// Wait waits for the ingestion of all the files, like Result.WaitWithOptions, and returns their status records in the
+// order of the files. Files that failed to be queued or weren't ingested have an empty status record.
+// The returned error combines the errors of all the files that did not succeed.
+func (r *GlobResult) Wait(ctx context.Context, options ...WaitOption) ([]StatusRecord, error) {
+	records := make([]StatusRecord, len(r.Files))
+	errs := make([]error, len(r.Files))
+
+	wg := sync.WaitGroup{}
+	for i, f := range r.Files {
+		if f.Result == nil {
+			errs[i] = f.Err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, result *Result) {
+			defer wg.Done()
+			records[i], errs[i] = result.WaitWithOptions(ctx, options...)
+		}(i, f.Result)
+	}
+	wg.Wait()
+
+	return records, errors.CombineErrors(errs...)
+}
+
+// FromGlob queues the local files that match a pattern for ingestion, every file like with FromFile. The pattern is
+// either a directory, whose regular files are all ingested, or a pattern of filepath.Match, such as "data/*.csv".
+// Subdirectories are not walked.
+//
+// Up to concurrency files are uploaded at the same time, a concurrency of 0 or less selects the default of 8.
+// A failed file doesn't stop the ingestion of the others, the outcome of every file is reported in the GlobResult.
+// The returned error combines the errors of all the failed files.
+func (i *Ingestion) FromGlob(ctx context.Context, pattern string, concurrency int, options ...FileOption) (*GlobResult, error) {
+	if concurrency <= 0 {
+		concurrency = defaultGlobConcurrency
+	}
+
+	paths, err := globFiles(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "no file matches %q", pattern).SetNoRetry()
+	}
+
+	result := &GlobResult{Files: make([]FileResult, len(paths))}
+	for n, path := range paths {
+		result.Files[n].Path = path
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for n := range result.Files {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(f *FileResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f.Result, f.Err = i.FromFile(ctx, f.Path, options...)
+		}(&result.Files[n])
+	}
+	wg.Wait()
+
+	errs := make([]error, 0, len(paths))
+	for _, f := range result.Files {
+		errs = append(errs, f.Err)
+	}
+	errs = append(errs, ctx.Err())
+	return result, errors.CombineErrors(errs...)
+}
+
+// globFiles returns the regular files that match a pattern, or that are in a directory, in lexical order.
+func globFiles(pattern string) ([]string, error) {
+	if stat, err := os.Stat(pattern); err == nil && stat.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "invalid pattern %q: %s", pattern, err).SetNoRetry()
+	}
+
+	paths := make([]string, 0, len(matches))
+	for _, m := range matches {
+		stat, err := os.Stat(m)
+		if err != nil {
+			return nil, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to stat file %s: %s", m, err).SetNoRetry()
+		}
+		if stat.Mode().IsRegular() {
+			paths = append(paths, m)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
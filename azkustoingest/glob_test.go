@@ -0,0 +1,93 @@
+package azkustoingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromGlob(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.csv", "b.csv", "c.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("1,a\n"), 0o600))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub.csv"), 0o700))
+
+	tests := []struct {
+		name    string
+		pattern string
+		fail    func(path string) bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "directory", pattern: dir, want: []string{"a.csv", "b.csv", "c.json"}},
+		{name: "pattern", pattern: filepath.Join(dir, "*.csv"), want: []string{"a.csv", "b.csv"}},
+		{
+			name:    "failed file",
+			pattern: dir,
+			fail:    func(path string) bool { return strings.HasSuffix(path, "b.csv") },
+			want:    []string{"a.csv", "c.json"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &splitRecorder{fail: test.fail}
+			ingestion := newSplitIngestion(t, rec)
+
+			result, err := ingestion.FromGlob(context.Background(), test.pattern, 2, FileFormat(CSV))
+			if test.wantErr {
+				assert.Error(t, err)
+				require.Len(t, result.Failed(), 1)
+				assert.Equal(t, filepath.Join(dir, "b.csv"), result.Failed()[0].Path)
+				assert.Nil(t, result.Failed()[0].Result)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, result.Failed())
+			}
+
+			sort.Strings(rec.local)
+			want := make([]string, len(test.want))
+			for i, name := range test.want {
+				want[i] = filepath.Join(dir, name)
+			}
+			assert.Equal(t, want, rec.local)
+
+			for _, f := range result.Files {
+				assert.Equal(t, f.Err == nil, f.Result != nil, f.Path)
+			}
+			_, err = result.Wait(context.Background())
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestFromGlobErrors(t *testing.T) {
+	t.Parallel()
+
+	ingestion := newSplitIngestion(t, &splitRecorder{})
+
+	_, err := ingestion.FromGlob(context.Background(), filepath.Join(t.TempDir(), "*.csv"), 0)
+	assert.ErrorContains(t, err, "no file matches")
+	_, err = ingestion.FromGlob(context.Background(), "[", 0)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.csv"), []byte("1,a\n"), 0o600))
+	_, err = ingestion.FromGlob(ctx, dir, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
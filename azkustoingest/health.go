@@ -0,0 +1,106 @@
+package azkustoingest
+
+import (
+	"context"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+)
+
+// The names of the checks of the Healthy methods, in addition to the ones of azkustodata.Client.Ping.
+const (
+	// HealthCheckResources reads the ingestion resources: the storage containers and queues of the ingestion service.
+	HealthCheckResources = "ingestion resources"
+	// HealthCheckAuthContext reads the authorization context that queued ingestions are sent with.
+	HealthCheckAuthContext = "authorization context"
+)
+
+// pinger is implemented by azkustodata.Client.
+type pinger interface {
+	Ping(ctx context.Context) azkustodata.Health
+}
+
+// Healthy checks that data can be queued, for readiness and liveness probes: it acquires a token for the ingestion
+// endpoint of the cluster, and reads the ingestion resources and the authorization context, which are cached by the
+// client. It times out after azkustodata.DefaultPingTimeout unless ctx is done earlier.
+func (i *Ingestion) Healthy(ctx context.Context) azkustodata.Health {
+	ctx, cancel := context.WithTimeout(ctx, azkustodata.DefaultPingTimeout)
+	defer cancel()
+
+	health := azkustodata.Health{}
+	endpoint := i.client.Endpoint()
+	health.Check(azkustodata.HealthCheckToken, endpoint, func() error {
+		return acquireToken(ctx, i.client)
+	})
+	health.Check(HealthCheckResources, endpoint, func() error {
+		// Fetch the resources with ctx if needed, as the getters retry for longer than the timeout.
+		if err := i.mgr.RefreshIfStale(ctx); err != nil {
+			return errors.E(errors.OpFileIngest, errors.KBlobstore, err)
+		}
+		containers, err := i.mgr.GetRankedStorageContainers()
+		if err != nil {
+			return errors.E(errors.OpFileIngest, errors.KBlobstore, err)
+		}
+		queues, err := i.mgr.GetRankedStorageQueues()
+		if err != nil {
+			return errors.E(errors.OpFileIngest, errors.KBlobstore, err)
+		}
+		if len(containers) == 0 || len(queues) == 0 {
+			return errors.ES(errors.OpFileIngest, errors.KBlobstore, "the ingestion resources have %d storage containers and %d queues, expected at least one of each",
+				len(containers), len(queues))
+		}
+		return nil
+	})
+	health.Check(HealthCheckAuthContext, endpoint, func() error {
+		_, err := i.mgr.AuthContext(ctx)
+		return err
+	})
+	return health
+}
+
+// Healthy checks that data can be streamed, for readiness and liveness probes, with azkustodata.Client.Ping on the
+// engine of the cluster. It times out after azkustodata.DefaultPingTimeout unless ctx is done earlier.
+func (i *Streaming) Healthy(ctx context.Context) azkustodata.Health {
+	if p, ok := i.client.(pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, azkustodata.DefaultPingTimeout)
+	defer cancel()
+
+	health := azkustodata.Health{}
+	health.Check(azkustodata.HealthCheckToken, i.client.Endpoint(), func() error {
+		return acquireToken(ctx, i.client)
+	})
+	health.Check(azkustodata.HealthCheckQuery, i.client.Endpoint(), func() error {
+		_, err := i.client.Query(ctx, "", kql.New("print 1"), azkustodata.QueryConsistency(azkustodata.WeakConsistency))
+		return err
+	})
+	return health
+}
+
+// Healthy checks that data can be both queued and streamed, with the checks of Ingestion.Healthy, then the ones of
+// Streaming.Healthy.
+func (m *Managed) Healthy(ctx context.Context) azkustodata.Health {
+	health := m.queued.Healthy(ctx)
+	if health.Healthy() {
+		health.Checks = append(health.Checks, m.streaming.Healthy(ctx).Checks...)
+	}
+	return health
+}
+
+// acquireToken acquires a token for the endpoint of client, if it requires one.
+func acquireToken(ctx context.Context, client QueryClient) error {
+	tkp := client.Auth().TokenProvider
+	if tkp == nil || !tkp.AuthorizationRequired() {
+		return nil
+	}
+	if http := client.HttpClient(); http != nil {
+		tkp.SetHttp(http)
+	}
+	if _, _, err := tkp.AcquireToken(ctx); err != nil {
+		return errors.ES(errors.OpFileIngest, errors.KInternal, "could not acquire a token: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package azkustoingest
+
+import (
+	"context"
+	goErrors "errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resourcesMockClient is a mock client whose ingestion resources have a storage container and a queue.
+func resourcesMockClient() mockClient {
+	client := newMockClient()
+	client.onMgmt = func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+		if query.String() != ".get ingestion resources" {
+			return nil, nil
+		}
+		return v1.NewDataset(ctx, errors.OpMgmt, v1.V1{Tables: []v1.RawTable{{
+			TableName: "Table_0",
+			Columns: []v1.RawColumn{
+				{ColumnName: "ResourceTypeName", ColumnType: string(types.String)},
+				{ColumnName: "StorageRoot", ColumnType: string(types.String)},
+			},
+			Rows: []v1.RawRow{
+				{Row: []interface{}{"TempStorage", "https://account.blob.core.windows.net/container?sas"}},
+				{Row: []interface{}{"SecuredReadyForAggregationQueue", "https://account.queue.core.windows.net/queue?sas"}},
+			},
+		}}})
+	}
+	return client
+}
+
+// checkNames returns the names of the checks of health.
+func checkNames(health azkustodata.Health) []string {
+	names := make([]string, len(health.Checks))
+	for i, c := range health.Checks {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func TestIngestionHealthy(t *testing.T) {
+	t.Parallel()
+
+	ingestion, err := newFromClient(resourcesMockClient(), &Ingestion{})
+	require.NoError(t, err)
+	defer ingestion.Close()
+
+	health := ingestion.Healthy(context.Background())
+	require.NoError(t, health.Err())
+	assert.Equal(t, []string{azkustodata.HealthCheckToken, HealthCheckResources, HealthCheckAuthContext}, checkNames(health))
+	assert.Equal(t, "localhost", health.Checks[0].Endpoint)
+}
+
+func TestIngestionUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	client.onMgmt = func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+		return nil, goErrors.New("forbidden")
+	}
+	ingestion, err := newFromClient(client, &Ingestion{})
+	require.NoError(t, err)
+	defer ingestion.Close()
+
+	start := time.Now()
+	health := ingestion.Healthy(context.Background())
+	assert.Less(t, time.Since(start), azkustodata.DefaultPingTimeout, "the resources are fetched without the retries of the getters")
+	assert.False(t, health.Healthy())
+	assert.ErrorContains(t, health.Err(), "forbidden")
+	assert.Equal(t, []string{azkustodata.HealthCheckToken, HealthCheckResources}, checkNames(health), "the checks stop at the first failure")
+}
+
+func TestStreamingAndManagedHealthy(t *testing.T) {
+	t.Parallel()
+
+	engine := newMockClient()
+	engine.onIterativeQuery = func(ctx context.Context, db string, q azkustodata.Statement, options ...azkustodata.QueryOption) (query.IterativeDataset, error) {
+		assert.Equal(t, "print 1", q.String())
+		return nil, goErrors.New("the engine is unreachable")
+	}
+	streaming, err := newStreamingFromClient(engine, &Ingestion{})
+	require.NoError(t, err)
+
+	health := streaming.Healthy(context.Background())
+	assert.ErrorContains(t, health.Err(), "the engine is unreachable")
+	assert.Equal(t, []string{azkustodata.HealthCheckToken, azkustodata.HealthCheckQuery}, checkNames(health))
+
+	queued, err := newFromClient(resourcesMockClient(), &Ingestion{})
+	require.NoError(t, err)
+	managed := &Managed{queued: queued, streaming: streaming}
+	defer managed.Close()
+
+	health = managed.Healthy(context.Background())
+	assert.ErrorContains(t, health.Err(), "the engine is unreachable")
+	assert.Equal(t, []string{azkustodata.HealthCheckToken, HealthCheckResources, HealthCheckAuthContext, azkustodata.HealthCheckToken, azkustodata.HealthCheckQuery},
+		checkNames(health))
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustodata"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/queued"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/sniff"
 	"github.com/google/uuid"
 	"io"
+	"sync"
 )
 
 type Ingestor interface {
@@ -19,12 +22,18 @@ type Ingestor interface {
 }
 
 // Ingestion provides data ingestion from external sources into Kusto.
+// An Ingestion is safe for concurrent use by multiple goroutines, and should be shared instead of created per call.
 type Ingestion struct {
 	db    string
 	table string
 
 	client QueryClient
 	mgr    *resources.Manager
+	// engine is the client of the engine of the cluster, used to read the batching policies and the schemas of tables.
+	engine   *engineClient
+	batching *batchingPolicyCache
+	// tableColumns caches the columns of the tables, used by AutoMapping.
+	tableColumns *tableColumnsCache
 
 	fs queued.Queued
 
@@ -35,12 +44,30 @@ type Ingestion struct {
 	customIngestConnectionString *azkustodata.ConnectionStringBuilder
 	applicationForTracing        string
 	clientVersionForTracing      string
+	statusBackend                StatusBackend
+	recordTransform              RecordTransform
+	// defaultOptions are applied to every ingestion, see WithDefaultOptions.
+	defaultOptions []FileOption
+
+	// withoutCompression and compressionLevel are set by WithoutCompression and WithCompressionLevel.
+	withoutCompression  bool
+	compressionLevel    int
+	compressionLevelSet bool
 }
 
 // New is a constructor for Ingestion.
 func New(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*Ingestion, error) {
 	i := getOptions(options)
+	if err := validateDefaultOptions(i.defaultOptions, QueuedClient); err != nil {
+		return nil, err
+	}
+	if err := validateCompression(i); err != nil {
+		return nil, err
+	}
+	return newQueued(kcsb, i)
+}
 
+func newQueued(kcsb *azkustodata.ConnectionStringBuilder, i *Ingestion) (*Ingestion, error) {
 	if !i.withoutEndpointCorrection {
 		newKcsb := *kcsb
 		newKcsb.DataSource = addIngestPrefix(newKcsb.DataSource)
@@ -55,9 +82,58 @@ func New(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*Ingesti
 		return nil, err
 	}
 
+	engineKcsb := *kcsb
+	engineKcsb.DataSource = removeIngestPrefix(engineKcsb.DataSource)
+	i.engine = &engineClient{kcsb: &engineKcsb}
+
 	return newFromClient(client, i)
 }
 
+// engineClient is the client of the engine of the cluster. It is only created when it's first used, as most
+// ingestions don't need it.
+type engineClient struct {
+	kcsb *azkustodata.ConnectionStringBuilder
+
+	mu     sync.Mutex
+	client QueryClient
+	closed bool
+}
+
+// get returns the client of the engine, creating it if needed. It returns nil if there is no engine.
+func (e *engineClient) get() (QueryClient, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "the client is closed").SetNoRetry()
+	}
+	if e.client == nil && e.kcsb != nil {
+		client, err := azkustodata.New(e.kcsb)
+		if err != nil {
+			return nil, err
+		}
+		e.client = client
+	}
+	return e.client, nil
+}
+
+func (e *engineClient) Close() error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	if e.client == nil {
+		return nil
+	}
+	return e.client.Close()
+}
+
 func newFromClient(client QueryClient, i *Ingestion) (*Ingestion, error) {
 	mgr, err := resources.New(client)
 	if err != nil {
@@ -67,6 +143,8 @@ func newFromClient(client QueryClient, i *Ingestion) (*Ingestion, error) {
 
 	i.client = client
 	i.mgr = mgr
+	i.batching = newBatchingPolicyCache(i.engine)
+	i.tableColumns = newTableColumnsCache(i.engine)
 
 	fs, err := queued.New(i.db, i.table, mgr, client.HttpClient(), i.applicationForTracing, i.clientVersionForTracing, queued.WithStaticBuffer(i.bufferSize, i.maxBuffers))
 	if err != nil {
@@ -82,6 +160,7 @@ func newFromClient(client QueryClient, i *Ingestion) (*Ingestion, error) {
 
 func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption, props properties.All, source SourceScope) (*Result, properties.All, error) {
 	result := newResult()
+	result.backend = i.statusBackend
 
 	auth, err := i.mgr.AuthContext(ctx)
 	if err != nil {
@@ -90,22 +169,21 @@ func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption,
 
 	props.Ingestion.Additional.AuthContext = auth
 
+	if err := validateOptions(options, QueuedClient, source); err != nil {
+		return nil, properties.All{}, err
+	}
 	for _, o := range options {
 		if err := o.Run(&props, QueuedClient, source); err != nil {
 			return nil, properties.All{}, err
 		}
 	}
 
-	if source == FromReader && props.Ingestion.Additional.Format == DFUnknown {
-		props.Ingestion.Additional.Format = CSV
+	if err := completeMappingKind(&props); err != nil {
+		return nil, properties.All{}, err
 	}
 
-	if props.Ingestion.Additional.IngestionMappingType != DFUnknown && props.Ingestion.Additional.Format.MappingKind() != props.Ingestion.Additional.IngestionMappingType {
-		return nil, properties.All{}, errors.ES(
-			errors.OpUnknown,
-			errors.KClientArgs,
-			"format and ingestion mapping type must match (hint: using ingestion mapping sets the format automatically)",
-		).SetNoRetry()
+	if i.statusBackend != nil && props.Source.ID == uuid.Nil {
+		props.Source.ID = uuid.New()
 	}
 
 	if props.Ingestion.ReportLevel != properties.None {
@@ -137,9 +215,18 @@ func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption,
 // FromFile allows uploading a data file for Kusto from either a local path or a blobstore URI path.
 // This method is thread-safe.
 func (i *Ingestion) FromFile(ctx context.Context, fPath string, options ...FileOption) (*Result, error) {
+	options, _ = withDefaults(i.defaultOptions, options, fileSource(fPath))
 	return i.fromFile(ctx, fPath, options, i.newProp())
 }
 
+// fileSource returns the source scope of a local path or a blob URI.
+func fileSource(fPath string) SourceScope {
+	if local, err := queued.IsLocalPath(fPath); err == nil && !local {
+		return FromBlob
+	}
+	return FromFile
+}
+
 // fromFile is an internal function to allow managed streaming to pass a properties object to the ingestion.
 func (i *Ingestion) fromFile(ctx context.Context, fPath string, options []FileOption, props properties.All) (*Result, error) {
 	local, err := queued.IsLocalPath(fPath)
@@ -159,6 +246,10 @@ func (i *Ingestion) fromFile(ctx context.Context, fPath string, options []FileOp
 	if err != nil {
 		return nil, err
 	}
+	if err := i.applyAutoMapping(ctx, &props, fPath); err != nil {
+		return nil, err
+	}
+	result.putProps(props)
 
 	result.record.IngestionSourcePath = fPath
 
@@ -172,45 +263,79 @@ func (i *Ingestion) fromFile(ctx context.Context, fPath string, options []FileOp
 		return nil, err
 	}
 
+	result.putBatching(i.batching, props)
 	result.putQueued(i.mgr)
+	result.report(ctx)
+	log.Writef(log.EventIngest, "queued %s for ingestion into %s.%s", result.record.IngestionSourcePath,
+		props.Ingestion.DatabaseName, props.Ingestion.TableName)
 	return result, nil
 }
 
 // FromReader allows uploading a data file for Kusto from an io.Reader. The content is uploaded to Blobstore and
 // ingested after all data in the reader is processed. Content should not use compression as the content will be
 // compressed with gzip. This method is thread-safe.
+// If no format is set with FileFormat or an ingestion mapping, it is detected from the first KB of the content, and an
+// error with the best guess is returned if it can't be detected with confidence.
 func (i *Ingestion) FromReader(ctx context.Context, reader io.Reader, options ...FileOption) (*Result, error) {
-	return i.fromReader(ctx, reader, options, i.newProp())
+	options, _ = withDefaults(i.defaultOptions, options, FromReader)
+	return i.fromReader(ctx, reader, options, i.newProp(), i.recordTransform)
 }
 
 // fromReader is an internal function to allow managed streaming to pass a properties object to the ingestion.
-func (i *Ingestion) fromReader(ctx context.Context, reader io.Reader, options []FileOption, props properties.All) (*Result, error) {
+// The records are transformed with transform, if it isn't nil.
+func (i *Ingestion) fromReader(ctx context.Context, reader io.Reader, options []FileOption, props properties.All, transform RecordTransform) (*Result, error) {
 	result, props, err := i.prepForIngestion(ctx, options, props, FromReader)
 	if err != nil {
 		return nil, err
 	}
 
-	path, err := i.fs.Reader(ctx, reader, props)
+	reader, err = sniff.Format(reader, &props)
 	if err != nil {
 		return nil, err
 	}
+	if err := i.applyAutoMapping(ctx, &props, ""); err != nil {
+		return nil, err
+	}
+	if err := completeMappingKind(&props); err != nil {
+		return nil, err
+	}
+	reader, transformer, err := transformRecords(reader, props, transform)
+	if err != nil {
+		return nil, err
+	}
+	defer transformer.Close()
+	result.putProps(props)
+
+	path, err := i.fs.Reader(ctx, reader, props)
+	if err != nil {
+		return nil, transformer.failure(err)
+	}
 
 	result.record.IngestionSourcePath = path
+	result.putBatching(i.batching, props)
 	result.putQueued(i.mgr)
+	result.report(ctx)
+	log.Writef(log.EventIngest, "queued %s for ingestion into %s.%s", result.record.IngestionSourcePath,
+		props.Ingestion.DatabaseName, props.Ingestion.TableName)
 	return result, nil
 }
 
 func (i *Ingestion) newProp() properties.All {
-	return properties.All{
+	props := properties.All{
 		Ingestion: properties.Ingestion{
 			DatabaseName: i.db,
 			TableName:    i.table,
 		},
 	}
+	setCompression(&props, i.withoutCompression, i.compressionLevel)
+	return props
 }
 
 func (i *Ingestion) Close() error {
 	i.mgr.Close()
+	if err := i.engine.Close(); err != nil {
+		return err
+	}
 	err := i.client.Close()
 	if err != nil {
 		return err
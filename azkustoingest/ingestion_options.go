@@ -2,6 +2,9 @@ package azkustoingest
 
 import (
 	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/gzip"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"net"
 	"strings"
 )
@@ -50,6 +53,48 @@ func WithCustomIngestConnectionString(kcsb *azkustodata.ConnectionStringBuilder)
 	}
 }
 
+// WithoutCompression disables the gzip compression of the payloads that are sent uncompressed otherwise, for the
+// environments where CPU is scarcer than bandwidth, such as small edge devices. Streaming ingestion sends them as is,
+// and queued ingestion uploads them as is, which uses more bandwidth and storage. Payloads that are already compressed,
+// or of compressed formats such as Parquet, are sent as they were before.
+// It only affects the ingestion payloads: the bodies of query and management requests are never compressed.
+func WithoutCompression() Option {
+	return func(s *Ingestion) {
+		s.withoutCompression = true
+	}
+}
+
+// WithCompressionLevel sets the level of the gzip compression of the payloads, from gzip.HuffmanOnly and
+// gzip.BestSpeed, which use the least CPU, to gzip.BestCompression. The default is gzip.DefaultCompression.
+// gzip.NoCompression is not valid, use WithoutCompression instead.
+// Like WithoutCompression, it only affects the ingestion payloads, as query and management requests are never compressed.
+func WithCompressionLevel(level int) Option {
+	return func(s *Ingestion) {
+		s.compressionLevel = level
+		s.compressionLevelSet = true
+	}
+}
+
+// validateCompression returns a KClientArgs error if the compression options of the client are not valid.
+func validateCompression(i *Ingestion) error {
+	if !i.compressionLevelSet {
+		return nil
+	}
+	if i.compressionLevel == 0 || !gzip.ValidLevel(i.compressionLevel) {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "WithCompressionLevel() level %d is not a valid gzip level", i.compressionLevel).SetNoRetry()
+	}
+	if i.withoutCompression {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "WithCompressionLevel() and WithoutCompression() can't be used together").SetNoRetry()
+	}
+	return nil
+}
+
+// setCompression sets the compression options of the client in props.
+func setCompression(props *properties.All, withoutCompression bool, level int) {
+	props.Source.NoCompression = withoutCompression
+	props.Source.CompressionLevel = level
+}
+
 func getOptions(options []Option) *Ingestion {
 	s := &Ingestion{}
 	for _, o := range options {
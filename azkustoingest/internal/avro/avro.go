@@ -0,0 +1,246 @@
+// Package avro reads the parts of Apache Avro needed to ingest Avro object container files: record schemas, and the
+// header and block framing of the container files. Records themselves are not decoded.
+// See https://avro.apache.org/docs/current/specification/
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Magic is the first bytes of an Avro object container file.
+var Magic = []byte{'O', 'b', 'j', 1}
+
+// SyncSize is the size of the sync marker that ends the header and every block of a container file.
+const SyncSize = 16
+
+// ErrShort is returned when decoding needs more bytes than given.
+var ErrShort = errors.New("avro: short buffer")
+
+// Codecs are the compression codecs of the blocks that Kusto can ingest.
+var Codecs = map[string]bool{"": true, "null": true, "deflate": true, "snappy": true}
+
+// Schema is an Avro record schema.
+type Schema struct {
+	Name   string
+	Fields []Field
+}
+
+// Field is a field of a record schema.
+type Field struct {
+	Name string
+	// Type is the schema of the field, as JSON.
+	Type json.RawMessage
+}
+
+// ParseSchema parses a record schema, as JSON.
+func ParseSchema(s string) (*Schema, error) {
+	var raw struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("the Avro schema is not a valid JSON object: %s", err)
+	}
+	if raw.Type != "record" {
+		return nil, fmt.Errorf("the Avro schema must be a record, was %q", raw.Type)
+	}
+	if len(raw.Fields) == 0 {
+		return nil, fmt.Errorf("the Avro schema of record %q has no fields", raw.Name)
+	}
+
+	schema := &Schema{Name: raw.Name, Fields: make([]Field, 0, len(raw.Fields))}
+	seen := make(map[string]bool, len(raw.Fields))
+	for i, f := range raw.Fields {
+		switch {
+		case f.Name == "":
+			return nil, fmt.Errorf("field %d of the Avro schema has no name", i)
+		case len(f.Type) == 0:
+			return nil, fmt.Errorf("field %q of the Avro schema has no type", f.Name)
+		case seen[f.Name]:
+			return nil, fmt.Errorf("field %q of the Avro schema is duplicated", f.Name)
+		}
+		seen[f.Name] = true
+		schema.Fields = append(schema.Fields, Field{Name: f.Name, Type: f.Type})
+	}
+	return schema, nil
+}
+
+// CheckWriter verifies that records written with the writer schema can be read with s: every field of s must be in
+// the writer schema, with the same type. The fields of the writer schema that aren't in s are ignored.
+func (s *Schema) CheckWriter(writer *Schema) error {
+	types := make(map[string]json.RawMessage, len(writer.Fields))
+	for _, f := range writer.Fields {
+		types[f.Name] = f.Type
+	}
+
+	for _, f := range s.Fields {
+		t, ok := types[f.Name]
+		if !ok {
+			return fmt.Errorf("field %q is missing from the schema of the data", f.Name)
+		}
+		if !sameType(f.Type, t) {
+			return fmt.Errorf("field %q has type %s in the schema of the data, expected %s", f.Name, compact(t), compact(f.Type))
+		}
+	}
+	return nil
+}
+
+// sameType compares two type schemas, ignoring their documentation.
+func sameType(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(stripDocs(x), stripDocs(y))
+}
+
+// stripDocs removes the attributes that don't change how values are read.
+func stripDocs(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		delete(v, "doc")
+		delete(v, "aliases")
+		delete(v, "default")
+		delete(v, "order")
+		for k, e := range v {
+			v[k] = stripDocs(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stripDocs(e)
+		}
+	}
+	return v
+}
+
+func compact(b json.RawMessage) string {
+	buf := bytes.Buffer{}
+	if json.Compact(&buf, b) != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// ReadLong decodes a zig-zag encoded long from the start of b, and returns it with the number of bytes read.
+func ReadLong(b []byte) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(b); i++ {
+		if i == 10 {
+			return 0, 0, errors.New("avro: long is too long")
+		}
+		u |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return int64(u>>1) ^ -int64(u&1), i + 1, nil
+		}
+	}
+	return 0, 0, ErrShort
+}
+
+// Header is the header of a container file.
+type Header struct {
+	Meta map[string][]byte
+	Sync [SyncSize]byte
+}
+
+// ReadHeader decodes the header of a container file from the start of b, and returns it with the number of bytes read.
+// It returns ErrShort if b doesn't hold the whole header. The values of the metadata are slices of b.
+func ReadHeader(b []byte) (*Header, int, error) {
+	if len(b) < len(Magic) {
+		if !bytes.HasPrefix(Magic, b) {
+			return nil, 0, errors.New("not an Avro object container file")
+		}
+		return nil, 0, ErrShort
+	}
+	if !bytes.Equal(b[:len(Magic)], Magic) {
+		return nil, 0, errors.New("not an Avro object container file")
+	}
+
+	h := &Header{Meta: map[string][]byte{}}
+	pos := len(Magic)
+	readBytes := func() ([]byte, error) {
+		size, n, err := ReadLong(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("invalid length %d in the Avro header", size)
+		}
+		if int64(len(b)-pos-n) < size {
+			return nil, ErrShort
+		}
+		pos += n
+		v := b[pos : pos+int(size)]
+		pos += int(size)
+		return v, nil
+	}
+
+	for {
+		count, n, err := ReadLong(b[pos:])
+		if err != nil {
+			return nil, 0, err
+		}
+		pos += n
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			// A negative count is followed by the size of the block in bytes.
+			count = -count
+			if _, n, err = ReadLong(b[pos:]); err != nil {
+				return nil, 0, err
+			}
+			pos += n
+		}
+		for ; count > 0; count-- {
+			key, err := readBytes()
+			if err != nil {
+				return nil, 0, err
+			}
+			value, err := readBytes()
+			if err != nil {
+				return nil, 0, err
+			}
+			h.Meta[string(key)] = value
+		}
+	}
+
+	if len(b)-pos < SyncSize {
+		return nil, 0, ErrShort
+	}
+	copy(h.Sync[:], b[pos:pos+SyncSize])
+	return h, pos + SyncSize, nil
+}
+
+// AppendLong appends the zig-zag encoding of v to b.
+func AppendLong(b []byte, v int64) []byte {
+	u := uint64(v<<1) ^ uint64(v>>63)
+	for u >= 0x80 {
+		b = append(b, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(b, byte(u))
+}
+
+// AppendHeader appends the encoding of the header of a container file to b.
+func AppendHeader(b []byte, h *Header) []byte {
+	b = append(b, Magic...)
+	if len(h.Meta) > 0 {
+		b = AppendLong(b, int64(len(h.Meta)))
+		for k, v := range h.Meta {
+			b = AppendLong(b, int64(len(k)))
+			b = append(b, k...)
+			b = AppendLong(b, int64(len(v)))
+			b = append(b, v...)
+		}
+	}
+	b = AppendLong(b, 0)
+	return append(b, h.Sync[:]...)
+}
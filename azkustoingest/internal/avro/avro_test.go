@@ -0,0 +1,155 @@
+package avro
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const personSchema = `{"type": "record", "name": "Person", "fields": [
+	{"name": "name", "type": "string", "doc": "The name"},
+	{"name": "age", "type": ["null", "int"]},
+	{"name": "tags", "type": {"type": "array", "items": "string"}}
+]}`
+
+func TestParseSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		schema  string
+		want    []string
+		wantErr string
+	}{
+		{desc: "record", schema: personSchema, want: []string{"name", "age", "tags"}},
+		{desc: "not JSON", schema: `{"type": `, wantErr: "not a valid JSON object"},
+		{desc: "not a record", schema: `{"type": "enum", "name": "E", "symbols": ["A"]}`, wantErr: `must be a record, was "enum"`},
+		{desc: "primitive", schema: `"string"`, wantErr: "not a valid JSON object"},
+		{desc: "no fields", schema: `{"type": "record", "name": "R", "fields": []}`, wantErr: `record "R" has no fields`},
+		{desc: "field without a name", schema: `{"type": "record", "name": "R", "fields": [{"type": "int"}]}`, wantErr: "field 0 of the Avro schema has no name"},
+		{desc: "field without a type", schema: `{"type": "record", "name": "R", "fields": [{"name": "a"}]}`, wantErr: `field "a" of the Avro schema has no type`},
+		{desc: "duplicated field", schema: `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}, {"name": "a", "type": "int"}]}`, wantErr: `field "a" of the Avro schema is duplicated`},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseSchema(test.schema)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(got.Fields))
+			for _, f := range got.Fields {
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, test.want, names)
+		})
+	}
+}
+
+func TestCheckWriter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		writer  string
+		wantErr string
+	}{
+		{desc: "same schema", writer: personSchema},
+		{
+			desc: "extra fields, other order and documentation",
+			writer: `{"type": "record", "name": "Person", "fields": [
+				{"name": "tags", "type": {"items": "string", "type": "array", "doc": "Tags"}},
+				{"name": "extra", "type": "long"},
+				{"name": "age", "type": ["null", "int"], "default": null},
+				{"name": "name", "type": "string"}
+			]}`,
+		},
+		{
+			desc:    "missing field",
+			writer:  `{"type": "record", "name": "Person", "fields": [{"name": "name", "type": "string"}, {"name": "age", "type": ["null", "int"]}]}`,
+			wantErr: `field "tags" is missing from the schema of the data`,
+		},
+		{
+			desc: "different type",
+			writer: `{"type": "record", "name": "Person", "fields": [
+				{"name": "name", "type": "string"},
+				{"name": "age", "type": "int"},
+				{"name": "tags", "type": {"type": "array", "items": "string"}}
+			]}`,
+			wantErr: `field "age" has type "int" in the schema of the data, expected ["null","int"]`,
+		},
+	}
+
+	reader, err := ParseSchema(personSchema)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			writer, err := ParseSchema(test.writer)
+			require.NoError(t, err)
+
+			err = reader.CheckWriter(writer)
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLong(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []int64{0, -1, 1, 63, -64, 64, 1 << 40, math.MaxInt64, math.MinInt64} {
+		b := AppendLong(nil, v)
+		got, n, err := ReadLong(b)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+		assert.Equal(t, len(b), n)
+
+		_, _, err = ReadLong(b[:len(b)-1])
+		assert.ErrorIs(t, err, ErrShort)
+	}
+
+	assert.Equal(t, []byte{0x02}, AppendLong(nil, 1))
+	assert.Equal(t, []byte{0x03}, AppendLong(nil, -2))
+	assert.Equal(t, []byte{0x80, 0x01}, AppendLong(nil, 64))
+}
+
+func TestReadHeader(t *testing.T) {
+	t.Parallel()
+
+	want := &Header{
+		Meta: map[string][]byte{"avro.schema": []byte(personSchema), "avro.codec": []byte("null")},
+		Sync: [SyncSize]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+	b := AppendHeader(nil, want)
+	b = append(b, "block"...)
+
+	got, n, err := ReadHeader(b)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, len(b)-len("block"), n)
+
+	for i := 0; i < n; i++ {
+		_, _, err := ReadHeader(b[:i])
+		require.ErrorIs(t, err, ErrShort, "prefix of %d bytes", i)
+	}
+
+	_, _, err = ReadHeader([]byte("PAR1"))
+	assert.EqualError(t, err, "not an Avro object container file")
+	_, _, err = ReadHeader([]byte("O"))
+	assert.ErrorIs(t, err, ErrShort)
+}
@@ -4,34 +4,82 @@ package gzip
 
 import (
 	"compress/gzip"
+	"context"
 	"io"
 	"sync"
 	"sync/atomic"
 )
 
-var compressPool = &sync.Pool{
+// chunkSize is the size of the chunks the input is read in. With the pipe to the reader, which holds no data, it bounds
+// the memory of a Streamer to a chunk and the state of the gzip writer, whatever the size of the input.
+const chunkSize = 64 * 1024
+
+// DefaultLevel is the default compression level, gzip.DefaultCompression.
+const DefaultLevel = gzip.DefaultCompression
+
+// compressPools hold the gzip writers of every compression level, from gzip.HuffmanOnly to gzip.BestCompression.
+var compressPools = func() []*sync.Pool {
+	pools := make([]*sync.Pool, gzip.BestCompression-gzip.HuffmanOnly+1)
+	for i := range pools {
+		level := i + gzip.HuffmanOnly
+		pools[i] = &sync.Pool{
+			New: func() interface{} {
+				zw, _ := gzip.NewWriterLevel(nil, level)
+				return zw
+			},
+		}
+	}
+	return pools
+}()
+
+// ValidLevel reports whether level is a compression level of compress/gzip, from gzip.HuffmanOnly to
+// gzip.BestCompression.
+func ValidLevel(level int) bool {
+	return level >= gzip.HuffmanOnly && level <= gzip.BestCompression
+}
+
+var chunkPool = &sync.Pool{
 	New: func() interface{} {
-		return gzip.NewWriter(nil)
+		b := make([]byte, chunkSize)
+		return &b
 	},
 }
 
 // Streamer implements an io.ReadCloser that converts data from a non-compressed stream to a compressed stream.
 type Streamer struct {
+	ctx         context.Context
 	userInput   io.ReadCloser
 	outputRead  *io.PipeReader
 	outputWrite *io.PipeWriter
 	size        int64
+	level       int
 	err         atomic.Value // holds error
 }
 
-// New creates a new streamer object. Use Reset() to initialize it.
+// New creates a new streamer object, which compresses with the default compression level. Use Reset() to initialize it.
 func New() *Streamer {
-	return &Streamer{}
+	return NewLevel(gzip.DefaultCompression)
+}
+
+// NewLevel creates a new streamer object, which compresses with level, see ValidLevel. Invalid levels are replaced by
+// the default compression level.
+func NewLevel(level int) *Streamer {
+	if !ValidLevel(level) {
+		level = gzip.DefaultCompression
+	}
+	return &Streamer{level: level}
 }
 
 // Reset resets the streamer object to defaults and accepts the io.ReadCloser.
 // You can only use Reset after a previous reader has closed.
 func (s *Streamer) Reset(reader io.ReadCloser) {
+	s.ResetContext(context.Background(), reader)
+}
+
+// ResetContext is like Reset, but the stream is aborted once ctx is done: Read returns the error of the context right
+// away, even if the compression is waiting for the input, and the input isn't read further.
+func (s *Streamer) ResetContext(ctx context.Context, reader io.ReadCloser) {
+	s.ctx = ctx
 	s.userInput = reader
 	s.outputRead, s.outputWrite = io.Pipe()
 	s.size = 0
@@ -47,30 +95,54 @@ func (s *Streamer) InputSize() int64 {
 }
 
 func Compress(payload io.Reader) io.Reader {
+	return CompressContext(context.Background(), payload)
+}
+
+// CompressContext returns a Streamer that compresses payload, and aborts once ctx is done, see ResetContext.
+// The Streamer should be closed once it is no longer read, so that the compression stops.
+func CompressContext(ctx context.Context, payload io.Reader) *Streamer {
+	return CompressContextLevel(ctx, payload, gzip.DefaultCompression)
+}
+
+// CompressContextLevel is CompressContext with a compression level, see NewLevel.
+func CompressContextLevel(ctx context.Context, payload io.Reader, level int) *Streamer {
 	var closer io.ReadCloser
 	var ok bool
 	if closer, ok = payload.(io.ReadCloser); !ok {
 		closer = io.NopCloser(payload)
 	}
-	zw := New()
-	zw.Reset(closer)
+	zw := NewLevel(level)
+	zw.ResetContext(ctx, closer)
 
 	return zw
 }
 
-// run copies the file into a buffer that we stream back via our Read() call.
+// run compresses the input, one chunk at a time, into the pipe that we stream back via our Read() call.
 func (s *Streamer) run() {
-	zw := compressPool.Get().(*gzip.Writer)
+	pool := compressPools[s.level-gzip.HuffmanOnly]
+	zw := pool.Get().(*gzip.Writer)
 	zw.Reset(s.outputWrite)
+	ctx := s.ctx
 
 	go func() {
-		defer compressPool.Put(zw)
-		defer s.outputWrite.Close()
+		var err error
+
+		// Unblocks the reader as soon as the context is done, even if the input is slow to return.
+		stop := context.AfterFunc(ctx, func() { s.outputWrite.CloseWithError(ctx.Err()) })
+		defer stop()
+
+		defer pool.Put(zw)
+		// If reading the input failed, the reader must see the error instead of a cleanly terminated stream.
+		defer func() { s.outputWrite.CloseWithError(err) }()
 		defer zw.Close()
 		defer zw.Flush()
 
-		amount, err := io.Copy(zw, s.userInput)
-		s.size = int64(amount)
+		chunk := chunkPool.Get().(*[]byte)
+		defer chunkPool.Put(chunk)
+
+		var amount int64
+		amount, err = copyChunks(ctx, zw, s.userInput, *chunk)
+		s.size = amount
 
 		if err != nil {
 			s.err.Store(err)
@@ -78,6 +150,30 @@ func (s *Streamer) run() {
 	}()
 }
 
+// copyChunks copies src to dst through chunk, until src is exhausted, writing fails or ctx is done.
+func copyChunks(ctx context.Context, dst io.Writer, src io.Reader, chunk []byte) (int64, error) {
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, rerr := src.Read(chunk)
+		if n > 0 {
+			w, werr := dst.Write(chunk[:n])
+			written += int64(w)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
 // Read implements io.Reader.
 func (s *Streamer) Read(b []byte) (int, error) {
 	amount, err := s.outputRead.Read(b)
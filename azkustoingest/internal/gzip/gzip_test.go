@@ -3,10 +3,13 @@ package gzip
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"math/rand"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -65,3 +68,139 @@ func TestStreamer(t *testing.T) {
 		t.Fatalf("TestStreamer(InputSize): got %d, want %d", streamer.InputSize(), len(str))
 	}
 }
+
+type failingReader struct {
+	err error
+}
+
+func (f failingReader) Read(_ []byte) (int, error) {
+	return 0, f.err
+}
+
+func TestStreamerPropagatesInputError(t *testing.T) {
+	t.Parallel()
+
+	want := io.ErrUnexpectedEOF
+	streamer := New()
+	streamer.Reset(io.NopCloser(io.MultiReader(bytes.NewReader([]byte("some data")), failingReader{err: want})))
+
+	if _, err := io.Copy(io.Discard, streamer); err != want {
+		t.Fatalf("TestStreamerPropagatesInputError: got err == %v, want err == %v", err, want)
+	}
+}
+
+// blockingReader returns data until it is exhausted, then blocks until unblock is closed.
+type blockingReader struct {
+	data    *bytes.Reader
+	unblock chan struct{}
+}
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	if b.data.Len() > 0 {
+		return b.data.Read(p)
+	}
+	<-b.unblock
+	return 0, io.EOF
+}
+
+func TestStreamerContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streamer := CompressContext(ctx, blockingReader{data: bytes.NewReader([]byte(randStringBytes(3 * chunkSize))), unblock: unblock})
+	defer streamer.Close()
+
+	// Read some of the compressed data, the input then blocks.
+	buf := make([]byte, 1024)
+	if _, err := streamer.Read(buf); err != nil {
+		t.Fatalf("TestStreamerContextCanceled: got err == %s, want err == nil", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, streamer)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("TestStreamerContextCanceled: got err == %v, want err == %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestStreamerContextCanceled: the reader wasn't unblocked when the context was canceled")
+	}
+}
+
+func TestStreamerClosedEarly(t *testing.T) {
+	t.Parallel()
+
+	input := &countingReader{r: bytes.NewReader([]byte(randStringBytes(64 * chunkSize)))}
+	streamer := CompressContext(context.Background(), input)
+	buf := make([]byte, 1024)
+	if _, err := streamer.Read(buf); err != nil {
+		t.Fatalf("TestStreamerClosedEarly: got err == %s, want err == nil", err)
+	}
+	if err := streamer.Close(); err != nil {
+		t.Fatalf("TestStreamerClosedEarly: got err == %s, want err == nil", err)
+	}
+
+	// Once closed, the compression stops without reading the rest of the input.
+	time.Sleep(100 * time.Millisecond)
+	if read := input.read(); read >= 64*chunkSize {
+		t.Fatalf("TestStreamerClosedEarly: read %d bytes of the input after the streamer was closed", read)
+	}
+}
+
+func TestStreamerLevels(t *testing.T) {
+	t.Parallel()
+
+	input := bytes.Repeat([]byte("a,b,c\n"), 64*1024)
+	sizes := map[int]int{}
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression, 42} {
+		compressed, err := io.ReadAll(CompressContextLevel(context.Background(), bytes.NewReader(input), level))
+		if err != nil {
+			t.Fatalf("TestStreamerLevels(%d): got err == %s, want err == nil", level, err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("TestStreamerLevels(%d): got err == %s, want err == nil", level, err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("TestStreamerLevels(%d): got err == %s, want err == nil", level, err)
+		}
+		if !bytes.Equal(got, input) {
+			t.Fatalf("TestStreamerLevels(%d): the decompressed output differs from the input", level)
+		}
+		sizes[level] = len(compressed)
+	}
+
+	if sizes[gzip.HuffmanOnly] <= sizes[gzip.BestCompression] {
+		t.Errorf("TestStreamerLevels: HuffmanOnly output (%d bytes) is not larger than BestCompression output (%d bytes)",
+			sizes[gzip.HuffmanOnly], sizes[gzip.BestCompression])
+	}
+	// Invalid levels use the default compression level.
+	if sizes[42] != sizes[gzip.DefaultCompression] {
+		t.Errorf("TestStreamerLevels: invalid level output is %d bytes, want the %d bytes of the default level", sizes[42], sizes[gzip.DefaultCompression])
+	}
+}
+
+type countingReader struct {
+	r *bytes.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReader) read() int64 {
+	return c.n.Load()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Azure/azure-kusto-go/azkustodata"
 	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
 	"github.com/cenkalti/backoff/v4"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -223,6 +224,8 @@ type ManagedStreaming struct {
 type Streaming struct {
 	// ClientRequestID is the client request ID to use for the ingestion.
 	ClientRequestId string
+	// Headers are the custom headers of the streaming request.
+	Headers http.Header
 }
 
 // SourceOptions are options that the user provides about the source that is going to be uploaded.
@@ -237,11 +240,32 @@ type SourceOptions struct {
 	// DontCompress indicates to not compress the file. In streaming - do not pass DontCompress if file is not already compressed.
 	DontCompress bool
 
+	// NoCompression indicates to send the payloads that would be compressed as is, see WithoutCompression. Unlike
+	// DontCompress, the payloads are not compressed.
+	NoCompression bool
+
+	// CompressionLevel is the gzip level of the compression of the payloads, or 0 for the default level, see
+	// WithCompressionLevel.
+	CompressionLevel int
+
 	// OriginalSource is the path to the original source file, used for deletion.
 	OriginalSource string
 
 	// CompressionType is the type of compression used on the file.
 	CompressionType ingestoptions.CompressionType
+
+	// ValidatePayload indicates to validate the structure of text payloads while they are uploaded, failing early on
+	// malformed records.
+	ValidatePayload bool
+
+	// AvroSchema is the Avro schema the records of Avro payloads are validated against, as JSON.
+	AvroSchema string
+
+	// AutoMapping indicates to generate the ingestion mapping from the schema of the destination table.
+	AutoMapping bool
+
+	// ChunkRetries is the number of times FromFileSplit queues a failed chunk again.
+	ChunkRetries int
 }
 
 // Ingestion is a JSON serializable set of options that must be provided to the service.
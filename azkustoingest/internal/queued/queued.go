@@ -4,6 +4,7 @@ package queued
 
 import (
 	"context"
+	goErrors "errors"
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/utils"
@@ -17,7 +18,9 @@ import (
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/gzip"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/quota"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/validation"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
@@ -52,6 +55,9 @@ type uploadStream func(context.Context, io.Reader, *azblob.Client, string, strin
 // uploadBlob provides a type that mimics `azblob.UploadFile` to allow fakes for test
 type uploadBlob func(context.Context, *os.File, *azblob.Client, string, string, *azblob.UploadFileOptions) (azblob.UploadFileResponse, error)
 
+// enqueueMessage provides a type that mimics `azqueue.QueueClient.EnqueueMessage` to allow fakes for test.
+type enqueueMessage func(context.Context, *azqueue.QueueClient, string) error
+
 // Ingestion provides methods for taking data from a filesystem of some type and ingesting it into Kusto.
 // This object is scoped for a single database and table.
 type Ingestion struct {
@@ -60,8 +66,9 @@ type Ingestion struct {
 	table string
 	mgr   *resources.Manager
 
-	uploadStream uploadStream
-	uploadBlob   uploadBlob
+	uploadStream   uploadStream
+	uploadBlob     uploadBlob
+	enqueueMessage enqueueMessage
 
 	bufferSize int
 	maxBuffers int
@@ -96,6 +103,10 @@ func New(db, table string, mgr *resources.Manager, http *http.Client, applicatio
 			options *azblob.UploadFileOptions) (azblob.UploadFileResponse, error) {
 			return client.UploadFile(ctx, container, blob, file, options)
 		},
+		enqueueMessage: func(ctx context.Context, queue *azqueue.QueueClient, content string) error {
+			_, err := queue.EnqueueMessage(ctx, content, nil)
+			return err
+		},
 		applicationForTracing:   applicationForTracing,
 		clientVersionForTracing: clientVersionForTracing,
 	}
@@ -108,7 +119,17 @@ func New(db, table string, mgr *resources.Manager, http *http.Client, applicatio
 }
 
 // Local ingests a local file into Kusto.
+// If the upload fails because the SAS of the ingestion resources expired, the resources are fetched again and the
+// upload is retried once.
 func (i *Ingestion) Local(ctx context.Context, from string, props properties.All) error {
+	err := i.local(ctx, from, props)
+	if goErrors.Is(err, ErrBlobUpload) && goErrors.Is(err, ErrSASExpired) && i.mgr.Refresh(ctx) == nil {
+		err = i.local(ctx, from, props)
+	}
+	return err
+}
+
+func (i *Ingestion) local(ctx context.Context, from string, props properties.All) error {
 	containers, err := i.mgr.GetRankedStorageContainers()
 	if err != nil {
 		return err
@@ -134,14 +155,16 @@ func (i *Ingestion) Local(ctx context.Context, from string, props properties.All
 	}
 
 	// Go over all the containers and try to upload the file to each one. If we succeed, we are done.
+	var lastErr error
 	for attempts, containerUri := range containers {
 		if attempts >= StorageMaxRetryPolicy {
-			return errors.ES(errors.OpFileIngest, errors.KBlobstore, "max retry policy reached").SetNoRetry()
+			return stageError(ErrBlobUpload, "max retry policy reached", lastErr).SetNoRetry()
 		}
 
 		client, containerName, err := i.upstreamContainer(containerUri)
 		if err != nil {
 			i.mgr.ReportStorageResourceResult(containerUri.Account(), false)
+			lastErr = err
 			continue
 		}
 
@@ -154,17 +177,20 @@ func (i *Ingestion) Local(ctx context.Context, from string, props properties.All
 		// check if the error is retryable
 		if errors.Retry(err) {
 			i.mgr.ReportStorageResourceResult(containerUri.Account(), false)
+			lastErr = storageFailure(err, containerUri)
 			continue
 		} else {
 			return err
 		}
 	}
 
-	return errors.ES(errors.OpFileIngest, errors.KBlobstore, "could not upload file to any container")
+	return stageError(ErrBlobUpload, "could not upload file to any container", lastErr)
 }
 
 // Reader uploads a file via an io.Reader.
 // If the function succeeds, it returns the path of the created blob.
+// The reader can't be read again, so the upload isn't retried if the SAS of the ingestion resources expired, but the
+// resources are fetched again for the next calls.
 func (i *Ingestion) Reader(ctx context.Context, reader io.Reader, props properties.All) (string, error) {
 	containers, err := i.mgr.GetRankedStorageContainers()
 	if err != nil {
@@ -196,19 +222,28 @@ func (i *Ingestion) Reader(ctx context.Context, reader io.Reader, props properti
 
 	size := int64(0)
 
+	release, err := quota.Global.Acquire(ctx, i.uploadBuffersSize(shouldCompress))
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	reader, validator := validation.Wrap(reader, &props)
 	if shouldCompress {
-		reader = gzip.Compress(reader)
+		reader = gzip.CompressContextLevel(context.Background(), reader, CompressionLevel(&props))
 	}
 
 	// Go over all the containers and try to upload the file to each one. If we succeed, we are done.
+	var lastErr error
 	for attempts, containerUri := range containers {
 		if attempts >= StorageMaxRetryPolicy {
-			return "", errors.ES(errors.OpFileIngest, errors.KBlobstore, "max retry policy reached").SetNoRetry()
+			return "", i.refreshOnExpiry(ctx, stageError(ErrBlobUpload, "max retry policy reached", lastErr).SetNoRetry())
 		}
 
 		client, containerName, err := i.upstreamContainer(containerUri)
 		if err != nil {
 			i.mgr.ReportStorageResourceResult(containerUri.Account(), false)
+			lastErr = err
 			continue
 		}
 
@@ -222,7 +257,12 @@ func (i *Ingestion) Reader(ctx context.Context, reader io.Reader, props properti
 		)
 
 		if err != nil {
+			// A malformed payload is not a storage problem, so don't penalize the account or try another one.
+			if validator.Err() != nil {
+				return "", validator.Err()
+			}
 			i.mgr.ReportStorageResourceResult(containerUri.Account(), false)
+			lastErr = storageFailure(err, containerUri)
 			continue
 		}
 
@@ -234,10 +274,21 @@ func (i *Ingestion) Reader(ctx context.Context, reader io.Reader, props properti
 		return blobName, err
 	}
 
-	return blobName, errors.ES(errors.OpFileIngest, errors.KBlobstore, "problem uploading to Blob Storage")
+	return blobName, i.refreshOnExpiry(ctx, stageError(ErrBlobUpload, "problem uploading to Blob Storage", lastErr))
+}
+
+// refreshOnExpiry fetches the ingestion resources again if err is caused by an expired SAS, so that the next calls
+// use a new SAS, and returns err.
+func (i *Ingestion) refreshOnExpiry(ctx context.Context, err error) error {
+	if goErrors.Is(err, ErrSASExpired) {
+		_ = i.mgr.Refresh(ctx)
+	}
+	return err
 }
 
 // Blob ingests a file from Azure Blob Storage into Kusto.
+// If posting the ingestion message fails because the SAS of the ingestion resources expired, the resources are fetched
+// again and the message is posted again once.
 func (i *Ingestion) Blob(ctx context.Context, from string, fileSize int64, props properties.All) error {
 	// To learn more about ingestion properties, go to:
 	// https://docs.microsoft.com/en-us/azure/kusto/management/data-ingestion/#ingestion-properties
@@ -263,32 +314,47 @@ func (i *Ingestion) Blob(ctx context.Context, from string, fileSize int64, props
 		return errors.ES(errors.OpFileIngest, errors.KInternal, "could not marshal the ingestion blob info: %s", err).SetNoRetry()
 	}
 
+	err = i.post(ctx, j)
+	if goErrors.Is(err, ErrSASExpired) && i.mgr.Refresh(ctx) == nil {
+		err = i.post(ctx, j)
+	}
+	if err != nil {
+		return err
+	}
+	return props.ApplyDeleteLocalSourceOption()
+}
+
+// post posts an ingestion message to one of the queues of the ingestion resources.
+func (i *Ingestion) post(ctx context.Context, message string) error {
 	queueResources, err := i.mgr.GetRankedStorageQueues()
 	if err != nil {
 		return err
 	}
 
 	// Go over all the queues and try to upload the file to each one. If we succeed, we are done.
+	var lastErr error
 	for attempts, queueUri := range queueResources {
 		if attempts >= StorageMaxRetryPolicy {
-			return errors.ES(errors.OpFileIngest, errors.KBlobstore, "max retry policy reached").SetNoRetry()
+			return stageError(ErrQueuePost, "max retry policy reached", lastErr).SetNoRetry()
 		}
 		queue, err := i.upstreamQueue(queueUri)
 		if err != nil {
 			i.mgr.ReportStorageResourceResult(queueUri.Account(), false)
+			lastErr = err
 			continue
 		}
 
-		if _, err := queue.EnqueueMessage(ctx, j, nil); err != nil {
+		if err := i.enqueueMessage(ctx, queue, message); err != nil {
 			i.mgr.ReportStorageResourceResult(queueUri.Account(), false)
+			lastErr = storageFailure(err, queueUri)
 			continue
 		} else {
 			i.mgr.ReportStorageResourceResult(queueUri.Account(), true)
-			return props.ApplyDeleteLocalSourceOption()
+			return nil
 		}
 	}
 
-	return errors.ES(errors.OpFileIngest, errors.KBlobstore, "could not upload file to any queue")
+	return stageError(ErrQueuePost, "could not upload file to any queue", lastErr)
 }
 
 func CompleteFormatFromFileName(props *properties.All, from string) error {
@@ -342,6 +408,25 @@ func (i *Ingestion) upstreamQueue(resourceUri *resources.URI) (*azqueue.QueueCli
 
 var nower = time.Now
 
+// uploadBuffersSize returns the approximate memory of the buffers of an upload, and of its compression, which is
+// acquired from the global buffer quota for the time of the upload.
+func (i *Ingestion) uploadBuffersSize(compress bool) int64 {
+	// These are the defaults of azblob.UploadStreamOptions.
+	blockSize, concurrency := int64(i.bufferSize), int64(i.maxBuffers)
+	if blockSize < 1<<20 {
+		blockSize = 1 << 20
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	size := blockSize * concurrency
+	if compress {
+		size += quota.CompressionBufferSize
+	}
+	return size
+}
+
 // localToBlob copies from a local to an Azure Blobstore blob. It returns the URL of the Blob, the local file info and an
 // error if there was one.
 func (i *Ingestion) localToBlob(ctx context.Context, from string, client *azblob.Client, container string, props *properties.All) (string, int64, error) {
@@ -349,6 +434,12 @@ func (i *Ingestion) localToBlob(ctx context.Context, from string, client *azblob
 	shouldCompress := ShouldCompress(props, compression)
 	blobName := GenBlobName(i.db, i.table, nower(), filepath.Base(uuid.New().String()), filepath.Base(from), compression, shouldCompress, props.Ingestion.Additional.Format.String())
 
+	release, err := quota.Global.Acquire(ctx, i.uploadBuffersSize(shouldCompress))
+	if err != nil {
+		return "", 0, err
+	}
+	defer release()
+
 	file, err := os.Open(from)
 	if err != nil {
 		return "", 0, errors.ES(
@@ -368,9 +459,11 @@ func (i *Ingestion) localToBlob(ctx context.Context, from string, client *azblob
 		).SetNoRetry()
 	}
 
+	source, validator := validation.Wrap(file, props)
+
 	if shouldCompress {
-		gstream := gzip.New()
-		gstream.Reset(file)
+		gstream := gzip.NewLevel(CompressionLevel(props))
+		gstream.Reset(io.NopCloser(source))
 
 		_, err = i.uploadStream(
 			ctx,
@@ -382,11 +475,34 @@ func (i *Ingestion) localToBlob(ctx context.Context, from string, client *azblob
 		)
 
 		if err != nil {
-			return "", 0, errors.ES(errors.OpFileIngest, errors.KBlobstore, "problem uploading to Blob Storage: %s", err)
+			if validator.Err() != nil {
+				return "", 0, validator.Err()
+			}
+			return "", 0, errors.E(errors.OpFileIngest, errors.KBlobstore, fmt.Errorf("problem uploading to Blob Storage: %w", err))
 		}
 		return fullUrl(client, container, blobName), gstream.InputSize(), nil
 	}
 
+	if validator != nil {
+		// The file can't be handed to UploadFile as is, since it would read it without going through the validator.
+		_, err = i.uploadStream(
+			ctx,
+			source,
+			client,
+			container,
+			blobName,
+			&azblob.UploadStreamOptions{BlockSize: int64(i.bufferSize), Concurrency: i.maxBuffers},
+		)
+
+		if err != nil {
+			if validator.Err() != nil {
+				return "", 0, validator.Err()
+			}
+			return "", 0, errors.E(errors.OpFileIngest, errors.KBlobstore, fmt.Errorf("problem uploading to Blob Storage: %w", err))
+		}
+		return fullUrl(client, container, blobName), stat.Size(), nil
+	}
+
 	// The high-level API UploadFileToBlockBlob function uploads blocks in parallel for optimal performance, and can handle large files as well.
 	// This function calls StageBlock/CommitBlockList for files larger 256 MBs, and calls Upload for any file smaller
 	_, err = i.uploadBlob(
@@ -402,7 +518,7 @@ func (i *Ingestion) localToBlob(ctx context.Context, from string, client *azblob
 	)
 
 	if err != nil {
-		return "", 0, errors.ES(errors.OpFileIngest, errors.KBlobstore, "problem uploading to Blob Storage: %s", err)
+		return "", 0, errors.E(errors.OpFileIngest, errors.KBlobstore, fmt.Errorf("problem uploading to Blob Storage: %w", err))
 	}
 
 	return fullUrl(client, container, blobName), stat.Size(), nil
@@ -425,9 +541,24 @@ func GenBlobName(databaseName string, tableName string, time time.Time, guid str
 	return blobName
 }
 
+// CompressionLevel returns the gzip level of the compression of the payloads of props.
+func CompressionLevel(props *properties.All) int {
+	if props.Source.CompressionLevel == 0 {
+		return gzip.DefaultLevel
+	}
+	return props.Source.CompressionLevel
+}
+
+// ShouldCompress reports whether the payload is compressed before it is sent: it needs compression, see
+// NeedsCompression, and NoCompression isn't set.
+func ShouldCompress(props *properties.All, compressionFileExtension ingestoptions.CompressionType) bool {
+	return !props.Source.NoCompression && NeedsCompression(props, compressionFileExtension)
+}
+
+// NeedsCompression reports whether the payload isn't compressed, and is of a format that is compressed when sent.
 // Do not compress if user specified in DontCompress or CompressionType,
 // if the file extension shows compression, or if the format is binary.
-func ShouldCompress(props *properties.All, compressionFileExtension ingestoptions.CompressionType) bool {
+func NeedsCompression(props *properties.All, compressionFileExtension ingestoptions.CompressionType) bool {
 	if props.Source.DontCompress {
 		return false
 	}
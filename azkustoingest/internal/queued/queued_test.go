@@ -348,6 +348,13 @@ func TestShouldCompress(t *testing.T) {
 				OriginalSource: "https://somehost.somedomain.com:8080/v1/somestuff/file.avro"}},
 			want: false,
 		},
+		{
+			name: "NoCompression is true",
+			props: &properties.All{Source: properties.SourceOptions{CompressionType: ingestoptions.CTNone,
+				NoCompression:  true,
+				OriginalSource: "https://somehost.somedomain.com:8080/v1/somestuff/file"}},
+			want: false,
+		},
 	}
 
 	for _, test := range tests {
@@ -360,6 +367,9 @@ func TestShouldCompress(t *testing.T) {
 			got := ShouldCompress(test.props,
 				utils.CompressionDiscovery(test.props.Source.OriginalSource))
 			assert.Equal(t, test.want, got)
+			// NoCompression only skips the compression of the payloads that need it.
+			needs := NeedsCompression(test.props, utils.CompressionDiscovery(test.props.Source.OriginalSource))
+			assert.Equal(t, test.want || test.props.Source.NoCompression, needs)
 		})
 	}
 }
@@ -0,0 +1,94 @@
+package queued
+
+import (
+	goErrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// The errors of the storage stage of queued ingestion, before Kusto is involved. The errors of Local, Reader and Blob
+// match them with errors.Is, and still are *errors.Error.
+var (
+	// ErrBlobUpload is returned when the data could not be uploaded to any of the containers of the ingestion resources.
+	ErrBlobUpload = goErrors.New("the upload to blob storage failed")
+	// ErrQueuePost is returned when the ingestion message could not be posted to any of the queues of the ingestion
+	// resources.
+	ErrQueuePost = goErrors.New("the ingestion message could not be posted to the queue")
+	// ErrSASExpired is returned along with ErrBlobUpload or ErrQueuePost when the storage refused the SAS of the
+	// ingestion resources, because it expired or was revoked.
+	ErrSASExpired = goErrors.New("the SAS of the ingestion resources expired")
+)
+
+// storageError is an error of the storage that matches some of the storage errors. It keeps the message of the
+// original error, and unwraps to all of them.
+type storageError struct {
+	sentinels []error
+	err       error
+}
+
+func (e *storageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *storageError) Unwrap() []error {
+	return append(append([]error(nil), e.sentinels...), e.err)
+}
+
+// storageFailure classifies the failure of an operation on a storage resource.
+func storageFailure(err error, uri *resources.URI) error {
+	if isSASExpired(err, uri) {
+		return &storageError{sentinels: []error{ErrSASExpired}, err: err}
+	}
+	return err
+}
+
+// isSASExpired reports whether the storage refused the SAS of a resource.
+func isSASExpired(err error, uri *resources.URI) bool {
+	if expiry, parseErr := time.Parse(time.RFC3339, uri.SAS().Get("se")); parseErr == nil && !expiry.After(nower()) {
+		return true
+	}
+
+	var respErr *azcore.ResponseError
+	return goErrors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden && respErr.ErrorCode == "AuthenticationFailed"
+}
+
+// storageRetryable reports whether an operation that failed with err may succeed if it is retried. Requests that the
+// storage refused won't succeed, unless they failed because of throttling, a timeout or an expired SAS, which is
+// renewed by the next fetch of the ingestion resources.
+func storageRetryable(err error) bool {
+	if goErrors.Is(err, ErrSASExpired) {
+		return true
+	}
+
+	var respErr *azcore.ResponseError
+	if !goErrors.As(err, &respErr) {
+		return true
+	}
+	switch {
+	case respErr.StatusCode == http.StatusRequestTimeout, respErr.StatusCode == http.StatusTooManyRequests:
+		return true
+	case respErr.StatusCode >= 400 && respErr.StatusCode < 500:
+		return false
+	}
+	return true
+}
+
+// stageError returns the error of a failed storage stage, ErrBlobUpload or ErrQueuePost, that wraps the last error of
+// the storage, if any.
+func stageError(stage error, msg string, cause error) *errors.Error {
+	err := goErrors.New(msg)
+	if cause != nil {
+		err = fmt.Errorf("%s: %w", msg, cause)
+	}
+
+	e := errors.E(errors.OpFileIngest, errors.KBlobstore, &storageError{sentinels: []error{stage}, err: err})
+	if cause != nil && !storageRetryable(cause) {
+		e.SetNoRetry()
+	}
+	return e
+}
@@ -0,0 +1,179 @@
+package queued
+
+import (
+	"context"
+	goErrors "errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMgmt counts the fetches of the ingestion resources.
+type countingMgmt struct {
+	*resources.FakeMgmt
+	fetches atomic.Int32
+}
+
+func (c *countingMgmt) Mgmt(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+	c.fetches.Add(1)
+	return c.FakeMgmt.Mgmt(ctx, db, query, options...)
+}
+
+var (
+	sasExpiredErr = &azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthenticationFailed"}
+	notFoundErr   = &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "ContainerNotFound"}
+	serverErr     = &azcore.ResponseError{StatusCode: http.StatusInternalServerError, ErrorCode: "InternalError"}
+)
+
+// failing returns the errors in order, and then nil.
+func failing(errs ...error) func() error {
+	var calls atomic.Int32
+	return func() error {
+		n := int(calls.Add(1)) - 1
+		if n < len(errs) {
+			return errs[n]
+		}
+		return nil
+	}
+}
+
+func newStorageTestIngestion(t *testing.T, upload func() error, enqueue func() error) (*Ingestion, *countingMgmt) {
+	mgmt := &countingMgmt{FakeMgmt: resources.SuccessfulFakeResources()}
+	mgr, err := resources.New(mgmt)
+	require.NoError(t, err)
+	t.Cleanup(mgr.Close)
+
+	return &Ingestion{
+		db:    "database",
+		table: "table",
+		mgr:   mgr,
+		uploadStream: func(_ context.Context, reader io.Reader, _ *azblob.Client, _, _ string, _ *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
+			_, _ = io.Copy(io.Discard, reader)
+			return azblob.UploadStreamResponse{}, upload()
+		},
+		uploadBlob: func(context.Context, *os.File, *azblob.Client, string, string, *azblob.UploadFileOptions) (azblob.UploadFileResponse, error) {
+			return azblob.UploadFileResponse{}, upload()
+		},
+		enqueueMessage: func(context.Context, *azqueue.QueueClient, string) error {
+			return enqueue()
+		},
+	}, mgmt
+}
+
+func TestStorageErrors(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "data.csv")
+	require.NoError(t, os.WriteFile(path, []byte("a,b\n"), 0o600))
+	props := properties.All{Ingestion: properties.Ingestion{
+		DatabaseName: "database",
+		TableName:    "table",
+		Additional:   properties.Additional{Format: properties.CSV, AuthContext: "authorization"},
+	}}
+
+	tests := []struct {
+		desc    string
+		upload  []error
+		enqueue []error
+		// reader ingests with Reader instead of Local.
+		reader  bool
+		want    []error
+		retry   bool
+		fetches int32
+	}{
+		{desc: "Success", fetches: 1},
+		{desc: "Upload SAS expired is retried", upload: []error{sasExpiredErr}, fetches: 2},
+		{desc: "Queue SAS expired is retried", enqueue: []error{sasExpiredErr}, fetches: 2},
+		{
+			desc:    "Upload SAS expired twice",
+			upload:  []error{sasExpiredErr, sasExpiredErr},
+			want:    []error{ErrBlobUpload, ErrSASExpired},
+			retry:   true,
+			fetches: 2,
+		},
+		{desc: "Upload refused", upload: []error{notFoundErr}, want: []error{ErrBlobUpload}, fetches: 1},
+		{desc: "Upload server error", upload: []error{serverErr}, want: []error{ErrBlobUpload}, retry: true, fetches: 1},
+		{desc: "Queue server error", enqueue: []error{serverErr}, want: []error{ErrQueuePost}, retry: true, fetches: 1},
+		{
+			desc:    "Reader SAS expired refreshes the resources",
+			reader:  true,
+			upload:  []error{sasExpiredErr},
+			want:    []error{ErrBlobUpload, ErrSASExpired},
+			retry:   true,
+			fetches: 2,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			in, mgmt := newStorageTestIngestion(t, failing(test.upload...), failing(test.enqueue...))
+
+			var err error
+			if test.reader {
+				_, err = in.Reader(context.Background(), strings.NewReader("a,b\n"), props)
+			} else {
+				err = in.Local(context.Background(), path, props)
+			}
+
+			assert.Equal(t, test.fetches, mgmt.fetches.Load())
+			if len(test.want) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, sentinel := range []error{ErrBlobUpload, ErrQueuePost, ErrSASExpired} {
+				assert.Equal(t, containsError(test.want, sentinel), goErrors.Is(err, sentinel), sentinel.Error())
+			}
+			assert.Equal(t, test.retry, errors.Retry(err))
+
+			e, ok := err.(*errors.Error)
+			require.True(t, ok)
+			assert.Equal(t, errors.KBlobstore, e.Kind)
+
+			var respErr *azcore.ResponseError
+			assert.True(t, goErrors.As(err, &respErr), "the error of the storage is wrapped")
+		})
+	}
+}
+
+func containsError(errs []error, err error) bool {
+	for _, e := range errs {
+		if e == err {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSASExpiryFromToken(t *testing.T) {
+	t.Parallel()
+
+	expired, err := resources.Parse("https://account.blob.core.windows.net/container?se=2020-01-01T00:00:00Z&sig=x")
+	require.NoError(t, err)
+	valid, err := resources.Parse("https://account.blob.core.windows.net/container?se=2999-01-01T00:00:00Z&sig=x")
+	require.NoError(t, err)
+
+	assert.True(t, isSASExpired(goErrors.New("failed"), expired))
+	assert.False(t, isSASExpired(goErrors.New("failed"), valid))
+	assert.True(t, isSASExpired(sasExpiredErr, valid))
+	assert.False(t, isSASExpired(notFoundErr, valid))
+}
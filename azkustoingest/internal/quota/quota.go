@@ -0,0 +1,155 @@
+// Package quota caps the memory of the buffers of the ingestions of a process, so that a burst of large ingestions
+// waits for buffers to be released instead of allocating them all at once.
+package quota
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// CompressionBufferSize is the approximate memory of the buffers of a gzip compression.
+const CompressionBufferSize = 1 << 20
+
+// Stats are the statistics of a Quota.
+type Stats struct {
+	// Quota is the current quota in bytes, or 0 if there is none.
+	Quota int64
+	// InUse is the amount of bytes acquired and not released yet, and PeakInUse is the highest it has been.
+	InUse     int64
+	PeakInUse int64
+	// Acquisitions is the number of acquisitions made while a quota was set.
+	Acquisitions int64
+	// Waits is the number of acquisitions that had to wait for buffers to be released, Waiting is the number of the ones
+	// that are waiting now, and WaitTime is the total time the acquisitions waited.
+	Waits    int64
+	Waiting  int64
+	WaitTime time.Duration
+	// Canceled is the number of acquisitions whose context was done while they were waiting.
+	Canceled int64
+}
+
+// waiter is an acquisition waiting for buffers to be released.
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// Quota is a size-capped pool of bytes, acquired before buffers are allocated and released once they are no longer
+// used. Acquisitions are granted in order, so that large ones are not starved by small ones.
+type Quota struct {
+	mu      sync.Mutex
+	stats   Stats
+	waiters list.List
+}
+
+// Global is the quota shared by all the ingestion clients of the process.
+var Global = &Quota{}
+
+// Set sets the quota in bytes. 0 removes it, and lets all the acquisitions through.
+// The bytes that are in use are kept in use, and count against the new quota.
+func (q *Quota) Set(bytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if bytes < 0 {
+		bytes = 0
+	}
+	q.stats.Quota = bytes
+	q.grant()
+}
+
+// Stats returns the statistics of the quota.
+func (q *Quota) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// Acquire acquires n bytes, waiting for them to be released by other acquisitions if needed, and returns the function
+// that releases them. An acquisition larger than the quota acquires the whole quota instead.
+// Without a quota, it returns immediately.
+func (q *Quota) Acquire(ctx context.Context, n int64) (release func(), err error) {
+	q.mu.Lock()
+	if q.stats.Quota == 0 || n <= 0 {
+		q.mu.Unlock()
+		return func() {}, nil
+	}
+	if n > q.stats.Quota {
+		n = q.stats.Quota
+	}
+	q.stats.Acquisitions++
+
+	if q.waiters.Len() == 0 && q.stats.InUse+n <= q.stats.Quota {
+		q.use(n)
+		q.mu.Unlock()
+		return q.releaser(n), nil
+	}
+
+	w := &waiter{n: n, ready: make(chan struct{})}
+	elem := q.waiters.PushBack(w)
+	q.stats.Waits++
+	q.stats.Waiting++
+	q.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+		q.mu.Lock()
+		q.stats.WaitTime += time.Since(start)
+		q.mu.Unlock()
+		return q.releaser(n), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.stats.WaitTime += time.Since(start)
+		q.stats.Canceled++
+		select {
+		case <-w.ready:
+			// The bytes were granted concurrently, so they are given back.
+			q.stats.InUse -= n
+		default:
+			q.waiters.Remove(elem)
+			q.stats.Waiting--
+		}
+		q.grant()
+		return nil, errors.ES(errors.OpFileIngest, errors.KTimeout, "gave up waiting for %d bytes of the global buffer quota: %s", n, ctx.Err())
+	}
+}
+
+// use marks n bytes as in use.
+func (q *Quota) use(n int64) {
+	q.stats.InUse += n
+	if q.stats.InUse > q.stats.PeakInUse {
+		q.stats.PeakInUse = q.stats.InUse
+	}
+}
+
+// grant grants the bytes of the waiters that fit in the quota, in order.
+func (q *Quota) grant() {
+	for e := q.waiters.Front(); e != nil; e = q.waiters.Front() {
+		w := e.Value.(*waiter)
+		if q.stats.Quota != 0 && q.stats.InUse+w.n > q.stats.Quota {
+			return
+		}
+		q.waiters.Remove(e)
+		q.stats.Waiting--
+		q.use(w.n)
+		close(w.ready)
+	}
+}
+
+// releaser returns the function that releases n bytes. Calling it more than once has no effect.
+func (q *Quota) releaser(n int64) func() {
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.stats.InUse -= n
+			q.grant()
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync acquires n bytes of q in a goroutine, and returns the channel its release function is sent to.
+func acquireAsync(t *testing.T, q *Quota, ctx context.Context, n int64) <-chan func() {
+	ch := make(chan func(), 1)
+	go func() {
+		release, err := q.Acquire(ctx, n)
+		if err != nil {
+			close(ch)
+			return
+		}
+		ch <- release
+	}()
+	return ch
+}
+
+// waitForWaiters waits until n acquisitions of q are waiting.
+func waitForWaiters(t *testing.T, q *Quota, n int64) {
+	require.Eventually(t, func() bool { return q.Stats().Waiting == n }, 5*time.Second, time.Millisecond)
+}
+
+func TestNoQuota(t *testing.T) {
+	t.Parallel()
+
+	q := &Quota{}
+	release, err := q.Acquire(context.Background(), 1<<40)
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, Stats{}, q.Stats())
+}
+
+func TestAcquireInOrder(t *testing.T) {
+	t.Parallel()
+
+	q := &Quota{}
+	q.Set(10)
+
+	first, err := q.Acquire(context.Background(), 6)
+	require.NoError(t, err)
+
+	// large waits for first, and small waits behind large even though it would fit.
+	large := acquireAsync(t, q, context.Background(), 8)
+	waitForWaiters(t, q, 1)
+	small := acquireAsync(t, q, context.Background(), 2)
+	waitForWaiters(t, q, 2)
+
+	select {
+	case <-small:
+		t.Fatal("an acquisition was granted before the one waiting ahead of it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	first()
+	first() // No effect.
+	releaseLarge := <-large
+	releaseSmall := <-small
+
+	stats := q.Stats()
+	assert.EqualValues(t, 10, stats.InUse)
+	assert.EqualValues(t, 10, stats.PeakInUse)
+	assert.EqualValues(t, 3, stats.Acquisitions)
+	assert.EqualValues(t, 2, stats.Waits)
+	assert.EqualValues(t, 0, stats.Waiting)
+	assert.Greater(t, stats.WaitTime, time.Duration(0))
+
+	releaseLarge()
+	releaseSmall()
+	assert.EqualValues(t, 0, q.Stats().InUse)
+}
+
+func TestAcquireLargerThanQuota(t *testing.T) {
+	t.Parallel()
+
+	q := &Quota{}
+	q.Set(10)
+
+	release, err := q.Acquire(context.Background(), 100)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, q.Stats().InUse)
+	release()
+	assert.EqualValues(t, 0, q.Stats().InUse)
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	t.Parallel()
+
+	q := &Quota{}
+	q.Set(10)
+
+	held, err := q.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx, 5)
+	assert.ErrorContains(t, err, "global buffer quota")
+
+	stats := q.Stats()
+	assert.EqualValues(t, 1, stats.Canceled)
+	assert.EqualValues(t, 0, stats.Waiting)
+
+	held()
+	release, err := q.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+	release()
+}
+
+func TestSetReleasesWaiters(t *testing.T) {
+	t.Parallel()
+
+	q := &Quota{}
+	q.Set(10)
+
+	held, err := q.Acquire(context.Background(), 10)
+	require.NoError(t, err)
+	waiting := acquireAsync(t, q, context.Background(), 5)
+	waitForWaiters(t, q, 1)
+
+	q.Set(0)
+	release, ok := <-waiting
+	require.True(t, ok)
+	release()
+	held()
+	assert.EqualValues(t, 0, q.Stats().InUse)
+}
@@ -18,6 +18,7 @@ import (
 
 	kustoErrors "github.com/Azure/azure-kusto-go/azkustodata/errors"
 	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	"github.com/cenkalti/backoff/v4"
 )
 
@@ -106,10 +107,12 @@ type token struct {
 	AuthContext string `kusto:"AuthorizationContext"`
 }
 
-// Manager manages Kusto resources.
+// Manager manages Kusto resources. It is safe for concurrent use - the resources and the auth context are cached
+// behind locks, and are refreshed by a single goroutine until Close is called.
 type Manager struct {
 	client                   mgmter
 	done                     chan struct{}
+	closeOnce                sync.Once
 	resources                atomic.Value // Stores Ingestion
 	lastFetchTime            atomic.Value // Stores time.Time
 	kustoToken               token
@@ -131,17 +134,11 @@ func New(client mgmter) (*Manager, error) {
 	return m, nil
 }
 
-// Close closes the manager. This stops any token refreshes.
+// Close closes the manager. This stops any token refreshes. It can be called more than once.
 func (m *Manager) Close() {
-	for {
-		select {
-		case <-m.done:
-			return
-		default:
-			close(m.done)
-			return
-		}
-	}
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
 }
 
 func (m *Manager) renewResources() {
@@ -288,13 +285,19 @@ func (i *Ingestion) getRankedStorageQueues(rankedStorageAccounts []RankedStorage
 }
 
 // fetch makes a azkustodata.Client.Mgmt() call to retrieve the resources used for Ingestion.
-func (m *Manager) fetch(ctx context.Context) error {
+func (m *Manager) fetch(ctx context.Context) (err error) {
 	m.fetchLock.Lock()
 	defer m.fetchLock.Unlock()
 
+	defer func() {
+		if err != nil {
+			log.Writef(log.EventResourceRefresh, "could not fetch the ingestion resources: %s", err)
+		}
+	}()
+
 	var dataset v1.Dataset
 	retryCtx := backoff.WithContext(initBackoff(), ctx)
-	err := backoff.Retry(func() error {
+	err = backoff.Retry(func() error {
 		var err error
 		dataset, err = m.client.Mgmt(ctx, "NetDefaultDB", kql.New(".get ingestion resources"))
 		if err == nil {
@@ -333,6 +336,8 @@ func (m *Manager) fetch(ctx context.Context) error {
 	m.resources.Store(ingest)
 
 	m.lastFetchTime.Store(time.Now().UTC())
+	log.Writef(log.EventResourceRefresh, "fetched the ingestion resources: %d queues, %d containers and %d tables",
+		len(ingest.Queues), len(ingest.Containers), len(ingest.Tables))
 
 	return nil
 }
@@ -371,9 +376,14 @@ func initBackoff() backoff.BackOff {
 
 // Resources returns information about the ingestion resources. This will used cached information instead
 // of fetching from source.
-func (m *Manager) getResources() (Ingestion, error) {
+// stale reports whether the resources must be fetched again before they are used.
+func (m *Manager) stale() bool {
 	lastFetchTime, ok := m.lastFetchTime.Load().(time.Time)
-	if !ok || lastFetchTime.Add(2*fetchInterval).Before(time.Now().UTC()) {
+	return !ok || lastFetchTime.Add(2*fetchInterval).Before(time.Now().UTC())
+}
+
+func (m *Manager) getResources() (Ingestion, error) {
+	if m.stale() {
 		err := m.fetchRetry(context.Background())
 		if err != nil {
 			return Ingestion{}, err
@@ -387,6 +397,22 @@ func (m *Manager) getResources() (Ingestion, error) {
 	return i, nil
 }
 
+// Refresh fetches the ingestion resources again, ahead of the periodic refresh, for instance when the SAS of the
+// resources expired.
+func (m *Manager) Refresh(ctx context.Context) error {
+	log.Write(log.EventResourceRefresh, "refreshing the ingestion resources ahead of the periodic refresh")
+	return m.fetch(ctx)
+}
+
+// RefreshIfStale fetches the ingestion resources if they must be fetched again before they are used, once and with ctx,
+// unlike the getters that retry for up to a few minutes. It is for the callers that must return by the deadline of ctx.
+func (m *Manager) RefreshIfStale(ctx context.Context) error {
+	if !m.stale() {
+		return nil
+	}
+	return m.fetch(ctx)
+}
+
 // Report storage account resource usage results.
 func (m *Manager) ReportStorageResourceResult(accountName string, success bool) {
 	m.rankedStorageAccount.addAccountResult(accountName, success)
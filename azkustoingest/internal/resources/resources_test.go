@@ -2,6 +2,7 @@ package resources
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
@@ -233,3 +234,53 @@ func TestResources(t *testing.T) {
 		})
 	}
 }
+
+func TestManagerConcurrent(t *testing.T) {
+	t.Parallel()
+
+	fakeMgmt := NewFakeMgmt(
+		[]v1.RawColumn{
+			{ColumnName: "ResourceTypeName", ColumnType: string(types.String)},
+			{ColumnName: "StorageRoot", ColumnType: string(types.String)},
+			{ColumnName: "AuthorizationContext", ColumnType: string(types.String)},
+		},
+		[]value.Values{
+			{
+				value.NewString("TempStorage"),
+				value.NewString("https://account.blob.core.windows.net/storageroot0"),
+				value.NewString("authtoken"),
+			},
+		},
+		false,
+	)
+	manager, err := New(fakeMgmt)
+	assert.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			auth, err := manager.AuthContext(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "authtoken", auth)
+
+			containers, err := manager.GetRankedStorageContainers()
+			assert.NoError(t, err)
+			assert.Len(t, containers, 1)
+			manager.ReportStorageResourceResult(containers[0].Account(), true)
+		}()
+	}
+	wg.Wait()
+
+	// Closing more than once, even concurrently, is allowed.
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			manager.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,247 @@
+// Package sniff detects the format of io.Reader payloads ingested without a format, from their first bytes.
+package sniff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+)
+
+// SampleSize is the amount of bytes read from the start of a payload to detect its format.
+const SampleSize = 1024
+
+// optionNames are the names of the formats, as they are passed to the FileFormat option.
+var optionNames = map[properties.DataFormat]string{
+	properties.AVRO:      "AVRO",
+	properties.CSV:       "CSV",
+	properties.JSON:      "JSON",
+	properties.MultiJSON: "MultiJSON",
+	properties.ORC:       "ORC",
+	properties.Parquet:   "Parquet",
+	properties.PSV:       "PSV",
+	properties.SCSV:      "SCSV",
+	properties.SOHSV:     "SOHSV",
+	properties.TSV:       "TSV",
+}
+
+// separators are the field separators of the delimited formats, in order of preference when they are equally likely.
+var separators = []struct {
+	sep    byte
+	format properties.DataFormat
+}{
+	{',', properties.CSV},
+	{'\t', properties.TSV},
+	{';', properties.SCSV},
+	{'|', properties.PSV},
+	{'\x01', properties.SOHSV},
+}
+
+var (
+	parquetMagic = []byte("PAR1")
+	avroMagic    = []byte("Obj\x01")
+	orcMagic     = []byte("ORC")
+	utf8BOM      = []byte("\xef\xbb\xbf")
+)
+
+// Format sets the format of props from the first bytes of reader, if no format was set.
+// It returns the io.Reader to use in place of reader, which still starts with the bytes read to detect the format.
+// If the format can't be detected with confidence, an error describing the best guess and how to set the format
+// explicitly is returned.
+func Format(reader io.Reader, props *properties.All) (io.Reader, error) {
+	if props.Ingestion.Additional.Format != properties.DFUnknown {
+		return reader, nil
+	}
+
+	buf := make([]byte, SampleSize)
+	n, err := io.ReadFull(reader, buf)
+	eof := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !eof {
+		return nil, errors.E(errors.OpFileIngest, errors.KIO, err)
+	}
+	buf = buf[:n]
+	reader = io.MultiReader(bytes.NewReader(buf), reader)
+
+	sample := buf
+	switch props.Source.CompressionType {
+	case ingestoptions.GZIP:
+		sample, eof, err = gunzipSample(buf, eof)
+		if err != nil {
+			return nil, detectionError(properties.DFUnknown, fmt.Sprintf("the gzip payload could not be read (%s)", err))
+		}
+	case ingestoptions.ZIP:
+		return nil, detectionError(properties.DFUnknown, "the format of zip payloads is not detected")
+	}
+
+	format, reason := Detect(sample, eof)
+	if reason != "" {
+		return nil, detectionError(format, reason)
+	}
+
+	props.Ingestion.Additional.Format = format
+	return reader, nil
+}
+
+// gunzipSample decompresses as much as possible of the start of a gzip payload.
+func gunzipSample(buf []byte, eof bool) ([]byte, bool, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, false, err
+	}
+	sample, err := io.ReadAll(io.LimitReader(gz, SampleSize))
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, false, err
+	}
+	// The decompressed sample is complete only if the whole payload was read and decompressed.
+	return sample, eof && err == nil && len(sample) < SampleSize, nil
+}
+
+func detectionError(guess properties.DataFormat, reason string) error {
+	if name, ok := optionNames[guess]; ok {
+		return errors.ES(errors.OpFileIngest, errors.KClientArgs,
+			"the format of the data could not be detected: %s. The best guess is %s; if it is correct, set it with the FileFormat(azkustoingest.%s) option, otherwise set the actual format with FileFormat()",
+			reason, guess, name).SetNoRetry()
+	}
+	return errors.ES(errors.OpFileIngest, errors.KClientArgs,
+		"the format of the data could not be detected: %s. Set the format with the FileFormat() option", reason).SetNoRetry()
+}
+
+// Detect returns the format of a payload from its first bytes. eof is true if sample is the whole payload.
+// If the format is not certain, the best guess is returned with the reason it is uncertain, and DFUnknown is returned
+// if there's no guess at all.
+func Detect(sample []byte, eof bool) (properties.DataFormat, string) {
+	switch {
+	case bytes.HasPrefix(sample, parquetMagic):
+		return properties.Parquet, ""
+	case bytes.HasPrefix(sample, avroMagic):
+		return properties.AVRO, ""
+	case bytes.HasPrefix(sample, orcMagic):
+		return properties.ORC, ""
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(sample, utf8BOM), " \t\r\n")
+	if len(text) == 0 {
+		// There's nothing to ingest, so any format will do.
+		return properties.CSV, ""
+	}
+	if !isText(text, eof) {
+		return properties.DFUnknown, "the payload is binary data of an unknown format"
+	}
+
+	switch text[0] {
+	case '[':
+		return properties.MultiJSON, ""
+	case '{':
+		// JSON lines have a complete object on every line, otherwise the objects span lines.
+		if line, _, ok := bytes.Cut(text, []byte("\n")); (ok || eof) && json.Valid(line) {
+			return properties.JSON, ""
+		}
+		return properties.MultiJSON, ""
+	}
+
+	return detectSeparator(text, eof)
+}
+
+// isText returns true if the sample is UTF-8 text without control characters, other than the ones used by the text
+// formats. The last rune may be cut by the end of the sample.
+func isText(sample []byte, eof bool) bool {
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && size <= 1 {
+			return !eof && !utf8.FullRune(sample)
+		}
+		if r < ' ' && r != '\t' && r != '\r' && r != '\n' && r != '\x01' {
+			return false
+		}
+		sample = sample[size:]
+	}
+	return true
+}
+
+// detectSeparator finds the separator of delimited text, the one that appears the same amount of times in every line.
+func detectSeparator(text []byte, eof bool) (properties.DataFormat, string) {
+	lines := splitRecords(text)
+	// The last line may be cut by the end of the sample.
+	if !eof && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+
+	best, bestCount, ambiguous := properties.DFUnknown, 0, false
+	guess, guessTotal := properties.DFUnknown, 0
+	for _, s := range separators {
+		count, consistent, total := -1, true, 0
+		for _, line := range lines {
+			c := bytes.Count(line, []byte{s.sep})
+			total += c
+			if count == -1 {
+				count = c
+			} else if c != count {
+				consistent = false
+			}
+		}
+
+		if total > guessTotal {
+			guess, guessTotal = s.format, total
+		}
+		if !consistent || count <= 0 {
+			continue
+		}
+		if count > bestCount {
+			best, bestCount, ambiguous = s.format, count, false
+		} else if count == bestCount {
+			ambiguous = true
+		}
+	}
+
+	switch {
+	case best != properties.DFUnknown && !ambiguous:
+		return best, ""
+	case best != properties.DFUnknown:
+		return best, "several separators appear the same amount of times in every line"
+	case guess != properties.DFUnknown:
+		return guess, fmt.Sprintf("%q separates a different amount of fields in different lines", separatorOf(guess))
+	}
+	// A single column has no separators, and is ingested the same way by all the delimited formats.
+	return properties.CSV, ""
+}
+
+func separatorOf(format properties.DataFormat) byte {
+	for _, s := range separators {
+		if s.format == format {
+			return s.sep
+		}
+	}
+	return 0
+}
+
+// splitRecords splits delimited text into its non-empty lines, without the quoted parts of the fields, which may
+// contain separators and line breaks.
+func splitRecords(text []byte) [][]byte {
+	var lines [][]byte
+	line := make([]byte, 0, len(text))
+	quoted := false
+	for _, c := range text {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '\n':
+			if line = bytes.TrimSuffix(line, []byte("\r")); len(line) > 0 {
+				lines = append(lines, line)
+			}
+			line = line[len(line):]
+		default:
+			line = append(line, c)
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,176 @@
+package sniff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	longLine := strings.Repeat("a,", SampleSize)
+
+	tests := []struct {
+		desc       string
+		sample     string
+		partial    bool
+		wantFormat properties.DataFormat
+		wantReason string
+	}{
+		{desc: "parquet", sample: "PAR1\x15\x04\x15", wantFormat: properties.Parquet},
+		{desc: "avro", sample: "Obj\x01\x04\x14", wantFormat: properties.AVRO},
+		{desc: "orc", sample: "ORC\x0a\x03", wantFormat: properties.ORC},
+		{desc: "empty", sample: "", wantFormat: properties.CSV},
+		{desc: "json lines", sample: "{\"a\": 1}\n{\"a\": 2}\n", wantFormat: properties.JSON},
+		{desc: "json lines with bom", sample: "\xef\xbb\xbf  {\"a\": 1}", wantFormat: properties.JSON},
+		{desc: "json object spanning lines", sample: "{\n  \"a\": 1\n}\n", wantFormat: properties.MultiJSON},
+		{desc: "json object longer than the sample", sample: "{\"a\": \"" + longLine, partial: true, wantFormat: properties.MultiJSON},
+		{desc: "json array", sample: "[{\"a\": 1}, {\"a\": 2}]", wantFormat: properties.MultiJSON},
+		{desc: "csv", sample: "a,b,c\n1,2,3\r\n4,5,6", wantFormat: properties.CSV},
+		{desc: "csv with quoted separators", sample: "a,\"b;c\nd\",e\n1,\"2;3\",4\n", wantFormat: properties.CSV},
+		{desc: "csv with a cut last line", sample: "a,b\n1,2\n3,4,", partial: true, wantFormat: properties.CSV},
+		{desc: "csv longer than the sample", sample: longLine, partial: true, wantFormat: properties.CSV},
+		{desc: "tsv", sample: "a\tb\n1\t2\n", wantFormat: properties.TSV},
+		{desc: "tsv with commas in fields", sample: "a,x\tb\n1\t2\n", wantFormat: properties.TSV},
+		{desc: "scsv", sample: "a;b;c\n1;2;3\n", wantFormat: properties.SCSV},
+		{desc: "psv", sample: "a|b\n1|2\n", wantFormat: properties.PSV},
+		{desc: "sohsv", sample: "a\x01b\n1\x012\n", wantFormat: properties.SOHSV},
+		{desc: "single column", sample: "a\nb\nc\n", wantFormat: properties.CSV},
+		{desc: "utf-8 rune cut by the sample", sample: "a,b\n\xc3\xa9,\xc3", partial: true, wantFormat: properties.CSV},
+		{
+			desc:       "inconsistent separators",
+			sample:     "a,b,c\n1,2\n",
+			wantFormat: properties.CSV,
+			wantReason: `',' separates a different amount of fields in different lines`,
+		},
+		{
+			desc:       "equally likely separators",
+			sample:     "a,b|c\n1,2|3\n",
+			wantFormat: properties.CSV,
+			wantReason: "several separators appear the same amount of times in every line",
+		},
+		{desc: "binary", sample: "\x00\x01\x02\xff", wantReason: "the payload is binary data of an unknown format"},
+		{desc: "invalid utf-8", sample: "a,b\n\xff,1\n", wantReason: "the payload is binary data of an unknown format"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			format, reason := Detect([]byte(test.sample), !test.partial)
+			assert.Equal(t, test.wantFormat, format)
+			assert.Equal(t, test.wantReason, reason)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	payload := "{\"a\": 1}\n" + strings.Repeat("{\"a\": 2}\n", SampleSize)
+
+	props := &properties.All{}
+	reader, err := Format(strings.NewReader(payload), props)
+	require.NoError(t, err)
+	assert.Equal(t, properties.JSON, props.Ingestion.Additional.Format)
+
+	// The bytes read to detect the format are not lost.
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, payload, string(got))
+}
+
+func TestFormatSet(t *testing.T) {
+	t.Parallel()
+
+	props := &properties.All{}
+	props.Ingestion.Additional.Format = properties.CSV
+
+	original := strings.NewReader("{\"a\": 1}")
+	reader, err := Format(original, props)
+	require.NoError(t, err)
+	assert.Equal(t, properties.CSV, props.Ingestion.Additional.Format)
+	assert.Same(t, original, reader)
+}
+
+func TestFormatGzip(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("a\tb\n1\t2\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	props := &properties.All{}
+	props.Source.CompressionType = ingestoptions.GZIP
+	reader, err := Format(bytes.NewReader(buf.Bytes()), props)
+	require.NoError(t, err)
+	assert.Equal(t, properties.TSV, props.Ingestion.Additional.Format)
+
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), got)
+}
+
+func TestFormatErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc        string
+		payload     string
+		compression ingestoptions.CompressionType
+		wantErr     string
+	}{
+		{
+			desc:    "guess",
+			payload: "a,b,c\n1,2\n",
+			wantErr: `the format of the data could not be detected: ',' separates a different amount of fields in different lines. The best guess is csv; if it is correct, set it with the FileFormat(azkustoingest.CSV) option, otherwise set the actual format with FileFormat()`,
+		},
+		{
+			desc:    "no guess",
+			payload: "\x00\x01",
+			wantErr: "the format of the data could not be detected: the payload is binary data of an unknown format. Set the format with the FileFormat() option",
+		},
+		{
+			desc:        "zip",
+			payload:     "PK\x03\x04",
+			compression: ingestoptions.ZIP,
+			wantErr:     "the format of the data could not be detected: the format of zip payloads is not detected. Set the format with the FileFormat() option",
+		},
+		{
+			desc:        "invalid gzip",
+			payload:     "a,b",
+			compression: ingestoptions.GZIP,
+			wantErr:     "the format of the data could not be detected: the gzip payload could not be read (unexpected EOF). Set the format with the FileFormat() option",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			props := &properties.All{}
+			props.Source.CompressionType = test.compression
+			_, err := Format(strings.NewReader(test.payload), props)
+			require.Error(t, err)
+
+			e, ok := errors.GetKustoError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.KClientArgs, e.Kind)
+			assert.Contains(t, err.Error(), test.wantErr)
+			assert.Equal(t, properties.DFUnknown, props.Ingestion.Additional.Format)
+		})
+	}
+}
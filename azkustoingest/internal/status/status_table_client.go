@@ -65,3 +65,15 @@ func (c *TableClient) Write(ingestionSourceID string, data map[string]interface{
 
 	return nil
 }
+
+// Upsert writes a table record containing ingestion status, replacing the existing record if any.
+func (c *TableClient) Upsert(ingestionSourceID string, data map[string]interface{}) error {
+	var emptyID = uuid.Nil.String()
+	entity := c.table.GetEntityReference(ingestionSourceID, emptyID)
+	entity.Properties = data
+
+	options := &storage.EntityOptions{}
+	options.Timeout = defaultTimeoutMsec
+
+	return entity.InsertOrReplace(options)
+}
@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/avro"
+)
+
+// maxAvroHeaderSize bounds the header of an Avro file, which is buffered until it is complete.
+const maxAvroHeaderSize = 4 * 1024 * 1024
+
+type avroState int
+
+const (
+	avroHeader avroState = iota
+	avroCount
+	avroSize
+	avroData
+	avroSync
+)
+
+// avroFile validates an Avro object container file: its header, the framing of its blocks, and that the schema of its
+// records is compatible with the expected schema, if any. The records themselves are not decoded.
+type avroFile struct {
+	schema *avro.Schema
+
+	state   avroState
+	pending []byte
+	sync    [avro.SyncSize]byte
+	block   int
+	records int64
+	// remaining is the amount of bytes left in the data or the sync marker of the current block.
+	remaining int64
+}
+
+func newAvroFile(schema *avro.Schema) *avroFile {
+	return &avroFile{schema: schema}
+}
+
+func (a *avroFile) write(b []byte) error {
+	a.pending = append(a.pending, b...)
+	n, err := a.consume(a.pending)
+	a.pending = append(a.pending[:0], a.pending[n:]...)
+	return err
+}
+
+// consume validates as much of b as possible, and returns the amount of bytes used.
+func (a *avroFile) consume(b []byte) (int, error) {
+	pos := 0
+	for pos < len(b) {
+		switch a.state {
+		case avroHeader:
+			header, n, err := avro.ReadHeader(b[pos:])
+			if errors.Is(err, avro.ErrShort) {
+				if len(b)-pos > maxAvroHeaderSize {
+					return pos, fmt.Errorf("the Avro header is larger than %d bytes", maxAvroHeaderSize)
+				}
+				return pos, nil
+			}
+			if err != nil {
+				return pos, err
+			}
+			if err := a.checkHeader(header); err != nil {
+				return pos, err
+			}
+			pos += n
+			a.state = avroCount
+		case avroCount, avroSize:
+			v, n, err := avro.ReadLong(b[pos:])
+			if errors.Is(err, avro.ErrShort) {
+				return pos, nil
+			}
+			if err != nil {
+				return pos, fmt.Errorf("block %d (after record %d): %s", a.block+1, a.records, err)
+			}
+			pos += n
+			if a.state == avroCount {
+				if v <= 0 {
+					return pos, fmt.Errorf("block %d (after record %d) has an invalid record count %d", a.block+1, a.records, v)
+				}
+				a.block++
+				a.records += v
+				a.state = avroSize
+			} else {
+				if v < 0 {
+					return pos, fmt.Errorf("block %d (after record %d) has an invalid size %d", a.block, a.records, v)
+				}
+				a.remaining = v
+				a.state = avroData
+			}
+		case avroData:
+			n := int64(len(b) - pos)
+			if n > a.remaining {
+				n = a.remaining
+			}
+			pos += int(n)
+			a.remaining -= n
+			if a.remaining == 0 {
+				a.remaining = avro.SyncSize
+				a.state = avroSync
+			}
+		case avroSync:
+			offset := avro.SyncSize - a.remaining
+			n := int64(len(b) - pos)
+			if n > a.remaining {
+				n = a.remaining
+			}
+			if !bytes.Equal(b[pos:pos+int(n)], a.sync[offset:offset+n]) {
+				return pos, fmt.Errorf("block %d (records %d and before) does not end with the sync marker of the file", a.block, a.records)
+			}
+			pos += int(n)
+			a.remaining -= n
+			if a.remaining == 0 {
+				a.state = avroCount
+			}
+		}
+	}
+	return pos, nil
+}
+
+func (a *avroFile) checkHeader(h *avro.Header) error {
+	a.sync = h.Sync
+
+	if codec := string(h.Meta["avro.codec"]); !avro.Codecs[codec] {
+		return fmt.Errorf("the Avro codec %q is not supported", codec)
+	}
+
+	if a.schema == nil {
+		return nil
+	}
+	writer, err := avro.ParseSchema(string(h.Meta["avro.schema"]))
+	if err != nil {
+		return fmt.Errorf("the schema of the data is invalid: %s", err)
+	}
+	return a.schema.CheckWriter(writer)
+}
+
+func (a *avroFile) finish() error {
+	switch {
+	case a.state == avroHeader:
+		return errors.New("the Avro header is truncated")
+	case a.state != avroCount || len(a.pending) > 0:
+		return fmt.Errorf("block %d (records %d and before) is truncated", a.block, a.records)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+// Package validation provides incremental validation of text payloads while they are being uploaded, so that a
+// malformed source fails on the client as soon as the corruption is read instead of later in the service.
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/avro"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/utils"
+)
+
+// validator is fed the payload chunk by chunk. finish is called once the payload has been fully read.
+type validator interface {
+	write(b []byte) error
+	finish() error
+}
+
+// Reader implements an io.Reader that validates the data passing through it. The first violation found stops the
+// stream: Read returns the error and Err will report it from then on.
+type Reader struct {
+	reader io.Reader
+	v      validator
+	err    atomic.Value // holds *errors.Error
+}
+
+// Wrap wraps reader with a validating Reader if props requested payload validation and the data format supports it.
+// Avro payloads are always validated against the Avro schema of props, if set.
+// It returns the io.Reader to use in place of reader and the validating Reader, which is nil if no validation is done.
+// Payloads that are already compressed are never validated.
+func Wrap(reader io.Reader, props *properties.All) (io.Reader, *Reader) {
+	if !props.Source.ValidatePayload && props.Source.AvroSchema == "" {
+		return reader, nil
+	}
+
+	compression := props.Source.CompressionType
+	if compression == ingestoptions.CTUnknown {
+		compression = utils.CompressionDiscovery(props.Source.OriginalSource)
+	}
+	if compression != ingestoptions.CTUnknown && compression != ingestoptions.CTNone {
+		return reader, nil
+	}
+
+	format := props.Ingestion.Additional.Format
+	if format == properties.DFUnknown {
+		format = properties.DataFormatDiscovery(props.Source.OriginalSource)
+	}
+	if format == properties.DFUnknown {
+		format = properties.CSV
+	}
+
+	v := newValidator(format, props)
+	if v == nil {
+		return reader, nil
+	}
+
+	r := &Reader{reader: reader, v: v}
+	return r, r
+}
+
+func newValidator(format properties.DataFormat, props *properties.All) validator {
+	if !props.Source.ValidatePayload && format != properties.AVRO {
+		return nil
+	}
+
+	switch format {
+	case properties.AVRO:
+		// The schema was validated by the AvroSchema option.
+		schema, _ := avro.ParseSchema(props.Source.AvroSchema)
+		return newAvroFile(schema)
+	case properties.CSV:
+		return newSeparatedValues(',', false)
+	case properties.PSV:
+		return newSeparatedValues('|', false)
+	case properties.SCSV:
+		return newSeparatedValues(';', false)
+	case properties.SOHSV:
+		return newSeparatedValues('\x01', false)
+	case properties.TSV:
+		return newSeparatedValues('\t', false)
+	case properties.TSVE:
+		return newSeparatedValues('\t', true)
+	case properties.JSON:
+		return newJSONRecords(false)
+	case properties.MultiJSON:
+		return newJSONRecords(true)
+	}
+	return nil
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(b []byte) (int, error) {
+	if err := r.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		if verr := r.v.write(b[:n]); verr != nil {
+			return 0, r.fail(verr)
+		}
+	}
+
+	if err == io.EOF {
+		if verr := r.v.finish(); verr != nil {
+			return 0, r.fail(verr)
+		}
+	}
+
+	return n, err
+}
+
+// Err returns the validation error found so far, if any. It is safe to call on a nil *Reader.
+func (r *Reader) Err() error {
+	if r == nil {
+		return nil
+	}
+
+	if err, ok := r.err.Load().(*errors.Error); ok {
+		return err
+	}
+	return nil
+}
+
+func (r *Reader) fail(err error) error {
+	e := errors.E(errors.OpFileIngest, errors.KClientArgs, fmt.Errorf("payload validation failed: %w", err)).SetNoRetry()
+	r.err.Store(e)
+	return e
+}
+
+// separatedValues validates the CSV family of formats. Each record must have the same amount of fields as the first
+// record. Quoting follows RFC 4180, and TSVE additionally escapes characters with a backslash.
+type separatedValues struct {
+	sep          byte
+	backslashEsc bool
+
+	line       int
+	record     int
+	recordLine int
+	fields     int
+	expected   int
+
+	empty        bool
+	fieldStart   bool
+	inQuotes     bool
+	quotePending bool
+	escaped      bool
+}
+
+func newSeparatedValues(sep byte, backslashEsc bool) *separatedValues {
+	return &separatedValues{
+		sep:          sep,
+		backslashEsc: backslashEsc,
+		line:         1,
+		expected:     -1,
+		empty:        true,
+		fieldStart:   true,
+	}
+}
+
+func (s *separatedValues) write(b []byte) error {
+	for _, c := range b {
+		if s.escaped {
+			s.escaped = false
+			if c == '\n' {
+				s.line++
+			}
+			continue
+		}
+
+		if s.inQuotes {
+			if !s.quotePending {
+				switch c {
+				case '"':
+					s.quotePending = true
+				case '\n':
+					s.line++
+				}
+				continue
+			}
+
+			// A quote inside a quoted field is either the first half of an escaped quote or the closing quote.
+			s.quotePending = false
+			if c == '"' {
+				continue
+			}
+			s.inQuotes = false
+		}
+
+		switch {
+		case c == '\r':
+		case c == '\n':
+			if err := s.endRecord(); err != nil {
+				return err
+			}
+			s.line++
+		case c == s.sep:
+			s.markData()
+			s.fields++
+			s.fieldStart = true
+		case c == '"' && s.fieldStart:
+			s.markData()
+			s.inQuotes = true
+			s.fieldStart = false
+		case c == '\\' && s.backslashEsc:
+			s.markData()
+			s.escaped = true
+			s.fieldStart = false
+		default:
+			s.markData()
+			s.fieldStart = false
+		}
+	}
+	return nil
+}
+
+func (s *separatedValues) finish() error {
+	if s.inQuotes && !s.quotePending {
+		return &errors.RecordError{Record: s.record + 1, Line: s.recordLine, Err: fmt.Errorf("has an unterminated quoted field")}
+	}
+	return s.endRecord()
+}
+
+// markData records that the current record has content, remembering the line it started on.
+func (s *separatedValues) markData() {
+	if s.empty {
+		s.empty = false
+		s.recordLine = s.line
+	}
+}
+
+func (s *separatedValues) endRecord() error {
+	if s.empty {
+		return nil
+	}
+
+	s.record++
+	count := s.fields + 1
+	if s.expected < 0 {
+		s.expected = count
+	} else if count != s.expected {
+		return &errors.RecordError{Record: s.record, Line: s.recordLine, Err: fmt.Errorf("has %d fields, expected %d", count, s.expected)}
+	}
+
+	s.fields = 0
+	s.empty = true
+	s.fieldStart = true
+	s.inQuotes = false
+	s.quotePending = false
+	return nil
+}
+
+// jsonRecords validates a stream of JSON objects. When allowArray is set (MultiJSON), the objects may also be
+// wrapped in top-level arrays.
+type jsonRecords struct {
+	allowArray bool
+
+	line       int
+	record     int
+	recordLine int
+	depth      int
+	inArray    bool
+	inString   bool
+	escaped    bool
+	buf        []byte
+}
+
+func newJSONRecords(allowArray bool) *jsonRecords {
+	return &jsonRecords{allowArray: allowArray, line: 1}
+}
+
+func (j *jsonRecords) write(b []byte) error {
+	for _, c := range b {
+		if c == '\n' {
+			j.line++
+		}
+
+		if j.depth == 0 {
+			switch {
+			case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			case c == '[' && j.allowArray && !j.inArray:
+				j.inArray = true
+			case c == ']' && j.inArray:
+				j.inArray = false
+			case c == ',' && j.inArray:
+			case c == '{':
+				j.depth = 1
+				j.recordLine = j.line
+				j.buf = append(j.buf[:0], c)
+			default:
+				return fmt.Errorf("line %d: unexpected character %q, expected the start of a JSON object", j.line, c)
+			}
+			continue
+		}
+
+		j.buf = append(j.buf, c)
+
+		if j.inString {
+			switch {
+			case j.escaped:
+				j.escaped = false
+			case c == '\\':
+				j.escaped = true
+			case c == '"':
+				j.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			j.inString = true
+		case '{', '[':
+			j.depth++
+		case '}', ']':
+			j.depth--
+			if j.depth == 0 {
+				j.record++
+				if !json.Valid(j.buf) {
+					return &errors.RecordError{Record: j.record, Line: j.recordLine, Err: fmt.Errorf("is not a valid JSON object")}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (j *jsonRecords) finish() error {
+	if j.depth > 0 {
+		return &errors.RecordError{Record: j.record + 1, Line: j.recordLine, Err: fmt.Errorf("is truncated")}
+	}
+	if j.inArray {
+		return fmt.Errorf("line %d: JSON array is not terminated", j.line)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package validation
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/avro"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		format  properties.DataFormat
+		payload string
+		wantErr string
+	}{
+		{desc: "csv: valid", format: properties.CSV, payload: "a,b,c\n1,2,3\r\n4,5,6"},
+		{desc: "csv: empty lines are skipped", format: properties.CSV, payload: "a,b\n\n1,2\n\n"},
+		{desc: "csv: quoted separators and newlines", format: properties.CSV, payload: "a,\"b,\nc\",d\n1,\"2\"\"\",3\n"},
+		{desc: "csv: too many fields", format: properties.CSV, payload: "a,b\n1,2\n1,2,3\n", wantErr: "record 3 (line 3) has 3 fields, expected 2"},
+		{desc: "csv: too few fields after multiline record", format: properties.CSV, payload: "a,\"x\ny\"\n1\n", wantErr: "record 2 (line 3) has 1 fields, expected 2"},
+		{desc: "csv: unterminated quote", format: properties.CSV, payload: "a,b\n1,\"2\n", wantErr: "record 2 (line 2) has an unterminated quoted field"},
+		{desc: "psv: valid", format: properties.PSV, payload: "a|b\n1|2\n"},
+		{desc: "scsv: invalid", format: properties.SCSV, payload: "a;b\n1\n", wantErr: "record 2 (line 2) has 1 fields, expected 2"},
+		{desc: "sohsv: valid", format: properties.SOHSV, payload: "a\x01b\n1\x012\n"},
+		{desc: "tsv: invalid", format: properties.TSV, payload: "a\tb\n1\t2\t3\n", wantErr: "record 2 (line 2) has 3 fields, expected 2"},
+		{desc: "tsve: escaped separator", format: properties.TSVE, payload: "a\tb\n1\\\t2\t3\n"},
+		{desc: "json: valid", format: properties.JSON, payload: "{\"a\": 1}\n{\"a\": \"}{\"}\n\n{\n\"a\": [1, 2]\n}\n"},
+		{desc: "json: invalid record", format: properties.JSON, payload: "{\"a\": 1}\n{\"a\": 1,}\n", wantErr: "record 2 (line 2) is not a valid JSON object"},
+		{desc: "json: arrays are not allowed", format: properties.JSON, payload: "[{\"a\": 1}]", wantErr: "line 1: unexpected character '[', expected the start of a JSON object"},
+		{desc: "json: truncated", format: properties.JSON, payload: "{\"a\": 1}\n{\"a\": ", wantErr: "record 2 (line 2) is truncated"},
+		{desc: "multijson: valid", format: properties.MultiJSON, payload: "[\n{\"a\": 1},\n{\"a\": 2}\n]\n{\"a\": 3}"},
+		{desc: "multijson: garbage between records", format: properties.MultiJSON, payload: "{\"a\": 1}\nfoo\n", wantErr: "line 2: unexpected character 'f', expected the start of a JSON object"},
+		{desc: "multijson: unterminated array", format: properties.MultiJSON, payload: "[{\"a\": 1},\n{\"a\": 2}", wantErr: "line 2: JSON array is not terminated"},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			props := &properties.All{}
+			props.Source.ValidatePayload = true
+			props.Ingestion.Additional.Format = test.format
+
+			// Reading a byte at a time makes sure that state is kept correctly across reads.
+			reader, validator := Wrap(iotest.OneByteReader(strings.NewReader(test.payload)), props)
+			require.NotNil(t, validator)
+
+			got, err := io.ReadAll(reader)
+			if test.wantErr == "" {
+				require.NoError(t, err)
+				assert.NoError(t, validator.Err())
+				assert.Equal(t, test.payload, string(got))
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+			assert.Equal(t, err, validator.Err())
+			assert.False(t, errors.Retry(err))
+
+			// The errors of records locate them.
+			if strings.HasPrefix(test.wantErr, "record ") {
+				var recordErr *errors.RecordError
+				require.ErrorAs(t, err, &recordErr)
+				assert.Equal(t, test.wantErr, recordErr.Error())
+				assert.Positive(t, recordErr.Record)
+				assert.Positive(t, recordErr.Line)
+			}
+		})
+	}
+}
+
+const avroSchema = `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "string"}]}`
+
+// avroPayload returns an Avro file with the schema and codec, and blocks of the given record counts and data.
+func avroPayload(schema string, codec string, blocks ...string) string {
+	sync := [avro.SyncSize]byte{'s', 'y', 'n', 'c', 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	b := avro.AppendHeader(nil, &avro.Header{Meta: map[string][]byte{"avro.schema": []byte(schema), "avro.codec": []byte(codec)}, Sync: sync})
+	for _, block := range blocks {
+		b = avro.AppendLong(b, 2)
+		b = avro.AppendLong(b, int64(len(block)))
+		b = append(b, block...)
+		b = append(b, sync[:]...)
+	}
+	return string(b)
+}
+
+func TestAvro(t *testing.T) {
+	t.Parallel()
+
+	valid := avroPayload(avroSchema, "null", "block1", "", "block3")
+	tests := []struct {
+		desc     string
+		schema   string
+		validate bool
+		payload  string
+		wantErr  string
+	}{
+		{desc: "valid", schema: avroSchema, payload: valid},
+		{desc: "framing only", validate: true, payload: valid},
+		{
+			desc:    "compatible schema",
+			schema:  `{"type": "record", "name": "R", "fields": [{"name": "b", "type": "string"}]}`,
+			payload: valid,
+		},
+		{
+			desc:    "incompatible schema",
+			schema:  `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "long"}]}`,
+			payload: valid,
+			wantErr: `field "a" has type "int" in the schema of the data, expected "long"`,
+		},
+		{desc: "unsupported codec", schema: avroSchema, payload: avroPayload(avroSchema, "bzip2", "block1"), wantErr: `the Avro codec "bzip2" is not supported`},
+		{desc: "not avro", schema: avroSchema, payload: "a,b\n1,2\n", wantErr: "not an Avro object container file"},
+		{desc: "empty", schema: avroSchema, payload: "", wantErr: "the Avro header is truncated"},
+		{desc: "truncated header", schema: avroSchema, payload: valid[:20], wantErr: "the Avro header is truncated"},
+		{desc: "truncated block", schema: avroSchema, payload: valid[:len(valid)-3], wantErr: "block 3 (records 6 and before) is truncated"},
+		{
+			desc:    "wrong sync marker",
+			schema:  avroSchema,
+			payload: strings.Replace(valid, "block1sync", "block1SYNC", 1),
+			wantErr: "block 1 (records 2 and before) does not end with the sync marker of the file",
+		},
+		{
+			desc:    "invalid record count",
+			schema:  avroSchema,
+			payload: valid + string(avro.AppendLong(nil, -1)),
+			wantErr: "block 4 (after record 6) has an invalid record count -1",
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			props := &properties.All{}
+			props.Source.AvroSchema = test.schema
+			props.Source.ValidatePayload = test.validate
+			props.Ingestion.Additional.Format = properties.AVRO
+
+			reader, validator := Wrap(iotest.OneByteReader(strings.NewReader(test.payload)), props)
+			require.NotNil(t, validator)
+
+			got, err := io.ReadAll(reader)
+			if test.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, test.payload, string(got))
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+			assert.False(t, errors.Retry(err))
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		props    properties.All
+		validate bool
+	}{
+		{desc: "not requested", props: properties.All{}},
+		{
+			desc:     "avro schema",
+			props:    properties.All{Source: properties.SourceOptions{AvroSchema: avroSchema, OriginalSource: "/path/to/file.avro"}},
+			validate: true,
+		},
+		{
+			desc:  "avro schema of another format",
+			props: properties.All{Source: properties.SourceOptions{AvroSchema: avroSchema, OriginalSource: "/path/to/file.csv"}},
+		},
+		{
+			desc:     "format from file name",
+			props:    properties.All{Source: properties.SourceOptions{ValidatePayload: true, OriginalSource: "/path/to/file.json"}},
+			validate: true,
+		},
+		{
+			desc:     "defaults to csv",
+			props:    properties.All{Source: properties.SourceOptions{ValidatePayload: true}},
+			validate: true,
+		},
+		{
+			desc: "unsupported format",
+			props: properties.All{
+				Source:    properties.SourceOptions{ValidatePayload: true},
+				Ingestion: properties.Ingestion{Additional: properties.Additional{Format: properties.Parquet}},
+			},
+		},
+		{
+			desc:  "compressed file",
+			props: properties.All{Source: properties.SourceOptions{ValidatePayload: true, OriginalSource: "/path/to/file.csv.gz"}},
+		},
+		{
+			desc:  "compression type",
+			props: properties.All{Source: properties.SourceOptions{ValidatePayload: true, CompressionType: ingestoptions.ZIP}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			original := strings.NewReader("")
+			reader, validator := Wrap(original, &test.props)
+			if test.validate {
+				assert.NotNil(t, validator)
+				assert.Equal(t, io.Reader(validator), reader)
+			} else {
+				assert.Nil(t, validator)
+				assert.Equal(t, io.Reader(original), reader)
+				assert.NoError(t, validator.Err())
+			}
+		})
+	}
+}
@@ -3,17 +3,23 @@ package azkustoingest
 import (
 	"bytes"
 	"context"
+	goErrors "errors"
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustodata"
 	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/queued"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/gzip"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/quota"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/sniff"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/utils"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/validation"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/google/uuid"
@@ -27,32 +33,49 @@ const (
 	retryCount             = 2
 )
 
+// Managed ingests data with streaming ingestion, and falls back to queued ingestion for large payloads or when
+// streaming fails transiently, or when the service doesn't support streaming ingestion.
+// A Managed client is safe for concurrent use by multiple goroutines, and should be shared instead of created per call.
 type Managed struct {
 	queued    *Ingestion
 	streaming *Streaming
+	// defaultOptions are applied to every ingestion, see WithDefaultOptions.
+	defaultOptions []FileOption
+
+	// noStreaming is set if the service doesn't accept streaming ingestion, as read once from its capabilities.
+	checkStreaming sync.Once
+	noStreaming    bool
 }
 
 // NewManaged is a constructor for Managed.
 func NewManaged(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*Managed, error) {
 	o := getOptions(options)
+	if err := validateDefaultOptions(o.defaultOptions, ManagedClient); err != nil {
+		return nil, err
+	}
+	if err := validateCompression(o); err != nil {
+		return nil, err
+	}
 
 	queuedKcsb := kcsb
 	if o.customIngestConnectionString != nil {
 		queuedKcsb = o.customIngestConnectionString
 	}
 
-	queued, err := New(queuedKcsb, options...)
+	// The default options are applied by the Managed client, so they're only validated for it.
+	queued, err := newQueued(queuedKcsb, getOptions(options))
 	if err != nil {
 		return nil, err
 	}
-	streaming, err := NewStreaming(kcsb, options...)
+	streaming, err := newStreaming(kcsb, getOptions(options))
 	if err != nil {
 		return nil, err
 	}
 
 	return &Managed{
-		queued:    queued,
-		streaming: streaming,
+		queued:         queued,
+		streaming:      streaming,
+		defaultOptions: o.defaultOptions,
 	}, nil
 }
 
@@ -67,6 +90,11 @@ func newManagedFromClients(queued *Ingestion, streaming *Streaming) *Managed {
 // If failed permanently - return err,nil.
 // If failed transiently - return nil,nil.
 func (m *Managed) streamWithRetries(ctx context.Context, payloadProvider func() io.Reader, props properties.All, isBlobUri bool) (*Result, error) {
+	if !m.streamingSupported(ctx) {
+		// Caller should fallback to queued
+		return nil, nil
+	}
+
 	var result *Result
 
 	hasCustomId := props.Streaming.ClientRequestId != ""
@@ -80,9 +108,12 @@ func (m *Managed) streamWithRetries(ctx context.Context, payloadProvider func()
 		if !hasCustomId {
 			props.Streaming.ClientRequestId = fmt.Sprintf("KGC.executeManagedStreamingIngest;%s;%d", managedUuid, i)
 		}
-		result, err = streamImpl(m.streaming.streamConn, ctx, payloadProvider(), props, isBlobUri)
+		result, err = streamImpl(m.streaming.streamConn, ctx, payloadProvider(), props, isBlobUri, m.streaming.statusBackend)
 		i++
 		if err != nil {
+			if shouldFallbackToQueued(err) {
+				return backoff.Permanent(err)
+			}
 			if e, ok := err.(*errors.Error); ok {
 				if errors.Retry(e) {
 					return err
@@ -100,15 +131,50 @@ func (m *Managed) streamWithRetries(ctx context.Context, payloadProvider func()
 		return result, nil
 	}
 
-	if errors.Retry(err) {
+	if errors.Retry(err) || shouldFallbackToQueued(err) {
 		// Caller should fallback to queued
+		log.Writef(log.EventIngest, "falling back to queued ingestion into %s.%s after streaming failed: %s",
+			props.Ingestion.DatabaseName, props.Ingestion.TableName, err)
 		return nil, nil
 	}
 
 	return nil, err
 }
 
+// capabilitiesClient is implemented by the clients that know the capabilities of the service, such as
+// azkustodata.Client.
+type capabilitiesClient interface {
+	Capabilities(ctx context.Context) (azkustodata.Capabilities, error)
+}
+
+// streamingSupported reports whether the service accepts streaming ingestion, as read from its capabilities on the first
+// call. Streaming is attempted if the capabilities can't be read.
+func (m *Managed) streamingSupported(ctx context.Context) bool {
+	m.checkStreaming.Do(func() {
+		client, ok := m.streaming.client.(capabilitiesClient)
+		if !ok {
+			return
+		}
+		caps, err := client.Capabilities(ctx)
+		if err != nil {
+			log.Writef(log.EventIngest, "could not read the capabilities of %s, attempting streaming ingestion: %s", m.streaming.client.Endpoint(), err)
+			return
+		}
+		if !caps.StreamingIngest {
+			log.Writef(log.EventIngest, "%s doesn't support streaming ingestion, the data will be queued", m.streaming.client.Endpoint())
+			m.noStreaming = true
+		}
+	})
+	return !m.noStreaming
+}
+
+// shouldFallbackToQueued reports whether a streaming error can't be fixed by retrying, but queued ingestion may succeed.
+func shouldFallbackToQueued(err error) bool {
+	return goErrors.Is(err, ErrStreamingPolicyDisabled) || goErrors.Is(err, ErrPayloadTooLarge)
+}
+
 func (m *Managed) FromFile(ctx context.Context, fPath string, options ...FileOption) (*Result, error) {
+	options, _ = withDefaults(m.defaultOptions, options, FromFile)
 	props := m.newProp()
 	file, err, local := prepFileAndProps(fPath, &props, options, ManagedClient)
 	if err != nil {
@@ -156,8 +222,12 @@ func shouldUseQueuedIngestBySize(compression ingestoptions.CompressionType, file
 }
 
 func (m *Managed) FromReader(ctx context.Context, reader io.Reader, options ...FileOption) (*Result, error) {
+	options, _ = withDefaults(m.defaultOptions, options, FromReader)
 	props := m.newProp()
 
+	if err := validateOptions(options, ManagedClient, FromReader); err != nil {
+		return nil, err
+	}
 	for _, prop := range options {
 		err := prop.Run(&props, ManagedClient, FromReader)
 		if err != nil {
@@ -165,38 +235,75 @@ func (m *Managed) FromReader(ctx context.Context, reader io.Reader, options ...F
 		}
 	}
 
-	return m.managedStreamImpl(ctx, io.NopCloser(reader), props)
+	reader, err := sniff.Format(reader, &props)
+	if err != nil {
+		return nil, err
+	}
+	if err := completeMappingKind(&props); err != nil {
+		return nil, err
+	}
+	reader, transformer, err := transformRecords(reader, props, m.streaming.recordTransform)
+	if err != nil {
+		return nil, err
+	}
+	defer transformer.Close()
+
+	result, err := m.managedStreamImpl(ctx, io.NopCloser(reader), props)
+	return result, transformer.failure(err)
 }
 
 func (m *Managed) managedStreamImpl(ctx context.Context, payload io.ReadCloser, props properties.All) (*Result, error) {
 	defer payload.Close()
 	compress := queued.ShouldCompress(&props, ingestoptions.CTUnknown)
-	var compressed io.Reader = payload
+	compressed, validator := validation.Wrap(payload, &props)
 	if compress {
-		compressed = gzip.Compress(io.NopCloser(payload))
+		streamer := gzip.CompressContextLevel(ctx, io.NopCloser(compressed), queued.CompressionLevel(&props))
+		defer streamer.Close()
+		compressed = streamer
 		props.Source.DontCompress = true
 	}
+	// The payload is validated here, before it is compressed, so the ingestion methods below must not validate again.
+	props.Source.ValidatePayload = false
+	props.Source.AvroSchema = ""
 
 	maxSize := maxStreamingSize
 
+	reserved := maxSize + 1
+	if compress {
+		reserved += quota.CompressionBufferSize
+	}
+	release, err := quota.Global.Acquire(ctx, reserved)
+	if err != nil {
+		return nil, err
+	}
+	// The queued fallbacks acquire the buffers of their upload, so the quota is released before them, as waiting for
+	// them while holding it could wait forever.
+	defer release()
+
 	buf, err := io.ReadAll(io.LimitReader(compressed, int64(maxSize+1)))
 	if err != nil {
 		return nil, err
 	}
 
 	if shouldUseQueuedIngestBySize(ingestoptions.GZIP, int64(len(buf))) {
+		release()
 		combinedBuf := io.MultiReader(bytes.NewReader(buf), compressed)
-		return m.queued.fromReader(ctx, combinedBuf, []FileOption{}, props)
+		res, err := m.queued.fromReader(ctx, combinedBuf, []FileOption{}, props, nil)
+		if err != nil && validator.Err() != nil {
+			return nil, validator.Err()
+		}
+		return res, err
 	}
 
 	res, err := m.streamWithRetries(ctx, func() io.Reader { return bytes.NewReader(buf) }, props, false)
 	if err != nil || res != nil {
 		return res, err
 	}
+	release()
 
 	// Theres no size estimation when ingesting from stream. If we did not already use queued ingestion
 	// we can assume all the original payload reader is < 4mb, therefore no need to combine
-	return m.queued.fromReader(ctx, bytes.NewReader(buf), []FileOption{}, props)
+	return m.queued.fromReader(ctx, bytes.NewReader(buf), []FileOption{}, props, nil)
 }
 
 func (m *Managed) newProp() properties.All {
@@ -204,7 +311,7 @@ func (m *Managed) newProp() properties.All {
 	exp.InitialInterval = defaultInitialInterval
 	exp.Multiplier = defaultMultiplier
 
-	return properties.All{
+	props := properties.All{
 		Ingestion: properties.Ingestion{
 			DatabaseName: m.streaming.db,
 			TableName:    m.streaming.table,
@@ -213,6 +320,8 @@ func (m *Managed) newProp() properties.All {
 			Backoff: exp,
 		},
 	}
+	setCompression(&props, m.streaming.withoutCompression, m.streaming.compressionLevel)
+	return props
 }
 
 func (m *Managed) Close() error {
@@ -13,7 +13,9 @@ import (
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
 	"github.com/cenkalti/backoff/v4"
 	"io"
+	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -258,6 +260,33 @@ func TestManaged(t *testing.T) {
 			expectedCounter: 4,
 			expectedStatus:  Queued,
 		},
+		{
+			name:    "TestStreamingPolicyDisabled",
+			options: []FileOption{},
+			onStreamIngest: func(t *testing.T, ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string,
+				clientRequestId string, isBlobUri bool) error {
+				return streamingHTTPError(http.StatusBadRequest, `{"error": {"code": "BadRequest_StreamingIngestionPolicyNotEnabled", "@permanent": true}}`)
+			},
+			onMgmt: func(t *testing.T, ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+				// .get ingestion resources is always called in the ctor
+				if query.String() == ".get ingestion resources" {
+					return resources.SuccessfulFakeResources().Mgmt(ctx, db, query, options...)
+				}
+				if query.String() == ".get kusto identity token" {
+					return nil, nil
+				}
+
+				require.Fail(t, "Unexpected queued ingest call")
+				return nil, nil
+			},
+			onReader: func(t *testing.T, ctx context.Context, reader io.Reader, props properties.All) (string, error) {
+				counter++
+				return "", nil
+			},
+			// Falls back to queued ingestion without retrying.
+			expectedCounter: 2,
+			expectedStatus:  Queued,
+		},
 		{
 			name:      "TestBigFile",
 			options:   []FileOption{},
@@ -445,3 +474,99 @@ func initFile(t *testing.T, reader *bytes.Reader) ([]byte, []byte) {
 	require.NoError(t, err)
 	return data, compressedBytes
 }
+
+func TestManagedConcurrent(t *testing.T) {
+	t.Parallel()
+
+	rec := &structsRecorder{}
+	managed := newStructsManaged(t, rec)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A backoff holds the state of the retries of a single call, so it can't be shared.
+			off := backoff.NewExponentialBackOff()
+			off.InitialInterval = time.Millisecond
+			if i%2 == 0 {
+				_, err := managed.FromReader(context.Background(), strings.NewReader(`{"Name":"a"}`), FileFormat(JSON), backOff(off))
+				assert.NoError(t, err)
+				return
+			}
+			_, err := managed.FromStructs(context.Background(), []structsRecord{{Name: "b"}}, backOff(off))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, rec.streamed, 20)
+}
+
+func TestManagedFromReaderDetectsFormat(t *testing.T) {
+	t.Parallel()
+
+	off := backoff.NewExponentialBackOff()
+	off.InitialInterval = time.Millisecond
+
+	// The recorder checks that the payload is streamed as JSON.
+	rec := &structsRecorder{}
+	managed := newStructsManaged(t, rec)
+	_, err := managed.FromReader(context.Background(), strings.NewReader("{\"Name\":\"a\"}\n{\"Name\":\"b\"}\n"), backOff(off))
+	require.NoError(t, err)
+	assert.Len(t, rec.streamed, 1)
+
+	_, err = managed.FromReader(context.Background(), strings.NewReader("a,b,c\n1,2\n"), backOff(off))
+	assert.ErrorContains(t, err, "FileFormat(azkustoingest.CSV)")
+	assert.Len(t, rec.streamed, 1)
+}
+
+// capabilitiesMockClient is a mockClient that reports the capabilities of the service.
+type capabilitiesMockClient struct {
+	mockClient
+	caps  azkustodata.Capabilities
+	err   error
+	calls int
+}
+
+func (c *capabilitiesMockClient) Capabilities(context.Context) (azkustodata.Capabilities, error) {
+	c.calls++
+	return c.caps, c.err
+}
+
+func TestManagedStreamingCapabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc         string
+		caps         azkustodata.Capabilities
+		err          error
+		wantStreamed int
+		wantQueued   int
+	}{
+		{desc: "Streaming supported", caps: azkustodata.Capabilities{StreamingIngest: true}, wantStreamed: 2},
+		{desc: "Streaming not supported", caps: azkustodata.Capabilities{ServiceType: azkustodata.ServiceTypeDataManagement}, wantQueued: 2},
+		{desc: "Unknown capabilities", err: errors.ES(errors.OpMgmt, errors.KHTTPError, "unavailable"), wantStreamed: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &structsRecorder{}
+			managed := newStructsManaged(t, rec)
+			client := &capabilitiesMockClient{mockClient: managed.streaming.client.(mockClient), caps: test.caps, err: test.err}
+			managed.streaming.client = client
+
+			for i := 0; i < 2; i++ {
+				_, err := managed.FromReader(context.Background(), strings.NewReader(`{"Name":"a"}`), FileFormat(JSON))
+				require.NoError(t, err)
+			}
+			assert.Len(t, rec.streamed, test.wantStreamed)
+			assert.Len(t, rec.queued, test.wantQueued)
+			assert.Equal(t, 1, client.calls, "the capabilities are read once")
+		})
+	}
+}
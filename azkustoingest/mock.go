@@ -14,13 +14,25 @@ type mockClient struct {
 	endpoint string
 	auth     azkustodata.Authorization
 	onMgmt   func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error)
+	// onIterativeQuery is called by IterativeQuery.
+	onIterativeQuery func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (query.IterativeDataset, error)
 }
 
-func (m mockClient) Query(_ context.Context, _ string, _ azkustodata.Statement, _ ...azkustodata.QueryOption) (query.Dataset, error) {
+func (m mockClient) Query(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (query.Dataset, error) {
+	if m.onIterativeQuery != nil {
+		dataset, err := m.onIterativeQuery(ctx, db, query, options...)
+		if err != nil {
+			return nil, err
+		}
+		return dataset.ToDataset()
+	}
 	panic("not implemented")
 }
 
-func (m mockClient) IterativeQuery(_ context.Context, _ string, _ azkustodata.Statement, _ ...azkustodata.QueryOption) (query.IterativeDataset, error) {
+func (m mockClient) IterativeQuery(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (query.IterativeDataset, error) {
+	if m.onIterativeQuery != nil {
+		return m.onIterativeQuery(ctx, db, query, options...)
+	}
 	panic("not implemented")
 }
 
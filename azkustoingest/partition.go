@@ -0,0 +1,215 @@
+package azkustoingest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+)
+
+// defaultPartitionBatchSize is the default maximum size of the batches of FromReaderPartitioned, before compression.
+// Every target buffers a batch in memory.
+const defaultPartitionBatchSize = int64(64 * mb)
+
+// Partitioner returns the target of a record: the database and the table it is ingested into, and the tags of the
+// extents it is ingested in. An empty database or table selects the default one, of the client or of the Database and
+// Table options. The tags are added to those of the Tags option.
+// record is a line of the input, without its newline, and is only valid until the partitioner returns.
+type Partitioner func(record []byte) (db, table string, tags []string)
+
+// partitionerOption carries the Partitioner of FromReaderPartitioned, which removes it from the options of the batches.
+type partitionerOption struct {
+	option
+	partitioner Partitioner
+}
+
+// WithPartitioner sets the Partitioner that routes the records passed to FromReaderPartitioned. It isn't valid with
+// the other ingestion methods.
+func WithPartitioner(partitioner Partitioner) FileOption {
+	return partitionerOption{
+		option: option{
+			run: func(p *properties.All) error {
+				return errors.ES(errors.OpFileIngest, errors.KClientArgs, "WithPartitioner is only valid with FromReaderPartitioned").SetNoRetry()
+			},
+			sourceScope:  FromReader,
+			clientScopes: QueuedClient,
+			name:         "WithPartitioner",
+		},
+		partitioner: partitioner,
+	}
+}
+
+// PartitionTarget is where a batch of FromReaderPartitioned is ingested.
+type PartitionTarget struct {
+	Database string
+	Table    string
+	// Tags are the tags returned by the Partitioner, without those of the Tags option.
+	Tags []string
+}
+
+// PartitionBatch is the outcome of the ingestion of one batch of FromReaderPartitioned.
+type PartitionBatch struct {
+	Target PartitionTarget
+	// ChunkResult is the outcome of the ingestion. FirstRecord is the index of the first record of the batch in the
+	// input, the other records of the batch may be anywhere after it.
+	ChunkResult
+}
+
+// PartitionResult is the outcome of a FromReaderPartitioned call.
+type PartitionResult struct {
+	// Batches are the batches the records were split into, in the order they were queued.
+	// If the ingestion stopped early, the records that weren't queued yet were not ingested.
+	Batches []PartitionBatch
+}
+
+// Failed returns the batches that failed to ingest.
+func (r *PartitionResult) Failed() []PartitionBatch {
+	var failed []PartitionBatch
+	for _, b := range r.Batches {
+		if b.Err != nil {
+			failed = append(failed, b)
+		}
+	}
+	return failed
+}
+
+// partitionBatch is the batch of a target being buffered.
+type partitionBatch struct {
+	target PartitionTarget
+	first  int
+	count  int
+	buf    bytes.Buffer
+}
+
+// FromReaderPartitioned queues the records of a reader for ingestion into several targets, chosen for every record by
+// the Partitioner set with WithPartitioner, so that a multiplexed stream doesn't need to be split beforehand.
+// The records of every target are batched in memory, and every batch is queued like with FromReader when it reaches
+// batchSize bytes before compression, or when the reader ends. A batchSize of 0 or less selects the default of 64MB.
+//
+// Only the formats with one record per line can be partitioned: CSV, PSV, SCSV, SOHSV, TSV, TSVE, TXT and JSON, and
+// the format must be set with FileFormat. CSV-like records with quoted newlines are kept whole. With IgnoreFirstRecord,
+// the first record of the reader is a header that isn't passed to the Partitioner, and starts every batch.
+//
+// A failed batch doesn't stop the ingestion of the others, the outcome of every batch is reported in the
+// PartitionResult. The returned error combines the errors of all the failed batches.
+func (i *Ingestion) FromReaderPartitioned(ctx context.Context, reader io.Reader, batchSize int64, options ...FileOption) (*PartitionResult, error) {
+	if batchSize <= 0 {
+		batchSize = defaultPartitionBatchSize
+	}
+
+	options, _ = withDefaults(i.defaultOptions, options, FromReader)
+	var partitioner Partitioner
+	batchOptions := make([]FileOption, 0, len(options))
+	for _, o := range options {
+		if p, ok := o.(partitionerOption); ok {
+			partitioner = p.partitioner
+			continue
+		}
+		batchOptions = append(batchOptions, o)
+	}
+	if partitioner == nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromReaderPartitioned requires the WithPartitioner option").SetNoRetry()
+	}
+
+	props := i.newProp()
+	if err := validateOptions(batchOptions, QueuedClient, FromReader); err != nil {
+		return nil, err
+	}
+	for _, o := range batchOptions {
+		if err := o.Run(&props, QueuedClient, FromReader); err != nil {
+			return nil, err
+		}
+	}
+	if !splittableFormat(props.Ingestion.Additional.Format) {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs,
+			"FromReaderPartitioned requires a FileFormat with one record per line, got %v", props.Ingestion.Additional.Format).SetNoRetry()
+	}
+
+	splitter := newRecordSplitter(reader, props)
+	result := &PartitionResult{}
+	var errs []error
+	var header []byte
+
+	batches := map[string]*partitionBatch{}
+
+	ingest := func(key string) {
+		b := batches[key]
+		delete(batches, key)
+
+		opts := append(append([]FileOption(nil), batchOptions...), Database(b.target.Database), Table(b.target.Table))
+		if len(b.target.Tags) > 0 {
+			tags := append(append([]string(nil), props.Ingestion.Additional.Tags...), b.target.Tags...)
+			opts = append(opts, Tags(tags))
+		}
+
+		batch := PartitionBatch{Target: b.target, ChunkResult: ChunkResult{FirstRecord: b.first, Records: b.count, Size: int64(b.buf.Len())}}
+		batch.Result, batch.Err = i.fromReader(ctx, &b.buf, opts, i.newProp(), nil)
+		if batch.Err != nil {
+			errs = append(errs, batch.Err)
+		}
+		result.Batches = append(result.Batches, batch)
+	}
+
+	for n := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return result, errors.CombineErrors(append(errs, err)...)
+		}
+
+		record, err := splitter.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, errors.CombineErrors(append(errs, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to read the records: %s", err).SetNoRetry())...)
+		}
+		line := bytes.TrimRight(record, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		if splitter.repeatHeader && header == nil {
+			header = append(append([]byte(nil), line...), '\n')
+			continue
+		}
+
+		db, table, tags := partitioner(line)
+		target := PartitionTarget{Database: db, Table: table, Tags: tags}
+		if target.Database == "" {
+			target.Database = props.Ingestion.DatabaseName
+		}
+		if target.Table == "" {
+			target.Table = props.Ingestion.TableName
+		}
+		key := target.Database + "\x00" + target.Table + "\x00" + strings.Join(target.Tags, "\x00")
+
+		b, ok := batches[key]
+		if !ok {
+			b = &partitionBatch{target: target, first: n}
+			b.buf.Write(header)
+			batches[key] = b
+		}
+		b.buf.Write(line)
+		b.buf.WriteByte('\n')
+		b.count++
+		n++
+
+		if int64(b.buf.Len()) >= batchSize {
+			ingest(key)
+		}
+	}
+
+	// The last batches are queued in the order of their first record.
+	keys := make([]string, 0, len(batches))
+	for key := range batches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool { return batches[keys[a]].first < batches[keys[b]].first })
+	for _, key := range keys {
+		ingest(key)
+	}
+
+	return result, errors.CombineErrors(errs...)
+}
@@ -0,0 +1,135 @@
+package azkustoingest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partitionByPrefix routes the records by their first field: "a" to the table A with no tags, "b" to the database
+// other and the table B with the tag "b", and the others to the default table.
+func partitionByPrefix(record []byte) (string, string, []string) {
+	switch {
+	case bytes.HasPrefix(record, []byte("a,")):
+		return "", "A", nil
+	case bytes.HasPrefix(record, []byte("b,")):
+		return "other", "B", []string{"b"}
+	}
+	return "", "", nil
+}
+
+func TestFromReaderPartitioned(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		content     string
+		batchSize   int64
+		options     []FileOption
+		fail        func(payload string) bool
+		want        []string
+		wantTargets []PartitionTarget
+		wantBatches []ChunkResult
+		wantErr     bool
+	}{
+		{
+			name:    "records",
+			content: "a,1\nb,2\nc,3\r\n\na,4\nb,\"5\n5\"",
+			want:    []string{"a,1\na,4\n", "b,2\nb,\"5\n5\"\n", "c,3\n"},
+			wantTargets: []PartitionTarget{
+				{Database: "db", Table: "A", Tags: []string{"t"}},
+				{Database: "other", Table: "B", Tags: []string{"t", "b"}},
+				{Database: "db", Table: "table", Tags: []string{"t"}},
+			},
+			wantBatches: []ChunkResult{
+				{FirstRecord: 0, Records: 2, Size: 8},
+				{FirstRecord: 1, Records: 2, Size: 12},
+				{FirstRecord: 2, Records: 1, Size: 4},
+			},
+		},
+		{
+			name:      "batch size",
+			content:   "a,1\na,2\nc,3\na,4\n",
+			batchSize: 8,
+			want:      []string{"a,1\na,2\n", "c,3\n", "a,4\n"},
+			wantBatches: []ChunkResult{
+				{FirstRecord: 0, Records: 2, Size: 8},
+				{FirstRecord: 2, Records: 1, Size: 4},
+				{FirstRecord: 3, Records: 1, Size: 4},
+			},
+		},
+		{
+			name:    "header",
+			content: "Kind,Value\na,1\nc,2\n",
+			options: []FileOption{IgnoreFirstRecord()},
+			want:    []string{"Kind,Value\na,1\n", "Kind,Value\nc,2\n"},
+		},
+		{
+			name:    "failed batch",
+			content: "a,1\nb,2\nc,3\n",
+			fail: func(payload string) bool {
+				return payload == "b,2\n"
+			},
+			want:    []string{"a,1\n", "c,3\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &splitRecorder{fail: test.fail}
+			ingestion := newSplitIngestion(t, rec)
+
+			options := append([]FileOption{FileFormat(CSV), Tags([]string{"t"}), WithPartitioner(partitionByPrefix)}, test.options...)
+			result, err := ingestion.FromReaderPartitioned(context.Background(), strings.NewReader(test.content), test.batchSize, options...)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, result.Failed(), 1)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, result.Failed())
+			}
+
+			assert.Equal(t, test.want, rec.payloads)
+			if test.wantTargets != nil {
+				assert.Equal(t, test.wantTargets, rec.targets)
+			}
+			if test.wantBatches != nil {
+				require.Len(t, result.Batches, len(test.wantBatches))
+				for i, b := range result.Batches {
+					assert.NotNil(t, b.Result)
+					b.Result = nil
+					assert.Equal(t, test.wantBatches[i], b.ChunkResult)
+				}
+			}
+		})
+	}
+}
+
+func TestFromReaderPartitionedErrors(t *testing.T) {
+	t.Parallel()
+
+	ingestion := newSplitIngestion(t, &splitRecorder{})
+	reader := strings.NewReader("a,1\n")
+
+	// The partitioner is required, and only valid with FromReaderPartitioned.
+	_, err := ingestion.FromReaderPartitioned(context.Background(), reader, 0, FileFormat(CSV))
+	assert.Error(t, err)
+	_, err = ingestion.FromReader(context.Background(), reader, FileFormat(CSV), WithPartitioner(partitionByPrefix))
+	assert.Error(t, err)
+
+	// The format must have one record per line.
+	for _, options := range [][]FileOption{{}, {FileFormat(Parquet)}} {
+		_, err = ingestion.FromReaderPartitioned(context.Background(), reader, 0, append(options, WithPartitioner(partitionByPrefix))...)
+		require.Error(t, err)
+		assert.False(t, errors.Retry(err))
+	}
+}
@@ -0,0 +1,187 @@
+package azkustoingest
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+// defaultPipeChunkSize is the default maximum size of a chunk of transformed rows.
+const defaultPipeChunkSize = 64 * mb
+
+// pipeOptions are the options of PipeQuery.
+type pipeOptions struct {
+	chunkSize     int
+	queryOptions  []azkustodata.QueryOption
+	ingestOptions []FileOption
+}
+
+// PipeOption is an option of PipeQuery.
+type PipeOption func(o *pipeOptions)
+
+// PipeChunkSize sets the maximum size, in bytes, of the chunks of transformed rows that are ingested. Defaults to 64MB.
+// PipeQuery holds at most two chunks in memory: the one being ingested, and the one being filled.
+func PipeChunkSize(size int) PipeOption {
+	return func(o *pipeOptions) {
+		o.chunkSize = size
+	}
+}
+
+// PipeQueryOptions sets the options of the source query.
+func PipeQueryOptions(options ...azkustodata.QueryOption) PipeOption {
+	return func(o *pipeOptions) {
+		o.queryOptions = append(o.queryOptions, options...)
+	}
+}
+
+// PipeIngestOptions sets the options the chunks are ingested with, such as their format and the destination table.
+func PipeIngestOptions(options ...FileOption) PipeOption {
+	return func(o *pipeOptions) {
+		o.ingestOptions = append(o.ingestOptions, options...)
+	}
+}
+
+// PipeResult is the outcome of a PipeQuery call.
+type PipeResult struct {
+	// Rows is the number of rows read from the query.
+	Rows int
+	// Chunks are the chunks the transformed rows were split into, in order. FirstRecord and Records of the chunks
+	// count the rows of the query, including the ones the transform skipped.
+	// If the pipe stopped early, the rows after the last chunk were not ingested.
+	Chunks []ChunkResult
+}
+
+// Failed returns the chunks that failed to ingest.
+func (r *PipeResult) Failed() []ChunkResult {
+	var failed []ChunkResult
+	for _, c := range r.Chunks {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// PipeQuery runs an iterative query on the source database, and ingests its rows into the destination ingestor, after
+// transforming them with transform. This covers the read-transform-write loop of copying data between tables or
+// clusters.
+//
+// transform returns the serialized record of a row, in the format the ingestor expects - set it with the FileFormat
+// option of PipeIngestOptions or of the ingestor. A newline is appended to records that don't end with one. Rows for
+// which transform returns no bytes are skipped, and an error stops the pipe.
+//
+// The records are gathered in chunks of up to PipeChunkSize bytes, and every chunk is ingested with FromReader while
+// the next one is filled. Reading the query waits for the ingestion of the previous chunk, so the memory use is bounded
+// whatever the size of the results.
+// A failed chunk doesn't stop the ingestion of the others, the outcome of every chunk is reported in the PipeResult.
+// The returned error combines the errors of the query, the transform and all the failed chunks.
+func PipeQuery(ctx context.Context, client QueryClient, db string, stmt azkustodata.Statement, ingestor Ingestor,
+	transform func(query.Row) ([]byte, error), options ...PipeOption) (*PipeResult, error) {
+	opts := pipeOptions{chunkSize: defaultPipeChunkSize}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.chunkSize <= 0 {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "PipeQuery() chunk size must be positive, got %d", opts.chunkSize).SetNoRetry()
+	}
+	if transform == nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "PipeQuery() requires a transform").SetNoRetry()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dataset, err := client.IterativeQuery(ctx, db, stmt, opts.queryOptions...)
+	if err != nil {
+		return nil, err
+	}
+	defer dataset.Close()
+
+	type pipeChunk struct {
+		chunk   ChunkResult
+		payload []byte
+	}
+
+	result := &PipeResult{}
+	var mu sync.Mutex
+	var errs []error
+
+	// The channel is unbuffered, so that a chunk is only handed over once the previous one is ingested.
+	chunks := make(chan pipeChunk)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for c := range chunks {
+			c.chunk.Size = int64(len(c.payload))
+			c.chunk.Result, c.chunk.Err = ingestor.FromReader(ctx, bytes.NewReader(c.payload), opts.ingestOptions...)
+
+			mu.Lock()
+			if c.chunk.Err != nil {
+				errs = append(errs, c.chunk.Err)
+			}
+			result.Chunks = append(result.Chunks, c.chunk)
+			mu.Unlock()
+		}
+	}()
+
+	buf := bytes.Buffer{}
+	chunk := ChunkResult{}
+	flush := func() bool {
+		select {
+		case chunks <- pipeChunk{chunk: chunk, payload: buf.Bytes()}:
+		case <-ctx.Done():
+			return false
+		}
+		buf = bytes.Buffer{}
+		chunk = ChunkResult{FirstRecord: result.Rows}
+		return true
+	}
+
+	readErr := func() error {
+		for tableResult := range dataset.Tables() {
+			if tableResult.Err() != nil {
+				return tableResult.Err()
+			}
+
+			for rowResult := range tableResult.Table().Rows() {
+				if rowResult.Err() != nil {
+					return rowResult.Err()
+				}
+
+				record, err := transform(rowResult.Row())
+				if err != nil {
+					return errors.ES(errors.OpFileIngest, errors.KClientArgs, "PipeQuery() could not transform row %d: %s", result.Rows, err).SetNoRetry()
+				}
+
+				if len(record) > 0 {
+					if buf.Len() > 0 && buf.Len()+len(record)+1 > opts.chunkSize && !flush() {
+						return ctx.Err()
+					}
+					buf.Write(record)
+					if record[len(record)-1] != '\n' {
+						buf.WriteByte('\n')
+					}
+				}
+				result.Rows++
+				chunk.Records++
+			}
+		}
+
+		if buf.Len() > 0 && !flush() {
+			return ctx.Err()
+		}
+		return nil
+	}()
+
+	close(chunks)
+	<-done
+
+	if readErr != nil {
+		errs = append(errs, readErr)
+	}
+	return result, errors.CombineErrors(errs...)
+}
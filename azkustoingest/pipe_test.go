@@ -0,0 +1,175 @@
+package azkustoingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/query/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeFrames are the frames of the results of a query with the rows 0 to 4.
+const pipeFrames = `[{"FrameType":"DataSetHeader","IsProgressive":false,"Version":"v2.0","IsFragmented":true,"ErrorReportingPlacement":"EndOfTable"}
+,{"FrameType":"DataTable","TableId":0,"TableKind":"QueryProperties","TableName":"@ExtendedProperties","Columns":[{"ColumnName":"TableId","ColumnType":"int"},{"ColumnName":"Key","ColumnType":"string"},{"ColumnName":"Value","ColumnType":"dynamic"}],"Rows":[]}
+,{"FrameType":"TableHeader","TableId":1,"TableKind":"PrimaryResult","TableName":"PrimaryResult","Columns":[{"ColumnName":"Id","ColumnType":"long"},{"ColumnName":"Name","ColumnType":"string"}]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[0,"a"],[1,"b"],[2,"c"]]}
+,{"FrameType":"TableFragment","TableFragmentType":"DataAppend","TableId":1,"Rows":[[3,"d"],[4,"e"]]}
+,{"FrameType":"TableCompletion","TableId":1,"RowCount":5}
+,{"FrameType":"DataSetCompletion","HasErrors":false,"Cancelled":false}
+]`
+
+// recordingIngestor records the payloads passed to FromReader.
+type recordingIngestor struct {
+	mu       sync.Mutex
+	payloads []string
+	fail     func(payload string) bool
+}
+
+func (r *recordingIngestor) FromFile(context.Context, string, ...FileOption) (*Result, error) {
+	panic("not implemented")
+}
+
+func (r *recordingIngestor) FromReader(_ context.Context, reader io.Reader, _ ...FileOption) (*Result, error) {
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil && r.fail(string(payload)) {
+		return nil, fmt.Errorf("ingestion failed")
+	}
+	r.payloads = append(r.payloads, string(payload))
+	return newResult(), nil
+}
+
+func (r *recordingIngestor) Close() error {
+	return nil
+}
+
+func TestPipeQuery(t *testing.T) {
+	t.Parallel()
+
+	csv := func(row query.Row) ([]byte, error) {
+		id, err := row.Value(0)
+		if err != nil {
+			return nil, err
+		}
+		name, err := row.Value(1)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf("%v,%v", id, name)), nil
+	}
+
+	tests := []struct {
+		desc       string
+		transform  func(query.Row) ([]byte, error)
+		chunkSize  int
+		fail       func(payload string) bool
+		want       []string
+		wantChunks []ChunkResult
+		wantFailed int
+		wantErr    bool
+	}{
+		{
+			desc:       "single chunk",
+			transform:  csv,
+			chunkSize:  defaultPipeChunkSize,
+			want:       []string{"0,a\n1,b\n2,c\n3,d\n4,e\n"},
+			wantChunks: []ChunkResult{{FirstRecord: 0, Records: 5, Size: 20}},
+		},
+		{
+			desc:      "chunks",
+			transform: csv,
+			chunkSize: 8,
+			want:      []string{"0,a\n1,b\n", "2,c\n3,d\n", "4,e\n"},
+			wantChunks: []ChunkResult{
+				{FirstRecord: 0, Records: 2, Size: 8},
+				{FirstRecord: 2, Records: 2, Size: 8},
+				{FirstRecord: 4, Records: 1, Size: 4},
+			},
+		},
+		{
+			desc: "skipped rows",
+			transform: func(row query.Row) ([]byte, error) {
+				if row.Index()%2 == 1 {
+					return nil, nil
+				}
+				return csv(row)
+			},
+			chunkSize:  defaultPipeChunkSize,
+			want:       []string{"0,a\n2,c\n4,e\n"},
+			wantChunks: []ChunkResult{{FirstRecord: 0, Records: 5, Size: 12}},
+		},
+		{
+			desc:      "failed chunk",
+			transform: csv,
+			chunkSize: 8,
+			fail: func(payload string) bool {
+				return strings.HasPrefix(payload, "2,c")
+			},
+			want:       []string{"0,a\n1,b\n", "4,e\n"},
+			wantFailed: 1,
+			wantErr:    true,
+		},
+		{
+			desc: "transform error",
+			transform: func(row query.Row) ([]byte, error) {
+				if row.Index() == 3 {
+					return nil, fmt.Errorf("bad row")
+				}
+				return csv(row)
+			},
+			chunkSize: 8,
+			want:      []string{"0,a\n1,b\n"},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := newMockClient()
+			client.onIterativeQuery = func(ctx context.Context, db string, stmt azkustodata.Statement, _ ...azkustodata.QueryOption) (query.IterativeDataset, error) {
+				assert.Equal(t, "src", db)
+				assert.Equal(t, "Source", stmt.String())
+				return v2.NewIterativeDataset(ctx, io.NopCloser(strings.NewReader(pipeFrames)), v2.DefaultIoCapacity, v2.DefaultRowCapacity, v2.DefaultTableCapacity)
+			}
+			ingestor := &recordingIngestor{fail: test.fail}
+
+			result, err := PipeQuery(context.Background(), client, "src", kql.New("Source"), ingestor, test.transform, PipeChunkSize(test.chunkSize))
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, 5, result.Rows)
+			}
+			assert.Equal(t, test.want, ingestor.payloads)
+			assert.Len(t, result.Failed(), test.wantFailed)
+
+			if test.wantChunks != nil {
+				require.Len(t, result.Chunks, len(test.wantChunks))
+				for i, c := range result.Chunks {
+					assert.NotNil(t, c.Result)
+					c.Result = nil
+					assert.Equal(t, test.wantChunks[i], c)
+				}
+			}
+		})
+	}
+
+	_, err := PipeQuery(context.Background(), newMockClient(), "src", kql.New("Source"), &recordingIngestor{}, nil)
+	assert.Error(t, err)
+}
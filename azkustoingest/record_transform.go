@@ -0,0 +1,116 @@
+package azkustoingest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+)
+
+// RecordTransform transforms a record before it is ingested, for example to hash or mask personal data on the client.
+// record is a line of the input, without its newline, and is only valid until the transform returns. Returning an empty
+// record drops it, and returning an error fails the ingestion.
+type RecordTransform func(record []byte) ([]byte, error)
+
+// WithRecordTransform sets a RecordTransform that the client applies to every record passed to FromReader, as the
+// records are read, so that they are transformed without an intermediate file.
+// Only the formats with one record per line can be transformed: CSV, PSV, SCSV, SOHSV, TSV, TSVE, TXT and JSON. CSV-like
+// records with quoted newlines are passed whole. With IgnoreFirstRecord, the first record is a header that is ingested
+// as is. The other ingestion methods don't transform their data.
+func WithRecordTransform(transform RecordTransform) Option {
+	return func(s *Ingestion) {
+		s.recordTransform = transform
+	}
+}
+
+// recordTransformer is a reader of the records of another reader, transformed by a RecordTransform.
+type recordTransformer struct {
+	pr   *io.PipeReader
+	done chan struct{}
+	// err is the error of the transform, set before done is closed.
+	err error
+}
+
+// transformRecords returns a reader of the transformed records of reader, and the transformer to pass its errors to,
+// or reader and a nil transformer if transform is nil.
+func transformRecords(reader io.Reader, props properties.All, transform RecordTransform) (io.Reader, *recordTransformer, error) {
+	if transform == nil {
+		return reader, nil, nil
+	}
+	if !splittableFormat(props.Ingestion.Additional.Format) {
+		return nil, nil, errors.ES(errors.OpFileIngest, errors.KClientArgs,
+			"WithRecordTransform requires a format with one record per line, got %v", props.Ingestion.Additional.Format).SetNoRetry()
+	}
+
+	pr, pw := io.Pipe()
+	t := &recordTransformer{pr: pr, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		pw.CloseWithError(t.run(newRecordSplitter(reader, props), pw, transform))
+	}()
+	return t, t, nil
+}
+
+// run writes the transformed records to w.
+func (t *recordTransformer) run(splitter *recordSplitter, w io.Writer, transform RecordTransform) error {
+	bw := bufio.NewWriterSize(w, splitReadBufferSize)
+	for n := 0; ; n++ {
+		record, err := splitter.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line := bytes.TrimRight(record, "\r\n")
+		if n > 0 || !splitter.repeatHeader {
+			line, err = transform(line)
+			if err != nil {
+				t.err = errors.E(errors.OpFileIngest, errors.KClientArgs, fmt.Errorf("the record transform failed on record %d: %w", n, err)).SetNoRetry()
+				return t.err
+			}
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *recordTransformer) Read(p []byte) (int, error) {
+	return t.pr.Read(p)
+}
+
+// Close stops the transform, and waits for it to return.
+func (t *recordTransformer) Close() error {
+	if t == nil {
+		return nil
+	}
+	_ = t.pr.CloseWithError(io.ErrClosedPipe)
+	<-t.done
+	return nil
+}
+
+// failure returns the error of the transform if it failed, which is what caused err, or err otherwise.
+// t may be nil.
+func (t *recordTransformer) failure(err error) error {
+	if t == nil || err == nil {
+		return err
+	}
+	_ = t.Close()
+	if t.err != nil {
+		return t.err
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package azkustoingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	goErrors "errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maskEmails masks the second field of CSV records, and drops the records whose first field is "skip".
+func maskEmails(record []byte) ([]byte, error) {
+	fields := bytes.Split(record, []byte(","))
+	if string(fields[0]) == "skip" {
+		return nil, nil
+	}
+	if len(fields) > 1 {
+		fields[1] = []byte("***")
+	}
+	return bytes.Join(fields, []byte(",")), nil
+}
+
+func TestRecordTransform(t *testing.T) {
+	t.Parallel()
+
+	errBadRecord := goErrors.New("bad record")
+
+	tests := []struct {
+		desc      string
+		input     string
+		options   []FileOption
+		transform RecordTransform
+		want      string
+		wantErr   error
+	}{
+		{
+			desc:      "Masked",
+			input:     "1,a@b.com\r\nskip,c@d.com\n2,\"e@f.com\nsecond line\"\n3,g@h.com",
+			options:   []FileOption{FileFormat(CSV)},
+			transform: maskEmails,
+			want:      "1,***\n2,***\n3,***\n",
+		},
+		{
+			desc:      "Header",
+			input:     "id,email\n1,a@b.com\n",
+			options:   []FileOption{FileFormat(CSV), IgnoreFirstRecord()},
+			transform: maskEmails,
+			want:      "id,email\n1,***\n",
+		},
+		{
+			desc:  "Failed",
+			input: "1,a@b.com\nbad\n",
+			transform: func(record []byte) ([]byte, error) {
+				if string(record) == "bad" {
+					return nil, errBadRecord
+				}
+				return record, nil
+			},
+			options: []FileOption{FileFormat(CSV)},
+			wantErr: errBadRecord,
+		},
+		{
+			desc:      "Not line based",
+			input:     "PAR1",
+			options:   []FileOption{FileFormat(Parquet)},
+			transform: maskEmails,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &splitRecorder{}
+			ingestion := newSplitIngestion(t, rec)
+			ingestion.recordTransform = test.transform
+
+			_, err := ingestion.FromReader(context.Background(), strings.NewReader(test.input), test.options...)
+			if test.want == "" {
+				require.Error(t, err)
+				assert.False(t, errors.Retry(err))
+				if test.wantErr != nil {
+					assert.ErrorIs(t, err, test.wantErr)
+					assert.ErrorContains(t, err, "record 1")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{test.want}, rec.payloads)
+		})
+	}
+}
+
+func TestRecordTransformStreaming(t *testing.T) {
+	t.Parallel()
+
+	var got []byte
+	streaming := &Streaming{
+		db:    "db",
+		table: "table",
+		streamConn: fakeStreamIngestor{onStreamIngest: func(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming,
+			mappingName string, clientRequestId string, isBlobUri bool) error {
+			gz, err := gzip.NewReader(payload)
+			if err != nil {
+				return err
+			}
+			got, err = io.ReadAll(gz)
+			return err
+		}},
+		recordTransform: maskEmails,
+	}
+
+	_, err := streaming.FromReader(context.Background(), strings.NewReader("1,a@b.com\nskip,c\n2,d@e.com\n"), FileFormat(CSV))
+	require.NoError(t, err)
+	assert.Equal(t, "1,***\n2,***\n", string(got))
+}
+
+func TestRecordTransformOtherMethods(t *testing.T) {
+	t.Parallel()
+
+	rec := &splitRecorder{}
+	ingestion := newSplitIngestion(t, rec)
+	ingestion.recordTransform = maskEmails
+
+	_, err := ingestion.FromReaderPartitioned(context.Background(), strings.NewReader("1,a@b.com\n"), 0, FileFormat(CSV),
+		WithPartitioner(func(record []byte) (string, string, []string) { return "", "", nil }))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1,a@b.com\n"}, rec.payloads)
+}
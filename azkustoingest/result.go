@@ -2,10 +2,11 @@ package azkustoingest
 
 import (
 	"context"
+	goErrors "errors"
 	"fmt"
-	"math/rand"
 	"time"
 
+	"github.com/Azure/azure-kusto-go/azkustodata/poll"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/status"
@@ -13,9 +14,18 @@ import (
 
 // Result provides a way for users track the state of ingestion jobs.
 type Result struct {
-	record        statusRecord
+	record        StatusRecord
 	tableClient   *status.TableClient
 	reportToTable bool
+	// backend is the store the outcome of the ingestion is written to, if any.
+	backend StatusBackend
+
+	// created is the time the ingestion was queued or streamed.
+	created time.Time
+	// batching is the cache of the batching policies of queued ingestions, and batchingProps the properties the data
+	// was queued with.
+	batching      *batchingPolicyCache
+	batchingProps properties.Ingestion
 }
 
 // newResult creates an initial ingestion status record.
@@ -23,6 +33,7 @@ func newResult() *Result {
 	ret := &Result{}
 
 	ret.record = newStatusRecord()
+	ret.created = time.Now()
 	return ret
 }
 
@@ -32,6 +43,54 @@ func (r *Result) putProps(props properties.All) {
 	r.record.FromProps(props)
 }
 
+// putBatching sets the cache the batching policy of the table of a queued ingestion is read from.
+func (r *Result) putBatching(cache *batchingPolicyCache, props properties.All) {
+	r.batching = cache
+	r.batchingProps = props.Ingestion
+}
+
+// BatchingPolicy returns the ingestion batching policy of the table the data was queued to, or of its database if the
+// table doesn't set one, or else DefaultBatchingPolicy. The policies are read from the engine of the cluster on the
+// first call for a table and cached by the client, and an error is returned if they can't be read, for instance
+// because the principal lacks the permissions to show them. It is the zero value for streaming ingestion.
+func (r *Result) BatchingPolicy(ctx context.Context) (BatchingPolicy, error) {
+	if r.batching == nil {
+		return BatchingPolicy{}, nil
+	}
+	return r.batching.get(ctx, r.batchingProps.DatabaseName, r.batchingProps.TableName)
+}
+
+// EstimatedBatchingDelay returns the estimated time queued data waits to be batched before it's ingested, from the
+// batching policy of the table. This is an upper bound: batches are also sealed when they reach the size or the
+// number of items limits of the policy. It is zero for streaming ingestion, and for data queued with FlushImmediately.
+func (r *Result) EstimatedBatchingDelay(ctx context.Context) (time.Duration, error) {
+	if r.batching == nil {
+		return 0, nil
+	}
+	policy, err := r.BatchingPolicy(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Batches are sealed right away when data is flushed, or when it exceeds the size limit of the policy.
+	rawSizeMB := r.batchingProps.RawDataSize / (1024 * 1024)
+	if r.batchingProps.FlushImmediately || (policy.MaximumRawDataSizeMB > 0 && rawSizeMB >= int64(policy.MaximumRawDataSizeMB)) {
+		return 0, nil
+	}
+	return policy.MaximumBatchingTimeSpan, nil
+}
+
+// EstimatedReadyTime returns the estimated time by which the data should be queryable, from the time it was queued
+// and the batching policy of the table. Ingesting a batch also takes some time after it is sealed, so queries that
+// verify the ingestion should allow a margin over this time.
+func (r *Result) EstimatedReadyTime(ctx context.Context) (time.Time, error) {
+	delay, err := r.EstimatedBatchingDelay(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return r.created.Add(delay), nil
+}
+
 // putQueued sets the initial success status depending on status reporting state
 func (r *Result) putQueued(mgr *resources.Manager) {
 	// If not checking status, just return queued
@@ -78,6 +137,63 @@ func (r *Result) putQueued(mgr *resources.Manager) {
 	r.tableClient = client
 }
 
+// report writes the status record to the status backend, if the status is an outcome of the ingestion.
+func (r *Result) report(ctx context.Context) {
+	if r.backend == nil || !isIngestionOutcome(r.record.Status) {
+		return
+	}
+	_ = r.backend.Write(ctx, r.record)
+}
+
+// defaultPollInterval is the interval at which Wait polls the status table.
+const defaultPollInterval = 10 * time.Second
+
+// statusReadRetries is the number of failed reads of the status table in a row that Wait retries.
+const statusReadRetries = 3
+
+// waitOptions are the options of WaitWithOptions.
+type waitOptions struct {
+	// poll are the options of the poller of the status table, applied after those of defaultWaitOptions.
+	poll    []poll.Option
+	maxWait time.Duration
+}
+
+// defaultWaitOptions polls the status table every defaultPollInterval, and retries failed reads.
+func defaultWaitOptions() waitOptions {
+	return waitOptions{poll: []poll.Option{poll.Interval(defaultPollInterval), poll.Multiplier(1), poll.MaxErrors(statusReadRetries), poll.WaitFirst()}}
+}
+
+// WaitOption is an option of Result.WaitWithOptions.
+type WaitOption func(o *waitOptions)
+
+// PollInterval sets the interval at which the status table is polled. Defaults to 10 seconds.
+func PollInterval(d time.Duration) WaitOption {
+	return PollOptions(poll.Interval(d))
+}
+
+// PollJitter adds a random delay of up to d to each poll interval, so that many concurrent waits don't poll the status
+// table at the same time. Defaults to no jitter.
+func PollJitter(d time.Duration) WaitOption {
+	return PollOptions(poll.Jitter(d))
+}
+
+// PollOptions sets options of the poller of the status table, for instance poll.Multiplier and poll.MaxInterval to
+// poll with an exponential backoff instead of a fixed interval. PollInterval and PollJitter are shorthands for
+// poll.Interval and poll.Jitter, and the options apply in order.
+func PollOptions(options ...poll.Option) WaitOption {
+	return func(o *waitOptions) {
+		o.poll = append(o.poll, options...)
+	}
+}
+
+// MaxWait sets the maximum time to wait for the ingestion to reach a final status. When it elapses, the wait stops
+// with the StatusRetrievalCanceled status, as when the context is done. Defaults to no limit other than the context.
+func MaxWait(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.maxWait = d
+	}
+}
+
 // Wait returns a channel that can be checked for ingestion results.
 // In order to check actual status please use the ReportResultToTable option when ingesting data.
 func (r *Result) Wait(ctx context.Context) chan error {
@@ -91,7 +207,7 @@ func (r *Result) Wait(ctx context.Context) chan error {
 	go func() {
 		defer close(ch)
 
-		r.poll(ctx)
+		r.poll(ctx, defaultWaitOptions())
 		if !r.record.Status.IsSuccess() {
 			ch <- r.record
 		}
@@ -100,58 +216,68 @@ func (r *Result) Wait(ctx context.Context) chan error {
 	return ch
 }
 
-func (r *Result) poll(ctx context.Context) {
-	const pollInterval = 10 * time.Second
-	attempts := 3
-	delay := [3]int{120, 60, 10} // attempts are counted backwards
+// WaitWithOptions waits for the ingestion to reach a final status, and returns the final status record. This carries
+// the identifiers of the ingestion, such as its operation id and the time its status was last updated.
+// The error is nil if the ingestion succeeded, and is the status record otherwise, as for Wait. Without the
+// ReportResultToTable option, the status of the ingestion can't be tracked and the record is returned right away,
+// with the Queued status.
+func (r *Result) WaitWithOptions(ctx context.Context, options ...WaitOption) (StatusRecord, error) {
+	opts := defaultWaitOptions()
+	for _, o := range options {
+		o(&opts)
+	}
 
-	// create a table client
-	if r.tableClient != nil {
-		// Create a ticker to poll the table in 10 second intervals.
-		timer := time.NewTimer(pollInterval)
-		defer timer.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				r.record.Status = StatusRetrievalCanceled
-				r.record.FailureStatus = Transient
-				return
-
-			case <-timer.C:
-				smap, err := r.tableClient.Read(r.record.IngestionSourceID.String())
-				if err != nil {
-					if attempts == 0 {
-						r.record.Status = StatusRetrievalFailed
-						r.record.FailureStatus = Transient
-						r.record.Details = "Failed reading from Status Table: " + err.Error()
-						return
-					}
-
-					attempts = attempts - 1
-					time.Sleep(time.Duration(delay[attempts]+rand.Intn(5)) * time.Second)
-				} else {
-					r.record.FromMap(smap)
-					if r.record.Status.IsFinal() {
-						return
-					}
-				}
-
-				timer.Reset(pollInterval)
-			}
+	if !r.record.Status.IsFinal() && r.reportToTable {
+		if opts.maxWait > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.maxWait)
+			defer cancel()
+		}
+		r.poll(ctx, opts)
+	}
+
+	if !r.record.Status.IsSuccess() {
+		return r.record, r.record
+	}
+	return r.record, nil
+}
+
+func (r *Result) poll(ctx context.Context, opts waitOptions) {
+	if r.tableClient == nil {
+		return
+	}
+
+	err := poll.New(opts.poll...).Poll(ctx, func(ctx context.Context) (bool, error) {
+		smap, err := r.tableClient.Read(r.record.IngestionSourceID.String())
+		if err != nil {
+			return false, err
 		}
+		r.record.FromMap(smap)
+		return r.record.Status.IsFinal(), nil
+	})
+
+	switch {
+	case err == nil:
+		r.report(ctx)
+	case ctx.Err() != nil || goErrors.Is(err, context.DeadlineExceeded):
+		r.record.Status = StatusRetrievalCanceled
+		r.record.FailureStatus = Transient
+	default:
+		r.record.Status = StatusRetrievalFailed
+		r.record.FailureStatus = Transient
+		r.record.Details = "Failed reading from Status Table: " + err.Error()
 	}
 }
 
 // IsStatusRecord verifies that the given error is a status record.
 func IsStatusRecord(err error) bool {
-	_, ok := err.(statusRecord)
+	_, ok := err.(StatusRecord)
 	return ok
 }
 
 // GetIngestionStatus extracts the ingestion status code from an ingestion error
 func GetIngestionStatus(err error) (StatusCode, error) {
-	if s, ok := err.(statusRecord); ok {
+	if s, ok := err.(StatusRecord); ok {
 		return s.Status, nil
 	}
 
@@ -160,7 +286,7 @@ func GetIngestionStatus(err error) (StatusCode, error) {
 
 // GetIngestionFailureStatus extracts the ingestion failure code from an ingestion error
 func GetIngestionFailureStatus(err error) (FailureStatusCode, error) {
-	if s, ok := err.(statusRecord); ok {
+	if s, ok := err.(StatusRecord); ok {
 		return s.FailureStatus, nil
 	}
 
@@ -169,7 +295,7 @@ func GetIngestionFailureStatus(err error) (FailureStatusCode, error) {
 
 // GetErrorCode extracts the error code from an ingestion error
 func GetErrorCode(err error) (string, error) {
-	if s, ok := err.(statusRecord); ok {
+	if s, ok := err.(StatusRecord); ok {
 		return s.ErrorCode, nil
 	}
 
@@ -178,7 +304,7 @@ func GetErrorCode(err error) (string, error) {
 
 // IsRetryable indicates whether there's any merit in retying ingestion
 func IsRetryable(err error) bool {
-	if s, ok := err.(statusRecord); ok {
+	if s, ok := err.(StatusRecord); ok {
 		return s.FailureStatus.IsRetryable()
 	}
 
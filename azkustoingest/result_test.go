@@ -0,0 +1,101 @@
+package azkustoingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/poll"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc       string
+		result     func() *Result
+		options    []WaitOption
+		wantStatus StatusCode
+		wantErr    bool
+	}{
+		{
+			desc: "untracked",
+			result: func() *Result {
+				r := newResult()
+				r.record.Status = Queued
+				return r
+			},
+			wantStatus: Queued,
+		},
+		{
+			desc: "succeeded",
+			result: func() *Result {
+				r := newResult()
+				r.reportToTable = true
+				r.record.Status = Succeeded
+				r.record.OperationID = r.record.IngestionSourceID
+				return r
+			},
+			wantStatus: Succeeded,
+		},
+		{
+			desc: "failed",
+			result: func() *Result {
+				r := newResult()
+				r.reportToTable = true
+				r.record.Status = Failed
+				r.record.FailureStatus = Permanent
+				return r
+			},
+			wantStatus: Failed,
+			wantErr:    true,
+		},
+		{
+			desc: "max wait",
+			result: func() *Result {
+				r := newResult()
+				r.reportToTable = true
+				r.record.Status = Pending
+				r.tableClient = &status.TableClient{}
+				return r
+			},
+			options:    []WaitOption{PollInterval(time.Hour), PollJitter(time.Minute), MaxWait(10 * time.Millisecond)},
+			wantStatus: StatusRetrievalCanceled,
+			wantErr:    true,
+		},
+		{
+			desc: "poll options",
+			result: func() *Result {
+				r := newResult()
+				r.reportToTable = true
+				r.record.Status = Pending
+				r.tableClient = &status.TableClient{}
+				return r
+			},
+			options:    []WaitOption{PollOptions(poll.Interval(time.Hour), poll.Multiplier(2), poll.MaxInterval(2*time.Hour)), MaxWait(10 * time.Millisecond)},
+			wantStatus: StatusRetrievalCanceled,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			result := test.result()
+			record, err := result.WaitWithOptions(context.Background(), test.options...)
+			assert.Equal(t, test.wantStatus, record.Status)
+			assert.Equal(t, result.record.IngestionSourceID, record.IngestionSourceID)
+			assert.Equal(t, result.record.OperationID, record.OperationID)
+			if test.wantErr {
+				assert.True(t, IsStatusRecord(err))
+				assert.Equal(t, record, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
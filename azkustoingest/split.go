@@ -0,0 +1,459 @@
+package azkustoingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/queued"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/utils"
+	"github.com/google/uuid"
+)
+
+// defaultSplitSize is the default maximum size of the chunks FromFileSplit splits files into, before compression.
+// Text formats typically compress 5 to 10 times, which keeps the uploaded chunks well under the recommended 1GB.
+const defaultSplitSize = int64(1024 * mb)
+
+// splitReadBufferSize is the size of the buffer used to read the records of a split file.
+const splitReadBufferSize = 1 * mb
+
+// SplitResult is the outcome of a FromFileSplit call.
+type SplitResult struct {
+	// SourceID is the source ID of the first chunk. The source IDs of the following chunks are sequential: the ID of
+	// chunk n is SourceID + n, as a 128-bit number.
+	SourceID uuid.UUID
+	// Chunks are the chunks the file was split into, in order. FirstRecord and Records count the records of the file,
+	// and are 0 if the file was ingested whole.
+	// If the ingestion stopped early, the records after the last chunk were not ingested.
+	Chunks []ChunkResult
+
+	// retrier queues the chunks again, with RetryChunks.
+	retrier *chunkRetrier
+}
+
+// Failed returns the chunks that failed to ingest.
+func (r *SplitResult) Failed() []ChunkResult {
+	var failed []ChunkResult
+	for _, c := range r.Chunks {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Wait waits for the ingestion of all the chunks, like Result.WaitWithOptions, and returns their status records in the
+// order of the chunks. Chunks that failed to be queued have an empty status record.
+// With RetryChunks, the chunks whose ingestion fails with a status that can be retried are queued again and waited
+// for, and their ChunkResult is updated.
+// The returned error combines the errors of all the chunks that did not succeed.
+func (r *SplitResult) Wait(ctx context.Context, options ...WaitOption) ([]StatusRecord, error) {
+	records := make([]StatusRecord, len(r.Chunks))
+	errs := make([]error, len(r.Chunks))
+
+	wg := sync.WaitGroup{}
+	for i, c := range r.Chunks {
+		if c.Err != nil {
+			errs[i] = c.Err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, result *Result) {
+			defer wg.Done()
+			for {
+				records[i], errs[i] = result.WaitWithOptions(ctx, options...)
+				// The statuses of the client, such as StatusRetrievalFailed, don't tell that the ingestion failed.
+				if errs[i] == nil || !IsRetryable(errs[i]) || !isIngestionOutcome(records[i].Status) || !r.retrier.canRetry(r.Chunks[i]) || ctx.Err() != nil {
+					return
+				}
+				r.Chunks[i] = r.retrier.retry(ctx, i, r.Chunks[i])
+				if r.Chunks[i].Err != nil {
+					records[i], errs[i] = StatusRecord{}, r.Chunks[i].Err
+					return
+				}
+				result = r.Chunks[i].Result
+			}
+		}(i, c.Result)
+	}
+	wg.Wait()
+
+	return records, errors.CombineErrors(errs...)
+}
+
+// FromFileSplit queues a local file for ingestion like FromFile, splitting it into chunks of up to maxSize bytes
+// before compression when it is larger, instead of uploading one large blob that the service would ingest into
+// oversized extents. A maxSize of 0 or less selects the default of 1GB. A chunk is only larger than maxSize when it
+// holds a single record that is.
+//
+// The file is split on record boundaries, so only the formats with one record per line can be split: CSV, PSV, SCSV,
+// SOHSV, TSV, TSVE, TXT and JSON. CSV-like records with quoted newlines are kept whole. With IgnoreFirstRecord, the
+// first record of the file is repeated at the start of every chunk.
+// Files of other formats, compressed files and blobs are ingested whole, as a single chunk.
+//
+// The chunks are streamed to the storage one after the other, so the memory use doesn't depend on the size of the
+// file. A failed chunk doesn't stop the ingestion of the others, the outcome of every chunk is reported in the
+// SplitResult. The returned error combines the errors of all the failed chunks.
+func (i *Ingestion) FromFileSplit(ctx context.Context, fPath string, maxSize int64, options ...FileOption) (*SplitResult, error) {
+	if maxSize <= 0 {
+		maxSize = defaultSplitSize
+	}
+	options, _ = withDefaults(i.defaultOptions, options, fileSource(fPath))
+
+	split, props, err := i.splitProps(fPath, maxSize, options)
+	if err != nil {
+		return nil, err
+	}
+	if !split {
+		if props.Source.ChunkRetries == 0 {
+			result, err := i.fromFile(ctx, fPath, options, i.newProp())
+			if err != nil {
+				return nil, err
+			}
+			return &SplitResult{SourceID: result.record.IngestionSourceID, Chunks: []ChunkResult{{Result: result}}}, nil
+		}
+
+		// The file is retried whole, as a single chunk.
+		result := &SplitResult{SourceID: uuid.New()}
+		result.retrier = &chunkRetrier{ingestion: i, path: fPath, options: options, sourceID: result.SourceID, retries: props.Source.ChunkRetries}
+		chunk := result.retrier.retry(ctx, 0, ChunkResult{})
+		for chunk.Err != nil && errors.Retry(chunk.Err) && result.retrier.canRetry(chunk) && ctx.Err() == nil {
+			chunk = result.retrier.retry(ctx, 0, chunk)
+		}
+		result.Chunks = []ChunkResult{chunk}
+		return result, chunk.Err
+	}
+
+	file, err := os.Open(fPath)
+	if err != nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to open file %s: %s", fPath, err).SetNoRetry()
+	}
+	defer file.Close()
+
+	splitter := newRecordSplitter(file, props)
+	result := &SplitResult{SourceID: uuid.New()}
+	if props.Source.ChunkRetries > 0 {
+		result.retrier = &chunkRetrier{
+			ingestion: i,
+			path:      fPath,
+			options:   options,
+			format:    props.Ingestion.Additional.Format,
+			sourceID:  result.SourceID,
+			retries:   props.Source.ChunkRetries,
+		}
+	}
+	var errs []error
+	for n := 0; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return result, errors.CombineErrors(append(errs, err)...)
+		}
+		if !splitter.more() {
+			break
+		}
+
+		chunkProps := i.newProp()
+		chunkProps.Source.ID = sequentialID(result.SourceID, n)
+		chunkProps.Ingestion.Additional.Format = props.Ingestion.Additional.Format
+
+		chunkOptions := options
+		if result.retrier != nil {
+			chunkOptions = result.retrier.chunkOptions(n)
+		}
+
+		start := splitter.offset
+		chunk, err := i.ingestSplitChunk(ctx, splitter, maxSize, chunkOptions, chunkProps)
+		if err != nil {
+			return result, errors.CombineErrors(append(errs, err)...)
+		}
+		if result.retrier != nil {
+			result.retrier.addChunk(start, splitter.offset-start, splitter.header)
+			chunk.Attempts, chunk.IngestByTag = 1, result.retrier.tag(n)
+			for chunk.Err != nil && errors.Retry(chunk.Err) && result.retrier.canRetry(chunk) && ctx.Err() == nil {
+				chunk = result.retrier.retry(ctx, n, chunk)
+			}
+		}
+		if chunk.Err != nil {
+			errs = append(errs, chunk.Err)
+		}
+		result.Chunks = append(result.Chunks, chunk)
+	}
+
+	return result, errors.CombineErrors(errs...)
+}
+
+// splitProps returns whether the file must be split, and the properties it is ingested with.
+func (i *Ingestion) splitProps(fPath string, maxSize int64, options []FileOption) (bool, properties.All, error) {
+	props := i.newProp()
+	props.Source.OriginalSource = fPath
+
+	local, err := queued.IsLocalPath(fPath)
+	if err != nil || !local {
+		return false, props, err
+	}
+
+	if err := validateOptions(options, QueuedClient, FromFile); err != nil {
+		return false, props, err
+	}
+	for _, o := range options {
+		if err := o.Run(&props, QueuedClient, FromFile); err != nil {
+			return false, props, err
+		}
+	}
+
+	if props.Ingestion.Additional.Format == DFUnknown {
+		props.Ingestion.Additional.Format = properties.DataFormatDiscovery(fPath)
+	}
+	if !splittableFormat(props.Ingestion.Additional.Format) || utils.CompressionDiscovery(fPath) != ingestoptions.CTNone {
+		return false, props, nil
+	}
+
+	if props.Source.ChunkRetries > 0 && props.Ingestion.Additional.IngestIfNotExists != "" {
+		return false, props, errors.ES(errors.OpFileIngest, errors.KClientArgs, "RetryChunks() can't be combined with IfNotExists()").SetNoRetry()
+	}
+
+	stat, err := os.Stat(fPath)
+	if err != nil {
+		return false, props, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to stat file %s: %s", fPath, err).SetNoRetry()
+	}
+	return stat.Size() > maxSize, props, nil
+}
+
+// ingestSplitChunk streams the next chunk of the file to FromReader. It only returns an error if the file can't be read,
+// the errors of the ingestion of the chunk are in the ChunkResult.
+func (i *Ingestion) ingestSplitChunk(ctx context.Context, splitter *recordSplitter, maxSize int64, options []FileOption, props properties.All) (ChunkResult, error) {
+	chunk := ChunkResult{FirstRecord: splitter.record}
+
+	pr, pw := io.Pipe()
+	var readErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		chunk.Size, chunk.Records, readErr = splitter.writeChunk(pw, maxSize)
+		pw.CloseWithError(readErr)
+	}()
+
+	result, err := i.fromReader(ctx, pr, options, props, nil)
+	// The ingestion might stop before reading the whole chunk, the splitter still skips to its end.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+
+	if readErr != nil {
+		return chunk, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to read the file: %s", readErr).SetNoRetry()
+	}
+	chunk.Result, chunk.Err = result, err
+	return chunk, nil
+}
+
+// chunkRetrier queues the chunks of a split file again, for RetryChunks. The chunks are read again from the file.
+type chunkRetrier struct {
+	ingestion *Ingestion
+	path      string
+	options   []FileOption
+	format    DataFormat
+	sourceID  uuid.UUID
+	retries   int
+
+	// spans are the ranges of the records of the chunks in the file, and header is the header repeated at the start of
+	// the chunks after the first, if any. There are no spans when the file is ingested whole.
+	spans  []chunkSpan
+	header []byte
+}
+
+type chunkSpan struct {
+	offset int64
+	length int64
+}
+
+// canRetry reports whether chunk can be retried. It is false for a nil retrier, without RetryChunks.
+func (r *chunkRetrier) canRetry(chunk ChunkResult) bool {
+	return r != nil && chunk.Attempts <= r.retries
+}
+
+// tag returns the ingest-by: tag value of chunk n, its source ID.
+func (r *chunkRetrier) tag(n int) string {
+	return sequentialID(r.sourceID, n).String()
+}
+
+// chunkOptions returns the options chunk n is ingested with.
+func (r *chunkRetrier) chunkOptions(n int) []FileOption {
+	options := make([]FileOption, 0, len(r.options)+1)
+	options = append(options, r.options...)
+	return append(options, chunkIngestByTag(r.tag(n)))
+}
+
+func (r *chunkRetrier) addChunk(offset, length int64, header []byte) {
+	r.spans = append(r.spans, chunkSpan{offset: offset, length: length})
+	r.header = header
+}
+
+// retry queues chunk n again, and returns it with the outcome of the new attempt.
+func (r *chunkRetrier) retry(ctx context.Context, n int, chunk ChunkResult) ChunkResult {
+	chunk.Attempts++
+	chunk.IngestByTag = r.tag(n)
+	chunk.Result, chunk.Err = r.queue(ctx, n)
+	return chunk
+}
+
+func (r *chunkRetrier) queue(ctx context.Context, n int) (*Result, error) {
+	props := r.ingestion.newProp()
+	props.Source.ID = sequentialID(r.sourceID, n)
+	if r.spans == nil {
+		return r.ingestion.fromFile(ctx, r.path, r.chunkOptions(n), props)
+	}
+	props.Ingestion.Additional.Format = r.format
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return nil, errors.ES(errors.OpFileIngest, errors.KLocalFileSystem, "unable to open file %s: %s", r.path, err).SetNoRetry()
+	}
+	defer file.Close()
+
+	span := r.spans[n]
+	var reader io.Reader = io.NewSectionReader(file, span.offset, span.length)
+	if n > 0 && r.header != nil {
+		reader = io.MultiReader(bytes.NewReader(r.header), reader)
+	}
+	return r.ingestion.fromReader(ctx, reader, r.chunkOptions(n), props, nil)
+}
+
+// splittableFormat reports whether the records of the format are delimited by newlines.
+func splittableFormat(format DataFormat) bool {
+	switch format {
+	case CSV, PSV, SCSV, SOHSV, TSV, TSVE, TXT, JSON:
+		return true
+	}
+	return false
+}
+
+// sequentialID returns base + n, as a 128-bit number.
+func sequentialID(base uuid.UUID, n int) uuid.UUID {
+	high := binary.BigEndian.Uint64(base[:8])
+	low := binary.BigEndian.Uint64(base[8:])
+
+	sum := low + uint64(n)
+	if sum < low {
+		high++
+	}
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], high)
+	binary.BigEndian.PutUint64(id[8:], sum)
+	return id
+}
+
+// recordSplitter reads the records of a file with one record per line.
+type recordSplitter struct {
+	reader *bufio.Reader
+	// quoted is set for the CSV-like formats, whose quoted fields can hold newlines.
+	quoted bool
+	// repeatHeader is set when the first record is a header that must start every chunk.
+	repeatHeader bool
+	header       []byte
+	// record is the index of the next record, and offset the offset of the next record in the file.
+	record int
+	offset int64
+	buf    []byte
+	// pending is a record that was read but didn't fit in the previous chunk, it starts the next one.
+	pending []byte
+}
+
+func newRecordSplitter(reader io.Reader, props properties.All) *recordSplitter {
+	format := props.Ingestion.Additional.Format
+	return &recordSplitter{
+		reader:       bufio.NewReaderSize(reader, splitReadBufferSize),
+		quoted:       format != JSON && format != TXT && format != TSVE,
+		repeatHeader: props.Ingestion.Additional.IgnoreFirstRecord,
+	}
+}
+
+// more reports whether there are records left.
+func (s *recordSplitter) more() bool {
+	if s.pending != nil {
+		return true
+	}
+	_, err := s.reader.Peek(1)
+	return err == nil
+}
+
+// next returns the next record, including its newline. The record is only valid until the next call.
+func (s *recordSplitter) next() ([]byte, error) {
+	if s.pending != nil {
+		record := s.pending
+		s.pending = nil
+		return record, nil
+	}
+
+	s.buf = s.buf[:0]
+	quotes := 0
+	for {
+		line, err := s.reader.ReadSlice('\n')
+		s.buf = append(s.buf, line...)
+		if s.quoted {
+			quotes += bytes.Count(line, []byte{'"'})
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(s.buf) > 0:
+			return s.buf, nil
+		case err != nil:
+			return nil, err
+		case quotes%2 == 0:
+			return s.buf, nil
+		}
+	}
+}
+
+// writeChunk writes records to w until the next record would take the chunk over maxSize bytes or the file ends, and
+// returns the size of the chunk and its number of records, not counting a repeated header. A chunk is only larger
+// than maxSize when its first record is.
+// Once writing to w fails, the records of the chunk are read and discarded, so that the next chunk starts after them.
+func (s *recordSplitter) writeChunk(w io.Writer, maxSize int64) (int64, int, error) {
+	var size int64
+	var writeErr error
+	write := func(b []byte) {
+		size += int64(len(b))
+		if writeErr == nil {
+			_, writeErr = w.Write(b)
+		}
+	}
+
+	if s.header != nil {
+		write(s.header)
+	}
+
+	records := 0
+	for {
+		record, err := s.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return size, records, err
+		}
+		if records > 0 && size+int64(len(record)) > maxSize {
+			s.pending = record
+			break
+		}
+		s.offset += int64(len(record))
+
+		if s.repeatHeader && s.record == 0 {
+			s.header = append([]byte(nil), record...)
+			if record[len(record)-1] != '\n' {
+				s.header = append(s.header, '\n')
+			}
+		}
+		write(record)
+		records++
+		s.record++
+	}
+	return size, records, nil
+}
@@ -0,0 +1,289 @@
+package azkustoingest
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitRecorder records the chunks uploaded by an Ingestion created with newSplitIngestion.
+type splitRecorder struct {
+	mu       sync.Mutex
+	payloads []string
+	ids      []uuid.UUID
+	formats  []DataFormat
+	targets  []PartitionTarget
+	local    []string
+	fail     func(payload string) bool
+}
+
+func newSplitIngestion(t *testing.T, rec *splitRecorder) *Ingestion {
+	client := mockClient{
+		endpoint: "https://test.kusto.windows.net",
+		auth:     azkustodata.Authorization{},
+		onMgmt: func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+			return nil, nil
+		},
+	}
+
+	ingestion, err := newFromClient(client, &Ingestion{db: "db", table: "table"})
+	require.NoError(t, err)
+	ingestion.fs = resources.FsMock{
+		OnReader: func(ctx context.Context, reader io.Reader, props properties.All) (string, error) {
+			b, err := io.ReadAll(reader)
+			if err != nil {
+				return "", err
+			}
+
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			if rec.fail != nil && rec.fail(string(b)) {
+				return "", errors.ES(errors.OpFileIngest, errors.KBlobstore, "upload failed")
+			}
+			rec.payloads = append(rec.payloads, string(b))
+			rec.ids = append(rec.ids, props.Source.ID)
+			rec.formats = append(rec.formats, props.Ingestion.Additional.Format)
+			rec.targets = append(rec.targets, PartitionTarget{Database: props.Ingestion.DatabaseName, Table: props.Ingestion.TableName, Tags: props.Ingestion.Additional.Tags})
+			return "", nil
+		},
+		OnLocal: func(ctx context.Context, from string, props properties.All) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			if rec.fail != nil && rec.fail(from) {
+				return errors.ES(errors.OpFileIngest, errors.KBlobstore, "upload failed")
+			}
+			rec.local = append(rec.local, from)
+			return nil
+		},
+	}
+	return ingestion
+}
+
+func writeSplitFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestFromFileSplit(t *testing.T) {
+	t.Parallel()
+
+	const csv = "1,a\n2,\"b\nb\"\n3,c\n4,d\n5,e"
+
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		maxSize    int64
+		options    []FileOption
+		fail       func(payload string) bool
+		want       []string
+		wantChunks []ChunkResult
+		wantLocal  bool
+		wantErr    bool
+	}{
+		{
+			name:    "records",
+			file:    "data.csv",
+			content: csv,
+			maxSize: 8,
+			want:    []string{"1,a\n", "2,\"b\nb\"\n", "3,c\n4,d\n", "5,e"},
+			wantChunks: []ChunkResult{
+				{FirstRecord: 0, Records: 1, Size: 4},
+				{FirstRecord: 1, Records: 1, Size: 8},
+				{FirstRecord: 2, Records: 2, Size: 8},
+				{FirstRecord: 4, Records: 1, Size: 3},
+			},
+		},
+		{
+			name:    "header",
+			file:    "data.csv",
+			content: "Id,Name\n" + csv,
+			maxSize: 16,
+			options: []FileOption{IgnoreFirstRecord()},
+			want:    []string{"Id,Name\n1,a\n", "Id,Name\n2,\"b\nb\"\n", "Id,Name\n3,c\n4,d\n", "Id,Name\n5,e"},
+			wantChunks: []ChunkResult{
+				{FirstRecord: 0, Records: 2, Size: 12},
+				{FirstRecord: 2, Records: 1, Size: 16},
+				{FirstRecord: 3, Records: 2, Size: 16},
+				{FirstRecord: 5, Records: 1, Size: 11},
+			},
+		},
+		{
+			name:    "json lines",
+			file:    "data",
+			content: "{\"a\":\"\\\"\"}\n{\"a\":2}\n",
+			maxSize: 4,
+			options: []FileOption{FileFormat(JSON)},
+			want:    []string{"{\"a\":\"\\\"\"}\n", "{\"a\":2}\n"},
+		},
+		{
+			name:      "small file",
+			file:      "data.csv",
+			content:   csv,
+			maxSize:   1024,
+			wantLocal: true,
+		},
+		{
+			name:      "multijson",
+			file:      "data.json",
+			content:   "[{\"a\":1},\n{\"a\":2}]",
+			maxSize:   4,
+			options:   []FileOption{FileFormat(MultiJSON)},
+			wantLocal: true,
+		},
+		{
+			name:    "failed chunk",
+			file:    "data.csv",
+			content: csv,
+			maxSize: 8,
+			fail: func(payload string) bool {
+				return payload == "3,c\n4,d\n"
+			},
+			want:    []string{"1,a\n", "2,\"b\nb\"\n", "5,e"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &splitRecorder{fail: test.fail}
+			ingestion := newSplitIngestion(t, rec)
+			path := writeSplitFile(t, test.file, test.content)
+
+			result, err := ingestion.FromFileSplit(context.Background(), path, test.maxSize, test.options...)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, result.Failed(), 1)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, result.Failed())
+			}
+
+			if test.wantLocal {
+				assert.Equal(t, []string{path}, rec.local)
+				assert.Empty(t, rec.payloads)
+				require.Len(t, result.Chunks, 1)
+				return
+			}
+
+			assert.Empty(t, rec.local)
+			assert.Equal(t, test.want, rec.payloads)
+			for i, id := range rec.ids {
+				assert.NotEqual(t, DFUnknown, rec.formats[i])
+				if test.fail == nil {
+					assert.Equal(t, sequentialID(result.SourceID, i), id)
+				}
+			}
+
+			if test.wantChunks != nil {
+				require.Len(t, result.Chunks, len(test.wantChunks))
+				for i, c := range result.Chunks {
+					assert.NotNil(t, c.Result)
+					c.Result = nil
+					assert.Equal(t, test.wantChunks[i], c)
+				}
+
+				records, err := result.Wait(context.Background())
+				assert.NoError(t, err)
+				assert.Len(t, records, len(test.wantChunks))
+			}
+		})
+	}
+}
+
+func TestSequentialID(t *testing.T) {
+	t.Parallel()
+
+	base := uuid.MustParse("00000000-0000-0001-ffff-ffffffffffff")
+	assert.Equal(t, base, sequentialID(base, 0))
+	assert.Equal(t, uuid.MustParse("00000000-0000-0002-0000-000000000000"), sequentialID(base, 1))
+	assert.Equal(t, uuid.MustParse("00000000-0000-0002-0000-000000000002"), sequentialID(base, 3))
+}
+
+func TestFromFileSplitRetryChunks(t *testing.T) {
+	t.Parallel()
+
+	const csv = "Id,Name\n1,a\n2,b\n3,c\n4,d\n5,e"
+
+	// The second chunk fails to upload once.
+	failed := false
+	rec := &splitRecorder{fail: func(payload string) bool {
+		if payload == "Id,Name\n3,c\n4,d\n" && !failed {
+			failed = true
+			return true
+		}
+		return false
+	}}
+	ingestion := newSplitIngestion(t, rec)
+	path := writeSplitFile(t, "data.csv", csv)
+
+	result, err := ingestion.FromFileSplit(context.Background(), path, 16, IgnoreFirstRecord(), Tags([]string{"t"}), RetryChunks(1))
+	require.NoError(t, err)
+	assert.Empty(t, result.Failed())
+	assert.Equal(t, []string{"Id,Name\n1,a\n2,b\n", "Id,Name\n3,c\n4,d\n", "Id,Name\n5,e"}, rec.payloads)
+	require.Len(t, result.Chunks, 3)
+	for i, c := range result.Chunks {
+		tag := sequentialID(result.SourceID, i).String()
+		assert.Equal(t, tag, c.IngestByTag)
+		assert.Equal(t, []string{"t", azkustodata.IngestByTagPrefix + tag}, rec.targets[i].Tags)
+		assert.Equal(t, tag, c.Result.record.IngestionSourceID.String())
+	}
+	assert.Equal(t, []int{1, 2, 1}, []int{result.Chunks[0].Attempts, result.Chunks[1].Attempts, result.Chunks[2].Attempts})
+
+	// The ingestion of the last chunk fails with a status that can be retried, it is queued again when waited for.
+	result.Chunks[2].Result.record.Status = Failed
+	result.Chunks[2].Result.record.FailureStatus = Transient
+	_, err = result.Wait(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Chunks[2].Attempts)
+	assert.Equal(t, "Id,Name\n5,e", rec.payloads[3])
+
+	// The retries are exhausted.
+	result.Chunks[2].Result.record.Status = Failed
+	result.Chunks[2].Result.record.FailureStatus = Transient
+	_, err = result.Wait(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, rec.payloads, 4)
+
+	_, err = ingestion.FromFileSplit(context.Background(), path, 16, IfNotExists("x"), RetryChunks(1))
+	assert.ErrorContains(t, err, "IfNotExists")
+}
+
+func TestFromFileSplitRetryWholeFile(t *testing.T) {
+	t.Parallel()
+
+	failures := 0
+	rec := &splitRecorder{fail: func(string) bool {
+		failures++
+		return failures <= 2
+	}}
+	ingestion := newSplitIngestion(t, rec)
+	path := writeSplitFile(t, "data.csv", "1,a\n")
+
+	result, err := ingestion.FromFileSplit(context.Background(), path, 1024, RetryChunks(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, rec.local)
+	require.Len(t, result.Chunks, 1)
+	assert.Equal(t, 3, result.Chunks[0].Attempts)
+	assert.Equal(t, sequentialID(result.SourceID, 0).String(), result.Chunks[0].IngestByTag)
+
+	failures = 0
+	_, err = ingestion.FromFileSplit(context.Background(), path, 1024, RetryChunks(1))
+	assert.Error(t, err)
+}
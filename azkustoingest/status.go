@@ -83,8 +83,9 @@ func (i FailureStatusCode) IsRetryable() bool {
 	}
 }
 
-// statusRecord is a record containing information regarding the status of an ingestion command
-type statusRecord struct {
+// StatusRecord is a record containing information regarding the status of an ingestion command.
+// It is the error returned by Result.Wait for failed ingestions, and the final record returned by Result.WaitWithOptions.
+type StatusRecord struct {
 	// Status is The ingestion status returned from the service. Status remains 'Pending' during the ingestion process and
 	// is updated by the service once the ingestion completes. When <see cref="IngestionReportMethod"/> is set to 'Queue', the ingestion status
 	// will always be 'Queued' and the caller needs to query the reports queues for ingestion status, as configured. To query statuses that were
@@ -113,9 +114,15 @@ type statusRecord struct {
 	// OperationID is the ingestion's operation ID.
 	OperationID uuid.UUID
 
-	// ActivityID is the ingestion's activity ID.
+	// ActivityID is the ingestion's activity ID. For streaming ingestions, it is the activity id the service gave to the
+	// request, from the x-ms-activity-id header of its response.
 	ActivityID uuid.UUID
 
+	// ClientRequestID is the client request id of a streaming ingestion, sent in the x-ms-client-request-id header, set
+	// with the ClientRequestId option or generated. Support traces streaming ingestions with it and ActivityID.
+	// It is empty for queued ingestions.
+	ClientRequestID string
+
 	// ErrorCode In case of a failure, indicates the failure's error code.
 	ErrorCode string
 
@@ -135,8 +142,8 @@ const (
 )
 
 // newStatusRecord creates a new record initialized with defaults.
-func newStatusRecord() statusRecord {
-	rec := statusRecord{
+func newStatusRecord() StatusRecord {
+	rec := StatusRecord{
 		Status:                     Failed,
 		IngestionSourceID:          uuid.Nil,
 		IngestionSourcePath:        undefinedString,
@@ -155,7 +162,7 @@ func newStatusRecord() statusRecord {
 }
 
 // FromProps takes in data from ingestion options.
-func (r *statusRecord) FromProps(props properties.All) {
+func (r *StatusRecord) FromProps(props properties.All) {
 	r.IngestionSourceID = props.Source.ID
 	r.Database = props.Ingestion.DatabaseName
 	r.Table = props.Ingestion.TableName
@@ -167,7 +174,7 @@ func (r *statusRecord) FromProps(props properties.All) {
 }
 
 // FromMap converts an ingestion status record to a key value map.
-func (r *statusRecord) FromMap(data map[string]interface{}) {
+func (r *StatusRecord) FromMap(data map[string]interface{}) {
 	strStatus := safeGetString(data, "Status")
 	if len(strStatus) > 0 {
 		r.Status = StatusCode(strStatus)
@@ -187,6 +194,7 @@ func (r *statusRecord) FromMap(data map[string]interface{}) {
 	r.IngestionSourceID = getGoogleUUIDFromInterface(data, "IngestionSourceId")
 	r.OperationID = getGoogleUUIDFromInterface(data, "OperationId")
 	r.ActivityID = getGoogleUUIDFromInterface(data, "ActivityId")
+	r.ClientRequestID = safeGetString(data, "ClientRequestId")
 
 	if data["UpdatedOn"] != nil {
 		if t, err := getTimeFromInterface(data["UpdatedOn"]); err == nil {
@@ -209,7 +217,7 @@ func StatusFromMapForTests(data map[string]interface{}) error {
 }
 
 // ToMap converts an ingestion status record to a key value map.
-func (r *statusRecord) ToMap() map[string]interface{} {
+func (r *StatusRecord) ToMap() map[string]interface{} {
 	data := make(map[string]interface{})
 
 	// Since we only create the initial record, It's not our responsibility to write the following fields:
@@ -226,12 +234,12 @@ func (r *statusRecord) ToMap() map[string]interface{} {
 }
 
 // String implements fmt.Stringer.
-func (r *statusRecord) String() string {
+func (r *StatusRecord) String() string {
 	return pretty.Sprint(r)
 }
 
 // Error converts an ingestion status to a string. Since we only provide the record in case of an error, the success branches will never be called.
-func (r statusRecord) Error() string {
+func (r StatusRecord) Error() string {
 	switch r.Status {
 	case Succeeded:
 		return fmt.Sprintf("Ingestion succeeded\n" + r.String())
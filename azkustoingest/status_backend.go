@@ -0,0 +1,149 @@
+package azkustoingest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/status"
+	"github.com/google/uuid"
+)
+
+// StatusBackend is a store of the outcomes of ingestions, set with WithStatusBackend. It can be used in addition to
+// or instead of the status table of the service.
+//
+// The clients write the final status record of every ingestion they learn the outcome of:
+//   - Queued, for queued ingestion without the ReportResultToTable option.
+//   - The final status read from the status table, when waiting on the Result of queued ingestion with
+//     the ReportResultToTable option.
+//   - The status of streaming ingestion, once the data is ingested.
+//
+// Records are keyed by their IngestionSourceID, which is set for every ingestion when a backend is used. The statuses
+// of the client itself, StatusRetrievalFailed and StatusRetrievalCanceled, are not written as they don't tell the
+// outcome of the ingestion. The clients don't retry failed writes, and an error of Write doesn't fail the ingestion.
+//
+// Write is called concurrently by the ingestions of a client.
+type StatusBackend interface {
+	// Write stores the status record of an ingestion, replacing the one of the same IngestionSourceID, if any.
+	Write(ctx context.Context, record StatusRecord) error
+}
+
+// WithStatusBackend configures the client to write the outcomes of its ingestions to the given StatusBackend.
+func WithStatusBackend(backend StatusBackend) Option {
+	return func(s *Ingestion) {
+		s.statusBackend = backend
+	}
+}
+
+// isIngestionOutcome reports whether the status is a final status of the ingestion, rather than of the client.
+func isIngestionOutcome(s StatusCode) bool {
+	return s.IsFinal() && s != StatusRetrievalFailed && s != StatusRetrievalCanceled
+}
+
+// MemoryStatusBackend is a StatusBackend that keeps the status records in memory.
+// Records are kept until they are deleted, so long-running processes should Delete the records they handled.
+type MemoryStatusBackend struct {
+	mu      sync.Mutex
+	records map[uuid.UUID]StatusRecord
+}
+
+// NewMemoryStatusBackend creates an empty MemoryStatusBackend.
+func NewMemoryStatusBackend() *MemoryStatusBackend {
+	return &MemoryStatusBackend{records: map[uuid.UUID]StatusRecord{}}
+}
+
+// Write implements StatusBackend.Write.
+func (m *MemoryStatusBackend) Write(_ context.Context, record StatusRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[record.IngestionSourceID] = record
+	return nil
+}
+
+// Get returns the status record of the ingestion with the given source id, if any.
+func (m *MemoryStatusBackend) Get(id uuid.UUID) (StatusRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[id]
+	return record, ok
+}
+
+// Records returns all the status records, ordered by the time they were last updated.
+func (m *MemoryStatusBackend) Records() []StatusRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]StatusRecord, 0, len(m.records))
+	for _, r := range m.records {
+		records = append(records, r)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].UpdatedOn.Before(records[j].UpdatedOn)
+	})
+	return records
+}
+
+// Delete removes the status record of the ingestion with the given source id.
+func (m *MemoryStatusBackend) Delete(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, id)
+}
+
+// TableStatusBackend is a StatusBackend that writes the status records to an Azure table, with the schema of the
+// status table of the service: the partition key is the IngestionSourceID, and the row key is the nil UUID.
+type TableStatusBackend struct {
+	client *status.TableClient
+}
+
+// NewTableStatusBackend creates a TableStatusBackend for the table at the given URL, which includes a SAS that allows
+// to read and write its entities, such as "https://account.table.core.windows.net/statuses?sv=...".
+func NewTableStatusBackend(tableURL string) (*TableStatusBackend, error) {
+	uri, err := resources.Parse(tableURL)
+	if err != nil {
+		return nil, errors.ES(errors.OpUnknown, errors.KClientArgs, "invalid status table URL: %s", err).SetNoRetry()
+	}
+
+	client, err := status.NewTableClient(*uri)
+	if err != nil {
+		return nil, errors.ES(errors.OpUnknown, errors.KClientArgs, "could not create a status table client: %s", err).SetNoRetry()
+	}
+	return &TableStatusBackend{client: client}, nil
+}
+
+// Write implements StatusBackend.Write.
+func (t *TableStatusBackend) Write(_ context.Context, record StatusRecord) error {
+	return t.client.Upsert(record.IngestionSourceID.String(), record.toFullMap())
+}
+
+// Read returns the status record of the ingestion with the given source id.
+func (t *TableStatusBackend) Read(_ context.Context, id uuid.UUID) (StatusRecord, error) {
+	data, err := t.client.Read(id.String())
+	if err != nil {
+		return StatusRecord{}, err
+	}
+
+	record := newStatusRecord()
+	record.FromMap(data)
+	return record, nil
+}
+
+// toFullMap converts all the fields of an ingestion status record to a key value map, unlike ToMap which only has the
+// fields of the initial record.
+func (r *StatusRecord) toFullMap() map[string]interface{} {
+	data := r.ToMap()
+	data["Status"] = string(r.Status)
+	data["OperationId"] = r.OperationID
+	data["ActivityId"] = r.ActivityID
+	data["ClientRequestId"] = r.ClientRequestID
+	data["ErrorCode"] = r.ErrorCode
+	data["FailureStatus"] = string(r.FailureStatus)
+	data["Details"] = r.Details
+	data["OriginatesFromUpdatePolicy"] = r.OriginatesFromUpdatePolicy
+	return data
+}
@@ -0,0 +1,148 @@
+package azkustoingest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusBackendQueued(t *testing.T) {
+	t.Parallel()
+
+	backend := NewMemoryStatusBackend()
+	ingestion := newSplitIngestion(t, &splitRecorder{})
+	ingestion.statusBackend = backend
+
+	result, err := ingestion.FromReader(context.Background(), strings.NewReader("1,a\n"), FileFormat(CSV))
+	require.NoError(t, err)
+
+	id := result.record.IngestionSourceID
+	require.NotEqual(t, uuid.Nil, id)
+
+	record, ok := backend.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, Queued, record.Status)
+	assert.Equal(t, "db", record.Database)
+	assert.Equal(t, "table", record.Table)
+}
+
+func TestStatusBackendStreaming(t *testing.T) {
+	t.Parallel()
+
+	backend := NewMemoryStatusBackend()
+	streaming := &Streaming{
+		db:    "db",
+		table: "table",
+		streamConn: fakeStreamIngestor{onStreamIngest: func(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming,
+			mappingName string, clientRequestId string, isBlobUri bool) error {
+			return nil
+		}},
+		statusBackend: backend,
+	}
+
+	result, err := streaming.FromReader(context.Background(), strings.NewReader("1,a\n"), FileFormat(CSV))
+	require.NoError(t, err)
+
+	records := backend.Records()
+	require.Len(t, records, 1)
+	assert.Equal(t, result.record.IngestionSourceID, records[0].IngestionSourceID)
+	assert.NotEqual(t, uuid.Nil, records[0].IngestionSourceID)
+	assert.Equal(t, result.record.Status, records[0].Status)
+}
+
+func TestStatusBackendReport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status StatusCode
+		want   bool
+	}{
+		{status: Pending},
+		{status: StatusRetrievalFailed},
+		{status: StatusRetrievalCanceled},
+		{status: Queued, want: true},
+		{status: Succeeded, want: true},
+		{status: Failed, want: true},
+		{status: PartiallySucceeded, want: true},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(string(test.status), func(t *testing.T) {
+			t.Parallel()
+
+			backend := NewMemoryStatusBackend()
+			r := newResult()
+			r.backend = backend
+			r.record.IngestionSourceID = uuid.New()
+			r.record.Status = test.status
+
+			r.report(context.Background())
+			_, ok := backend.Get(r.record.IngestionSourceID)
+			assert.Equal(t, test.want, ok)
+		})
+	}
+}
+
+func TestMemoryStatusBackend(t *testing.T) {
+	t.Parallel()
+
+	backend := NewMemoryStatusBackend()
+	now := time.Now()
+	first, second := newStatusRecord(), newStatusRecord()
+	first.IngestionSourceID, first.UpdatedOn = uuid.New(), now.Add(time.Minute)
+	second.IngestionSourceID, second.UpdatedOn = uuid.New(), now
+
+	require.NoError(t, backend.Write(context.Background(), first))
+	require.NoError(t, backend.Write(context.Background(), second))
+	assert.Equal(t, []StatusRecord{second, first}, backend.Records())
+
+	first.Status = Succeeded
+	require.NoError(t, backend.Write(context.Background(), first))
+	got, ok := backend.Get(first.IngestionSourceID)
+	require.True(t, ok)
+	assert.Equal(t, Succeeded, got.Status)
+
+	backend.Delete(second.IngestionSourceID)
+	assert.Equal(t, []StatusRecord{first}, backend.Records())
+}
+
+func TestStatusRecordFullMap(t *testing.T) {
+	t.Parallel()
+
+	record := newStatusRecord()
+	record.Status = PartiallySucceeded
+	record.IngestionSourceID = uuid.New()
+	record.IngestionSourcePath = "https://account.blob.core.windows.net/container/blob"
+	record.Database = "db"
+	record.Table = "table"
+	record.UpdatedOn = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	record.OperationID = uuid.New()
+	record.ActivityID = uuid.New()
+	record.ErrorCode = "BadRequest_EmptyBlob"
+	record.FailureStatus = Permanent
+	record.Details = "empty blob"
+	record.OriginatesFromUpdatePolicy = true
+
+	got := newStatusRecord()
+	got.FromMap(record.toFullMap())
+	assert.Equal(t, record, got)
+}
+
+func TestNewTableStatusBackend(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTableStatusBackend("http://account.table.core.windows.net/statuses")
+	assert.Error(t, err)
+
+	backend, err := NewTableStatusBackend("https://account.table.core.windows.net/statuses?sv=2020-08-04&sig=x")
+	require.NoError(t, err)
+	assert.NotNil(t, backend)
+}
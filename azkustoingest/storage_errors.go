@@ -0,0 +1,21 @@
+package azkustoingest
+
+import (
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/queued"
+)
+
+// The errors of queued ingestion that happen in the storage of the ingestion resources, before Kusto is involved.
+// The errors returned by FromFile and FromReader of the Ingestion client match them with errors.Is, and still are
+// *errors.Error whose retry status tells if the operation may succeed later:
+//
+//	if errors.Is(err, azkustoingest.ErrBlobUpload) { ... }
+var (
+	// ErrBlobUpload is returned when the data could not be uploaded to any of the containers of the ingestion resources.
+	ErrBlobUpload = queued.ErrBlobUpload
+	// ErrQueuePost is returned when the ingestion message could not be posted to any of the queues of the ingestion
+	// resources.
+	ErrQueuePost = queued.ErrQueuePost
+	// ErrSASExpired is returned along with ErrBlobUpload or ErrQueuePost when the storage refused the SAS of the
+	// ingestion resources. The client refreshes the resources and retries once before returning it.
+	ErrSASExpired = queued.ErrSASExpired
+)
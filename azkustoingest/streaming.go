@@ -11,9 +11,12 @@ import (
 
 	"github.com/Azure/azure-kusto-go/azkustodata"
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/log"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/gzip"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/queued"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/sniff"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/validation"
 	"github.com/google/uuid"
 )
 
@@ -22,12 +25,60 @@ type streamIngestor interface {
 	StreamIngest(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) error
 }
 
+// tracedStreamIngestor is a streamIngestor that reports the ids of its requests, such as azkustodata.Conn.
+type tracedStreamIngestor interface {
+	StreamIngestWithInfo(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) (azkustodata.StreamIngestInfo, error)
+}
+
+// optionsStreamIngestor is a tracedStreamIngestor that sends custom headers and uncompressed payloads, such as
+// azkustodata.Conn.
+type optionsStreamIngestor interface {
+	StreamIngestWithOptions(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string,
+		clientRequestId string, isBlobUri bool, options azkustodata.StreamIngestOptions) (azkustodata.StreamIngestInfo, error)
+}
+
+// streamIngest streams the payload with c, and returns the ids of the request, which only has the client request id if
+// c doesn't report them. If uncompressed is true, the payload isn't compressed.
+func streamIngest(c streamIngestor, ctx context.Context, payload io.Reader, props properties.All, isBlobUri, uncompressed bool) (azkustodata.StreamIngestInfo, error) {
+	if uncompressed || len(props.Streaming.Headers) > 0 {
+		withOptions, ok := c.(optionsStreamIngestor)
+		if !ok {
+			option := "WithHTTPHeader()"
+			if uncompressed {
+				option = "WithoutCompression()"
+			}
+			return azkustodata.StreamIngestInfo{ClientRequestID: props.Streaming.ClientRequestId},
+				errors.ES(errors.OpIngestStream, errors.KClientArgs, "%s is not supported by the streaming connection", option).SetNoRetry()
+		}
+		return withOptions.StreamIngestWithOptions(ctx, props.Ingestion.DatabaseName, props.Ingestion.TableName, payload, props.Ingestion.Additional.Format,
+			props.Ingestion.Additional.IngestionMappingRef, props.Streaming.ClientRequestId, isBlobUri,
+			azkustodata.StreamIngestOptions{Headers: props.Streaming.Headers, Uncompressed: uncompressed})
+	}
+	if traced, ok := c.(tracedStreamIngestor); ok {
+		return traced.StreamIngestWithInfo(ctx, props.Ingestion.DatabaseName, props.Ingestion.TableName, payload, props.Ingestion.Additional.Format,
+			props.Ingestion.Additional.IngestionMappingRef, props.Streaming.ClientRequestId, isBlobUri)
+	}
+	err := c.StreamIngest(ctx, props.Ingestion.DatabaseName, props.Ingestion.TableName, payload, props.Ingestion.Additional.Format,
+		props.Ingestion.Additional.IngestionMappingRef, props.Streaming.ClientRequestId, isBlobUri)
+	return azkustodata.StreamIngestInfo{ClientRequestID: props.Streaming.ClientRequestId}, err
+}
+
 // Streaming provides data ingestion from external sources into Kusto.
+// A Streaming client is safe for concurrent use by multiple goroutines, and should be shared instead of created per call.
 type Streaming struct {
 	db         string
 	table      string
 	client     QueryClient
 	streamConn streamIngestor
+
+	statusBackend   StatusBackend
+	recordTransform RecordTransform
+	// defaultOptions are applied to every ingestion, see WithDefaultOptions.
+	defaultOptions []FileOption
+
+	// withoutCompression and compressionLevel are set by WithoutCompression and WithCompressionLevel.
+	withoutCompression bool
+	compressionLevel   int
 }
 
 type blobUri struct {
@@ -39,7 +90,16 @@ type blobUri struct {
 // https://docs.microsoft.com/en-us/azure/kusto/management/create-ingestion-mapping-command
 func NewStreaming(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*Streaming, error) {
 	o := getOptions(options)
+	if err := validateDefaultOptions(o.defaultOptions, StreamingClient); err != nil {
+		return nil, err
+	}
+	if err := validateCompression(o); err != nil {
+		return nil, err
+	}
+	return newStreaming(kcsb, o)
+}
 
+func newStreaming(kcsb *azkustodata.ConnectionStringBuilder, o *Ingestion) (*Streaming, error) {
 	if !o.withoutEndpointCorrection {
 		newKcsb := *kcsb
 		newKcsb.DataSource = removeIngestPrefix(newKcsb.DataSource)
@@ -66,6 +126,13 @@ func newStreamingFromClient(client QueryClient, o *Ingestion) (*Streaming, error
 		table:      o.table,
 		client:     client,
 		streamConn: streamConn,
+
+		statusBackend:   o.statusBackend,
+		recordTransform: o.recordTransform,
+		defaultOptions:  o.defaultOptions,
+
+		withoutCompression: o.withoutCompression,
+		compressionLevel:   o.compressionLevel,
 	}
 
 	return i, nil
@@ -74,6 +141,7 @@ func newStreamingFromClient(client QueryClient, o *Ingestion) (*Streaming, error
 // FromFile allows uploading a data file for Kusto from either a local path or a blobstore URI path.
 // This method is thread-safe.
 func (i *Streaming) FromFile(ctx context.Context, fPath string, options ...FileOption) (*Result, error) {
+	options, _ = withDefaults(i.defaultOptions, options, FromFile)
 	props := i.newProp()
 	file, err, local := prepFileAndProps(fPath, &props, options, StreamingClient)
 
@@ -82,15 +150,18 @@ func (i *Streaming) FromFile(ctx context.Context, fPath string, options ...FileO
 	}
 
 	if !local {
-		return streamImpl(i.streamConn, ctx, generateBlobUriPayloadReader(fPath), props, true)
+		return streamImpl(i.streamConn, ctx, generateBlobUriPayloadReader(fPath), props, true, i.statusBackend)
 	}
 
 	defer file.Close()
-	return streamImpl(i.streamConn, ctx, file, props, false)
+	return streamImpl(i.streamConn, ctx, file, props, false, i.statusBackend)
 }
 
 // Returns the opened file, err, boolean indicator if its a local file
 func prepFileAndProps(fPath string, props *properties.All, options []FileOption, client ClientScope) (*os.File, error, bool) {
+	if err := validateOptions(options, client, FromFile); err != nil {
+		return nil, err, true
+	}
 	var err error
 	for _, option := range options {
 		err := option.Run(props, client, FromFile)
@@ -105,6 +176,9 @@ func prepFileAndProps(fPath string, props *properties.All, options []FileOption,
 	}
 
 	if !local {
+		if err := completeMappingKind(props); err != nil {
+			return nil, err, false
+		}
 		return nil, nil, false
 	}
 
@@ -114,8 +188,11 @@ func prepFileAndProps(fPath string, props *properties.All, options []FileOption,
 	if err != nil {
 		return nil, err, true
 	}
+	if err := completeMappingKind(props); err != nil {
+		return nil, err, true
+	}
 
-	props.Source.DontCompress = !queued.ShouldCompress(props, compression)
+	props.Source.DontCompress = !queued.NeedsCompression(props, compression)
 
 	file, err := os.Open(fPath)
 	if err != nil {
@@ -127,9 +204,15 @@ func prepFileAndProps(fPath string, props *properties.All, options []FileOption,
 // FromReader allows uploading a data file for Kusto from an io.Reader. The content is uploaded to Blobstore and
 // ingested after all data in the reader is processed. Content should not use compression as the content will be
 // compressed with gzip. This method is thread-safe.
+// If no format is set with FileFormat or an ingestion mapping, it is detected from the first KB of the content, and an
+// error with the best guess is returned if it can't be detected with confidence.
 func (i *Streaming) FromReader(ctx context.Context, reader io.Reader, options ...FileOption) (*Result, error) {
+	options, _ = withDefaults(i.defaultOptions, options, FromReader)
 	props := i.newProp()
 
+	if err := validateOptions(options, StreamingClient, FromReader); err != nil {
+		return nil, err
+	}
 	for _, prop := range options {
 		err := prop.Run(&props, StreamingClient, FromReader)
 		if err != nil {
@@ -137,27 +220,52 @@ func (i *Streaming) FromReader(ctx context.Context, reader io.Reader, options ..
 		}
 	}
 
-	return streamImpl(i.streamConn, ctx, reader, props, false)
-}
-
-func streamImpl(c streamIngestor, ctx context.Context, payload io.Reader, props properties.All, isBlobUri bool) (*Result, error) {
-	compress := queued.ShouldCompress(&props, ingestoptions.CTUnknown)
-	if compress && !isBlobUri {
-		payload = gzip.Compress(payload)
+	reader, err := sniff.Format(reader, &props)
+	if err != nil {
+		return nil, err
+	}
+	if err := completeMappingKind(&props); err != nil {
+		return nil, err
+	}
+	reader, transformer, err := transformRecords(reader, props, i.recordTransform)
+	if err != nil {
+		return nil, err
 	}
+	defer transformer.Close()
+
+	result, err := streamImpl(i.streamConn, ctx, reader, props, false, i.statusBackend)
+	return result, transformer.failure(err)
+}
 
+func streamImpl(c streamIngestor, ctx context.Context, payload io.Reader, props properties.All, isBlobUri bool, backend StatusBackend) (*Result, error) {
 	if props.Ingestion.Additional.Format == DFUnknown {
 		props.Ingestion.Additional.Format = CSV
 	}
 
-	err := c.StreamIngest(ctx, props.Ingestion.DatabaseName, props.Ingestion.TableName, payload, props.Ingestion.Additional.Format,
-		props.Ingestion.Additional.IngestionMappingRef,
-		props.Streaming.ClientRequestId,
-		isBlobUri)
+	var validator *validation.Reader
+	compress := queued.ShouldCompress(&props, ingestoptions.CTUnknown)
+	if compress && !isBlobUri {
+		payload, validator = validation.Wrap(payload, &props)
+		// The payload is compressed while the request is sent, and the compression stops with the request.
+		compressed := gzip.CompressContextLevel(ctx, payload, queued.CompressionLevel(&props))
+		defer compressed.Close()
+		payload = compressed
+	}
+	// Without compression, the payloads that would have been compressed are sent as is.
+	uncompressed := !isBlobUri && props.Source.NoCompression && queued.NeedsCompression(&props, ingestoptions.CTUnknown)
+	if uncompressed {
+		payload, validator = validation.Wrap(payload, &props)
+	}
 
+	info, err := streamIngest(c, ctx, payload, props, isBlobUri, uncompressed)
 	if err != nil {
+		log.Writef(log.EventIngest, "streaming ingestion into %s.%s with client request id %q and activity id %q failed: %s",
+			props.Ingestion.DatabaseName, props.Ingestion.TableName, info.ClientRequestID, info.ActivityID, err)
+		if validator.Err() != nil {
+			return nil, validator.Err()
+		}
 		if e, ok := errors.GetKustoError(err); ok {
-			return nil, e
+			return nil, classifyStreamingError(err, e)
 		}
 		return nil, errors.E(errors.OpIngestStream, errors.KClientArgs, err)
 	}
@@ -167,15 +275,27 @@ func streamImpl(c streamIngestor, ctx context.Context, payload io.Reader, props
 		return nil, err
 	}
 
+	if backend != nil && props.Source.ID == uuid.Nil {
+		props.Source.ID = uuid.New()
+	}
+
 	result := newResult()
+	result.backend = backend
 	result.putProps(props)
 	result.record.Status = "Success"
+	result.record.ClientRequestID = info.ClientRequestID
+	if activityID, err := uuid.Parse(info.ActivityID); err == nil {
+		result.record.ActivityID = activityID
+	}
+	result.report(ctx)
+	log.Writef(log.EventIngest, "streamed data into %s.%s with client request id %q and activity id %q",
+		props.Ingestion.DatabaseName, props.Ingestion.TableName, info.ClientRequestID, info.ActivityID)
 
 	return result, nil
 }
 
 func (i *Streaming) newProp() properties.All {
-	return properties.All{
+	props := properties.All{
 		Ingestion: properties.Ingestion{
 			DatabaseName: i.db,
 			TableName:    i.table,
@@ -184,6 +304,8 @@ func (i *Streaming) newProp() properties.All {
 			ClientRequestId: "KGC.executeStreaming;" + uuid.New().String(),
 		},
 	}
+	setCompression(&props, i.withoutCompression, i.compressionLevel)
+	return props
 }
 
 func (i *Streaming) Close() error {
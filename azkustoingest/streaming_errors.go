@@ -0,0 +1,98 @@
+package azkustoingest
+
+import (
+	goErrors "errors"
+	"net/http"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// The errors of streaming ingestion that are identified from the response of the service. The errors returned by the
+// Streaming and Managed clients match them with errors.Is, and still are *errors.Error:
+//
+//	if errors.Is(err, azkustoingest.ErrThrottled) { ... }
+var (
+	// ErrStreamingPolicyDisabled is returned when the streaming ingestion policy of the table or database isn't enabled.
+	// The Managed client falls back to queued ingestion without retrying.
+	ErrStreamingPolicyDisabled = goErrors.New("the streaming ingestion policy is not enabled")
+	// ErrTableNotFound is returned when the table, or its database, doesn't exist.
+	ErrTableNotFound = goErrors.New("the table was not found")
+	// ErrPayloadTooLarge is returned when the payload exceeds the size limit of streaming ingestion.
+	// The Managed client falls back to queued ingestion without retrying.
+	ErrPayloadTooLarge = goErrors.New("the payload is too large for streaming ingestion")
+	// ErrThrottled is returned when the service throttled the request. It can be retried after a backoff.
+	ErrThrottled = goErrors.New("the request was throttled")
+)
+
+// streamingErrorCodes maps the codes and the types of the REST errors of the service to the streaming errors.
+var streamingErrorCodes = map[string]error{
+	"BadRequest_StreamingIngestionPolicyNotEnabled":                         ErrStreamingPolicyDisabled,
+	"Kusto.DataNode.Exceptions.StreamingIngestionPolicyNotEnabledException": ErrStreamingPolicyDisabled,
+	"BadRequest_EntityNotFound":                                             ErrTableNotFound,
+	"Kusto.Data.Exceptions.EntityNotFoundException":                         ErrTableNotFound,
+	"TooManyRequests": ErrThrottled,
+}
+
+// streamingErrorStatuses maps the HTTP statuses that identify a streaming error without a known code.
+var streamingErrorStatuses = map[int]error{
+	http.StatusNotFound:              ErrTableNotFound,
+	http.StatusRequestEntityTooLarge: ErrPayloadTooLarge,
+	http.StatusTooManyRequests:       ErrThrottled,
+}
+
+// streamingError is the error of a *errors.Error that matches a streaming error. It keeps the message of the original
+// error, and unwraps to both.
+type streamingError struct {
+	sentinel error
+	err      error
+}
+
+func (e *streamingError) Error() string {
+	return e.err.Error()
+}
+
+func (e *streamingError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// classifyStreamingError finds the streaming error of err, from the response of the service that err wraps, and
+// returns a copy of e that matches it. e is returned as is if err isn't a known streaming error.
+func classifyStreamingError(err error, e *errors.Error) *errors.Error {
+	var httpErr *errors.HttpError
+	if !goErrors.As(err, &httpErr) {
+		return e
+	}
+
+	sentinel := streamingErrorFromREST(httpErr)
+	if sentinel == nil {
+		sentinel = streamingErrorStatuses[httpErr.StatusCode]
+	}
+	if sentinel == nil {
+		return e
+	}
+
+	classified := *e
+	classified.Err = &streamingError{sentinel: sentinel, err: e.Err}
+	return &classified
+}
+
+// streamingErrorFromREST returns the streaming error of the code or the type of the REST error, if known.
+func streamingErrorFromREST(e *errors.HttpError) error {
+	m := e.UnmarshalREST()
+	if m == nil {
+		return nil
+	}
+	errMap, ok := m["error"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, key := range []string{"code", "@type"} {
+		if s, ok := errMap[key].(string); ok {
+			if sentinel, ok := streamingErrorCodes[s]; ok {
+				return sentinel
+			}
+		}
+	}
+	return nil
+}
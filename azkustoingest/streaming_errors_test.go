@@ -0,0 +1,99 @@
+package azkustoingest
+
+import (
+	"context"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingHTTPError returns the error of a failed streaming ingestion, as returned by azkustodata.Conn.
+func streamingHTTPError(statusCode int, body string) error {
+	httpErr := errors.HTTP(errors.OpIngestStream, http.StatusText(statusCode), statusCode, io.NopCloser(strings.NewReader(body)), "error from Kusto endpoint")
+	return errors.E(errors.OpIngestStream, errors.KHTTPError, fmt.Errorf("streaming ingestion failed: %w", httpErr))
+}
+
+func TestStreamingErrors(t *testing.T) {
+	t.Parallel()
+
+	sentinels := []error{ErrStreamingPolicyDisabled, ErrTableNotFound, ErrPayloadTooLarge, ErrThrottled}
+
+	tests := []struct {
+		desc     string
+		err      error
+		expected error
+	}{
+		{
+			desc:     "Policy disabled code",
+			err:      streamingHTTPError(http.StatusBadRequest, `{"error": {"code": "BadRequest_StreamingIngestionPolicyNotEnabled", "@permanent": true}}`),
+			expected: ErrStreamingPolicyDisabled,
+		},
+		{
+			desc:     "Policy disabled type",
+			err:      streamingHTTPError(http.StatusBadRequest, `{"error": {"code": "BadRequest", "@type": "Kusto.DataNode.Exceptions.StreamingIngestionPolicyNotEnabledException"}}`),
+			expected: ErrStreamingPolicyDisabled,
+		},
+		{
+			desc:     "Table not found code",
+			err:      streamingHTTPError(http.StatusBadRequest, `{"error": {"code": "BadRequest_EntityNotFound"}}`),
+			expected: ErrTableNotFound,
+		},
+		{
+			desc:     "Table not found status",
+			err:      streamingHTTPError(http.StatusNotFound, ``),
+			expected: ErrTableNotFound,
+		},
+		{
+			desc:     "Payload too large",
+			err:      streamingHTTPError(http.StatusRequestEntityTooLarge, `{"error": {"code": "BadRequest"}}`),
+			expected: ErrPayloadTooLarge,
+		},
+		{
+			desc:     "Throttled",
+			err:      streamingHTTPError(http.StatusTooManyRequests, ``),
+			expected: ErrThrottled,
+		},
+		{
+			desc: "Unknown code",
+			err:  streamingHTTPError(http.StatusBadRequest, `{"error": {"code": "BadRequest_SyntaxError"}}`),
+		},
+		{
+			desc: "Not an HTTP error",
+			err:  errors.ES(errors.OpIngestStream, errors.KHTTPError, "connection reset"),
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conn := fakeStreamIngestor{
+				onStreamIngest: func(context.Context, string, string, io.Reader, azkustodata.DataFormatForStreaming, string, string, bool) error {
+					return test.err
+				},
+			}
+			props := properties.All{Ingestion: properties.Ingestion{DatabaseName: "db", TableName: "table"}}
+			_, err := streamImpl(conn, context.Background(), strings.NewReader("a,b\n"), props, false, nil)
+			require.Error(t, err)
+
+			// The error keeps its type and its message.
+			_, ok := err.(*errors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, test.err.Error(), err.Error())
+
+			for _, sentinel := range sentinels {
+				assert.Equal(t, sentinel == test.expected, goErrors.Is(err, sentinel), sentinel.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package azkustoingest
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+)
+
+// StreamingPool streams data into any table of a cluster, with the database and table chosen per call.
+// All the calls share the same HTTP transport, authentication and compression buffers, so a single pool should serve
+// all the tables, instead of creating a Streaming client per table.
+// A StreamingPool is safe for concurrent use by multiple goroutines.
+type StreamingPool struct {
+	streaming *Streaming
+}
+
+// NewStreamingPool is the constructor for StreamingPool. The options are the same as for NewStreaming, except that the
+// default database and table are ignored.
+func NewStreamingPool(kcsb *azkustodata.ConnectionStringBuilder, options ...Option) (*StreamingPool, error) {
+	streaming, err := NewStreaming(kcsb, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamingPool{streaming: streaming}, nil
+}
+
+// Ingest streams the content of reader into the table of the database, like Streaming.FromReader.
+// The Database and Table options are overridden by db and table.
+func (p *StreamingPool) Ingest(ctx context.Context, db, table string, reader io.Reader, options ...FileOption) (*Result, error) {
+	options, err := p.tableOptions(db, table, options)
+	if err != nil {
+		return nil, err
+	}
+	return p.streaming.FromReader(ctx, reader, options...)
+}
+
+// IngestFile streams a local file or a blob into the table of the database, like Streaming.FromFile.
+// The Database and Table options are overridden by db and table.
+func (p *StreamingPool) IngestFile(ctx context.Context, db, table, fPath string, options ...FileOption) (*Result, error) {
+	options, err := p.tableOptions(db, table, options)
+	if err != nil {
+		return nil, err
+	}
+	return p.streaming.FromFile(ctx, fPath, options...)
+}
+
+// tableOptions returns the options of a call, with the options that target the table of the call last.
+func (p *StreamingPool) tableOptions(db, table string, options []FileOption) ([]FileOption, error) {
+	if db == "" || table == "" {
+		return nil, errors.ES(errors.OpIngestStream, errors.KClientArgs, "StreamingPool requires a database and a table, got database %q and table %q", db, table).SetNoRetry()
+	}
+
+	all := make([]FileOption, 0, len(options)+2)
+	all = append(all, options...)
+	return append(all, Database(db), Table(table)), nil
+}
+
+// Close closes the connections of the pool.
+func (p *StreamingPool) Close() error {
+	return p.streaming.Close()
+}
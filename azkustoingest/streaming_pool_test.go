@@ -0,0 +1,61 @@
+package azkustoingest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingPool(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	targets := map[string]int{}
+	pool := &StreamingPool{streaming: &Streaming{
+		client: newMockClient(),
+		streamConn: fakeStreamIngestor{
+			onStreamIngest: func(_ context.Context, db, table string, payload io.Reader, _ azkustodata.DataFormatForStreaming, _ string, _ string, _ bool) error {
+				if _, err := io.ReadAll(payload); err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				targets[db+"."+table]++
+				return nil
+			},
+		},
+	}}
+
+	var wg sync.WaitGroup
+	for _, table := range []string{"a", "b", "c", "a"} {
+		table := table // capture
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The Database and Table options are overridden by the arguments.
+			result, err := pool.Ingest(context.Background(), "db", table, strings.NewReader("1,2\n"), FileFormat(CSV), Table("other"))
+			assert.NoError(t, err)
+			assert.Equal(t, StatusCode("Success"), result.record.Status)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"db.a": 2, "db.b": 1, "db.c": 1}, targets)
+
+	_, err := pool.Ingest(context.Background(), "db", "", strings.NewReader("1,2\n"))
+	e, ok := errors.GetKustoError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.KClientArgs, e.Kind)
+
+	_, err = pool.IngestFile(context.Background(), "", "table", "data.csv")
+	assert.Error(t, err)
+
+	assert.NoError(t, pool.Close())
+}
@@ -2,15 +2,18 @@ package azkustoingest
 
 import (
 	"bytes"
+	stdgzip "compress/gzip"
 	"context"
 	"fmt"
 	"github.com/Azure/azure-kusto-go/azkustodata"
 	"io"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustoingest/ingestoptions"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/gzip"
 	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
 	"github.com/google/uuid"
@@ -223,3 +226,190 @@ func TestStreaming(t *testing.T) {
 	}
 
 }
+
+// tracedFakeStreamIngestor reports the ids of its requests, like azkustodata.Conn.
+type tracedFakeStreamIngestor struct {
+	fakeStreamIngestor
+	activityID string
+}
+
+func (f tracedFakeStreamIngestor) StreamIngestWithInfo(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) (azkustodata.StreamIngestInfo, error) {
+	err := f.onStreamIngest(ctx, db, table, payload, format, mappingName, clientRequestId, isBlobUri)
+	return azkustodata.StreamIngestInfo{ClientRequestID: clientRequestId, EchoedClientRequestID: clientRequestId, ActivityID: f.activityID}, err
+}
+
+func TestStreamingRequestIDs(t *testing.T) {
+	t.Parallel()
+
+	activityID := uuid.New()
+	succeed := fakeStreamIngestor{onStreamIngest: func(context.Context, string, string, io.Reader, azkustodata.DataFormatForStreaming, string, string, bool) error {
+		return nil
+	}}
+
+	tests := []struct {
+		name           string
+		conn           streamIngestor
+		wantActivityID uuid.UUID
+	}{
+		{name: "Traced", conn: tracedFakeStreamIngestor{fakeStreamIngestor: succeed, activityID: activityID.String()}, wantActivityID: activityID},
+		{name: "Not a uuid", conn: tracedFakeStreamIngestor{fakeStreamIngestor: succeed, activityID: "activity"}},
+		{name: "Not traced", conn: succeed},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			streaming := Streaming{db: "db", table: "table", client: mockClient{endpoint: "https://test.kusto.windows.net"}, streamConn: test.conn}
+			result, err := streaming.FromReader(context.Background(), strings.NewReader("a,b"), ClientRequestId("MyApp;1"))
+			require.NoError(t, err)
+
+			record := result.record
+			assert.Equal(t, "MyApp;1", record.ClientRequestID)
+			assert.Equal(t, test.wantActivityID, record.ActivityID)
+		})
+	}
+}
+
+// optionsFakeStreamIngestor records the options of its requests, like azkustodata.Conn.
+type optionsFakeStreamIngestor struct {
+	tracedFakeStreamIngestor
+	options *azkustodata.StreamIngestOptions
+}
+
+func (f optionsFakeStreamIngestor) StreamIngestWithOptions(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming,
+	mappingName string, clientRequestId string, isBlobUri bool, options azkustodata.StreamIngestOptions) (azkustodata.StreamIngestInfo, error) {
+	*f.options = options
+	return f.StreamIngestWithInfo(ctx, db, table, payload, format, mappingName, clientRequestId, isBlobUri)
+}
+
+func TestStreamingHTTPHeaders(t *testing.T) {
+	t.Parallel()
+
+	succeed := fakeStreamIngestor{onStreamIngest: func(context.Context, string, string, io.Reader, azkustodata.DataFormatForStreaming, string, string, bool) error {
+		return nil
+	}}
+	var options azkustodata.StreamIngestOptions
+	conn := optionsFakeStreamIngestor{tracedFakeStreamIngestor: tracedFakeStreamIngestor{fakeStreamIngestor: succeed}, options: &options}
+
+	streaming := Streaming{db: "db", table: "table", client: mockClient{endpoint: "https://test.kusto.windows.net"}, streamConn: conn}
+	_, err := streaming.FromReader(context.Background(), strings.NewReader("a,b"), WithHTTPHeader("x-route-cluster", "west"))
+	require.NoError(t, err)
+	assert.Equal(t, azkustodata.StreamIngestOptions{Headers: http.Header{"X-Route-Cluster": {"west"}}}, options)
+
+	_, err = streaming.FromReader(context.Background(), strings.NewReader("a,b"), WithHTTPHeader("Authorization", "Bearer x"))
+	assert.Error(t, err)
+
+	// A connection that can't send the headers fails rather than dropping them.
+	streaming.streamConn = succeed
+	_, err = streaming.FromReader(context.Background(), strings.NewReader("a,b"), WithHTTPHeader("x-route-cluster", "west"))
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestStreamingCompression(t *testing.T) {
+	t.Parallel()
+
+	var payloads [][]byte
+	record := fakeStreamIngestor{onStreamIngest: func(_ context.Context, _ string, _ string, payload io.Reader, _ azkustodata.DataFormatForStreaming, _ string, _ string, _ bool) error {
+		b, err := io.ReadAll(payload)
+		payloads = append(payloads, b)
+		return err
+	}}
+	var options azkustodata.StreamIngestOptions
+	conn := optionsFakeStreamIngestor{tracedFakeStreamIngestor: tracedFakeStreamIngestor{fakeStreamIngestor: record}, options: &options}
+	input := strings.Repeat("a,b\n", 1024)
+
+	// By default, the payload is compressed.
+	streaming := Streaming{db: "db", table: "table", client: mockClient{endpoint: "https://test.kusto.windows.net"}, streamConn: conn}
+	_, err := streaming.FromReader(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	assert.False(t, options.Uncompressed)
+	zr, err := stdgzip.NewReader(bytes.NewReader(payloads[0]))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(decompressed))
+
+	// With a compression level.
+	streaming.compressionLevel = stdgzip.BestSpeed
+	_, err = streaming.FromReader(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	zr, err = stdgzip.NewReader(bytes.NewReader(payloads[1]))
+	require.NoError(t, err)
+	decompressed, err = io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(decompressed))
+
+	// Without compression, the payload is sent as is, with the custom headers.
+	streaming.compressionLevel = 0
+	streaming.withoutCompression = true
+	_, err = streaming.FromReader(context.Background(), strings.NewReader(input), WithHTTPHeader("x-route-cluster", "west"))
+	require.NoError(t, err)
+	assert.True(t, options.Uncompressed)
+	assert.Equal(t, http.Header{"X-Route-Cluster": {"west"}}, options.Headers)
+	assert.Equal(t, input, string(payloads[2]))
+
+	// Payloads that are already compressed are sent as before.
+	options = azkustodata.StreamIngestOptions{}
+	_, err = streaming.FromReader(context.Background(), bytes.NewReader(payloads[0]), CompressionType(ingestoptions.GZIP), FileFormat(CSV))
+	require.NoError(t, err)
+	assert.False(t, options.Uncompressed)
+	assert.Equal(t, payloads[0], payloads[3])
+
+	// A connection that can't send uncompressed payloads fails.
+	streaming.streamConn = record
+	_, err = streaming.FromReader(context.Background(), strings.NewReader(input))
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestCompressionOptions(t *testing.T) {
+	t.Parallel()
+
+	kcsb := azkustodata.NewConnectionStringBuilder("https://test.kusto.windows.net")
+	_, err := NewStreaming(kcsb, WithCompressionLevel(42))
+	assert.ErrorContains(t, err, "not a valid gzip level")
+	_, err = NewStreaming(kcsb, WithCompressionLevel(stdgzip.NoCompression))
+	assert.ErrorContains(t, err, "not a valid gzip level")
+	_, err = NewStreaming(kcsb, WithCompressionLevel(stdgzip.BestSpeed), WithoutCompression())
+	assert.ErrorContains(t, err, "can't be used together")
+
+	streaming, err := NewStreaming(kcsb, WithCompressionLevel(stdgzip.BestSpeed))
+	require.NoError(t, err)
+	defer streaming.Close()
+	assert.Equal(t, stdgzip.BestSpeed, streaming.newProp().Source.CompressionLevel)
+
+	streaming, err = NewStreaming(kcsb, WithoutCompression())
+	require.NoError(t, err)
+	defer streaming.Close()
+	assert.True(t, streaming.newProp().Source.NoCompression)
+}
+
+func TestStreamingCanceledWhileSending(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	readErr := make(chan error, 1)
+	conn := fakeStreamIngestor{onStreamIngest: func(ctx context.Context, _ string, _ string, payload io.Reader, _ azkustodata.DataFormatForStreaming, _ string, _ string, _ bool) error {
+		buf := make([]byte, 16)
+		if _, err := payload.Read(buf); err != nil {
+			return err
+		}
+		cancel()
+		_, err := io.Copy(io.Discard, payload)
+		readErr <- err
+		return err
+	}}
+
+	// The input never ends, so the payload can only stop because the context is canceled.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		_, _ = pw.Write([]byte("a,b\n"))
+	}()
+
+	streaming := Streaming{db: "db", table: "table", client: mockClient{endpoint: "https://test.kusto.windows.net"}, streamConn: conn}
+	_, err := streaming.FromReader(ctx, pr, FileFormat(CSV))
+	require.Error(t, err)
+	assert.ErrorIs(t, <-readErr, context.Canceled)
+}
@@ -0,0 +1,412 @@
+package azkustoingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/shopspring/decimal"
+)
+
+// maxStructsChunkSize is the maximum size of the JSON payload of a chunk of records, before compression.
+// Chunks that are small enough are streamed, and the larger ones are queued.
+const maxStructsChunkSize = int64(256 * mb)
+
+// ChunkResult is the outcome of the ingestion of one chunk of the records passed to FromStructs.
+type ChunkResult struct {
+	// FirstRecord is the index of the first record of the chunk.
+	FirstRecord int
+	// Records is the number of records in the chunk.
+	Records int
+	// Size is the size of the JSON payload of the chunk, before compression.
+	Size int64
+	// Result is the result of the ingestion of the chunk, or nil if it failed.
+	Result *Result
+	// Err is the error that made the chunk fail, if any.
+	Err error
+	// Attempts is the number of times the chunk was queued, and IngestByTag the value of the ingest-by: tag it was
+	// ingested with. They are only set for the chunks of FromFileSplit with RetryChunks.
+	Attempts    int
+	IngestByTag string
+}
+
+// StructsResult is the outcome of a FromStructs call.
+type StructsResult struct {
+	// Chunks are the chunks the records were split into, in order.
+	// If the ingestion stopped early, the records after the last chunk were not ingested.
+	Chunks []ChunkResult
+}
+
+// Failed returns the chunks that failed to ingest.
+func (r *StructsResult) Failed() []ChunkResult {
+	var failed []ChunkResult
+	for _, c := range r.Chunks {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// structColumn is an exported field of a struct, ingested into the column with the same name.
+type structColumn struct {
+	name string
+	// field is the name of the field, prefixed with the names of the structs it is nested in.
+	field string
+	// index is the index sequence of the field, as used by reflect.Value.FieldByIndex.
+	index []int
+}
+
+// jsonColumnMapping is an entry of a JSON ingestion mapping.
+type jsonColumnMapping struct {
+	Column     string            `json:"column"`
+	Properties map[string]string `json:"Properties"`
+}
+
+// FromStructs ingests a slice of structs (or pointers to structs) into the table.
+// The records are serialized as JSON, and every exported field is ingested into the column with the same name, or the
+// name in its `kusto:"<column>"` tag, following the same rules as query.ToStruct. Fields tagged with `kusto:"-"` are
+// skipped. The fields of embedded structs, and of nested structs with a `kusto:"<prefix>_"` tag, are ingested into
+// columns of their own, prefixed with the tag - a nil pointer to such a struct ingests nulls into its columns.
+// Fields of the value types, such as value.Long, are ingested as the value they hold, and time.Duration fields as
+// timespans. Fields of types that can't be serialized, such as channels and functions, are an error.
+// An ingestion mapping is generated from the fields, unless one is provided with IngestionMappingRef or IngestionMapping.
+//
+// The records are split into chunks by their serialized size, and every chunk is ingested like with FromReader - small
+// chunks are streamed, and large ones are queued.
+// A failed chunk doesn't stop the ingestion of the others, the outcome of every chunk is reported in the StructsResult.
+// The returned error combines the errors of all the failed chunks.
+func (m *Managed) FromStructs(ctx context.Context, data interface{}, options ...FileOption) (*StructsResult, error) {
+	return m.fromStructs(ctx, data, maxStructsChunkSize, options)
+}
+
+func (m *Managed) fromStructs(ctx context.Context, data interface{}, chunkSize int64, options []FileOption) (*StructsResult, error) {
+	records := reflect.ValueOf(data)
+	if records.Kind() != reflect.Slice {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() requires a slice of structs, got %T", data).SetNoRetry()
+	}
+
+	elem := records.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() requires a slice of structs, got %T", data).SetNoRetry()
+	}
+
+	columns, err := structColumns(elem)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err = m.structsOptions(columns, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StructsResult{}
+	var errs []error
+
+	ingest := func(chunk ChunkResult, payload []byte) {
+		chunk.Size = int64(len(payload))
+		chunk.Result, chunk.Err = m.FromReader(ctx, bytes.NewReader(payload), options...)
+		if chunk.Err != nil {
+			errs = append(errs, chunk.Err)
+		}
+		result.Chunks = append(result.Chunks, chunk)
+	}
+
+	buf := bytes.Buffer{}
+	chunk := ChunkResult{}
+	for i := 0; i < records.Len(); i++ {
+		if err := ctx.Err(); err != nil {
+			return result, errors.CombineErrors(append(errs, err)...)
+		}
+
+		record := records.Index(i)
+		if isPtr {
+			if record.IsNil() {
+				return result, errors.CombineErrors(append(errs, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() record %d is nil", i).SetNoRetry())...)
+			}
+			record = record.Elem()
+		}
+
+		line, err := encodeStruct(record, columns)
+		if err != nil {
+			return result, errors.CombineErrors(append(errs, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() could not encode record %d: %s", i, err).SetNoRetry())...)
+		}
+
+		if buf.Len() > 0 && int64(buf.Len()+len(line)) > chunkSize {
+			ingest(chunk, buf.Bytes())
+			buf = bytes.Buffer{}
+			chunk = ChunkResult{FirstRecord: i}
+		}
+		buf.Write(line)
+		chunk.Records++
+	}
+
+	if buf.Len() > 0 {
+		ingest(chunk, buf.Bytes())
+	}
+
+	return result, errors.CombineErrors(errs...)
+}
+
+// structsOptions validates the options passed to FromStructs, and adds the generated ingestion mapping if needed.
+func (m *Managed) structsOptions(columns []structColumn, options []FileOption) ([]FileOption, error) {
+	props := m.newProp()
+	// The default options of the client are checked too, as FromReader applies them.
+	merged, _ := withDefaults(m.defaultOptions, options, FromReader)
+	if err := validateOptions(merged, ManagedClient, FromReader); err != nil {
+		return nil, err
+	}
+	for _, o := range merged {
+		if err := o.Run(&props, ManagedClient, FromReader); err != nil {
+			return nil, err
+		}
+	}
+
+	if f := props.Ingestion.Additional.Format; f != DFUnknown && f != JSON {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() ingests JSON records, and does not support the format %v", f).SetNoRetry()
+	}
+
+	if props.Ingestion.Additional.IngestionMapping != "" || props.Ingestion.Additional.IngestionMappingRef != "" {
+		return options, nil
+	}
+
+	mapping := make([]jsonColumnMapping, 0, len(columns))
+	for _, c := range columns {
+		path, err := json.Marshal(c.name)
+		if err != nil {
+			return nil, err
+		}
+		mapping = append(mapping, jsonColumnMapping{
+			Column:     c.name,
+			Properties: map[string]string{"Path": "$[" + string(path) + "]"},
+		})
+	}
+
+	// Streaming ingestion doesn't support inline mappings, but as the properties are named after the columns, the
+	// records are ingested the same way without it.
+	return append([]FileOption{IngestionMapping(mapping, JSON)}, options...), nil
+}
+
+// structColumns returns the columns the fields of a struct type are ingested into.
+func structColumns(t reflect.Type) ([]structColumn, error) {
+	columns, err := appendStructColumns(nil, t, nil, "", "", map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	// As with Go's promoted fields, a field of an embedded struct is shadowed by a field of the same name in an outer
+	// struct, and fields at the same depth can't share a column.
+	byName := make(map[string]int, len(columns))
+	kept := make([]bool, len(columns))
+	for i, c := range columns {
+		prev, ok := byName[c.name]
+		switch {
+		case !ok:
+		case len(columns[prev].index) < len(c.index):
+			continue
+		case len(columns[prev].index) == len(c.index):
+			return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() fields %s.%s and %s.%s are both ingested into column %s", t, columns[prev].field, t, c.field, c.name).SetNoRetry()
+		default:
+			kept[prev] = false
+		}
+		byName[c.name] = i
+		kept[i] = true
+	}
+	n := 0
+	for i, c := range columns {
+		if kept[i] {
+			columns[n] = c
+			n++
+		}
+	}
+	columns = columns[:n]
+
+	if len(columns) == 0 {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() struct %s has no exported fields to ingest", t).SetNoRetry()
+	}
+	return columns, nil
+}
+
+// appendStructColumns appends the columns of the fields of the struct type t to columns, flattening embedded structs
+// and nested structs with a prefix tag. index is the index sequence of t in the outer struct, field the prefix of the
+// names of its fields, and prefix the prefix of the names of their columns. visiting are the types of the structs t is
+// nested in, to stop at recursive types.
+func appendStructColumns(columns []structColumn, t reflect.Type, index []int, field, prefix string, visiting map[reflect.Type]bool) ([]structColumn, error) {
+	if visiting[t] {
+		return columns, nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.TrimSpace(f.Tag.Get("kusto"))
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if nested, ok := flattenedStruct(f, tag); ok {
+			var err error
+			columns, err = appendStructColumns(columns, nested, fieldIndex, field+f.Name+".", prefix+tag, visiting)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := prefix + f.Name
+		if !supportedFieldType(f.Type) {
+			return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() does not support the type %s of field %s.%s", f.Type, t, f.Name).SetNoRetry()
+		}
+		if strings.HasPrefix(tag, "#") {
+			return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "FromStructs() does not support the column index tag %q of field %s.%s", tag, t, f.Name).SetNoRetry()
+		} else if tag != "" {
+			name = prefix + tag
+		}
+		columns = append(columns, structColumn{name: name, field: field + f.Name, index: fieldIndex})
+	}
+	return columns, nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	decimalType  = reflect.TypeOf(decimal.Decimal{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	kustoType    = reflect.TypeOf((*value.Kusto)(nil)).Elem()
+)
+
+// supportedFieldType reports whether the values of a field of type t can be encoded as JSON.
+func supportedFieldType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// flattenedStruct returns the struct type of a field if its fields are ingested into columns of their own: if the
+// field is an embedded struct without a tag, or a struct with a `kusto:"<prefix>_"` tag, as in query.ToStruct.
+func flattenedStruct(f reflect.StructField, tag string) (reflect.Type, bool) {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t == decimalType {
+		return nil, false
+	}
+	if f.Anonymous && tag == "" {
+		// query.ToStruct can't allocate a nil pointer to an unexported struct, so it isn't flattened either.
+		if f.Type.Kind() == reflect.Ptr && !f.IsExported() {
+			return nil, false
+		}
+		return t, true
+	}
+	if f.IsExported() && strings.HasSuffix(tag, "_") {
+		return t, true
+	}
+	return nil, false
+}
+
+// fieldByIndex returns the field of the struct v with the index sequence index, or false if it is in a nested struct
+// that is a nil pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// encodeStruct encodes a struct as a line of JSON, with a property for every column.
+func encodeStruct(v reflect.Value, columns []structColumn) ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, c := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(c.name)
+		if err != nil {
+			return nil, err
+		}
+		encoded := []byte("null")
+		if field, ok := fieldByIndex(v, c.index); ok {
+			encoded, err = json.Marshal(fieldJSON(field))
+			if err != nil {
+				return nil, err
+			}
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// fieldJSON returns the value a field is encoded as. The Kusto values, such as value.Long, are encoded as the value
+// they hold, and durations as timespans, which are the types query.ToStruct decodes them from.
+func fieldJSON(field reflect.Value) interface{} {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		if k, ok := field.Interface().(value.Kusto); ok {
+			return kustoJSON(k)
+		}
+		field = field.Elem()
+	}
+
+	switch {
+	case field.Type() == durationType:
+		return value.TimespanString(time.Duration(field.Int()))
+	case reflect.PointerTo(field.Type()).Implements(kustoType):
+		p := reflect.New(field.Type())
+		p.Elem().Set(field)
+		return kustoJSON(p.Interface().(value.Kusto))
+	}
+	return field.Interface()
+}
+
+// kustoJSON returns the value a Kusto value is encoded as. Dynamics are written as is, and the values that have no
+// JSON equivalent as their text.
+func kustoJSON(v value.Kusto) interface{} {
+	if value.IsNull(v) {
+		return nil
+	}
+
+	switch val := v.(type) {
+	case *value.Dynamic:
+		return json.RawMessage(val.Value)
+	case *value.Timespan:
+		return val.Marshal()
+	case *value.Real:
+		if f := *val.Ptr(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return val.String()
+		}
+	}
+	return v.GetValue()
+}
@@ -0,0 +1,386 @@
+package azkustoingest
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	v1 "github.com/Azure/azure-kusto-go/azkustodata/query/v1"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/azkustoingest/internal/resources"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type structsRecord struct {
+	Name    string
+	Count   int32     `kusto:"Amount"`
+	When    time.Time `kusto:"Timestamp"`
+	Skipped string    `kusto:"-"`
+	hidden  string
+}
+
+// structsRecorder records the payloads ingested by a Managed client created with newStructsManaged.
+type structsRecorder struct {
+	mu       sync.Mutex
+	streamed []string
+	queued   []properties.All
+	fail     func(payload string) error
+}
+
+func newStructsManaged(t *testing.T, rec *structsRecorder) *Managed {
+	mockClient := mockClient{
+		endpoint: "https://test.kusto.windows.net",
+		auth:     azkustodata.Authorization{},
+		onMgmt: func(ctx context.Context, db string, query azkustodata.Statement, options ...azkustodata.QueryOption) (v1.Dataset, error) {
+			return nil, nil
+		},
+	}
+
+	ingestion, err := newFromClient(mockClient, &Ingestion{db: "defaultDb", table: "defaultTable"})
+	require.NoError(t, err)
+	ingestion.fs = resources.FsMock{
+		OnReader: func(ctx context.Context, reader io.Reader, props properties.All) (string, error) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.queued = append(rec.queued, props)
+			return "", nil
+		},
+	}
+
+	return &Managed{
+		queued: ingestion,
+		streaming: &Streaming{
+			db:     "defaultDb",
+			table:  "defaultTable",
+			client: mockClient,
+			streamConn: fakeStreamIngestor{
+				onStreamIngest: func(ctx context.Context, db, table string, payload io.Reader, format azkustodata.DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) error {
+					assert.Equal(t, properties.JSON, format)
+					reader, err := gzip.NewReader(payload)
+					require.NoError(t, err)
+					b, err := io.ReadAll(reader)
+					require.NoError(t, err)
+
+					rec.mu.Lock()
+					defer rec.mu.Unlock()
+					if rec.fail != nil {
+						if err := rec.fail(string(b)); err != nil {
+							return err
+						}
+					}
+					rec.streamed = append(rec.streamed, string(b))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func TestFromStructs(t *testing.T) {
+	t.Parallel()
+
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []structsRecord{
+		{Name: "a", Count: 1, When: when, Skipped: "x", hidden: "y"},
+		{Name: "b", Count: 2, When: when},
+		{Name: "c", Count: 3, When: when},
+	}
+	lines := []string{
+		`{"Name":"a","Amount":1,"Timestamp":"2024-01-02T03:04:05Z"}` + "\n",
+		`{"Name":"b","Amount":2,"Timestamp":"2024-01-02T03:04:05Z"}` + "\n",
+		`{"Name":"c","Amount":3,"Timestamp":"2024-01-02T03:04:05Z"}` + "\n",
+	}
+
+	off := backoff.NewExponentialBackOff()
+	off.InitialInterval = time.Millisecond
+
+	tests := []struct {
+		name      string
+		data      interface{}
+		chunkSize int64
+		options   []FileOption
+		fail      func(payload string) error
+		// wantErr is set when the call fails before ingesting anything.
+		wantErr      bool
+		wantStreamed []string
+		wantChunks   []ChunkResult
+		wantFailed   int
+	}{
+		{
+			name:         "single chunk",
+			data:         records,
+			chunkSize:    maxStructsChunkSize,
+			wantStreamed: []string{strings.Join(lines, "")},
+			wantChunks:   []ChunkResult{{FirstRecord: 0, Records: 3, Size: int64(len(strings.Join(lines, "")))}},
+		},
+		{
+			name:         "pointers",
+			data:         []*structsRecord{&records[0], &records[1], &records[2]},
+			chunkSize:    maxStructsChunkSize,
+			wantStreamed: []string{strings.Join(lines, "")},
+			wantChunks:   []ChunkResult{{FirstRecord: 0, Records: 3, Size: int64(len(strings.Join(lines, "")))}},
+		},
+		{
+			name:         "chunked",
+			data:         records,
+			chunkSize:    int64(len(lines[0]) * 2),
+			wantStreamed: []string{lines[0] + lines[1], lines[2]},
+			wantChunks: []ChunkResult{
+				{FirstRecord: 0, Records: 2, Size: int64(len(lines[0] + lines[1]))},
+				{FirstRecord: 2, Records: 1, Size: int64(len(lines[2]))},
+			},
+		},
+		{
+			name:      "failed chunk is reported",
+			data:      records,
+			chunkSize: int64(len(lines[0])),
+			fail: func(payload string) error {
+				if payload == lines[1] {
+					return errors.ES(errors.OpIngestStream, errors.KClientArgs, "bad chunk").SetNoRetry()
+				}
+				return nil
+			},
+			wantStreamed: []string{lines[0], lines[2]},
+			wantFailed:   1,
+			wantChunks: []ChunkResult{
+				{FirstRecord: 0, Records: 1, Size: int64(len(lines[0]))},
+				{FirstRecord: 1, Records: 1, Size: int64(len(lines[1]))},
+				{FirstRecord: 2, Records: 1, Size: int64(len(lines[2]))},
+			},
+		},
+		{
+			name:      "empty",
+			data:      []structsRecord{},
+			chunkSize: maxStructsChunkSize,
+		},
+		{
+			name:      "not a slice",
+			data:      records[0],
+			chunkSize: maxStructsChunkSize,
+			wantErr:   true,
+		},
+		{
+			name:      "not structs",
+			data:      []string{"a"},
+			chunkSize: maxStructsChunkSize,
+			wantErr:   true,
+		},
+		{
+			name: "index tags",
+			data: []struct {
+				A string `kusto:"#0"`
+			}{{A: "a"}},
+			chunkSize: maxStructsChunkSize,
+			wantErr:   true,
+		},
+		{
+			name: "duplicate columns",
+			data: []struct {
+				A string
+				B string `kusto:"A"`
+			}{{}},
+			chunkSize: maxStructsChunkSize,
+			wantErr:   true,
+		},
+		{
+			name:      "other format",
+			data:      records,
+			chunkSize: maxStructsChunkSize,
+			options:   []FileOption{FileFormat(CSV)},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &structsRecorder{fail: test.fail}
+			managed := newStructsManaged(t, rec)
+
+			options := append([]FileOption{backOff(off)}, test.options...)
+			result, err := managed.fromStructs(context.Background(), test.data, test.chunkSize, options)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				assert.Empty(t, rec.streamed)
+				return
+			}
+
+			failed := 0
+			require.Len(t, result.Chunks, len(test.wantChunks))
+			for i, c := range result.Chunks {
+				assert.Equal(t, test.wantChunks[i].FirstRecord, c.FirstRecord)
+				assert.Equal(t, test.wantChunks[i].Records, c.Records)
+				assert.Equal(t, test.wantChunks[i].Size, c.Size)
+				if c.Err != nil {
+					failed++
+					assert.Nil(t, c.Result)
+				} else {
+					assert.NotNil(t, c.Result)
+				}
+			}
+			assert.Equal(t, test.wantFailed, failed)
+			assert.Len(t, result.Failed(), failed)
+			if failed > 0 {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.wantStreamed, rec.streamed)
+			assert.Empty(t, rec.queued)
+		})
+	}
+}
+
+func TestFromStructsQueuesLargeChunks(t *testing.T) {
+	t.Parallel()
+
+	rec := &structsRecorder{}
+	managed := newStructsManaged(t, rec)
+
+	// Random data doesn't compress well, so the chunk is too large to be streamed.
+	random := rand.New(rand.NewSource(1))
+	records := make([]structsRecord, 10*1024)
+	for i := range records {
+		b := make([]byte, 1024)
+		for j := range b {
+			b[j] = byte('a' + random.Intn(26))
+		}
+		records[i] = structsRecord{Name: string(b), Count: int32(i)}
+	}
+
+	result, err := managed.FromStructs(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, result.Chunks, 1)
+	assert.Equal(t, len(records), result.Chunks[0].Records)
+
+	assert.Empty(t, rec.streamed)
+	require.Len(t, rec.queued, 1)
+	props := rec.queued[0]
+	assert.Equal(t, properties.JSON, props.Ingestion.Additional.Format)
+	assert.Equal(t, properties.JSON, props.Ingestion.Additional.IngestionMappingType)
+	assert.Equal(t, `[{"column":"Name","Properties":{"Path":"$[\"Name\"]"}},{"column":"Amount","Properties":{"Path":"$[\"Amount\"]"}},{"column":"Timestamp","Properties":{"Path":"$[\"Timestamp\"]"}}]`,
+		props.Ingestion.Additional.IngestionMapping)
+}
+
+func TestFromStructsMappingRef(t *testing.T) {
+	t.Parallel()
+
+	rec := &structsRecorder{}
+	managed := newStructsManaged(t, rec)
+
+	options, err := managed.structsOptions([]structColumn{{name: "A"}}, []FileOption{IngestionMappingRef("ref", JSON)})
+	require.NoError(t, err)
+	assert.Len(t, options, 1)
+
+	options, err = managed.structsOptions([]structColumn{{name: "A"}}, nil)
+	require.NoError(t, err)
+	assert.Len(t, options, 1)
+
+	_, err = managed.structsOptions([]structColumn{{name: "A"}}, []FileOption{IngestionMappingRef("ref", CSV)})
+	assert.Error(t, err)
+}
+
+type StructsAudit struct {
+	Created time.Time
+	Name    string
+}
+
+type structsAddress struct {
+	City string
+	Zip  string `kusto:"PostalCode"`
+}
+
+type structsNested struct {
+	*StructsAudit
+	Name string
+	Home structsAddress  `kusto:"Home_"`
+	Work *structsAddress `kusto:"Work_"`
+}
+
+func TestStructColumnsNested(t *testing.T) {
+	t.Parallel()
+
+	columns, err := structColumns(reflect.TypeOf(structsNested{}))
+	require.NoError(t, err)
+	var names []string
+	for _, c := range columns {
+		names = append(names, c.name)
+	}
+	// Name is shadowed by the field of the outer struct, like in Go.
+	assert.Equal(t, []string{"Created", "Name", "Home_City", "Home_PostalCode", "Work_City", "Work_PostalCode"}, names)
+
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	line, err := encodeStruct(reflect.ValueOf(structsNested{
+		StructsAudit: &StructsAudit{Created: when, Name: "shadowed"},
+		Name:         "a",
+		Home:         structsAddress{City: "Seattle", Zip: "98101"},
+	}), columns)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Created":"2024-01-02T03:04:05Z","Name":"a","Home_City":"Seattle","Home_PostalCode":"98101","Work_City":null,"Work_PostalCode":null}`+"\n", string(line))
+
+	_, err = structColumns(reflect.TypeOf(struct {
+		Home structsAddress `kusto:"Home_"`
+		Work structsAddress `kusto:"Home_"`
+	}{}))
+	assert.Error(t, err)
+}
+
+func TestEncodeStructKustoValues(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		Count    value.Long
+		Ratio    *value.Real
+		Took     value.Timespan
+		Props    value.Dynamic
+		Missing  *value.Long
+		Null     value.Int
+		Duration time.Duration
+		Timeout  *time.Duration
+	}
+	timeout := 90 * time.Second
+	columns, err := structColumns(reflect.TypeOf(record{}))
+	require.NoError(t, err)
+
+	line, err := encodeStruct(reflect.ValueOf(record{
+		Count:    *value.NewLong(42),
+		Ratio:    value.NewReal(math.Inf(1)),
+		Took:     *value.NewTimespan(26*time.Hour + time.Second),
+		Props:    *value.NewDynamic([]byte(`{"a":1}`)),
+		Null:     *value.NewNullInt(),
+		Duration: 1500 * time.Millisecond,
+		Timeout:  &timeout,
+	}), columns)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Count":42,"Ratio":"+Inf","Took":"1.02:00:01","Props":{"a":1},"Missing":null,"Null":null,"Duration":"00:00:01.5","Timeout":"00:01:30"}`+"\n", string(line))
+}
+
+func TestStructColumnsUnsupportedTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(struct{ C chan int }{}),
+		reflect.TypeOf(struct{ F func() }{}),
+		reflect.TypeOf(struct{ N *complex128 }{}),
+	} {
+		_, err := structColumns(typ)
+		require.Error(t, err, typ)
+		assert.Contains(t, err.Error(), "does not support the type")
+	}
+}
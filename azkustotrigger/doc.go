@@ -0,0 +1,36 @@
+/*
+Package azkustotrigger polls a table or a materialized view for new rows, change-feed style, and dispatches them to a
+handler.
+
+A Trigger keeps a watermark: the time up to which rows were handled, according to their ingestion time or to a datetime
+column. Every poll queries the rows past the watermark, passes them to the handler, and saves the new watermark in a
+Store provided by the user, so that polling resumes where it stopped after a restart:
+
+	client, err := azkustodata.New(kcsb)
+	if err != nil {
+		panic(err)
+	}
+
+	trigger, err := azkustotrigger.New(client, "Samples", azkustotrigger.Source{Name: "Alerts"}, store,
+		func(ctx context.Context, batch azkustotrigger.Batch) error {
+			alerts, err := query.ToStructs[Alert](batch.Rows)
+			if err != nil {
+				return err
+			}
+			return notify(ctx, alerts)
+		},
+		azkustotrigger.Interval(time.Minute),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	err = trigger.Run(ctx)
+
+Rows are delivered at least once: the watermark is saved after the handler returns, so a batch is handled again if the
+handler fails, or if the process stops before the watermark is saved. Handlers should be idempotent.
+
+Rows are only polled once their time is older than the Delay of the trigger, to give data that is still being ingested
+time to become visible. Rows that become visible after the watermark passed their time are not delivered.
+*/
+package azkustotrigger
@@ -0,0 +1,14 @@
+module github.com/Azure/azure-kusto-go/azkustotrigger
+
+go 1.22
+
+require (
+	github.com/Azure/azure-kusto-go/azkustodata v1.0.0-preview-5
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
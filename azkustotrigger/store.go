@@ -0,0 +1,43 @@
+package azkustotrigger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists the watermarks of triggers. Implementations must be durable for the delivery of the rows to survive
+// restarts, and safe for concurrent use if they are shared by triggers.
+type Store interface {
+	// Load returns the watermark saved for key, or the zero time if none was saved.
+	Load(ctx context.Context, key string) (time.Time, error)
+	// Save saves the watermark of key.
+	Save(ctx context.Context, key string, watermark time.Time) error
+}
+
+// MemoryStore is a Store that keeps the watermarks in memory. It is meant for tests, and for triggers that may handle
+// rows again after a restart.
+type MemoryStore struct {
+	mu         sync.Mutex
+	watermarks map[string]time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{watermarks: map[string]time.Time{}}
+}
+
+// Load implements Store.Load.
+func (m *MemoryStore) Load(_ context.Context, key string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.watermarks[key], nil
+}
+
+// Save implements Store.Save.
+func (m *MemoryStore) Save(_ context.Context, key string, watermark time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watermarks[key] = watermark
+	return nil
+}
@@ -0,0 +1,266 @@
+package azkustotrigger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/kql"
+	"github.com/Azure/azure-kusto-go/azkustodata/poll"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	defaultDelay    = time.Minute
+	defaultWindow   = time.Hour
+)
+
+// Querier runs queries. It is implemented by *azkustodata.Client.
+type Querier interface {
+	Query(ctx context.Context, db string, kqlQuery azkustodata.Statement, options ...azkustodata.QueryOption) (query.Dataset, error)
+}
+
+// Source is the table or materialized view polled by a Trigger.
+type Source struct {
+	// Name is the name of the table, or of the materialized view if MaterializedView is set.
+	Name string
+	// MaterializedView is set if Name is a materialized view.
+	MaterializedView bool
+	// TimeColumn is the datetime column that orders the rows. If empty, the ingestion time of the rows is used, which
+	// requires the IngestionTime policy to be enabled. Materialized views should set it.
+	TimeColumn string
+}
+
+// Batch is the rows found by a poll, in a time window.
+type Batch struct {
+	// From is the start of the window, exclusive. It is the watermark before the batch.
+	From time.Time
+	// To is the end of the window, inclusive. It is the watermark once the batch is handled.
+	To time.Time
+	// Rows are the rows of the window, ordered by time.
+	Rows []query.Row
+}
+
+// Handler handles a batch of rows. If it returns an error, the watermark isn't advanced and the batch is polled again.
+type Handler func(ctx context.Context, batch Batch) error
+
+// Option is an option for New.
+type Option func(t *Trigger)
+
+// Key sets the key of the watermark in the Store. It defaults to "<db>/<source name>", and must be set for triggers
+// that poll the same source into different handlers.
+func Key(key string) Option {
+	return func(t *Trigger) {
+		t.key = key
+	}
+}
+
+// Interval sets the time between polls in Run. It defaults to 30 seconds.
+func Interval(d time.Duration) Option {
+	return func(t *Trigger) {
+		t.interval = d
+	}
+}
+
+// Delay sets how old rows must be before they are polled, to give the data that is still being ingested time to become
+// visible. It defaults to one minute.
+func Delay(d time.Duration) Option {
+	return func(t *Trigger) {
+		t.delay = d
+	}
+}
+
+// Window sets the largest time span queried at once. Polling a watermark that is further behind is done in several
+// batches. It defaults to one hour.
+func Window(d time.Duration) Option {
+	return func(t *Trigger) {
+		t.window = d
+	}
+}
+
+// StartAt sets the watermark to start from when none is saved in the Store. By default, the trigger starts from the
+// time of its first poll, and only delivers rows that arrive after it.
+func StartAt(watermark time.Time) Option {
+	return func(t *Trigger) {
+		t.start = watermark
+	}
+}
+
+// OnError sets a function called with the errors of the polls in Run, which then keeps polling. Without it, Run
+// returns the first error.
+func OnError(f func(err error)) Option {
+	return func(t *Trigger) {
+		t.onError = f
+	}
+}
+
+// QueryOptions sets options of the queries of the polls.
+func QueryOptions(options ...azkustodata.QueryOption) Option {
+	return func(t *Trigger) {
+		t.queryOptions = options
+	}
+}
+
+// Trigger polls a source for rows past its watermark, and dispatches them to a handler. See the package documentation.
+// A Trigger must not be polled concurrently.
+type Trigger struct {
+	client  Querier
+	db      string
+	source  Source
+	store   Store
+	handler Handler
+
+	key          string
+	interval     time.Duration
+	delay        time.Duration
+	window       time.Duration
+	start        time.Time
+	onError      func(err error)
+	queryOptions []azkustodata.QueryOption
+
+	now func() time.Time
+}
+
+// New creates a Trigger that polls the source in the database db.
+func New(client Querier, db string, source Source, store Store, handler Handler, options ...Option) (*Trigger, error) {
+	switch {
+	case client == nil:
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "azkustotrigger.New requires a client").SetNoRetry()
+	case source.Name == "":
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "azkustotrigger.New requires a source name").SetNoRetry()
+	case store == nil:
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "azkustotrigger.New requires a store").SetNoRetry()
+	case handler == nil:
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "azkustotrigger.New requires a handler").SetNoRetry()
+	}
+
+	t := &Trigger{
+		client:   client,
+		db:       db,
+		source:   source,
+		store:    store,
+		handler:  handler,
+		key:      db + "/" + source.Name,
+		interval: defaultInterval,
+		delay:    defaultDelay,
+		window:   defaultWindow,
+		now:      time.Now,
+	}
+	for _, o := range options {
+		o(t)
+	}
+
+	if t.interval <= 0 || t.window <= 0 || t.delay < 0 {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "the interval and the window of a trigger must be positive, and its delay can't be negative").SetNoRetry()
+	}
+	return t, nil
+}
+
+// Poll handles the rows past the watermark once, and returns the number of rows handled. It stops at the first error,
+// after saving the watermark of the batches that were handled.
+func (t *Trigger) Poll(ctx context.Context) (int, error) {
+	watermark, err := t.store.Load(ctx, t.key)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load the watermark of %q: %w", t.key, err)
+	}
+
+	until := t.now().UTC().Add(-t.delay)
+	if watermark.IsZero() {
+		watermark = t.start
+		if watermark.IsZero() {
+			watermark = until
+		}
+		if err := t.store.Save(ctx, t.key, watermark); err != nil {
+			return 0, fmt.Errorf("unable to save the watermark of %q: %w", t.key, err)
+		}
+	}
+
+	handled := 0
+	for watermark.Before(until) {
+		to := watermark.Add(t.window)
+		if to.After(until) {
+			to = until
+		}
+
+		rows, err := t.rows(ctx, watermark, to)
+		if err != nil {
+			return handled, err
+		}
+		if len(rows) > 0 {
+			if err := t.handler(ctx, Batch{From: watermark, To: to, Rows: rows}); err != nil {
+				return handled, err
+			}
+			handled += len(rows)
+		}
+
+		// Empty windows are only saved once the poll is done.
+		watermark = to
+		if len(rows) > 0 || !watermark.Before(until) {
+			if err := t.store.Save(ctx, t.key, watermark); err != nil {
+				return handled, fmt.Errorf("unable to save the watermark of %q: %w", t.key, err)
+			}
+		}
+	}
+	return handled, nil
+}
+
+// Run polls every interval, until ctx is done or a poll fails. See OnError to keep polling after failures.
+// Polls the service throttled are retried after the delay it asked for, see poll.RetryAfter.
+func (t *Trigger) Run(ctx context.Context) error {
+	p := poll.New(poll.Interval(t.interval), poll.Multiplier(1))
+	return p.Poll(ctx, func(ctx context.Context) (bool, error) {
+		if _, err := t.Poll(ctx); err != nil {
+			if t.onError == nil || ctx.Err() != nil {
+				return false, err
+			}
+			t.onError(err)
+			if poll.RetryAfter(err) > 0 {
+				return false, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// rows queries the rows with a time in (from, to].
+func (t *Trigger) rows(ctx context.Context, from, to time.Time) ([]query.Row, error) {
+	dataset, err := t.client.Query(ctx, t.db, t.query(from, to), t.queryOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	table := query.PrimaryByOrdinal(dataset.Tables(), 0)
+	if table == nil {
+		return nil, errors.ES(errors.OpQuery, errors.KInternal, "the poll of %q returned no primary result", t.key)
+	}
+	return table.Rows(), nil
+}
+
+func (t *Trigger) query(from, to time.Time) *kql.Builder {
+	q := kql.New("")
+	if t.source.MaterializedView {
+		q.AddLiteral("materialized_view(").AddString(t.source.Name).AddLiteral(")")
+	} else {
+		q.AddTable(t.source.Name)
+	}
+
+	column := func() {
+		if t.source.TimeColumn == "" {
+			q.AddLiteral("ingestion_time()")
+		} else {
+			q.AddColumn(t.source.TimeColumn)
+		}
+	}
+
+	q.AddLiteral(" | where ")
+	column()
+	q.AddLiteral(" > ").AddDateTime(from).AddLiteral(" and ")
+	column()
+	q.AddLiteral(" <= ").AddDateTime(to).AddLiteral(" | order by ")
+	column()
+	q.AddLiteral(" asc")
+	return q
+}
@@ -0,0 +1,232 @@
+package azkustotrigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/azkustodata"
+	"github.com/Azure/azure-kusto-go/azkustodata/errors"
+	"github.com/Azure/azure-kusto-go/azkustodata/query"
+	"github.com/Azure/azure-kusto-go/azkustodata/types"
+	"github.com/Azure/azure-kusto-go/azkustodata/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier returns a table of ids for every query, from responses in order. Missing responses are empty tables.
+type fakeQuerier struct {
+	mu        sync.Mutex
+	responses [][]int64
+	err       error
+	queries   []string
+}
+
+func (f *fakeQuerier) Query(ctx context.Context, _ string, kqlQuery azkustodata.Statement, _ ...azkustodata.QueryOption) (query.Dataset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.queries = append(f.queries, kqlQuery.String())
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var ids []int64
+	if len(f.responses) > 0 {
+		ids, f.responses = f.responses[0], f.responses[1:]
+	}
+
+	base := query.NewBaseDataset(ctx, errors.OpQuery, "PrimaryResult")
+	table := query.NewBaseTableWithOrdinal(base, 1, 0, "", "PrimaryResult", "PrimaryResult",
+		[]query.Column{query.NewColumn(0, "Id", types.Long)})
+	rows := make([]query.Row, 0, len(ids))
+	for i, id := range ids {
+		rows = append(rows, query.NewRow(table, i, value.Values{value.NewLong(id)}))
+	}
+	return query.NewDataset(base, []query.Table{query.NewTable(table, rows)}), nil
+}
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestTrigger(t *testing.T, client Querier, store Store, handler Handler, options ...Option) *Trigger {
+	trigger, err := New(client, "db", Source{Name: "Alerts"}, store, handler, options...)
+	require.NoError(t, err)
+	trigger.now = func() time.Time { return start.Add(3 * time.Hour) }
+	return trigger
+}
+
+func ids(t *testing.T, batch Batch) []int64 {
+	out := make([]int64, 0, len(batch.Rows))
+	for _, row := range batch.Rows {
+		id, err := row.LongByIndex(0)
+		require.NoError(t, err)
+		out = append(out, *id)
+	}
+	return out
+}
+
+func TestPoll(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeQuerier{responses: [][]int64{{1, 2}, {}}}
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(context.Background(), "db/Alerts", start))
+
+	var batches []Batch
+	var got [][]int64
+	trigger := newTestTrigger(t, client, store, func(_ context.Context, batch Batch) error {
+		batches = append(batches, batch)
+		got = append(got, ids(t, batch))
+		return nil
+	}, Delay(time.Hour))
+
+	n, err := trigger.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// The two hours before the delay are polled in windows of an hour, and empty windows aren't handled.
+	assert.Equal(t, [][]int64{{1, 2}}, got)
+	assert.Equal(t, start, batches[0].From)
+	assert.Equal(t, start.Add(time.Hour), batches[0].To)
+	assert.Equal(t, []string{
+		"Alerts | where ingestion_time() > datetime(2024-01-01T00:00:00Z) and ingestion_time() <= datetime(2024-01-01T01:00:00Z) | order by ingestion_time() asc",
+		"Alerts | where ingestion_time() > datetime(2024-01-01T01:00:00Z) and ingestion_time() <= datetime(2024-01-01T02:00:00Z) | order by ingestion_time() asc",
+	}, client.queries)
+
+	watermark, err := store.Load(context.Background(), "db/Alerts")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(2*time.Hour), watermark)
+
+	// Nothing is polled until time passes.
+	n, err = trigger.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, client.queries, 2)
+}
+
+func TestPollSource(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeQuerier{}
+	trigger, err := New(client, "db", Source{Name: "DailyAlerts", MaterializedView: true, TimeColumn: "Timestamp"},
+		NewMemoryStore(), func(context.Context, Batch) error { return nil }, StartAt(start), Delay(0), Window(24*time.Hour))
+	require.NoError(t, err)
+	trigger.now = func() time.Time { return start.Add(time.Hour) }
+
+	_, err = trigger.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`materialized_view("DailyAlerts") | where Timestamp > datetime(2024-01-01T00:00:00Z) and Timestamp <= datetime(2024-01-01T01:00:00Z) | order by Timestamp asc`,
+	}, client.queries)
+}
+
+func TestPollStart(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeQuerier{}
+	store := NewMemoryStore()
+	trigger := newTestTrigger(t, client, store, func(context.Context, Batch) error { return nil })
+
+	// Without a watermark, the trigger starts from now, minus the delay.
+	n, err := trigger.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, client.queries)
+
+	watermark, err := store.Load(context.Background(), "db/Alerts")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(3*time.Hour-time.Minute), watermark)
+}
+
+func TestPollRetry(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeQuerier{responses: [][]int64{{1}, {2}, {2}}}
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(context.Background(), "db/Alerts", start))
+
+	fail := true
+	var got [][]int64
+	trigger := newTestTrigger(t, client, store, func(_ context.Context, batch Batch) error {
+		got = append(got, ids(t, batch))
+		if got[len(got)-1][0] == 2 && fail {
+			fail = false
+			return fmt.Errorf("handler failed")
+		}
+		return nil
+	}, Delay(time.Hour))
+
+	n, err := trigger.Poll(context.Background())
+	assert.EqualError(t, err, "handler failed")
+	assert.Equal(t, 1, n)
+
+	// The watermark was saved after the first batch, so only the failed batch is handled again.
+	watermark, err := store.Load(context.Background(), "db/Alerts")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Hour), watermark)
+
+	n, err = trigger.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [][]int64{{1}, {2}, {2}}, got)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeQuerier{err: fmt.Errorf("query failed")}
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(context.Background(), "db/Alerts", start))
+	handler := func(context.Context, Batch) error { return nil }
+
+	// Without OnError, Run returns the first error.
+	trigger := newTestTrigger(t, client, store, handler, Interval(time.Millisecond))
+	assert.EqualError(t, trigger.Run(context.Background()), "query failed")
+
+	// With it, Run keeps polling until the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := 0
+	trigger = newTestTrigger(t, client, store, handler, Interval(time.Millisecond), OnError(func(err error) {
+		assert.EqualError(t, err, "query failed")
+		errs++
+		if errs == 3 {
+			cancel()
+		}
+	}))
+	assert.ErrorIs(t, trigger.Run(ctx), context.Canceled)
+	assert.Equal(t, 3, errs)
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+
+	handler := func(context.Context, Batch) error { return nil }
+	tests := []struct {
+		desc    string
+		client  Querier
+		source  Source
+		store   Store
+		handler Handler
+		options []Option
+	}{
+		{desc: "No client", source: Source{Name: "T"}, store: NewMemoryStore(), handler: handler},
+		{desc: "No source", client: &fakeQuerier{}, store: NewMemoryStore(), handler: handler},
+		{desc: "No store", client: &fakeQuerier{}, source: Source{Name: "T"}, handler: handler},
+		{desc: "No handler", client: &fakeQuerier{}, source: Source{Name: "T"}, store: NewMemoryStore()},
+		{desc: "Negative delay", client: &fakeQuerier{}, source: Source{Name: "T"}, store: NewMemoryStore(), handler: handler, options: []Option{Delay(-1)}},
+		{desc: "Zero window", client: &fakeQuerier{}, source: Source{Name: "T"}, store: NewMemoryStore(), handler: handler, options: []Option{Window(0)}},
+	}
+
+	for _, test := range tests {
+		test := test // capture
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(test.client, "db", test.source, test.store, test.handler, test.options...)
+			require.Error(t, err)
+			assert.False(t, errors.Retry(err))
+		})
+	}
+}